@@ -0,0 +1,156 @@
+// Package analyzers exposes every ollygarden rule as a go/analysis Analyzer, so the rules run
+// under go vet, gopls, golangci-lint module plugins or any other analysis driver.
+//
+// The rules themselves live in the Python rules package; each analyzer runs it once per package
+// (see runner.go) and reports the findings of its own rule, with their autofixes as
+// SuggestedFixes. Analyzer names are the rule IDs with dashes replaced by underscores, since
+// drivers turn them into flags. The rules a project adds in .ollygarden.yaml, custom rules and
+// those of rule plugins, are reported together by the project_rules analyzer.
+package analyzers
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// ruleInfo describes one rule; the list is generated into rules_gen.go by
+// `otel_cli.py gen-analyzers`.
+type ruleInfo struct {
+	ID       string
+	Name     string
+	Severity string
+	OptIn    bool
+	// Signals the rule checks: traces, metrics, logs, baggage, resource
+	Signals []string
+	Doc     string
+}
+
+// projectRules stands for the rules of the analyzed project's config, which aren't known when
+// the analyzers are built.
+var projectRules = ruleInfo{
+	ID:       "project-rules",
+	Name:     "project_rules",
+	Severity: "medium",
+	Signals:  []string{"traces", "metrics", "logs", "baggage", "resource"},
+	Doc: "Rules of the project's .ollygarden.yaml\n\nReports the custom rules and the rules of rule plugins " +
+		"the project's .ollygarden.yaml defines, each finding prefixed with its rule ID.",
+}
+
+// registered is every rule an analyzer reports: the generated ones, then projectRules.
+var registered = append(rules[:len(rules):len(rules)], projectRules)
+
+var byName = map[string]*analysis.Analyzer{}
+
+func init() {
+	for _, r := range registered {
+		byName[r.Name] = newAnalyzer(r)
+	}
+}
+
+func newAnalyzer(r ruleInfo) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: r.Name,
+		Doc:  r.Doc,
+		URL:  "https://github.com/aditya-prakash-git/ollygarden-opentelemetry#rules",
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return nil, report(pass, r)
+		},
+	}
+}
+
+// All returns one analyzer per rule, sorted by name. Opt-in rules are included: drivers
+// select analyzers with their own flags.
+func All() []*analysis.Analyzer {
+	all := make([]*analysis.Analyzer, 0, len(byName))
+	for _, a := range byName {
+		all = append(all, a)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Defaults returns the analyzers of the rules that run without being enabled explicitly.
+func Defaults() []*analysis.Analyzer {
+	var defaults []*analysis.Analyzer
+	for _, r := range registered {
+		if !r.OptIn {
+			defaults = append(defaults, byName[r.Name])
+		}
+	}
+	sort.Slice(defaults, func(i, j int) bool { return defaults[i].Name < defaults[j].Name })
+	return defaults
+}
+
+// Lookup returns the analyzer for a rule ID or analyzer name, or nil.
+func Lookup(name string) *analysis.Analyzer {
+	for _, r := range registered {
+		if r.ID == name || r.Name == name {
+			return byName[r.Name]
+		}
+	}
+	return nil
+}
+
+// Select returns the analyzers a project config's rules.enable and rules.disable would run:
+// the defaults plus the opt-in rules in enable, or only the rules in enable when it lists a
+// default rule, minus those in disable. Names are rule IDs or analyzer names.
+func Select(enable, disable []string) ([]*analysis.Analyzer, error) {
+	enabled, disabled := map[string]bool{}, map[string]bool{}
+	restricted := false
+	for _, name := range enable {
+		a := Lookup(name)
+		if a == nil {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		enabled[a.Name] = true
+	}
+	for _, name := range disable {
+		a := Lookup(name)
+		if a == nil {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		disabled[a.Name] = true
+	}
+	for _, r := range registered {
+		if enabled[r.Name] && !r.OptIn {
+			restricted = true
+		}
+	}
+	var selected []*analysis.Analyzer
+	for _, r := range registered {
+		if (enabled[r.Name] || (!r.OptIn && !restricted)) && !disabled[r.Name] {
+			selected = append(selected, byName[r.Name])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+	return selected, nil
+}
+
+// ForSignals returns the analyzers among selected whose rules check one of signals; all of them
+// when signals is empty.
+func ForSignals(selected []*analysis.Analyzer, signals []string) []*analysis.Analyzer {
+	if len(signals) == 0 {
+		return selected
+	}
+	wanted := map[string]bool{}
+	for _, s := range signals {
+		wanted[s] = true
+	}
+	covered := map[string]bool{}
+	for _, r := range registered {
+		for _, s := range r.Signals {
+			if wanted[s] {
+				covered[r.Name] = true
+			}
+		}
+	}
+	var kept []*analysis.Analyzer
+	for _, a := range selected {
+		if covered[a.Name] {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
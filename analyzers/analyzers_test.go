@@ -0,0 +1,168 @@
+package analyzers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestMain(m *testing.M) {
+	// The rules package is in the checkout above this module
+	home, err := filepath.Abs("..")
+	if err != nil {
+		panic(err)
+	}
+	Configure(Settings{Home: home})
+	os.Exit(m.Run())
+}
+
+// One analyzer per rule family, each over a package holding a violation and a correct example
+func TestAnalyzers(t *testing.T) {
+	for _, tc := range []struct {
+		rule, pkg string
+	}{
+		{"span-name-unbounded", "traces"},
+		{"log-pii-field", "logs"},
+		{"library-sets-global-provider", "sdk"},
+		{"pii-in-telemetry", "privacy"},
+		{"project-rules", "custom"},
+	} {
+		t.Run(tc.rule, func(t *testing.T) {
+			analysistest.Run(t, analysistest.TestData(), lookup(t, tc.rule), tc.pkg)
+		})
+	}
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), lookup(t, "metric-unit-invalid"), "metrics")
+}
+
+func TestHomeRequired(t *testing.T) {
+	t.Setenv("OLLYGARDEN_HOME", "")
+	Configure(Settings{})
+	t.Cleanup(func() {
+		home, _ := filepath.Abs("..")
+		Configure(Settings{Home: home})
+	})
+	_, err := runRules([]string{filepath.Join(analysistest.TestData(), "src", "sdk", "sdk.go")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "OLLYGARDEN_HOME") {
+		t.Errorf("got %v, want an error asking for OLLYGARDEN_HOME", err)
+	}
+}
+
+// run runs analyzer a over one file holding code, which is not what is saved on disk, as gopls
+// does with an unsaved buffer. Each call is a new analysis of the package.
+func run(t *testing.T, a *analysis.Analyzer, name, code string) []analysis.Diagnostic {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, code, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []analysis.Diagnostic
+	pass := &analysis.Pass{
+		Analyzer: a,
+		Fset:     fset,
+		Files:    []*ast.File{f},
+		Pkg:      types.NewPackage("shop", "shop"),
+		ReadFile: func(string) ([]byte, error) { return []byte(code), nil },
+		Report:   func(d analysis.Diagnostic) { found = append(found, d) },
+	}
+	if _, err := a.Run(pass); err != nil {
+		t.Fatal(err)
+	}
+	for i := range found {
+		found[i].Pos = token.Pos(fset.Position(found[i].Pos).Offset)
+	}
+	return found
+}
+
+const download = `package shop
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("shop")
+
+func handleDownload(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), NAME)
+	defer span.End()
+}
+`
+
+func TestUnsavedEdits(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "shop.go")
+	saved := strings.Replace(download, "NAME", `"download"`, 1)
+	if err := os.WriteFile(name, []byte(saved), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a := lookup(t, "span-name-unbounded")
+	if found := run(t, a, name, saved); len(found) != 0 {
+		t.Fatalf("saved file: %v", found)
+	}
+	edited := strings.Replace(download, "NAME", `"GET "+r.URL.Path`, 1)
+	found := run(t, a, name, edited)
+	if len(found) != 1 || int(found[0].Pos) != strings.Index(edited, `"GET "`) {
+		t.Errorf("edited buffer: got %v, want a finding at %d", found, strings.Index(edited, `"GET "`))
+	}
+	if found := run(t, a, name, saved); len(found) != 0 {
+		t.Errorf("after undoing the edit: %v", found)
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "shop.go")
+	code := strings.ReplaceAll(strings.Replace(download, "NAME", `"GET "+r.URL.Path`, 1), "\n", "\r\n")
+	if err := os.WriteFile(name, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	found := run(t, lookup(t, "span-name-unbounded"), name, code)
+	if len(found) != 1 || int(found[0].Pos) != strings.Index(code, `"GET "`) {
+		t.Errorf("got %v, want a finding at byte %d", found, strings.Index(code, `"GET "`))
+	}
+}
+
+func TestSelect(t *testing.T) {
+	selected, err := Select([]string{"span-name-unbounded"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].Name != "span_name_unbounded" {
+		t.Errorf("enabling a default rule selected %v", selected)
+	}
+	defaults, err := Select(nil, []string{"project_rules"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range defaults {
+		if a.Name == "project_rules" {
+			t.Error("disabled project_rules was selected")
+		}
+	}
+	if Lookup("project-rules") == nil || len(Defaults()) != len(defaults)+1 {
+		t.Errorf("project_rules is not a default analyzer")
+	}
+	if _, err := Select([]string{"no-such-rule"}, nil); err == nil {
+		t.Error("unknown rule accepted")
+	}
+}
+
+func lookup(t *testing.T, rule string) *analysis.Analyzer {
+	t.Helper()
+	a := Lookup(rule)
+	if a == nil {
+		t.Fatalf("no analyzer for %s", rule)
+	}
+	return a
+}
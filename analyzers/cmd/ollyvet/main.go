@@ -0,0 +1,19 @@
+// Command ollyvet runs the ollygarden rules as go/analysis analyzers:
+//
+//	ollyvet ./...
+//	ollyvet -fix ./...
+//	go vet -vettool=$(which ollyvet) ./...
+//
+// Every analyzer is enabled by default, opt-in rules included; pass -NAME flags
+// (e.g. -span_name_unbounded) to run only some of them.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/analyzers"
+)
+
+func main() {
+	multichecker.Main(analyzers.All()...)
+}
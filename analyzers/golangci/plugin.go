@@ -0,0 +1,113 @@
+// Package golangci registers the ollygarden analyzers as a golangci-lint module plugin, so the
+// rules run inside an existing golangci-lint setup instead of as a second linter binary.
+//
+// Build a custom golangci-lint with this package in .custom-gcl.yml, then enable the linter and
+// map its settings in .golangci.yml:
+//
+//	linters:
+//	  enable:
+//	    - ollygarden
+//	  settings:
+//	    custom:
+//	      ollygarden:
+//	        type: module
+//	        description: OpenTelemetry instrumentation rules
+//	        settings:
+//	          home: /path/to/ollygarden-opentelemetry
+//	          profile: ci
+//	          disable: [stdout-exporter]
+//	          options:
+//	            span-name-convention:
+//	              max_length: 60
+//
+// The selection works like rules.enable and rules.disable in .ollygarden.yaml, which the rules
+// still read (with its profiles, includes and excludes) from the analyzed module.
+package golangci
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/analyzers"
+)
+
+func init() {
+	register.Plugin("ollygarden", New)
+}
+
+// Settings are the linter's settings in .golangci.yml.
+type Settings struct {
+	// Enable adds opt-in rules to the defaults; listing a default rule runs only the listed ones.
+	Enable []string `json:"enable"`
+	// Disable turns rules off.
+	Disable []string `json:"disable"`
+	// Profile selects a .ollygarden.yaml profile.
+	Profile string `json:"profile"`
+	// Signals runs only the rules checking these signals, e.g. [traces, metrics].
+	Signals []string `json:"signals"`
+	// Options maps rule IDs to option values, merged over .ollygarden.yaml's.
+	Options map[string]map[string]any `json:"options"`
+	// Python is the interpreter running the rules (default python3).
+	Python string `json:"python"`
+	// Home is the checkout holding the rules package. A custom golangci-lint is built from the
+	// module cache, which doesn't have it, so set this (or OLLYGARDEN_HOME).
+	Home string `json:"home"`
+}
+
+// Plugin is the golangci-lint linter.
+type Plugin struct {
+	settings Settings
+}
+
+var _ register.LinterPlugin = (*Plugin)(nil)
+
+// New decodes the settings golangci-lint passes from .golangci.yml.
+func New(raw any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](raw)
+	if err != nil {
+		return nil, fmt.Errorf("ollygarden: %w", err)
+	}
+	return &Plugin{settings: s}, nil
+}
+
+// BuildAnalyzers returns the selected analyzers, configured to run the rules as the settings say.
+func (p *Plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	selected, err := analyzers.Select(p.settings.Enable, p.settings.Disable)
+	if err != nil {
+		return nil, fmt.Errorf("ollygarden: %w", err)
+	}
+	s, err := p.analyzerSettings()
+	if err != nil {
+		return nil, err
+	}
+	analyzers.Configure(s)
+	return analyzers.ForSignals(selected, p.settings.Signals), nil
+}
+
+// analyzerSettings returns the settings the analyzers run the rules with.
+func (p *Plugin) analyzerSettings() (analyzers.Settings, error) {
+	// The rules take options by rule ID; settings may use analyzer names as well
+	options := map[string]map[string]any{}
+	for name, values := range p.settings.Options {
+		a := analyzers.Lookup(name)
+		if a == nil {
+			return analyzers.Settings{}, fmt.Errorf("ollygarden: options for unknown rule %q", name)
+		}
+		options[strings.ReplaceAll(a.Name, "_", "-")] = values
+	}
+	return analyzers.Settings{
+		Python:  p.settings.Python,
+		Home:    p.settings.Home,
+		Profile: p.settings.Profile,
+		Signals: p.settings.Signals,
+		Options: options,
+	}, nil
+}
+
+// GetLoadMode reports that the rules only need the syntax of each package's files.
+func (p *Plugin) GetLoadMode() string {
+	return register.LoadModeSyntax
+}
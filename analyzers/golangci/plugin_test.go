@@ -0,0 +1,115 @@
+package golangci
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+)
+
+// build decodes raw as golangci-lint passes it from .golangci.yml and returns the plugin with
+// the analyzers it selects.
+func build(t *testing.T, raw map[string]any) (*Plugin, []string) {
+	t.Helper()
+	linter, err := New(raw)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	p := linter.(*Plugin)
+	selected, err := p.BuildAnalyzers()
+	if err != nil {
+		t.Fatalf("BuildAnalyzers: %v", err)
+	}
+	return p, names(selected)
+}
+
+func names(selected []*analysis.Analyzer) []string {
+	var names []string
+	for _, a := range selected {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func TestSettings(t *testing.T) {
+	p, selected := build(t, map[string]any{
+		"home":    "/opt/ollygarden",
+		"python":  "python3.12",
+		"profile": "ci",
+		"disable": []any{"stdout-exporter"},
+		"options": map[string]any{
+			"span_name_convention": map[string]any{"max_length": 60},
+			"span-name-unbounded":  map[string]any{"limit": 10},
+		},
+	})
+	if slices.Contains(selected, "stdout_exporter") || !slices.Contains(selected, "span_name_convention") {
+		t.Errorf("selected %v", selected)
+	}
+	if p.GetLoadMode() != register.LoadModeSyntax {
+		t.Errorf("load mode %q", p.GetLoadMode())
+	}
+	s, err := p.analyzerSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Home != "/opt/ollygarden" || s.Python != "python3.12" || s.Profile != "ci" {
+		t.Errorf("configured %+v", s)
+	}
+	// Options reach the rules by rule ID, whichever name the settings use
+	want := map[string]map[string]any{
+		"span-name-convention": {"max_length": float64(60)},
+		"span-name-unbounded":  {"limit": float64(10)},
+	}
+	if !reflect.DeepEqual(s.Options, want) {
+		t.Errorf("options %v, want %v", s.Options, want)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	_, defaults := build(t, nil)
+	if slices.Contains(defaults, "semconv_constant_available") || !slices.Contains(defaults, "project_rules") {
+		t.Errorf("defaults %v", defaults)
+	}
+	_, optIn := build(t, map[string]any{"enable": []any{"semconv-constant-available"}})
+	if len(optIn) != len(defaults)+1 || !slices.Contains(optIn, "semconv_constant_available") {
+		t.Errorf("enabling an opt-in rule selected %v", optIn)
+	}
+	_, only := build(t, map[string]any{"enable": []any{"span_name_unbounded", "span-name-convention"}})
+	if !reflect.DeepEqual(only, []string{"span_name_convention", "span_name_unbounded"}) {
+		t.Errorf("enabling default rules selected %v", only)
+	}
+	_, metrics := build(t, map[string]any{"signals": []any{"metrics"}})
+	if !slices.Contains(metrics, "counter_negative_increment") || !slices.Contains(metrics, "stdout_exporter") ||
+		slices.Contains(metrics, "span_name_unbounded") {
+		t.Errorf("metrics rules %v", metrics)
+	}
+}
+
+func TestInvalidSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		raw  map[string]any
+		err  string
+	}{
+		{"unknown enabled rule", map[string]any{"enable": []any{"no-such-rule"}}, `unknown rule "no-such-rule"`},
+		{"unknown disabled rule", map[string]any{"disable": []any{"no_such_rule"}}, `unknown rule "no_such_rule"`},
+		{"options of an unknown rule", map[string]any{"options": map[string]any{"no-such-rule": map[string]any{}}},
+			`options for unknown rule "no-such-rule"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			linter, err := New(tc.raw)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if _, err := linter.BuildAnalyzers(); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got %v, want %q", err, tc.err)
+			}
+		})
+	}
+	if _, err := New(map[string]any{"enable": "span-name-unbounded"}); err == nil {
+		t.Error("enable given as a string was accepted")
+	}
+}
@@ -0,0 +1,77 @@
+// Command example is a rule plugin enforcing a house convention no upstream rule knows about:
+// FIX protocol fields recorded on telemetry go under the fix.* namespace, in snake case.
+//
+//	go build -o bin/fix-rules ./plugin/example
+//
+//	# .ollygarden.yaml
+//	plugins:
+//	  - name: fix-protocol
+//	    command: [./bin/fix-rules]
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/analyzers/plugin"
+)
+
+// FIX field names and the attribute keys they are recorded under
+var fields = map[string]string{
+	"clordid":      "fix.cl_ord_id",
+	"origclordid":  "fix.orig_cl_ord_id",
+	"orderid":      "fix.order_id",
+	"execid":       "fix.exec_id",
+	"msgtype":      "fix.msg_type",
+	"msgseqnum":    "fix.msg_seq_num",
+	"sendercompid": "fix.sender_comp_id",
+	"targetcompid": "fix.target_comp_id",
+	"ordstatus":    "fix.ord_status",
+	"exectype":     "fix.exec_type",
+}
+
+var namespace = &analysis.Analyzer{
+	Name:     "fix_attribute_namespace",
+	Doc:      "FIX protocol fields are recorded under fix.*, in snake case",
+	Requires: []*analysis.Analyzer{plugin.Analyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		for _, f := range pass.ResultOf[plugin.Analyzer].(*plugin.Model).Files {
+			for _, a := range f.Attributes {
+				segments := strings.Split(a.Key, ".")
+				last := strings.ToLower(strings.ReplaceAll(segments[len(segments)-1], "_", ""))
+				want, ok := fields[last]
+				if !ok || a.Key == want || !a.Pos.IsValid() {
+					continue
+				}
+				d := analysis.Diagnostic{
+					Pos:     a.Pos,
+					End:     a.End,
+					Message: "FIX field " + strconv.Quote(a.Key) + " is recorded outside the fix namespace; use " + strconv.Quote(want),
+				}
+				if a.Signal != "log" {
+					d.SuggestedFixes = []analysis.SuggestedFix{{
+						Message:   "Rename the key to " + want,
+						TextEdits: []analysis.TextEdit{{Pos: a.Pos, End: a.End, NewText: []byte(strconv.Quote(want))}},
+					}}
+				}
+				pass.Report(d)
+			}
+		}
+		return nil, nil
+	},
+}
+
+func main() {
+	plugin.Main(plugin.Rule{
+		ID:       "fix-attribute-namespace",
+		Title:    "Record FIX protocol fields under fix.*",
+		Category: "conventions",
+		Severity: "low",
+		Description: "FIX fields (ClOrdID, MsgType, SenderCompID, ...) are recorded as fix.cl_ord_id, " +
+			"fix.msg_type, fix.sender_comp_id, so dashboards and queries find them under one namespace.",
+		Autofix:  true,
+		Analyzer: namespace,
+	})
+}
@@ -0,0 +1,332 @@
+// Package plugin builds rule plugins: executables that add rules to ollygarden for conventions
+// too involved for custom_rules expressions, or too specific to upstream. Each rule is a
+// go/analysis Analyzer, so it sees the syntax and types of the package (and its SSA when it
+// requires buildssa.Analyzer), plus the span model the Python rules parsed, through Analyzer:
+//
+//	var fixNamespace = &analysis.Analyzer{
+//		Name:     "fix_attribute_namespace",
+//		Doc:      "FIX protocol attributes live under fix.*",
+//		Requires: []*analysis.Analyzer{plugin.Analyzer},
+//		Run: func(pass *analysis.Pass) (any, error) {
+//			for _, f := range pass.ResultOf[plugin.Analyzer].(*plugin.Model).Files {
+//				for _, a := range f.Attributes {
+//					...
+//					pass.Reportf(a.Pos, "...")
+//				}
+//			}
+//			return nil, nil
+//		},
+//	}
+//
+//	func main() {
+//		plugin.Main(plugin.Rule{ID: "fix-attribute-namespace", Title: "...", Analyzer: fixNamespace})
+//	}
+//
+// Build the program and list it under plugins in .ollygarden.yaml; the engine runs it with
+// describe when the config is loaded and with check, once per run, for the analyzed files
+// (see rules/plugins.py for the protocol). Findings are reported and suppressed like those of
+// the built-in rules.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// Protocol is the version of the describe/check exchange the plugin speaks.
+const Protocol = 1
+
+// Rule is one rule of a plugin: its metadata, as list-rules and the reports show it, and the
+// analyzer finding its violations.
+type Rule struct {
+	// ID is the rule ID used in .ollygarden.yaml and otel:ignore directives, e.g. fix-tag-names.
+	ID    string
+	Title string
+	// Category defaults to conventions, Signal to traces and Severity to medium.
+	Category    string
+	Signal      string
+	Severity    string
+	Description string
+	// Autofix tells whether diagnostics carry SuggestedFixes.
+	Autofix  bool
+	Analyzer *analysis.Analyzer
+}
+
+// Model is the span model of a package, as parsed by the rules; Analyzer computes it.
+type Model struct {
+	Files []*File
+}
+
+// File is the model of one analyzed file.
+type File struct {
+	Path        string `json:"path"`
+	Package     string `json:"package"`
+	PackagePath string `json:"package_path"`
+	// Syntax is the file in the pass.
+	Syntax     *ast.File   `json:"-"`
+	Spans      []Span      `json:"spans"`
+	Attributes []Attribute `json:"attributes"`
+	Calls      []Call      `json:"calls"`
+}
+
+// Span is a span start: tracer.Start or a configured span helper.
+type Span struct {
+	Pos, End  token.Pos `json:"-"`
+	Offset    int       `json:"offset"`
+	EndOffset int       `json:"end"`
+	Function  string    `json:"function"`
+	// Name is the literal name, or the expression building it when Dynamic.
+	Name    string `json:"name"`
+	Dynamic bool   `json:"dynamic"`
+	// Kind is server, client, producer, consumer or internal.
+	Kind string `json:"kind"`
+	// Attributes maps the keys set at the start and by SetAttributes to their literal values
+	// ("" when not literal).
+	Attributes   map[string]string `json:"attributes"`
+	Events       []string          `json:"events"`
+	RecordsError bool              `json:"records_error"`
+	SetsStatus   bool              `json:"sets_status"`
+	Ended        bool              `json:"ended"`
+}
+
+// Attribute is a literal attribute key on a span, metric or log record.
+type Attribute struct {
+	Pos, End  token.Pos `json:"-"`
+	Offset    int       `json:"offset"`
+	EndOffset int       `json:"end"`
+	Function  string    `json:"function"`
+	Key       string    `json:"key"`
+	// Value is the literal value, or the expression when not Literal.
+	Value   string `json:"value"`
+	Literal bool   `json:"literal"`
+	// Type is the constructor (String, Int, Bool, ...), "" for log fields.
+	Type string `json:"type"`
+	// Signal is span, metric or log; SpanKind the kind of the span open around it, "" for none.
+	Signal   string `json:"signal"`
+	SpanKind string `json:"span_kind"`
+}
+
+// Call is a function or method call.
+type Call struct {
+	Pos, End  token.Pos `json:"-"`
+	Offset    int       `json:"offset"`
+	EndOffset int       `json:"end"`
+	Function  string    `json:"function"`
+	// Callee is the callee as written (s.db.QueryContext), Name its last element.
+	Callee string `json:"callee"`
+	Name   string `json:"name"`
+	// Import is the import path when called through an import, "" otherwise.
+	Import string   `json:"import"`
+	Args   []string `json:"args"`
+	// InSpan tells whether a span started in the enclosing function is open around the call.
+	InSpan   bool   `json:"in_span"`
+	SpanKind string `json:"span_kind"`
+}
+
+// Analyzer provides the *Model of the package to the analyzers that require it.
+var Analyzer = &analysis.Analyzer{
+	Name:       "ollygarden_model",
+	Doc:        "span model of the package, as parsed by the ollygarden rules",
+	Run:        model,
+	ResultType: reflect.TypeOf((*Model)(nil)),
+}
+
+type request struct {
+	Protocol int      `json:"protocol"`
+	Root     string   `json:"root"`
+	Rules    []string `json:"rules"`
+	Files    []*File  `json:"files"`
+}
+
+type edit struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	NewText string `json:"new_text"`
+}
+
+type fix struct {
+	Description string `json:"description"`
+	Edits       []edit `json:"edits"`
+}
+
+type diagnostic struct {
+	RuleID  string `json:"rule_id"`
+	File    string `json:"file"`
+	Offset  int    `json:"offset"`
+	End     int    `json:"end"`
+	Message string `json:"message"`
+	Fixes   []fix  `json:"fixes"`
+}
+
+// files of the running check, by path
+var files = map[string]*File{}
+
+func model(pass *analysis.Pass) (any, error) {
+	m := &Model{}
+	for _, syntax := range pass.Files {
+		tf := pass.Fset.File(syntax.Pos())
+		f := files[tf.Name()]
+		if f == nil {
+			continue
+		}
+		at := func(offset int) token.Pos {
+			if offset < 0 || offset > tf.Size() {
+				return token.NoPos
+			}
+			return tf.Pos(offset)
+		}
+		copied := *f
+		copied.Syntax = syntax
+		copied.Spans = append([]Span(nil), f.Spans...)
+		for i := range copied.Spans {
+			copied.Spans[i].Pos, copied.Spans[i].End = at(f.Spans[i].Offset), at(f.Spans[i].EndOffset)
+		}
+		copied.Attributes = append([]Attribute(nil), f.Attributes...)
+		for i := range copied.Attributes {
+			copied.Attributes[i].Pos, copied.Attributes[i].End = at(f.Attributes[i].Offset), at(f.Attributes[i].EndOffset)
+		}
+		copied.Calls = append([]Call(nil), f.Calls...)
+		for i := range copied.Calls {
+			copied.Calls[i].Pos, copied.Calls[i].End = at(f.Calls[i].Offset), at(f.Calls[i].EndOffset)
+		}
+		m.Files = append(m.Files, &copied)
+	}
+	return m, nil
+}
+
+// Main runs the plugin: `describe` prints its rules, `check` reads a request from stdin, runs
+// the analyzers over its files and prints their findings. It doesn't return.
+func Main(rules ...Rule) {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s describe|check\n", filepath.Base(os.Args[0]))
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "describe":
+		err = describe(os.Stdout, rules)
+	case "check":
+		err = check(os.Stdin, os.Stdout, rules)
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func describe(w io.Writer, rules []Rule) error {
+	type described struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		Category    string `json:"category,omitempty"`
+		Signal      string `json:"signal,omitempty"`
+		Severity    string `json:"severity,omitempty"`
+		Description string `json:"description,omitempty"`
+		Autofix     bool   `json:"autofix,omitempty"`
+	}
+	list := []described{}
+	for _, r := range rules {
+		list = append(list, described{r.ID, r.Title, r.Category, r.Signal, r.Severity, r.Description, r.Autofix})
+	}
+	return json.NewEncoder(w).Encode(map[string]any{"protocol": Protocol, "rules": list})
+}
+
+func check(r io.Reader, w io.Writer, rules []Rule) error {
+	var req request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("reading the request: %v", err)
+	}
+	if req.Protocol != Protocol {
+		return fmt.Errorf("request of protocol %d, the plugin speaks %d", req.Protocol, Protocol)
+	}
+	var patterns []string
+	for _, f := range req.Files {
+		files[f.Path] = f
+		patterns = append(patterns, "file="+f.Path)
+	}
+	diagnostics := []diagnostic{}
+	if len(patterns) > 0 {
+		var err error
+		if diagnostics, err = analyze(req.Root, patterns, rules); err != nil {
+			return err
+		}
+	}
+	return json.NewEncoder(w).Encode(map[string]any{"diagnostics": diagnostics})
+}
+
+func analyze(root string, patterns []string, rules []Rule) ([]diagnostic, error) {
+	cfg := &packages.Config{Mode: packages.LoadAllSyntax, Dir: root}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %v", err)
+	}
+	if n := packages.PrintErrors(pkgs); n > 0 {
+		return nil, fmt.Errorf("%d errors loading packages", n)
+	}
+	byAnalyzer := map[*analysis.Analyzer]string{}
+	var analyzers []*analysis.Analyzer
+	for _, r := range rules {
+		byAnalyzer[r.Analyzer] = r.ID
+		analyzers = append(analyzers, r.Analyzer)
+	}
+	graph, err := checker.Analyze(analyzers, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics := []diagnostic{}
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, fmt.Errorf("%s: %v", act, act.Err)
+		}
+		fset := act.Package.Fset
+		for _, d := range act.Diagnostics {
+			pos := fset.Position(d.Pos)
+			if files[pos.Filename] == nil {
+				continue
+			}
+			end := pos.Offset
+			if d.End.IsValid() {
+				end = fset.Position(d.End).Offset
+			}
+			found := diagnostic{RuleID: byAnalyzer[act.Analyzer], File: pos.Filename, Offset: pos.Offset, End: end,
+				Message: d.Message, Fixes: []fix{}}
+			for _, sf := range d.SuggestedFixes {
+				f := fix{Description: sf.Message}
+				for _, e := range sf.TextEdits {
+					start := fset.Position(e.Pos)
+					if start.Filename != pos.Filename {
+						continue
+					}
+					stop := start.Offset
+					if e.End.IsValid() {
+						stop = fset.Position(e.End).Offset
+					}
+					f.Edits = append(f.Edits, edit{start.Offset, stop, string(e.NewText)})
+				}
+				if len(f.Edits) > 0 {
+					found.Fixes = append(found.Fixes, f)
+				}
+			}
+			diagnostics = append(diagnostics, found)
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		return a.File < b.File || a.File == b.File && a.Offset < b.Offset
+	})
+	return diagnostics, nil
+}
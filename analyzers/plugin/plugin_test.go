@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The analyzed project: a module whose OpenTelemetry dependency is a local stub, so it loads
+// without the network
+var project = map[string]string{
+	"go.mod":               "module example.com/trading\n\ngo 1.22\n\nrequire go.opentelemetry.io/otel v1.0.0\n\nreplace go.opentelemetry.io/otel => ./otel\n",
+	"otel/go.mod":          "module go.opentelemetry.io/otel\n\ngo 1.22\n",
+	"otel/attribute/kv.go": "package attribute\n\ntype KeyValue struct{}\n\nfunc String(key, value string) KeyValue { return KeyValue{} }\n",
+	".ollygarden.yaml":     "plugins:\n  - name: fix-protocol\n    command: [PLUGIN]\n",
+	"orders.go": `package trading
+
+import "go.opentelemetry.io/otel/attribute"
+
+func orderAttributes(id, kind string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("ClOrdID", id),
+		attribute.String("fix.msg_type", kind),
+	}
+}
+`,
+}
+
+// TestRoundTrip runs the example plugin through the Python rules, as .ollygarden.yaml lists it:
+// describe when the config loads, check with the span model of the analyzed files, and its
+// findings and autofix mapped back onto the source.
+func TestRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found")
+	}
+	home, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "fix-rules")
+	if out, err := exec.Command("go", "build", "-o", bin, "./example").CombinedOutput(); err != nil {
+		t.Fatalf("building the example plugin: %v\n%s", err, out)
+	}
+	root := filepath.Join(dir, "trading")
+	for name, content := range project {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.ReplaceAll(content, "PLUGIN", bin)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := filepath.Join(root, "orders.go")
+	cmd := exec.Command("python3", "-m", "rules.analysis", "--rule", "fix-attribute-namespace", source)
+	cmd.Dir = home
+	cmd.Env = append(os.Environ(), "PYTHONDONTWRITEBYTECODE=1", "GOFLAGS=-mod=mod", "GOPROXY=off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the rules: %v\n%s", err, stderr.String())
+	}
+	var report struct {
+		Diagnostics []struct {
+			Analyzer string `json:"analyzer"`
+			RuleID   string `json:"rule_id"`
+			File     string `json:"file"`
+			Offset   int    `json:"offset"`
+			End      int    `json:"end"`
+			Message  string `json:"message"`
+			Fixes    []fix  `json:"fixes"`
+		} `json:"diagnostics"`
+		Incomplete map[string]string `json:"incomplete"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("reading %s: %v", out, err)
+	}
+	if len(report.Incomplete) > 0 {
+		t.Fatalf("plugin failed: %v", report.Incomplete)
+	}
+	if len(report.Diagnostics) != 1 {
+		t.Fatalf("got %d diagnostics, want 1 for ClOrdID: %+v", len(report.Diagnostics), report.Diagnostics)
+	}
+	d := report.Diagnostics[0]
+	code := []byte(project["orders.go"])
+	key := bytes.Index(code, []byte(`"ClOrdID"`))
+	if d.RuleID != "fix-attribute-namespace" || d.Analyzer != "fix_attribute_namespace" || d.File != source {
+		t.Errorf("diagnostic of %s (%s) in %s", d.RuleID, d.Analyzer, d.File)
+	}
+	if d.Offset != key || d.End != key+len(`"ClOrdID"`) {
+		t.Errorf("diagnostic at %d-%d, want %d-%d", d.Offset, d.End, key, key+len(`"ClOrdID"`))
+	}
+	if !strings.Contains(d.Message, `use "fix.cl_ord_id"`) {
+		t.Errorf("message %q", d.Message)
+	}
+	if len(d.Fixes) != 1 || len(d.Fixes[0].Edits) != 1 {
+		t.Fatalf("fixes %+v, want one edit", d.Fixes)
+	}
+	e := d.Fixes[0].Edits[0]
+	fixed := string(code[:e.Start]) + e.NewText + string(code[e.End:])
+	if !strings.Contains(fixed, `attribute.String("fix.cl_ord_id", id)`) {
+		t.Errorf("fix makes\n%s", fixed)
+	}
+}
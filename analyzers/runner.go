@@ -0,0 +1,239 @@
+package analyzers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// diagnostic is one finding as printed by `python3 -m rules.analysis`, positioned by byte offset.
+type diagnostic struct {
+	Analyzer   string `json:"analyzer"`
+	RuleID     string `json:"rule_id"`
+	File       string `json:"file"`
+	Offset     int    `json:"offset"`
+	End        int    `json:"end"`
+	Severity   string `json:"severity"`
+	Category   string `json:"category"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+	Fixes      []struct {
+		Description string `json:"description"`
+		Edits       []struct {
+			Start   int    `json:"start"`
+			End     int    `json:"end"`
+			NewText string `json:"new_text"`
+		} `json:"edits"`
+	} `json:"fixes"`
+}
+
+// result holds the findings of one package, shared by the analyzers of one analysis of it.
+type result struct {
+	pkg         *types.Package
+	once        sync.Once
+	diagnostics []diagnostic
+	err         error
+}
+
+// Settings configure how the analyzers run the rules. Empty fields fall back to the
+// OLLYGARDEN_* environment variables.
+type Settings struct {
+	// Python is the interpreter running the rules (OLLYGARDEN_PYTHON, default python3).
+	Python string
+	// Home is the checkout holding the rules package (OLLYGARDEN_HOME). One of the two is
+	// required: a binary built by go install, with -trimpath or from the module cache doesn't
+	// know where the checkout is.
+	Home string
+	// Profile selects a .ollygarden.yaml profile (OLLYGARDEN_PROFILE).
+	Profile string
+	// Signals limits the rules to those checking one of these signals (OLLYGARDEN_SIGNALS).
+	Signals []string
+	// Options maps rule IDs to option values, merged over the project config's.
+	Options map[string]map[string]any
+}
+
+var (
+	mu sync.Mutex
+	// results maps the file names of a package to the findings of its latest analysis. A
+	// driver type-checks the package anew whenever it analyzes it (gopls on every edit), so a
+	// result for another *types.Package is stale.
+	results  = map[string]*result{}
+	settings Settings
+)
+
+// Configure sets how every analyzer runs the rules. Drivers call it once, before the first
+// package is analyzed.
+func Configure(s Settings) {
+	mu.Lock()
+	defer mu.Unlock()
+	settings = s
+	results = map[string]*result{}
+}
+
+// report runs the rules over the package (once per analysis of it, whichever analyzer gets
+// there first) and reports the findings of rule r.
+func report(pass *analysis.Pass, r ruleInfo) error {
+	files := map[string]*token.File{}
+	var names []string
+	for _, f := range pass.Files {
+		tf := pass.Fset.File(f.Pos())
+		if tf == nil || !strings.HasSuffix(tf.Name(), ".go") {
+			continue
+		}
+		files[tf.Name()] = tf
+		names = append(names, tf.Name())
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	key := strings.Join(names, "\x00")
+	mu.Lock()
+	res, ok := results[key]
+	if !ok || res.pkg != pass.Pkg {
+		res = &result{pkg: pass.Pkg}
+		results[key] = res
+	}
+	mu.Unlock()
+	res.once.Do(func() {
+		contents, err := readFiles(pass, names)
+		if err != nil {
+			res.err = err
+			return
+		}
+		res.diagnostics, res.err = runRules(names, contents)
+	})
+	if res.err != nil {
+		return res.err
+	}
+
+	for _, d := range res.diagnostics {
+		tf := files[d.File]
+		if !reports(r, d) || tf == nil || d.Offset > tf.Size() {
+			continue
+		}
+		diag := analysis.Diagnostic{
+			Pos:      tf.Pos(d.Offset),
+			End:      tf.Pos(min(max(d.End, d.Offset), tf.Size())),
+			Category: d.Category,
+			Message:  d.Message,
+		}
+		if d.Suggestion != "" {
+			diag.Message = fmt.Sprintf("%s (%s)", d.Message, d.Suggestion)
+		}
+		if r.Name == projectRules.Name {
+			diag.Message = d.RuleID + ": " + diag.Message
+		}
+		for _, fix := range d.Fixes {
+			sf := analysis.SuggestedFix{Message: fix.Description}
+			for _, e := range fix.Edits {
+				if e.End > tf.Size() {
+					continue
+				}
+				sf.TextEdits = append(sf.TextEdits, analysis.TextEdit{
+					Pos: tf.Pos(e.Start), End: tf.Pos(e.End), NewText: []byte(e.NewText),
+				})
+			}
+			diag.SuggestedFixes = append(diag.SuggestedFixes, sf)
+		}
+		pass.Report(diag)
+	}
+	return nil
+}
+
+// reports says whether the analyzer of rule r reports d: its own findings, and for projectRules
+// those of the rules no other analyzer has.
+func reports(r ruleInfo, d diagnostic) bool {
+	if r.Name == projectRules.Name {
+		_, known := byName[d.Analyzer]
+		return !known
+	}
+	return d.Analyzer == r.Name
+}
+
+// readFiles returns the contents of the files as the driver sees them, which for gopls
+// includes unsaved edits.
+func readFiles(pass *analysis.Pass, names []string) (map[string]string, error) {
+	read := pass.ReadFile
+	if read == nil {
+		read = os.ReadFile
+	}
+	contents := make(map[string]string, len(names))
+	for _, name := range names {
+		b, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		contents[name] = string(b)
+	}
+	return contents, nil
+}
+
+// runRules runs every rule the project config doesn't disable over the files of one package,
+// given by name and contents, as the current Settings say.
+func runRules(files []string, contents map[string]string) ([]diagnostic, error) {
+	mu.Lock()
+	s := settings
+	mu.Unlock()
+	python := firstNonEmpty(s.Python, os.Getenv("OLLYGARDEN_PYTHON"), "python3")
+	home := firstNonEmpty(s.Home, os.Getenv("OLLYGARDEN_HOME"))
+	if home == "" {
+		return nil, errors.New("no rules: set OLLYGARDEN_HOME to the checkout holding the rules package")
+	}
+
+	args := []string{"-m", "rules.analysis", "--all"}
+	if s.Profile != "" {
+		args = append(args, "--profile", s.Profile)
+	}
+	if len(s.Signals) > 0 {
+		args = append(args, "--signals", strings.Join(s.Signals, ","))
+	}
+	if len(s.Options) > 0 {
+		options, err := json.Marshal(s.Options)
+		if err != nil {
+			return nil, fmt.Errorf("encoding rule options: %v", err)
+		}
+		args = append(args, "--options", string(options))
+	}
+	input, err := json.Marshal(contents)
+	if err != nil {
+		return nil, fmt.Errorf("encoding files: %v", err)
+	}
+	args = append(args, "--stdin")
+	cmd := exec.Command(python, append(args, files...)...)
+	cmd.Dir = home
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running rules in %s: %v: %s", home, err, strings.TrimSpace(stderr.String()))
+	}
+	var out struct {
+		Diagnostics []diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("reading rule output: %v", err)
+	}
+	return out.Diagnostics, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
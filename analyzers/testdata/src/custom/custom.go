@@ -0,0 +1,18 @@
+package custom
+
+import (
+	"context"
+	"net/http"
+)
+
+func fetchProfile(ctx context.Context, url string) (*http.Response, error) {
+	return http.Get(url) // want `no-direct-http-get: http.Get bypasses the instrumented client`
+}
+
+func fetchAvatar(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,8 @@
+// Package attribute is a stub of the OpenTelemetry attribute API for the analyzer tests.
+package attribute
+
+type KeyValue struct{}
+
+func String(key, value string) KeyValue { return KeyValue{} }
+
+func Int(key string, value int) KeyValue { return KeyValue{} }
@@ -0,0 +1,22 @@
+// Package metric is a stub of the OpenTelemetry metric API for the analyzer tests.
+package metric
+
+type Meter interface {
+	Int64Counter(name string, opts ...Int64CounterOption) (Int64Counter, error)
+	Float64Histogram(name string, opts ...Float64HistogramOption) (Float64Histogram, error)
+}
+
+type Int64Counter interface{}
+
+type Float64Histogram interface{}
+
+type Int64CounterOption interface{}
+
+type Float64HistogramOption interface{}
+
+type InstrumentOption interface {
+	Int64CounterOption
+	Float64HistogramOption
+}
+
+func WithUnit(unit string) InstrumentOption { return nil }
@@ -0,0 +1,15 @@
+// Package otel is a stub of the OpenTelemetry API for the analyzer tests.
+package otel
+
+import (
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Tracer(name string, opts ...trace.TracerOption) trace.Tracer { return nil }
+
+func GetTracerProvider() trace.TracerProvider { return nil }
+
+func SetTracerProvider(tp trace.TracerProvider) {}
+
+func Meter(name string) metric.Meter { return nil }
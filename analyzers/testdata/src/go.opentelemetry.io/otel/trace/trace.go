@@ -0,0 +1,34 @@
+// Package trace is a stub of the OpenTelemetry trace API for the analyzer tests.
+package trace
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type TracerProvider interface {
+	Tracer(name string, opts ...TracerOption) Tracer
+}
+
+type Tracer interface {
+	Start(ctx context.Context, name string, opts ...SpanStartOption) (context.Context, Span)
+}
+
+type Span interface {
+	End(opts ...SpanEndOption)
+	AddEvent(name string, opts ...EventOption)
+	SetAttributes(kv ...attribute.KeyValue)
+}
+
+type TracerOption interface{}
+
+type SpanStartOption interface{}
+
+type SpanEndOption interface{}
+
+type EventOption interface{}
+
+func WithInstrumentationVersion(version string) TracerOption { return nil }
+
+func WithSchemaURL(url string) TracerOption { return nil }
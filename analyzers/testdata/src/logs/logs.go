@@ -0,0 +1,31 @@
+package logs
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("logs")
+
+type Subscriber struct {
+	ID           string
+	EmailAddress string
+}
+
+func notifySubscriber(ctx context.Context, s Subscriber) {
+	ctx, span := tracer.Start(ctx, "notify subscriber")
+	defer span.End()
+	span.SetAttributes(attribute.String("subscriber.email_hash", hashEmail(s.EmailAddress)))
+	slog.InfoContext(ctx, "sending notification", "email", s.EmailAddress) // want `Log field "email" records s.EmailAddress, personal data`
+}
+
+func remindSubscriber(ctx context.Context, s Subscriber) {
+	ctx, span := tracer.Start(ctx, "remind subscriber")
+	defer span.End()
+	slog.InfoContext(ctx, "sending reminder", "subscriber.id", s.ID, "email_hash", hashEmail(s.EmailAddress))
+}
+
+func hashEmail(email string) string { return email }
@@ -0,0 +1,8 @@
+package metrics
+
+import "go.opentelemetry.io/otel/metric"
+
+func newTransferInstruments(meter metric.Meter) {
+	meter.Float64Histogram("transfer.duration", metric.WithUnit("seconds")) // want `Unit 'seconds' of instrument transfer.duration isn't UCUM`
+	meter.Int64Counter("transfer.requests", metric.WithUnit("{request}"))
+}
@@ -0,0 +1,21 @@
+package privacy
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("privacy")
+
+func handleSignup(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /signup")
+	defer span.End()
+	email := r.FormValue("email")
+	span.SetAttributes(attribute.String("signup.contact", email)) // want `Attribute "signup.contact" records the request's "email" form field`
+	createAccount(ctx, email)
+}
+
+func createAccount(ctx context.Context, email string) {}
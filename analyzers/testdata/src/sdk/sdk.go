@@ -0,0 +1,15 @@
+// Package sdk is library code: it must leave the global providers to the application.
+package sdk
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Instrument(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp) // want `Library package sdk calls otel.SetTracerProvider`
+}
+
+func Tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer("sdk")
+}
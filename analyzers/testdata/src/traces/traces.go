@@ -0,0 +1,25 @@
+package traces
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("traces")
+
+func handleDownload(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "GET "+r.URL.Path) // want `Span name "GET "\+r.URL.Path is built from r.URL.Path`
+	defer span.End()
+	serveFile(ctx, w, r.URL.Path)
+}
+
+func runJob(ctx context.Context, kind, id string) {
+	ctx, span := tracer.Start(ctx, "run "+kind+" job")
+	defer span.End()
+	span.SetAttributes(attribute.String("job.id", id))
+}
+
+func serveFile(ctx context.Context, w http.ResponseWriter, path string) {}
@@ -0,0 +1,86 @@
+package ollylintprocessor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// What to do with a batch the rules can't check, see Config.OnError
+const (
+	// OnErrorPass passes the batch on unchecked.
+	OnErrorPass = "pass"
+	// OnErrorDrop drops the batch.
+	OnErrorDrop = "drop"
+	// OnErrorError returns the error to the receiver, which may have its client retry.
+	OnErrorError = "error"
+)
+
+// Config is the processor's section of the Collector configuration.
+type Config struct {
+	// Python is the interpreter running the rules (default python3, or OLLYGARDEN_PYTHON).
+	Python string `mapstructure:"python"`
+	// Home is the checkout holding the rules package (default OLLYGARDEN_HOME); one of the two
+	// is required. A Collector built with the builder compiles this package from the module
+	// cache, which doesn't have it.
+	Home string `mapstructure:"home"`
+	// ConfigDir is where the .ollygarden.yaml selecting and configuring the rules is looked up.
+	ConfigDir string `mapstructure:"config_dir"`
+	// Profile selects a .ollygarden.yaml profile (OLLYGARDEN_PROFILE).
+	Profile string `mapstructure:"profile"`
+
+	// Annotate records the IDs of the rules a span breaks in its otel.lint.violations attribute.
+	Annotate bool `mapstructure:"annotate"`
+	// Metrics counts spans and findings per service, rule and severity in the Collector's own
+	// telemetry.
+	Metrics bool `mapstructure:"metrics"`
+	// Redact replaces the values of span and event attributes holding secrets or personal data.
+	Redact bool `mapstructure:"redact"`
+	// RedactedValue is what redacted values are replaced with.
+	RedactedValue string `mapstructure:"redacted_value"`
+
+	// Timeout bounds the checking of one batch; batches that take longer are handled as OnError
+	// says.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// OnError is what happens to a batch the rules can't check in time, or at all: pass, drop or
+	// error. It defaults to drop when Redact is set, so personal data never leaves unredacted,
+	// and to pass otherwise.
+	OnError string `mapstructure:"on_error"`
+}
+
+func createDefaultConfig() *Config {
+	return &Config{
+		Annotate:      true,
+		Metrics:       true,
+		RedactedValue: "[REDACTED]",
+		Timeout:       5 * time.Second,
+	}
+}
+
+// Validate checks the configuration when the Collector loads it.
+func (c *Config) Validate() error {
+	if !c.Annotate && !c.Metrics && !c.Redact {
+		return errors.New("nothing to do: enable at least one of annotate, metrics and redact")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	switch c.OnError {
+	case "", OnErrorPass, OnErrorDrop, OnErrorError:
+	default:
+		return fmt.Errorf("on_error must be %s, %s or %s, not %q", OnErrorPass, OnErrorDrop, OnErrorError, c.OnError)
+	}
+	return nil
+}
+
+// onError is OnError with its default applied.
+func (c *Config) onError() string {
+	switch {
+	case c.OnError != "":
+		return c.OnError
+	case c.Redact:
+		return OnErrorDrop
+	default:
+		return OnErrorPass
+	}
+}
@@ -0,0 +1,56 @@
+// Package ollylintprocessor is an OpenTelemetry Collector processor that holds spans to the
+// ollygarden rules in flight, so conventions are enforced in the pipeline as well as in the
+// source: for code the static rules don't see (auto-instrumentation, other languages, third
+// party services) and as a last line of defense for personal data.
+//
+// The checks are the exported-telemetry checks of `otel_cli.py analyze-traces`, run by one
+// long-lived `python3 -m rules.processor` per processor (see internal/pyworker). Each span can get an
+// otel.lint.violations attribute listing the rules it breaks, findings can be counted in the
+// Collector's own metrics, and attributes holding secrets or personal data can be redacted
+// before they reach an exporter:
+//
+//	processors:
+//	  ollylint:
+//	    home: /opt/ollygarden-opentelemetry
+//	    config_dir: /etc/ollygarden      # .ollygarden.yaml selecting the rules
+//	    annotate: true
+//	    metrics: true
+//	    redact: true
+//	    on_error: drop                   # the default with redact: true
+//
+// A batch the rules can't check in time, or at all, is dropped when attributes are redacted,
+// so nothing passes unredacted, and passes through unchanged otherwise; on_error overrides
+// either.
+package ollylintprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+var componentType = component.MustNewType("ollylint")
+
+// NewFactory returns the factory to register in a Collector's processors.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		componentType,
+		func() component.Config { return createDefaultConfig() },
+		processor.WithTraces(createTraces, component.StabilityLevelAlpha),
+	)
+}
+
+func createTraces(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Traces) (processor.Traces, error) {
+	p, err := newLintProcessor(cfg.(*Config), set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+	return processorhelper.NewTraces(ctx, set, cfg, next, p.processTraces,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(p.shutdown),
+	)
+}
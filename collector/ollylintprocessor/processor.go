@@ -0,0 +1,191 @@
+package ollylintprocessor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	// ViolationsAttribute lists the IDs of the rules a span breaks.
+	ViolationsAttribute = "otel.lint.violations"
+	scopeName           = "github.com/aditya-prakash-git/ollygarden-opentelemetry/collector/ollylintprocessor"
+)
+
+// Rules whose findings are about attribute values that must not be exported
+var redactedRules = map[string]bool{
+	"secret-in-telemetry":          true,
+	"classified-data-in-telemetry": true,
+	"pii-in-telemetry":             true,
+}
+
+type lintProcessor struct {
+	cfg    *Config
+	logger *zap.Logger
+
+	// Started with the processor; concurrent batches are queued for its one worker
+	rules *pyworker.Checker
+
+	spans    metric.Int64Counter
+	findings metric.Int64Counter
+}
+
+func newLintProcessor(cfg *Config, set component.TelemetrySettings) (*lintProcessor, error) {
+	p := &lintProcessor{cfg: cfg, logger: set.Logger}
+	if !cfg.Metrics {
+		return p, nil
+	}
+	// Named like the metrics of `otel_cli.py serve`, so dashboards work with either
+	meter := set.MeterProvider.Meter(scopeName)
+	var err error
+	p.spans, err = meter.Int64Counter("ollygarden.spans",
+		metric.WithDescription("Spans checked"), metric.WithUnit("{span}"))
+	if err != nil {
+		return nil, err
+	}
+	p.findings, err = meter.Int64Counter("ollygarden.span.findings",
+		metric.WithDescription("Spans with a finding, per rule"), metric.WithUnit("{span}"))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *lintProcessor) start(context.Context, component.Host) error {
+	rules, err := pyworker.Start(pyworker.Options{
+		Python:    p.cfg.Python,
+		Home:      p.cfg.Home,
+		ConfigDir: p.cfg.ConfigDir,
+		Profile:   p.cfg.Profile,
+		Timeout:   p.cfg.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	p.rules = rules
+	return nil
+}
+
+func (p *lintProcessor) shutdown(ctx context.Context) error {
+	if p.rules == nil {
+		return nil
+	}
+	return p.rules.Close(ctx)
+}
+
+func (p *lintProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	var batch []pyworker.Span
+	var spans []ptrace.Span
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		service := ""
+		if v, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			service = v.AsString()
+		}
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			for k := 0; k < ss.Spans().Len(); k++ {
+				s := ss.Spans().At(k)
+				batch = append(batch, toSpan(s, service, ss.Scope().Name()))
+				spans = append(spans, s)
+			}
+		}
+	}
+	if len(batch) == 0 {
+		return td, nil
+	}
+
+	found, err := p.rules.Check(batch)
+	if err != nil {
+		switch p.cfg.onError() {
+		case OnErrorDrop:
+			p.logger.Warn("Dropping spans the rules couldn't check", zap.Int("spans", len(batch)), zap.Error(err))
+			return td, processorhelper.ErrSkipProcessingData
+		case OnErrorError:
+			return td, fmt.Errorf("checking %d spans: %w", len(batch), err)
+		}
+		p.logger.Warn("Passing spans through unchecked", zap.Int("spans", len(batch)), zap.Error(err))
+		return td, nil
+	}
+	for i, findings := range found {
+		if p.cfg.Metrics {
+			p.record(ctx, batch[i].Service, findings)
+		}
+		if p.cfg.Redact {
+			p.redact(spans[i], findings)
+		}
+		if p.cfg.Annotate && len(findings) > 0 {
+			annotate(spans[i], findings)
+		}
+	}
+	return td, nil
+}
+
+func toSpan(s ptrace.Span, service, scope string) pyworker.Span {
+	out := pyworker.Span{
+		Name:       s.Name(),
+		Kind:       strings.ToLower(s.Kind().String()),
+		Service:    service,
+		Scope:      scope,
+		Attributes: s.Attributes().AsRaw(),
+	}
+	for i := 0; i < s.Events().Len(); i++ {
+		e := s.Events().At(i)
+		out.Events = append(out.Events, pyworker.Event{Name: e.Name(), Attributes: e.Attributes().AsRaw()})
+	}
+	return out
+}
+
+// record counts the span, and each rule it breaks once.
+func (p *lintProcessor) record(ctx context.Context, service string, findings []pyworker.Finding) {
+	p.spans.Add(ctx, 1, metric.WithAttributes(attribute.String("service", service)))
+	seen := map[string]bool{}
+	for _, f := range findings {
+		if seen[f.RuleID] {
+			continue
+		}
+		seen[f.RuleID] = true
+		p.findings.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("service", service),
+			attribute.String("rule_id", f.RuleID),
+			attribute.String("severity", f.Severity),
+		))
+	}
+}
+
+func (p *lintProcessor) redact(s ptrace.Span, findings []pyworker.Finding) {
+	for _, f := range findings {
+		if !redactedRules[f.RuleID] || f.Attribute == "" {
+			continue
+		}
+		attrs := s.Attributes()
+		if f.Event >= 0 {
+			if f.Event >= s.Events().Len() {
+				continue
+			}
+			attrs = s.Events().At(f.Event).Attributes()
+		}
+		if _, ok := attrs.Get(f.Attribute); ok {
+			attrs.PutStr(f.Attribute, p.cfg.RedactedValue)
+		}
+	}
+}
+
+func annotate(s ptrace.Span, findings []pyworker.Finding) {
+	violations := s.Attributes().PutEmptySlice(ViolationsAttribute)
+	seen := map[string]bool{}
+	for _, f := range findings {
+		if !seen[f.RuleID] {
+			seen[f.RuleID] = true
+			violations.AppendEmpty().SetStr(f.RuleID)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package ollylintprocessor
+
+import (
+	"context"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker"
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker/pyworkertest"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+// rules flags span names holding a path and user.email attributes, and crashes on "crash".
+func rules(s pyworker.Span) []pyworker.Finding {
+	if s.Name == "crash" {
+		os.Exit(3)
+	}
+	var found []pyworker.Finding
+	if strings.Contains(s.Name, "/") {
+		found = append(found, pyworker.Finding{RuleID: "span-name-unbounded", Severity: "medium", Event: -1})
+	}
+	if _, ok := s.Attributes["user.email"]; ok {
+		found = append(found, pyworker.Finding{RuleID: "pii-in-telemetry", Severity: "high", Attribute: "user.email", Event: -1})
+	}
+	for i, e := range s.Events {
+		if _, ok := e.Attributes["user.email"]; ok {
+			found = append(found, pyworker.Finding{RuleID: "pii-in-telemetry", Severity: "high", Attribute: "user.email", Event: i})
+		}
+	}
+	return found
+}
+
+func TestMain(m *testing.M) {
+	pyworkertest.Main(rules)
+	os.Exit(m.Run())
+}
+
+func newProcessor(t *testing.T, configure func(*Config)) (processor.Traces, *consumertest.TracesSink) {
+	t.Helper()
+	fake := pyworkertest.Options(t)
+	cfg := createDefaultConfig()
+	cfg.Python, cfg.Home = fake.Python, fake.Home
+	if configure != nil {
+		configure(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	sink := new(consumertest.TracesSink)
+	p, err := NewFactory().CreateTraces(context.Background(), processortest.NewNopSettings(componentType), cfg, sink)
+	if err != nil {
+		t.Fatalf("CreateTraces: %v", err)
+	}
+	if err := p.Start(context.Background(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := p.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+	return p, sink
+}
+
+func traces(names ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "shop")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, name := range names {
+		s := spans.AppendEmpty()
+		s.SetName(name)
+		s.SetKind(ptrace.SpanKindServer)
+	}
+	return td
+}
+
+func spanAt(td ptrace.Traces, i int) ptrace.Span {
+	return td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(i)
+}
+
+func violations(s ptrace.Span) []string {
+	v, ok := s.Attributes().Get(ViolationsAttribute)
+	if !ok {
+		return nil
+	}
+	var ids []string
+	for _, id := range v.Slice().AsRaw() {
+		ids = append(ids, id.(string))
+	}
+	return ids
+}
+
+func TestPassthrough(t *testing.T) {
+	p, sink := newProcessor(t, nil)
+	td := traces("checkout", "charge card")
+	if err := p.ConsumeTraces(context.Background(), td); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.AllTraces()) != 1 || sink.SpanCount() != 2 {
+		t.Fatalf("got %d batches with %d spans, want 1 with 2", len(sink.AllTraces()), sink.SpanCount())
+	}
+	got := sink.AllTraces()[0]
+	for i := range 2 {
+		if _, ok := spanAt(got, i).Attributes().Get(ViolationsAttribute); ok {
+			t.Errorf("span %q annotated without violations", spanAt(got, i).Name())
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	p, sink := newProcessor(t, nil)
+	td := traces("GET /users/42", "checkout")
+	spanAt(td, 0).Attributes().PutStr("user.email", "a@example.com")
+	if err := p.ConsumeTraces(context.Background(), td); err != nil {
+		t.Fatal(err)
+	}
+	got := sink.AllTraces()[0]
+	if ids := violations(spanAt(got, 0)); strings.Join(ids, ",") != "span-name-unbounded,pii-in-telemetry" {
+		t.Errorf("first span annotated with %v", ids)
+	}
+	if ids := violations(spanAt(got, 1)); ids != nil {
+		t.Errorf("second span annotated with %v", ids)
+	}
+	if v, _ := spanAt(got, 0).Attributes().Get("user.email"); v.Str() != "a@example.com" {
+		t.Errorf("user.email changed to %q without redact", v.Str())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	p, sink := newProcessor(t, func(cfg *Config) {
+		cfg.Annotate, cfg.Redact = false, true
+	})
+	td := traces("checkout")
+	span := spanAt(td, 0)
+	span.Attributes().PutStr("user.email", "a@example.com")
+	event := span.Events().AppendEmpty()
+	event.SetName("signup")
+	event.Attributes().PutStr("user.email", "b@example.com")
+	if err := p.ConsumeTraces(context.Background(), td); err != nil {
+		t.Fatal(err)
+	}
+	got := spanAt(sink.AllTraces()[0], 0)
+	if v, _ := got.Attributes().Get("user.email"); v.Str() != "[REDACTED]" {
+		t.Errorf("span attribute is %q", v.Str())
+	}
+	if v, _ := got.Events().At(0).Attributes().Get("user.email"); v.Str() != "[REDACTED]" {
+		t.Errorf("event attribute is %q", v.Str())
+	}
+	if ids := violations(got); ids != nil {
+		t.Errorf("annotated with %v although annotate is off", ids)
+	}
+}
+
+func TestNonFiniteAttribute(t *testing.T) {
+	p, sink := newProcessor(t, nil)
+	td := traces("checkout", "GET /users/42")
+	spanAt(td, 0).Attributes().PutDouble("cart.discount", math.NaN())
+	if err := p.ConsumeTraces(context.Background(), td); err != nil {
+		t.Fatal(err)
+	}
+	if ids := violations(spanAt(sink.AllTraces()[0], 1)); len(ids) != 1 {
+		t.Errorf("a NaN attribute in the batch kept the next span from being checked: %v", ids)
+	}
+}
+
+func TestWorkerFailure(t *testing.T) {
+	p, sink := newProcessor(t, nil)
+	if err := p.ConsumeTraces(context.Background(), traces("GET /a/b", "crash")); err != nil {
+		t.Fatalf("a batch the rules can't check must pass through, got %v", err)
+	}
+	if sink.SpanCount() != 2 || violations(spanAt(sink.AllTraces()[0], 0)) != nil {
+		t.Fatalf("unchecked batch changed: %d spans", sink.SpanCount())
+	}
+	// The next batch starts the rules again
+	if err := p.ConsumeTraces(context.Background(), traces("GET /a/b")); err != nil {
+		t.Fatal(err)
+	}
+	if ids := violations(spanAt(sink.AllTraces()[1], 0)); len(ids) != 1 {
+		t.Errorf("after restart annotated with %v", ids)
+	}
+}
+
+func TestWorkerFailureOnError(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		configure func(*Config)
+		spans     int
+		err       bool
+	}{
+		{"redact drops", func(cfg *Config) { cfg.Redact = true }, 0, false},
+		{"redact passes when told", func(cfg *Config) { cfg.Redact, cfg.OnError = true, OnErrorPass }, 2, false},
+		{"error", func(cfg *Config) { cfg.OnError = OnErrorError }, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, sink := newProcessor(t, tc.configure)
+			td := traces("GET /a/b", "crash")
+			spanAt(td, 0).Attributes().PutStr("user.email", "a@example.com")
+			err := p.ConsumeTraces(context.Background(), td)
+			if (err != nil) != tc.err {
+				t.Errorf("ConsumeTraces: %v", err)
+			}
+			if sink.SpanCount() != tc.spans {
+				t.Errorf("%d spans passed on, want %d", sink.SpanCount(), tc.spans)
+			}
+		})
+	}
+}
+
+func TestValidateOnError(t *testing.T) {
+	cfg := createDefaultConfig()
+	cfg.OnError = "retry"
+	if err := cfg.Validate(); err == nil {
+		t.Error("on_error: retry accepted")
+	}
+}
+
+func TestStartWithoutRules(t *testing.T) {
+	t.Setenv("OLLYGARDEN_HOME", "")
+	cfg := createDefaultConfig()
+	p, err := NewFactory().CreateTraces(context.Background(), processortest.NewNopSettings(componentType), cfg, consumertest.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(context.Background(), componenttest.NewNopHost()); err == nil {
+		t.Error("started without a rules checkout")
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown after a failed Start: %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker
+
+go 1.25.0
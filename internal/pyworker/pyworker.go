@@ -0,0 +1,428 @@
+// Package pyworker runs the exported-telemetry checks of `otel_cli.py analyze-traces` for the
+// Go processors that check spans in flight, spancheck and collector/ollylintprocessor. It keeps
+// one `python3 -m rules.processor` running and speaks its line protocol (see
+// rules/processor.py):
+//
+//	checker, err := pyworker.Start(pyworker.Options{Home: home, ConfigDir: dir, Timeout: 5 * time.Second})
+//	if err != nil {
+//		return err
+//	}
+//	defer checker.Close(ctx)
+//	found, err := checker.Check(spans) // found[i] holds the findings of spans[i]
+//
+// Check may be called from any number of goroutines. Calls queue up for one goroutine that owns
+// the process and sends whatever is queued as one batch, so callers wait on their own reply and
+// never on each other's I/O. A batch that fails or takes longer than the timeout stops the
+// process, and the next batch starts a new one.
+package pyworker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Check once Close was called.
+var ErrClosed = errors.New("rules stopped")
+
+// Span is one span as `python3 -m rules.processor` reads it.
+type Span struct {
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	Service    string         `json:"service"`
+	Scope      string         `json:"scope"`
+	Attributes map[string]any `json:"attributes"`
+	Events     []Event        `json:"events"`
+}
+
+// Event is one span event.
+type Event struct {
+	Name       string         `json:"name"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// Finding is one rule a span breaks, with the attribute it is about ("" for the span itself)
+// and the index of the span event holding that attribute (-1 for the span's own attributes).
+type Finding struct {
+	RuleID    string `json:"rule_id"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	Attribute string `json:"attribute"`
+	Event     int    `json:"event"`
+}
+
+// Options says how to run the rules.
+type Options struct {
+	// Python is the interpreter (default OLLYGARDEN_PYTHON, or python3).
+	Python string
+	// Home is the checkout holding the rules package (default OLLYGARDEN_HOME). It is
+	// required: binaries built from the module cache, with -trimpath or by go install don't
+	// know where the checkout is.
+	Home string
+	// ConfigDir is where .ollygarden.yaml is looked up (default the working directory).
+	ConfigDir string
+	// Profile selects a .ollygarden.yaml profile (default OLLYGARDEN_PROFILE).
+	Profile string
+	// Timeout bounds the wait for one Check (default 5s).
+	Timeout time.Duration
+}
+
+// Most spans sent to the process at once; calls queued beyond it wait for the next batch
+const maxBatch = 1024
+
+type request struct {
+	spans []json.RawMessage
+	reply chan result
+}
+
+type result struct {
+	found [][]Finding
+	err   error
+}
+
+// Checker checks spans with a running `python3 -m rules.processor`.
+type Checker struct {
+	opts Options
+
+	queue   chan request
+	done    chan struct{}
+	stopped chan struct{}
+	close   sync.Once
+}
+
+// Start starts the rules and returns a Checker using them. It fails when the rules can't be
+// run or the project config is invalid.
+func Start(opts Options) (*Checker, error) {
+	opts.Python = firstNonEmpty(opts.Python, os.Getenv("OLLYGARDEN_PYTHON"), "python3")
+	opts.Home = firstNonEmpty(opts.Home, os.Getenv("OLLYGARDEN_HOME"))
+	if opts.Home == "" {
+		return nil, errors.New("no rules: set OLLYGARDEN_HOME to the checkout holding the rules package")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	configDir, err := filepath.Abs(firstNonEmpty(opts.ConfigDir, "."))
+	if err != nil {
+		return nil, err
+	}
+	opts.ConfigDir = configDir
+
+	c := &Checker{opts: opts, queue: make(chan request), done: make(chan struct{}), stopped: make(chan struct{})}
+	w, err := c.startWorker()
+	if err != nil {
+		return nil, err
+	}
+	go c.run(w)
+	return c, nil
+}
+
+// Check returns the findings of each span, in order. Spans that can't be encoded fail this
+// call only; the rules and the checks of other callers carry on.
+func (c *Checker) Check(spans []Span) ([][]Finding, error) {
+	encoded, err := encode(spans)
+	if err != nil {
+		return nil, err
+	}
+	req := request{spans: encoded, reply: make(chan result, 1)}
+	timeout := time.NewTimer(c.opts.Timeout)
+	defer timeout.Stop()
+	select {
+	case <-c.done:
+		return nil, ErrClosed
+	default:
+	}
+	select {
+	case c.queue <- req:
+	case <-c.done:
+		return nil, ErrClosed
+	case <-timeout.C:
+		return nil, fmt.Errorf("rules busy for longer than %v", c.opts.Timeout)
+	}
+	select {
+	case r := <-req.reply:
+		return r.found, r.err
+	case <-c.done:
+		return nil, ErrClosed
+	case <-timeout.C:
+		return nil, fmt.Errorf("rules took longer than %v", c.opts.Timeout)
+	}
+}
+
+// Close stops the rules and waits for the process to exit, or for ctx to be done. Checks
+// waiting for a reply return ErrClosed.
+func (c *Checker) Close(ctx context.Context) error {
+	c.close.Do(func() { close(c.done) })
+	select {
+	case <-c.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run owns the process: it sends the queued checks in batches until the Checker is closed.
+func (c *Checker) run(w *worker) {
+	defer close(c.stopped)
+	defer func() {
+		if w != nil {
+			w.stop()
+		}
+	}()
+	for {
+		var batch []request
+		select {
+		case <-c.done:
+			return
+		case req := <-c.queue:
+			batch = append(batch, req)
+		}
+		n := len(batch[0].spans)
+	queued:
+		for n < maxBatch {
+			select {
+			case req := <-c.queue:
+				batch = append(batch, req)
+				n += len(req.spans)
+			default:
+				break queued
+			}
+		}
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		if w == nil {
+			var err error
+			if w, err = c.startWorker(); err != nil {
+				reply(batch, result{err: err})
+				continue
+			}
+		}
+		spans := make([]json.RawMessage, 0, n)
+		for _, req := range batch {
+			spans = append(spans, req.spans...)
+		}
+		found, err := w.check(spans, c.opts.Timeout, c.done)
+		if err != nil {
+			w.stop()
+			w = nil
+			reply(batch, result{err: err})
+			continue
+		}
+		for _, req := range batch {
+			req.reply <- result{found: found[:len(req.spans):len(req.spans)]}
+			found = found[len(req.spans):]
+		}
+	}
+}
+
+// encode returns the JSON of each span. Non-finite floats, which JSON can't hold, are sent as
+// the strings "NaN", "+Inf" and "-Inf".
+func encode(spans []Span) ([]json.RawMessage, error) {
+	encoded := make([]json.RawMessage, len(spans))
+	for i, s := range spans {
+		b, err := json.Marshal(s)
+		var unsupported *json.UnsupportedValueError
+		if errors.As(err, &unsupported) {
+			b, err = json.Marshal(finite(s))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encoding span %q: %v", s.Name, err)
+		}
+		encoded[i] = b
+	}
+	return encoded, nil
+}
+
+// finite returns s with its non-finite float attribute values replaced by strings.
+func finite(s Span) Span {
+	s.Attributes = finiteValue(s.Attributes).(map[string]any)
+	events := make([]Event, len(s.Events))
+	for i, e := range s.Events {
+		events[i] = Event{Name: e.Name, Attributes: finiteValue(e.Attributes).(map[string]any)}
+	}
+	s.Events = events
+	return s
+}
+
+func finiteValue(v any) any {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	case float32:
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, 32)
+		}
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = finiteValue(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = finiteValue(e)
+		}
+		return out
+	case []float64:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = finiteValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+func reply(batch []request, r result) {
+	for _, req := range batch {
+		req.reply <- r
+	}
+}
+
+// worker is a running `python3 -m rules.processor`, answering one batch per line.
+type worker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *tailBuffer
+}
+
+func (c *Checker) startWorker() (*worker, error) {
+	args := []string{"-m", "rules.processor", "--config-dir", c.opts.ConfigDir}
+	if c.opts.Profile != "" {
+		args = append(args, "--profile", c.opts.Profile)
+	}
+	cmd := exec.Command(c.opts.Python, args...)
+	cmd.Dir = c.opts.Home
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	w := &worker{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout), stderr: &tailBuffer{}}
+	cmd.Stderr = w.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting rules in %s: %v", c.opts.Home, err)
+	}
+	// An empty batch proves the rules load and the config is valid before spans arrive
+	if _, err := w.check(nil, 30*time.Second, c.done); err != nil {
+		w.stop()
+		return nil, err
+	}
+	return w, nil
+}
+
+// check returns the findings of each encoded span, in order, giving up when done is closed.
+// After an error the worker is unusable: stop it.
+func (w *worker) check(spans []json.RawMessage, timeout time.Duration, done <-chan struct{}) ([][]Finding, error) {
+	request := []byte(`{"spans":[`)
+	for i, s := range spans {
+		if i > 0 {
+			request = append(request, ',')
+		}
+		request = append(request, s...)
+	}
+	request = append(request, "]}"...)
+	type reply struct {
+		line []byte
+		err  error
+	}
+	replied := make(chan reply, 1)
+	go func() {
+		if _, err := w.stdin.Write(append(request, '\n')); err != nil {
+			replied <- reply{err: err}
+			return
+		}
+		line, err := w.stdout.ReadBytes('\n')
+		replied <- reply{line, err}
+	}()
+
+	var r reply
+	select {
+	case r = <-replied:
+	case <-done:
+		return nil, ErrClosed
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("rules took longer than %v", timeout)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("rules exited: %v: %s", r.err, w.stderr.String())
+	}
+	var out struct {
+		Spans [][]Finding `json:"spans"`
+		Error string      `json:"error"`
+	}
+	if err := json.Unmarshal(r.line, &out); err != nil {
+		return nil, fmt.Errorf("reading rule output: %v", err)
+	}
+	if out.Error != "" {
+		return nil, errors.New(out.Error)
+	}
+	if len(out.Spans) != len(spans) {
+		return nil, fmt.Errorf("rules answered for %d of %d spans", len(out.Spans), len(spans))
+	}
+	return out.Spans, nil
+}
+
+func (w *worker) stop() {
+	w.stdin.Close()
+	if w.cmd.Process != nil {
+		w.cmd.Process.Kill()
+	}
+	w.cmd.Wait()
+}
+
+// tailBuffer keeps the last few KB written to it, for error messages.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+const tailSize = 4096
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > tailSize {
+		t.buf = t.buf[len(t.buf)-tailSize:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
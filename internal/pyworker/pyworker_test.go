@@ -0,0 +1,188 @@
+package pyworker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker"
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker/pyworkertest"
+)
+
+// rules flags span names with a path, crashes on "crash" and hangs on "hang". "pid" is
+// answered with the process ID of the worker, and "ratio" with the ratio attribute it got.
+func rules(s pyworker.Span) []pyworker.Finding {
+	switch s.Name {
+	case "crash":
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Hour)
+	case "pid":
+		return []pyworker.Finding{{RuleID: "pid", Message: fmt.Sprint(os.Getpid()), Event: -1}}
+	case "ratio":
+		return []pyworker.Finding{{RuleID: "ratio", Message: fmt.Sprint(s.Attributes["ratio"]), Event: -1}}
+	}
+	if strings.Contains(s.Name, "/") {
+		return []pyworker.Finding{{RuleID: "span-name-unbounded", Severity: "high", Message: "name holds a path", Event: -1}}
+	}
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	pyworkertest.Main(rules)
+	os.Exit(m.Run())
+}
+
+func start(t *testing.T, timeout time.Duration) *pyworker.Checker {
+	t.Helper()
+	opts := pyworkertest.Options(t)
+	opts.Timeout = timeout
+	c, err := pyworker.Start(opts)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { c.Close(context.Background()) })
+	return c
+}
+
+func TestCheck(t *testing.T) {
+	c := start(t, 5*time.Second)
+	found, err := c.Check([]pyworker.Span{{Name: "GET /users/42"}, {Name: "checkout"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || len(found[0]) != 1 || found[0][0].RuleID != "span-name-unbounded" || len(found[1]) != 0 {
+		t.Errorf("got %+v", found)
+	}
+}
+
+func TestConcurrentChecks(t *testing.T) {
+	c := start(t, 5*time.Second)
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("op-%d", i)
+			if i%2 == 0 {
+				name = fmt.Sprintf("GET /items/%d", i)
+			}
+			found, err := c.Check([]pyworker.Span{{Name: name}})
+			switch {
+			case err != nil:
+				errs <- err
+			case len(found) != 1 || (len(found[0]) == 1) != (i%2 == 0):
+				errs <- fmt.Errorf("span %q got findings %+v", name, found)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRestartAfterCrash(t *testing.T) {
+	c := start(t, 5*time.Second)
+	if _, err := c.Check([]pyworker.Span{{Name: "crash"}}); err == nil || !strings.Contains(err.Error(), "rules exited") {
+		t.Fatalf("crash: got %v", err)
+	}
+	found, err := c.Check([]pyworker.Span{{Name: "GET /a/b"}})
+	if err != nil || len(found) != 1 || len(found[0]) != 1 {
+		t.Fatalf("after crash: got %+v, %v", found, err)
+	}
+}
+
+func TestNonFiniteAttributes(t *testing.T) {
+	c := start(t, 5*time.Second)
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		found, err := c.Check([]pyworker.Span{{Name: "ratio", Attributes: map[string]any{"ratio": v, "list": []any{v}},
+			Events: []pyworker.Event{{Name: "sample", Attributes: map[string]any{"value": v}}}}})
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		if want := strconv.FormatFloat(v, 'g', -1, 64); found[0][0].Message != want {
+			t.Errorf("%v arrived as %q, want %q", v, found[0][0].Message, want)
+		}
+	}
+}
+
+func TestUnencodableSpan(t *testing.T) {
+	c := start(t, 5*time.Second)
+	pid := func() string {
+		t.Helper()
+		found, err := c.Check([]pyworker.Span{{Name: "pid"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return found[0][0].Message
+	}
+	before := pid()
+	if _, err := c.Check([]pyworker.Span{{Name: "op", Attributes: map[string]any{"f": func() {}}}}); err == nil {
+		t.Fatal("no error for a span JSON can't hold")
+	}
+	if after := pid(); after != before {
+		t.Errorf("worker restarted after an unencodable span: pid %s, then %s", before, after)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := start(t, 200*time.Millisecond)
+	if _, err := c.Check([]pyworker.Span{{Name: "hang"}}); err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Fatalf("hang: got %v", err)
+	}
+	if _, err := c.Check([]pyworker.Span{{Name: "op"}}); err != nil {
+		t.Fatalf("after timeout: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := start(t, time.Minute)
+	hung := make(chan error, 1)
+	go func() {
+		_, err := c.Check([]pyworker.Span{{Name: "hang"}})
+		hung <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-hung; !errors.Is(err, pyworker.ErrClosed) {
+		t.Errorf("check in flight: got %v, want ErrClosed", err)
+	}
+	if _, err := c.Check([]pyworker.Span{{Name: "op"}}); !errors.Is(err, pyworker.ErrClosed) {
+		t.Errorf("check after Close: got %v, want ErrClosed", err)
+	}
+	if err := c.Close(ctx); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestCloseHonorsContext(t *testing.T) {
+	c := start(t, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The process may or may not have exited by now; a done context must not block either way
+	if err := c.Close(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestStartNeedsHome(t *testing.T) {
+	t.Setenv("OLLYGARDEN_HOME", "")
+	if _, err := pyworker.Start(pyworker.Options{}); err == nil || !strings.Contains(err.Error(), "OLLYGARDEN_HOME") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Package pyworkertest stands in for `python3 -m rules.processor` in tests, so the processors
+// built on pyworker can be tested without Python. The test binary plays the rules: TestMain
+// serves the protocol when it was started as the worker, and the tests point the Python option
+// at the binary itself.
+//
+//	func TestMain(m *testing.M) {
+//		pyworkertest.Main(func(s pyworker.Span) []pyworker.Finding { ... })
+//		os.Exit(m.Run())
+//	}
+//
+//	checker, err := pyworker.Start(pyworkertest.Options(t))
+package pyworkertest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker"
+)
+
+// Environment variable marking the test binary as started to serve the rules
+const serveEnv = "PYWORKERTEST_SERVE"
+
+// Rules returns the findings of one span. Rules that panic or exit make the worker crash, like
+// broken rules would.
+type Rules func(pyworker.Span) []pyworker.Finding
+
+// Main serves the protocol with rules and exits when the test binary was started as the
+// worker, and returns otherwise. Call it first thing in TestMain.
+func Main(rules Rules) {
+	if os.Getenv(serveEnv) == "" {
+		return
+	}
+	Serve(os.Stdin, os.Stdout, rules)
+	os.Exit(0)
+}
+
+// Serve answers batches read from r until r is closed, like rules.processor.serve.
+func Serve(r io.Reader, w io.Writer, rules Rules) {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(nil, 64<<20)
+	enc := json.NewEncoder(w)
+	for lines.Scan() {
+		var batch struct {
+			Spans []pyworker.Span `json:"spans"`
+		}
+		if err := json.Unmarshal(lines.Bytes(), &batch); err != nil {
+			enc.Encode(map[string]string{"error": "malformed batch: " + err.Error()})
+			continue
+		}
+		found := make([][]pyworker.Finding, len(batch.Spans))
+		for i, s := range batch.Spans {
+			found[i] = append([]pyworker.Finding{}, rules(s)...)
+		}
+		enc.Encode(map[string]any{"spans": found})
+	}
+}
+
+// Options returns the pyworker options running the rules given to Main. The ConfigDir and
+// Profile options, which the fake ignores, are left for the test to set.
+func Options(t testing.TB) pyworker.Options {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("pyworkertest: locating the test binary: %v", err)
+	}
+	t.Setenv(serveEnv, "1")
+	return pyworker.Options{Python: self, Home: t.TempDir()}
+}
@@ -11,30 +11,7 @@ from langchain_chroma import Chroma
 from langchain.schema import Document
 from pydantic import BaseModel
 import json
-from dataclasses import dataclass
-
-@dataclass
-class CodeLocation:
-    line_number: int
-    column: int
-    function_name: str
-    code_snippet: str
-    context_lines: List[str]
-
-@dataclass 
-class TelemetryViolation:
-    violation_id: str
-    severity: str
-    file_path: str
-    location: CodeLocation
-    violation_type: str
-    rule_violated: str
-    description: str
-    fix_suggestion: str
-    kb_reference: str
-    confidence: float
-    detection_method: str
-    language: str
+from rules import CodeLocation, TelemetryViolation, RuleEngine
 
 class MultiLanguagePatternDetector:
     """Enhanced detector with better context extraction and deduplication"""
@@ -405,6 +382,7 @@ class MultiLanguagePatternDetector:
                         "context_lines": context_lines,
                         "function_name": self._get_function_name(lines, line_num - 1, language),
                         "detection_method": "multi_language_pattern",
+                        "file_path": file_path,
                         "language": language,
                         "confidence": 0.85,
                         "span_context": span_context  # Enhanced context
@@ -456,6 +434,9 @@ class MultiLanguageOTelAnalyzer:
         
         # USING ENHANCED PATTERN DETECTION
         self.pattern_detector = MultiLanguagePatternDetector(self.vectorstore, self.llm)
+        
+        # Deterministic rules (no LLM involved) run alongside the RAG validation
+        self.rule_engine = RuleEngine()
     
     def _load_vector_store(self) -> Chroma:
         if not os.path.exists(self.vector_store_path):
@@ -466,27 +447,34 @@ class MultiLanguageOTelAnalyzer:
             embedding_function=self.embeddings
         )
     
-    def analyze_telemetry_patterns(self, code: str, file_path: str, query: str = None) -> Dict[str, Any]:
-        """Analyze telemetry patterns with enhanced context-aware validation"""
+    def analyze_telemetry_patterns(self, code: str, file_path: str, query: str = None,
+                                   rule_violations: Optional[List[TelemetryViolation]] = None) -> Dict[str, Any]:
+        """Analyze telemetry patterns with enhanced context-aware validation. rule_violations are
+        the rule engine's findings for the file when the caller already has them (scan runs the
+        rules package by package)"""
         
         print(f"Starting multi-language analysis for {Path(file_path).name}")
         
         # Step 1:DETECT PATTERNS
         detected_patterns = self.pattern_detector.find_patterns(code, file_path)
         
+        # Rule engine findings don't depend on detected patterns
+        if rule_violations is None:
+            rule_violations = self.rule_engine.analyze(code, file_path)
+        
         if not detected_patterns:
             return {
                 "file_path": file_path,
                 "language": self.pattern_detector._detect_language(file_path, code),
                 "total_patterns": 0,
-                "violations": [],
-                "summary": {"total_violations": 0},
+                "violations": rule_violations,
+                "summary": self._create_summary(rule_violations),
                 "kb_sections_used": []
             }
         
         # Step 2: validate NAMING CONVENTION USING RAG
         print(f"Validating {len(detected_patterns)} patterns against naming conventions...")
-        violations = []
+        violations = list(rule_violations)
         kb_docs_used = []
         
         for pattern in detected_patterns:
@@ -675,7 +663,7 @@ JSON Response:"""
                 return TelemetryViolation(
                     violation_id=f"{pattern['violation_type'].upper()}_{pattern['line_number']}",
                     severity="high" if result.get("confidence", 0) > 0.9 else "medium",
-                    file_path="current_file",
+                    file_path=pattern.get("file_path", "current_file"),
                     location=location,
                     violation_type=pattern['violation_type'],
                     rule_violated=result.get("rule_violated", "Naming convention violation"),
@@ -7,14 +7,17 @@ It now Supports Go, Python, JavaScript/TypeScript, Java, C#
 import click
 import sys
 import os
+import threading
+import time
 from pathlib import Path
-from typing import Optional, Dict
+from typing import Dict, List, Optional
 import json
 from rich.console import Console
 from rich.table import Table
 from rich.panel import Panel
 from rich.syntax import Syntax
-from rich.progress import Progress, SpinnerColumn, TextColumn
+from rich.progress import Progress, SpinnerColumn, TextColumn, BarColumn, MofNCompleteColumn, TimeRemainingColumn
+from contextlib import contextmanager
 from dotenv import load_dotenv
 
 # Import the multi-language analyzer
@@ -23,17 +26,61 @@ sys.path.insert(0, str(current_dir))
 
 try:
     from multilang_analyzer import MultiLanguageOTelAnalyzer, TelemetryViolation
+    from rules import apply_fixes, fix_diff, all_rules, RuleEngine
+    from rules.base import SEVERITIES, SIGNALS
+    from rules.fixtures import write_fixtures
+    from rules.golang import GoFile
+    from rules.migration import migration_report, rewrite_opencensus
+    from rules.semconv_migration import parse_version, import_path, plan_migration, migrate_file
+    from rules.score import quality_score
+    from rules.revisions import diff_revisions, parse_range
+    from rules.config import (load_config, ConfigError, CONFIG_FILE, PROFILE_ENV, SIGNALS_ENV, parse_signals,
+                              find_config, check_config, effective_config, config_schema)
+    from rules.scaffold import inspect_repository, render_config
+    from rules.context import Context, cancel_on_interrupt
+    from rules.collector import CollectorConfig, crosscheck
+    from rules.telemetry import read_spans, analyze_spans
+    from rules.receiver import ScoringReceiver, otlp_http_server, otlp_grpc_server, metrics_server
+    from rules.shard import parse_shard, select_shard, merge_reports
+    from rules.profiling import profiled, write_trace, benchmark, rule_totals
+    from rules import otlp
+    from rules.modules import discover_modules, analyze_module, overall_score, Module
+    from rules.coverage import coverage_report
+    from rules.budget import budget_report
+    from rules.grouping import grouping_report
+    from rules.overhead import hot_sites, write_module, parse_benchmarks, overhead_report
+    from rules.redaction import redaction_report, write_processor, package_name
+    from rules.analysis import render_go_registry
+    from rules.baseline import BASELINE_FILE, write_baseline
+    from renderer import TerminalRenderer
 except ImportError:
     print("Could not import multilang_analyzer. Make sure the file is in the same directory.")
     sys.exit(1)
 
-console = Console()
+# https://no-color.org: any non-empty NO_COLOR disables color
+NO_COLOR = bool(os.environ.get('NO_COLOR'))
+console = Console(no_color=NO_COLOR)
+# Status messages go here when stdout is reserved for a patch
+err_console = Console(stderr=True, no_color=NO_COLOR)
 
 @click.group()
 @click.option('--vector-store', default='./vector_store', help='Path to vector store directory')
 @click.option('--verbose', '-v', is_flag=True, help='Enable verbose output')
+@click.option('--quiet', '-q', is_flag=True, help='One line per finding, no progress or banners')
+@click.option('--no-progress', is_flag=True, help='Do not report progress on stderr')
+@click.option('--timeout', type=float, help='Stop analysis after this many seconds and report partial results')
+@click.option('--package-timeout', type=float, help='Per-package analysis budget in seconds')
+@click.option('--profile', help='Config profile to apply, e.g. dev or prod (default: $OLLYGARDEN_PROFILE)')
+@click.option('--signals', help='Only run rules for these signals, comma-separated, e.g. traces,metrics '
+                                '(default: $OLLYGARDEN_SIGNALS)')
+@click.option('--no-baseline', is_flag=True, help='Report every finding, including those recorded in the baseline')
+@click.option('--cpuprofile', type=click.Path(dir_okay=False), help='Write a cProfile dump of the run to this file')
+@click.option('--memprofile', type=click.Path(dir_okay=False), help='Write the top allocation sites of the run to this file')
+@click.option('--trace', 'trace_path', type=click.Path(dir_okay=False),
+              help='Write per-rule, per-package timings as a Chrome trace (chrome://tracing, Perfetto)')
 @click.pass_context
-def cli(ctx, vector_store, verbose):
+def cli(ctx, vector_store, verbose, quiet, no_progress, timeout, package_timeout, profile, signals, no_baseline,
+        cpuprofile, memprofile, trace_path):
     """
     Multi-Language OpenTelemetry Analyzer
     
@@ -45,16 +92,97 @@ def cli(ctx, vector_store, verbose):
     
     ctx.obj['vector_store'] = vector_store
     ctx.obj['verbose'] = verbose
-    
-    # Initialize analyzer with progress indicator
-    with console.status("[bold green]Initializing multi-language analyzer..."):
+    ctx.obj['quiet'] = quiet
+    ctx.obj['no_progress'] = no_progress
+    ctx.obj['timeout'] = timeout
+    ctx.obj['package_timeout'] = package_timeout
+    ctx.obj['no_baseline'] = no_baseline
+    if profile:
+        # Through the environment so per-module configs and the go vet analyzers see it too
+        os.environ[PROFILE_ENV] = profile
+    if signals:
         try:
-            ctx.obj['analyzer'] = MultiLanguageOTelAnalyzer(vector_store)
-            if verbose:
-                console.print("[dim]Multi-language analyzer ready[/dim]")
-        except Exception as e:
-            console.print(f"[red]Failed to initialize analyzer: {e}[/red]")
-            sys.exit(1)
+            os.environ[SIGNALS_ENV] = ",".join(parse_signals(signals))
+        except ConfigError as e:
+            raise click.BadParameter(str(e), param_hint="'--signals'")
+    ctx.with_resource(profiled(cpuprofile, memprofile))
+    if trace_path:
+        ctx.obj['timings'] = []
+        ctx.call_on_close(lambda: write_trace(ctx.obj['timings'], trace_path))
+
+def _progress_enabled(ctx) -> bool:
+    return not (ctx.obj.get('quiet') or ctx.obj.get('no_progress'))
+
+@contextmanager
+def _progress_reporter(ctx):
+    """Rule engine progress callback rendering done/total and ETA on stderr"""
+    
+    with Progress(
+        TextColumn("[progress.description]{task.description}"),
+        BarColumn(),
+        MofNCompleteColumn(),
+        TimeRemainingColumn(),
+        console=err_console,
+        transient=True,
+        disable=not _progress_enabled(ctx)
+    ) as progress:
+        tasks = {}
+        
+        def report(phase: str, done: int, total: int):
+            if phase not in tasks:
+                tasks[phase] = progress.add_task(phase, total=total)
+            progress.update(tasks[phase], completed=done, total=total)
+        
+        yield report
+
+@contextmanager
+def _analysis_context(ctx):
+    """Context honoring --timeout that Ctrl-C cancels, so partial results can still be reported"""
+    
+    with cancel_on_interrupt(Context(ctx.obj.get('timeout'))) as analysis_ctx:
+        yield analysis_ctx
+
+def _report_incomplete(analysis_ctx, incomplete: Dict[str, str]):
+    """Warn about packages that were cut short; exits non-zero after results were printed"""
+    
+    if not incomplete:
+        return
+    err_console.print("[yellow]Results are partial; not fully analyzed:[/yellow]")
+    for package, reason in sorted(incomplete.items()):
+        err_console.print(f"  {package}: {reason}")
+    sys.exit(130 if analysis_ctx.err() == "interrupted" else 1)
+
+def _rule_engine(ctx, config) -> RuleEngine:
+    """Engine for a project config, recording rule timings when --trace is given and leaving out
+    baselined findings unless --no-baseline is"""
+    
+    try:
+        engine = RuleEngine.from_config(config)
+    except ConfigError as e:
+        console.print(f"[red]Invalid configuration: {e}[/red]")
+        sys.exit(1)
+    if ctx.obj.get('no_baseline'):
+        engine.baseline = None
+    engine.timings = ctx.obj.get('timings')
+    return engine
+
+def _report_baselined(engine: RuleEngine):
+    if engine.baselined:
+        err_console.print(f"[dim]{engine.baselined} baselined finding(s) not shown (--no-baseline to include them)[/dim]")
+
+def _get_analyzer(ctx) -> MultiLanguageOTelAnalyzer:
+    """Create the LLM-backed analyzer on first use; rule-only commands never need it"""
+    
+    if 'analyzer' not in ctx.obj:
+        with console.status("[bold green]Initializing multi-language analyzer..."):
+            try:
+                ctx.obj['analyzer'] = MultiLanguageOTelAnalyzer(ctx.obj['vector_store'])
+                if ctx.obj.get('verbose'):
+                    console.print("[dim]Multi-language analyzer ready[/dim]")
+            except Exception as e:
+                console.print(f"[red]Failed to initialize analyzer: {e}[/red]")
+                sys.exit(1)
+    return ctx.obj['analyzer']
 
 @cli.command()
 @click.argument('file_path')
@@ -63,24 +191,29 @@ def cli(ctx, vector_store, verbose):
               type=click.Choice(['rich', 'json', 'summary']), help='Output format')
 @click.option('--confidence-threshold', default=0.7, type=float,
               help='Minimum confidence for reporting violations (0.0-1.0)')
+@click.option('--fix', 'apply_fix', is_flag=True, help='Apply automated fixes to the file in place')
+@click.option('--dry-run', is_flag=True, help='With --fix, print the fixes as a unified diff instead of writing them')
 @click.pass_context
-def analyze(ctx, file_path, focus, output_format, confidence_threshold):
+def analyze(ctx, file_path, focus, output_format, confidence_threshold, apply_fix, dry_run):
     """
     Analyze OpenTelemetry patterns in any supported language
     
     FILE_PATH: Source code file to analyze
     """
-    analyzer = ctx.obj['analyzer']
+    analyzer = _get_analyzer(ctx)
+    analyzer.rule_engine = _rule_engine(ctx, _load_config(file_path))
     
     if not os.path.exists(file_path):
         console.print(f"[red]File not found: {file_path}[/red]")
         sys.exit(1)
     
     # Show analysis progress
+    quiet = ctx.obj.get('quiet', False)
     with Progress(
         SpinnerColumn(),
         TextColumn("[progress.description]{task.description}"),
-        console=console
+        console=err_console,
+        disable=not _progress_enabled(ctx)
     ) as progress:
         
         # Read file
@@ -114,13 +247,23 @@ def analyze(ctx, file_path, focus, output_format, confidence_threshold):
             sys.exit(1)
         progress.remove_task(task2)
     
+    # A dry run's stdout is the patch itself, so it can be piped into `git apply`
+    if apply_fix and dry_run:
+        _apply_fixes(file_path, code, result['violations'], dry_run=True)
+        return
+    
     # Output results
     if output_format == 'json':
         _output_json(result)
     elif output_format == 'summary':
         _output_summary(result, file_path, focus)
+    elif quiet:
+        TerminalRenderer(console, quiet=True).findings({file_path: result['violations']})
     else:
         _output_rich_detailed(result, file_path, focus, confidence_threshold)
+    
+    if apply_fix:
+        _apply_fixes(file_path, code, result['violations'])
 
 @cli.command()
 @click.argument('directory')
@@ -130,14 +273,22 @@ def analyze(ctx, file_path, focus, output_format, confidence_threshold):
 @click.option('--focus', help='Analysis focus')
 @click.option('--format', 'output_format', default='rich', 
               type=click.Choice(['rich', 'json']), help='Output format')
+@click.option('--fix', 'apply_fix', is_flag=True, help='Apply automated fixes to every scanned file')
+@click.option('--dry-run', is_flag=True, help='With --fix, print one unified diff of all fixes instead of writing them')
+@click.option('--shard', help='Only analyze shard i of n (e.g. 2/4), split deterministically by package')
+@click.option('--otlp', 'export_otlp', is_flag=True,
+              help='Also export findings as OTLP logs and metrics (OTEL_EXPORTER_OTLP_ENDPOINT, default localhost:4318)')
+@click.option('--otlp-endpoint', help='OTLP/HTTP endpoint to export to; implies --otlp')
 @click.pass_context  
-def scan(ctx, directory, patterns, focus, output_format):
+def scan(ctx, directory, patterns, focus, output_format, apply_fix, dry_run, shard, export_otlp, otlp_endpoint):
     """
     Scan directory for OpenTelemetry patterns across languages
     
     DIRECTORY: Path to the directory to scan
     """
-    analyzer = ctx.obj['analyzer']
+    analyzer = _get_analyzer(ctx)
+    config = _load_config(directory)
+    analyzer.rule_engine = _rule_engine(ctx, config)
     
     if not os.path.exists(directory):
         console.print(f"[red]Directory not found: {directory}[/red]")
@@ -149,39 +300,159 @@ def scan(ctx, directory, patterns, focus, output_format):
     files_found = set()
     for pattern in patterns:
         files_found.update(dir_path.rglob(pattern))
-    files_to_analyze = list(files_found)
+    files_to_analyze = _shard_files(sorted(f for f in files_found if not config.is_excluded(str(f))), directory, shard)
     
     if not files_to_analyze:
         console.print(f"[yellow]No files found matching patterns: {patterns}[/yellow]")
         return
     
-    console.print(f"Found {len(files_to_analyze)} files to analyze")
+    quiet = ctx.obj.get('quiet', False)
+    if not quiet:
+        (err_console if dry_run else console).print(f"Found {len(files_to_analyze)} files to analyze")
     
     # Analyze each file
     results = {}
-    with Progress(console=console) as progress:
-        task = progress.add_task("Scanning files...", total=len(files_to_analyze))
-        
-        for file_path in files_to_analyze:
-            try:
-                with open(file_path, 'r', encoding='utf-8') as f:
-                    code = f.read()
-                
-                result = analyzer.analyze_telemetry_patterns(code, str(file_path), focus)
-                if result['violations']:  # Only store files with violations
-                    results[str(file_path)] = result
+    sources = {}
+    with _analysis_context(ctx) as analysis_ctx:
+        # The rules run package by package, under --timeout and --package-timeout
+        with _progress_reporter(ctx) as report_progress:
+            rule_results = analyzer.rule_engine.analyze_files([str(f) for f in files_to_analyze], report_progress,
+                                                             analysis_ctx, ctx.obj.get('package_timeout'))
+        incomplete = dict(analyzer.rule_engine.incomplete)
+        with Progress(
+            TextColumn("[progress.description]{task.description}"),
+            BarColumn(),
+            MofNCompleteColumn(),
+            TimeRemainingColumn(),
+            console=err_console,
+            disable=not _progress_enabled(ctx)
+        ) as progress:
+            task = progress.add_task("Scanning files...", total=len(files_to_analyze))
+            
+            for done, file_path in enumerate(files_to_analyze):
+                if analysis_ctx.err():
+                    incomplete[directory] = f"{analysis_ctx.err()} after {done} of {len(files_to_analyze)} files"
+                    break
+                try:
+                    with open(file_path, 'r', encoding='utf-8') as f:
+                        code = f.read()
                     
-                progress.advance(task)
-                
-            except Exception as e:
-                console.print(f"[red]Error analyzing {file_path}: {e}[/red]")
-                continue
+                    result = analyzer.analyze_telemetry_patterns(code, str(file_path), focus,
+                                                                 rule_results.get(str(file_path), []))
+                    if result['violations']:  # Only store files with violations
+                        results[str(file_path)] = result
+                        sources[str(file_path)] = code
+                        
+                    progress.advance(task)
+                    
+                except Exception as e:
+                    console.print(f"[red]Error analyzing {file_path}: {e}[/red]")
+                    continue
+    
+    if apply_fix and dry_run:
+        for file_path in sorted(results):
+            _apply_fixes(file_path, sources[file_path], results[file_path]['violations'], dry_run=True)
+        _report_incomplete(analysis_ctx, incomplete)
+        return
     
     # Output results
     if output_format == 'json':
         _output_scan_json(results)
     else:
-        _output_scan_rich(results, directory, focus)
+        _output_scan_rich(results, directory, focus, ctx.obj.get('quiet', False))
+    
+    if apply_fix:
+        for file_path in sorted(results):
+            _apply_fixes(file_path, sources[file_path], results[file_path]['violations'])
+    
+    if export_otlp or otlp_endpoint:
+        _export_otlp({path: result['violations'] for path, result in results.items()}, directory, otlp_endpoint)
+    
+    _report_incomplete(analysis_ctx, incomplete)
+
+@cli.command()
+@click.argument('path', default='./...')
+@click.option('--repeat', default=3, type=click.IntRange(min=1), help='Runs per rule; the fastest is reported')
+@click.option('--top', default=20, type=click.IntRange(min=1), help='Show the N slowest rule/package pairs')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+@click.pass_context
+def bench(ctx, path, repeat, top, output_format):
+    """
+    Time each deterministic rule on each package
+    
+    Rules that dominate the total are candidates for rules.disable in .ollygarden.yaml
+    on large repositories.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    config = _load_config(_pattern_root(path))
+    files = _go_files(path, config)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8')) for f in files]
+    engine = _rule_engine(ctx, config)
+    rows = benchmark(engine, sources, repeat)
+    totals = rule_totals(rows)
+    
+    if output_format == 'json':
+        _print_json({"rules": totals, "packages": rows})
+        return
+    
+    overall = sum(t['seconds'] for t in totals)
+    table = Table(title=f"Rule time over {len(sources)} file(s), best of {repeat} run(s)")
+    table.add_column("Rule", style="cyan", no_wrap=True)
+    table.add_column("Total", justify="right")
+    table.add_column("Share", justify="right")
+    table.add_column("Findings", justify="right")
+    for t in totals:
+        share = t['seconds'] / overall * 100 if overall else 0
+        table.add_row(t['rule_id'], f"{t['seconds'] * 1000:.1f} ms", f"{share:.0f}%", str(t['findings']))
+    console.print(table)
+    
+    slowest = Table(title=f"Slowest {min(top, len(rows))} rule/package pair(s)")
+    slowest.add_column("Rule", style="cyan", no_wrap=True)
+    slowest.add_column("Package")
+    slowest.add_column("Time", justify="right")
+    for row in rows[:top]:
+        slowest.add_row(row['rule_id'], row['package'], f"{row['seconds'] * 1000:.1f} ms")
+    console.print(slowest)
+
+@cli.command('merge-reports')
+@click.argument('reports', nargs=-1, required=True, type=click.Path(exists=True, dir_okay=False))
+@click.option('--format', 'output_format', default='json',
+              type=click.Choice(['json', 'rich']), help='Output format')
+def merge_reports_cmd(reports, output_format):
+    """
+    Combine the JSON reports of sharded runs into one report of the findings per file
+    
+    REPORTS: files written by `score --shard i/n --format json` or `scan --shard i/n --format json`
+    """
+    loaded = []
+    for report in reports:
+        try:
+            loaded.append(json.loads(Path(report).read_text(encoding='utf-8')))
+        except json.JSONDecodeError as e:
+            console.print(f"[red]{report} is not a JSON scan report: {e}[/red]")
+            sys.exit(1)
+    merged, duplicates = merge_reports(loaded)
+    if duplicates:
+        err_console.print(f"[yellow]{len(duplicates)} file(s) appear in more than one report; "
+                          f"were the shards scanned from different trees?[/yellow]")
+        for path in duplicates:
+            err_console.print(f"  {path}")
+    
+    if output_format == 'json':
+        _print_json(merged)
+        return
+    
+    findings = [(path, v) for path, result in merged.items() for v in result['violations']]
+    table = Table(title=f"{len(findings)} finding(s) in {len(merged)} file(s) from {len(reports)} report(s)")
+    table.add_column("Location", style="cyan")
+    table.add_column("Severity")
+    table.add_column("Rule")
+    table.add_column("Description")
+    for path, v in findings:
+        table.add_row(f"{path}:{v['line_number']}", v['severity'], v.get('rule_id') or v['rule_violated'],
+                      v['description'])
+    console.print(table)
 
 @cli.command()
 @click.argument('question')
@@ -190,7 +461,7 @@ def ask(ctx, question):
     """
     Ask about OpenTelemetry best practices
     """
-    analyzer = ctx.obj['analyzer']
+    analyzer = _get_analyzer(ctx)
     
     with console.status("Searching knowledge base..."):
         try:
@@ -214,6 +485,998 @@ def ask(ctx, question):
         except Exception as e:
             console.print(f"[red]Knowledge base query failed: {e}[/red]")
 
+@cli.command()
+@click.argument('path', default='./...')
+@click.option('--min', 'minimum', type=float, help='Exit with status 1 when the score is below this value')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+@click.option('--otlp', 'export_otlp', is_flag=True,
+              help='Also export findings and the score as OTLP logs and metrics')
+@click.option('--otlp-endpoint', help='OTLP/HTTP endpoint to export to; implies --otlp')
+@click.option('--shard', help='Only analyze shard i of n (e.g. 2/4), split deterministically by package; '
+                              'the JSON report then lists the findings for merge-reports')
+@click.pass_context
+def score(ctx, path, minimum, output_format, export_otlp, otlp_endpoint, shard):
+    """
+    Print the aggregate instrumentation quality score and per-category subscores
+    
+    Uses the deterministic rules only, so it is fast and reproducible enough for CI gating.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    config = _load_config(_pattern_root(path))
+    files = _shard_files(_go_files(path, config), _pattern_root(path), shard)
+    engine = _rule_engine(ctx, config)
+    with _analysis_context(ctx) as analysis_ctx, _progress_reporter(ctx) as progress:
+        results = engine.analyze_files([str(f) for f in files], progress, analysis_ctx,
+                                       ctx.obj.get('package_timeout'))
+    total_lines = sum(len(f.read_text(encoding='utf-8').splitlines()) for f in files)
+    report = quality_score(results, total_lines)
+    passed = minimum is None or report['score'] >= minimum
+    
+    if output_format == 'json' and shard:
+        _print_json(dict(report, minimum=minimum, passed=passed, results={
+            path: {"violations": [_violation_to_dict(v) for v in vs]} for path, vs in results.items() if vs}))
+    elif output_format == 'json':
+        _print_json(dict(report, minimum=minimum, passed=passed))
+    else:
+        color = "green" if report['score'] >= 90 else "yellow" if report['score'] >= 70 else "red"
+        console.print(f"[bold {color}]Score: {report['score']:.1f}/100[/bold {color}] "
+                      f"[dim]({report['findings']} finding(s) in {report['files']} file(s), {report['lines']} lines)[/dim]")
+        table = Table()
+        table.add_column("Category")
+        table.add_column("Score", justify="right")
+        table.add_column("Findings", justify="right")
+        for category, sub in report['categories'].items():
+            table.add_row(category, f"{sub['score']:.1f}", str(sub['findings']))
+        console.print(table)
+        if minimum is not None:
+            console.print(f"[green]Passed[/green] (minimum {minimum:g})" if passed
+                          else f"[red]Failed[/red]: score is below the minimum of {minimum:g}")
+    
+    if export_otlp or otlp_endpoint:
+        _export_otlp(results, _pattern_root(path), otlp_endpoint, report)
+    
+    _report_baselined(engine)
+    _report_incomplete(analysis_ctx, engine.incomplete)
+    if not passed:
+        sys.exit(1)
+
+@cli.command()
+@click.argument('path', default='./...')
+@click.option('--min', 'minimum', type=float, help='Exit with status 1 when coverage is below this percentage')
+@click.option('--uncovered', is_flag=True, help='List every boundary that is not instrumented')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def coverage(path, minimum, uncovered, output_format):
+    """
+    Report trace coverage: the percentage of boundaries that are instrumented
+    
+    Boundaries are HTTP routes, gRPC methods, outgoing HTTP requests, database calls and
+    message publishes and consumers. One counts as instrumented when its function starts a
+    span or an instrumentation library (otelhttp, otelgrpc, otelsql, ...) covers it.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    root = _pattern_root(path)
+    config = _load_config(root)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8'), config.span_helpers) for f in _go_files(path, config)]
+    report = coverage_report(sources, root if Path(root).is_dir() else str(Path(root).parent))
+    passed = minimum is None or report['coverage'] >= minimum
+    
+    if output_format == 'json':
+        _print_json(dict(report, minimum=minimum, passed=passed))
+    else:
+        color = "green" if report['coverage'] >= 90 else "yellow" if report['coverage'] >= 70 else "red"
+        console.print(f"[bold {color}]Trace coverage: {report['coverage']:.1f}%[/bold {color}] "
+                      f"[dim]({report['instrumented']} of {report['boundaries']} boundaries instrumented)[/dim]")
+        if report['kinds']:
+            table = Table()
+            table.add_column("Boundary")
+            table.add_column("Instrumented", justify="right")
+            table.add_column("Coverage", justify="right")
+            for kind in report['kinds'].values():
+                table.add_row(kind['label'], f"{kind['instrumented']}/{kind['boundaries']}", f"{kind['coverage']:.1f}%")
+            console.print(table)
+            table = Table()
+            table.add_column("Package", style="cyan")
+            table.add_column("Instrumented", justify="right")
+            table.add_column("Coverage", justify="right")
+            for package, sub in report['packages'].items():
+                table.add_row(package, f"{sub['instrumented']}/{sub['boundaries']}", f"{sub['coverage']:.1f}%")
+            console.print(table)
+        if uncovered:
+            for gap in report['uninstrumented']:
+                where = f" in {gap['function']}" if gap['function'] else ""
+                console.print(f"{gap['file']}:{gap['line']} {gap['kind']}{where} "
+                              f"[dim]suggested span name {gap['suggested_span_name']}[/dim]", highlight=False, soft_wrap=True)
+        if minimum is not None:
+            console.print(f"[green]Passed[/green] (minimum {minimum:g}%)" if passed
+                          else f"[red]Failed[/red]: coverage is below the minimum of {minimum:g}%")
+    
+    if not passed:
+        sys.exit(1)
+
+@cli.command()
+@click.argument('path', default='./...')
+@click.option('--qps', default=100.0, type=click.FloatRange(min=0), help='Requests per second to each entry point')
+@click.option('--loop-iterations', default=10, type=click.IntRange(min=1),
+              help='Iterations assumed for loops without a literal bound')
+@click.option('--top', default=10, type=click.IntRange(min=1), help='Show the N span sites with the largest share')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def budget(path, qps, loop_iterations, top, output_format):
+    """
+    Estimate the trace volume per request and at a given request rate
+
+    Entry points are functions nothing in the code calls (handlers, consumers, main). Each
+    counts the spans of the sites it reaches, multiplied by the loops around them and around
+    the calls leading to them, and their size from the name, attributes and events recorded.
+    The figures are rough; they show which call sites dominate the volume.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    root = _pattern_root(path)
+    config = _load_config(root)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8'), config.span_helpers) for f in _go_files(path, config)]
+    report = budget_report(sources, qps, loop_iterations, root if Path(root).is_dir() else str(Path(root).parent))
+
+    if output_format == 'json':
+        _print_json(report)
+        return
+    if not report['entry_points']:
+        console.print("[yellow]No span sites reachable from an entry point[/yellow]")
+        return
+    table = Table(title=f"Per request, and at {qps:g} requests/s each")
+    table.add_column("Entry point", style="cyan")
+    table.add_column("Spans", justify="right")
+    table.add_column("Bytes", justify="right")
+    table.add_column("Spans/s", justify="right")
+    table.add_column("Per day", justify="right")
+    for entry in report['entry_points']:
+        table.add_row(f"{entry['function']} [dim]{entry['location']}[/dim]", f"{entry['spans_per_request']:g}",
+                      _size(entry['bytes_per_request']), f"{entry['spans_per_second']:g}", _size(entry['bytes_per_day']))
+    console.print(table)
+    table = Table(title="Span sites by share of the bytes")
+    table.add_column("Site", style="cyan")
+    table.add_column("Span name")
+    table.add_column("Spans/request", justify="right")
+    table.add_column("Bytes/span", justify="right")
+    table.add_column("Share", justify="right")
+    for site in report['sites'][:top]:
+        table.add_row(site['location'], site['span_name'], f"{site['spans_per_request']:g}",
+                      str(site['bytes_per_span']), f"{site['share']:.1f}%")
+    console.print(table)
+    console.print(f"Total: {report['total']['spans_per_second']:g} spans/s, "
+                  f"{_size(report['total']['bytes_per_day'])} per day before sampling and compression")
+
+@cli.command('span-names')
+@click.argument('path', default='./...')
+@click.option('--problems', 'problems_only', is_flag=True, help='Only names that group badly')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def span_names(path, problems_only, output_format):
+    """
+    Show how Jaeger, Tempo and Datadog group each span name
+
+    Jaeger lists an operation per span name, Tempo searches and computes span metrics by it, and
+    Datadog derives an operation from the span kind and protocol and a resource from the HTTP
+    method and route or the span name. Names that group badly are flagged: unbounded ones, generic
+    ones, names shared by several functions and endpoints collapsing into one operation.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    root = _pattern_root(path)
+    config = _load_config(root)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8'), config.span_helpers) for f in _go_files(path, config)]
+    report = grouping_report(sources, root if Path(root).is_dir() else str(Path(root).parent))
+    if problems_only:
+        report['names'] = [n for n in report['names'] if n['problems']]
+
+    if output_format == 'json':
+        _print_json(report)
+        return
+    if not report['names']:
+        console.print("[yellow]No span names found[/yellow]" if not problems_only
+                      else "[green]Every span name groups cleanly[/green]")
+        return
+    table = Table(title="Span names as backends group them")
+    table.add_column("Span name", style="cyan")
+    table.add_column("Kind")
+    table.add_column("Jaeger operation / Tempo name")
+    table.add_column("Datadog operation")
+    table.add_column("Datadog resource")
+    table.add_column("Groups badly")
+    for entry in report['names']:
+        name = entry['name'] + (f" [dim]({len(entry['sites'])} sites)[/dim]" if len(entry['sites']) > 1 else "")
+        table.add_row(name, entry['kind'], entry['jaeger_operation'], entry['datadog_operation'],
+                      entry['datadog_resource'], "[yellow]" + "\n".join(entry['problems']) + "[/yellow]")
+    console.print(table)
+    crowded = {op: n for op, n in report['datadog_operations'].items() if n > 1}
+    if crowded:
+        console.print("Datadog operations shared by several span names (told apart by resource only): "
+                      + ", ".join(f"{op} ({n})" for op, n in crowded.items()))
+    console.print(f"{report['problems']} span name(s) group badly")
+
+@cli.command()
+@click.argument('path', default='./...')
+@click.option('--output', '-o', default='./ollybench', help='Directory of the generated benchmark module')
+@click.option('--top', default=10, type=click.IntRange(min=1), help='Benchmark the span sites of the N most called instrumented functions')
+@click.option('--loop-iterations', default=10, type=click.IntRange(min=1),
+              help='Iterations assumed for loops without a literal bound')
+@click.option('--run', 'run_benchmarks', is_flag=True, help='Run the benchmarks with go test and report the overhead')
+@click.option('--benchtime', default='1s', help='go test -benchtime for --run')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format of --run')
+def overhead(path, output, top, loop_iterations, run_benchmarks, benchtime, output_format):
+    """
+    Generate micro-benchmarks of the instrumentation on hot paths, and optionally run them
+
+    The span sites of the most called instrumented functions (call-graph fan-in) are replayed
+    by their shape (name, attributes by type, events, errors) without a span, with a noop
+    tracer, and with the SDK dropping and keeping the span. The module written to OUTPUT runs
+    on its own with `go test -bench .`; --run does that and reports the cost per span and per
+    request.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    import subprocess
+
+    root = _pattern_root(path)
+    config = _load_config(root)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8'), config.span_helpers) for f in _go_files(path, config)]
+    base = root if Path(root).is_dir() else str(Path(root).parent)
+    shapes = hot_sites(sources, top, loop_iterations)
+    if not shapes:
+        console.print("[yellow]No span sites found[/yellow]")
+        return
+    out = write_module(output, shapes, base)
+    if not run_benchmarks:
+        console.print(f"Wrote {len(shapes)} benchmark(s) to {out / 'overhead_test.go'}")
+        console.print(f"Run them with: cd {out} && go mod tidy && go test -run '^$' -bench . -benchmem")
+        return
+
+    try:
+        subprocess.run(["go", "mod", "tidy"], cwd=out, check=True, capture_output=True, text=True)
+        result = subprocess.run(["go", "test", "-run", "^$", "-bench", ".", "-benchmem", "-benchtime", benchtime],
+                                cwd=out, check=True, capture_output=True, text=True)
+    except FileNotFoundError:
+        console.print("[red]go is not on PATH[/red]")
+        sys.exit(2)
+    except subprocess.CalledProcessError as e:
+        console.print(f"[red]Benchmarks failed:[/red]\n{e.stdout}{e.stderr}")
+        sys.exit(1)
+    rows = overhead_report(shapes, parse_benchmarks(result.stdout), base)
+
+    if output_format == 'json':
+        _print_json({"sites": rows})
+        return
+    table = Table(title="Instrumentation cost per span (ns over no span; allocations)")
+    table.add_column("Site", style="cyan")
+    table.add_column("Span name")
+    table.add_column("Fan-in", justify="right")
+    table.add_column("Noop", justify="right")
+    table.add_column("Unsampled", justify="right")
+    table.add_column("Sampled", justify="right")
+    table.add_column("Sampled/request", justify="right")
+    for row in rows:
+        def cell(variant):
+            if f"{variant}_ns_per_span" not in row:
+                return "-"
+            return f"{row[f'{variant}_ns_per_span']:g} ns, {row[f'{variant}_allocs_per_span']:g}"
+        per_request = f"{row['sampled_us_per_request']:g} µs" if "sampled_us_per_request" in row else "-"
+        table.add_row(f"{row['function']} [dim]{row['location']}[/dim]", row['span_name'], str(row['fan_in']),
+                      cell("noop"), cell("unsampled"), cell("sampled"), per_request)
+    console.print(table)
+
+@cli.command()
+@click.argument('path', default='./...')
+@click.option('--output', '-o', default='./ollyredact', help='Directory of the generated package')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format of the summary')
+def redaction(path, output, output_format):
+    """
+    Generate a SpanProcessor redacting the attributes found holding secrets or personal data
+
+    secret-in-telemetry, pii-in-telemetry and classified-data-in-telemetry run over PATH, and
+    every attribute key they report is listed in the generated package, with the findings on
+    it. Its NewSpanProcessor wraps the processor the SDK exports through and replaces those
+    values in span and event attributes: a stopgap while the call sites are fixed. Findings
+    on event names, log fields, baggage and computed keys are listed for fixing by hand.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    root = _pattern_root(path)
+    config = _load_config(root)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8'), config.span_helpers) for f in _go_files(path, config)]
+    report = redaction_report(sources, config, root if Path(root).is_dir() else str(Path(root).parent))
+    if not report['keys'] and not report['uncovered']:
+        if output_format == 'json':
+            _print_json({"output": None, "keys": [], "uncovered": []})
+        else:
+            console.print("[green]No secrets or personal data found in telemetry[/green]")
+        return
+    written = write_processor(output, report) if report['keys'] else None
+
+    if output_format == 'json':
+        _print_json({
+            "output": str(written) if written else None,
+            "keys": [{"key": k.key, "findings": k.sites} for k in report['keys']],
+            "uncovered": [{"rule_id": u.rule_id, "location": u.where, "what": u.what, "message": u.message}
+                          for u in report['uncovered']],
+        })
+        return
+    if written:
+        table = Table(title=f"Attributes redacted by {written}")
+        table.add_column("Key", style="cyan")
+        table.add_column("Findings")
+        for k in report['keys']:
+            table.add_row(k.key, "\n".join(f"{rule_id}: {', '.join(sites)}" for rule_id, sites in sorted(k.sites.items())))
+        console.print(table)
+        console.print(f"Register it in place of the exporting processor: "
+                      f"sdktrace.WithSpanProcessor({package_name(output)}.NewSpanProcessor(bsp))")
+    if report['uncovered']:
+        console.print(f"[yellow]{len(report['uncovered'])} finding(s) the processor can't redact; fix them at the "
+                      f"call site:[/yellow]")
+        for u in report['uncovered']:
+            console.print(f"  {u.where}: {u.what} ({u.rule_id})")
+
+@cli.command()
+@click.argument('path', default='.')
+@click.option('--all-modules', is_flag=True, help='Analyze every go.mod module under PATH separately')
+@click.option('--min', 'minimum', type=float, help='Exit with status 1 when any module scores below this value')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+@click.option('--fix', 'apply_fix', is_flag=True,
+              help='Apply the automated fixes of every rule, project-wide and custom rules included')
+@click.option('--dry-run', is_flag=True, help='With --fix, print one unified diff of all fixes instead of writing them')
+@click.pass_context
+def run(ctx, path, all_modules, minimum, output_format, apply_fix, dry_run):
+    """
+    Analyze a Go module, or with --all-modules every module in a monorepo
+    
+    Each module is analyzed with the .ollygarden.yaml nearest to it and scored on its own;
+    the report ends with the score over all modules.
+    PATH: Repository or module directory
+    """
+    if not Path(path).is_dir():
+        console.print(f"[red]Directory not found: {path}[/red]")
+        sys.exit(1)
+    modules = discover_modules(path) if all_modules else []
+    if not modules:
+        if all_modules:
+            console.print(f"[yellow]No go.mod found under {path}; analyzing it as one module[/yellow]")
+        modules = [Module(Path(path).resolve().name, Path(path).resolve())]
+    
+    reports = []
+    incomplete = {}
+    with _analysis_context(ctx) as analysis_ctx, _progress_reporter(ctx) as progress:
+        for module in modules:
+            if analysis_ctx.err():
+                incomplete[module.path] = f"not analyzed ({analysis_ctx.err()})"
+                continue
+            report = analyze_module(module, modules, progress, analysis_ctx, ctx.obj.get('package_timeout'))
+            reports.append(report)
+            incomplete.update({f"{module.path}: {pkg}": reason for pkg, reason in report.incomplete.items()})
+    overall = overall_score(reports)
+    failing = [r.module.path for r in reports if minimum is not None and r.score['score'] < minimum]
+    fixable = {p: vs for r in reports for p, vs in r.results.items() if any(v.fix for v in vs)}
+    
+    # A dry run's stdout is the patch itself, so it can be piped into `git apply`
+    if apply_fix and dry_run:
+        for file_path in sorted(fixable):
+            _apply_fixes(file_path, Path(file_path).read_text(encoding='utf-8'), fixable[file_path], dry_run=True)
+        _report_incomplete(analysis_ctx, incomplete)
+        return
+    
+    if output_format == 'json':
+        _print_json({
+            "modules": [{
+                "module": r.module.path,
+                "directory": str(r.module.directory),
+                "score": r.score,
+                "violations": [_violation_to_dict(v) for vs in r.results.values() for v in vs],
+            } for r in reports],
+            "overall": overall,
+            "minimum": minimum,
+            "passed": not failing,
+        })
+    elif ctx.obj.get('quiet'):
+        TerminalRenderer(console, quiet=True).findings({p: v for r in reports for p, v in r.results.items()})
+    else:
+        TerminalRenderer(console).findings({p: v for r in reports for p, v in r.results.items()})
+        table = Table(title=f"{len(reports)} module(s)")
+        table.add_column("Module", style="cyan")
+        table.add_column("Files", justify="right")
+        table.add_column("Lines", justify="right")
+        table.add_column("Findings", justify="right")
+        table.add_column("Score", justify="right")
+        for r in reports:
+            color = "green" if r.score['score'] >= 90 else "yellow" if r.score['score'] >= 70 else "red"
+            table.add_row(r.module.path, str(len(r.results)), str(r.score['lines']), str(r.score['findings']),
+                          f"[{color}]{r.score['score']:.1f}[/{color}]")
+        table.add_section()
+        table.add_row("[bold]all modules[/bold]", str(overall['files']), str(overall['lines']),
+                      str(overall['findings']), f"[bold]{overall['score']:.1f}[/bold]")
+        console.print(table)
+        if failing:
+            console.print(f"[red]Below the minimum of {minimum:g}:[/red] {', '.join(failing)}")
+    
+    if apply_fix:
+        for file_path in sorted(fixable):
+            _apply_fixes(file_path, Path(file_path).read_text(encoding='utf-8'), fixable[file_path])
+    
+    _report_incomplete(analysis_ctx, incomplete)
+    if failing:
+        sys.exit(1)
+
+@cli.command()
+@click.argument('revisions')
+@click.option('--repo', default='.', help='Path to the git repository')
+@click.option('--path', 'subpath', default='', help='Only analyze files under this directory of the repository')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json', 'markdown']), help='Output format')
+@click.option('--fail-on-new', is_flag=True, help='Exit with status 1 if the head revision adds findings')
+@click.pass_context
+def diff(ctx, revisions, repo, subpath, output_format, fail_on_new):
+    """
+    Report findings added and resolved between two git revisions
+    
+    REVISIONS: "base..head" (e.g. main..HEAD), or a single ref compared with HEAD
+    """
+    import subprocess
+    try:
+        base_ref, head_ref = parse_range(revisions)
+        engine = _rule_engine(ctx, _load_config(repo))
+        # Both revisions are compared in full; a baseline would hide findings on one side only
+        engine.baseline = None
+        with _analysis_context(ctx) as analysis_ctx, _progress_reporter(ctx) as progress:
+            changes = diff_revisions(repo, base_ref, head_ref, subpath, engine, progress,
+                                     analysis_ctx, ctx.obj.get('package_timeout'))
+    except (ValueError, subprocess.CalledProcessError) as e:
+        detail = e.stderr.strip() if getattr(e, 'stderr', None) else str(e)
+        console.print(f"[red]Cannot compare {revisions}: {detail}[/red]")
+        sys.exit(1)
+    
+    added, resolved = changes['added'], changes['resolved']
+    if output_format == 'json':
+        _print_json({
+            "base": base_ref,
+            "head": head_ref,
+            "added": [_violation_to_dict(v) for v in added],
+            "resolved": [_violation_to_dict(v) for v in resolved],
+        })
+    elif output_format == 'markdown':
+        print(f"## Telemetry findings: {base_ref}..{head_ref}\n")
+        for heading, items in (("Added", added), ("Resolved", resolved)):
+            print(f"### {heading} ({len(items)})\n")
+            for v in items:
+                print(f"- `{v.rule_id}` {v.file_path}:{v.location.line_number} ({v.severity}): {v.description}")
+            print()
+    else:
+        for heading, items, color in (("Added", added, "red"), ("Resolved", resolved, "green")):
+            table = Table(title=f"{heading} in {base_ref}..{head_ref} ({len(items)})", title_style=color)
+            table.add_column("Location")
+            table.add_column("Rule", style="cyan")
+            table.add_column("Severity")
+            table.add_column("Finding")
+            for v in items:
+                table.add_row(f"{v.file_path}:{v.location.line_number}", v.rule_id, v.severity, v.description)
+            console.print(table)
+    
+    _report_incomplete(analysis_ctx, changes['incomplete'])
+    if fail_on_new and added:
+        sys.exit(1)
+
+@cli.command()
+@click.argument('path', default='.')
+@click.option('--force', is_flag=True, help='Overwrite an existing configuration')
+def init(path, force):
+    """
+    Inspect a repository and write a starter .ollygarden.yaml
+    
+    PATH: Repository root (default: current directory)
+    """
+    target = Path(path) / CONFIG_FILE
+    if target.exists() and not force:
+        console.print(f"[red]{target} already exists (use --force to overwrite)[/red]")
+        sys.exit(1)
+    
+    facts = inspect_repository(path)
+    target.write_text(render_config(facts), encoding='utf-8')
+    
+    console.print(f"[green]Wrote {target}[/green] from {facts['files']} Go file(s)")
+    console.print(f"  Frameworks: {', '.join(facts['frameworks']) or 'none detected'}")
+    console.print(f"  Semconv: {', '.join(facts['semconv_versions']) or 'none imported'}")
+    console.print(f"  Tracers: {', '.join(facts['tracer_names']) or 'none found'}")
+
+@cli.group('config')
+def config_group():
+    """Validate the project configuration"""
+
+@config_group.command('check')
+@click.argument('path', default='.')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def config_check(path, output_format):
+    """
+    Validate .ollygarden.yaml and print the configuration a run would use
+
+    Reports every problem at once: unknown keys, unknown rule ids and options (with the closest
+    known name), option values of the wrong type and invalid regular expressions, in the base
+    config and in every profile. A valid config is printed merged: the selected profile
+    applied, naming spread over the rule options and option defaults filled in.
+    PATH: Directory (or file) whose nearest .ollygarden.yaml is checked
+    """
+    import yaml
+
+    found = find_config(path)
+    data = {}
+    if found is not None:
+        try:
+            data = yaml.safe_load(found.read_text(encoding='utf-8')) or {}
+        except yaml.YAMLError as e:
+            console.print(f"[red]{found}: {e}[/red]")
+            sys.exit(1)
+        problems = check_config(data, str(found.parent))
+        if problems:
+            if output_format == 'json':
+                _print_json({"config": str(found), "problems": problems})
+            else:
+                for problem in problems:
+                    console.print(f"[red]{found}:[/red] {problem}")
+                console.print(f"[red]{len(problems)} problem(s)[/red]")
+            sys.exit(1)
+    effective = effective_config(_load_config(path))
+    if output_format == 'json':
+        _print_json({"config": str(found) if found else None, "problems": [], "effective": effective})
+        return
+    if found is None:
+        console.print(f"[yellow]No {CONFIG_FILE} found; the defaults apply[/yellow]")
+    else:
+        console.print(f"[green]{found} is valid[/green]")
+    click.echo(yaml.safe_dump(effective, sort_keys=False, default_flow_style=None), nl=False)
+
+@config_group.command('schema')
+@click.option('--output', '-o', default='ollygarden.schema.json', help='File to write, - for stdout')
+def config_schema_cmd(output):
+    """
+    Write the JSON schema of .ollygarden.yaml
+
+    Editors use it for completion and validation, e.g. with the YAML language server:
+    # yaml-language-server: $schema=./ollygarden.schema.json
+    Regenerate it after adding a rule or an option.
+    """
+    schema = json.dumps(config_schema(), indent=2) + "\n"
+    if output == '-':
+        click.echo(schema, nl=False)
+        return
+    Path(output).write_text(schema, encoding='utf-8')
+    console.print(f"Wrote {output}")
+
+@cli.command('collector-check')
+@click.argument('config_path')
+@click.argument('path', default='./...')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+@click.pass_context
+def collector_check(ctx, config_path, path, output_format):
+    """
+    Cross-check an OpenTelemetry Collector config against what the code emits
+    
+    Reports signals with no pipeline, attribute processors that drop keys the code sets,
+    tail-sampling/filter policies that reference span names the code never produces, and
+    trace receivers whose senders use a propagation format the code doesn't configure.
+    
+    CONFIG_PATH: Collector configuration YAML
+    PATH: Go file or directory the Collector receives telemetry from
+    """
+    try:
+        config = CollectorConfig(config_path, Path(config_path).read_text(encoding='utf-8'))
+    except (OSError, ValueError) as e:
+        console.print(f"[red]Cannot read Collector config {config_path}: {e}[/red]")
+        sys.exit(1)
+    
+    project = _load_config(_pattern_root(path))
+    files = _go_files(path, project)
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8'), project.span_helpers) for f in files]
+    findings = crosscheck(config, sources)
+    
+    if output_format == 'json':
+        _print_json([_violation_to_dict(v) for v in findings])
+    elif not findings:
+        console.print(f"[green]{config_path} is consistent with the code[/green]")
+    else:
+        TerminalRenderer(console, quiet=ctx.obj.get('quiet', False)).findings({config_path: findings})
+
+@cli.command('analyze-traces')
+@click.argument('files', nargs=-1, required=True, type=click.Path(exists=True, dir_okay=False))
+@click.option('--config-dir', default='.', help='Directory whose .ollygarden.yaml selects and configures the rules')
+@click.option('--top', default=50, type=click.IntRange(min=1), help='Show the N findings affecting the most spans')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def analyze_traces(files, config_dir, top, output_format):
+    """
+    Check exported OTLP trace files against the naming, attribute, cardinality and PII rules
+
+    Reads OTLP JSON (one document or JSON lines, as the Collector's file exporter writes them)
+    and OTLP protobuf (one message or length-prefixed), gzipped or not. Each problem is reported
+    once per span name shape ("GET /users/{id}") with the number of spans showing it, so what
+    wrappers and auto-instrumentation emit is checked as well as the code.
+
+    FILES: Exported trace files
+    """
+    spans = []
+    for path in files:
+        try:
+            spans.extend(read_spans(path))
+        except (OSError, ValueError) as e:
+            console.print(f"[red]Cannot read trace file {path}: {e}[/red]")
+            sys.exit(1)
+    findings = analyze_spans(spans, _load_config(config_dir))
+
+    if output_format == 'json':
+        _print_json({"spans": len(spans), "findings": [f.to_dict() for f in findings]})
+        return
+    if not findings:
+        console.print(f"[green]{len(spans)} span(s) follow the conventions[/green]")
+        return
+    table = Table(title=f"{len(findings)} finding(s) in {len(spans)} span(s)")
+    table.add_column("Spans", justify="right")
+    table.add_column("Span name", style="cyan")
+    table.add_column("Rule")
+    table.add_column("Problem")
+    table.add_column("Example", style="dim")
+    severity_colors = {"critical": "red", "high": "red", "medium": "yellow", "low": "blue"}
+    for f in findings[:top]:
+        color = severity_colors.get(f.severity, "white")
+        table.add_row(str(f.spans), f.span_name, f"[{color}]{f.rule_id}[/{color}]", f.message, f.example)
+    console.print(table)
+    if len(findings) > top:
+        console.print(f"[dim]{len(findings) - top} more finding(s) not shown (--top to see them)[/dim]")
+
+@cli.command()
+@click.option('--host', default='0.0.0.0', help='Address to listen on')
+@click.option('--grpc-port', default=4317, type=int, help='OTLP/gRPC port (needs grpcio); 0 to disable')
+@click.option('--http-port', default=4318, type=int, help='OTLP/HTTP port; 0 to disable')
+@click.option('--metrics-port', default=9464, type=int, help='Port of the Prometheus /metrics endpoint')
+@click.option('--config-dir', default='.', help='Directory whose .ollygarden.yaml selects and configures the rules')
+def serve(host, grpc_port, http_port, metrics_port, config_dir):
+    """
+    Receive OTLP traces and expose per-service findings and quality scores to Prometheus
+
+    Point SDKs or a Collector exporter at it; spans are checked as they arrive with the same
+    rules as analyze-traces. /metrics serves ollygarden_spans_total,
+    ollygarden_span_findings_total and ollygarden_quality_score per service, and /report
+    the findings behind them as JSON. Runs until interrupted.
+    """
+    receiver = ScoringReceiver(_load_config(config_dir))
+    servers = []
+    if http_port:
+        servers.append(otlp_http_server(receiver, host, http_port))
+        console.print(f"OTLP/HTTP on {host}:{http_port}/v1/traces")
+    servers.append(metrics_server(receiver, host, metrics_port))
+    console.print(f"Prometheus metrics on {host}:{metrics_port}/metrics")
+    grpc_server = otlp_grpc_server(receiver, host, grpc_port) if grpc_port else None
+    if grpc_server:
+        console.print(f"OTLP/gRPC on {host}:{grpc_port}")
+    elif grpc_port:
+        console.print("[yellow]OTLP/gRPC disabled: install grpcio to enable it[/yellow]")
+
+    threads = [threading.Thread(target=s.serve_forever, daemon=True) for s in servers]
+    for thread in threads:
+        thread.start()
+    try:
+        while True:
+            time.sleep(3600)
+    except KeyboardInterrupt:
+        pass
+    finally:
+        for s in servers:
+            s.shutdown()
+        if grpc_server:
+            grpc_server.stop(grace=1)
+
+@cli.command('list-rules')
+@click.option('--category', '-c', multiple=True, help='Only rules in these categories')
+@click.option('--signal', '-s', multiple=True,
+              type=click.Choice(list(SIGNALS)), help='Only rules checking these signals')
+@click.option('--severity', multiple=True, type=click.Choice(list(SEVERITIES)), help='Only rules with these severities')
+@click.option('--autofix/--no-autofix', default=None, help='Only rules with (or without) automated fixes')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def list_rules(category, signal, severity, autofix, output_format):
+    """
+    List the deterministic rules, custom and plugin rules of the project config included, optionally filtered
+    """
+    rules = [
+        r for r in sorted(all_rules() + _load_config('.').project_rules(), key=lambda r: r.rule_id)
+        if (not category or r.category in category)
+        and r.covers(signal)
+        and (not severity or r.severity in severity)
+        and (autofix is None or r.autofix == autofix)
+    ]
+    
+    if output_format == 'json':
+        _print_json([{
+            "rule_id": r.rule_id,
+            "title": r.title,
+            "category": r.category,
+            "signal": r.signal,
+            "signals": list(r.signals),
+            "severity": r.severity,
+            "scope": r.scope,
+            "autofix": r.autofix,
+            "opt_in": r.opt_in,
+            "description": r.description,
+        } for r in rules])
+        return
+    
+    table = Table(title=f"Rules ({len(rules)})")
+    table.add_column("Rule", style="cyan", no_wrap=True)
+    table.add_column("Category")
+    table.add_column("Signal")
+    table.add_column("Severity")
+    table.add_column("Fix", justify="center")
+    table.add_column("Title")
+    severity_colors = {"critical": "red", "high": "red", "medium": "yellow", "low": "blue"}
+    for r in rules:
+        color = severity_colors.get(r.severity, "white")
+        title = f"{r.title} [dim](opt-in)[/dim]" if r.opt_in else r.title
+        # The main signal, then the others the rule checks
+        signals = r.signal if r.signal == "all" else ",".join(r.signals)
+        table.add_row(r.rule_id, r.category, signals, f"[{color}]{r.severity}[/{color}]",
+                      "yes" if r.autofix else "", title)
+    console.print(table)
+
+@cli.command('gen-fixtures')
+@click.option('--output', '-o', default='./test-files/generated', help='Directory to write fixtures into')
+@click.option('--rule', 'rule_ids', multiple=True, help='Only generate fixtures for these rule IDs')
+def gen_fixtures(output, rule_ids):
+    """
+    Generate labeled Go fixtures from rule metadata and verify every rule against them
+    
+    Each fixture holds a VIOLATION section that must be reported and a CORRECT
+    section that must not be; any mismatch is a rule regression.
+    """
+    rules = [r for r in all_rules() if not rule_ids or r.rule_id in rule_ids]
+    missing = [r.rule_id for r in rules if not r.bad_example]
+    results = write_fixtures(output, rules)
+    
+    regressions = [p for problems in results.values() for p in problems]
+    console.print(f"Wrote {len(results)} fixture(s) to {output}")
+    if missing:
+        console.print(f"[yellow]Rules without examples: {', '.join(missing)}[/yellow]")
+    for problem in regressions:
+        console.print(f"[red]REGRESSION[/red] {problem}")
+    if regressions:
+        sys.exit(1)
+    console.print("[green]All rules match their fixtures[/green]")
+
+@cli.command('gen-analyzers')
+@click.option('--output', '-o', default='./analyzers/rules_gen.go', help='Go file to write the rule list to')
+def gen_analyzers(output):
+    """
+    Regenerate the rule list of the go/analysis analyzers in analyzers/
+
+    Run after adding or renaming a rule so `ollyvet` and `go vet -vettool` expose it.
+    """
+    rules = all_rules()
+    Path(output).write_text(render_go_registry(rules), encoding='utf-8')
+    console.print(f"Wrote {len(rules)} analyzer(s) to {output}")
+
+@cli.group()
+def baseline():
+    """Record existing findings so only new ones are reported"""
+
+@baseline.command('generate')
+@click.argument('path', default='./...')
+@click.option('--output', '-o', help=f'Baseline file to write (default: the config\'s baseline, {BASELINE_FILE})')
+@click.pass_context
+def baseline_generate(ctx, path, output):
+    """
+    Snapshot the current findings; later runs report only findings not in the snapshot
+
+    Findings are matched by rule, file, function, code and message, so edits that only move
+    code around don't resurface them. Commit the file; regenerate it to accept new findings.
+    PATH: Go file or directory ("./..." style patterns are accepted)
+    """
+    config = _load_config(_pattern_root(path))
+    files = _go_files(path, config)
+    engine = _rule_engine(ctx, config)
+    engine.baseline = None
+    with _analysis_context(ctx) as analysis_ctx, _progress_reporter(ctx) as progress:
+        results = engine.analyze_files([str(f) for f in files], progress, analysis_ctx,
+                                       ctx.obj.get('package_timeout'))
+    if engine.incomplete:
+        # A partial snapshot would make the findings it missed look new on the next run
+        _report_incomplete(analysis_ctx, engine.incomplete)
+    target = output or str(config.baseline_path())
+    count = write_baseline(target, results, config.root)
+    console.print(f"[green]Recorded {count} finding(s) in {len(files)} file(s) to {target}[/green]")
+
+@cli.command('migration-report')
+@click.argument('path')
+@click.option('--rewrite', is_flag=True, help='Rewrite files whose OpenCensus usage is fully mechanical')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def migration_report_cmd(path, rewrite, output_format):
+    """
+    Map every OpenCensus and OpenTracing call site to its OpenTelemetry equivalent
+    
+    PATH: Go file or directory to inspect
+    """
+    files = _go_files(path, _load_config(_pattern_root(path)))
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8')) for f in files]
+    report = migration_report(sources)
+    
+    if output_format == 'json':
+        _print_json(report)
+    elif not report:
+        console.print("[green]No OpenCensus or OpenTracing usage found[/green]")
+    else:
+        table = Table(title=f"Migration Report: {path}")
+        table.add_column("Location")
+        table.add_column("Legacy API")
+        table.add_column("OpenTelemetry")
+        table.add_column("Auto", justify="center")
+        for entry in report:
+            table.add_row(f"{Path(entry['file']).name}:{entry['line']}", entry['legacy'],
+                          entry['opentelemetry'], "yes" if entry['automated'] else "")
+        console.print(table)
+        manual = len([e for e in report if not e['automated']])
+        console.print(f"{len(report)} call site(s), {manual} need manual migration")
+    
+    if rewrite:
+        rewritten = 0
+        for source in sources:
+            new_code = rewrite_opencensus(source)
+            if new_code is not None:
+                Path(source.path).write_text(new_code, encoding='utf-8')
+                rewritten += 1
+        console.print(f"[green]Rewrote {rewritten} file(s)[/green]")
+
+@cli.command('migrate-semconv')
+@click.argument('path', default='./...')
+@click.option('--from', 'from_version', required=True, help='Semconv version the code is on, e.g. v1.20')
+@click.option('--to', 'to_version', required=True, help='Semconv version to migrate to, e.g. v1.27')
+@click.option('--dry-run', is_flag=True,
+              help='Print the rewrites as a unified diff instead of writing them (to stderr with --format json)')
+@click.option('--format', 'output_format', default='rich',
+              type=click.Choice(['rich', 'json']), help='Output format')
+def migrate_semconv_cmd(path, from_version, to_version, dry_run, output_format):
+    """
+    Rename the attribute keys and semconv constants deprecated between two semconv versions and
+    move the semconv imports to the new one, listing the renames left to do by hand
+    
+    PATH: Go file, directory or package pattern to migrate (default: ./...)
+    """
+    try:
+        old, new = parse_version(from_version), parse_version(to_version)
+    except ValueError as e:
+        raise click.BadParameter(str(e))
+    if old >= new:
+        raise click.BadParameter(f"--to {to_version} is not after --from {from_version}")
+    files = _go_files(path, _load_config(_pattern_root(path)))
+    sources = [GoFile(str(f), f.read_text(encoding='utf-8')) for f in files]
+    packages = {}
+    for source in sources:
+        packages.setdefault(str(Path(source.path).parent), []).append(source)
+    for source in sources:
+        source.package_sources = packages[str(Path(source.path).parent)]
+    
+    report, rewritten = [], 0
+    for source in sources:
+        changes = plan_migration(source, old, new, import_path(to_version))
+        report += [{"file": source.path, "line": c.line, "old": c.old, "new": c.new, "note": c.note,
+                    "automated": c.edit is not None} for c in changes]
+        code, applied = migrate_file(source, changes)
+        if not applied:
+            continue
+        rewritten += 1
+        if dry_run:
+            # The JSON report has stdout to itself
+            (sys.stderr if output_format == 'json' else sys.stdout).write(fix_diff(source.path, source.code, code))
+        else:
+            Path(source.path).write_text(code, encoding='utf-8')
+    
+    out = err_console if dry_run else console
+    manual = [e for e in report if not e['automated']]
+    if output_format == 'json':
+        _print_json(report)
+    elif not report:
+        out.print(f"[green]Nothing to migrate between {from_version} and {to_version}[/green]")
+    elif manual:
+        table = Table(title=f"Left to migrate by hand: {path}")
+        table.add_column("Location")
+        table.add_column("Deprecated")
+        table.add_column("Replacement")
+        table.add_column("Note")
+        for entry in manual:
+            table.add_row(f"{os.path.relpath(entry['file'])}:{entry['line']}", entry['old'], entry['new'] or "",
+                          entry['note'])
+        out.print(table)
+    if output_format != 'json':
+        verb = "Would rewrite" if dry_run else "Rewrote"
+        out.print(f"{verb} {len(report) - len(manual)} key(s), constant(s) and import(s) in {rewritten} file(s); "
+                  f"{len(manual)} left to migrate by hand")
+
+def _export_otlp(results: Dict, root: str, endpoint: Optional[str], score_report: Optional[Dict] = None):
+    """Send findings to an OTLP/HTTP endpoint; a failed export is reported but doesn't fail the run"""
+    
+    try:
+        sent = otlp.export(results, root, endpoint, score_report)
+    except OSError as e:
+        err_console.print(f"[yellow]OTLP export failed: {e}[/yellow]")
+        return
+    err_console.print(f"[dim]Exported {sent} finding(s) over OTLP[/dim]")
+
+def _size(n: float) -> str:
+    for unit in ("B", "KB", "MB", "GB"):
+        if n < 1024:
+            return f"{n:.0f} {unit}" if unit == "B" else f"{n:.1f} {unit}"
+        n /= 1024
+    return f"{n:.1f} TB"
+
+def _print_json(data):
+    """JSON on stdout, untouched by rich's wrapping and markup so it always parses"""
+    click.echo(json.dumps(data, indent=2))
+
+def _load_config(path: str):
+    try:
+        return load_config(path)
+    except ConfigError as e:
+        console.print(f"[red]Invalid configuration: {e}[/red]")
+        sys.exit(1)
+
+def _shard_files(files: List[Path], root: str, shard: Optional[str]) -> List[Path]:
+    """The files of the --shard i/n packages, all of them without --shard"""
+    
+    if not shard:
+        return files
+    try:
+        index, count = parse_shard(shard)
+    except ValueError as e:
+        console.print(f"[red]Invalid --shard: {e}[/red]")
+        sys.exit(1)
+    return select_shard(files, root, index, count)
+
+def _pattern_root(path: str) -> str:
+    """Directory named by a Go package pattern ("./...", "./internal/...")"""
+    if path.endswith("..."):
+        return path[:-3].rstrip("/") or "."
+    return path
+
+def _go_files(path: str, config=None):
+    """Go files under path (or path itself), skipping vendored code and configured excludes"""
+    
+    path = _pattern_root(path)
+    target = Path(path)
+    if not target.exists():
+        console.print(f"[red]Path not found: {path}[/red]")
+        sys.exit(1)
+    if target.is_file():
+        return [target]
+    return sorted(f for f in target.rglob('*.go')
+                  if 'vendor' not in f.parts and not (config and config.is_excluded(str(f))))
+
+def _apply_fixes(file_path: str, code: str, violations, dry_run: bool = False) -> int:
+    """Apply the automated fixes carried by violations and rewrite the file.
+    With dry_run the file is left alone and a git-style patch is printed to stdout instead."""
+    
+    out = err_console if dry_run else console
+    fixes = [v.fix for v in violations if v.fix]
+    if not fixes:
+        out.print(f"[dim]No automated fixes available for {file_path}[/dim]")
+        return 0
+    
+    fixed_code, applied = apply_fixes(code, fixes)
+    if dry_run:
+        sys.stdout.write(fix_diff(file_path, code, fixed_code))
+    else:
+        with open(file_path, 'w', encoding='utf-8') as f:
+            f.write(fixed_code)
+    
+    skipped = len(fixes) - applied
+    verb = "Would apply" if dry_run else "Applied"
+    out.print(f"[green]{verb} {applied} fix(es) to {file_path}[/green]" +
+              (f" [yellow]({skipped} skipped due to overlapping edits)[/yellow]" if skipped else ""))
+    return applied
+
 def _output_rich_detailed(result: Dict, file_path: str, focus: Optional[str], confidence_threshold: float):
     """Rich detailed output with assessment-first format to match Juraci's requirements"""
     
@@ -227,7 +1490,7 @@ def _output_rich_detailed(result: Dict, file_path: str, focus: Optional[str], co
     total_patterns = result.get('total_patterns', 0)
     
     # Calculate compliance metrics
-    compliant_patterns = total_patterns - len(violations)
+    compliant_patterns = max(0, total_patterns - len(violations))
     compliance_rate = (compliant_patterns / total_patterns * 100) if total_patterns > 0 else 100
     
     # Generate assessment summary (Juraci's requested format)
@@ -360,75 +1623,61 @@ def _output_json(result: Dict):
         "language": result.get("language", "unknown"),
         "total_patterns_detected": result["total_patterns"],
         "summary": result["summary"],
-        "violations": [
-            {
-                "violation_id": v.violation_id,
-                "severity": v.severity,
-                "line_number": v.location.line_number,
-                "column": v.location.column,
-                "function_name": v.location.function_name,
-                "violation_type": v.violation_type,
-                "rule_violated": v.rule_violated,
-                "description": v.description,
-                "fix_suggestion": v.fix_suggestion,
-                "kb_reference": v.kb_reference,
-                "confidence": v.confidence,
-                "detection_method": v.detection_method,
-                "language": v.language,
-                "code_snippet": v.location.code_snippet,
-                "context_lines": v.location.context_lines
-            }
-            for v in result["violations"]
-        ],
+        "violations": [_violation_to_dict(v) for v in result["violations"]],
         "kb_sections_used": result["kb_sections_used"]
     }
     
-    console.print(json.dumps(json_result, indent=2))
+    _print_json(json_result)
+
+def _violation_to_dict(v) -> Dict:
+    return {
+        "violation_id": v.violation_id,
+        "rule_id": v.rule_id,
+        "severity": v.severity,
+        "file_path": v.file_path,
+        "line_number": v.location.line_number,
+        "column": v.location.column,
+        "function_name": v.location.function_name,
+        "violation_type": v.violation_type,
+        "rule_violated": v.rule_violated,
+        "description": v.description,
+        "fix_suggestion": v.fix_suggestion,
+        "kb_reference": v.kb_reference,
+        "confidence": v.confidence,
+        "detection_method": v.detection_method,
+        "language": v.language,
+        "code_snippet": v.location.code_snippet,
+        "context_lines": v.location.context_lines,
+        "fix": _fix_to_dict(v.fix)
+    }
 
-def _output_scan_rich(results: Dict, directory: str, focus: Optional[str]):
-    """Rich output for directory scan results"""
+def _fix_to_dict(fix) -> Optional[Dict]:
+    if fix is None:
+        return None
+    return {
+        "description": fix.description,
+        "edits": [{"start": e.start, "end": e.end, "new_text": e.new_text} for e in fix.edits]
+    }
+
+def _output_scan_rich(results: Dict, directory: str, focus: Optional[str], quiet: bool = False):
+    """Compiler-style findings with source snippets, followed by a per-package summary"""
     
     title = f"Directory Scan: {directory}"
     if focus:
         title += f" (Focus: {focus})"
     
     if not results:
-        console.print(Panel(
-            "No violations found in scanned files.",
-            title=title,
-            border_style="green"
-        ))
+        if not quiet:
+            console.print(Panel(
+                "No violations found in scanned files.",
+                title=title,
+                border_style="green"
+            ))
         return
     
-    # Overall summary
-    total_violations = sum(len(result['violations']) for result in results.values())
-    total_files = len(results)
-    
-    # Group by language
-    by_language = {}
-    for result in results.values():
-        lang = result.get('language', 'unknown')
-        by_language[lang] = by_language.get(lang, 0) + len(result['violations'])
-    
-    summary_text = f"Files with violations: {total_files}\n"
-    summary_text += f"Total violations: {total_violations}\n"
-    summary_text += f"Languages: {', '.join(by_language.keys())}"
-    
-    console.print(Panel(summary_text, title="Scan Summary", border_style="blue"))
-    
-    # Results per file
-    for file_path, result in results.items():
-        violations = result['violations']
-        language = result.get('language', 'unknown')
-        
-        console.print(f"\n[bold]{Path(file_path).name}[/bold] ({language.upper()}) - {len(violations)} violation(s)")
-        
-        for violation in violations[:3]:  # Show first 3 violations per file
-            severity_colors = {'critical': 'red', 'high': 'yellow', 'medium': 'blue', 'low': 'dim'}
-            color = severity_colors.get(violation.severity, 'white')
-            
-            console.print(f"   [{color}]{violation.severity.upper()}[/{color}]: {violation.description}")
-            console.print(f"   Line {violation.location.line_number}: {violation.fix_suggestion}")
+    renderer = TerminalRenderer(console, quiet=quiet)
+    renderer.findings({path: result['violations'] for path, result in results.items()})
+    renderer.package_summary({path: result['violations'] for path, result in results.items()})
 
 def _output_scan_json(results: Dict):
     """JSON output for directory scan"""
@@ -442,6 +1691,7 @@ def _output_scan_json(results: Dict):
             "violations": [
                 {
                     "violation_id": v.violation_id,
+                    "rule_id": v.rule_id,
                     "severity": v.severity,
                     "line_number": v.location.line_number,
                     "violation_type": v.violation_type,
@@ -455,7 +1705,7 @@ def _output_scan_json(results: Dict):
             ]
         }
     
-    console.print(json.dumps(output, indent=2))
+    _print_json(output)
 
 if __name__ == '__main__':
     cli()
\ No newline at end of file
@@ -0,0 +1,92 @@
+"""
+Human-oriented terminal output for findings: colored severities, the offending source line
+with a caret underline, and a per-package summary footer. Honors NO_COLOR through the
+console it is given and prints one line per finding in quiet mode.
+"""
+
+from pathlib import Path
+from typing import Dict, List
+
+from rich.console import Console
+from rich.markup import escape
+from rich.table import Table
+
+SEVERITY_ORDER = ["critical", "high", "medium", "low"]
+SEVERITY_STYLES = {"critical": "bold red", "high": "red", "medium": "yellow", "low": "cyan"}
+
+TAB_WIDTH = 4
+
+class TerminalRenderer:
+    def __init__(self, console: Console, quiet: bool = False):
+        self.console = console
+        self.quiet = quiet
+        self._sources: Dict[str, List[str]] = {}
+
+    def _source_line(self, path: str, line: int) -> str:
+        if path not in self._sources:
+            try:
+                self._sources[path] = Path(path).read_text(encoding="utf-8").split("\n")
+            except OSError:
+                self._sources[path] = []
+        lines = self._sources[path]
+        return lines[line - 1] if 0 < line <= len(lines) else ""
+
+    def finding(self, v) -> None:
+        style = SEVERITY_STYLES.get(v.severity, "white")
+        loc = v.location
+        where = f"{v.file_path}:{loc.line_number}:{loc.column}"
+        rule = v.rule_id or v.violation_type
+
+        if self.quiet:
+            self.console.print(f"{escape(where)}: [{style}]{v.severity}[/{style}] {escape(rule)}: {escape(v.description)}",
+                               highlight=False, soft_wrap=True)
+            return
+
+        self.console.print(f"[{style}]{v.severity.upper()}[/{style}] [bold]{escape(rule)}[/bold]: {escape(v.description)}",
+                           highlight=False)
+        gutter = " " * len(str(loc.line_number))
+        self.console.print(f"[dim]{gutter}-->[/dim] {escape(where)}", highlight=False)
+
+        text = self._source_line(v.file_path, loc.line_number) or loc.code_snippet
+        if text:
+            start = max(loc.column - 1, 0)
+            end = loc.end_column if loc.end_column >= loc.column else len(text.rstrip())
+            # Keep tabs in the padding so the carets line up once both lines are expanded
+            padding = "".join(ch if ch == "\t" else " " for ch in text[:start])
+            carets = "^" * max(1, len(text[start:end].expandtabs(TAB_WIDTH)))
+            self.console.print(f"[dim]{gutter} |[/dim]", highlight=False)
+            self.console.print(f"[dim]{loc.line_number} |[/dim] {escape(text.expandtabs(TAB_WIDTH))}", highlight=False)
+            self.console.print(f"[dim]{gutter} |[/dim] {padding.expandtabs(TAB_WIDTH)}[{style}]{carets}[/{style}]",
+                               highlight=False)
+        if v.fix_suggestion:
+            self.console.print(f"[dim]{gutter} =[/dim] [green]fix[/green]: {escape(v.fix_suggestion)}", highlight=False)
+        self.console.print()
+
+    def findings(self, results: Dict[str, List]) -> None:
+        for path in sorted(results):
+            for v in results[path]:
+                self.finding(v)
+
+    def package_summary(self, results: Dict[str, List]) -> None:
+        """Footer with finding counts per package (directory)"""
+
+        packages: Dict[str, Dict[str, int]] = {}
+        files: Dict[str, set] = {}
+        for path, violations in results.items():
+            package = str(Path(path).parent)
+            counts = packages.setdefault(package, {s: 0 for s in SEVERITY_ORDER})
+            files.setdefault(package, set()).add(path)
+            for v in violations:
+                counts[v.severity] = counts.get(v.severity, 0) + 1
+
+        total = sum(len(vs) for vs in results.values())
+        table = Table(title=f"{total} finding(s) in {len(packages)} package(s)", title_justify="left")
+        table.add_column("Package")
+        table.add_column("Files", justify="right")
+        for severity in SEVERITY_ORDER:
+            table.add_column(severity.title(), justify="right", style=SEVERITY_STYLES[severity])
+        for package in sorted(packages):
+            counts = packages[package]
+            table.add_row(package, str(len(files[package])),
+                          *[str(counts[s]) if counts[s] else "" for s in SEVERITY_ORDER])
+        self.console.print(table)
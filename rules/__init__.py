@@ -0,0 +1,11 @@
+"""
+Deterministic OpenTelemetry rule engine for Go sources.
+Rules live in per-signal packages and register themselves on import.
+"""
+
+from .base import CodeLocation, TelemetryViolation, Diagnostic, Rule, Fix, TextEdit
+from .registry import rule, all_rules, default_rules, get_rule
+from .engine import RuleEngine
+from .fixes import apply_fixes, fix_diff
+
+from . import traces, metrics, logs, sdk, privacy, consistency, suppression
@@ -0,0 +1,145 @@
+"""
+Machine interface for the Go analyzers in analyzers/, which expose every rule as a
+golang.org/x/tools/go/analysis.Analyzer. The Go side runs
+
+    python3 -m rules.analysis --all [--profile NAME] [--signals LIST] [--options JSON] --stdin FILE.go ...
+
+once per package, with the contents of the files as it sees them (an editor's unsaved buffers
+included) on stdin, and reads one JSON document with the diagnostics and their fixes, positioned
+by byte offset so they map directly onto token.Pos. `otel_cli.py gen-analyzers` regenerates
+analyzers/rules_gen.go from the registry.
+"""
+
+import argparse
+import json
+import re
+import sys
+from pathlib import Path
+from typing import Dict, List, Optional
+
+from .base import Rule, TelemetryViolation
+from .config import ConfigError, load_config, parse_signals
+from .engine import RuleEngine
+from .golang import GoFile
+from .registry import all_rules, get_rule
+
+def analyzer_name(rule_id: str) -> str:
+    """Go identifier used as the analyzer (and go vet flag) name for a rule"""
+    return rule_id.replace("-", "_")
+
+def _byte_offset(code: str, pos: int) -> int:
+    return len(code[:pos].encode("utf-8"))
+
+def _line_offset(code: str, line: int, column: int) -> int:
+    lines = code.split("\n")
+    return _byte_offset(code, sum(len(l) + 1 for l in lines[:line - 1]) + column - 1)
+
+def diagnostic_dict(v: TelemetryViolation, code: str) -> Dict:
+    loc = v.location
+    start = _line_offset(code, loc.line_number, loc.column)
+    end = _line_offset(code, loc.line_number, loc.end_column + 1) if loc.end_column else start
+    return {
+        "analyzer": analyzer_name(v.rule_id),
+        "rule_id": v.rule_id,
+        "file": v.file_path,
+        "offset": start,
+        "end": max(start, end),
+        "line": loc.line_number,
+        "column": loc.column,
+        "severity": v.severity,
+        "category": v.violation_type,
+        "message": v.description,
+        "suggestion": v.fix_suggestion,
+        "fixes": [{
+            "description": v.fix.description,
+            "edits": [{
+                "start": _byte_offset(code, e.start),
+                "end": _byte_offset(code, e.end),
+                "new_text": e.new_text,
+            } for e in v.fix.edits],
+        }] if v.fix else [],
+    }
+
+def run(paths: List[str], rule_ids: Optional[List[str]] = None, every_rule: bool = False,
+        profile: Optional[str] = None, options: Optional[Dict[str, Dict]] = None,
+        signals: Optional[List[str]] = None, contents: Optional[Dict[str, str]] = None) -> Dict:
+    """Diagnostics over paths of the given rules, of every rule the config doesn't disable
+    (every_rule, used when the analysis driver does the selecting), or of the config's selection.
+    options (rule id -> option values, from the driver's settings) override the config's; only
+    rules checking one of signals run (default: $OLLYGARDEN_SIGNALS, every signal when unset).
+    contents maps paths to the text to check instead of the file on disk."""
+
+    start = str(Path(paths[0]).parent) if paths else "."
+    config = load_config(start, profile, signals)
+    for rule_id, values in (options or {}).items():
+        rule = get_rule(rule_id)
+        if rule is None:
+            raise ConfigError(f"unknown rule '{rule_id}'")
+        unknown = sorted(set(values or {}) - set(rule.options))
+        if unknown:
+            raise ConfigError(f"rule '{rule_id}' has no option(s) {', '.join(unknown)}")
+        config.options.setdefault(rule_id, {}).update(values or {})
+    paths = [p for p in paths if not config.is_excluded(p)]
+    engine = RuleEngine.from_config(config)
+    # Custom and plugin rules have no analyzer of their own; the project_rules analyzer reports them
+    available = all_rules() + config.project_rules()
+    if rule_ids:
+        engine.rules = [r for r in available if r.rule_id in rule_ids or analyzer_name(r.rule_id) in rule_ids]
+    elif every_rule:
+        engine.rules = [r for r in available if r.rule_id not in config.disable and r.covers(config.signals)]
+    # Offsets are into the bytes the driver has, so \r\n must not be translated
+    code = {p: contents[p] if contents and p in contents else Path(p).read_bytes().decode("utf-8") for p in paths}
+    results = engine.analyze_sources([GoFile(p, code[p]) for p in paths])
+    diagnostics = []
+    for path, violations in sorted(results.items()):
+        diagnostics.extend(diagnostic_dict(v, code[path]) for v in violations)
+    return {"diagnostics": diagnostics, "incomplete": engine.incomplete}
+
+def _go_string(text: str) -> str:
+    return json.dumps(text, ensure_ascii=False)
+
+def render_go_registry(rules: List[Rule]) -> str:
+    """analyzers/rules_gen.go: one entry per rule, in registry order"""
+
+    lines = [
+        "// Code generated by `otel_cli.py gen-analyzers`; DO NOT EDIT.",
+        "",
+        "package analyzers",
+        "",
+        "var rules = []ruleInfo{",
+    ]
+    for r in rules:
+        doc = r.title + "\n\n" + re.sub(r'\s+', ' ', r.description).strip()
+        lines.append(f"\t{{ID: {_go_string(r.rule_id)}, Name: {_go_string(analyzer_name(r.rule_id))}, "
+                     f"Severity: {_go_string(r.severity)}, OptIn: {'true' if r.opt_in else 'false'}, "
+                     f"Signals: []string{{{', '.join(_go_string(s) for s in r.signals)}}}, "
+                     f"Doc: {_go_string(doc)}}},")
+    lines.append("}")
+    return "\n".join(lines) + "\n"
+
+def main(argv: Optional[List[str]] = None) -> int:
+    parser = argparse.ArgumentParser(prog="python -m rules.analysis", description=__doc__.split("\n\n")[0])
+    parser.add_argument("--rule", action="append", default=[], help="rule id or analyzer name; repeatable")
+    parser.add_argument("--all", action="store_true", help="run opt-in rules too, except those the config disables")
+    parser.add_argument("--profile", help="config profile (default: $OLLYGARDEN_PROFILE)")
+    parser.add_argument("--signals", help="comma-separated signals to check (default: $OLLYGARDEN_SIGNALS)")
+    parser.add_argument("--options", type=json.loads, default={},
+                        help="JSON object of rule id -> options, merged over the config's")
+    parser.add_argument("--stdin", action="store_true",
+                        help="read the files' contents from stdin, as a JSON object of path -> text")
+    parser.add_argument("files", nargs="*")
+    args = parser.parse_args(argv)
+    contents = json.load(sys.stdin) if args.stdin else None
+    try:
+        signals = parse_signals(args.signals) if args.signals is not None else None
+        report = run([f for f in args.files if f.endswith(".go")], args.rule, args.all, args.profile, args.options,
+                     signals, contents)
+    except ConfigError as e:
+        print(f"invalid configuration: {e}", file=sys.stderr)
+        return 2
+    json.dump(report, sys.stdout)
+    sys.stdout.write("\n")
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
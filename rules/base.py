@@ -0,0 +1,91 @@
+"""
+Core data model shared by the rule engine and the LLM analyzer
+"""
+
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional, Callable, Tuple
+
+SEVERITIES = ("critical", "high", "medium", "low")
+SIGNALS = ("traces", "metrics", "logs", "baggage", "resource")
+
+@dataclass
+class TextEdit:
+    """Replace code[start:end] with new_text (start == end inserts)"""
+    start: int
+    end: int
+    new_text: str
+
+@dataclass
+class Fix:
+    description: str
+    edits: List[TextEdit]
+
+@dataclass
+class CodeLocation:
+    line_number: int
+    column: int
+    function_name: str
+    code_snippet: str
+    context_lines: List[str]
+    # Last column (inclusive) of the offending expression on the same line; 0 if unknown
+    end_column: int = 0
+
+@dataclass
+class TelemetryViolation:
+    violation_id: str
+    severity: str
+    file_path: str
+    location: CodeLocation
+    violation_type: str
+    rule_violated: str
+    description: str
+    fix_suggestion: str
+    kb_reference: str
+    confidence: float
+    detection_method: str
+    language: str
+    rule_id: str = ""
+    fix: Optional[Fix] = None
+
+@dataclass
+class Diagnostic:
+    """A single finding produced by a rule check, positioned by character offset"""
+    pos: int
+    message: str
+    suggestion: str
+    confidence: float = 0.9
+    severity: Optional[str] = None
+    fix: Optional[Fix] = None
+    # End offset of the offending expression; defaults to the expression starting at pos
+    end: Optional[int] = None
+    # Set by project-scope rules, which report across several files
+    file: Optional[object] = None
+
+@dataclass
+class Rule:
+    rule_id: str
+    title: str
+    category: str
+    signal: str
+    severity: str
+    description: str
+    check: Callable
+    languages: Tuple[str, ...] = ("go",)
+    scope: str = "file"
+    kb_reference: str = "knowledge_base/instrumentation.md"
+    # Whether the rule attaches a Fix to its diagnostics
+    autofix: bool = False
+    # Off unless a project config lists it under rules.enable
+    opt_in: bool = False
+    # Tunable settings and their defaults. Checks of rules with options are called as
+    # check(target, options), with project config overrides merged over these defaults.
+    options: Dict[str, Any] = field(default_factory=dict)
+    # Go snippets (top level declarations) used to generate labeled fixtures
+    bad_example: str = ""
+    good_example: str = ""
+    # Every signal the rule checks, signal first; set by the registry
+    signals: Tuple[str, ...] = ()
+
+    def covers(self, signals) -> bool:
+        """Whether the rule checks any of signals (an empty selection means every signal)"""
+        return not signals or any(s in self.signals for s in signals)
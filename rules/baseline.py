@@ -0,0 +1,73 @@
+"""
+Baselines: a snapshot of the findings a codebase already has, so that adopting the rules (or
+enabling a new one) only reports what changes introduce. Findings are matched like `diff` does,
+by rule, file, function, code and message, so line shifts don't resurface them.
+"""
+
+import json
+from collections import Counter
+from pathlib import Path
+from typing import Dict, List, Tuple
+
+from .base import TelemetryViolation
+
+BASELINE_FILE = ".ollygarden-baseline.json"
+VERSION = 1
+
+def _relative(path: str, root: str) -> str:
+    try:
+        return Path(path).resolve().relative_to(Path(root).resolve()).as_posix()
+    except ValueError:
+        return Path(path).as_posix()
+
+class Baseline:
+    """Findings recorded by `baseline generate`, with file paths relative to root"""
+
+    def __init__(self, root: str, counts: Counter):
+        self.root = root
+        self.counts = counts
+
+    @classmethod
+    def load(cls, path: str, root: str) -> "Baseline":
+        with open(path, "r", encoding="utf-8") as f:
+            data = json.load(f)
+        if data.get("version") != VERSION:
+            raise ValueError(f"unsupported baseline version {data.get('version')!r}")
+        counts = Counter()
+        for entry in data.get("findings", []):
+            key = (entry["rule_id"], entry["file"], entry["function"], entry["code"], entry["message"])
+            counts[key] += entry.get("count", 1)
+        return cls(root, counts)
+
+    def key(self, v: TelemetryViolation) -> Tuple:
+        return (v.rule_id, _relative(v.file_path, self.root), v.location.function_name,
+                v.location.code_snippet, v.description)
+
+    def new_findings(self, violations: List[TelemetryViolation]) -> List[TelemetryViolation]:
+        """The violations of one file that the baseline doesn't account for. Identical findings
+        are matched by count, so a second copy of a baselined finding is still new."""
+
+        remaining = Counter({k: n for k, n in self.counts.items()
+                             if violations and k[1] == _relative(violations[0].file_path, self.root)})
+        new = []
+        for v in violations:
+            key = self.key(v)
+            if remaining[key] > 0:
+                remaining[key] -= 1
+            else:
+                new.append(v)
+        return new
+
+def write_baseline(path: str, results: Dict[str, List[TelemetryViolation]], root: str) -> int:
+    """Record every finding in results; returns how many were written"""
+
+    baseline = Baseline(root, Counter())
+    counts = Counter(baseline.key(v) for vs in results.values() for v in vs if v.rule_id)
+    findings = [
+        {"rule_id": k[0], "file": k[1], "function": k[2], "code": k[3], "message": k[4], "count": n}
+        for k, n in sorted(counts.items())
+    ]
+    with open(path, "w", encoding="utf-8") as f:
+        json.dump({"version": VERSION, "findings": findings}, f, indent=2)
+        f.write("\n")
+    return sum(counts.values())
@@ -0,0 +1,228 @@
+"""
+Telemetry budget: a rough estimate of the spans and bytes each request produces, from the span
+sites the code has, the loops around them (and around the calls leading to them) and the
+attributes and events they record. Good enough to see which call sites dominate the volume
+before the bill does; the sizes approximate OTLP protobuf encoding.
+"""
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Set, Tuple
+
+from .golang import GoFile, GoFunc, SpanStart
+from .traces.attributes import attribute_calls
+
+# Trace and span IDs, parent, timestamps, kind, status and the field tags around them
+SPAN_OVERHEAD = 60
+# Tags and lengths around one attribute, and around one event plus its timestamp
+ATTRIBUTE_OVERHEAD = 6
+EVENT_OVERHEAD = 14
+# Values whose size isn't known statically
+DYNAMIC_STRING = 32
+VALUE_SIZES = {"Int": 8, "Int64": 8, "Float64": 8, "Bool": 1}
+# exception.type and exception.message of RecordError, and the stack trace WithStackTrace adds
+RECORDED_ERROR = 120
+STACK_TRACE = 1500
+
+@dataclass
+class SiteCost:
+    """One span site and what a single execution of it costs"""
+    source: GoFile
+    start: SpanStart
+    function: str
+    bytes_per_span: int
+    # Spans per execution of the enclosing function, from the loops around the site
+    per_call: float
+
+    @property
+    def where(self) -> str:
+        return f"{self.source.path}:{self.source.line_of(self.start.call.start)}"
+
+    @property
+    def name(self) -> str:
+        return self.start.name if self.start.name is not None else (self.start.name_arg.text.strip() if self.start.name_arg else "?")
+
+@dataclass
+class EntryCost:
+    """What one call of an entry point (a function nothing in the code calls: a handler, a
+    consumer, main) costs, with the sites it reaches"""
+    function: str
+    where: str
+    spans: float = 0.0
+    bytes: float = 0.0
+    # site where -> spans per request
+    sites: Dict[str, float] = field(default_factory=dict)
+
+def _loop_iterations(source: GoFile, loop: Tuple[int, int, int], default: int) -> int:
+    """Iterations of a loop with a literal bound (i < 3, range of a 3 element literal), else default"""
+
+    header = source.masked[loop[0]:loop[1]]
+    m = re.search(r'<=?\s*(\d+)\s*;', header)
+    if m:
+        return max(1, int(m.group(1)) + (1 if "<=" in m.group(0) else 0))
+    m = re.search(r'\brange\s+(\d+)\s*$', header.strip())
+    if m:
+        return max(1, int(m.group(1)))
+    return default
+
+def loop_multiplier(source: GoFile, fn: GoFunc, pos: int, default: int, since: int = -1) -> float:
+    """Product of the iterations of the loops of fn around pos (entered after since)"""
+
+    factor = 1.0
+    for loop in source.loops(fn.body_start, fn.body_end):
+        if loop[1] < pos < loop[2] and loop[0] > since:
+            factor *= _loop_iterations(source, loop, default)
+    return factor
+
+def _value_size(constructor: str, value_text: str) -> int:
+    kind = constructor.rsplit(".", 1)[-1]
+    if kind in VALUE_SIZES:
+        return VALUE_SIZES[kind]
+    literal = re.fullmatch(r'\s*"((?:[^"\\]|\\.)*)"\s*', value_text)
+    size = len(literal.group(1)) if literal else DYNAMIC_STRING
+    return size * 4 if kind.endswith("Slice") else size
+
+def _attribute_bytes(source: GoFile, start: int, end: int) -> int:
+    total = 0
+    for call in attribute_calls(source):
+        if start <= call.start < end and len(call.args) >= 2:
+            key = call.args[0].literal or call.args[0].text.strip()
+            total += ATTRIBUTE_OVERHEAD + len(key) + _value_size(call.name, call.args[1].text)
+    # semconv helpers and prebuilt key-values: the size of a typical key and value
+    for m in re.finditer(r'\bsemconv\w*\.\w+\(|\w+Key\.\w+\(', source.masked[start:end]):
+        total += ATTRIBUTE_OVERHEAD + 16 + DYNAMIC_STRING
+    return total
+
+def _span_bytes(source: GoFile, start: SpanStart, fn: GoFunc, default_iterations: int) -> int:
+    """Size of one span from the site: its name, start options, and what the span variable
+    records in the function, events in loops counted per iteration"""
+
+    size = SPAN_OVERHEAD + len(start.name if start.name is not None else "x" * DYNAMIC_STRING)
+    size += _attribute_bytes(source, start.call.open_paren, start.call.close_paren)
+    var = start.span_var
+    if not var or var == "_":
+        return size
+    site_loop = source.enclosing_loop(start.call.start, fn)
+    since = site_loop[0] if site_loop else -1
+    for call in source.calls(re.escape(var) + r'\.(?:SetAttributes|AddEvent|RecordError)', start.call.end, fn.body_end):
+        times = loop_multiplier(source, fn, call.start, default_iterations, since)
+        method = call.name.rsplit(".", 1)[-1]
+        if method == "SetAttributes":
+            size += times * _attribute_bytes(source, call.open_paren, call.close_paren)
+        elif method == "AddEvent":
+            name = call.args[0].literal if call.args else None
+            size += times * (EVENT_OVERHEAD + len(name if name is not None else "x" * DYNAMIC_STRING)
+                             + _attribute_bytes(source, call.open_paren, call.close_paren))
+        else:
+            stack = STACK_TRACE if "WithStackTrace(true)" in source.masked[call.open_paren:call.close_paren] else 0
+            size += times * (EVENT_OVERHEAD + RECORDED_ERROR + stack)
+    return int(size)
+
+class Budget:
+    """Span sites and the call graph between the functions of the code, by function name"""
+
+    def __init__(self, sources: List[GoFile], loop_iterations: int = 10):
+        self.loop_iterations = loop_iterations
+        self.sources = [s for s in sources if not s.path.endswith("_test.go")]
+        self.functions: Dict[str, List[Tuple[GoFile, GoFunc]]] = {}
+        for source in self.sources:
+            for fn in source.functions:
+                if not fn.is_literal:
+                    self.functions.setdefault(fn.name, []).append((source, fn))
+        self.sites: Dict[str, List[SiteCost]] = {}
+        for source in self.sources:
+            for start in source.span_starts:
+                fn = source.func_at(start.call.start)
+                if fn is None:
+                    continue
+                self.sites.setdefault(fn.name, []).append(SiteCost(
+                    source, start, fn.name, _span_bytes(source, start, fn, loop_iterations),
+                    loop_multiplier(source, fn, start.call.start, loop_iterations),
+                ))
+        # caller -> [(callee, calls per execution of the caller)]
+        self.callees: Dict[str, List[Tuple[str, float]]] = {}
+        called: Set[str] = set()
+        pattern = re.compile(r'(?<![\w])(?:\w+\.)?(\w+)\s*\(')
+        for name, defs in self.functions.items():
+            for source, fn in defs:
+                for m in pattern.finditer(source.masked, fn.body_start, fn.body_end):
+                    callee = m.group(1)
+                    if callee in self.functions and callee != name:
+                        self.callees.setdefault(name, []).append(
+                            (callee, loop_multiplier(source, fn, m.start(), loop_iterations)))
+                        called.add(callee)
+        self.entries = sorted(n for n in self.functions if n not in called and self._reaches_span(n, set()))
+
+    def _reaches_span(self, name: str, seen: Set[str]) -> bool:
+        if name in self.sites:
+            return True
+        seen.add(name)
+        return any(c not in seen and self._reaches_span(c, seen) for c, _ in self.callees.get(name, []))
+
+    def _collect(self, name: str, times: float, entry: EntryCost, stack: Set[str]):
+        """Add the spans of one call chain; recursion is followed once"""
+
+        if name in stack:
+            return
+        stack = stack | {name}
+        for site in self.sites.get(name, []):
+            spans = times * site.per_call
+            entry.spans += spans
+            entry.bytes += spans * site.bytes_per_span
+            entry.sites[site.where] = entry.sites.get(site.where, 0.0) + spans
+        for callee, factor in self.callees.get(name, []):
+            self._collect(callee, times * factor, entry, stack)
+
+    def entry_costs(self) -> List[EntryCost]:
+        costs = []
+        for name in self.entries:
+            source, fn = self.functions[name][0]
+            entry = EntryCost(name, f"{source.path}:{source.line_of(fn.start)}")
+            self._collect(name, 1.0, entry, set())
+            costs.append(entry)
+        return sorted(costs, key=lambda e: -e.bytes)
+
+def budget_report(sources: List[GoFile], qps: float = 100.0, loop_iterations: int = 10, root: str = ".") -> Dict:
+    """Spans and bytes per request of every entry point, what they come to at qps requests per
+    second each, and the span sites ranked by their share of the bytes"""
+
+    budget = Budget(sources, loop_iterations)
+    entries = budget.entry_costs()
+    sites = {site.where: site for found in budget.sites.values() for site in found}
+    site_spans: Dict[str, float] = {}
+    for entry in entries:
+        for where, spans in entry.sites.items():
+            site_spans[where] = site_spans.get(where, 0.0) + spans
+    total_bytes = sum(site_spans[w] * sites[w].bytes_per_span for w in site_spans) or 1.0
+
+    def relative(where: str) -> str:
+        try:
+            return Path(where).resolve().relative_to(Path(root).resolve()).as_posix()
+        except ValueError:
+            return where
+
+    return {
+        "qps": qps,
+        "loop_iterations": loop_iterations,
+        "entry_points": [{
+            "function": e.function,
+            "location": relative(e.where),
+            "spans_per_request": round(e.spans, 1),
+            "bytes_per_request": round(e.bytes),
+            "spans_per_second": round(e.spans * qps, 1),
+            "bytes_per_day": round(e.bytes * qps * 86400),
+        } for e in entries],
+        "sites": sorted(({
+            "location": relative(where),
+            "function": sites[where].function,
+            "span_name": sites[where].name,
+            "spans_per_request": round(spans, 1),
+            "bytes_per_span": sites[where].bytes_per_span,
+            "share": round(100.0 * spans * sites[where].bytes_per_span / total_bytes, 1),
+        } for where, spans in site_spans.items()), key=lambda s: -s["share"]),
+        "total": {
+            "spans_per_second": round(sum(e.spans for e in entries) * qps, 1),
+            "bytes_per_day": round(sum(e.bytes for e in entries) * qps * 86400),
+        },
+    }
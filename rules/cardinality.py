@@ -0,0 +1,412 @@
+"""
+Bounded-value proofs for Go string expressions.
+
+A name built at runtime is only low cardinality if every piece of it comes from a small, fixed
+set: literals and constants, values of a typed enum, the cases of a switch, the keys of a
+package-level map literal that is never written to, or a handful of request properties that are
+bounded by construction (the HTTP method, the matched route template).
+
+Values flow through calls: a function of the same package (`spanNameFor(op)`) takes the values
+of its return statements, with each parameter standing for the argument of the call being
+followed. Constants and package-level variables are looked up in every file of the package.
+"""
+
+import re
+from contextlib import contextmanager
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from .conventions import HTTP_METHODS
+from .golang import Call, GoFile, GoFunc, mask_code, match_bracket, parse_params, split_args, split_plus, string_literal
+
+# Request properties with a fixed set of values: method and matched route template
+BOUNDED_PROPERTIES = [
+    (r'\w+(?:\.Request)?\.Method(?:\(\))?|\w+\.Request\(\)\.Method', set(HTTP_METHODS)),
+    (r'\w+\.Pattern|\w+\.FullPath\(\)|\w+\.Path\(\)|\w+\.Route\(\)\.Path', {"<route template>"}),
+]
+# Parameter names that identify an entity rather than an operation
+ID_LIKE = r'(?i).*(?:id|uuid|email|path|url|uri|key|token|query)|.*I[Dd]s?'
+
+# A fmt verb: %% or flags, width, precision and the verb letter
+FORMAT_VERB = r'%%|%[-+# 0]*(?:\d+|\*)?(?:\.(?:\d+|\*))?[a-zA-Z]'
+
+# What an unbounded operand most likely holds, for messages; ID_LIKE is tried on its last name
+CULPRIT_KINDS = [
+    (r'time\.(?:Now|Since|Unix\w*)\(.*|.*\.(?:Unix|UnixNano|UnixMilli|UnixMicro|Nanoseconds)\(\)'
+     r'|.*\.Format\(\s*time\.\w+\s*\)', "a timestamp"),
+    (r'\w+(?:\.Request)?\.(?:URL(?:\.\w+)?|RequestURI|URL\.String\(\)|Host|RemoteAddr)', "part of the request URL"),
+    (r'err|\w+\.Error\(\)', "an error message"),
+    (r'(?:rand|uuid)\.\w+\(.*\)|.*\.(?:NewString|NewRandom)\(\)', "a random value"),
+]
+# Verbs that render their operand as a number
+NUMERIC_VERBS = "bcdoOxXeEfFgG"
+
+# Calls that map one value to one value
+PASSTHROUGH = r'strings\.(?:ToLower|ToUpper|TrimSpace|Title)|string'
+
+class Bounds:
+    """Proves, per file, which expressions can only take a bounded set of values.
+    values() returns that set (placeholders like "<Name>" stand for values not known
+    statically), or None with the unbounded piece in self.culprit."""
+
+    def __init__(self, source: GoFile, limit: int = 20):
+        self.source = source
+        self.limit = limit
+        self.culprit = ""
+        # Format verb the culprit was rendered with, when it's a fmt.Sprintf operand
+        self.verb = ""
+        # Parameters the value depends on; their callers decide the cardinality
+        self.params: Set[str] = set()
+        self._enums: Dict[str, Dict[str, List[str]]] = {}
+        # (file, function start, parameter) -> (file, text, offset) of the argument it is bound to
+        self._bindings: Dict[Tuple[str, int, str], Tuple[GoFile, str, int]] = {}
+
+    @contextmanager
+    def _in(self, source: GoFile) -> Iterator[None]:
+        """Evaluate in another file of the package; offsets are that file's while inside"""
+        previous, self.source = self.source, source
+        try:
+            yield
+        finally:
+            self.source = previous
+
+    def values(self, expr: str, pos: int) -> Optional[Set[str]]:
+        self.culprit = ""
+        self.verb = ""
+        self.params = set()
+        self._bindings = {}
+        self._at, self._culprit_source = (self.source, pos), self.source
+        return self._eval(expr.strip(), pos, 0)
+
+    def values_at_call(self, expr: str, pos: int, callee: GoFile, fn: GoFunc, call: Call) -> Optional[Set[str]]:
+        """values() of expr at pos in fn of callee, with the parameters of fn taking the
+        arguments of call, a call of fn in this file"""
+
+        self.culprit = ""
+        self.verb = ""
+        self.params = set()
+        self._bindings = {(callee.path, fn.start, param): (self.source, arg.text, arg.start)
+                          for (param, _), arg in zip(parse_params(fn.params), call.args) if param and param != "_"}
+        self._at, self._culprit_source = (self.source, call.start), self.source
+        with self._in(callee):
+            return self._eval(expr.strip(), pos, 0)
+
+    def culprit_kind(self) -> str:
+        """What the culprit of the last values() probably holds ("a timestamp", "an ID"), if known"""
+        return _culprit_kind(self.culprit, self.verb)
+
+    def explain(self) -> str:
+        """The culprit of the last values() that returned None, with what it probably holds and
+        the verb that formatted it, e.g. time.Now().Unix() (a timestamp, formatted with %d)"""
+
+        kind = self.culprit_kind()
+        source, pos = self._at
+        fn = source.func_at(pos, include_literals=True) if self._culprit_source is source else None
+        if fn is not None and re.fullmatch(r'\w+', self.culprit) and re.search(
+                r'\bfor\s+(?:\w+\s*,\s*)?' + re.escape(self.culprit) + r'(?:\s*,\s*\w+)?\s*:=', source.masked[fn.body_start:pos]):
+            kind = "a loop variable"
+        details = [d for d in (kind, f"formatted with {self.verb}" if self.verb else "") if d]
+        return f"{self.culprit} ({', '.join(details)})" if details else self.culprit
+
+    def _unbounded(self, expr: str) -> None:
+        if not self.culprit:
+            self.culprit = expr
+            self._culprit_source = self.source
+        return None
+
+    def _eval(self, expr: str, pos: int, depth: int) -> Optional[Set[str]]:
+        expr = expr.strip()
+        while expr.startswith("(") and match_bracket(mask_code(expr), 0) == len(expr) - 1:
+            expr = expr[1:-1].strip()
+        if depth > 8:
+            return self._unbounded(expr)
+        literal = string_literal(expr)
+        if literal is not None:
+            return {literal}
+        parts = split_plus(expr)
+        if len(parts) > 1:
+            return self._product([self._eval(p, pos, depth + 1) for p in parts], expr)
+        for pattern, values in BOUNDED_PROPERTIES:
+            if re.fullmatch(pattern, expr):
+                return set(values)
+        m = re.fullmatch(r'fmt\.Sprintf\s*\((.*)\)', expr, re.S)
+        if m:
+            args = [m.group(1)[s:e] for s, e in split_args(mask_code(m.group(1)), 0, len(m.group(1)))]
+            return self._sprintf(args, pos, depth, expr)
+        m = re.fullmatch(r'(?:' + PASSTHROUGH + r')\s*\((.*)\)', expr, re.S)
+        if m:
+            return self._eval(m.group(1), pos, depth + 1)
+        m = re.fullmatch(r'(\w+)\.String\(\)', expr)
+        if m:
+            return self._eval(m.group(1), pos, depth + 1)
+        if re.fullmatch(r'\w+', expr):
+            return self._identifier(expr, pos, depth)
+        m = re.fullmatch(r'(?:(\w+)\.)?(\w+)\s*\(', expr[:expr.find("(") + 1]) if expr.endswith(")") else None
+        if m and match_bracket(mask_code(expr), expr.find("(")) == len(expr) - 1:
+            return self._call(m.group(1), m.group(2), expr, pos, depth)
+        return self._unbounded(expr)
+
+    def _call(self, qualifier: Optional[str], name: str, expr: str, pos: int, depth: int) -> Optional[Set[str]]:
+        """Values returned by a function or method of the package, called as expr at pos"""
+
+        if qualifier in self.source.imports:
+            return self._unbounded(expr)
+        for source in self.source.package_sources:
+            for fn in source.functions:
+                if fn.is_literal or fn.name != name or bool(fn.receiver_type) != bool(qualifier):
+                    continue
+                open_paren = expr.find("(")
+                masked = mask_code(expr)
+                args = [(expr[s:e], pos) for s, e in split_args(masked, open_paren + 1, len(expr) - 1)]
+                params = [p for p, _ in parse_params(fn.params)]
+                for param, (text, _) in zip(params, args):
+                    if param and param != "_":
+                        self._bindings[(source.path, fn.start, param)] = (self.source, text, pos)
+                with self._in(source):
+                    returned = [self._eval(e, p, depth + 1) for e, p in self._returns(fn)]
+                if not returned:
+                    return self._unbounded(expr)
+                return self._union(returned, expr)
+        return self._unbounded(expr)
+
+    def _returns(self, fn: GoFunc) -> List[Tuple[str, int]]:
+        """(expression, offset) of the first result of each return statement of fn, leaving out
+        those of function literals inside it"""
+
+        masked = self.source.masked
+        found = []
+        for m in re.finditer(r'\breturn\b[ \t]*', masked[fn.body_start:fn.body_end]):
+            start = fn.body_start + m.end()
+            if self.source.func_at(start, include_literals=True) is not fn:
+                continue
+            end, nesting = start, 0
+            while end < fn.body_end:
+                ch = masked[end]
+                if ch in "([{":
+                    nesting += 1
+                elif ch in ")]}":
+                    if nesting == 0:
+                        break
+                    nesting -= 1
+                elif ch in "\n;" and nesting == 0:
+                    break
+                end += 1
+            results = split_args(masked, start, end)
+            if results:
+                s, e = results[0]
+                found.append((self.source.code[s:e], s))
+        return found
+
+    def _sprintf(self, args: List[str], pos: int, depth: int, expr: str) -> Optional[Set[str]]:
+        """Values of fmt.Sprintf(args...): each verb of a literal format replaced by the values
+        of its operand"""
+
+        layout = string_literal(args[0]) if args else None
+        if layout is None:
+            return self._product([self._eval(a, pos, depth + 1) for a in args], expr)
+        pieces, operands, last = [], iter(args[1:]), 0
+        for verb in re.finditer(FORMAT_VERB, layout):
+            pieces.append({layout[last:verb.start()]})
+            last = verb.end()
+            if verb.group() == "%%":
+                pieces.append({"%"})
+                continue
+            operand = next(operands, None)
+            if operand is None:
+                pieces.append({verb.group()})
+            elif verb.group().endswith("t"):
+                pieces.append({"true", "false"})
+            elif verb.group().endswith("T"):
+                # The operand's type, not its value
+                pieces.append({f"<type of {operand.strip()}>"})
+            else:
+                values = self._eval(operand, pos, depth + 1)
+                if values is None and not self.verb:
+                    self.verb = verb.group()
+                pieces.append(values)
+                if values is None:
+                    break
+        pieces.append({layout[last:]})
+        return self._product(pieces, expr)
+
+    def _product(self, sets: List[Optional[Set[str]]], expr: str) -> Optional[Set[str]]:
+        result = {""}
+        for values in sets:
+            if values is None:
+                return None
+            result = {a + b for a in result for b in values}
+            if len(result) > self.limit:
+                return self._unbounded(expr)
+        return result
+
+    def _identifier(self, name: str, pos: int, depth: int) -> Optional[Set[str]]:
+        source = self.source
+        fn = source.func_at(pos, include_literals=True)
+        if fn is not None:
+            for finder in (self._switch_case, self._range_key):
+                found = finder(fn, name, pos, depth)
+                if found is not None:
+                    return found
+            assigned = self._assignments(fn, name, pos)
+            if assigned is not None:
+                if not assigned:
+                    return self._unbounded(name)
+                return self._union([self._eval(e, p, depth + 1) for e, p in assigned], name)
+            for outer in source.functions:
+                if not outer.contains(pos):
+                    continue
+                for param, typ in parse_params(outer.params):
+                    if param != name:
+                        continue
+                    binding = self._bindings.get((source.path, outer.start, name))
+                    if binding is not None:
+                        caller, text, at = binding
+                        with self._in(caller):
+                            return self._eval(text, at, depth + 1)
+                    if self.enum_values(typ):
+                        return set(self.enum_values(typ))
+                    if re.fullmatch(ID_LIKE, name) or typ == "error":
+                        return self._unbounded(name)
+                    self.params.add(name)
+                    return {f"<{name}>"}
+        # Package level, in this file or another of the package: a constant, or a variable that
+        # must be initialized once and never written
+        for declared in [source] + [s for s in source.package_sources if s is not source]:
+            at = pos if declared is source else 0
+            if name in declared.constants:
+                with self._in(declared):
+                    value = self._eval(declared.constants[name], at, depth + 1)
+                return value if value is not None else {f"<{name}>"}
+            m = re.search(r'^var\s+' + re.escape(name) + r'\b[^=\n]*(=\s*(.+))?$', declared.masked, re.M)
+            if m:
+                if not m.group(1) or self._written(name):
+                    return self._unbounded(name)
+                with self._in(declared):
+                    return self._eval(declared.code[m.start(2):m.end(2)], at, depth + 1)
+        # An iota constant, or a constant of another package
+        return {f"<{name}>"}
+
+    def _union(self, sets: List[Optional[Set[str]]], expr: str) -> Optional[Set[str]]:
+        result = set()
+        for values in sets:
+            if values is None:
+                return None
+            result |= values
+        return result if len(result) <= self.limit else self._unbounded(expr)
+
+    def _assignments(self, fn: GoFunc, name: str, pos: int) -> Optional[List[Tuple[str, int]]]:
+        """(expression, offset) of every value a local name in fn may hold at pos; None when the
+        name isn't a local, [] when it is one but gets a value we can't follow. An assignment in a
+        block that holds pos replaces the values assigned before it."""
+
+        masked = self.source.masked
+        body = masked[fn.body_start:pos]
+        assigned = []
+        for m in re.finditer(r'(?<![\w.])' + re.escape(name) + r'\b(\s*,[\w\s,]*)?(\s+[\w.\[\]*]+)?\s*(:=|=)(?!=)', body):
+            if m.group(1):
+                return []
+            start = fn.body_start + m.end()
+            end = min(i for i in (masked.find("\n", start), masked.find(";", start), len(masked)) if i != -1)
+            assigned.append((fn.body_start + m.start(), self.source.code[start:end].strip().rstrip("{").strip(), start))
+        for m in re.finditer(r'\bvar\s+' + re.escape(name) + r'\s+[\w.]+\s*$', body, re.M):
+            assigned.append((fn.body_start + m.start(), '""', fn.body_start))
+        if not assigned:
+            return None
+        if re.search(r'(?:&|\.Scan\w*\s*\([^)]*)\b' + re.escape(name) + r'\b', body):
+            return []
+        found = []
+        for at, text, start in sorted(assigned):
+            if self.source.block_end(at, fn) >= pos:
+                found = []
+            found.append((text, start))
+        return found
+
+    def _switch_case(self, fn: GoFunc, name: str, pos: int, depth: int) -> Optional[Set[str]]:
+        """Values of name inside the case clause of `switch name {` that contains pos"""
+
+        masked = self.source.masked
+        for m in re.finditer(r'\bswitch\s+' + re.escape(name) + r'\s*\{', masked[fn.body_start:pos]):
+            open_brace = fn.body_start + m.end() - 1
+            close = match_bracket(masked, open_brace)
+            if close < pos:
+                continue
+            clauses = list(re.finditer(r'^\s*(case\s+([^:]*)|default)\s*:', masked[open_brace:close], re.M))
+            current = None
+            for clause in clauses:
+                if open_brace + clause.start() < pos:
+                    current = clause
+            if current is None or current.group(1) == "default":
+                return None
+            text = self.source.code[open_brace + current.start(2):open_brace + current.end(2)]
+            values = [self._eval(v, pos, depth + 1) for v in text.split(",")]
+            return self._union(values, name)
+        return None
+
+    def _range_key(self, fn: GoFunc, name: str, pos: int, depth: int) -> Optional[Set[str]]:
+        """Keys of a package-level map literal (or values of a slice literal) ranged over with name"""
+
+        masked = self.source.masked
+        for m in re.finditer(r'\bfor\s+(\w+)(?:\s*,\s*(\w+))?\s*:=\s*range\s+(\w+)\s*\{', masked[fn.body_start:pos]):
+            if name not in (m.group(1), m.group(2)) or m.group(1) == "_" and name != m.group(2):
+                continue
+            collection = m.group(3)
+            if self._written(collection):
+                return None
+            literal = re.search(r'^var\s+' + collection + r'\s*=\s*(map|\[\])', masked, re.M)
+            if not literal:
+                return None
+            open_brace = masked.find("{", literal.end())
+            entries = [self.source.code[s:e] for s, e in split_args(masked, open_brace + 1, match_bracket(masked, open_brace))]
+            if literal.group(1) == "map" and name == m.group(1):
+                exprs = [e.split(":", 1)[0] for e in entries if ":" in e]
+            elif literal.group(1) == "[]" and name == m.group(2):
+                exprs = entries
+            else:
+                return None
+            return self._union([self._eval(e, pos, depth + 1) for e in exprs if e.strip()], name)
+        return None
+
+    def _written(self, name: str) -> bool:
+        """Whether a package-level variable is assigned or written to inside any function of the package"""
+
+        return any(re.search(r'\b' + re.escape(name) + r'\s*(?:\[[^\]]*\])?\s*(?:=|\+=)(?!=)|\bdelete\s*\(\s*' + re.escape(name) + r'\b',
+                             source.masked[fn.body_start:fn.body_end])
+                   for source in self.source.package_sources for fn in source.functions)
+
+    def enum_values(self, type_name: str) -> List[str]:
+        """Constants declared with type_name, as their literal values or "<Name>" placeholders"""
+
+        names = self.enums().get(type_name.strip(), [])
+        values = []
+        for name in names:
+            literal = string_literal(self.source.constants.get(name, ""))
+            values.append(literal if literal is not None else f"<{name}>")
+        return values
+
+    def enums(self) -> Dict[str, List[str]]:
+        """Type name -> constants of that type, following implicit repetition in const blocks"""
+
+        if self.source.path not in self._enums:
+            enums = self._enums[self.source.path] = {}
+            for m in re.finditer(r'^const\s*\(', self.source.masked, re.M):
+                close = match_bracket(self.source.masked, m.end() - 1)
+                current = None
+                for line in self.source.masked[m.end():close].split("\n"):
+                    spec = re.match(r'\s*(\w+)(?:\s+([\w.]+))?\s*(=)?', line)
+                    if not spec:
+                        continue
+                    if spec.group(3):
+                        current = spec.group(2)
+                    if current and not (spec.group(2) and spec.group(2) != current):
+                        enums.setdefault(current, []).append(spec.group(1))
+        return self._enums[self.source.path]
+
+def _culprit_kind(culprit: str, verb: str) -> str:
+    for pattern, kind in CULPRIT_KINDS:
+        if re.fullmatch(pattern, culprit, re.S):
+            return kind
+    last = re.findall(r'\w+', culprit)
+    if last and re.fullmatch(ID_LIKE, last[-1]):
+        return "an ID"
+    if verb and verb[-1] in NUMERIC_VERBS:
+        return "a number"
+    return ""
@@ -0,0 +1,414 @@
+"""
+A subset of CEL (https://github.com/google/cel-spec), the expression language of custom rules.
+
+Supported: string, int, float, bool, null, list and map literals; field access and indexing;
+! - * / % + < <= > >= == != in && || and ?:; the functions size, int, string and has; the
+string methods startsWith, endsWith, contains, matches, lowerAscii and upperAscii; and the
+macros all, exists, exists_one, filter and map over lists and maps. && and || short-circuit
+and, as in CEL, a side that fails to evaluate is ignored when the other side decides.
+Expressions are parsed once, when the config is loaded, so syntax errors surface there.
+"""
+
+import re
+import string
+from typing import Any, Dict, Iterator, List, Optional, Set, Tuple
+
+TOKEN = re.compile(r'''
+    (?P<space>\s+)
+  | (?P<float>\d+\.\d+(?:[eE][+-]?\d+)?|\d+[eE][+-]?\d+)
+  | (?P<int>0x[0-9a-fA-F]+|\d+)
+  | (?P<string>[rR]?(?:"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'))
+  | (?P<ident>[A-Za-z_]\w*)
+  | (?P<op>&&|\|\||==|!=|<=|>=|[-+*/%!<>?:.,()\[\]{}])
+''', re.X)
+KEYWORDS = {"true": True, "false": False, "null": None}
+MACROS = {"all", "exists", "exists_one", "filter", "map"}
+ESCAPES = {"n": "\n", "t": "\t", "r": "\r", "\\": "\\", '"': '"', "'": "'"}
+# lowerAscii and upperAscii change A-Z and a-z only: "İ" and "ß" stay as they are
+LOWER_ASCII = str.maketrans(string.ascii_uppercase, string.ascii_lowercase)
+UPPER_ASCII = str.maketrans(string.ascii_lowercase, string.ascii_uppercase)
+
+class CelError(ValueError):
+    pass
+
+def _tokens(text: str) -> List[Tuple[str, str, int]]:
+    tokens, pos = [], 0
+    while pos < len(text):
+        m = TOKEN.match(text, pos)
+        if m is None:
+            raise CelError(f"unexpected {text[pos]!r} at column {pos + 1}")
+        if m.lastgroup != "space":
+            tokens.append((m.lastgroup, m.group(), pos))
+        pos = m.end()
+    tokens.append(("end", "", len(text)))
+    return tokens
+
+def _unquote(token: str) -> str:
+    if token[0] in "rR":
+        return token[2:-1]
+    return re.sub(r'\\(.)', lambda m: ESCAPES.get(m.group(1), m.group(1)), token[1:-1])
+
+class _Parser:
+    """Recursive descent over the tokens, building tuples: (op, operands...)"""
+
+    def __init__(self, text: str):
+        self.text = text
+        self.tokens = _tokens(text)
+        self.i = 0
+
+    def peek(self, value: str = "") -> bool:
+        kind, text, _ = self.tokens[self.i]
+        return text == value and kind in ("op", "ident") if value else kind != "end"
+
+    def take(self, value: str = "") -> Tuple[str, str, int]:
+        token = self.tokens[self.i]
+        if value and token[1] != value:
+            where = f"{token[1]!r} at column {token[2] + 1}" if token[0] != "end" else "the end"
+            raise CelError(f"expected '{value}' but found {where}")
+        self.i += 1
+        return token
+
+    def parse(self) -> tuple:
+        node = self.conditional()
+        if self.peek():
+            _, text, pos = self.tokens[self.i]
+            raise CelError(f"unexpected {text!r} at column {pos + 1}")
+        return node
+
+    def conditional(self) -> tuple:
+        node = self.binary(0)
+        if self.peek("?"):
+            self.take("?")
+            then = self.conditional()
+            self.take(":")
+            return ("?:", node, then, self.conditional())
+        return node
+
+    LEVELS = [("||",), ("&&",), ("==", "!=", "<", "<=", ">", ">=", "in"), ("+", "-"), ("*", "/", "%")]
+
+    def binary(self, level: int) -> tuple:
+        if level == len(self.LEVELS):
+            return self.unary()
+        node = self.binary(level + 1)
+        while any(self.peek(op) for op in self.LEVELS[level]):
+            op = self.take()[1]
+            node = (op, node, self.binary(level + 1))
+        return node
+
+    def unary(self) -> tuple:
+        if self.peek("!") or self.peek("-"):
+            op = self.take()[1]
+            return ("!" if op == "!" else "neg", self.unary())
+        return self.member()
+
+    def member(self) -> tuple:
+        node = self.primary()
+        while True:
+            if self.peek("."):
+                self.take(".")
+                kind, name, pos = self.take()
+                if kind != "ident":
+                    raise CelError(f"expected a field name at column {pos + 1}")
+                if self.peek("("):
+                    node = self.method(node, name, pos)
+                else:
+                    node = ("field", node, name)
+            elif self.peek("["):
+                self.take("[")
+                node = ("index", node, self.conditional())
+                self.take("]")
+            else:
+                return node
+
+    def method(self, target: tuple, name: str, pos: int) -> tuple:
+        args = self.args(")")
+        if name in MACROS:
+            if len(args) != 2 or args[0][0] != "ident":
+                raise CelError(f"{name} takes a variable and an expression, at column {pos + 1}")
+            return ("macro", name, target, args[0][1], args[1])
+        return ("call", name, target, args)
+
+    def args(self, close: str) -> List[tuple]:
+        self.take("(" if close == ")" else "[")
+        args = []
+        while not self.peek(close):
+            args.append(self.conditional())
+            if not self.peek(close):
+                self.take(",")
+        self.take(close)
+        return args
+
+    def primary(self) -> tuple:
+        kind, text, pos = self.tokens[self.i]
+        if kind in ("int", "float", "string"):
+            self.take()
+            value = {"int": lambda t: int(t, 0), "float": float, "string": _unquote}[kind](text)
+            return ("lit", value)
+        if kind == "ident":
+            self.take()
+            if text in KEYWORDS:
+                return ("lit", KEYWORDS[text])
+            if self.peek("("):
+                args = self.args(")")
+                if text == "has" and (len(args) != 1 or args[0][0] != "field"):
+                    raise CelError(f"has takes a field selection like has(span.kind), at column {pos + 1}")
+                return ("call", text, None, args)
+            return ("ident", text)
+        if text == "(":
+            self.take("(")
+            node = self.conditional()
+            self.take(")")
+            return node
+        if text == "[":
+            return ("list", self.args("]"))
+        if text == "{":
+            self.take("{")
+            entries = []
+            while not self.peek("}"):
+                key = self.conditional()
+                self.take(":")
+                entries.append((key, self.conditional()))
+                if not self.peek("}"):
+                    self.take(",")
+            self.take("}")
+            return ("map", entries)
+        raise CelError(f"unexpected {text or 'end of expression'!r} at column {pos + 1}")
+
+def _type(value: Any) -> str:
+    if isinstance(value, bool):
+        return "bool"
+    return {int: "int", float: "double", str: "string", list: "list", dict: "map", type(None): "null"}.get(
+        type(value), type(value).__name__)
+
+def _bool(value: Any, what: str) -> bool:
+    if not isinstance(value, bool):
+        raise CelError(f"{what} must be a bool, got {_type(value)}")
+    return value
+
+def _same_kind(a: Any, b: Any) -> bool:
+    numbers = (int, float)
+    if isinstance(a, bool) or isinstance(b, bool):
+        return isinstance(a, bool) and isinstance(b, bool)
+    return (isinstance(a, numbers) and isinstance(b, numbers)) or type(a) is type(b)
+
+def _equal(a: Any, b: Any) -> bool:
+    """CEL equality: Python's, except that a bool never equals a number, also inside lists and maps"""
+    if not _same_kind(a, b):
+        return False
+    if isinstance(a, list):
+        return len(a) == len(b) and all(_equal(x, y) for x, y in zip(a, b))
+    if isinstance(a, dict):
+        return len(a) == len(b) and all(_has_key(b, k) and _equal(v, _get(b, k)) for k, v in a.items())
+    return a == b
+
+def _has_key(target: dict, key: Any) -> bool:
+    return any(_same_kind(k, key) and k == key for k in target)
+
+def _get(target: dict, key: Any) -> Any:
+    return next(v for k, v in target.items() if _same_kind(k, key) and k == key)
+
+class Expression:
+    """A parsed CEL expression, evaluated against a mapping of variables"""
+
+    def __init__(self, text: str):
+        self.text = str(text)
+        self.tree = _Parser(self.text).parse()
+
+    def __repr__(self) -> str:
+        return f"Expression({self.text!r})"
+
+    def variables(self) -> Set[str]:
+        """Names the expression reads that no macro binds"""
+        return set(_free(self.tree, frozenset()))
+
+    def fields(self, variable: str) -> Set[str]:
+        """Fields the expression selects on variable: span.kind -> kind"""
+        return {node[2] for node in _nodes(self.tree) if node[0] == "field" and node[1] == ("ident", variable)}
+
+    def evaluate(self, env: Dict[str, Any]) -> Any:
+        return _eval(self.tree, env)
+
+def _nodes(node) -> Iterator[tuple]:
+    if isinstance(node, tuple) and node and isinstance(node[0], str):
+        yield node
+    for part in node if isinstance(node, (tuple, list)) else ():
+        if isinstance(part, (tuple, list)):
+            yield from _nodes(part)
+
+def _free(node: tuple, bound: frozenset) -> Iterator[str]:
+    op = node[0]
+    if op == "ident":
+        if node[1] not in bound:
+            yield node[1]
+    elif op == "macro":
+        yield from _free(node[2], bound)
+        yield from _free(node[4], bound | {node[3]})
+    elif op in ("list", "call"):
+        for arg in node[-1]:
+            yield from _free(arg, bound)
+        if op == "call" and node[2] is not None:
+            yield from _free(node[2], bound)
+    elif op == "map":
+        for key, value in node[1]:
+            yield from _free(key, bound)
+            yield from _free(value, bound)
+    elif op == "field":
+        yield from _free(node[1], bound)
+    elif op != "lit":
+        for operand in node[1:]:
+            yield from _free(operand, bound)
+
+def _eval(node: tuple, env: Dict[str, Any]) -> Any:
+    op = node[0]
+    if op == "lit":
+        return node[1]
+    if op == "ident":
+        if node[1] not in env:
+            raise CelError(f"undeclared reference to '{node[1]}'")
+        return env[node[1]]
+    if op == "field":
+        target = _eval(node[1], env)
+        if not isinstance(target, dict):
+            raise CelError(f"can't select {node[2]} on a {_type(target)}")
+        if node[2] not in target:
+            raise CelError(f"no such key: {node[2]}")
+        return target[node[2]]
+    if op == "index":
+        return _index(_eval(node[1], env), _eval(node[2], env))
+    if op == "list":
+        return [_eval(item, env) for item in node[1]]
+    if op == "map":
+        return {_eval(k, env): _eval(v, env) for k, v in node[1]}
+    if op == "!":
+        return not _bool(_eval(node[1], env), "the operand of !")
+    if op == "neg":
+        value = _eval(node[1], env)
+        if isinstance(value, bool) or not isinstance(value, (int, float)):
+            raise CelError(f"can't negate a {_type(value)}")
+        return -value
+    if op == "?:":
+        return _eval(node[2] if _bool(_eval(node[1], env), "the condition of ?:") else node[3], env)
+    if op in ("&&", "||"):
+        return _logical(op, node[1], node[2], env)
+    if op == "macro":
+        return _macro(node[1], _eval(node[2], env), node[3], node[4], env)
+    if op == "call":
+        return _call(node[1], node[2], node[3], env)
+    return _binary(op, _eval(node[1], env), _eval(node[2], env))
+
+def _logical(op: str, left: tuple, right: tuple, env: Dict[str, Any]) -> bool:
+    decisive = op == "||"
+    error: Optional[CelError] = None
+    for side in (left, right):
+        try:
+            if _bool(_eval(side, env), f"the operands of {op}") == decisive:
+                return decisive
+        except CelError as e:
+            error = error or e
+    if error is not None:
+        raise error
+    return not decisive
+
+def _index(target: Any, key: Any) -> Any:
+    if isinstance(target, list):
+        if isinstance(key, bool) or not isinstance(key, int):
+            raise CelError(f"a list index must be an int, got {_type(key)}")
+        if not 0 <= key < len(target):
+            raise CelError(f"index {key} out of range")
+        return target[key]
+    if isinstance(target, dict):
+        if not _has_key(target, key):
+            raise CelError(f"no such key: {key}")
+        return _get(target, key)
+    raise CelError(f"can't index a {_type(target)}")
+
+def _binary(op: str, a: Any, b: Any) -> Any:
+    if op == "in":
+        if isinstance(b, list):
+            return any(_equal(a, item) for item in b)
+        if isinstance(b, dict):
+            return _has_key(b, a)
+        raise CelError(f"'in' needs a list or map on its right, got {_type(b)}")
+    if op in ("==", "!="):
+        equal = _equal(a, b)
+        return equal if op == "==" else not equal
+    if not _same_kind(a, b):
+        raise CelError(f"no such overload: {_type(a)} {op} {_type(b)}")
+    if op in ("<", "<=", ">", ">="):
+        if isinstance(a, (list, dict, type(None))):
+            raise CelError(f"can't compare {_type(a)} values")
+        return {"<": a < b, "<=": a <= b, ">": a > b, ">=": a >= b}[op]
+    if op == "+":
+        if isinstance(a, (bool, dict, type(None))):
+            raise CelError(f"can't add {_type(a)} values")
+        return a + b
+    if isinstance(a, (bool, str, list, dict, type(None))):
+        raise CelError(f"no such overload: {_type(a)} {op} {_type(b)}")
+    if op == "-":
+        return a - b
+    if op == "*":
+        return a * b
+    if b == 0:
+        raise CelError("division by zero")
+    if isinstance(a, int) and isinstance(b, int):
+        quotient = abs(a) // abs(b) * (1 if (a < 0) == (b < 0) else -1)
+        return quotient if op == "/" else a - quotient * b
+    return a / b if op == "/" else a % b
+
+def _macro(name: str, target: Any, var: str, body: tuple, env: Dict[str, Any]) -> Any:
+    if not isinstance(target, (list, dict)):
+        raise CelError(f"{name} needs a list or map, got {_type(target)}")
+    items = list(target)
+    results = [_eval(body, {**env, var: item}) for item in items]
+    if name == "map":
+        return results
+    if name == "filter":
+        return [item for item, keep in zip(items, results) if _bool(keep, "the filter condition")]
+    matches = [_bool(r, f"the {name} condition") for r in results]
+    if name == "all":
+        return all(matches)
+    if name == "exists":
+        return any(matches)
+    return matches.count(True) == 1
+
+def _call(name: str, target: Optional[tuple], args: List[tuple], env: Dict[str, Any]) -> Any:
+    if name == "has":
+        selected = _eval(args[0][1], env)
+        return isinstance(selected, dict) and args[0][2] in selected
+    values = [_eval(arg, env) for arg in args]
+    if target is not None:
+        values.insert(0, _eval(target, env))
+    if name == "size" and len(values) == 1:
+        if isinstance(values[0], (str, list, dict)):
+            return len(values[0])
+        raise CelError(f"size needs a string, list or map, got {_type(values[0])}")
+    if name == "int" and len(values) == 1 and target is None:
+        try:
+            return int(values[0])
+        except (TypeError, ValueError):
+            raise CelError(f"can't convert {values[0]!r} to int")
+    if name == "string" and len(values) == 1 and target is None:
+        value = values[0]
+        return ("true" if value else "false") if isinstance(value, bool) else str(value)
+    strings = {
+        "startsWith": lambda s, p: s.startswith(p),
+        "endsWith": lambda s, p: s.endswith(p),
+        "contains": lambda s, p: p in s,
+        "matches": _matches,
+        "lowerAscii": lambda s: s.translate(LOWER_ASCII),
+        "upperAscii": lambda s: s.translate(UPPER_ASCII),
+    }
+    if name in strings and target is not None:
+        if not all(isinstance(v, str) for v in values):
+            raise CelError(f"{name} needs strings, got {', '.join(_type(v) for v in values)}")
+        try:
+            return strings[name](*values)
+        except TypeError:
+            raise CelError(f"{name} takes {strings[name].__code__.co_argcount - 1} argument(s)")
+    raise CelError(f"unknown function {name}")
+
+def _matches(text: str, pattern: str) -> bool:
+    try:
+        return re.search(pattern, text) is not None
+    except re.error as e:
+        raise CelError(f"invalid regular expression {pattern!r}: {e}")
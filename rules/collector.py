@@ -0,0 +1,197 @@
+"""
+Cross-checks between the code and an OpenTelemetry Collector configuration: signals with no
+pipeline, processors that drop attributes the code sets, sampling/filter policies that
+reference span names the code never produces, and propagators that don't speak the trace
+context format of the services the Collector receives from.
+"""
+
+import re
+from typing import Dict, List, Optional, Tuple
+
+import yaml
+
+from .base import CodeLocation, TelemetryViolation
+from .golang import GoFile
+from .inventory import Inventory, build_inventory
+
+class CollectorConfig:
+    def __init__(self, path: str, text: str):
+        self.path = path
+        self.text = text
+        self.lines = text.split("\n")
+        self.data = yaml.safe_load(text) or {}
+        self.processors: Dict = self.data.get("processors") or {}
+        self.pipelines: Dict = ((self.data.get("service") or {}).get("pipelines")) or {}
+
+    def pipeline_signals(self) -> Dict[str, List[str]]:
+        """signal -> pipeline ids ("traces", "traces/sampled", ...)"""
+
+        signals: Dict[str, List[str]] = {}
+        for pipeline_id in self.pipelines:
+            signals.setdefault(pipeline_id.split("/", 1)[0], []).append(pipeline_id)
+        return signals
+
+    def processors_in(self, signal: str) -> List[str]:
+        """Processor ids used by any pipeline of the signal"""
+
+        used = []
+        for pipeline_id, pipeline in self.pipelines.items():
+            if pipeline_id.split("/", 1)[0] == signal:
+                used.extend(p for p in (pipeline or {}).get("processors", []) if p not in used)
+        return used
+
+    def line_of(self, needle: str, after: int = 0) -> int:
+        """First line (1-based) at or after `after` that contains needle"""
+
+        for i in range(after, len(self.lines)):
+            if needle in self.lines[i]:
+                return i + 1
+        return 1
+
+def _violation(config: CollectorConfig, line: int, rule_id: str, severity: str, message: str,
+               suggestion: str, confidence: float = 0.9) -> TelemetryViolation:
+    text = config.lines[line - 1] if line <= len(config.lines) else ""
+    return TelemetryViolation(
+        violation_id=f"{rule_id}_{line}",
+        severity=severity,
+        file_path=config.path,
+        location=CodeLocation(
+            line_number=line,
+            column=len(text) - len(text.lstrip()) + 1,
+            function_name="",
+            code_snippet=text.strip(),
+            context_lines=config.lines[max(0, line - 3):line + 2],
+        ),
+        violation_type="collector",
+        rule_violated=rule_id,
+        description=message,
+        fix_suggestion=suggestion,
+        kb_reference="knowledge_base/instrumentation.md",
+        confidence=confidence,
+        detection_method="collector_crosscheck",
+        language="yaml",
+        rule_id=rule_id,
+    )
+
+def _missing_pipelines(config: CollectorConfig, inv: Inventory) -> List[TelemetryViolation]:
+    found = []
+    pipelines = config.pipeline_signals()
+    for signal in sorted(inv.signals):
+        if signal not in pipelines:
+            line = config.line_of("pipelines:")
+            found.append(_violation(
+                config, line, "collector-missing-pipeline", "high",
+                f"The code emits {signal} but the Collector has no {signal} pipeline, so they are rejected",
+                f"Add a service.pipelines.{signal} entry with an OTLP receiver and an exporter",
+            ))
+    return found
+
+def _dropped_attributes(config: CollectorConfig, inv: Inventory) -> List[TelemetryViolation]:
+    found = []
+    for signal in ("traces", "metrics", "logs"):
+        for processor_id in config.processors_in(signal):
+            if processor_id.split("/", 1)[0] != "attributes":
+                continue
+            start = config.line_of(f"{processor_id}:") - 1
+            for action in (config.processors.get(processor_id) or {}).get("actions", []):
+                key, kind = action.get("key"), action.get("action")
+                if kind not in ("delete", "hash") or key not in inv.attribute_keys:
+                    continue
+                verb = "deletes" if kind == "delete" else "hashes"
+                found.append(_violation(
+                    config, config.line_of(f"{key}", start), "collector-drops-attribute", "medium",
+                    f"Processor {processor_id} {verb} '{key}' in the {signal} pipeline, but the code sets it "
+                    f"({inv.attribute_keys[key]})",
+                    "Stop emitting the attribute, or narrow the processor (include/exclude match) if it is still needed downstream",
+                ))
+    return found
+
+def _policy_span_names(policy: Dict) -> List[str]:
+    """Span names a tail_sampling policy (including nested and/composite policies) matches on"""
+
+    names = []
+    for condition in ((policy.get("ottl_condition") or {}).get("span") or []):
+        names += re.findall(r'\bname\s*==\s*"([^"]+)"', condition)
+    for nested_key in ("and", "composite"):
+        nested = policy.get(nested_key) or {}
+        for sub in nested.get(f"{nested_key}_sub_policy", []) or []:
+            names += _policy_span_names(sub)
+    return names
+
+def _unknown_span_names(config: CollectorConfig, inv: Inventory) -> List[TelemetryViolation]:
+    found = []
+    # With dynamic span names we can't be sure a name is never produced
+    confidence = 0.6 if inv.dynamic_span_names else 0.9
+    for processor_id in config.processors_in("traces"):
+        kind = processor_id.split("/", 1)[0]
+        settings = config.processors.get(processor_id) or {}
+        referenced: List[Tuple[str, str]] = []
+        if kind == "tail_sampling":
+            for policy in settings.get("policies", []) or []:
+                referenced += [(name, f"policy '{policy.get('name', '?')}'") for name in _policy_span_names(policy)]
+        elif kind == "filter":
+            for condition in ((settings.get("traces") or {}).get("span") or []):
+                referenced += [(name, "filter condition") for name in re.findall(r'\bname\s*==\s*"([^"]+)"', condition)]
+            for section in ("include", "exclude"):
+                for name in ((settings.get("spans") or {}).get(section) or {}).get("span_names", []) or []:
+                    referenced.append((name, f"{section} span_names"))
+        start = config.line_of(f"{processor_id}:") - 1
+        for name, where in referenced:
+            if name in inv.span_names or _matches_any(name, inv.span_names):
+                continue
+            found.append(_violation(
+                config, config.line_of(name, start), "collector-unknown-span-name", "medium",
+                f"{processor_id} {where} references span name '{name}', which the code never produces",
+                "Update the policy to the span names the code uses" + (
+                    f" (e.g. {', '.join(sorted(inv.span_names)[:3])})" if inv.span_names else ""),
+                confidence,
+            ))
+    return found
+
+# Trace receivers -> the propagation format the services sending to them use
+RECEIVER_FORMATS = {
+    "zipkin": ("b3", "b3multi"),
+    "jaeger": ("jaeger",),
+    "otlp": ("tracecontext", "autoprop"),
+}
+FORMAT_NAMES = {"b3": "B3", "jaeger": "Jaeger (uber-trace-id)", "otlp": "W3C trace context"}
+
+def _propagator_mismatch(config: CollectorConfig, inv: Inventory) -> List[TelemetryViolation]:
+    """Receivers whose senders use a propagation format the code neither injects nor extracts"""
+
+    if not inv.propagators:
+        return []
+    found = []
+    code_formats = ", ".join(sorted(inv.propagators))
+    receivers = {r for pipeline_id, pipeline in config.pipelines.items()
+                 if pipeline_id.split("/", 1)[0] == "traces" for r in (pipeline or {}).get("receivers", [])}
+    for receiver in sorted(receivers):
+        kind = receiver.split("/", 1)[0]
+        formats = RECEIVER_FORMATS.get(kind)
+        if not formats or any(f in inv.propagators for f in formats):
+            continue
+        found.append(_violation(
+            config, config.line_of(f"{receiver}:"), "collector-propagator-mismatch", "medium",
+            f"The Collector receives {kind} traces, whose senders propagate {FORMAT_NAMES[kind]}, but the code "
+            f"only configures {code_formats} ({next(iter(inv.propagators.values()))}), so their traces "
+            f"don't join this service's",
+            f"Add the {FORMAT_NAMES[kind]} propagator to the composite propagator alongside the current ones",
+            0.7,
+        ))
+    return found
+
+def _matches_any(pattern: str, names: Dict[str, str]) -> bool:
+    """Filter span_names may be regexps; treat the name as one when it isn't literal"""
+
+    if not re.search(r'[\\^$.*+?()\[\]{}|]', pattern):
+        return False
+    try:
+        return any(re.fullmatch(pattern, n) for n in names)
+    except re.error:
+        return False
+
+def crosscheck(config: CollectorConfig, sources: List[GoFile], inventory: Optional[Inventory] = None) -> List[TelemetryViolation]:
+    inv = inventory or build_inventory(sources)
+    findings = (_missing_pipelines(config, inv) + _dropped_attributes(config, inv) + _unknown_span_names(config, inv)
+                + _propagator_mismatch(config, inv))
+    return sorted(findings, key=lambda v: (v.location.line_number, v.rule_id))
@@ -0,0 +1,627 @@
+"""
+Project configuration (.ollygarden.yaml).
+
+    rules:
+      disable: [prometheus-name-translation]
+      severity:
+        attribute-set-rebuilt: medium
+      options:
+        secret-in-telemetry:
+          allowlist: ["^test-"]
+        span-name-convention:
+          separators: [" ", "."]
+          max_length: 60
+    naming:
+      verbs: [checkout, reserve, charge, ship]
+      terms: [cart, inventory, order, PayPal]
+    escalation:
+      conventions:
+        boundary: high
+    span_helpers:
+      - call: tracing.StartSpan
+        name: 1
+    include:
+      - services/
+    exclude:
+      - vendor/
+      - "**/*.pb.go"
+    baseline: .ollygarden-baseline.json
+    custom_rules:
+      - id: billing-server-span-tenant
+        title: Server spans of the billing service record the tenant
+        target: span
+        when: span.kind == "server" && span.package_path.startsWith("example.com/shop/billing")
+        require: '"tenant.id" in span.attributes'
+    plugins:
+      - name: fix-protocol
+        command: [./bin/fix-rules]
+    profiles:
+      dev:
+        rules:
+          disable: [stdout-exporter, sampler-always-on]
+      prod:
+        rules:
+          severity:
+            stdout-exporter: critical
+"""
+
+import difflib
+import fnmatch
+import os
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+
+import yaml
+
+from .base import Rule, SEVERITIES, SIGNALS
+from .baseline import BASELINE_FILE, Baseline
+from .custom import FIELDS, TARGETS, custom_rule
+from .escalation import DEFAULT_ESCALATION, SPAN_CLASSES, valid_level
+from .golang import SpanHelper
+from .plugins import DEFAULT_TIMEOUT, Plugin, PluginError, plugin
+from .registry import all_rules, get_rule
+
+CONFIG_FILE = ".ollygarden.yaml"
+# Selects a profile when no --profile is given
+PROFILE_ENV = "OLLYGARDEN_PROFILE"
+# Comma-separated signals to check when no --signals is given, e.g. traces,metrics
+SIGNALS_ENV = "OLLYGARDEN_SIGNALS"
+# What a profile may change; everything else is shared by all profiles
+PROFILE_KEYS = {"rules", "include", "exclude", "escalation"}
+# The naming dictionary: option defaults for every rule that takes them
+NAMING_KEYS = {"verbs", "terms"}
+TOP_LEVEL_KEYS = {"rules", "naming", "escalation", "span_helpers", "include", "exclude", "baseline", "profiles",
+                  "custom_rules", "plugins"}
+RULES_KEYS = {"enable", "disable", "severity", "options"}
+# Rule options whose values are regular expressions, checked when the config is loaded
+REGEX_OPTIONS = {"secret-in-telemetry": ["allowlist"], "pii-in-telemetry": ["patterns"]}
+
+class ConfigError(ValueError):
+    pass
+
+@dataclass
+class Config:
+    # Empty means every default rule. Opt-in rules listed here are added to the default set;
+    # listing any other rule restricts the run to the listed rules.
+    enable: List[str] = field(default_factory=list)
+    disable: List[str] = field(default_factory=list)
+    severity: Dict[str, str] = field(default_factory=dict)
+    # rule id -> option overrides
+    options: Dict[str, Dict] = field(default_factory=dict)
+    # Empty means every file; otherwise only files matching one of these globs are analyzed
+    include: List[str] = field(default_factory=list)
+    exclude: List[str] = field(default_factory=list)
+    # category -> span class -> severity or step; replaces the default entry for that category
+    escalation: Dict[str, Dict] = field(default_factory=dict)
+    # Project functions that start spans, treated like tracer.Start by every rule
+    span_helpers: List[SpanHelper] = field(default_factory=list)
+    # Directory the config was loaded from; include and exclude globs are relative to it
+    root: str = "."
+    # Profile merged over the base config, "" for none
+    profile: str = ""
+    # Findings recorded by `baseline generate`, relative to root; used when the file exists
+    baseline: str = BASELINE_FILE
+    # Only rules checking one of these signals run; empty means every signal
+    signals: List[str] = field(default_factory=list)
+    # Rules defined under custom_rules, selected like the registered ones
+    custom_rules: List[Rule] = field(default_factory=list)
+    # Executables adding rules, and the rules they describe
+    plugins: List[Plugin] = field(default_factory=list)
+    plugin_rules: List[Rule] = field(default_factory=list)
+
+    def project_rules(self) -> List[Rule]:
+        """Rules the config adds to the registered ones: custom rules and those of plugins"""
+        return self.custom_rules + self.plugin_rules
+
+    def select_rules(self, rules: Optional[List[Rule]] = None) -> List[Rule]:
+        rules = rules if rules is not None else all_rules() + self.project_rules()
+        restricted = any(not r.opt_in for r in rules if r.rule_id in self.enable)
+        return [
+            r for r in rules
+            if (r.rule_id in self.enable or (not r.opt_in and not restricted)) and r.rule_id not in self.disable
+            and r.covers(self.signals)
+        ]
+
+    def baseline_path(self) -> Path:
+        return Path(self.root) / self.baseline
+
+    def load_baseline(self) -> Optional[Baseline]:
+        """The findings recorded by `baseline generate`, None when there's no baseline file"""
+        path = self.baseline_path()
+        if not path.is_file():
+            return None
+        try:
+            return Baseline.load(str(path), self.root)
+        except (OSError, ValueError, KeyError) as e:
+            raise ConfigError(f"cannot read baseline {path}: {e}") from e
+
+    def escalation_levels(self) -> Dict[str, Dict]:
+        return {**DEFAULT_ESCALATION, **self.escalation}
+
+    def is_excluded(self, path: str) -> bool:
+        """Whether path is outside the include globs (when there are any) or matches an exclude glob"""
+
+        try:
+            relative = Path(path).resolve().relative_to(Path(self.root).resolve()).as_posix()
+        except ValueError:
+            relative = Path(path).as_posix()
+        if self.include and not any(_matches(relative, p) for p in self.include):
+            return True
+        return any(_matches(relative, p) for p in self.exclude)
+
+def _matches(relative: str, pattern: str) -> bool:
+    if pattern.endswith("/"):
+        # Directory pattern: matches the directory at any depth
+        directory = pattern.rstrip("/")
+        return relative.startswith(directory + "/") or f"/{directory}/" in f"/{relative}"
+    return fnmatch.fnmatch(relative, pattern) or fnmatch.fnmatch(relative, pattern.replace("**/", ""))
+
+def apply_profile(data: Dict, profile: str) -> Dict:
+    """data with the named profile merged over it: rule lists and globs are extended, severities,
+    options and escalation entries override, and enabling a rule undoes a base disable (and vice versa)"""
+
+    overlay = data["profiles"][profile] or {}
+    unknown = sorted(set(overlay) - PROFILE_KEYS)
+    if unknown:
+        raise ConfigError(f"a profile can't set {', '.join(unknown)}")
+
+    merged = dict(data)
+    base_rules, rules = data.get("rules") or {}, overlay.get("rules") or {}
+    enable, disable = list(rules.get("enable") or []), list(rules.get("disable") or [])
+    options = {k: dict(v or {}) for k, v in (base_rules.get("options") or {}).items()}
+    for rule_id, values in (rules.get("options") or {}).items():
+        options.setdefault(rule_id, {}).update(values or {})
+    merged["rules"] = {
+        "enable": [r for r in base_rules.get("enable") or [] if r not in disable] + enable,
+        "disable": [r for r in base_rules.get("disable") or [] if r not in enable] + disable,
+        "severity": {**(base_rules.get("severity") or {}), **(rules.get("severity") or {})},
+        "options": options,
+    }
+    for key in ("include", "exclude"):
+        merged[key] = list(data.get(key) or []) + list(overlay.get(key) or [])
+    merged["escalation"] = {**(data.get("escalation") or {}), **(overlay.get("escalation") or {})}
+    return merged
+
+def parse_config(data: Dict, root: str = ".", profile: str = "") -> Config:
+    """Build a Config from parsed YAML, rejecting unknown rule ids and severities"""
+
+    data = data or {}
+    if profile:
+        profiles = data.get("profiles") or {}
+        if profile not in profiles:
+            raise ConfigError(f"unknown profile '{profile}' ({', '.join(sorted(profiles)) or 'none defined'})")
+        try:
+            return _parse(apply_profile(data, profile), root, profile)
+        except ConfigError as e:
+            raise ConfigError(f"profile '{profile}': {e}")
+    config = _parse(data, root)
+    # Catch mistakes in every profile, not just in the one a pipeline happens to select
+    for name in data.get("profiles") or {}:
+        parse_config(data, root, name)
+    return config
+
+def _parse(data: Dict, root: str, profile: str = "") -> Config:
+    plugins, plugin_rules = _plugins(data, root)
+    problem = next(_problems(data, custom=[r.rule_id for r in plugin_rules]), None)
+    if problem is not None:
+        raise ConfigError(problem)
+    rules = data.get("rules") or {}
+    config = Config(
+        enable=list(rules.get("enable") or []),
+        disable=list(rules.get("disable") or []),
+        severity=dict(rules.get("severity") or {}),
+        options={k: dict(v or {}) for k, v in (rules.get("options") or {}).items()},
+        include=list(data.get("include") or []),
+        exclude=list(data.get("exclude") or []),
+        escalation={k: dict(v or {}) for k, v in (data.get("escalation") or {}).items()},
+        span_helpers=[_span_helper(h) for h in data.get("span_helpers") or []],
+        root=root,
+        profile=profile,
+        baseline=str(data.get("baseline") or BASELINE_FILE),
+        plugins=plugins,
+        plugin_rules=plugin_rules,
+    )
+    _apply_naming(config, data.get("naming") or {})
+    categories = {r.category for r in all_rules()}
+    config.custom_rules = [custom_rule(entry, categories) for entry in data.get("custom_rules") or []]
+    if config.project_rules():
+        known = config.options.setdefault("invalid-suppression", {}).setdefault("known_rules", [])
+        known.extend(r.rule_id for r in config.project_rules() if r.rule_id not in known)
+    for category, levels in config.escalation.items():
+        if category not in categories:
+            raise ConfigError(f"escalation for unknown category '{category}'")
+        for span_class, level in levels.items():
+            if span_class not in SPAN_CLASSES:
+                raise ConfigError(f"escalation for '{category}' has unknown span class '{span_class}'")
+            if not valid_level(level):
+                raise ConfigError(f"escalation for '{category}' {span_class} spans must be a severity or a step like +1, got '{level}'")
+    return config
+
+def _did_you_mean(name: str, known) -> str:
+    close = difflib.get_close_matches(str(name), sorted(known), n=1, cutoff=0.6)
+    return f" (did you mean '{close[0]}'?)" if close else ""
+
+def _type_name(value: Any) -> str:
+    return {bool: "a boolean", int: "a number", float: "a number", str: "a string",
+            list: "a list", dict: "a mapping"}.get(type(value), type(value).__name__)
+
+def _option_problem(rule_id: str, option: str, value: Any, default: Any) -> Optional[str]:
+    """Why value can't replace an option's default, or None"""
+
+    if isinstance(default, bool):
+        valid = isinstance(value, bool)
+    elif isinstance(default, (int, float)):
+        valid = isinstance(value, (int, float)) and not isinstance(value, bool)
+        valid = valid and (isinstance(default, float) or float(value).is_integer())
+    elif isinstance(default, list):
+        valid = isinstance(value, list)
+        # Lists of words and patterns; lists of mappings are read by the rule itself
+        if valid and all(isinstance(d, str) for d in default) and not all(isinstance(v, str) for v in value):
+            return f"rule '{rule_id}' option {option} must be a list of strings"
+    else:
+        valid = isinstance(value, type(default))
+    if not valid:
+        return f"rule '{rule_id}' option {option} must be {_type_name(default)}, got {value!r}"
+    patterns = value.values() if isinstance(value, dict) else value
+    for pattern in patterns if option in REGEX_OPTIONS.get(rule_id, []) else []:
+        try:
+            re.compile(pattern)
+        except re.error as e:
+            return f"rule '{rule_id}' option {option} has an invalid regular expression {pattern!r}: {e}"
+    return None
+
+def _custom_rule_ids(data: Dict) -> List[str]:
+    entries = data.get("custom_rules") if isinstance(data, dict) else None
+    if not isinstance(entries, list):
+        return []
+    return [str(e["id"]) for e in entries if isinstance(e, dict) and e.get("id")]
+
+def _custom_rule_problems(entries: Any) -> Iterator[str]:
+    if not isinstance(entries, list):
+        yield "custom_rules must be a list of rule definitions"
+        return
+    categories = {r.category for r in all_rules()}
+    seen = set()
+    for i, entry in enumerate(entries):
+        name = f"'{entry['id']}'" if isinstance(entry, dict) and entry.get("id") else f"#{i + 1}"
+        try:
+            custom_rule(entry, categories)
+        except ValueError as e:
+            yield f"custom rule {name}: {e}"
+            continue
+        if get_rule(entry["id"]) is not None:
+            yield f"custom rule {name} has the ID of a built-in rule"
+        elif entry["id"] in seen:
+            yield f"custom rule {name} is defined twice"
+        seen.add(entry["id"])
+
+def _plugins(data: Dict, root: str) -> Tuple[List[Plugin], List[Rule]]:
+    """The plugins a config lists and the rules they describe, running each plugin's describe"""
+
+    entries = data.get("plugins") if isinstance(data, dict) else None
+    if not entries:
+        return [], []
+    if not isinstance(entries, list):
+        raise ConfigError("plugins must be a list of plugin commands")
+    plugins, rules = [], []
+    categories = {r.category for r in all_rules()}
+    for i, entry in enumerate(entries):
+        name = f"'{entry['name']}'" if isinstance(entry, dict) and entry.get("name") else f"#{i + 1}"
+        try:
+            p = plugin(entry, root)
+            described = p.rules(categories)
+        except ValueError as e:
+            raise ConfigError(f"plugin {name}: {e}")
+        except PluginError as e:
+            raise ConfigError(str(e))
+        for r in described:
+            if get_rule(r.rule_id) is not None or r.rule_id in _custom_rule_ids(data):
+                raise ConfigError(f"plugin {name} rule '{r.rule_id}' has the ID of a built-in or custom rule")
+            if any(r.rule_id == known.rule_id for known in rules):
+                raise ConfigError(f"plugin {name} rule '{r.rule_id}' is described by another plugin")
+        plugins.append(p)
+        rules.extend(described)
+    return plugins, rules
+
+def _problems(data: Dict, keys=TOP_LEVEL_KEYS, custom: Optional[List[str]] = None) -> Iterator[str]:
+    """Unknown keys, rule ids, options and severities, option values of the wrong type and
+    invalid custom rules, in the order a reader meets them. custom lists the IDs of rules defined
+    elsewhere: the plugins' and, for profiles, the base config's custom rules."""
+
+    if not isinstance(data, dict):
+        yield "the configuration must be a mapping"
+        return
+    for key in data:
+        if key not in keys:
+            yield f"unknown key '{key}'{_did_you_mean(key, keys)}"
+    if "custom_rules" in data and "custom_rules" in keys:
+        yield from _custom_rule_problems(data["custom_rules"] or [])
+    custom = set(_custom_rule_ids(data)) | set(custom or [])
+    rules = data.get("rules") or {}
+    if not isinstance(rules, dict):
+        yield "rules must map enable, disable, severity and options"
+        return
+    for key in rules:
+        if key not in RULES_KEYS:
+            yield f"unknown key 'rules.{key}'{_did_you_mean(key, RULES_KEYS)}"
+    known = [r.rule_id for r in all_rules()] + sorted(custom)
+    for key in ("enable", "disable", "severity", "options"):
+        entries = rules.get(key) or ([] if key in ("enable", "disable") else {})
+        if not isinstance(entries, list if key in ("enable", "disable") else dict):
+            yield f"rules.{key} must be {'a list of rule ids' if key in ('enable', 'disable') else 'a mapping by rule id'}"
+            continue
+        for rule_id in entries:
+            if get_rule(rule_id) is None and rule_id not in custom:
+                yield f"unknown rule '{rule_id}' in rules.{key}{_did_you_mean(rule_id, known)}"
+    for rule_id, severity in (rules.get("severity") or {}).items():
+        if (get_rule(rule_id) is not None or rule_id in custom) and severity not in SEVERITIES:
+            yield f"rule '{rule_id}' has unknown severity '{severity}'{_did_you_mean(severity, SEVERITIES)}"
+    for rule_id, options in (rules.get("options") or {}).items():
+        r = get_rule(rule_id)
+        if r is None:
+            if rule_id in custom:
+                yield f"custom or plugin rule '{rule_id}' has no options"
+            continue
+        if not isinstance(options or {}, dict):
+            yield f"rules.options.{rule_id} must map option names to values"
+            continue
+        for option, value in (options or {}).items():
+            if option not in r.options:
+                known_options = f"; it has {', '.join(sorted(r.options))}" if r.options else ""
+                yield f"rule '{rule_id}' has no option {option}{_did_you_mean(option, r.options)}{known_options}"
+                continue
+            problem = _option_problem(rule_id, option, value, r.options[option])
+            if problem:
+                yield problem
+
+def check_config(data: Dict, root: str = ".") -> List[str]:
+    """Every problem of a parsed config file, profiles included, where load_config stops at the
+    first one"""
+
+    data = data or {}
+    try:
+        defined = [r.rule_id for r in _plugins(data, root)[1]]
+    except ConfigError as e:
+        # Still report everything else, without the plugin's rule IDs
+        return [str(e)] + [p for p in _problems(data) if not p.startswith("unknown rule ")]
+    problems = list(_problems(data, custom=defined))
+    profiles = data.get("profiles") if isinstance(data, dict) else None
+    for name, overlay in (profiles or {}).items():
+        problems += [f"profile '{name}': {p}"
+                     for p in _problems(overlay or {}, PROFILE_KEYS, _custom_rule_ids(data) + defined)]
+    if not problems:
+        # What remains is structural (escalation, naming, span helpers), one at a time
+        try:
+            parse_config(data, root)
+        except ConfigError as e:
+            problems.append(str(e))
+    return problems
+
+def effective_config(config: Config) -> Dict:
+    """The settings a run with config uses: the rules it selects with their severities and
+    merged options, and everything else after profile merging, defaults filled in"""
+
+    selected = config.select_rules()
+    return {
+        "profile": config.profile or None,
+        "signals": config.signals or list(SIGNALS),
+        "root": config.root,
+        "rules": {
+            "enabled": [r.rule_id for r in selected],
+            "disabled": sorted(r.rule_id for r in all_rules() + config.project_rules() if r not in selected),
+            "severity": {r.rule_id: config.severity.get(r.rule_id, r.severity)
+                         for r in selected if r.rule_id in config.severity},
+            "options": {r.rule_id: {**r.options, **config.options.get(r.rule_id, {})} for r in selected if r.options},
+        },
+        "escalation": config.escalation_levels(),
+        "span_helpers": [{"call": h.call, "ctx": h.ctx_index, "name": h.name_index} for h in config.span_helpers],
+        "include": config.include,
+        "exclude": config.exclude,
+        "baseline": config.baseline,
+        "custom_rules": [r.rule_id for r in config.custom_rules],
+        "plugins": [{"name": p.name, "command": p.command, "timeout": p.timeout, "rules": p.rule_ids()}
+                    for p in config.plugins],
+    }
+
+def _option_schema(default: Any) -> Dict:
+    if isinstance(default, bool):
+        schema = {"type": "boolean"}
+    elif isinstance(default, int):
+        schema = {"type": "integer"}
+    elif isinstance(default, float):
+        schema = {"type": "number"}
+    elif isinstance(default, str):
+        schema = {"type": "string"}
+    elif isinstance(default, list):
+        schema = {"type": "array"}
+        if all(isinstance(d, str) for d in default):
+            schema["items"] = {"type": "string"}
+    else:
+        schema = {"type": "object"}
+    return {**schema, "default": default}
+
+def config_schema() -> Dict:
+    """JSON schema of .ollygarden.yaml for editors and CI, generated from the registered rules"""
+
+    def ref(name: str) -> Dict:
+        return {"$ref": f"#/definitions/{name}"}
+
+    rules = all_rules()
+    rule_list = {"type": "array", "items": ref("ruleId"), "uniqueItems": True}
+    rules_section = {
+        "type": "object",
+        "additionalProperties": False,
+        "properties": {
+            "enable": {**rule_list, "description": "Opt-in rules to add, or the only rules to run"},
+            "disable": {**rule_list, "description": "Rules not to run"},
+            "severity": {"type": "object", "propertyNames": ref("ruleId"), "additionalProperties": ref("severity"),
+                         "description": "Severity overrides by rule"},
+            "options": {
+                "type": "object",
+                "additionalProperties": False,
+                "description": "Option overrides by rule",
+                "properties": {
+                    r.rule_id: {"type": "object", "additionalProperties": False,
+                                "properties": {k: _option_schema(v) for k, v in r.options.items()}}
+                    for r in sorted(rules, key=lambda r: r.rule_id) if r.options
+                },
+            },
+        },
+    }
+    level = {"anyOf": [ref("severity"), {"type": "string", "pattern": "^[+-][0-9]+$"}, {"type": "integer"}]}
+    escalation = {
+        "type": "object",
+        "propertyNames": {"enum": sorted({r.category for r in rules})},
+        "additionalProperties": {"type": "object", "additionalProperties": False,
+                                 "properties": {c: level for c in SPAN_CLASSES}},
+    }
+    globs = {"type": "array", "items": {"type": "string"}}
+    span_helper = {"anyOf": [
+        {"type": "string"},
+        {"type": "object", "required": ["call"], "additionalProperties": False,
+         "properties": {"call": {"type": "string"}, "ctx": {"type": "integer", "minimum": 0},
+                        "name": {"type": "integer", "minimum": 0}}},
+    ]}
+    expression = {"type": "string", "description": "CEL expression"}
+    custom = {
+        "type": "object",
+        "required": ["id", "title", "target", "require"],
+        "additionalProperties": False,
+        "properties": {
+            "id": {"type": "string", "pattern": "^[a-z][a-z0-9]*(-[a-z0-9]+)*$"},
+            "title": {"type": "string"},
+            "target": {"enum": list(TARGETS), "description": "; ".join(
+                f"{t}: {', '.join(sorted(FIELDS[t]))}" for t in TARGETS)},
+            "when": {**expression, "description": "CEL expression selecting the items the rule applies to"},
+            "require": {**expression, "description": "CEL expression every selected item must satisfy"},
+            "message": {"type": "string", "description": "Finding message; {expression} placeholders are evaluated"},
+            "suggestion": {"type": "string"},
+            "description": {"type": "string"},
+            "severity": ref("severity"),
+            "category": {"enum": sorted({r.category for r in rules})},
+            "signal": {"enum": list(SIGNALS) + ["all"]},
+        },
+    }
+    plugin_entry = {
+        "type": "object",
+        "required": ["name", "command"],
+        "additionalProperties": False,
+        "properties": {
+            "name": {"type": "string"},
+            "command": {"anyOf": [{"type": "string"}, {"type": "array", "items": {"type": "string"}}],
+                        "description": "Executable and arguments, relative to the config's directory"},
+            "timeout": {"type": "number", "exclusiveMinimum": 0, "default": DEFAULT_TIMEOUT,
+                        "description": "Seconds a run may take"},
+        },
+    }
+    profile = {
+        "type": "object",
+        "additionalProperties": False,
+        "properties": {"rules": ref("rules"), "include": globs, "exclude": globs, "escalation": escalation},
+    }
+    return {
+        "$schema": "http://json-schema.org/draft-07/schema#",
+        "title": "ollygarden configuration (.ollygarden.yaml)",
+        "type": "object",
+        "additionalProperties": False,
+        "definitions": {
+            # Built-in rules, or rules the config defines under custom_rules or its plugins describe
+            "ruleId": {"anyOf": [{"enum": sorted(r.rule_id for r in rules)},
+                                 {"type": "string", "pattern": "^[a-z][a-z0-9]*(-[a-z0-9]+)*$"}]},
+            "severity": {"enum": list(SEVERITIES)},
+            "rules": rules_section,
+        },
+        "properties": {
+            "rules": ref("rules"),
+            "naming": {"type": "object", "additionalProperties": False,
+                       "properties": {k: {"type": "array", "items": {"type": "string"}} for k in sorted(NAMING_KEYS)}},
+            "escalation": escalation,
+            "span_helpers": {"type": "array", "items": span_helper},
+            "include": globs,
+            "exclude": globs,
+            "baseline": {"type": "string"},
+            "profiles": {"type": "object", "additionalProperties": profile},
+            "custom_rules": {"type": "array", "items": custom},
+            "plugins": {"type": "array", "items": plugin_entry},
+        },
+    }
+
+def _apply_naming(config: Config, naming: Dict):
+    """Fill the verbs and terms options of the naming rules from the shared naming dictionary;
+    a rule's own options still win"""
+
+    if not isinstance(naming, dict):
+        raise ConfigError("naming must map verbs and terms to lists of words")
+    unknown = sorted(set(naming) - NAMING_KEYS)
+    if unknown:
+        raise ConfigError(f"naming has unknown key(s) {', '.join(unknown)}")
+    for key, words in naming.items():
+        if not isinstance(words, list) or not all(isinstance(w, str) and w.strip() for w in words):
+            raise ConfigError(f"naming {key} must be a list of words")
+        for r in all_rules():
+            if key in r.options:
+                config.options.setdefault(r.rule_id, {}).setdefault(key, list(words))
+
+def _span_helper(entry) -> SpanHelper:
+    if isinstance(entry, str):
+        entry = {"call": entry}
+    if not isinstance(entry, dict) or not entry.get("call"):
+        raise ConfigError(f"span_helpers entries need a call, got {entry!r}")
+    unknown = sorted(set(entry) - {"call", "ctx", "name"})
+    if unknown:
+        raise ConfigError(f"span helper '{entry['call']}' has unknown key(s) {', '.join(unknown)}")
+    ctx, name = entry.get("ctx", 0), entry.get("name", 1)
+    if not isinstance(ctx, int) or not isinstance(name, int) or ctx < 0 or name < 0 or ctx == name:
+        raise ConfigError(f"span helper '{entry['call']}' needs distinct non-negative ctx and name positions")
+    if not re.fullmatch(r'(?:\*|\w+)(?:\.\w+)*', entry["call"]):
+        raise ConfigError(f"span helper call '{entry['call']}' should look like pkg.Func or *.Method")
+    return SpanHelper(entry["call"], ctx, name)
+
+def find_config(start: str) -> Optional[Path]:
+    """Nearest .ollygarden.yaml in start or one of its parents"""
+
+    directory = Path(start).resolve()
+    if directory.is_file():
+        directory = directory.parent
+    for candidate in [directory, *directory.parents]:
+        if (candidate / CONFIG_FILE).is_file():
+            return candidate / CONFIG_FILE
+    return None
+
+def parse_signals(text: str) -> List[str]:
+    """The signals of a comma-separated list, e.g. traces,metrics"""
+
+    signals = [s.strip() for s in text.split(",") if s.strip()]
+    unknown = [s for s in signals if s not in SIGNALS]
+    if unknown:
+        raise ConfigError(f"unknown signal '{unknown[0]}' (choose from {', '.join(SIGNALS)})")
+    return list(dict.fromkeys(signals))
+
+def load_config(start: str = ".", profile: Optional[str] = None, signals: Optional[List[str]] = None) -> Config:
+    """Config for the project containing start, with profile (default: $OLLYGARDEN_PROFILE)
+    applied and rules limited to signals (default: $OLLYGARDEN_SIGNALS); defaults when there
+    is no config file"""
+
+    profile = os.environ.get(PROFILE_ENV, "") if profile is None else profile
+    signals = parse_signals(os.environ.get(SIGNALS_ENV, "")) if signals is None else signals
+    config = _load(start, profile)
+    config.signals = signals
+    return config
+
+def _load(start: str, profile: str) -> Config:
+    path = find_config(start)
+    if path is None:
+        if profile:
+            raise ConfigError(f"profile '{profile}' selected but no {CONFIG_FILE} found")
+        return Config()
+    with open(path, "r", encoding="utf-8") as f:
+        try:
+            data = yaml.safe_load(f)
+        except yaml.YAMLError as e:
+            raise ConfigError(f"{path}: {e}")
+    try:
+        return parse_config(data, root=str(path.parent), profile=profile)
+    except ConfigError as e:
+        raise ConfigError(f"{path}: {e}")
@@ -0,0 +1,293 @@
+"""
+Attribute keys across signals: spans, metrics and logs of one module describing the same concept
+must use the same key, or queries and correlation across signals miss half of the data. The same
+goes for semantic convention versions: a module whose packages pin different semconv versions
+records one attribute under two names.
+"""
+
+import re
+from dataclasses import dataclass
+from pathlib import Path, PurePath
+from typing import Dict, Iterator, List, Optional, Tuple
+
+from .base import Diagnostic, Fix, TextEdit
+from .conventions import attribute_key_problems
+from .golang import Arg, GoFile
+from .logs.records import LOGS_API, log_calls
+from .metrics.instruments import metric_attributes
+from .registry import rule
+from .sdk.library import module_root
+from .semconv import SEMCONV_PKG, go_constant
+from .semconv_migration import KEY_RENAMES
+from .traces.attributes import attribute_calls
+
+SIGNALS = ("span", "metric", "log")
+NOUN = {"span": "span attribute", "metric": "metric attribute", "log": "log field"}
+
+@dataclass
+class KeyUse:
+    signal: str
+    key: str
+    arg: Arg
+    source: GoFile
+
+    @property
+    def where(self) -> str:
+        return f"{Path(self.source.path).name}:{self.source.line_of(self.arg.start)}"
+
+def concept(key: str) -> Tuple[str, ...]:
+    """The words of a key, whatever its style: "order.id", "orderId", "order_id" -> (order, id)"""
+
+    words = re.sub(r'(?<=[a-z0-9])(?=[A-Z])|(?<=[A-Z])(?=[A-Z][a-z])', " ", key)
+    return tuple(w.lower() for w in re.split(r'[\s._\-]+', words) if w)
+
+def key_uses(source: GoFile) -> Iterator[KeyUse]:
+    """Literal attribute keys of spans, metrics and logs"""
+
+    on_metrics = {call.start for call in metric_attributes(source)}
+    for call in attribute_calls(source):
+        if call.args and call.args[0].literal:
+            yield KeyUse("metric" if call.start in on_metrics else "span", call.args[0].literal, call.args[0], source)
+    for call in log_calls(source):
+        for key, key_arg, _ in call.fields:
+            if key and key_arg is not None:
+                yield KeyUse("log", key, key_arg, source)
+    # Attributes of records emitted through the logs API: log.String("k", v)
+    for alias in [n for n, p in source.imports.items() if p == LOGS_API]:
+        for call in source.calls(re.escape(alias) + r'\.(?:String|Int|Int64|Float64|Bool|Bytes|Map|Slice|Empty)'):
+            if call.args and call.args[0].literal:
+                yield KeyUse("log", call.args[0].literal, call.args[0], source)
+
+def _preferred(uses: List[KeyUse]) -> KeyUse:
+    """The use whose key the others should adopt: conventional, namespaced, and from spans first"""
+
+    return min(uses, key=lambda u: (len(attribute_key_problems(u.key)), "." not in u.key,
+                                    SIGNALS.index(u.signal), u.source.path, u.arg.start))
+
+@rule(
+    rule_id="cross-signal-attribute-key",
+    title="Use one attribute key per concept across spans, metrics and logs",
+    category="conventions",
+    signal="all",
+    severity="medium",
+    autofix=True,
+    scope="project",
+    description="Backends join signals on attribute keys: a dashboard going from a metric to its exemplar "
+                "traces, or from a span to its logs, filters on the same key in both. \"order.id\" on spans "
+                "with \"orderId\" on metric attributes and \"order_id\" in log fields are three attributes "
+                "to every query. Keys with the same words are compared across signals, and each divergent "
+                "signal pair is reported at the key that differs from the conventional one.",
+    bad_example='''
+func placeOrder(ctx context.Context, orderID string) {
+	ctx, span := tracer.Start(ctx, "place order")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.id", orderID), attribute.String("order.channel", "web"))
+	ordersPlaced.Add(ctx, 1, metric.WithAttributes(attribute.String("orderChannel", "web")))
+	slog.InfoContext(ctx, "order placed", "order_id", orderID)
+}''',
+    good_example='''
+func refundPayment(ctx context.Context, refundID string) {
+	ctx, span := tracer.Start(ctx, "refund payment")
+	defer span.End()
+	span.SetAttributes(attribute.String("refund.id", refundID), attribute.String("refund.reason", "damaged"))
+	refundsIssued.Add(ctx, 1, metric.WithAttributes(attribute.String("refund.reason", "damaged")))
+	slog.InfoContext(ctx, "payment refunded", "refund.id", refundID)
+}''',
+)
+def check_cross_signal_attribute_key(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    by_concept: Dict[Tuple[str, ...], Dict[str, List[KeyUse]]] = {}
+    for source in sources:
+        if source.path.endswith("_test.go"):
+            continue
+        for use in key_uses(source):
+            words = concept(use.key)
+            if len(words) > 1 or "." in use.key:
+                by_concept.setdefault(words, {}).setdefault(use.signal, []).append(use)
+    for words, signals in sorted(by_concept.items()):
+        if len(signals) < 2:
+            continue
+        preferred = _preferred([u for uses in signals.values() for u in uses])
+        for i, first in enumerate(SIGNALS):
+            for second in SIGNALS[i + 1:]:
+                if first not in signals or second not in signals:
+                    continue
+                keys = {u.key for u in signals[first]}, {u.key for u in signals[second]}
+                if keys[0] & keys[1]:
+                    continue
+                if preferred.key not in keys[0] | keys[1] and preferred.signal not in (first, second):
+                    continue  # both sides are reported against the signal using the preferred key
+                # Report the side that doesn't use the preferred key, or both when neither does
+                for signal, other in ((first, second), (second, first)):
+                    if preferred.key in {u.key for u in signals[signal]}:
+                        continue
+                    reference = next((u for u in signals[other] if u.key == preferred.key), signals[other][0])
+                    for use in signals[signal]:
+                        yield Diagnostic(
+                            pos=use.arg.start,
+                            end=use.arg.end,
+                            message=f'{NOUN[use.signal].capitalize()} "{use.key}" names the same concept as '
+                                    f'{NOUN[reference.signal]} "{reference.key}" ({reference.where})',
+                            suggestion=f'Use "{preferred.key}" on every signal',
+                            confidence=0.75,
+                            fix=Fix(description=f'Rename "{use.key}" to "{preferred.key}"',
+                                    edits=[TextEdit(use.arg.start, use.arg.end, f'"{preferred.key}"')]),
+                            file=use.source,
+                        )
+
+# semconv/vX.Y.Z and its subpackages
+SEMCONV_VERSION_PATH = re.compile(re.escape(SEMCONV_PKG) + r'/v(\d+)\.(\d+)\.(\d+)(?:/\w+)?$')
+# Deprecated key -> the key that replaced it; net.peer.* have two, depending on the span kind
+DEPRECATED_KEYS = {r.key: r.new for r in KEY_RENAMES if r.new is not None and not r.constant_only}
+# Go identifiers of the keys on either side (HTTPMethodKey, HTTPRequestMethodKey) -> their key
+KEY_CONSTANTS = {go_constant(k): k for pair in DEPRECATED_KEYS.items() for k in pair}
+# Files listed per version in a finding
+SHOWN_FILES = 3
+
+@dataclass
+class SpellingUse:
+    key: str
+    source: GoFile
+    start: int
+    end: int
+    # Literal keys can be renamed in place; constants need the semconv version that has the new one
+    literal: bool
+
+    @property
+    def where(self) -> str:
+        return f"{Path(self.source.path).name}:{self.source.line_of(self.start)}"
+
+def _version_text(version: Tuple[int, ...]) -> str:
+    return "v" + ".".join(str(n) for n in version)
+
+def _semconv_imports(source: GoFile) -> Iterator[Tuple[Tuple[int, int, int], int, int]]:
+    """(version, start, end) of the file's semconv imports, subpackages included"""
+
+    for path in source.imports.values():
+        m = SEMCONV_VERSION_PATH.match(path)
+        if m:
+            start = source.code.find(f'"{path}"')
+            yield (int(m.group(1)), int(m.group(2)), int(m.group(3))), start, start + len(path) + 2
+
+def _spellings(source: GoFile) -> Iterator[SpellingUse]:
+    """Uses of deprecated keys and their replacements: literal keys of spans, metrics and logs and
+    the semconv constants and helpers naming them"""
+
+    for use in key_uses(source):
+        if use.key in DEPRECATED_KEYS or use.key in DEPRECATED_KEYS.values():
+            yield SpellingUse(use.key, source, use.arg.start, use.arg.end, True)
+    for alias in source.import_alias(SEMCONV_PKG):
+        for m in re.finditer(r'(?<![\w.])' + re.escape(alias) + r'\.(\w+)\b', source.masked):
+            name = m.group(1)
+            key = KEY_CONSTANTS.get(name if name.endswith("Key") else name + "Key")
+            if key is not None:
+                yield SpellingUse(key, source, m.start(), m.end(), False)
+
+def _modules(sources: List[GoFile]) -> Dict[Optional[PurePath], List[GoFile]]:
+    modules: Dict[Optional[PurePath], List[GoFile]] = {}
+    for source in sources:
+        if not source.path.endswith("_test.go"):
+            modules.setdefault(module_root(source), []).append(source)
+    return modules
+
+@rule(
+    rule_id="semconv-version-mixed",
+    title="Use one semantic convention version per module",
+    category="conventions",
+    signal="all",
+    severity="medium",
+    autofix=True,
+    scope="project",
+    description="Each semconv/vX.Y.Z package spells the keys of its version: code importing v1.20.0 "
+                "records http.method and net.peer.name, code importing v1.26.0 http.request.method and "
+                "server.address. A module whose packages pin different versions, or write deprecated "
+                "keys as literals next to their replacements, sends the same attribute under two names, "
+                "and every dashboard, alert and query filtering on one of them misses the other's "
+                "telemetry. Files importing an older semconv version than the rest of the module are "
+                "reported with the files pinning each version (migrate-semconv moves them), and uses of "
+                "a deprecated key are reported where the module also uses its replacement. Modules are "
+                "told apart by their go.mod; test files don't count.",
+    bad_example='''
+func handleInventory(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "GET /inventory", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	span.SetAttributes(attribute.String("http.method", r.Method))
+	writeInventory(w)
+}
+
+func handlePrices(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "GET /prices", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.method", r.Method))
+	writePrices(w)
+}''',
+    good_example='''
+func handleStock(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "GET /stock", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.method", r.Method))
+	writeStock(w)
+}
+
+func handleOffers(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "GET /offers", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.method", r.Method))
+	writeOffers(w)
+}''',
+)
+def check_semconv_version_mixed(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for _, module in sorted(_modules(sources).items(), key=lambda item: str(item[0])):
+        # version -> the file names importing it
+        versions: Dict[Tuple[int, int, int], List[str]] = {}
+        imports = []
+        for source in module:
+            for version, start, end in _semconv_imports(source):
+                names = versions.setdefault(version, [])
+                # A subpackage of the same version (httpconv) is the same pin
+                if Path(source.path).name not in names:
+                    names.append(Path(source.path).name)
+                    imports.append((source, version, start, end))
+        newest = max(versions, default=None)
+        for source, version, start, end in imports:
+            if version == newest:
+                continue
+            others = "; ".join(
+                f"{_version_text(v)} in {', '.join(files[:SHOWN_FILES])}"
+                + (f" and {len(files) - SHOWN_FILES} more" if len(files) > SHOWN_FILES else "")
+                for v, files in sorted(versions.items(), reverse=True) if v != version)
+            yield Diagnostic(
+                pos=start,
+                end=end,
+                message=f"semconv {_version_text(version)} is imported here, while the module also pins "
+                        f"{others}",
+                suggestion=f"Move the module to semconv {_version_text(newest)}: otel_cli.py migrate-semconv "
+                           f"--from {_version_text(version[:2])} --to {_version_text(newest[:2])}",
+                confidence=0.9,
+                file=source,
+            )
+
+        uses: Dict[str, List[SpellingUse]] = {}
+        for source in module:
+            for use in _spellings(source):
+                uses.setdefault(use.key, []).append(use)
+        for old, new in sorted(DEPRECATED_KEYS.items()):
+            if old not in uses or new not in uses:
+                continue
+            for use in uses[old]:
+                current = next((u for u in uses[new] if u.source is use.source), uses[new][0])
+                fix = None
+                if use.literal:
+                    fix = Fix(description=f'Rename "{old}" to "{new}"',
+                              edits=[TextEdit(use.start, use.end, f'"{new}"')])
+                what = f'"{old}"' if use.literal else f'{use.source.code[use.start:use.end]} ("{old}")'
+                yield Diagnostic(
+                    pos=use.start,
+                    end=use.end,
+                    message=f'{what} is the deprecated spelling of "{new}", which the module also records '
+                            f'({current.where}); queries on either key miss the other\'s telemetry',
+                    suggestion=f'Record "{new}" everywhere' + ("" if use.literal else
+                               ", with the constant of a semconv version that has it"),
+                    confidence=0.85,
+                    fix=fix,
+                    file=use.source,
+                )
@@ -0,0 +1,62 @@
+"""
+Cancellation and deadlines for long analyses, modeled on Go's context.Context.
+Checks are cooperative: the engine polls err() between files, rules and diagnostics.
+"""
+
+import signal
+import threading
+import time
+from contextlib import contextmanager
+from typing import Optional
+
+CANCELED = "canceled"
+DEADLINE_EXCEEDED = "deadline exceeded"
+
+class Context:
+    def __init__(self, timeout: Optional[float] = None, parent: Optional["Context"] = None):
+        self.parent = parent
+        self.deadline = time.monotonic() + timeout if timeout else None
+        if parent and parent.deadline and (self.deadline is None or parent.deadline < self.deadline):
+            self.deadline = parent.deadline
+        self._canceled = threading.Event()
+        self.reason = ""
+
+    def with_timeout(self, timeout: Optional[float]) -> "Context":
+        """Child context that expires after timeout (or with this one, whichever comes first)"""
+        return Context(timeout, parent=self)
+
+    def cancel(self, reason: str = CANCELED):
+        if not self._canceled.is_set():
+            self.reason = reason
+            self._canceled.set()
+
+    def err(self) -> Optional[str]:
+        """None while the context is live, otherwise why it ended"""
+
+        if self._canceled.is_set():
+            return self.reason
+        if self.parent and self.parent.err():
+            return self.parent.err()
+        if self.deadline is not None and time.monotonic() >= self.deadline:
+            return DEADLINE_EXCEEDED
+        return None
+
+    def own_deadline_exceeded(self) -> bool:
+        """This context timed out on its own budget rather than inheriting the parent's end"""
+        return self.err() == DEADLINE_EXCEEDED and not (self.parent and self.parent.err())
+
+@contextmanager
+def cancel_on_interrupt(ctx: Context):
+    """Turn the first SIGINT into ctx.cancel(); a second one interrupts as usual"""
+
+    previous = signal.getsignal(signal.SIGINT)
+
+    def handler(signum, frame):
+        ctx.cancel("interrupted")
+        signal.signal(signal.SIGINT, previous)
+
+    signal.signal(signal.SIGINT, handler)
+    try:
+        yield ctx
+    finally:
+        signal.signal(signal.SIGINT, previous)
@@ -0,0 +1,162 @@
+"""
+Naming convention predicates shared by the rule engine and the ollytest helpers,
+so runtime assertions and static checks agree on what a good name looks like.
+"""
+
+import difflib
+import re
+from typing import List, Optional, Sequence
+
+HTTP_METHODS = ("GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH")
+
+# Unique-looking tokens that make a name high cardinality
+_HIGH_CARDINALITY = [
+    (re.compile(r'[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}'), "a UUID"),
+    (re.compile(r'\d{4,}'), "a long number (ID or timestamp)"),
+    (re.compile(r'[^\s@]+@[^\s@]+\.\w+'), "an email address"),
+]
+
+# Characters that may separate the words of a span name ("fetch user", "db.query", "GET /users")
+SPAN_NAME_SEPARATORS = (" ", ".", "-", "/", ":")
+
+def is_camel_case(text: str) -> bool:
+    return bool(re.search(r'[a-z][A-Z]', text)) and " " not in text
+
+def _brand_terms(terms: Sequence[str]) -> List[str]:
+    """Terms written with capitals ("PayPal", "iOS"), which names must spell exactly, longest first"""
+
+    return sorted((t for t in terms if t != t.lower()), key=len, reverse=True)
+
+def span_name(name: str, separator: str = " ", terms: Sequence[str] = ()) -> str:
+    """name rewritten as lowercase words ("processUserData" -> "process user data"), keeping the
+    spelling of brand terms ("chargePaypalAccount" -> "charge PayPal account" given "PayPal")"""
+
+    brands = _brand_terms(terms)
+    for i, term in enumerate(brands):
+        name = re.sub(r'(?<![A-Z])' + re.escape(term) + r'(?![a-z])', f" \x00{i}\x00 ", name)
+    name = re.sub(r'(?<=[a-z0-9])(?=[A-Z])', " ", name.strip())
+    name = separator.join(re.sub(r'[\s_]+', " ", name).lower().split())
+    for i, term in enumerate(brands):
+        name = name.replace(f"\x00{i}\x00", term)
+    for term in brands:
+        name = re.sub(r'(?<![^\W_])' + re.escape(term.lower()) + r'(?![^\W_])', term, name)
+    return name
+
+def span_name_problems(name: str, separators: Sequence[str] = SPAN_NAME_SEPARATORS,
+                       max_length: int = 0, terms: Sequence[str] = ()) -> List[str]:
+    """Convention problems with a span name ("{verb} {object}", low cardinality), with words
+    separated only by separators, brand terms spelled as listed in terms and, when max_length is
+    set, at most that many characters"""
+
+    problems = []
+    if not name.strip():
+        return ["span name is empty"]
+    brands = _brand_terms(terms)
+    for term in brands:
+        for m in re.finditer(r'(?i)(?<![^\W_])' + re.escape(term) + r'(?![^\W_])', name):
+            if m.group(0) != term:
+                problems.append(f"spells {m.group(0)!r} instead of {term!r}")
+    # Brand terms may be capitalized inside words; only the rest of the name is judged
+    for term in brands:
+        name = name.replace(term, term.lower())
+    for pattern, what in _HIGH_CARDINALITY:
+        if pattern.search(name):
+            problems.append(f"contains {what}")
+    if max_length and len(name) > max_length:
+        problems.append(f"is {len(name)} characters long (at most {max_length})")
+    # HTTP route templates are the one place mixed case and slashes are expected
+    first = name.split(" ", 1)[0]
+    if first.upper() in HTTP_METHODS:
+        if first != first.upper():
+            problems.append("HTTP method must be uppercase")
+        return problems
+    if is_camel_case(name):
+        problems.append("uses camelCase instead of '{verb} {object}'")
+    elif "_" in name and " " not in name and "_" not in separators:
+        problems.append("uses snake_case instead of '{verb} {object}'")
+    elif name.isupper() and len(name) > 3:
+        problems.append("is all uppercase")
+    # '_' is judged by the snake_case check above: inside a word it usually names an identifier
+    disallowed = sorted(set(re.findall(r'[ .\-/:]', name.strip())) - set(separators))
+    if disallowed:
+        problems.append(f"separates words with {', '.join(repr(c) for c in disallowed)} "
+                        f"(allowed: {', '.join(repr(c) for c in separators)})")
+    return problems
+
+def _mentions(words: List[str], term: str) -> bool:
+    """Whether the lowercase words contain term, a word or phrase, also in the plural"""
+
+    phrase = span_name(term).split()
+    for i in range(len(words) - len(phrase) + 1):
+        found = words[i:i + len(phrase)]
+        if found[:-1] == phrase[:-1] and found[-1] in (phrase[-1], phrase[-1] + "s", phrase[-1] + "es"):
+            return True
+    return False
+
+def vocabulary_problems(name: str, verbs: Sequence[str] = (), terms: Sequence[str] = ()) -> List[str]:
+    """What a span name fails to say given a team's dictionary: an approved operation verb
+    ("reserve", first in "reserve inventory", or last in a dotted "inventory.reserve") and at
+    least one of its domain terms. Routes and RPC methods are named by their protocol instead."""
+
+    first = name.strip().split(" ", 1)[0]
+    if not name.strip() or first.upper() in HTTP_METHODS or "/" in name:
+        return []
+    words = re.split(r'[\s.:\-]+', span_name(name, " ", terms).lower())
+    words = [w for w in words if w]
+    problems = []
+    approved = {v.lower() for v in verbs}
+    if approved and words[0] not in approved and not (" " not in name.strip() and words[-1] in approved):
+        close = difflib.get_close_matches(words[0], sorted(approved), n=1, cutoff=0.75)
+        problems.append(f"starts with {words[0]!r}, which isn't an approved operation verb"
+                        + (f" (did you mean {close[0]!r}?)" if close else ""))
+    if terms and not any(_mentions(words, t.lower()) for t in terms):
+        problems.append("names none of the domain terms")
+    return problems
+
+def event_name(name: str) -> str:
+    """name rewritten as lowercase, dot separated words ("cacheMiss" -> "cache.miss")"""
+
+    name = re.sub(r'(?<=[a-z0-9])(?=[A-Z])', ".", name.strip())
+    return re.sub(r'[\s_-]+', ".", name).lower()
+
+def event_name_problems(name: str, span_name: Optional[str] = None) -> List[str]:
+    """Convention problems with a span event name (lowercase, dot separated, low cardinality)"""
+
+    if not name.strip():
+        return ["event name is empty"]
+    problems = [f"contains {what}" for pattern, what in _HIGH_CARDINALITY if pattern.search(name)]
+    if is_camel_case(name):
+        problems.append("uses camelCase instead of lowercase dot separated words")
+    if " " in name.strip():
+        problems.append("contains spaces")
+    if span_name and event_name(name) == event_name(span_name):
+        problems.append("repeats the span's own name")
+    return problems
+
+def free_text_problems(value: str) -> List[str]:
+    """Problems with a value meant to be one of a few codes (a status, state or error.type):
+    free text with spaces, or several words glued to a numeric code ("APPROVED_OK_200_SUCCESS")"""
+
+    problems = [f"contains {what}" for pattern, what in _HIGH_CARDINALITY if pattern.search(value)]
+    words = [w for w in re.split(r'[_\-.:/]+', value.strip()) if w]
+    if re.search(r'\s', value.strip()):
+        return problems + ["is free text with spaces"]
+    if len(words) > 3:
+        problems.append(f"strings {len(words)} words together")
+    if len(words) > 1 and any(w.isdigit() for w in words) and any(w.isalpha() for w in words):
+        problems.append("mixes a numeric code with words")
+    return problems
+
+def attribute_key_problems(key: str) -> List[str]:
+    """Convention problems with an attribute key (lowercase, dot separated namespaces)"""
+
+    problems = []
+    if not key:
+        return ["attribute key is empty"]
+    if re.search(r'[A-Z]', key):
+        problems.append("contains uppercase letters")
+    if re.search(r'[^A-Za-z0-9._]', key):
+        problems.append("contains characters other than letters, digits, '.' and '_'")
+    if key.startswith(".") or key.endswith(".") or ".." in key:
+        problems.append("has an empty namespace segment")
+    return problems
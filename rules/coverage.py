@@ -0,0 +1,59 @@
+"""
+Trace coverage: the share of boundaries (routes, RPC methods, outgoing requests, database calls,
+message publishes and consumers) that are instrumented, overall, per kind and per package.
+A set with no boundaries counts as fully covered.
+"""
+
+from pathlib import Path
+from typing import Dict, List
+
+from .golang import GoFile
+from .traces.boundaries import KIND_LABELS, Boundary, find_boundaries, is_instrumented, wrapped_kinds
+
+def _tally(pairs: List[tuple]) -> Dict:
+    total = len(pairs)
+    instrumented = sum(1 for _, covered in pairs if covered)
+    return {
+        "boundaries": total,
+        "instrumented": instrumented,
+        "coverage": round(100.0 * instrumented / total, 1) if total else 100.0,
+    }
+
+def _package(boundary: Boundary, root: str) -> str:
+    directory = Path(boundary.source.path).resolve().parent
+    try:
+        return directory.relative_to(Path(root).resolve()).as_posix() or "."
+    except ValueError:
+        return directory.as_posix()
+
+def coverage_report(sources: List[GoFile], root: str = ".") -> Dict:
+    """Coverage over all boundaries in sources, broken down by kind and by package (relative to root),
+    with the uninstrumented boundaries listed"""
+
+    wrapped = wrapped_kinds(sources)
+    boundaries = sorted(find_boundaries(sources), key=lambda b: (b.source.path, b.pos))
+    pairs = [(b, is_instrumented(b, wrapped)) for b in boundaries]
+    by_kind = {kind: [p for p in pairs if p[0].kind == kind] for kind in KIND_LABELS}
+    packages: Dict[str, List[tuple]] = {}
+    for pair in pairs:
+        packages.setdefault(_package(pair[0], root), []).append(pair)
+
+    return dict(
+        _tally(pairs),
+        kinds={kind: dict(_tally(found), label=KIND_LABELS[kind]) for kind, found in by_kind.items() if found},
+        packages={
+            package: dict(_tally(found), kinds={
+                kind: _tally([p for p in found if p[0].kind == kind])
+                for kind in KIND_LABELS if any(p[0].kind == kind for p in found)
+            })
+            for package, found in sorted(packages.items())
+        },
+        uninstrumented=[{
+            "file": b.source.path,
+            "line": b.source.line_of(b.pos),
+            "kind": b.kind,
+            "function": b.func.name if b.func and not b.func.is_literal else "",
+            "target": b.target,
+            "suggested_span_name": b.suggested_name,
+        } for b, covered in pairs if not covered],
+    )
@@ -0,0 +1,304 @@
+"""
+Custom rules: organization conventions written in .ollygarden.yaml as CEL expressions (see
+cel.py) over the spans, attributes and calls of the code, no plugin needed.
+
+    custom_rules:
+      - id: billing-server-span-tenant
+        title: Server spans of the billing service record the tenant
+        target: span
+        when: span.kind == "server" && span.package_path.startsWith("example.com/shop/billing")
+        require: '"tenant.id" in span.attributes'
+        severity: high
+        message: "Server span {span.name} doesn't record tenant.id"
+
+Every span, attribute or call of the target kind for which when holds (every one when there's
+no when) is reported unless require holds too; require: "false" forbids what when selects.
+Custom rules run like the built-in ones: they can be disabled, re-severitied and suppressed by
+ID, and test files are left out.
+"""
+
+import re
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional
+
+from .base import Diagnostic, Rule, SEVERITIES, SIGNALS
+from .cel import CelError, Expression
+from .golang import GoFile, SpanStart, string_literal
+from .grouping import span_attributes
+from .logs.records import log_calls
+from .metrics.instruments import metric_attributes
+from .sdk.library import module_dir, module_file, module_root
+from .traces.attributes import attribute_calls
+from .traces.lifetime import span_calls
+
+# Fields every target has
+COMMON_FIELDS = {
+    "package": "name of the Go package",
+    "package_path": "import path of the package, from go.mod (the directory outside a module)",
+    "file": "path of the file",
+    "function": "enclosing function, empty at package level",
+}
+FIELDS = {
+    "span": {
+        "name": "the literal name, or the expression building it",
+        "dynamic": "whether the name is built at run time",
+        "kind": "server, client, producer, consumer or internal",
+        "attributes": "map of the keys set at the start and by SetAttributes to their literal values ('' when not literal)",
+        "events": "names of the events added with AddEvent",
+        "records_error": "whether the span gets RecordError",
+        "sets_status": "whether the span gets SetStatus",
+        "ended": "whether End is called on the span",
+        **COMMON_FIELDS,
+    },
+    "attribute": {
+        "key": "the literal key",
+        "value": "the literal value, or the expression",
+        "literal": "whether the value is a literal",
+        "type": "constructor: String, Int, Bool, ... ('' for log fields)",
+        "signal": "span, metric or log",
+        "span_kind": "kind of the span open around it, '' when none",
+        **COMMON_FIELDS,
+    },
+    "call": {
+        "callee": "the callee as written: http.Get, s.db.QueryContext",
+        "name": "the function or method name: Get, QueryContext",
+        "import": "import path when called through an import (net/http), '' otherwise",
+        "args": "the arguments as written",
+        "in_span": "whether a span started in the enclosing function is open around the call",
+        "span_kind": "kind of that span, '' when none",
+        **COMMON_FIELDS,
+    },
+}
+TARGETS = tuple(FIELDS)
+KEYS = {"id", "title", "target", "when", "require", "message", "suggestion", "severity", "category", "signal",
+        "description"}
+# Main signal of a target's rules unless the entry sets one
+TARGET_SIGNALS = {"span": "traces", "attribute": "traces", "call": "all"}
+# {expression} placeholders of a message or suggestion
+PLACEHOLDER = re.compile(r'\{([^{}]+)\}')
+# Identifiers followed by ( that aren't calls
+NOT_CALLS = {"if", "for", "switch", "return", "func", "go", "defer", "select", "case", "range", "map", "chan",
+             "interface", "struct", "else", "var", "const", "type", "import"}
+
+@dataclass
+class Item:
+    """One span, attribute or call with its model, positioned for reporting"""
+    pos: int
+    end: int
+    model: Dict[str, Any]
+    what: str
+
+def package_path(source: GoFile) -> str:
+    """Import path of the file's package: the module path joined with its directory"""
+
+    root = module_root(source)
+    if root is None:
+        return Path(source.path).parent.as_posix()
+    m = re.search(r'^module\s+"?([^\s"]+)"?', module_file(source, root), re.M)
+    relative = module_dir(source, root).as_posix()
+    return (m.group(1) if m else root.name) + ("" if relative == "." else "/" + relative)
+
+def _common(source: GoFile, pos: int) -> Dict[str, Any]:
+    fn = source.func_at(pos)
+    return {"package": source.package, "package_path": package_path(source), "file": source.path,
+            "function": fn.name if fn is not None else ""}
+
+def _open_span(source: GoFile, pos: int) -> Optional[SpanStart]:
+    """The span started last before pos in the function around it"""
+
+    starts = [s for s in source.span_starts if s.func is not None and s.func.contains(pos) and s.call.end <= pos]
+    return max(starts, key=lambda s: s.call.start, default=None)
+
+def spans(source: GoFile) -> Iterator[Item]:
+    for start in source.span_starts:
+        named = start.span_var and start.span_var != "_" and start.func is not None
+        events = [c.args[0].literal for c in span_calls(source, start, "AddEvent")
+                  if c.args and c.args[0].literal is not None] if named else []
+        called = {c.name.rsplit(".", 1)[-1] for c in span_calls(source, start, "RecordError|SetStatus|End")} \
+            if named else set()
+        name = start.name if start.name is not None else (start.name_arg.text.strip() if start.name_arg else "")
+        model = {
+            "name": name,
+            "dynamic": start.name is None,
+            "kind": start.kind or "internal",
+            "attributes": span_attributes(source, start),
+            "events": events,
+            "records_error": "RecordError" in called,
+            "sets_status": "SetStatus" in called,
+            "ended": "End" in called,
+            **_common(source, start.call.start),
+        }
+        yield Item(start.call.start, start.call.end, model, f'span "{name}"' if start.name is not None else f"span {name}")
+
+def attributes(source: GoFile) -> Iterator[Item]:
+    on_metrics = {call.start for call in metric_attributes(source)}
+    for call in attribute_calls(source):
+        if not call.args or call.args[0].literal is None:
+            continue
+        value = call.args[1] if len(call.args) > 1 else None
+        signal = "metric" if call.start in on_metrics else "span"
+        yield _attribute(source, call.args[0].literal, value, call.name.rsplit(".", 1)[-1], signal, call.args[0])
+    for call in log_calls(source):
+        for key, key_arg, value in call.fields:
+            if key and key_arg is not None:
+                yield _attribute(source, key, value, "", "log", key_arg)
+
+def _attribute(source: GoFile, key: str, value, constructor: str, signal: str, at) -> Item:
+    literal = string_literal(value.text) if value is not None else None
+    if literal is None and value is not None and re.fullmatch(r'-?\d+(?:\.\d+)?|true|false', value.text.strip()):
+        literal = value.text.strip()
+    span = _open_span(source, at.start)
+    model = {
+        "key": key,
+        "value": literal if literal is not None else (value.text.strip() if value is not None else ""),
+        "literal": literal is not None,
+        "type": constructor,
+        "signal": signal,
+        "span_kind": (span.kind or "internal") if span is not None else "",
+        **_common(source, at.start),
+    }
+    return Item(at.start, at.end, model, f"{signal} attribute {key}" if signal != "log" else f"log field {key}")
+
+def calls(source: GoFile) -> Iterator[Item]:
+    for call in source.calls(r'[A-Za-z_]\w*(?:\.\w+)*'):
+        head = call.name.split(".", 1)[0]
+        if head in NOT_CALLS or re.search(r'\bfunc\s*(?:\([^()]*\)\s*)?$', source.masked[max(0, call.start - 80):call.start]):
+            continue
+        qualifier, _, name = call.name.rpartition(".")
+        span = _open_span(source, call.start)
+        model = {
+            "callee": call.name,
+            "name": name,
+            "import": source.imports.get(qualifier, "") if qualifier and "." not in qualifier else "",
+            "args": [a.text.strip() for a in call.args],
+            "in_span": span is not None,
+            "span_kind": (span.kind or "internal") if span is not None else "",
+            **_common(source, call.start),
+        }
+        yield Item(call.start, call.end, model, f"call to {call.name}")
+
+ITEMS = {"span": spans, "attribute": attributes, "call": calls}
+
+def _expression(entry: Dict, key: str, target: str) -> Expression:
+    try:
+        expression = Expression(entry[key])
+    except CelError as e:
+        raise CelError(f"{key}: {e}")
+    _check_names(expression, key, target)
+    return expression
+
+def _check_names(expression: Expression, key: str, target: str):
+    unknown = sorted(expression.variables() - {target})
+    if unknown:
+        raise CelError(f"{key}: unknown variable '{unknown[0]}'; {target} rules see {target}")
+    fields = sorted(expression.fields(target) - set(FIELDS[target]))
+    if fields:
+        raise CelError(f"{key}: {target} has no field {fields[0]}; it has {', '.join(sorted(FIELDS[target]))}")
+
+def _template(entry: Dict, key: str, target: str) -> List[Expression]:
+    text = entry.get(key)
+    if text is None:
+        return []
+    if not isinstance(text, str):
+        raise CelError(f"{key} must be a string")
+    placeholders = []
+    for m in PLACEHOLDER.finditer(text):
+        try:
+            expression = Expression(m.group(1))
+        except CelError as e:
+            raise CelError(f"{key}: {{{m.group(1)}}}: {e}")
+        _check_names(expression, key, target)
+        placeholders.append(expression)
+    return placeholders
+
+def _render(text: str, env: Dict[str, Any]) -> str:
+    def value(m):
+        try:
+            result = Expression(m.group(1)).evaluate(env)
+        except CelError:
+            return m.group(0)
+        if isinstance(result, bool):
+            return "true" if result else "false"
+        return ", ".join(map(str, result)) if isinstance(result, list) else str(result)
+    return PLACEHOLDER.sub(value, text)
+
+def custom_rule(entry: Dict, categories) -> Rule:
+    """The Rule a custom_rules entry defines; raises ValueError (CelError for expressions) with
+    what's wrong with it"""
+
+    if not isinstance(entry, dict):
+        raise ValueError("must map id, title, target and require")
+    unknown = sorted(str(k) for k in set(entry) - KEYS)
+    if unknown:
+        raise ValueError(f"unknown key(s) {', '.join(unknown)}")
+    missing = [k for k in ("id", "title", "target", "require") if not entry.get(k)]
+    if missing:
+        raise ValueError(f"needs {', '.join(missing)}")
+    rule_id, target = str(entry["id"]), entry["target"]
+    if not re.fullmatch(r'[a-z][a-z0-9]*(?:-[a-z0-9]+)*', rule_id):
+        raise ValueError("id must be lowercase words joined by dashes, like billing-span-tenant")
+    if target not in TARGETS:
+        raise ValueError(f"unknown target '{target}' (choose from {', '.join(TARGETS)})")
+    severity = entry.get("severity", "medium")
+    if severity not in SEVERITIES:
+        raise ValueError(f"unknown severity '{severity}'")
+    category = entry.get("category", "conventions")
+    if category not in categories:
+        raise ValueError(f"unknown category '{category}' (choose from {', '.join(sorted(categories))})")
+    signal = entry.get("signal", TARGET_SIGNALS[target])
+    if signal != "all" and signal not in SIGNALS:
+        raise ValueError(f"unknown signal '{signal}'")
+    when = _expression(entry, "when", target) if entry.get("when") is not None else None
+    require = _expression(entry, "require", target)
+    _template(entry, "message", target)
+    _template(entry, "suggestion", target)
+    title = str(entry["title"])
+    message = entry.get("message")
+    suggestion = entry.get("suggestion") or f"Make {target}s{' where ' + when.text if when else ''} " \
+                                             f"satisfy {require.text}"
+
+    def check(source: GoFile) -> Iterator[Diagnostic]:
+        if source.path.endswith("_test.go"):
+            return
+        for item in ITEMS[target](source):
+            env = {target: item.model}
+            try:
+                if when is not None and when.evaluate(env) is not True:
+                    continue
+            except CelError:
+                continue
+            try:
+                if require.evaluate(env) is True:
+                    continue
+                problem = ""
+            except CelError as e:
+                problem = f" (require failed to evaluate: {e})"
+            if message:
+                text = _render(message, env)
+            elif require.text.strip() == "false":
+                text = f"{title}: {item.what}"
+            else:
+                text = f"{title}: {item.what} doesn't satisfy {require.text}"
+            yield Diagnostic(
+                pos=item.pos,
+                end=item.end,
+                message=text + problem,
+                suggestion=_render(suggestion, env),
+                confidence=0.8,
+            )
+
+    signals = SIGNALS if signal == "all" else (signal,)
+    if target == "attribute" and "signal" not in entry:
+        signals = ("traces", "metrics", "logs")
+    return Rule(
+        rule_id=rule_id,
+        title=title,
+        category=category,
+        signal=signal,
+        severity=severity,
+        description=str(entry.get("description") or f"Custom rule: every {target}"
+                        f"{' where ' + when.text if when else ''} must satisfy {require.text}."),
+        check=check,
+        signals=signals,
+    )
@@ -0,0 +1,251 @@
+"""
+Deterministic rule engine. Runs registered rules over Go sources and converts their
+diagnostics into TelemetryViolation objects so they share output with the LLM analyzer.
+"""
+
+import time
+from contextlib import contextmanager
+from dataclasses import dataclass
+from pathlib import Path
+from typing import List, Dict, Optional, Iterable, Iterator, Callable
+
+from .base import Rule, Diagnostic, TelemetryViolation, CodeLocation
+from .baseline import Baseline
+from .context import Context
+from .escalation import DEFAULT_ESCALATION, escalate, span_class
+from .golang import GoFile, SpanHelper
+from .plugins import PluginError
+from .registry import default_rules
+from .suppression import Suppression, suppressions
+
+# progress(phase, done, total), called after each file loaded and each package analyzed
+ProgressCallback = Callable[[str, int, int], None]
+
+# Package name under which cross-package rules are reported and timed
+PROJECT_PACKAGE = "(cross-package rules)"
+
+@dataclass
+class RuleTiming:
+    """Time one rule spent on one package"""
+    rule_id: str
+    package: str
+    start: float
+    seconds: float = 0.0
+    findings: int = 0
+
+class RuleEngine:
+    """Runs file-scope and project-scope rules"""
+
+    def __init__(self, rules: Optional[List[Rule]] = None, severity_overrides: Optional[Dict[str, str]] = None,
+                 rule_options: Optional[Dict[str, Dict]] = None, escalation: Optional[Dict[str, Dict]] = None,
+                 span_helpers: Optional[List[SpanHelper]] = None):
+        self.rules = rules if rules is not None else default_rules()
+        self.severity_overrides = severity_overrides or {}
+        self.rule_options = rule_options or {}
+        # category -> span class -> severity or step, applied after severity overrides
+        self.escalation = escalation if escalation is not None else DEFAULT_ESCALATION
+        self.span_helpers = span_helpers or []
+        # Findings to leave out of results, and how many were left out so far
+        self.baseline: Optional[Baseline] = None
+        self.baselined = 0
+        # Path -> otel:ignore directives of the current run, parsed on first use
+        self._suppressions: Dict[str, List[Suppression]] = {}
+        # Package -> reason, for packages the last run could not finish
+        self.incomplete: Dict[str, str] = {}
+        # Set to a list to have every run append a RuleTiming per rule and package (or file)
+        self.timings: Optional[List[RuleTiming]] = None
+
+    @classmethod
+    def from_config(cls, config) -> "RuleEngine":
+        """Engine running the rules a project Config selects, with its overrides, span helpers and
+        baseline (when the baseline file exists)"""
+        engine = cls(config.select_rules(), config.severity, config.options, config.escalation_levels(),
+                     config.span_helpers)
+        engine.baseline = config.load_baseline()
+        return engine
+
+    def analyze(self, code: str, file_path: str) -> List[TelemetryViolation]:
+        """Run file-scope rules over a single source file"""
+
+        if Path(file_path).suffix.lower() != ".go":
+            return []
+
+        source = GoFile(file_path, code, self.span_helpers)
+        self._suppressions = {}
+        violations = []
+        for rule in self.rules:
+            if rule.scope != "file":
+                continue
+            with self._timed(rule, str(Path(file_path).parent)) as timing:
+                for diag in self._run(rule, source) or []:
+                    if self._suppressed(rule, source, diag):
+                        continue
+                    violations.append(self._to_violation(rule, source, diag))
+                    timing.findings += 1
+        return self._new(self._sorted(violations))
+
+    def analyze_files(self, paths: Iterable[str], progress: Optional[ProgressCallback] = None,
+                      ctx: Optional[Context] = None, package_timeout: Optional[float] = None) -> Dict[str, List[TelemetryViolation]]:
+        """Run all rules over a set of files, including cross-file checks"""
+
+        ctx = ctx or Context()
+        paths = [p for p in paths if Path(p).suffix.lower() == ".go"]
+        sources = []
+        for i, path in enumerate(paths, 1):
+            if ctx.err():
+                break
+            with open(path, "r", encoding="utf-8") as f:
+                sources.append(GoFile(str(path), f.read(), self.span_helpers))
+            if progress:
+                progress("Loading files", i, len(paths))
+        results = self.analyze_sources(sources, progress, ctx, package_timeout)
+        if len(sources) < len(paths):
+            self.incomplete["(loading)"] = f"{ctx.err()} after loading {len(sources)} of {len(paths)} files"
+        return results
+
+    def analyze_sources(self, sources: List[GoFile], progress: Optional[ProgressCallback] = None,
+                        ctx: Optional[Context] = None, package_timeout: Optional[float] = None) -> Dict[str, List[TelemetryViolation]]:
+        """Run all rules over already loaded sources, one package (directory) at a time.
+        When ctx ends, or a package exceeds package_timeout seconds, the results so far are
+        returned and the packages that were cut short are listed in self.incomplete."""
+
+        ctx = ctx or Context()
+        self.incomplete = {}
+        # The same paths may come with other contents, e.g. both revisions of a diff
+        self._suppressions = {}
+        results: Dict[str, List[TelemetryViolation]] = {s.path: [] for s in sources}
+        packages: Dict[str, List[GoFile]] = {}
+        for source in sources:
+            if self.span_helpers:
+                source.use_span_helpers(self.span_helpers)
+            packages.setdefault(str(Path(source.path).parent), []).append(source)
+        for files in packages.values():
+            for source in files:
+                source.package_sources = files
+        file_rules = [r for r in self.rules if r.scope == "file"]
+        project_rules = [r for r in self.rules if r.scope != "file"]
+        total = len(packages) + (1 if project_rules else 0)
+
+        for done, package in enumerate(sorted(packages), 1):
+            if ctx.err():
+                self.incomplete[package] = f"not analyzed ({ctx.err()})"
+                continue
+            package_ctx = ctx.with_timeout(package_timeout)
+            for rule in file_rules:
+                with self._timed(rule, package) as timing:
+                    for source in packages[package]:
+                        for diag in self._checked(self._run(rule, source), package_ctx):
+                            if self._suppressed(rule, source, diag):
+                                continue
+                            results[source.path].append(self._to_violation(rule, source, diag))
+                            timing.findings += 1
+            if package_ctx.own_deadline_exceeded():
+                self.incomplete[package] = f"exceeded its {package_timeout:g}s budget"
+            elif package_ctx.err():
+                self.incomplete[package] = f"partially analyzed ({package_ctx.err()})"
+            if progress:
+                progress("Analyzing packages", done, total)
+
+        if project_rules:
+            for rule in project_rules:
+                with self._timed(rule, PROJECT_PACKAGE) as timing:
+                    try:
+                        for diag in self._checked(self._run(rule, sources), ctx):
+                            if self._suppressed(rule, diag.file, diag):
+                                continue
+                            results[diag.file.path].append(self._to_violation(rule, diag.file, diag))
+                            timing.findings += 1
+                    except PluginError as e:
+                        self.incomplete[f"plugin {e.plugin}"] = e.reason
+            if ctx.err():
+                self.incomplete[PROJECT_PACKAGE] = f"partially analyzed ({ctx.err()})"
+            if progress:
+                progress("Analyzing packages", total, total)
+
+        return {path: self._new(self._sorted(v)) for path, v in results.items()}
+
+    def _new(self, violations: List[TelemetryViolation]) -> List[TelemetryViolation]:
+        if self.baseline is None:
+            return violations
+        new = self.baseline.new_findings(violations)
+        self.baselined += len(violations) - len(new)
+        return new
+
+    @contextmanager
+    def _timed(self, rule: Rule, package: str):
+        timing = RuleTiming(rule.rule_id, package, time.perf_counter())
+        try:
+            yield timing
+        finally:
+            if self.timings is not None:
+                timing.seconds = time.perf_counter() - timing.start
+                self.timings.append(timing)
+
+    def _run(self, rule: Rule, target):
+        if not rule.options:
+            return rule.check(target)
+        return rule.check(target, {**rule.options, **self.rule_options.get(rule.rule_id, {})})
+
+    def _suppressed(self, rule: Rule, source: GoFile, diag: Diagnostic) -> bool:
+        """Whether an otel:ignore directive with a reason covers the finding"""
+
+        if rule.rule_id == "invalid-suppression":
+            return False
+        if source.path not in self._suppressions:
+            self._suppressions[source.path] = suppressions(source) if "otel:ignore" in source.code else []
+        return any(s.covers(rule.rule_id, diag.pos) for s in self._suppressions[source.path])
+
+    @staticmethod
+    def _checked(diagnostics, ctx: Context) -> Iterator[Diagnostic]:
+        """Diagnostics from a check until ctx ends"""
+
+        if ctx.err():
+            return
+        for diag in diagnostics or []:
+            yield diag
+            if ctx.err():
+                return
+
+    def _to_violation(self, rule: Rule, source: GoFile, diag: Diagnostic) -> TelemetryViolation:
+        line = source.line_of(diag.pos)
+        end = diag.end if diag.end is not None else source.expression_end(diag.pos)
+        end_line_end = source.line_start(line) + len(source.lines[line - 1])
+        start_context = max(0, line - 3)
+        end_context = min(len(source.lines), line + 2)
+
+        return TelemetryViolation(
+            violation_id=f"{rule.rule_id}_{line}",
+            severity=self._severity(rule, source, diag),
+            file_path=source.path,
+            location=CodeLocation(
+                line_number=line,
+                column=source.column_of(diag.pos),
+                function_name=source.function_name_at(diag.pos),
+                code_snippet=source.lines[line - 1].strip(),
+                context_lines=source.lines[start_context:end_context],
+                end_column=source.column_of(max(diag.pos, min(end, end_line_end) - 1)),
+            ),
+            violation_type=rule.category,
+            rule_violated=rule.title,
+            description=diag.message,
+            fix_suggestion=diag.suggestion,
+            kb_reference=rule.kb_reference,
+            confidence=diag.confidence,
+            detection_method="rule_engine",
+            language="go",
+            rule_id=rule.rule_id,
+            fix=diag.fix,
+        )
+
+    def _severity(self, rule: Rule, source: GoFile, diag: Diagnostic) -> str:
+        severity = self.severity_overrides.get(rule.rule_id) or diag.severity or rule.severity
+        levels = self.escalation.get(rule.category)
+        if levels:
+            level = levels.get(span_class(source, diag.pos))
+            if level is not None:
+                severity = escalate(severity, level)
+        return severity
+
+    @staticmethod
+    def _sorted(violations: List[TelemetryViolation]) -> List[TelemetryViolation]:
+        return sorted(violations, key=lambda v: (v.location.line_number, v.location.column, v.rule_id))
@@ -0,0 +1,62 @@
+"""
+Severity escalation by span boundary.
+
+A finding inside a span that crosses a process boundary (server, client, producer or consumer)
+is what other services and dashboards see, so it usually matters more than the same finding on
+an internal span. Escalation is configured per rule category and span class:
+
+    escalation:
+      conventions:
+        boundary: high      # a fixed severity
+        internal: "-1"      # or a step up (+) or down (-) from the rule's severity
+"""
+
+from typing import Dict, Optional
+
+from .base import SEVERITIES
+from .golang import GoFile
+
+SPAN_CLASSES = ("boundary", "internal")
+BOUNDARY_KINDS = ("server", "client", "producer", "consumer")
+
+# Applied unless the project config replaces the entry for a category
+DEFAULT_ESCALATION: Dict[str, Dict[str, str]] = {
+    "conventions": {"boundary": "+1"},
+    "propagation": {"boundary": "+1"},
+}
+
+def span_class(source: GoFile, pos: int) -> Optional[str]:
+    """"boundary" or "internal" for the span started last before pos in the function enclosing it,
+    None when pos is not under any span started in that file"""
+
+    current = None
+    for start in source.span_starts:
+        if start.func is None or not start.func.contains(pos) or start.call.start > pos:
+            continue
+        if current is None or start.call.start > current.call.start:
+            current = start
+    if current is None:
+        return None
+    return "boundary" if current.kind in BOUNDARY_KINDS else "internal"
+
+def _normalized(level) -> str:
+    # YAML reads an unquoted +1 or -1 as an integer
+    return f"{level:+d}" if isinstance(level, int) else str(level)
+
+def valid_level(level) -> bool:
+    """A severity name, or a signed step such as "+1" or "-2" """
+
+    level = _normalized(level)
+    if level in SEVERITIES:
+        return True
+    return level[:1] in "+-" and level[1:].isdigit()
+
+def escalate(severity: str, level) -> str:
+    """severity adjusted by level (see valid_level), clamped to the known severities"""
+
+    level = _normalized(level)
+    if level in SEVERITIES:
+        return level
+    # SEVERITIES runs from most to least severe, so stepping up moves towards index 0
+    index = SEVERITIES.index(severity) - int(level)
+    return SEVERITIES[min(max(index, 0), len(SEVERITIES) - 1)]
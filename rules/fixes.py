@@ -0,0 +1,99 @@
+"""
+Applying suggested fixes to source files
+"""
+
+import difflib
+import os
+import re
+from pathlib import Path
+from typing import List, Optional, Tuple
+
+from .base import Fix, TextEdit
+from .golang import GoFile, match_bracket
+
+def apply_fixes(code: str, fixes: List[Fix]) -> Tuple[str, int]:
+    """Apply non-conflicting fixes, returning the new code and how many fixes were applied.
+    A fix is skipped as a whole if any of its edits overlaps an edit already accepted.
+    Identical edits shared by several fixes (e.g. one hoisted declaration) are applied once."""
+
+    accepted: List[TextEdit] = []
+    seen = set()
+    applied = 0
+    for fix in fixes:
+        new_edits = [e for e in fix.edits if (e.start, e.end, e.new_text) not in seen]
+        if any(_overlaps(e, other) for e in new_edits for other in accepted):
+            continue
+        for e in new_edits:
+            seen.add((e.start, e.end, e.new_text))
+            accepted.append(e)
+        applied += 1
+
+    for edit in sorted(accepted, key=lambda e: (e.start, e.end), reverse=True):
+        code = code[:edit.start] + edit.new_text + code[edit.end:]
+    return code, applied
+
+def import_edit(source: GoFile, path: str, alias: Optional[str] = None) -> TextEdit:
+    """Insertion adding an import of path: as the last spec of the last import block, else after
+    the single-line imports or the package clause. Fixes needing the import share this edit, so
+    it is applied once."""
+
+    spec = (f"{alias} " if alias else "") + f'"{path}"'
+    blocks = list(re.finditer(r'^import\s*\(', source.masked, re.M))
+    if blocks:
+        close = match_bracket(source.masked, blocks[-1].end() - 1)
+        line_start = source.masked.rfind("\n", 0, close) + 1
+        return TextEdit(line_start, line_start, f"\t{spec}\n")
+    end = source.decl_insert_pos()
+    if re.search(r'^import\s', source.masked, re.M):
+        return TextEdit(end, end, f"\nimport {spec}")
+    return TextEdit(end, end, f"\n\nimport {spec}")
+
+def unused_import_edit(source: GoFile, alias: str, replaced: List[Tuple[int, int]]) -> Optional[TextEdit]:
+    """Deletion of the import alias names when every use of it is inside the replaced ranges,
+    so fixes replacing them all don't leave it unused; None while other uses remain"""
+
+    path = source.imports.get(alias)
+    body = source.decl_insert_pos()
+    uses = [m.start() for m in re.finditer(r'(?<![\w.])' + re.escape(alias) + r'\.', source.masked[body:])]
+    if path is None or any(not any(lo <= body + u < hi for lo, hi in replaced) for u in uses):
+        return None
+    for pos, imported in source.import_positions(path):
+        if imported == path:
+            # The line with the newline before it, keeping clear of import_edit's insertion
+            start = source.code.rfind("\n", 0, pos)
+            end = source.code.find("\n", pos)
+            return TextEdit(start, len(source.code) if end == -1 else end, "")
+    return None
+
+def _overlaps(a: TextEdit, b: TextEdit) -> bool:
+    if a.start == a.end and b.start == b.end:
+        return False
+    return a.start < b.end and b.start < a.end
+
+def diff_root(path: str) -> Path:
+    """Directory fix_diff paths are relative to: the repository holding path (where `git apply`
+    runs), else its Go module, else the current directory"""
+
+    parents = Path(path).resolve().parents
+    for marker in (".git", "go.mod"):
+        for directory in parents:
+            if (directory / marker).exists():
+                return directory
+    return Path.cwd()
+
+def fix_diff(path: str, original: str, fixed: str) -> str:
+    """Unified diff in git format (a/ and b/ prefixes, paths relative to diff_root), applicable
+    with `git apply` from that directory"""
+
+    if original == fixed:
+        return ""
+    path = Path(os.path.relpath(Path(path).resolve(), diff_root(path))).as_posix()
+    lines = []
+    for line in difflib.unified_diff(
+        original.splitlines(keepends=True), fixed.splitlines(keepends=True),
+        fromfile=f"a/{path}", tofile=f"b/{path}",
+    ):
+        lines.append(line)
+        if not line.endswith("\n"):
+            lines.append("\n\\ No newline at end of file\n")
+    return f"diff --git a/{path} b/{path}\n" + "".join(lines)
@@ -0,0 +1,161 @@
+"""
+Generates labeled Go fixtures from rule metadata and checks rules against them.
+Each rule's bad_example must trigger the rule and its good_example must not.
+"""
+
+import re
+from pathlib import Path
+from typing import List, Dict, Tuple
+
+from .base import Rule
+from .engine import RuleEngine
+from .golang import GoFile
+
+# Package qualifier -> import path, used to build the import block for a snippet
+KNOWN_IMPORTS = {
+    "context": "context",
+    "errors": "errors",
+    "fmt": "fmt",
+    "http": "net/http",
+    "log": "log",
+    "net": "net",
+    "os": "os",
+    "rand": "math/rand",
+    "signal": "os/signal",
+    "slog": "log/slog",
+    "sql": "database/sql",
+    "strconv": "strconv",
+    "strings": "strings",
+    "sync": "sync",
+    "syscall": "syscall",
+    "time": "time",
+    "otel": "go.opentelemetry.io/otel",
+    "attribute": "go.opentelemetry.io/otel/attribute",
+    "baggage": "go.opentelemetry.io/otel/baggage",
+    "codes": "go.opentelemetry.io/otel/codes",
+    "metric": "go.opentelemetry.io/otel/metric",
+    "otellog": "go.opentelemetry.io/otel/log",
+    "otelslog": "go.opentelemetry.io/contrib/bridges/otelslog",
+    "propagation": "go.opentelemetry.io/otel/propagation",
+    "trace": "go.opentelemetry.io/otel/trace",
+    "sdktrace": "go.opentelemetry.io/otel/sdk/trace",
+    "sdkmetric": "go.opentelemetry.io/otel/sdk/metric",
+    "resource": "go.opentelemetry.io/otel/sdk/resource",
+    "semconv": "go.opentelemetry.io/otel/semconv/v1.26.0",
+    "prometheus": "github.com/prometheus/client_golang/prometheus",
+    "otlptrace": "go.opentelemetry.io/otel/exporters/otlp/otlptrace",
+    "otlptracegrpc": "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc",
+    "jaeger": "go.opentelemetry.io/otel/exporters/jaeger",
+    "stdouttrace": "go.opentelemetry.io/otel/exporters/stdout/stdouttrace",
+    "octrace": "go.opencensus.io/trace",
+    "stats": "go.opencensus.io/stats",
+    "view": "go.opencensus.io/stats/view",
+    "tag": "go.opencensus.io/tag",
+    "opencensus": "go.opentelemetry.io/otel/bridge/opencensus",
+    "opentracing": "github.com/opentracing/opentracing-go",
+    "ext": "github.com/opentracing/opentracing-go/ext",
+}
+
+def _imports_for(code: str) -> List[Tuple[str, str]]:
+    """(alias, path) pairs for every known package qualifier used in code"""
+
+    used = set(re.findall(r'(?<![\w.])([a-z]\w*)\.\w', code))
+    found = []
+    for name in sorted(used):
+        path = KNOWN_IMPORTS.get(name)
+        if not path:
+            continue
+        last = path.rsplit("/", 1)[-1]
+        if re.fullmatch(r'v[\d.]+', last):
+            last = path.rsplit("/", 2)[-2]
+        found.append(("" if last == name else name, path))
+    # gofmt/goimports order: standard library first, then everything else
+    return sorted(found, key=lambda imp: ("." in imp[1].split("/")[0], imp[1]))
+
+# The tracer of examples that use one without declaring it; the fixtures package is library code,
+# so it carries the scope library-tracer-scope asks for
+FIXTURE_TRACER = ('var tracer = otel.Tracer("fixtures", trace.WithInstrumentationVersion("0.1.0"), '
+                  'trace.WithSchemaURL(semconv.SchemaURL))')
+
+def fixture_name(rule: Rule) -> str:
+    return rule.rule_id.replace("-", "_") + ".go"
+
+def render_fixture(rule: Rule) -> Tuple[str, Tuple[int, int], Tuple[int, int]]:
+    """Render the fixture source and the (first, last) line ranges of the bad and good sections"""
+
+    body_code = rule.bad_example + "\n" + rule.good_example
+    needs_tracer = re.search(r'\btracer\.', body_code) and not re.search(r'\btracer\s*:?=', body_code)
+    if needs_tracer:
+        body_code += "\n" + FIXTURE_TRACER
+
+    header = [
+        f"// {fixture_name(rule)}",
+        f"// Code generated by `otel_cli.py gen-fixtures`; DO NOT EDIT.",
+        f"// Rule {rule.rule_id}: {rule.title}",
+        "package fixtures",
+        "",
+    ]
+    imports = _imports_for(body_code)
+    if imports:
+        header.append("import (")
+        previous_std = None
+        for alias, path in imports:
+            is_std = "." not in path.split("/")[0]
+            if previous_std and not is_std:
+                header.append("")
+            previous_std = is_std
+            header.append(f'\t{alias + " " if alias else ""}"{path}"')
+        header.append(")")
+        header.append("")
+    if needs_tracer:
+        header.append(FIXTURE_TRACER)
+        header.append("")
+
+    lines = list(header)
+    lines.append(f"// VIOLATION: {rule.rule_id}")
+    bad_start = len(lines) + 1
+    lines.extend(rule.bad_example.strip("\n").split("\n"))
+    bad_end = len(lines)
+    lines.append("")
+    lines.append("// CORRECT")
+    good_start = len(lines) + 1
+    lines.extend(rule.good_example.strip("\n").split("\n"))
+    good_end = len(lines)
+    return "\n".join(lines) + "\n", (bad_start, bad_end), (good_start, good_end)
+
+def verify_rule(rule: Rule) -> List[str]:
+    """Problems found when running a rule against its own examples"""
+
+    if not rule.bad_example:
+        return [f"{rule.rule_id}: no examples in rule metadata"]
+
+    code, (bad_start, bad_end), (good_start, good_end) = render_fixture(rule)
+    engine = RuleEngine(rules=[rule])
+    path = fixture_name(rule)
+    if rule.scope == "file":
+        violations = engine.analyze(code, path)
+    else:
+        violations = engine.analyze_sources([GoFile(path, code)]).get(path, [])
+
+    problems = []
+    if not any(bad_start <= v.location.line_number <= bad_end for v in violations):
+        problems.append(f"{rule.rule_id}: violation example is not reported")
+    for v in violations:
+        if good_start <= v.location.line_number <= good_end:
+            problems.append(f"{rule.rule_id}: correct example reported at line {v.location.line_number}: {v.description}")
+    return problems
+
+def write_fixtures(out_dir: str, rules: List[Rule]) -> Dict[str, List[str]]:
+    """Write one fixture per rule with examples; returns path -> verification problems"""
+
+    out = Path(out_dir)
+    out.mkdir(parents=True, exist_ok=True)
+    results = {}
+    for rule in rules:
+        if not rule.bad_example:
+            continue
+        code, _, _ = render_fixture(rule)
+        path = out / fixture_name(rule)
+        path.write_text(code, encoding="utf-8")
+        results[str(path)] = verify_rule(rule)
+    return results
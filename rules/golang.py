@@ -0,0 +1,657 @@
+"""
+Lightweight Go source model used by the rule engine.
+We don't have a Go type checker here, so everything works on a "masked" copy of the
+source where comments and string contents are blanked out but offsets are preserved.
+"""
+
+import re
+from dataclasses import dataclass, field
+from typing import List, Dict, Optional, Iterator
+
+_OPEN = {"(": ")", "[": "]", "{": "}"}
+
+# Import paths whose package name can't be derived from the last path element
+PACKAGE_NAMES = {
+    "github.com/uber/jaeger-client-go": "jaeger",
+}
+
+def mask_code(code: str) -> str:
+    """Blank out comments and string/rune contents, keeping quotes, newlines and offsets"""
+
+    out = list(code)
+    i, n = 0, len(code)
+    while i < n:
+        c = code[i]
+        if code.startswith("//", i):
+            while i < n and code[i] != "\n":
+                out[i] = " "
+                i += 1
+        elif code.startswith("/*", i):
+            end = code.find("*/", i + 2)
+            end = n if end == -1 else end + 2
+            for j in range(i, end):
+                if code[j] != "\n":
+                    out[j] = " "
+            i = end
+        elif c in "\"'`":
+            j = i + 1
+            while j < n and code[j] != c:
+                if c != "`" and code[j] == "\\":
+                    out[j] = " "
+                    j += 1
+                elif c != "`" and code[j] == "\n":
+                    break
+                if j < n and code[j] != "\n":
+                    out[j] = " "
+                j += 1
+            i = j + 1
+        else:
+            i += 1
+    return "".join(out)
+
+def match_bracket(masked: str, idx: int) -> int:
+    """Return the index of the bracket closing the one at idx, or -1"""
+
+    opening = masked[idx]
+    closing = _OPEN.get(opening)
+    if closing is None:
+        return -1
+    depth = 0
+    for j in range(idx, len(masked)):
+        ch = masked[j]
+        if ch == opening:
+            depth += 1
+        elif ch == closing:
+            depth -= 1
+            if depth == 0:
+                return j
+    return -1
+
+def split_args(masked: str, start: int, end: int) -> List[tuple]:
+    """Split masked[start:end] on top-level commas, returning (start, end) spans"""
+
+    spans = []
+    depth = 0
+    seg_start = start
+    for j in range(start, end):
+        ch = masked[j]
+        if ch in "([{":
+            depth += 1
+        elif ch in ")]}":
+            depth -= 1
+        elif ch == "," and depth == 0:
+            spans.append((seg_start, j))
+            seg_start = j + 1
+    if masked[seg_start:end].strip():
+        spans.append((seg_start, end))
+    # Trim surrounding whitespace from each span
+    trimmed = []
+    for s, e in spans:
+        while s < e and masked[s].isspace():
+            s += 1
+        while e > s and masked[e - 1].isspace():
+            e -= 1
+        trimmed.append((s, e))
+    return trimmed
+
+def split_plus(expr: str) -> List[str]:
+    """Operands of a top-level string concatenation"""
+
+    parts, depth, start = [], 0, 0
+    for i, ch in enumerate(mask_code(expr)):
+        if ch in "([{":
+            depth += 1
+        elif ch in ")]}":
+            depth -= 1
+        elif ch == "+" and depth == 0:
+            parts.append(expr[start:i])
+            start = i + 1
+    parts.append(expr[start:])
+    return [p for p in parts if p.strip()] if len(parts) > 1 else [expr]
+
+def string_literal(text: str) -> Optional[str]:
+    """Return the value of a plain Go string literal, or None if text is anything else"""
+
+    text = text.strip()
+    m = re.fullmatch(r'"((?:[^"\\\n]|\\.)*)"', text)
+    if m:
+        return bytes(m.group(1), "utf-8").decode("unicode_escape", errors="replace")
+    m = re.fullmatch(r'`([^`]*)`', text)
+    if m:
+        return m.group(1)
+    return None
+
+def parse_params(params: str) -> List[tuple]:
+    """Parse a Go parameter list into (name, type) pairs; unnamed params get name ''"""
+
+    masked = mask_code(params)
+    pieces = [params[s:e] for s, e in split_args(masked, 0, len(masked))]
+    result, pending = [], []
+    for piece in pieces:
+        parts = piece.split(None, 1)
+        if len(parts) == 2 and re.fullmatch(r'\w+', parts[0]):
+            for name in pending:
+                result.append((name, parts[1].strip()))
+            pending = []
+            result.append((parts[0], parts[1].strip()))
+        elif re.fullmatch(r'\w+', piece.strip()):
+            pending.append(piece.strip())
+        else:
+            result.append(("", piece.strip()))
+    # A trailing run of bare identifiers is a list of unnamed types
+    result.extend(("", name) for name in pending)
+    return result
+
+@dataclass
+class Arg:
+    text: str
+    start: int
+    end: int
+
+    @property
+    def literal(self) -> Optional[str]:
+        return string_literal(self.text)
+
+@dataclass
+class Call:
+    name: str
+    start: int
+    open_paren: int
+    close_paren: int
+    args: List[Arg]
+
+    @property
+    def end(self) -> int:
+        return self.close_paren + 1
+
+@dataclass
+class GoFunc:
+    name: str
+    receiver: str
+    receiver_type: str
+    params: str
+    start: int
+    body_start: int
+    body_end: int
+    is_literal: bool = False
+
+    def contains(self, pos: int) -> bool:
+        return self.body_start <= pos <= self.body_end
+
+@dataclass
+class SpanStart:
+    """A tracer.Start call site and what we could learn about it statically"""
+    call: Call
+    tracer: str
+    ctx_var: str
+    span_var: str
+    name_arg: Optional[Arg]
+    name: Optional[str]
+    kind: str
+    func: Optional[GoFunc]
+    assign_op: str = ""
+
+@dataclass
+class SpanHelper:
+    """A project function that starts spans like tracer.Start, e.g. tracing.StartSpan(ctx, name).
+    call is the callee as written; "*.StartSpan" matches the method on any receiver."""
+    call: str
+    ctx_index: int = 0
+    name_index: int = 1
+
+    @property
+    def pattern(self) -> str:
+        if self.call.startswith("*."):
+            return r'[\w.]+\.' + re.escape(self.call[2:])
+        return re.escape(self.call)
+
+class GoFile:
+    """Parsed view of a Go source file with cached structural lookups"""
+
+    def __init__(self, path: str, code: str, span_helpers: Optional[List[SpanHelper]] = None):
+        self.path = path
+        self.code = code
+        self.span_helpers = list(span_helpers or [])
+        self.masked = mask_code(code)
+        self.lines = code.split("\n")
+        self._line_starts = [0]
+        for m in re.finditer("\n", code):
+            self._line_starts.append(m.end())
+        self._functions = None
+        self._imports = None
+        self._span_starts = None
+        # Files of the same package (this one included); the engine sets it per package
+        self.package_sources: List["GoFile"] = [self]
+        # The git revision the file was read from (rules/revisions.py), None for files on disk
+        self.tree = None
+
+    # Positions
+
+    def line_of(self, pos: int) -> int:
+        lo, hi = 0, len(self._line_starts) - 1
+        while lo < hi:
+            mid = (lo + hi + 1) // 2
+            if self._line_starts[mid] <= pos:
+                lo = mid
+            else:
+                hi = mid - 1
+        return lo + 1
+
+    def column_of(self, pos: int) -> int:
+        return pos - self._line_starts[self.line_of(pos) - 1] + 1
+
+    def line_start(self, line: int) -> int:
+        return self._line_starts[line - 1]
+
+    def text(self, start: int, end: int) -> str:
+        return self.code[start:end]
+
+    # Package level structure
+
+    @property
+    def package(self) -> str:
+        m = re.search(r'^package\s+(\w+)', self.masked, re.M)
+        return m.group(1) if m else ""
+
+    @property
+    def imports(self) -> Dict[str, str]:
+        """Map of local import name -> import path"""
+
+        if self._imports is None:
+            self._imports = {}
+            for m in re.finditer(r'^import\s*\(', self.masked, re.M):
+                close = match_bracket(self.masked, m.end() - 1)
+                block = self.code[m.end():close]
+                for spec in re.finditer(r'^\s*(?:([\w.]+)\s+)?"([^"]+)"', block, re.M):
+                    self._add_import(spec.group(1), spec.group(2))
+            for m in re.finditer(r'^import\s+(?:([\w.]+)\s+)?"([^"]+)"', self.code, re.M):
+                self._add_import(m.group(1), m.group(2))
+        return self._imports
+
+    def _add_import(self, alias: Optional[str], path: str):
+        name = alias
+        if not name:
+            parts = path.split("/")
+            name = parts[-1]
+            # Major version suffixes (".../v2") and semconv versions aren't the package name
+            if re.fullmatch(r'v\d+(\.\d+)*', name) and len(parts) > 1:
+                name = parts[-2] if not parts[-2].startswith("semconv") else "semconv"
+            # "opentracing-go" and "go-redis" style repository names drop the go affix
+            name = re.sub(r'^go-|-go$', "", name).replace("-", "").replace(".", "")
+            name = PACKAGE_NAMES.get(path, name)
+        self._imports[name] = path
+
+    @property
+    def constants(self) -> Dict[str, str]:
+        """Package level const name -> value expression text"""
+
+        consts = {}
+        for m in re.finditer(r'^const\s*\(', self.masked, re.M):
+            close = match_bracket(self.masked, m.end() - 1)
+            block = self.code[m.end():close]
+            for spec in re.finditer(r'^\s*(\w+)(?:\s+[\w.]+)?\s*=\s*(.+?)\s*(?://.*)?$', block, re.M):
+                consts[spec.group(1)] = spec.group(2)
+        for m in re.finditer(r'^const\s+(\w+)(?:\s+[\w.]+)?\s*=\s*(.+?)\s*(?://.*)?$', self.code, re.M):
+            consts[m.group(1)] = m.group(2)
+        return consts
+
+    def decl_insert_pos(self) -> int:
+        """Offset just after the import declarations (or the package clause), for new top level decls"""
+
+        end = 0
+        for m in re.finditer(r'^import\s*\(', self.masked, re.M):
+            end = max(end, match_bracket(self.masked, m.end() - 1) + 1)
+        for m in re.finditer(r'^import\s+[^(\n]*$', self.masked, re.M):
+            end = max(end, m.end())
+        if end == 0:
+            m = re.search(r'^package\s+\w+.*$', self.masked, re.M)
+            end = m.end() if m else 0
+        return end
+
+    def import_alias(self, path_prefix: str) -> List[str]:
+        """Local names of imports whose path starts with path_prefix"""
+        return [n for n, p in self.imports.items() if p == path_prefix or p.startswith(path_prefix + "/")]
+
+    def imports_path(self, path_prefix: str) -> bool:
+        return any(p == path_prefix or p.startswith(path_prefix) for p in self.imports.values())
+
+    def import_positions(self, path_prefix: str) -> List[tuple]:
+        """(offset, path) of each import spec whose path starts with path_prefix"""
+        end = self.decl_insert_pos()
+        pattern = re.compile(r'"(' + re.escape(path_prefix) + r'[^"]*)"')
+        return [(m.start(), m.group(1)) for m in pattern.finditer(self.code, 0, end)
+                if m.group(1) in self.imports.values()]
+
+    @property
+    def functions(self) -> List[GoFunc]:
+        """Top level functions and methods, followed by function literals"""
+
+        if self._functions is None:
+            self._functions = list(self._find_functions())
+        return self._functions
+
+    def _find_functions(self) -> Iterator[GoFunc]:
+        masked = self.masked
+        decl = re.compile(r'^func\s*(?:\(\s*(?:(\w+)\s+)?\*?\s*([\w.]+)(?:\[[^\]]*\])?\s*\))?\s*(\w+)\s*(?:\[[^\]]*\])?\s*\(', re.M)
+        for m in decl.finditer(masked):
+            params_open = m.end() - 1
+            params_close = match_bracket(masked, params_open)
+            if params_close == -1:
+                continue
+            body_open = self._find_body_open(params_close + 1)
+            if body_open == -1:
+                continue
+            body_close = match_bracket(masked, body_open)
+            if body_close == -1:
+                continue
+            yield GoFunc(
+                name=m.group(3),
+                receiver=m.group(1) or "",
+                receiver_type=(m.group(2) or ""),
+                params=self.code[params_open + 1:params_close],
+                start=m.start(),
+                body_start=body_open,
+                body_end=body_close,
+            )
+        for m in re.finditer(r'\bfunc\s*\(', masked):
+            line_start = masked.rfind("\n", 0, m.start()) + 1
+            if masked[line_start:m.start()].strip() == "" and m.start() == line_start:
+                continue
+            params_close = match_bracket(masked, m.end() - 1)
+            if params_close == -1:
+                continue
+            body_open = self._find_body_open(params_close + 1)
+            if body_open == -1:
+                continue
+            # func types in signatures ("fn func(int) error,") have no body before the next comma/paren
+            between = masked[params_close + 1:body_open]
+            if re.search(r'[,;)=]', between) or "\n" in between:
+                continue
+            body_close = match_bracket(masked, body_open)
+            if body_close == -1:
+                continue
+            yield GoFunc(
+                name="func literal",
+                receiver="",
+                receiver_type="",
+                params=self.code[m.end():params_close],
+                start=m.start(),
+                body_start=body_open,
+                body_end=body_close,
+                is_literal=True,
+            )
+
+    def _find_body_open(self, idx: int) -> int:
+        masked = self.masked
+        j = idx
+        while j < len(masked):
+            ch = masked[j]
+            if ch == "(" or ch == "[":
+                j = match_bracket(masked, j)
+                if j == -1:
+                    return -1
+            elif ch == "{":
+                word = re.search(r'(\w+)\s*$', masked[idx:j])
+                if word and word.group(1) in ("struct", "interface"):
+                    j = match_bracket(masked, j)
+                    if j == -1:
+                        return -1
+                else:
+                    return j
+            elif ch == "\n" and masked[idx:j].strip() == "" and j > idx + 1:
+                # Declarations without a body (assembly stubs)
+                return -1
+            j += 1
+        return -1
+
+    def methods_of(self, type_name: str) -> Dict[str, GoFunc]:
+        return {fn.name: fn for fn in self.functions if fn.receiver_type.lstrip("*") == type_name}
+
+    def struct_fields(self, type_name: str) -> Dict[str, str]:
+        """Field name -> type text for a struct declared in this file"""
+
+        m = re.search(r'\btype\s+' + re.escape(type_name) + r'\s+struct\s*\{', self.masked)
+        if not m:
+            return {}
+        close = match_bracket(self.masked, m.end() - 1)
+        fields = {}
+        for line in self.code[m.end():close].split("\n"):
+            line = line.split("//")[0].strip()
+            named = re.match(r'^(\w+(?:\s*,\s*\w+)*)\s+(\S.*)$', line)
+            if named:
+                for name in named.group(1).split(","):
+                    fields[name.strip()] = named.group(2).strip()
+            elif line:
+                # Embedded field, e.g. sync.Mutex
+                fields[line.split(".")[-1].lstrip("*")] = line
+        return fields
+
+    def annotations(self, directive: str) -> List[tuple]:
+        """(declared name, owner type or "", directive argument, offset) for struct fields, consts
+        and vars annotated with a `// <directive> <argument>` comment, trailing or on the line above"""
+
+        found = []
+        pattern = re.compile(r'//\s*' + re.escape(directive) + r'\s+([\w-]+)')
+        owners = []
+        for m in re.finditer(r'\btype\s+(\w+)\s+struct\s*\{', self.masked):
+            owners.append((m.end(), match_bracket(self.masked, m.end() - 1), m.group(1)))
+        for m in pattern.finditer(self.code):
+            line_start = self.code.rfind("\n", 0, m.start()) + 1
+            declared = self.code[line_start:m.start()]
+            decl_pos = line_start
+            if not declared.strip():
+                # Comment on its own line annotates the next line
+                decl_pos = self.code.find("\n", m.end()) + 1
+                declared = self.code[decl_pos:self.code.find("\n", decl_pos)].split("//")[0]
+            owner = next((name for start, end, name in owners if start <= decl_pos <= end), "")
+            decl = re.match(r'\s*(?:(?:const|var)\s+)?(\w+(?:\s*,\s*\w+)*)', declared)
+            if not decl:
+                continue
+            for name in decl.group(1).split(","):
+                found.append((name.strip(), owner, m.group(1), decl_pos + len(declared) - len(declared.lstrip())))
+        return found
+
+    def func_at(self, pos: int, include_literals: bool = False) -> Optional[GoFunc]:
+        """Innermost function whose body contains pos"""
+
+        best = None
+        for fn in self.functions:
+            if fn.is_literal and not include_literals:
+                continue
+            if fn.contains(pos) and (best is None or fn.body_start > best.body_start):
+                best = fn
+        return best
+
+    def func_named(self, name: str) -> Optional[GoFunc]:
+        for fn in self.functions:
+            if not fn.is_literal and fn.name == name:
+                return fn
+        return None
+
+    def function_name_at(self, pos: int) -> str:
+        fn = self.func_at(pos)
+        return fn.name if fn else "global"
+
+    # Calls and blocks
+
+    def calls(self, name_regex: str, start: int = 0, end: Optional[int] = None) -> Iterator[Call]:
+        """Find calls whose callee matches name_regex (matched against masked source)"""
+
+        end = len(self.masked) if end is None else end
+        pattern = re.compile(r'(?<![\w.])(' + name_regex + r')\s*\(')
+        for m in pattern.finditer(self.masked, start, end):
+            open_paren = m.end() - 1
+            close = match_bracket(self.masked, open_paren)
+            if close == -1:
+                continue
+            args = [Arg(self.code[s:e], s, e) for s, e in split_args(self.masked, open_paren + 1, close)]
+            yield Call(m.group(1), m.start(1), open_paren, close, args)
+
+    def loops(self, start: int = 0, end: Optional[int] = None) -> List[tuple]:
+        """(for_keyword_pos, body_open, body_close) for every for statement in range"""
+
+        end = len(self.masked) if end is None else end
+        found = []
+        for m in re.finditer(r'\bfor\b', self.masked[start:end]):
+            kw = start + m.start()
+            j = kw + 3
+            body_open = -1
+            while j < end:
+                ch = self.masked[j]
+                if ch in "([":
+                    j = match_bracket(self.masked, j)
+                    if j == -1:
+                        break
+                elif ch == "{":
+                    # Slice and map literals in the header ("range []int{1, 2}") aren't the body
+                    prev = self.masked[kw + 3:j].rstrip()
+                    if re.search(r'\][\w.*]+$', prev):
+                        j = match_bracket(self.masked, j)
+                        if j == -1:
+                            break
+                    else:
+                        body_open = j
+                        break
+                j += 1
+            if body_open != -1:
+                close = match_bracket(self.masked, body_open)
+                if close != -1:
+                    found.append((kw, body_open, close))
+        return found
+
+    def enclosing_loop(self, pos: int, within: Optional[GoFunc] = None) -> Optional[tuple]:
+        """Innermost loop around pos that does not cross a function boundary"""
+
+        fn = within or self.func_at(pos, include_literals=True)
+        lo = fn.body_start if fn else 0
+        hi = fn.body_end if fn else len(self.masked)
+        best = None
+        for loop in self.loops(lo, hi):
+            if loop[1] < pos < loop[2] and (best is None or loop[1] > best[1]):
+                best = loop
+        return best
+
+    def block_end(self, pos: int, within: GoFunc) -> int:
+        """Offset of the closing brace of the innermost block of within holding pos"""
+
+        depth = 0
+        for i in range(pos - 1, within.body_start - 1, -1):
+            ch = self.masked[i]
+            if ch in ")]}":
+                depth += 1
+            elif ch in "([{":
+                if depth:
+                    depth -= 1
+                elif ch == "{":
+                    return match_bracket(self.masked, i)
+        return within.body_end
+
+    def statement_prefix(self, pos: int) -> str:
+        """Source text from the start of pos's line up to pos"""
+        line_start = self.code.rfind("\n", 0, pos) + 1
+        return self.code[line_start:pos]
+
+    def expression_end(self, pos: int) -> int:
+        """End offset of the selector or call expression starting at pos, clipped to its line"""
+
+        line_end = self.masked.find("\n", pos)
+        line_end = len(self.masked) if line_end == -1 else line_end
+        m = re.compile(r'[\w.*&]+').match(self.masked, pos)
+        if not m:
+            return min(pos + 1, line_end)
+        end = m.end()
+        while end < line_end and self.masked[end] in "([{":
+            close = match_bracket(self.masked, end)
+            if close == -1:
+                break
+            end = close + 1
+            m = re.compile(r'\.[\w.]+').match(self.masked, end)
+            if m:
+                end = m.end()
+        return min(end, line_end)
+
+    def identifiers_used(self, name: str, start: int, end: int) -> List[int]:
+        return [start + m.start() for m in re.finditer(r'(?<![\w.])' + re.escape(name) + r'\b', self.masked[start:end])]
+
+    # OpenTelemetry specifics
+
+    def tracer_names(self) -> List[str]:
+        """Identifiers that hold a trace.Tracer"""
+
+        names = set()
+        for m in re.finditer(r'(\w+)\s*(?::=|=)\s*(?:\w+\.)?(?:Tracer|GetTracerProvider\(\)\.Tracer|TracerProvider\(\)\.Tracer)\s*\(', self.masked):
+            names.add(m.group(1))
+        for m in re.finditer(r'(\w+)\s+=\s*otel\.Tracer\s*\(', self.masked):
+            names.add(m.group(1))
+        for m in re.finditer(r'(\w+)\s+(?:trace\.)?Tracer\b', self.masked):
+            names.add(m.group(1))
+        for m in re.finditer(r'\b(\w*[tT]racer)\.Start\s*\(', self.masked):
+            names.add(m.group(1))
+        names.discard("otel")
+        names.discard("trace")
+        return sorted(names)
+
+    @property
+    def span_starts(self) -> List[SpanStart]:
+        if self._span_starts is None:
+            self._span_starts = list(self._find_span_starts())
+        return self._span_starts
+
+    def use_span_helpers(self, helpers: List[SpanHelper]):
+        """Treat calls to helpers as span starts from now on"""
+
+        if list(helpers) != self.span_helpers:
+            self.span_helpers = list(helpers)
+            self._span_starts = None
+
+    def _find_span_starts(self) -> Iterator[SpanStart]:
+        found = {}
+        tracers = self.tracer_names()
+        if tracers:
+            callee = r'(?:' + "|".join(re.escape(t) for t in tracers) + r'|[\w.]*\.Tracer\([^()]*\))\.Start'
+            for call in self.calls(callee):
+                found[call.start] = self._span_start_from_call(call, call.name.rsplit(".Start", 1)[0], 0, 1)
+        for helper in self.span_helpers:
+            for call in self.calls(helper.pattern):
+                if call.start not in found:
+                    found[call.start] = self._span_start_from_call(call, call.name, helper.ctx_index, helper.name_index)
+        for pos in sorted(found):
+            yield found[pos]
+
+    def _span_start_from_call(self, call: Call, tracer: str, ctx_index: int, name_index: int) -> SpanStart:
+        prefix = self.masked[self.masked.rfind("\n", 0, call.start) + 1:call.start]
+        ctx_var, span_var, op = "", "", ""
+        m = re.search(r'(\w+)\s*,\s*(\w+)\s*(:=|=)\s*$', prefix)
+        single = re.search(r'^\s*(\w+)\s*(:=|=)\s*$', prefix)
+        if m:
+            ctx_var, span_var, op = m.group(1), m.group(2), m.group(3)
+        elif single:
+            # Helpers may return just the span
+            span_var, op = single.group(1), single.group(2)
+        name_arg = call.args[name_index] if len(call.args) > name_index else None
+        options = " ".join(a.text for a in call.args[max(ctx_index, name_index) + 1:])
+        kind = ""
+        k = re.search(r'SpanKind(Server|Client|Producer|Consumer|Internal)', options)
+        if k:
+            kind = k.group(1).lower()
+        return SpanStart(
+            call=call,
+            tracer=tracer,
+            ctx_var=ctx_var,
+            span_var=span_var,
+            name_arg=name_arg,
+            name=name_arg.literal if name_arg else None,
+            kind=kind,
+            func=self.func_at(call.start, include_literals=True),
+            assign_op=op,
+        )
+
+    def span_vars(self) -> List[str]:
+        """Identifiers that hold spans, from tracer.Start and trace.SpanFromContext"""
+
+        names = {s.span_var for s in self.span_starts if s.span_var and s.span_var != "_"}
+        for m in re.finditer(r'(\w+)\s*:?=\s*trace\.SpanFromContext\s*\(', self.masked):
+            names.add(m.group(1))
+        for m in re.finditer(r'(\w+)\s+(?:trace\.)?(?:ReadOnlySpan|ReadWriteSpan|Span)\b', self.masked):
+            names.add(m.group(1))
+        return sorted(names)
@@ -0,0 +1,55 @@
+"""
+Golden-file snapshot testing for rule engine output.
+Every fixture has a .golden file holding the diagnostics it is expected to produce;
+contributors regenerate them with --update after intentionally changing a rule.
+"""
+
+import difflib
+from pathlib import Path
+from typing import List, Dict, Iterable
+
+from .base import TelemetryViolation
+from .engine import RuleEngine
+from .registry import all_rules
+
+def snapshot(violations: Iterable[TelemetryViolation]) -> str:
+    """Stable, diff-friendly rendering of diagnostics"""
+
+    lines = [
+        f"{v.location.line_number}:{v.location.column} {v.rule_id} [{v.severity}] {v.description}"
+        for v in violations
+    ]
+    return "\n".join(lines) + ("\n" if lines else "")
+
+def golden_path(fixture: Path, golden_dir: Path, root: Path) -> Path:
+    relative = fixture.relative_to(root)
+    return golden_dir / relative.with_suffix(relative.suffix + ".golden")
+
+def check_golden(fixtures: List[Path], golden_dir: Path, root: Path,
+                 update: bool = False, engine: RuleEngine = None) -> Dict[str, str]:
+    """Compare engine output with golden files; returns fixture -> unified diff for mismatches,
+    including fixtures without a golden file. With update=True the golden files are rewritten
+    instead and nothing is reported."""
+
+    # Opt-in rules are snapshotted too
+    engine = engine or RuleEngine(all_rules())
+    results = engine.analyze_files([str(f) for f in fixtures])
+    mismatches = {}
+    for fixture in fixtures:
+        actual = snapshot(results.get(str(fixture), []))
+        target = golden_path(fixture, golden_dir, root)
+        if update:
+            target.parent.mkdir(parents=True, exist_ok=True)
+            target.write_text(actual, encoding="utf-8")
+            continue
+        if not target.exists():
+            mismatches[str(fixture)] = f"{target} is missing; create it with --update"
+            continue
+        expected = target.read_text(encoding="utf-8")
+        if actual != expected:
+            diff = difflib.unified_diff(
+                expected.splitlines(keepends=True), actual.splitlines(keepends=True),
+                fromfile=str(target), tofile=f"{fixture} (actual)",
+            )
+            mismatches[str(fixture)] = "".join(diff)
+    return mismatches
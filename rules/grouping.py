@@ -0,0 +1,214 @@
+"""
+How backends group spans by name. Jaeger lists one operation per span name and service, Tempo
+searches and its span metrics key on the name, and Datadog derives an operation name from the
+span's kind and protocol and a resource name from the HTTP method and route, or else the span
+name. Mapping every span site onto them shows names that group badly: one operation per request,
+or unrelated work collapsing into one.
+
+The Datadog names follow the Agent's default OTLP mapping (operation and resource name logic
+v2); they are approximations for spans whose attributes are set elsewhere.
+"""
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional, Set, Tuple
+
+from .cardinality import Bounds
+from .conventions import HTTP_METHODS
+from .golang import GoFile, SpanStart, string_literal
+from .semconv import SEMCONV_KEYS, go_constant
+from .traces.attributes import attribute_calls
+from .traces.parenting import GENERIC_SPAN_NAME
+
+# Protocols Datadog names operations after, by attribute key prefix
+PROTOCOLS = {"http": "http", "url": "http", "db": "db", "rpc": "rpc", "messaging": "messaging"}
+# Semconv Go identifiers (HTTPRoute, DBSystemPostgreSQL) -> their key
+SEMCONV_NAMES = {go_constant(k)[:-len("Key")]: k for k in SEMCONV_KEYS}
+# Values of a dynamic name listed in the report
+SHOWN_VALUES = 5
+
+@dataclass
+class NameSite:
+    source: GoFile
+    start: SpanStart
+    # The literal name, or the expression building it
+    name: str
+    # Every value the name takes, None when it can't be bounded
+    values: Optional[Set[str]]
+    kind: str
+    protocol: str = ""
+    # Attributes with a literal value set in the span's function: http.route, db.system, ...
+    attributes: Dict[str, str] = field(default_factory=dict)
+
+    @property
+    def where(self) -> str:
+        return f"{self.source.path}:{self.source.line_of(self.start.call.start)}"
+
+    @property
+    def function(self) -> str:
+        fn = self.start.func
+        return fn.name if fn is not None and fn.name else "<func literal>"
+
+def span_attributes(source: GoFile, start: SpanStart) -> Dict[str, str]:
+    """Keys start's span records in its options and SetAttributes calls, with their literal
+    values ("" when not literal)"""
+
+    ranges = [(start.call.start, start.call.end)]
+    if start.span_var and start.span_var != "_" and start.func is not None:
+        ranges += [(c.start, c.end) for c in source.calls(re.escape(start.span_var) + r'\.SetAttributes')
+                   if start.func.contains(c.start)]
+    found = {}
+    for call in attribute_calls(source):
+        if any(lo <= call.start < hi for lo, hi in ranges) and call.args and call.args[0].literal is not None:
+            value = call.args[1].literal if len(call.args) > 1 else None
+            found[call.args[0].literal] = value or ""
+    for alias in source.import_alias("go.opentelemetry.io/otel/semconv"):
+        for lo, hi in ranges:
+            pattern = re.escape(alias) + r'\.(\w+?)(?:Key\.String\s*\(|\s*\(|\b)\s*("[^"\n]*")?'
+            for m in re.finditer(pattern, source.masked[lo:hi]):
+                key, value = _semconv_key(m.group(1))
+                if key is not None:
+                    literal = source.code[lo + m.start(2):lo + m.end(2)] if m.group(2) else ""
+                    found[key] = string_literal(literal) or value
+    return found
+
+def _semconv_key(name: str) -> Tuple[Optional[str], str]:
+    """The key a semconv identifier sets, and the value of enum members: HTTPRoute -> http.route,
+    DBSystemPostgreSQL -> db.system = postgresql, HTTPRequestMethodGet -> http.request.method = GET"""
+
+    if name in SEMCONV_NAMES:
+        return SEMCONV_NAMES[name], ""
+    prefixes = [p for p in SEMCONV_NAMES if name.startswith(p)]
+    if not prefixes:
+        return None, ""
+    prefix = max(prefixes, key=len)
+    key, member = SEMCONV_NAMES[prefix], name[len(prefix):]
+    return key, member.upper() if key in ("http.request.method", "http.method") else member.lower()
+
+def _protocol(attributes: Dict[str, str]) -> str:
+    for key in attributes:
+        protocol = PROTOCOLS.get(key.split(".", 1)[0])
+        if protocol:
+            return protocol
+    return ""
+
+def _method(site: NameSite) -> str:
+    method = site.attributes.get("http.request.method") or site.attributes.get("http.method") or ""
+    if not method and site.start.name:
+        first = site.start.name.split(" ", 1)[0]
+        method = first if first in HTTP_METHODS else ""
+    return method
+
+def datadog_operation(site: NameSite) -> str:
+    kind = site.kind or "internal"
+    system = site.attributes.get(f"{site.protocol}.system", "")
+    if site.protocol == "http" and kind in ("server", "client"):
+        return f"http.{kind}.request"
+    if site.protocol == "rpc" and kind in ("server", "client"):
+        return f"{system or 'rpc'}.{kind}.request"
+    if site.protocol == "db" and kind == "client":
+        return f"{system or 'db'}.query"
+    if site.protocol == "messaging" and kind in ("producer", "consumer"):
+        return f"{system or 'messaging'}.{'send' if kind == 'producer' else 'process'}"
+    return {"server": "server.request", "client": "client.request"}.get(kind, kind)
+
+def datadog_resource(site: NameSite) -> str:
+    if site.protocol == "http" and site.kind in ("server", "client"):
+        method = _method(site) or "<method>"
+        route = site.attributes.get("http.route")
+        if site.kind == "server" and route:
+            return f"{method} {route}"
+        if site.kind == "client":
+            return method
+    return site.name
+
+def name_sites(sources: List[GoFile]) -> List[NameSite]:
+    sites = []
+    for source in sources:
+        bounds = Bounds(source)
+        for start in source.span_starts:
+            if start.name_arg is None:
+                continue
+            if start.name is not None:
+                name, values = start.name, {start.name}
+            else:
+                name = start.name_arg.text.strip()
+                values = bounds.values(name, start.name_arg.start)
+            attributes = span_attributes(source, start)
+            sites.append(NameSite(source, start, name, values, start.kind or "internal", _protocol(attributes),
+                                  attributes))
+    return sites
+
+def _shown(values: Optional[Set[str]]) -> str:
+    if values is None:
+        return "unbounded"
+    listed = sorted(values)
+    more = f", ... ({len(listed)} values)" if len(listed) > SHOWN_VALUES else ""
+    return ", ".join(listed[:SHOWN_VALUES]) + more
+
+def _problems(name: str, group: List[NameSite], resources: Dict[str, Set[str]]) -> List[str]:
+    site = group[0]
+    problems = []
+    if site.values is None:
+        problems.append("unbounded: every value becomes its own Jaeger operation, Tempo span name and "
+                        "Datadog resource")
+        return problems
+    if site.start.name is not None and GENERIC_SPAN_NAME.fullmatch(name.strip()):
+        problems.append("generic: unrelated work shares one operation and its latency percentiles")
+    if site.values and all(v.strip() in HTTP_METHODS for v in site.values):
+        problems.append("method only: every endpoint collapses into one Jaeger operation and Tempo span name "
+                        "per HTTP method; name it 'METHOD /route/template'")
+    functions = sorted({s.function for s in group})
+    if len(functions) > 1:
+        problems.append(f"shared by {len(functions)} functions ({', '.join(functions[:3])}"
+                        f"{', ...' if len(functions) > 3 else ''}): their latencies are averaged together")
+    resource = datadog_resource(site)
+    if site.protocol == "http" and site.kind == "client" and len(resources.get(resource, ())) > 1:
+        problems.append(f"Datadog names outgoing HTTP spans after the method, so {len(resources[resource])} "
+                        f"client span names share the resource '{resource}'")
+    return problems
+
+def grouping_report(sources: List[GoFile], root: str = ".") -> Dict:
+    """Per span name: the sites using it, what Jaeger, Tempo and Datadog group it under, and how
+    it groups badly"""
+
+    def relative(where: str) -> str:
+        try:
+            return Path(where).resolve().relative_to(Path(root).resolve()).as_posix()
+        except ValueError:
+            return where
+
+    groups: Dict[str, List[NameSite]] = {}
+    for site in name_sites(sources):
+        groups.setdefault(site.name, []).append(site)
+    # Datadog resource -> the span names mapped onto it
+    resources: Dict[str, Set[str]] = {}
+    for name, group in groups.items():
+        resources.setdefault(datadog_resource(group[0]), set()).add(name)
+    names = []
+    for name, group in sorted(groups.items()):
+        site = group[0]
+        jaeger = name if site.start.name is not None else _shown(site.values)
+        resource = ", ".join(sorted({datadog_resource(s) for s in group}))
+        names.append({
+            "name": name,
+            "dynamic": site.start.name is None,
+            "values": None if site.values is None else len(site.values),
+            "sites": [relative(s.where) for s in group],
+            "kind": site.kind,
+            "jaeger_operation": jaeger,
+            "tempo_span_name": jaeger,
+            "datadog_operation": datadog_operation(site),
+            "datadog_resource": jaeger if resource == name else resource,
+            "problems": _problems(name, group, resources),
+        })
+    operations: Dict[str, int] = {}
+    for entry in names:
+        operations[entry["datadog_operation"]] = operations.get(entry["datadog_operation"], 0) + 1
+    return {
+        "names": names,
+        # Datadog operation -> the span names grouped under it; they differ by resource only
+        "datadog_operations": dict(sorted(operations.items(), key=lambda item: -item[1])),
+        "problems": sum(1 for entry in names if entry["problems"]),
+    }
@@ -0,0 +1,57 @@
+"""
+What a codebase emits: signals, span names, attribute keys and instrument names.
+Used to cross-check the code against things that live outside it, like Collector config.
+"""
+
+import re
+from dataclasses import dataclass, field
+from typing import Dict, List, Set
+
+from .golang import GoFile
+from .metrics.instruments import instruments
+from .traces.attributes import attribute_calls
+from .traces.propagators import propagator_setups
+
+LOG_IMPORTS = ("go.opentelemetry.io/otel/log", "go.opentelemetry.io/contrib/bridges")
+
+@dataclass
+class Inventory:
+    signals: Set[str] = field(default_factory=set)
+    # Literal names -> "file:line" of the first place they appear
+    span_names: Dict[str, str] = field(default_factory=dict)
+    attribute_keys: Dict[str, str] = field(default_factory=dict)
+    metric_names: Dict[str, str] = field(default_factory=dict)
+    # Span starts whose name isn't a literal, so span_names is a lower bound
+    dynamic_span_names: int = 0
+    # Propagation formats the code configures ("tracecontext", "b3", ...) -> where
+    propagators: Dict[str, str] = field(default_factory=dict)
+
+def _where(source: GoFile, pos: int) -> str:
+    return f"{source.path}:{source.line_of(pos)}"
+
+def build_inventory(sources: List[GoFile]) -> Inventory:
+    inv = Inventory()
+    for source in sources:
+        for start in source.span_starts:
+            inv.signals.add("traces")
+            if start.name is not None:
+                inv.span_names.setdefault(start.name, _where(source, start.call.start))
+            else:
+                inv.dynamic_span_names += 1
+        for call in attribute_calls(source):
+            key = call.args[0].literal if call.args else None
+            if key is not None:
+                inv.attribute_keys.setdefault(key, _where(source, call.start))
+        for m in re.finditer(r'attribute\.Key\(\s*"([^"]+)"\s*\)', source.code):
+            inv.attribute_keys.setdefault(m.group(1), _where(source, m.start()))
+        for inst in instruments(source):
+            inv.signals.add("metrics")
+            if inst.name is not None:
+                inv.metric_names.setdefault(inst.name, _where(source, inst.call.start))
+        for call, members in propagator_setups(source):
+            for name, _ in members:
+                if name:
+                    inv.propagators.setdefault(name, _where(source, call.start))
+        if any(source.imports_path(p) for p in LOG_IMPORTS):
+            inv.signals.add("logs")
+    return inv
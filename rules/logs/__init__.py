@@ -0,0 +1,5 @@
+"""
+Rules for logs: the logs API and the slog, zap and logrus bridges that feed it
+"""
+
+from . import correlation, pii, severity
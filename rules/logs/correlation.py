@@ -0,0 +1,170 @@
+"""
+Correlating logs with traces: the bridges copy the trace and span ID of the span in the context
+onto each record, so the context has to reach the log call
+"""
+
+import re
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..golang import GoFile
+from ..metrics.exemplars import TRACE_KEY, TRACE_VALUE
+from ..registry import rule
+from .records import DETACHED_CONTEXT, LogCall, active_span, bridged_libraries, context_param, log_calls
+
+def _context_fix(call: LogCall, ctx: str) -> Optional[Fix]:
+    if call.context is not None:
+        return Fix(description=f"Pass {ctx}", edits=[TextEdit(call.context.start, call.context.end, ctx)])
+    if call.library == "slog" and call.method in ("Debug", "Info", "Warn", "Error"):
+        return Fix(description=f"Use {call.method}Context({ctx}, ...)", edits=[
+            TextEdit(call.method_pos + len(call.method), call.open_paren + 1, f"Context({ctx}, "),
+        ])
+    if call.library == "logrus":
+        return Fix(description=f"Add .WithContext({ctx})",
+                   edits=[TextEdit(call.method_pos - 1, call.method_pos - 1, f".WithContext({ctx})")])
+    if call.library == "zap" and not call.formatted:
+        field = f'"ctx", {ctx}' if call.method.endswith("w") else f'zap.Any("ctx", {ctx})'
+        return Fix(description=f"Add {field}",
+                   edits=[TextEdit(call.close_paren, call.close_paren, (", " if call.args else "") + field)])
+    return None
+
+@rule(
+    rule_id="log-missing-trace-context",
+    title="Pass the context to log calls made inside a span",
+    category="propagation",
+    signal="logs",
+    signals=("traces",),
+    severity="medium",
+    autofix=True,
+    scope="project",
+    description="The otelslog, otelzap and otellogrus bridges (and slog handlers that read the span from the "
+                "context) take the trace and span ID from the context of each call. slog.Info instead of "
+                "InfoContext, a zap call without the context field, a logrus call without WithContext, or "
+                "context.Background() inside a span produce records that can't be found from the trace. Only "
+                "libraries with such a bridge in the program are checked.",
+    bad_example='''
+func reserveStock(ctx context.Context, sku string) {
+	ctx, span := tracer.Start(ctx, "reserve stock")
+	defer span.End()
+	slog.Info("reserving stock", "sku", sku)
+	slog.WarnContext(context.Background(), "stock low", "sku", sku)
+	_ = ctx
+}''',
+    good_example='''
+var logProvider = otelslog.NewHandler("example.com/stock")
+
+func releaseStock(ctx context.Context, sku string) {
+	ctx, span := tracer.Start(ctx, "release stock")
+	defer span.End()
+	slog.InfoContext(ctx, "releasing stock", "sku", sku)
+}''',
+)
+def check_log_missing_trace_context(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    bridged = bridged_libraries(sources)
+    if not bridged:
+        return
+    for source in sources:
+        for call in log_calls(source):
+            if call.library not in bridged:
+                continue
+            span = active_span(source, call.start)
+            if span is None:
+                continue
+            where, ctx = span
+            if ctx in ("", "_"):
+                ctx = context_param(source.func_at(call.start, include_literals=True))
+            if call.context is not None:
+                if not DETACHED_CONTEXT.match(call.context.text.strip()):
+                    continue
+                message = (f"Log call passes {call.context.text.strip()} inside {where}, so the record "
+                           f"isn't correlated with it")
+            else:
+                how = {"slog": f"{call.method} has no context", "zap": "it has no context field",
+                       "logrus": "it has no WithContext"}
+                message = (f"Log call inside {where} can't be correlated with it: "
+                           f"{how[call.library]}")
+            if call.library == "slog":
+                suggestion = f"Use {re.sub(r'Context$', '', call.method)}Context({ctx or 'ctx'}, ...)"
+            elif call.library == "zap":
+                suggestion = f'Add zap.Any("ctx", {ctx or "ctx"}) to the fields'
+            else:
+                suggestion = f"Log through .WithContext({ctx or 'ctx'})"
+            yield Diagnostic(
+                pos=call.start,
+                end=call.end,
+                message=message,
+                suggestion=suggestion,
+                confidence=0.8,
+                fix=_context_fix(call, ctx) if ctx else None,
+                file=source,
+            )
+
+def _trace_fields(call: LogCall) -> Iterator[tuple]:
+    """(index into call.fields, what) for fields that carry a trace or span ID; -1 for the message itself"""
+
+    if call.message is not None and re.search(TRACE_VALUE, call.message.text):
+        yield -1, "the message"
+    for i, (key, _, value) in enumerate(call.fields):
+        if key is not None and TRACE_KEY.match(key):
+            yield i, f'field "{key}"'
+        elif re.search(TRACE_VALUE, value.text):
+            yield i, "the message" if call.formatted else "a field"
+
+@rule(
+    rule_id="log-trace-id-formatted",
+    title="Let the bridge attach trace IDs instead of formatting them into logs",
+    category="conventions",
+    signal="logs",
+    signals=("traces",),
+    severity="medium",
+    autofix=True,
+    scope="project",
+    description="A trace ID formatted into the message text can't be queried or linked, and a trace_id field "
+                "next to a bridge duplicates the TraceId the record already carries, often in another "
+                "format. Pass the context instead; without a bridge, a structured trace_id field is the "
+                "way to correlate and only IDs in the message text are reported.",
+    bad_example='''
+func chargeCard(ctx context.Context, amount int64) {
+	span := trace.SpanFromContext(ctx)
+	log.Printf("charging %d (trace %s)", amount, span.SpanContext().TraceID().String())
+}''',
+    good_example='''
+func refundCard(ctx context.Context, amount int64) {
+	span := trace.SpanFromContext(ctx)
+	slog.InfoContext(ctx, "refunding", "amount", amount, "trace_id", span.SpanContext().TraceID().String())
+}''',
+)
+def check_log_trace_id_formatted(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    bridged = bridged_libraries(sources)
+    for source in sources:
+        for call in log_calls(source):
+            for i, what in _trace_fields(call):
+                if call.formatted or i == -1:
+                    message = "Trace ID is formatted into the log message, where it can't be queried or linked"
+                    suggestion = ("Pass the context to the log call and let an otelslog/otelzap/otellogrus bridge "
+                                  "attach it, or log it as a trace_id field")
+                    fix = None
+                elif call.library in bridged:
+                    message = (f"Log {what} repeats the trace ID that the {call.library} bridge already attaches "
+                               f"from the context")
+                    suggestion = "Remove it" + ("" if call.context else " and pass the context to the call")
+                    fix = None
+                    args = call.field_args[i]
+                    if call.context and args:
+                        all_args = call.args
+                        index = next((j for j, a in enumerate(all_args) if a.start == args[0].start), None)
+                        if index:
+                            fix = Fix(description="Remove the trace ID field",
+                                      edits=[TextEdit(all_args[index - 1].end, args[-1].end, "")])
+                else:
+                    continue
+                value = call.fields[i][2] if i >= 0 else call.message
+                yield Diagnostic(
+                    pos=value.start,
+                    end=value.end,
+                    message=message,
+                    suggestion=suggestion,
+                    confidence=0.85,
+                    fix=fix,
+                    file=source,
+                )
@@ -0,0 +1,105 @@
+"""
+Personal data in log records. Once a log is correlated with a trace, or exported through the logs
+API, it sits next to the span attributes: the redaction applied to spans has to hold for logs too.
+"""
+
+import re
+from typing import Dict, Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import Arg, GoFile
+from ..privacy.classification import SANITIZER, classified_names
+from ..privacy.pii import PII_NAME, snake
+from ..registry import rule
+from ..traces.attributes import attribute_calls
+from .records import LogCall, active_span, bridged_libraries, log_calls
+
+def is_pii(key: Optional[str], value: Arg) -> bool:
+    """Whether the field's key, or the identifier it logs, names personal data"""
+
+    if SANITIZER.search(value.text):
+        return False
+    if key and PII_NAME.search(snake(key)):
+        return True
+    m = re.fullmatch(r'\s*(?:[\w.]+\.)?(\w+)(?:\(\))?\s*', value.text)
+    return bool(m and PII_NAME.search(snake(m.group(1))))
+
+def _span_redacts(source: GoFile, call: LogCall, value: Arg) -> Optional[str]:
+    """Key of a span attribute in the same function that records value only through a sanitizer"""
+
+    fn = source.func_at(call.start, include_literals=True)
+    if fn is None:
+        return None
+    text = value.text.strip()
+    for attr in attribute_calls(source):
+        if fn.contains(attr.start) and len(attr.args) >= 2 and SANITIZER.search(attr.args[1].text) \
+                and re.search(r'(?<![\w.])' + re.escape(text) + r'(?!\w)', attr.args[1].text):
+            return attr.args[0].literal or attr.args[0].text.strip()
+    return None
+
+@rule(
+    rule_id="log-pii-field",
+    title="Keep personal data out of logs that travel with traces",
+    category="security",
+    signal="logs",
+    severity="high",
+    scope="project",
+    options={
+        # Log field keys whose values the pipeline redacts
+        "redacted_keys": [],
+    },
+    description="Log records written inside a span carry its trace and span ID, and records sent through an "
+                "OpenTelemetry bridge go to the same backends as the spans. Email addresses, phone numbers, "
+                "card numbers, birth dates and names logged there are joined to the request's trace, often "
+                "after the span attributes were carefully hashed. Values passed through a redact/hash/mask "
+                "function, keys on redacted_keys and fields annotated with olly:data-class (reported by "
+                "classified-data-in-telemetry) are skipped.",
+    bad_example='''
+type Subscriber struct {
+	ID           string
+	EmailAddress string
+}
+
+func notifySubscriber(ctx context.Context, s Subscriber) {
+	ctx, span := tracer.Start(ctx, "notify subscriber")
+	defer span.End()
+	span.SetAttributes(attribute.String("subscriber.email_hash", hashEmail(s.EmailAddress)))
+	slog.InfoContext(ctx, "sending notification", "email", s.EmailAddress)
+}''',
+    good_example='''
+func remindSubscriber(ctx context.Context, s Subscriber) {
+	ctx, span := tracer.Start(ctx, "remind subscriber")
+	defer span.End()
+	slog.InfoContext(ctx, "sending reminder", "subscriber.id", s.ID, "email_hash", hashEmail(s.EmailAddress))
+}''',
+)
+def check_log_pii_field(sources: List[GoFile], options: Dict) -> Iterator[Diagnostic]:
+    bridged = bridged_libraries(sources)
+    classified, names = classified_names(sources, ["pii", "phi", "pci", "secret", "sensitive"])
+    annotated = set(classified) | set(names)
+    redacted = set(options["redacted_keys"])
+    for source in sources:
+        for call in log_calls(source):
+            span = active_span(source, call.start)
+            if span is None and call.library not in bridged:
+                continue
+            for key, _, value in call.fields:
+                if key in redacted:
+                    continue
+                if not is_pii(key, value) or any(re.search(r'\b' + re.escape(n) + r'\b', value.text) for n in annotated):
+                    continue
+                where = f"inside {span[0]}" if span else f"through the {call.library} bridge"
+                field = f'Log field "{key}"' if key else "Log message"
+                hashed = _span_redacts(source, call, value)
+                message = f"{field} records {value.text.strip()}, personal data, {where}"
+                if hashed:
+                    message += f", although the span only records it redacted as {hashed!r}"
+                yield Diagnostic(
+                    pos=value.start,
+                    end=value.end,
+                    message=message,
+                    suggestion="Log an identifier or a hash instead"
+                               + (f", or add '{key}' to redacted_keys if the pipeline redacts it" if key else ""),
+                    confidence=0.8 if hashed or key and PII_NAME.search(snake(key)) else 0.7,
+                    file=source,
+                )
@@ -0,0 +1,269 @@
+"""
+Log calls of the standard library log, slog, zap and logrus, and the bridges that turn them into
+OpenTelemetry log records
+"""
+
+import re
+from dataclasses import dataclass, field
+from typing import Dict, Iterator, List, Optional, Set
+
+from ..golang import Arg, GoFile, GoFunc, match_bracket, split_args
+
+# Library -> import path of the logger, and of the bridge that emits its records through the logs API
+LIBRARIES = {
+    "slog": "log/slog",
+    "zap": "go.uber.org/zap",
+    "logrus": "github.com/sirupsen/logrus",
+    "log": "log",
+}
+BRIDGES = {
+    "slog": "go.opentelemetry.io/contrib/bridges/otelslog",
+    "zap": "go.opentelemetry.io/contrib/bridges/otelzap",
+    "logrus": "go.opentelemetry.io/contrib/bridges/otellogrus",
+}
+LOGS_API = "go.opentelemetry.io/otel/log"
+
+LEVELS = {
+    "trace": "trace", "debug": "debug", "info": "info", "print": "info", "warn": "warn", "warning": "warn",
+    "error": "error", "dpanic": "panic", "panic": "panic", "fatal": "fatal",
+}
+LOG_METHOD = re.compile(
+    r'\.(Trace|Debug|Info|Print|Warn|Warning|Error|DPanic|Panic|Fatal|Log|LogAttrs)(Context|f|w|ln)?\s*\('
+)
+# Receivers that are loggers: slog.Default(), s.logger, log, entry, zap.L()
+LOGGER_RECEIVER = re.compile(r'(?i)(?:^|[.(])(?:\w*log\w*|\w*sugar\w*|entry|l|zap\.[LS]\(\))(?:\(\))?$')
+# A context argument nobody derived from the request
+DETACHED_CONTEXT = re.compile(r'^context\.(?:Background|TODO)\(\)$')
+
+@dataclass
+class LogCall:
+    """One call that writes a log record"""
+    source: GoFile
+    library: str
+    # Start of the receiver chain and the method name
+    start: int
+    method: str
+    method_pos: int
+    open_paren: int
+    close_paren: int
+    level: str
+    # The context passed along (an argument, a zap field or logrus WithContext), if any
+    context: Optional[Arg]
+    message: Optional[Arg]
+    # printf-style: the arguments after the message are formatted into it
+    formatted: bool
+    # (key literal or None, key arg or None, value arg) for every structured field
+    fields: List[tuple] = field(default_factory=list)
+    # Arguments that make up each field, for removing it
+    field_args: List[List[Arg]] = field(default_factory=list)
+
+    @property
+    def end(self) -> int:
+        return self.close_paren + 1
+
+    @property
+    def args(self) -> List[Arg]:
+        return _args(self.source, self.open_paren, self.close_paren)
+
+def _args(source: GoFile, open_paren: int, close_paren: int) -> List[Arg]:
+    return [Arg(source.code[s:e], s, e) for s, e in split_args(source.masked, open_paren + 1, close_paren)]
+
+def _receiver_start(masked: str, dot: int) -> int:
+    """Start of the selector/call chain that ends just before dot"""
+
+    j = dot
+    while j > 0:
+        ch = masked[j - 1]
+        if ch.isalnum() or ch in "_.":
+            j -= 1
+        elif ch == ")":
+            depth = 0
+            k = j - 1
+            while k >= 0:
+                if masked[k] == ")":
+                    depth += 1
+                elif masked[k] == "(":
+                    depth -= 1
+                    if depth == 0:
+                        break
+                k -= 1
+            if k < 0:
+                break
+            j = k
+        else:
+            break
+    return j
+
+def _library(source: GoFile, receiver: str, method: str, suffix: str, args: List[Arg]) -> Optional[str]:
+    imported = [lib for lib, path in LIBRARIES.items() if path in source.imports.values()]
+    root = re.match(r'\w+', receiver)
+    root = root.group(0) if root else ""
+    for lib in imported:
+        aliases = [n for n, p in source.imports.items() if p == LIBRARIES[lib]]
+        if root in aliases:
+            return lib
+    if not LOGGER_RECEIVER.search(re.sub(r'\.With\w*\([^()]*(?:\([^()]*\)[^()]*)*\)', "", receiver)):
+        return None
+    candidates = [lib for lib in imported if lib != "log"]
+    if not candidates:
+        return None
+    if len(candidates) == 1:
+        return candidates[0]
+    if suffix == "Context" or method in ("Log", "LogAttrs"):
+        return "slog" if "slog" in candidates else None
+    if re.search(r'\.With(?:Fields?|Context|Error)\(', receiver) and "logrus" in candidates:
+        return "logrus"
+    if suffix == "w" or any(re.match(r'zap\.', a.text) for a in args):
+        return "zap" if "zap" in candidates else None
+    return candidates[0]
+
+def _pairs(args: List[Arg]) -> Iterator[List[Arg]]:
+    """Group alternating key/value arguments, keeping typed attributes (slog.String(...)) on their own"""
+
+    i = 0
+    while i < len(args):
+        if args[i].literal is not None and i + 1 < len(args):
+            yield [args[i], args[i + 1]]
+            i += 2
+        else:
+            yield [args[i]]
+            i += 1
+
+def _typed_field(source: GoFile, arg: Arg) -> Optional[tuple]:
+    """(key literal, key arg, value arg) of slog.String("k", v), zap.Error(err) and friends"""
+
+    m = re.match(r'(?:slog|zap)\.(\w+)\s*\(', arg.text)
+    if not m:
+        return None
+    open_paren = arg.start + m.end() - 1
+    close = match_bracket(source.masked, open_paren)
+    if close == -1:
+        return None
+    inner = _args(source, open_paren, close)
+    if m.group(1) == "Error" and len(inner) == 1:
+        return "error", None, inner[0]
+    if len(inner) >= 2:
+        return inner[0].literal, inner[0], inner[1]
+    return None
+
+def _logrus_chain(source: GoFile, start: int, dot: int) -> tuple:
+    """Context and fields added by WithContext, WithField(s) and WithError in a logrus chain"""
+
+    context, fields = None, []
+    for m in re.finditer(r'\.(WithContext|WithField|WithFields|WithError)\s*\(', source.masked[start:dot]):
+        open_paren = start + m.end() - 1
+        close = match_bracket(source.masked, open_paren)
+        inner = _args(source, open_paren, close)
+        if m.group(1) == "WithContext" and inner:
+            context = inner[0]
+        elif m.group(1) == "WithError" and inner:
+            fields.append(("error", None, inner[0]))
+        elif m.group(1) == "WithField" and len(inner) == 2:
+            fields.append((inner[0].literal, inner[0], inner[1]))
+        elif m.group(1) == "WithFields" and inner:
+            brace = source.masked.find("{", inner[0].start, inner[0].end)
+            if brace == -1:
+                continue
+            end = match_bracket(source.masked, brace)
+            for s, e in split_args(source.masked, brace + 1, end):
+                colon = source.masked.find(":", s, e)
+                if colon != -1:
+                    key = Arg(source.code[s:colon].strip(), s, colon)
+                    value_start = colon + 1 + len(source.code[colon + 1:e]) - len(source.code[colon + 1:e].lstrip())
+                    fields.append((key.literal, key, Arg(source.code[value_start:e], value_start, e)))
+    return context, fields
+
+def log_calls(source: GoFile) -> List[LogCall]:
+    if not any(path in source.imports.values() for path in LIBRARIES.values()):
+        return []
+    found = []
+    for m in LOG_METHOD.finditer(source.masked):
+        dot = m.start()
+        start = _receiver_start(source.masked, dot)
+        receiver = source.masked[start:dot]
+        if not receiver or receiver[0] == ".":
+            continue
+        method, suffix = m.group(1), m.group(2) or ""
+        open_paren = m.end() - 1
+        close = match_bracket(source.masked, open_paren)
+        if close == -1:
+            continue
+        args = _args(source, open_paren, close)
+        library = _library(source, receiver, method, suffix, args)
+        if library is None or (method in ("Log", "LogAttrs") and library != "slog"):
+            continue
+        if library == "log" and (suffix in ("Context", "w") or method not in ("Print", "Fatal", "Panic")):
+            continue
+        call = LogCall(source, library, start, method + suffix, dot + 1, open_paren, close,
+                       LEVELS.get(method.lower(), ""), None, None, suffix == "f")
+        rest = args
+        if library == "slog" and (suffix == "Context" or method in ("Log", "LogAttrs")):
+            call.context, rest = (args[0] if args else None), args[1:]
+            if method in ("Log", "LogAttrs") and rest:
+                level = re.search(r'Level(Debug|Info|Warn|Error)\b', rest[0].text)
+                call.level = level.group(1).lower() if level else ""
+                rest = rest[1:]
+        if library == "logrus":
+            call.context, call.fields = _logrus_chain(source, start, dot)
+            call.field_args = [[] for _ in call.fields]
+        if rest:
+            call.message, rest = rest[0], rest[1:]
+        if call.formatted or library == "log" or (library == "logrus" and suffix != "f"):
+            # Everything is rendered into the message text
+            call.formatted = True
+            call.fields.extend((None, None, a) for a in rest)
+            call.field_args.extend([a] for a in rest)
+        else:
+            for group in _pairs(rest):
+                if len(group) == 2:
+                    call.fields.append((group[0].literal, group[0], group[1]))
+                else:
+                    typed = _typed_field(source, group[0])
+                    if typed is None:
+                        if library == "zap" and re.match(r'zap\.(?:Any|Reflect)\(', group[0].text) is None:
+                            continue
+                        typed = (None, None, group[0])
+                    call.fields.append(typed)
+                call.field_args.append(group)
+        if library == "zap" and call.context is None:
+            call.context = next((v for k, _, v in call.fields if _is_context(v.text)), None)
+        found.append(call)
+    return found
+
+def _is_context(text: str) -> bool:
+    return bool(re.fullmatch(r'\s*(?:ctx|\w*Ctx|\w*[cC]ontext|r\.Context\(\)|req\.Context\(\))\s*', text))
+
+def bridged_libraries(sources: List[GoFile]) -> Set[str]:
+    """Libraries whose records reach the logs API, where the bridge reads the span from the context"""
+
+    bridged = {lib for lib, path in BRIDGES.items() for s in sources if s.imports_path(path)}
+    for source in sources:
+        # A slog.Handler of the program's own that copies the span context onto records
+        if re.search(r'\bfunc\s*\([^)]*\)\s*Handle\s*\(\s*\w+\s+context\.Context\s*,\s*\w+\s+slog\.Record\s*\)',
+                     source.masked) and re.search(r'\bSpan(?:Context)?FromContext\s*\(', source.masked):
+            bridged.add("slog")
+    return bridged
+
+def active_span(source: GoFile, pos: int) -> Optional[tuple]:
+    """(description, context variable) of the span current at pos in its function, if any"""
+
+    fn: Optional[GoFunc] = source.func_at(pos, include_literals=True)
+    if fn is None:
+        return None
+    for start in reversed(source.span_starts):
+        if fn.contains(start.call.start) and start.call.start < pos and source.func_at(
+                start.call.start, include_literals=True) is fn:
+            return (f"span {start.name!r}" if start.name else "the current span"), start.ctx_var
+    m = re.search(r'(\w+)\s*:=\s*trace\.SpanFromContext\s*\(\s*(\w+)\s*\)', source.masked[fn.body_start:pos])
+    if m:
+        return "the current span", m.group(2)
+    return None
+
+def context_param(fn: Optional[GoFunc]) -> str:
+    if fn is None:
+        return ""
+    m = re.search(r'(\w+)\s+context\.Context\b', fn.params)
+    return m.group(1) if m else ""
+
+def field_keys(call: LogCall) -> Dict[str, Arg]:
+    return {k: v for k, _, v in call.fields if k}
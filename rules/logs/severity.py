@@ -0,0 +1,166 @@
+"""
+Severity: the logs API records a SeverityNumber that backends filter and alert on, and a
+SeverityText shown as is. Bridges derive both from the library level, so the level has to be right.
+"""
+
+import re
+from typing import Iterator, Optional
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..golang import GoFile
+from ..registry import rule
+from .records import LOGS_API, log_calls
+
+# Severity constants of the logs API: log.SeverityWarn2 -> warn
+SEVERITY = r'Severity(Trace|Debug|Info|Warn|Error|Fatal)\d?'
+# Library level constants: slog.LevelWarn, zap.WarnLevel, zapcore.ErrorLevel, logrus.InfoLevel
+LEVEL = r'(?:slog\.Level(Debug|Info|Warn|Error)|(?:zap|zapcore|logrus)\.(Trace|Debug|Info|Warn|Error|Fatal)Level)\b'
+SEVERITY_TEXT = {"trace": "TRACE", "debug": "DEBUG", "info": "INFO", "warn": "WARN", "error": "ERROR", "fatal": "FATAL"}
+# Messages that say something failed
+FAILURE = re.compile(r'(?i)\b(?:fail\w*|error\w*|unable|cannot|can\'t|could not|couldn\'t)\b')
+# slog levels map to SeverityNumber level + 9; outside [-8, 15] they fall off the scale
+SLOG_OFFSET = 9
+
+def _text_level(text: str) -> Optional[str]:
+    word = re.sub(r'\d+$', "", text.strip().lower())
+    return {"warning": "warn", "err": "error", "critical": "fatal", "crit": "fatal", "fatal": "fatal",
+            "panic": "fatal"}.get(word, word if word in SEVERITY_TEXT else None)
+
+def _record_mismatches(source: GoFile) -> Iterator[Diagnostic]:
+    """SetSeverity and SetSeverityText on the same record disagreeing, or only the text being set"""
+
+    for fn in source.functions:
+        body = source.masked[fn.body_start:fn.body_end]
+        numbers = {m.group(1): m for m in re.finditer(r'(\w+)\.SetSeverity\s*\(\s*(\w+\.' + SEVERITY + r')\s*\)', body)}
+        for text_call in re.finditer(r'(\w+)\.SetSeverityText\s*\(\s*"', body):
+            record = text_call.group(1)
+            literal_start = fn.body_start + text_call.end() - 1
+            literal_end = source.code.find('"', literal_start + 1) + 1
+            text = source.code[literal_start + 1:literal_end - 1]
+            number = numbers.get(record)
+            if number is None:
+                if not re.search(r'\b' + re.escape(record) + r'\.SetSeverity\s*\(', body):
+                    yield Diagnostic(
+                        pos=fn.body_start + text_call.start(),
+                        message=f"Record {record} gets SeverityText {text!r} but no SeverityNumber, so backends "
+                                f"treat it as unspecified and severity filters skip it",
+                        suggestion=f"Also call {record}.SetSeverity with the matching log.Severity constant",
+                        confidence=0.85,
+                    )
+                continue
+            level, named = number.group(3).lower(), _text_level(text)
+            if named in (None, level):
+                continue
+            alias = number.group(2).split(".", 1)[0]
+            pos, end = fn.body_start + number.start(2), fn.body_start + number.end(2)
+            yield Diagnostic(
+                pos=pos,
+                end=end,
+                message=f"Record {record} has SeverityNumber {number.group(3)} but SeverityText {text!r}",
+                suggestion=f"Make them agree: {alias}.Severity{named.capitalize()}, or "
+                           f'SetSeverityText("{SEVERITY_TEXT[level]}")',
+                confidence=0.85,
+                fix=Fix(description=f"Use {alias}.Severity{named.capitalize()}",
+                        edits=[TextEdit(pos, end, f"{alias}.Severity{named.capitalize()}")]),
+            )
+
+def _mapping_mismatches(source: GoFile) -> Iterator[Diagnostic]:
+    """Switch cases mapping a library level to a different logs API severity"""
+
+    for case in re.finditer(r'\bcase\s+([^:]+):', source.masked):
+        levels = {(m.group(1) or m.group(2)).lower() for m in re.finditer(LEVEL, case.group(1))}
+        if len(levels) != 1:
+            continue
+        level = levels.pop()
+        next_case = re.compile(r'\bcase\b|\bdefault\s*:|^\s*}', re.M).search(source.masked, case.end())
+        end = next_case.start() if next_case else len(source.masked)
+        severity = re.compile(r'\b\w+\.' + SEVERITY).search(source.masked, case.end(), end)
+        if severity is None or severity.group(1).lower() == level:
+            continue
+        yield Diagnostic(
+            pos=severity.start(),
+            end=severity.end(),
+            message=f"Level {case.group(1).strip()} is mapped to {severity.group(0)}, so records change "
+                    f"severity on their way to the logs API",
+            suggestion=f"Map it to Severity{level.capitalize()}",
+            confidence=0.8,
+        )
+
+def _slog_levels(source: GoFile) -> Iterator[Diagnostic]:
+    """Custom slog levels outside the range the bridge can map"""
+
+    for m in re.finditer(r'\bslog\.Level\(\s*(-?\d+)\s*\)', source.masked):
+        value = int(m.group(1))
+        if -SLOG_OFFSET < value <= 24 - SLOG_OFFSET:
+            continue
+        yield Diagnostic(
+            pos=m.start(),
+            end=m.end(),
+            message=f"slog.Level({value}) maps to SeverityNumber {value + SLOG_OFFSET}, outside the 1-24 range "
+                    f"of the logs API",
+            suggestion="Keep custom levels between slog.Level(-8) (TRACE) and slog.Level(15) (FATAL4)",
+            confidence=0.8,
+        )
+
+def _errors_below_warn(source: GoFile) -> Iterator[Diagnostic]:
+    """Failures logged with an error at debug or info level"""
+
+    for call in log_calls(source):
+        # The standard logger has no levels to get wrong
+        if call.library == "log" or call.level not in ("trace", "debug", "info"):
+            continue
+        error = next((v for k, _, v in call.fields
+                      if k in ("error", "err") or re.fullmatch(r'\s*err(?:\.Error\(\))?\s*', v.text)), None)
+        message = call.message and call.message.literal
+        if error is None or not message or not FAILURE.search(message):
+            continue
+        method = call.source.code[call.method_pos:call.open_paren]
+        raised = re.sub(r'^(?:Trace|Debug|Info|Print)', "Error", method)
+        fix = None
+        if raised != method:
+            fix = Fix(description=f"Log at {raised}",
+                      edits=[TextEdit(call.method_pos, call.open_paren, raised)])
+        yield Diagnostic(
+            pos=call.method_pos,
+            end=call.open_paren,
+            message=f"Failure {message!r} is logged with {error.text.strip()} at {call.level} level, so it "
+                    f"becomes Severity{call.level.capitalize()} and error filters and alerts miss it",
+            suggestion=f"Log it with {raised}, or Warn if it's handled",
+            confidence=0.6,
+            fix=fix,
+        )
+
+@rule(
+    rule_id="log-severity-mismatch",
+    title="Give log records the severity they describe",
+    category="correctness",
+    signal="logs",
+    severity="medium",
+    autofix=True,
+    description="Backends filter and alert on the SeverityNumber. A record whose SeverityText says ERROR but "
+                "whose number says Info, a SeverityText without a number, a level switch mapping WARN to "
+                "SeverityError, an slog.Level the otelslog bridge shifts past FATAL4, or a failure logged with "
+                "its error at Info all make records show up under the wrong severity, or none.",
+    bad_example='''
+func emitPaymentFailure(ctx context.Context, logger otellog.Logger, err error) {
+	var record otellog.Record
+	record.SetSeverity(otellog.SeverityInfo)
+	record.SetSeverityText("ERROR")
+	logger.Emit(ctx, record)
+	slog.InfoContext(ctx, "payment failed", "error", err)
+}''',
+    good_example='''
+func emitRefundFailure(ctx context.Context, logger otellog.Logger, err error) {
+	var record otellog.Record
+	record.SetSeverity(otellog.SeverityError)
+	record.SetSeverityText("ERROR")
+	logger.Emit(ctx, record)
+	slog.ErrorContext(ctx, "refund failed", "error", err)
+}''',
+)
+def check_log_severity_mismatch(source: GoFile) -> Iterator[Diagnostic]:
+    if source.imports_path(LOGS_API):
+        yield from _record_mismatches(source)
+        yield from _mapping_mismatches(source)
+    yield from _slog_levels(source)
+    yield from _errors_below_warn(source)
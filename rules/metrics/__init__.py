@@ -0,0 +1,5 @@
+"""
+Metric signal rules
+"""
+
+from . import naming, spans, exemplars, units, kinds, attributes
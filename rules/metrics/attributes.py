@@ -0,0 +1,81 @@
+"""
+Metric attributes: every distinct attribute set is its own time series, so unlike span attributes
+their values must come from a small set
+"""
+
+import re
+from typing import Dict, Iterator, Optional, Tuple
+
+from ..base import Diagnostic
+from ..cardinality import Bounds
+from ..golang import Call, GoFile
+from ..registry import rule
+from .exemplars import TRACE_KEY
+from .instruments import metric_attributes
+
+# Keys whose values identify a user, request or resource instance
+UNBOUNDED_KEYS = re.compile(
+    r'(?i)(?:.*[._])?(?:id|uuid|guid|email|user(?:name)?|session|token|ip|address|url|uri|path|query|target'
+    r'|user_agent(?:\.original)?|timestamp|time|message)'
+)
+# Semconv keys that look unbounded but aren't
+BOUNDED_KEYS = {"http.route", "server.address", "url.scheme", "network.peer.address", "error.type"}
+
+@rule(
+    rule_id="metric-attribute-high-cardinality",
+    title="Keep metric attribute values to a small, fixed set",
+    category="performance",
+    signal="metrics",
+    severity="high",
+    options={"max_values": 100},
+    description="Each distinct combination of attribute values on an instrument is a separate time series that "
+                "the SDK keeps in memory and the backend stores and bills for. User and request IDs, URLs with "
+                "their paths and queries, error messages and timestamps give every request its own series; "
+                "they belong on spans, with the metric keeping bounded dimensions like http.route. Values are "
+                "checked like span names (constants, enums, switch cases); parameters are left to the callers.",
+    bad_example='''
+func recordOrder(ctx context.Context, r *http.Request, userID string, err error) {
+	ordersPlaced.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("user.id", userID),
+		attribute.String("url.path", r.URL.Path),
+		attribute.String("error.message", err.Error()),
+	))
+}''',
+    good_example='''
+func recordOrderBounded(ctx context.Context, r *http.Request) {
+	ordersPlaced.Add(ctx, 1, metric.WithAttributes(
+		semconv.HTTPRoute("/orders"),
+		semconv.HTTPRequestMethodKey.String(r.Method),
+	))
+}''',
+)
+def check_metric_attribute_high_cardinality(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    bounds = Bounds(source, options["max_values"])
+    for call in metric_attributes(source):
+        problem = unbounded_attribute(call, bounds)
+        if problem is None:
+            continue
+        message, confidence = problem
+        yield Diagnostic(
+            pos=call.start,
+            end=call.end,
+            message=message + ", so each value becomes its own time series",
+            suggestion="Record it as a span attribute instead, and keep metric attributes bounded (route "
+                       "template, method, status class)",
+            confidence=confidence,
+        )
+
+def unbounded_attribute(call: Call, bounds: Bounds) -> Optional[Tuple[str, float]]:
+    """Why the metric attribute call takes unbounded values, with the confidence, or None when
+    its key and value don't show it"""
+
+    key_arg, value = call.args
+    key = key_arg.literal
+    if key is not None and TRACE_KEY.match(key):
+        return None  # trace-id-metric-attribute
+    if key is not None and key not in BOUNDED_KEYS and UNBOUNDED_KEYS.fullmatch(key):
+        return f"Metric attribute {key!r} identifies a single user, request or resource", 0.8
+    if call.name.endswith(".String") and bounds.values(value.text, value.start) is None and bounds.culprit_kind():
+        return (f"Metric attribute {key_arg.text.strip()} is set from {bounds.explain()}, which takes a new "
+                f"value on every request"), 0.7
+    return None
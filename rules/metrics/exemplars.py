@@ -0,0 +1,155 @@
+"""
+Exemplars: the link from a metric data point to a trace that contributed to it.
+They only work when measurements are recorded with the context carrying the span, and they are
+the supported way to attach trace IDs to metrics.
+"""
+
+import re
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, parse_params
+from ..registry import rule
+from .instruments import instrument_vars, PROMETHEUS
+
+EXEMPLARS_OFF = (r'\bWithExemplarFilter\s*\(\s*[\w.]*AlwaysOffFilter\s*\)'
+                 r'|Setenv\s*\(\s*"OTEL_METRICS_EXEMPLAR_FILTER"\s*,\s*"always_off"')
+DETACHED_CONTEXT = r'context\.(?:Background|TODO)\(\)'
+
+TRACE_KEY = re.compile(r'(?i)^(?:otel[._])?(?:trace|span)(?:[._-]?id)?$')
+TRACE_VALUE = r'\.(?:TraceID|SpanID)\(\)'
+
+def _uses_traces(sources: List[GoFile]) -> bool:
+    return any(s.span_starts or re.search(r'\bNewTracerProvider\s*\(|\botel(?:http|grpc)\.', s.masked)
+               for s in sources)
+
+def _request_context(fn: Optional[GoFunc], source: GoFile, pos: int) -> Optional[str]:
+    """An expression for the context that carries the current span at pos, if the function has one"""
+
+    if fn is None:
+        return None
+    for start in source.span_starts:
+        if start.func is fn and start.call.start < pos and start.ctx_var and start.ctx_var != "_":
+            return start.ctx_var
+    for name, typ in parse_params(fn.params):
+        if name and typ.endswith("context.Context"):
+            return name
+        if name and typ.endswith("http.Request"):
+            return f"{name}.Context()"
+    return None
+
+@rule(
+    rule_id="exemplars-not-linked",
+    title="Record measurements with the span's context so exemplars link to traces",
+    category="sdk",
+    signal="metrics",
+    signals=("traces",),
+    severity="low",
+    scope="project",
+    description="With traces and metrics in the same program, exemplars let a latency spike on a dashboard "
+                "jump to a trace that caused it. The SDK samples exemplars from the span in the context "
+                "passed to Add or Record, so measurements recorded with context.Background() never carry "
+                "one, and an AlwaysOff exemplar filter disables them altogether.",
+    bad_example='''
+func handleCheckout(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /checkout")
+	defer span.End()
+	start := time.Now()
+	checkout(ctx, r)
+	checkoutDuration.Record(context.Background(), time.Since(start).Seconds())
+}
+
+var checkoutDuration, _ = meter.Float64Histogram("checkout.duration")''',
+    good_example='''
+func handleCheckoutLinked(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /checkout")
+	defer span.End()
+	start := time.Now()
+	checkout(ctx, r)
+	checkoutDuration.Record(ctx, time.Since(start).Seconds())
+}
+
+var checkoutDuration, _ = meter.Float64Histogram("checkout.duration")''',
+)
+def check_exemplars_not_linked(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    names = instrument_vars(sources)
+    if not names or not _uses_traces(sources):
+        return
+    for source in sources:
+        for m in re.finditer(EXEMPLARS_OFF, source.masked):
+            yield Diagnostic(
+                pos=m.start(),
+                message="Exemplars are switched off although the program records traces and metrics",
+                suggestion="Use the trace_based exemplar filter (the SDK default) so data points link to sampled traces",
+                confidence=0.8,
+                file=source,
+            )
+        if source.path.endswith("_test.go"):
+            continue
+        for name in sorted(names):
+            for call in source.calls(r'(?:[\w.]+\.)?' + re.escape(name) + r'\.(?:Add|Record)'):
+                if not call.args or not re.fullmatch(DETACHED_CONTEXT, call.args[0].text.strip()):
+                    continue
+                ctx = _request_context(source.func_at(call.start, include_literals=True), source, call.start)
+                if ctx is None:
+                    continue
+                yield Diagnostic(
+                    pos=call.args[0].start,
+                    end=call.args[0].end,
+                    message=f"{name} is recorded with {call.args[0].text.strip()} although {ctx} carries the "
+                            f"current span, so its data points can't get exemplars",
+                    suggestion=f"Pass {ctx} to {call.name.rsplit('.', 1)[1]}",
+                    confidence=0.8,
+                    file=source,
+                )
+
+def _trace_id_attributes(source: GoFile) -> Iterator[tuple]:
+    """(offset, description) of trace or span IDs attached to metric data points or label sets"""
+
+    for call in source.calls(r'[\w.]*\.WithAttributes|[\w.]*\.WithAttributeSet'):
+        if not re.match(r'metric\b|\w*metric\w*\.', call.name):
+            continue
+        for arg in call.args:
+            key = re.match(r'\s*attribute\.\w+\s*\(\s*"([^"]*)"', arg.text)
+            if key and TRACE_KEY.match(key.group(1)):
+                yield arg.start, f'attribute "{key.group(1)}"'
+            elif re.search(TRACE_VALUE, arg.text):
+                yield arg.start, "an attribute holding a trace ID"
+    if source.imports_path(PROMETHEUS):
+        for call in source.calls(r'[\w.]+\.(?:New\w+Vec)'):
+            for m in re.finditer(r'"([^"]*)"', call.args[-1].text if call.args else ""):
+                if TRACE_KEY.match(m.group(1)):
+                    yield call.args[-1].start + m.start(), f'label "{m.group(1)}"'
+        for call in source.calls(r'[\w.]+\.WithLabelValues'):
+            if any(re.search(TRACE_VALUE, a.text) for a in call.args):
+                yield call.start, "a label value holding a trace ID"
+
+@rule(
+    rule_id="trace-id-metric-attribute",
+    title="Use exemplars, not attributes, to put trace IDs on metrics",
+    category="performance",
+    signal="metrics",
+    signals=("traces",),
+    severity="high",
+    description="A trace or span ID as a metric attribute or Prometheus label creates a new time series for "
+                "every request, which is exactly the cardinality metrics backends can't handle. Exemplars "
+                "carry the trace ID alongside a data point without making it a dimension.",
+    bad_example='''
+func recordLatency(ctx context.Context, d time.Duration) {
+	sc := trace.SpanContextFromContext(ctx)
+	latency.Record(ctx, d.Seconds(), metric.WithAttributes(attribute.String("trace_id", sc.TraceID().String())))
+}''',
+    good_example='''
+func recordLatencyWithExemplar(ctx context.Context, d time.Duration) {
+	latency.Record(ctx, d.Seconds(), metric.WithAttributes(attribute.String("http.route", "/checkout")))
+}''',
+)
+def check_trace_id_metric_attribute(source: GoFile) -> Iterator[Diagnostic]:
+    for pos, what in _trace_id_attributes(source):
+        yield Diagnostic(
+            pos=pos,
+            message=f"Metric data points carry {what}, creating a time series per trace",
+            suggestion="Drop it and record the measurement with the span's context; exemplars attach the trace ID "
+                       "(Prometheus: ObserveWithExemplar/AddWithExemplar)",
+            confidence=0.85,
+        )
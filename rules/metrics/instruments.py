@@ -0,0 +1,107 @@
+"""
+Metric instrument discovery shared by the metric rules
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from ..golang import Arg, GoFile, Call, match_bracket
+from ..traces.attributes import attribute_calls
+
+INSTRUMENT_KINDS = r'(?:Int64|Float64)(?:Counter|UpDownCounter|Histogram|Gauge|ObservableCounter|ObservableUpDownCounter|ObservableGauge)'
+
+PROMETHEUS = "github.com/prometheus/client_golang"
+METRIC_PKG = "go.opentelemetry.io/otel/metric"
+
+@dataclass
+class Instrument:
+    """A meter.<Kind>(name, options...) call"""
+    call: Call
+    kind: str
+    name: Optional[str]
+    unit: Optional[str]
+    source: Optional[GoFile] = None
+
+    @property
+    def is_counter(self) -> bool:
+        return self.kind.endswith("Counter") and "UpDown" not in self.kind
+
+def instruments(source: GoFile) -> Iterator[Instrument]:
+    for call in source.calls(r'[\w.]+\.' + INSTRUMENT_KINDS):
+        if not call.args:
+            continue
+        kind = call.name.rsplit(".", 1)[1]
+        unit = None
+        for arg in call.args[1:]:
+            m = re.fullmatch(r'[\w.]*WithUnit\s*\(\s*("[^"]*")\s*\)', arg.text.strip())
+            if m:
+                unit = m.group(1)[1:-1]
+        yield Instrument(call, kind, call.args[0].literal, unit, source)
+
+def assigned_name(source: GoFile, pos: int) -> str:
+    """Variable or field a constructor call at pos is assigned to ("c" for `s.c, err = ...`)"""
+
+    m = re.search(r'([\w.]+?)(?:\s*,\s*\w+)?\s*:?=\s*$|(\w+)\s*:\s*$', source.statement_prefix(pos))
+    if not m:
+        return ""
+    return (m.group(1) or m.group(2)).rsplit(".", 1)[-1]
+
+def instrument_vars(sources: List[GoFile], counters_only: bool = False) -> Set[str]:
+    """Variables and fields holding synchronous instruments (or only counters)"""
+
+    names = set()
+    for source in sources:
+        for inst in instruments(source):
+            if "Observable" not in inst.kind and (inst.is_counter or not counters_only):
+                names.add(assigned_name(source, inst.call.start))
+    names.discard("")
+    return names
+
+def instruments_by_var(sources: List[GoFile]) -> Dict[str, Instrument]:
+    """Variables and fields holding synchronous instruments -> the instrument"""
+
+    found = {}
+    for source in sources:
+        for inst in instruments(source):
+            name = assigned_name(source, inst.call.start)
+            if name and "Observable" not in inst.kind:
+                found.setdefault(name, inst)
+    return found
+
+def measurements(source: GoFile, by_var: Dict[str, Instrument]) -> Iterator[Tuple[Call, str, Instrument, Arg]]:
+    """(call, variable, instrument, value) of the Add and Record calls on known instruments"""
+
+    for name in sorted(by_var):
+        for call in source.calls(r'(?:[\w.]+\.)?' + re.escape(name) + r'\.(?:Add|Record)'):
+            if len(call.args) >= 2:
+                yield call, name, by_var[name], call.args[1]
+
+def metric_attributes(source: GoFile) -> Iterator[Call]:
+    """attribute.<Constructor>(key, value) calls inside metric.WithAttributes options"""
+
+    options = [option for alias in source.import_alias(METRIC_PKG)
+               for option in source.calls(re.escape(alias) + r'\.WithAttributes')]
+    for call in attribute_calls(source):
+        if len(call.args) == 2 and any(o.open_paren < call.start < o.close_paren for o in options):
+            yield call
+
+@dataclass
+class PrometheusMetric:
+    """A client_golang collector declared through one of the *Opts structs"""
+    source: GoFile
+    pos: int
+    name: str
+
+def prometheus_metrics(source: GoFile) -> List[PrometheusMetric]:
+    found = []
+    for alias in source.import_alias(PROMETHEUS):
+        for m in re.finditer(re.escape(alias) + r'\.(?:Counter|Gauge|Histogram|Summary)(?:Vec)?Opts\s*\{', source.masked):
+            close = match_bracket(source.masked, m.end() - 1)
+            body = source.code[m.end():close]
+            fields = dict(re.findall(r'\b(Namespace|Subsystem|Name)\s*:\s*"([^"]*)"', body))
+            if not fields.get("Name"):
+                continue
+            parts = [fields.get("Namespace", ""), fields.get("Subsystem", ""), fields["Name"]]
+            found.append(PrometheusMetric(source, m.start(), "_".join(p for p in parts if p)))
+    return found
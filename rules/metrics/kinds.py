@@ -0,0 +1,150 @@
+"""
+Instrument kinds: a Counter only goes up, an UpDownCounter tracks a level through deltas, and a
+gauge reports a level as it is. Picking the wrong one corrupts every rate and sum computed from it.
+"""
+
+import re
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+from .instruments import instruments_by_var, measurements
+
+# Last name segments of quantities that go down as well as up
+LEVEL_WORDS = r'active|inflight|in_flight|pending|queued|queue_size|depth|open|current|usage|used|free|available|size|goroutines|connections'
+# Values that are a reading of a level, not an increment
+LEVEL_READINGS = r'runtime\.NumGoroutine\(\)|[\w.]+\.(?:Len|Size|Stats|InUse|Idle|OpenConnections)\(\)(?:\.\w+)?'
+
+def _negative(value: str) -> Optional[float]:
+    """Confidence that an Add value is negative: a literal or negated operand, or a difference"""
+
+    value = re.sub(r'^(?:u?int(?:64)?|float64)\((.*)\)$', r'\1', value.strip())
+    if re.fullmatch(r'-\s*[\w.]+(?:\(\))?', value):
+        return 0.95
+    if re.fullmatch(r'[\w.]+(?:\([^()]*\))?\s*-\s*[\w.]+(?:\([^()]*\))?', value):
+        return 0.5
+    return None
+
+@rule(
+    rule_id="counter-negative-increment",
+    title="Never add negative values to a Counter",
+    category="correctness",
+    signal="metrics",
+    severity="high",
+    scope="project",
+    description="Counters are monotonic: backends compute rates from them and read any decrease as a process "
+                "restart. Add(ctx, -1) on a Counter, or a difference that can go below zero, produces "
+                "nonsense rates (the SDK may drop it, exporters may not), so a value that goes down needs an "
+                "UpDownCounter, or a gauge if it is read rather than counted.",
+    bad_example='''
+var openSessions, _ = meter.Int64Counter("app.sessions.open")
+
+func closeSession(ctx context.Context) {
+	openSessions.Add(ctx, -1)
+}''',
+    good_example='''
+var liveSessions, _ = meter.Int64UpDownCounter("app.sessions.live")
+
+func endSession(ctx context.Context) {
+	liveSessions.Add(ctx, -1)
+}''',
+)
+def check_counter_negative_increment(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    counters = {name: inst for name, inst in instruments_by_var(sources).items() if inst.is_counter}
+    for source in sources:
+        for call, name, inst, value in measurements(source, counters):
+            confidence = _negative(value.text)
+            if confidence is None:
+                continue
+            what = "a negative value" if confidence > 0.9 else f"{value.text.strip()}, which can be negative"
+            yield Diagnostic(
+                pos=value.start,
+                end=value.end,
+                message=f"Counter {name} ({inst.name or 'instrument'}) is given {what}, but counters only go up",
+                suggestion=f"Make {name} an {inst.kind.replace('Counter', 'UpDownCounter')}",
+                confidence=confidence,
+                file=source,
+            )
+
+@rule(
+    rule_id="instrument-kind-mismatch",
+    title="Pick Counter, UpDownCounter or gauge for what the value does",
+    category="correctness",
+    signal="metrics",
+    severity="medium",
+    scope="project",
+    description="A Counter counts events and only goes up; an UpDownCounter tracks a level (active requests, "
+                "queue length) through increments and decrements; a gauge records a level that is read "
+                "(runtime.NumGoroutine(), pool.Stats()). A Counter named like a level, or fed readings, "
+                "sums the readings into meaningless totals, and an UpDownCounter that is only ever "
+                "incremented loses the rate functions backends offer for counters.",
+    bad_example='''
+var activeWorkers, _ = meter.Int64Counter("jobs.workers.active")
+var jobsDone, _ = meter.Int64UpDownCounter("jobs.completed")
+
+func reportWorkers(ctx context.Context) {
+	activeWorkers.Add(ctx, int64(runtime.NumGoroutine()))
+}
+
+func completeJob(ctx context.Context) {
+	jobsDone.Add(ctx, 1)
+}''',
+    good_example='''
+var workerCount, _ = meter.Int64Gauge("jobs.workers")
+var jobsFinished, _ = meter.Int64Counter("jobs.finished")
+
+func reportWorkerCount(ctx context.Context) {
+	workerCount.Record(ctx, int64(runtime.NumGoroutine()))
+}
+
+func finishJob(ctx context.Context) {
+	jobsFinished.Add(ctx, 1)
+}''',
+)
+def check_instrument_kind_mismatch(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    by_var = instruments_by_var(sources)
+    adds = {}
+    for source in sources:
+        for call, name, inst, value in measurements(source, by_var):
+            adds.setdefault(name, []).append((source, call, value))
+    for name, inst in sorted(by_var.items()):
+        calls = adds.get(name, [])
+        if inst.is_counter:
+            level = inst.name and re.search(r'(?:^|[._])(?:' + LEVEL_WORDS + r')$', inst.name)
+            reading = next(((s, v) for s, _, v in calls if re.search(LEVEL_READINGS, v.text)), None)
+            if reading:
+                s, v = reading
+                yield Diagnostic(
+                    pos=v.start,
+                    end=v.end,
+                    message=f"Counter {name} is given {v.text.strip()}, a reading of a current level, so it "
+                            f"sums the readings",
+                    suggestion=f"Record it with an {inst.kind.replace('Counter', 'Gauge')} (or an observable "
+                               f"gauge callback)",
+                    confidence=0.8,
+                    file=s,
+                )
+            elif level and not any(_negative(v.text) for _, _, v in calls):
+                yield Diagnostic(
+                    pos=inst.call.start,
+                    end=inst.call.end,
+                    message=f"Counter {name} ({inst.name!r}) is named like a level, which goes down as well as up",
+                    suggestion=f"Make it an {inst.kind.replace('Counter', 'UpDownCounter')} and decrement it, "
+                               f"or a gauge if the level is read",
+                    confidence=0.6,
+                    file=inst.source,
+                )
+        elif "UpDownCounter" in inst.kind and calls and all(
+                re.fullmatch(r'\d+(?:\.\d+)?|(?:u?int(?:64)?|float64)\(len\([^()]*\)\)|len\([^()]*\)', v.text.strip())
+                for _, _, v in calls):
+            yield Diagnostic(
+                pos=inst.call.start,
+                end=inst.call.end,
+                message=f"UpDownCounter {name} ({inst.name or 'instrument'}) is only ever incremented, so it "
+                        f"is a Counter",
+                suggestion=f"Make it an {inst.kind.replace('UpDownCounter', 'Counter')} so backends can "
+                           f"compute its rate",
+                confidence=0.6,
+                file=inst.source,
+            )
@@ -0,0 +1,269 @@
+"""
+Instrument naming rules
+"""
+
+import re
+from pathlib import Path
+from typing import Iterator, List, Dict, Optional, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+from .instruments import instruments, prometheus_metrics, Instrument, PROMETHEUS
+
+# UCUM unit -> suffix the Prometheus exporter appends
+PROMETHEUS_UNITS = {
+    "d": "days", "h": "hours", "min": "minutes", "s": "seconds", "ms": "milliseconds",
+    "us": "microseconds", "ns": "nanoseconds", "By": "bytes", "KiBy": "kibibytes",
+    "MiBy": "mebibytes", "GiBy": "gibibytes", "kBy": "kilobytes", "KBy": "kilobytes", "MBy": "megabytes",
+    "GBy": "gigabytes", "m": "meters", "V": "volts", "A": "amperes", "J": "joules",
+    "W": "watts", "g": "grams", "Cel": "celsius", "Hz": "hertz", "%": "percent",
+}
+
+# Unit words people bake into names instead of passing metric.WithUnit
+NAME_UNIT_SUFFIXES = {
+    "ms": "ms", "millis": "ms", "milliseconds": "ms", "seconds": "s", "secs": "s", "sec": "s",
+    "us": "us", "micros": "us", "microseconds": "us", "ns": "ns", "nanos": "ns", "nanoseconds": "ns",
+    "bytes": "By", "kb": "kBy", "mb": "MBy", "percent": "%", "pct": "%",
+}
+
+# What the API accepts: the SDK returns an error for anything else
+INSTRUMENT_NAME = re.compile(r'[A-Za-z][A-Za-z0-9_.\-/]{0,254}')
+
+def prometheus_name(name: str, unit: Optional[str], counter: bool) -> str:
+    """The metric name the OpenTelemetry Prometheus exporter exposes for an instrument"""
+
+    translated = re.sub(r'[^A-Za-z0-9_:]', "_", name)
+    translated = re.sub(r'__+', "_", translated).strip("_")
+    if translated[:1].isdigit():
+        translated = "_" + translated
+    # Curly-brace annotations ("{request}") carry no unit
+    unit = re.sub(r'\{[^}]*\}', "", unit or "")
+    suffix = PROMETHEUS_UNITS.get(unit, "")
+    if unit == "1" and not counter:
+        suffix = "ratio"
+    if suffix and not translated.endswith("_" + suffix):
+        translated += "_" + suffix
+    if counter and not translated.endswith("_total"):
+        translated += "_total"
+    return translated
+
+def _segments(name: str) -> List[str]:
+    return [s for s in re.split(r'[._]', name) if s]
+
+def naming_problems(inst: Instrument) -> Tuple[List[str], str, Optional[str]]:
+    """(problems, suggested name, suggested unit) for an instrument name under Prometheus translation"""
+
+    name = inst.name or ""
+    problems = []
+    suggested_unit = inst.unit
+    segments = _segments(name)
+
+    illegal = sorted(set(re.findall(r'[^A-Za-z0-9_.:]', name)))
+    if illegal:
+        problems.append(f"characters {' '.join(repr(c) for c in illegal)} are rewritten to '_'")
+        segments = [s for s in re.split(r'[^A-Za-z0-9]+', name) if s]
+
+    if segments and segments[-1].lower() == "total":
+        problems.append(f"ends in 'total', which the exporter {'adds itself' if inst.is_counter else 'makes look like a counter'}")
+        segments = segments[:-1]
+
+    if segments and segments[-1].lower() in NAME_UNIT_SUFFIXES:
+        unit = NAME_UNIT_SUFFIXES[segments[-1].lower()]
+        exposed = prometheus_name(name, inst.unit, inst.is_counter)
+        if inst.unit and inst.unit != unit:
+            problems.append(f"name says '{segments[-1]}' but the unit is {inst.unit!r} (exposed as {exposed})")
+        else:
+            problems.append(f"unit '{segments[-1]}' is part of the name instead of metric.WithUnit")
+            suggested_unit = unit
+        segments = segments[:-1]
+
+    if name != name.lower():
+        problems.append("contains uppercase letters")
+
+    suggested = ".".join(s.lower() for s in segments)
+    return problems, suggested, suggested_unit
+
+@rule(
+    rule_id="prometheus-name-translation",
+    title="Metric names must survive Prometheus name translation",
+    category="conventions",
+    signal="metrics",
+    severity="medium",
+    description="In codebases that also use prometheus/client_golang, OpenTelemetry instruments are usually "
+                "scraped through the Prometheus exporter, which rewrites illegal characters and appends unit "
+                "and _total suffixes. Names that already carry those suffixes get mangled, and translated names "
+                "can collide with existing client_golang metrics.",
+    scope="project",
+    bad_example='''
+var legacyRequests = prometheus.NewCounter(prometheus.CounterOpts{Name: "http_requests_total"})
+
+func newInstruments(meter metric.Meter) {
+	meter.Int64Counter("http.requests")
+	meter.Float64Histogram("checkout-latency_ms")
+}''',
+    good_example='''
+var legacyErrors = prometheus.NewCounter(prometheus.CounterOpts{Name: "http_errors_total"})
+
+func newCompatibleInstruments(meter metric.Meter) {
+	meter.Int64Counter("http.server.requests")
+	meter.Float64Histogram("checkout.duration", metric.WithUnit("ms"))
+}''',
+)
+def check_prometheus_names(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    if not any(s.imports_path(PROMETHEUS) for s in sources):
+        return
+
+    existing: Dict[str, str] = {}
+    for source in sources:
+        for metric in prometheus_metrics(source):
+            existing.setdefault(metric.name, f"{Path(source.path).name}:{source.line_of(metric.pos)}")
+
+    exposed: Dict[str, Tuple[str, str]] = {}
+    for source in sources:
+        for inst in instruments(source):
+            if inst.name is None:
+                continue
+            translated = prometheus_name(inst.name, inst.unit, inst.is_counter)
+            problems, suggested, unit = naming_problems(inst)
+
+            if translated in existing:
+                problems.append(f"exposed as {translated}, colliding with the client_golang metric at {existing[translated]}")
+            elif translated in exposed and exposed[translated][0] != inst.name:
+                other, where = exposed[translated]
+                problems.append(f"exposed as {translated}, the same name as instrument {other!r} at {where}")
+            exposed.setdefault(translated, (inst.name, f"{Path(source.path).name}:{source.line_of(inst.call.start)}"))
+
+            if not problems:
+                continue
+            suggestion = f'Name it "{suggested}"' if suggested and suggested != inst.name else "Rename the instrument"
+            if unit and unit != inst.unit:
+                suggestion += f' with metric.WithUnit("{unit}")'
+            if translated in existing:
+                suggestion += ", or give it a namespace that doesn't clash with the client_golang metric"
+            yield Diagnostic(
+                pos=inst.call.start,
+                message=f"Instrument {inst.name!r}: " + "; ".join(problems),
+                suggestion=suggestion,
+                confidence=0.85,
+                file=source,
+            )
+
+def conventional_name(name: str) -> str:
+    """name in semconv style: lowercase, '.' between namespaces and '_' between words"""
+
+    snake = re.sub(r'([a-z0-9])([A-Z])', r'\1_\2', name).lower()
+    snake = re.sub(r'[^a-z0-9_.]+', "_", snake)
+    if "." not in snake:
+        snake = snake.replace("_", ".")
+    segments = [s.strip("_") for s in snake.split(".") if s.strip("_")]
+    if len(segments) > 1 and segments[-1] == "total":
+        segments = segments[:-1]
+    return ".".join(segments)
+
+def name_convention_problems(name: str) -> List[str]:
+    problems = []
+    if not INSTRUMENT_NAME.fullmatch(name):
+        problems.append("isn't a valid instrument name (a letter, then up to 254 letters, digits, "
+                        "'_', '.', '-' or '/'), so the SDK returns an error for it")
+    if re.search(r'[a-z0-9][A-Z]', name):
+        problems.append("is camelCase")
+    elif name != name.lower():
+        problems.append("contains uppercase letters")
+    if re.search(r'[-/\s]', name):
+        problems.append("separates words with '-', '/' or spaces")
+    if "." not in name and "_" in name:
+        problems.append("separates namespaces with '_' instead of '.'")
+    elif "." not in name and not re.search(r'[a-z0-9][A-Z]|[-/\s]', name):
+        problems.append("has no namespace, so it collides with any other library's metric of that name")
+    if len(_segments(name)) > 1 and _segments(name)[-1].lower() == "total":
+        problems.append("ends in 'total', which exporters that need it add themselves")
+    return problems
+
+@rule(
+    rule_id="metric-name-convention",
+    title="Name instruments in lowercase, namespaced with dots",
+    category="conventions",
+    signal="metrics",
+    severity="medium",
+    description="Semantic conventions name metrics like http.server.request.duration: lowercase, a namespace "
+                "per '.', and '_' only between words of one segment. A bare name (\"requests\") collides with "
+                "every other library's, camelCase and '-' turn into different names in every backend, and a "
+                "name the API doesn't accept makes the SDK return an error and a no-op instrument.",
+    bad_example='''
+func newCheckoutInstruments(meter metric.Meter) {
+	meter.Int64Counter("checkoutRequests")
+	meter.Int64UpDownCounter("inflight")
+}''',
+    good_example='''
+func newCheckoutInstrumentsNamespaced(meter metric.Meter) {
+	meter.Int64Counter("checkout.requests")
+	meter.Int64UpDownCounter("checkout.requests.active")
+}''',
+)
+def check_metric_name_convention(source: GoFile) -> Iterator[Diagnostic]:
+    for inst in instruments(source):
+        if inst.name is None:
+            continue
+        problems = name_convention_problems(inst.name)
+        if not problems:
+            continue
+        suggested = conventional_name(inst.name)
+        if "." not in suggested:
+            suggestion = f'Prefix it with the service or library namespace, e.g. "<namespace>.{suggested}"'
+        else:
+            suggestion = f'Name it "{suggested}"'
+        yield Diagnostic(
+            pos=inst.call.args[0].start,
+            end=inst.call.args[0].end,
+            message=f"Instrument name {inst.name!r} " + "; ".join(problems),
+            suggestion=suggestion,
+            confidence=0.8,
+        )
+
+@rule(
+    rule_id="metric-unit-in-name",
+    title="Pass the unit with metric.WithUnit, not in the instrument name",
+    category="conventions",
+    signal="metrics",
+    severity="low",
+    description="The unit is metadata of the instrument: backends use it to scale and label axes, and the "
+                "Prometheus exporter appends it as a suffix, so search.duration_ms with unit \"ms\" is "
+                "exposed as search_duration_ms_milliseconds. A unit in the name that disagrees with "
+                "WithUnit is worse: one of them is wrong.",
+    bad_example='''
+func newLatencyInstruments(meter metric.Meter) {
+	meter.Float64Histogram("search.latency_ms")
+	meter.Int64Counter("upload.size.bytes", metric.WithUnit("KiBy"))
+}''',
+    good_example='''
+func newLatencyInstrumentsWithUnits(meter metric.Meter) {
+	meter.Float64Histogram("search.latency", metric.WithUnit("ms"))
+	meter.Int64Counter("upload.size", metric.WithUnit("KiBy"))
+}''',
+)
+def check_metric_unit_in_name(source: GoFile) -> Iterator[Diagnostic]:
+    for inst in instruments(source):
+        if inst.name is None:
+            continue
+        segments = _segments(inst.name)
+        if len(segments) < 2 or segments[-1].lower() not in NAME_UNIT_SUFFIXES:
+            continue
+        word = segments[-1]
+        unit = NAME_UNIT_SUFFIXES[word.lower()]
+        base = re.sub(r'[._]+(?:in[._]+)?' + re.escape(word) + r'$', "", inst.name)
+        if inst.unit and inst.unit != unit:
+            message = f"Instrument {inst.name!r} says '{word}' in its name but its unit is {inst.unit!r}"
+            suggestion = f'Name it "{base}" and check which of the two is right'
+            confidence = 0.85
+        else:
+            message = f"Instrument {inst.name!r} carries its unit '{word}' in the name"
+            suggestion = f'Name it "{base}"' + ("" if inst.unit else f' with metric.WithUnit("{unit}")')
+            confidence = 0.8
+        yield Diagnostic(
+            pos=inst.call.args[0].start,
+            end=inst.call.args[0].end,
+            message=message,
+            suggestion=suggestion,
+            confidence=confidence,
+        )
@@ -0,0 +1,145 @@
+"""
+Metrics and spans measuring the same thing: counters that count spans, and spans that only time
+an operation. Each signal has a cheaper way to get what the other one records.
+"""
+
+import re
+from typing import Iterator, List, Set
+
+from ..base import Diagnostic
+from ..golang import GoFile, SpanStart
+from ..registry import rule
+from .instruments import assigned_name, instrument_vars, PROMETHEUS
+
+PROMETHEUS_COUNTERS = r'[\w.]+\.(?:NewCounter|NewCounterVec)'
+
+def counter_names(sources: List[GoFile]) -> Set[str]:
+    """Variables and fields holding OpenTelemetry or Prometheus counters"""
+
+    names = instrument_vars(sources, counters_only=True)
+    for source in sources:
+        if source.imports_path(PROMETHEUS):
+            names.update(assigned_name(source, call.start) for call in source.calls(PROMETHEUS_COUNTERS))
+    names.discard("")
+    return names
+
+def _increments(source: GoFile, name: str, start: int, end: int) -> Iterator[int]:
+    """Offsets where counter name is incremented by one"""
+
+    for call in source.calls(r'(?:[\w.]+\.)?' + re.escape(name) + r'\.Add', start, end):
+        if len(call.args) >= 2 and call.args[1].text.strip() in ("1", "1.0"):
+            yield call.start
+    pattern = r'(?<![\w])' + re.escape(name) + r'(?:\.(?:WithLabelValues|With)\s*\([^()]*\))?\.Inc\s*\(\s*\)'
+    for m in re.finditer(pattern, source.masked[start:end]):
+        yield start + m.start()
+
+@rule(
+    rule_id="counter-duplicates-span",
+    title="Don't count spans with a counter",
+    category="performance",
+    signal="metrics",
+    signals=("traces",),
+    severity="low",
+    scope="project",
+    description="A counter incremented once per span counts the same thing the span records. Request, "
+                "error and call counts can be derived from spans with the Collector's spanmetrics "
+                "connector, with the same dimensions and without a second instrument to maintain.",
+    bad_example='''
+func chargeCard(ctx context.Context, amount int64) error {
+	ctx, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+	chargesCounter.Add(ctx, 1)
+	return gateway.Charge(ctx, amount)
+}
+
+var chargesCounter, _ = meter.Int64Counter("payments.charges")''',
+    good_example='''
+func importRows(ctx context.Context, rows []Row) {
+	ctx, span := tracer.Start(ctx, "import rows")
+	defer span.End()
+	rowsCounter.Add(ctx, int64(len(rows)))
+}
+
+var rowsCounter, _ = meter.Int64Counter("imports.rows")''',
+)
+def check_counter_duplicates_span(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    counters = counter_names(sources)
+    if not counters:
+        return
+    for source in sources:
+        for start in source.span_starts:
+            fn = start.func
+            if fn is None:
+                continue
+            for name in sorted(counters):
+                for pos in _increments(source, name, start.call.end, fn.body_end):
+                    # Per-item counts in a loop measure something the span doesn't
+                    if source.enclosing_loop(pos, fn) or source.func_at(pos, include_literals=True) is not fn:
+                        continue
+                    yield Diagnostic(
+                        pos=pos,
+                        message=f"Counter {name} is incremented once per {start.name_arg.text if start.name_arg else ''} "
+                                f"span, duplicating the span count",
+                        suggestion="Drop the counter and derive the count from spans with the spanmetrics connector "
+                                   "in the Collector",
+                        confidence=0.6,
+                        file=source,
+                    )
+
+def _only_times(source: GoFile, start: SpanStart) -> bool:
+    """Whether the span records nothing but its duration and parents nothing"""
+
+    fn = start.func
+    if fn is None or not start.span_var or start.span_var == "_":
+        return False
+    after = source.masked[start.call.end:fn.body_end]
+    uses = re.findall(r'(?<![\w.])' + re.escape(start.span_var) + r'\b(\.\w+)?', after)
+    if any(method != ".End" for method in uses):
+        return False
+    if start.ctx_var and start.ctx_var != "_" and re.search(r'(?<![\w.])' + re.escape(start.ctx_var) + r'\b', after):
+        return False
+    options = " ".join(a.text for a in start.call.args[2:])
+    return not re.search(r'WithAttributes|WithLinks|WithNewRoot', options)
+
+@rule(
+    rule_id="span-only-for-duration",
+    title="Use a histogram to time operations that need no span",
+    category="performance",
+    signal="traces",
+    signals=("metrics",),
+    severity="low",
+    description="A span that records no attributes, events or status and has no child spans only measures "
+                "how long something took. A duration histogram gives the same number aggregated, at a "
+                "fraction of the cost of exporting and storing a span per call.",
+    bad_example='''
+func compressPayload(ctx context.Context, data []byte) []byte {
+	_, span := tracer.Start(ctx, "compress payload")
+	defer span.End()
+	return gzipBytes(data)
+}''',
+    good_example='''
+func compressPayloadTimed(ctx context.Context, data []byte) []byte {
+	start := time.Now()
+	defer func() { compressDuration.Record(ctx, time.Since(start).Seconds()) }()
+	return gzipBytes(data)
+}
+
+func fetchProfile(ctx context.Context, id string) (*Profile, error) {
+	ctx, span := tracer.Start(ctx, "fetch profile")
+	defer span.End()
+	return profiles.Get(ctx, id)
+}''',
+)
+def check_span_only_for_duration(source: GoFile) -> Iterator[Diagnostic]:
+    for start in source.span_starts:
+        if not _only_times(source, start):
+            continue
+        name = start.name_arg.text if start.name_arg else "the span"
+        yield Diagnostic(
+            pos=start.call.start,
+            end=start.call.end,
+            message=f"Span {name} records nothing but its duration and has no children",
+            suggestion="Record the duration in a Float64Histogram with unit \"s\" instead, or add the attributes "
+                       "and child spans that make the span worth keeping",
+            confidence=0.5,
+        )
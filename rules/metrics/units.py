@@ -0,0 +1,130 @@
+"""
+Instrument units: UCUM case-sensitive codes, as the semantic conventions and exporters expect
+"""
+
+import re
+from typing import Iterator, Optional, Tuple
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..golang import GoFile
+from ..registry import rule
+from .instruments import Instrument, instruments
+
+# Units that take SI prefixes (ms, kBy, MHz)
+METRIC_ATOMS = {
+    "s", "m", "g", "l", "L", "By", "bit", "Hz", "V", "A", "W", "J", "N", "Pa", "K", "mol", "cd",
+    "Ohm", "C", "F", "T", "Wb", "S", "rad", "sr", "lm", "lx", "Bq", "Gy", "Sv", "t", "eV", "B",
+}
+OTHER_ATOMS = {"min", "h", "d", "wk", "mo", "a", "%", "deg", "Cel", "1"}
+DECIMAL_PREFIXES = ("da", "Y", "Z", "E", "P", "T", "G", "M", "k", "h", "d", "c", "m", "u", "n", "p", "f", "a", "z", "y")
+BINARY_PREFIXES = ("Ki", "Mi", "Gi", "Ti", "Pi", "Ei")
+
+# Spellings people use -> the UCUM code (keys lowercase)
+UNIT_SPELLINGS = {
+    "sec": "s", "secs": "s", "second": "s", "seconds": "s",
+    "msec": "ms", "msecs": "ms", "millis": "ms", "millisecond": "ms", "milliseconds": "ms",
+    "usec": "us", "µs": "us", "μs": "us", "micros": "us", "microsecond": "us", "microseconds": "us",
+    "nsec": "ns", "nanos": "ns", "nanosecond": "ns", "nanoseconds": "ns",
+    "mins": "min", "minute": "min", "minutes": "min", "hr": "h", "hrs": "h", "hour": "h", "hours": "h",
+    "day": "d", "days": "d",
+    "byte": "By", "bytes": "By", "kb": "kBy", "kby": "kBy", "kib": "KiBy", "mb": "MBy", "mib": "MiBy", "gb": "GBy", "gib": "GiBy",
+    "bits": "bit", "percent": "%", "pct": "%", "percentage": "%", "ratio": "1",
+    "celsius": "Cel", "hertz": "Hz", "volts": "V", "watts": "W", "meters": "m",
+}
+
+def _valid_term(term: str) -> bool:
+    m = re.fullmatch(r'([A-Za-z%]+|1)([+-]?\d+)?', term)
+    if not m:
+        return False
+    atom = m.group(1)
+    if atom in METRIC_ATOMS or atom in OTHER_ATOMS:
+        # B is the bel; nobody measures that in a metric
+        return atom != "B"
+    if any(atom.startswith(p) and atom[len(p):] in METRIC_ATOMS - {"B"} for p in DECIMAL_PREFIXES):
+        return True
+    return any(atom.startswith(p) and atom[len(p):] in ("By", "bit") for p in BINARY_PREFIXES)
+
+def _fixed_term(term: str) -> Optional[str]:
+    if term == "B":
+        return "By"
+    if term.lower() in UNIT_SPELLINGS:
+        return UNIT_SPELLINGS[term.lower()]
+    if re.fullmatch(r'[A-Za-z_]+', term):
+        # A count of things: an annotation, in the singular like the semantic conventions
+        word = term.lower()
+        return "{" + (word[:-1] if len(word) > 3 and word.endswith("s") and not word.endswith("ss") else word) + "}"
+    return None
+
+def unit_problem(unit: str) -> Optional[Tuple[str, Optional[str]]]:
+    """(offending terms, corrected unit or None) when unit isn't a valid UCUM code"""
+
+    if "(" in unit or "[" in unit:
+        return None
+    terms = re.split(r'([./])', unit)
+    bad, fixed = [], []
+    for term in terms:
+        bare = re.sub(r'\{[^{}]*\}', "", term)
+        if term in (".", "/") or (not bare and term) or (term == "" and len(terms) > 1) or _valid_term(bare):
+            fixed.append(term)
+            continue
+        bad.append(term)
+        replacement = _fixed_term(bare) if bare == term else None
+        if replacement is None:
+            return ", ".join(repr(t) for t in bad), None
+        fixed.append(replacement)
+    if not bad:
+        return None
+    return ", ".join(repr(t) for t in bad), "".join(fixed)
+
+def _unit_literal(inst: Instrument) -> Tuple[int, int]:
+    """Offsets of the string literal passed to WithUnit"""
+
+    for arg in inst.call.args[1:]:
+        m = re.fullmatch(r'\s*[\w.]*WithUnit\s*\(\s*("[^"]*")\s*\)\s*', arg.text)
+        if m:
+            return arg.start + m.start(1), arg.start + m.end(1)
+    return inst.call.start, inst.call.end
+
+@rule(
+    rule_id="metric-unit-invalid",
+    title="Use UCUM codes for instrument units",
+    category="conventions",
+    signal="metrics",
+    severity="medium",
+    autofix=True,
+    description="Units are UCUM case-sensitive codes: s, ms, By, KiBy, 1 for ratios and annotations in braces "
+                "for counts of things ({request}). Backends and the Prometheus exporter only understand those: "
+                "\"seconds\" or \"MB\" is shown verbatim and never converted, \"B\" is the bel, and a plural word "
+                "such as \"requests\" becomes a unit instead of a description.",
+    bad_example='''
+func newTransferInstruments(meter metric.Meter) {
+	meter.Float64Histogram("transfer.duration", metric.WithUnit("seconds"))
+	meter.Int64Counter("transfer.size", metric.WithUnit("MB"))
+	meter.Int64Counter("transfer.requests", metric.WithUnit("requests"))
+}''',
+    good_example='''
+func newTransferInstrumentsUCUM(meter metric.Meter) {
+	meter.Float64Histogram("transfer.duration", metric.WithUnit("s"))
+	meter.Int64Counter("transfer.size", metric.WithUnit("MBy"))
+	meter.Int64Counter("transfer.requests", metric.WithUnit("{request}"))
+}''',
+)
+def check_metric_unit_invalid(source: GoFile) -> Iterator[Diagnostic]:
+    for inst in instruments(source):
+        if not inst.unit:
+            continue
+        problem = unit_problem(inst.unit)
+        if problem is None:
+            continue
+        terms, fixed = problem
+        pos, end = _unit_literal(inst)
+        yield Diagnostic(
+            pos=pos,
+            end=end,
+            message=f"Unit {inst.unit!r} of instrument {inst.name or inst.call.args[0].text.strip()} isn't UCUM "
+                    f"({terms})",
+            suggestion=f'Use metric.WithUnit("{fixed}")' if fixed else
+                       "Use a UCUM code (s, ms, By, 1, {thing} for counts)",
+            confidence=0.85,
+            fix=Fix(description=f'Use unit "{fixed}"', edits=[TextEdit(pos, end, f'"{fixed}"')]) if fixed else None,
+        )
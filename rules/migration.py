@@ -0,0 +1,315 @@
+"""
+OpenCensus/OpenTracing -> OpenTelemetry migration mapping, report and mechanical rewrites
+"""
+
+import re
+from dataclasses import dataclass
+from typing import List, Optional, Dict, Tuple
+
+from .conventions import attribute_key_problems
+from .golang import GoFile
+
+OC_TRACE = "go.opencensus.io/trace"
+OC_STATS = "go.opencensus.io/stats"
+OC_TAG = "go.opencensus.io/tag"
+OC_PLUGINS = "go.opencensus.io/plugin"
+OC_BRIDGE = "go.opentelemetry.io/otel/bridge/opencensus"
+OT = "github.com/opentracing/opentracing-go"
+OT_EXT = OT + "/ext"
+OT_LOG = OT + "/log"
+OT_BRIDGE = "go.opentelemetry.io/otel/bridge/opentracing"
+
+@dataclass
+class Mapping:
+    legacy_call: str
+    otel_equivalent: str
+    # Replacement for the callee when the rewrite is purely mechanical
+    rewrite: Optional[str] = None
+
+# Keyed by (import path, function) for package functions
+OC_FUNCTIONS: Dict[Tuple[str, str], Mapping] = {
+    (OC_TRACE, "StartSpan"): Mapping("trace.StartSpan", "tracer.Start (otel.Tracer)", "tracer.Start"),
+    (OC_TRACE, "StartSpanWithRemoteParent"): Mapping("trace.StartSpanWithRemoteParent", "tracer.Start on a context from propagator.Extract"),
+    (OC_TRACE, "FromContext"): Mapping("trace.FromContext", "trace.SpanFromContext", "trace.SpanFromContext"),
+    (OC_TRACE, "NewContext"): Mapping("trace.NewContext", "trace.ContextWithSpan", "trace.ContextWithSpan"),
+    (OC_TRACE, "StringAttribute"): Mapping("trace.StringAttribute", "attribute.String", "attribute.String"),
+    (OC_TRACE, "Int64Attribute"): Mapping("trace.Int64Attribute", "attribute.Int64", "attribute.Int64"),
+    (OC_TRACE, "Float64Attribute"): Mapping("trace.Float64Attribute", "attribute.Float64", "attribute.Float64"),
+    (OC_TRACE, "BoolAttribute"): Mapping("trace.BoolAttribute", "attribute.Bool", "attribute.Bool"),
+    (OC_TRACE, "WithSpanKind"): Mapping("trace.WithSpanKind", "trace.WithSpanKind(trace.SpanKind...)"),
+    (OC_TRACE, "WithSampler"): Mapping("trace.WithSampler", "sdktrace.WithSampler on the TracerProvider"),
+    (OC_TRACE, "ApplyConfig"): Mapping("trace.ApplyConfig", "sdktrace.NewTracerProvider options"),
+    (OC_TRACE, "RegisterExporter"): Mapping("trace.RegisterExporter", "sdktrace.WithBatcher(otlptracegrpc exporter)"),
+    (OC_STATS, "Int64"): Mapping("stats.Int64", "meter.Int64Counter / meter.Int64Histogram"),
+    (OC_STATS, "Float64"): Mapping("stats.Float64", "meter.Float64Counter / meter.Float64Histogram"),
+    (OC_STATS, "Record"): Mapping("stats.Record", "instrument.Add / instrument.Record with metric.WithAttributes"),
+    (OC_STATS, "RecordWithTags"): Mapping("stats.RecordWithTags", "instrument.Record with metric.WithAttributes"),
+    (OC_STATS + "/view", "Register"): Mapping("view.Register", "sdkmetric.WithView on the MeterProvider"),
+    (OC_TAG, "New"): Mapping("tag.New", "attribute sets passed to metric.WithAttributes (or baggage for propagation)"),
+    (OC_TAG, "NewKey"): Mapping("tag.NewKey", "attribute.Key"),
+    (OC_TAG, "MustNewKey"): Mapping("tag.MustNewKey", "attribute.Key"),
+    (OC_TAG, "Upsert"): Mapping("tag.Upsert", "attribute.KeyValue in the instrument's attribute set"),
+    (OC_TAG, "Insert"): Mapping("tag.Insert", "attribute.KeyValue in the instrument's attribute set"),
+    (OC_PLUGINS + "/ochttp", "Handler"): Mapping("ochttp.Handler", "otelhttp.NewHandler"),
+    (OC_PLUGINS + "/ochttp", "Transport"): Mapping("ochttp.Transport", "otelhttp.NewTransport"),
+    (OC_PLUGINS + "/ocgrpc", "ServerHandler"): Mapping("ocgrpc.ServerHandler", "grpc.StatsHandler(otelgrpc.NewServerHandler())"),
+    (OC_PLUGINS + "/ocgrpc", "ClientHandler"): Mapping("ocgrpc.ClientHandler", "grpc.WithStatsHandler(otelgrpc.NewClientHandler())"),
+    (OC_BRIDGE, "InstallTraceBridge"): Mapping("opencensus.InstallTraceBridge", "remove once all OpenCensus call sites are migrated"),
+    (OC_BRIDGE, "NewMetricProducer"): Mapping("opencensus.NewMetricProducer", "remove once all OpenCensus metrics are migrated"),
+}
+
+# Methods on an OpenCensus *trace.Span
+OC_SPAN_METHODS: Dict[str, Mapping] = {
+    "AddAttributes": Mapping("span.AddAttributes", "span.SetAttributes", "SetAttributes"),
+    "Annotate": Mapping("span.Annotate", "span.AddEvent(msg, trace.WithAttributes(...))"),
+    "Annotatef": Mapping("span.Annotatef", "span.AddEvent with a constant name and attributes"),
+    "SetStatus": Mapping("span.SetStatus(trace.Status{...})", "span.SetStatus(codes.Error, description)"),
+    "AddLink": Mapping("span.AddLink", "span.AddLink(trace.Link{SpanContext: ...})"),
+    "IsRecordingEvents": Mapping("span.IsRecordingEvents", "span.IsRecording", "IsRecording"),
+}
+
+OT_FUNCTIONS: Dict[Tuple[str, str], Mapping] = {
+    (OT, "GlobalTracer"): Mapping("opentracing.GlobalTracer", "otel.Tracer(name)"),
+    (OT, "SetGlobalTracer"): Mapping("opentracing.SetGlobalTracer", "otel.SetTracerProvider (or the OpenTracing bridge during migration)"),
+    (OT, "InitGlobalTracer"): Mapping("opentracing.InitGlobalTracer", "otel.SetTracerProvider (or the OpenTracing bridge during migration)"),
+    (OT, "StartSpanFromContext"): Mapping("opentracing.StartSpanFromContext", "tracer.Start(ctx, name) (note the ctx, span return order)"),
+    (OT, "StartSpan"): Mapping("opentracing.StartSpan", "tracer.Start(ctx, name)"),
+    (OT, "SpanFromContext"): Mapping("opentracing.SpanFromContext", "trace.SpanFromContext"),
+    (OT, "ContextWithSpan"): Mapping("opentracing.ContextWithSpan", "trace.ContextWithSpan"),
+    (OT, "ChildOf"): Mapping("opentracing.ChildOf", "parent taken from ctx in tracer.Start"),
+    (OT, "FollowsFrom"): Mapping("opentracing.FollowsFrom", "trace.WithLinks(trace.LinkFromContext(ctx))"),
+    (OT_LOG, "String"): Mapping("log.String", "attribute.String on span.AddEvent"),
+    (OT_LOG, "Error"): Mapping("log.Error", "span.RecordError"),
+}
+
+# Methods on an opentracing.Span
+OT_SPAN_METHODS: Dict[str, Mapping] = {
+    "SetTag": Mapping("span.SetTag", "span.SetAttributes(attribute.X(key, value))"),
+    "LogFields": Mapping("span.LogFields", "span.AddEvent(name, trace.WithAttributes(...))"),
+    "LogKV": Mapping("span.LogKV", "span.AddEvent(name, trace.WithAttributes(...))"),
+    "SetOperationName": Mapping("span.SetOperationName", "span.SetName"),
+    "SetBaggageItem": Mapping("span.SetBaggageItem", "baggage.NewMember + baggage.ContextWithBaggage"),
+    "BaggageItem": Mapping("span.BaggageItem", "baggage.FromContext(ctx).Member(key)"),
+    "Finish": Mapping("span.Finish", "span.End"),
+}
+
+# OpenTracing semantic tags -> current OpenTelemetry semantic convention keys
+OT_TAG_KEYS: Dict[str, str] = {
+    "component": "otel.scope.name (set by the tracer name)",
+    "span.kind": "trace.WithSpanKind",
+    "error": "span.SetStatus(codes.Error, ...)",
+    "http.method": "http.request.method",
+    "http.url": "url.full",
+    "http.status_code": "http.response.status_code",
+    "db.type": "db.system",
+    "db.instance": "db.namespace",
+    "db.statement": "db.query.text",
+    "db.user": "(removed from semconv; drop it)",
+    "peer.service": "peer.service",
+    "peer.hostname": "server.address",
+    "peer.address": "server.address",
+    "peer.ipv4": "network.peer.address",
+    "peer.ipv6": "network.peer.address",
+    "peer.port": "server.port",
+    "message_bus.destination": "messaging.destination.name",
+    "sampling.priority": "a sampler decision (no attribute equivalent)",
+}
+
+# opentracing-go/ext tag constants -> the key they set
+OT_EXT_TAGS: Dict[str, str] = {
+    "Component": "component",
+    "SpanKind": "span.kind",
+    "Error": "error",
+    "HTTPMethod": "http.method",
+    "HTTPUrl": "http.url",
+    "HTTPStatusCode": "http.status_code",
+    "DBType": "db.type",
+    "DBInstance": "db.instance",
+    "DBStatement": "db.statement",
+    "DBUser": "db.user",
+    "PeerService": "peer.service",
+    "PeerHostname": "peer.hostname",
+    "PeerHostIPv4": "peer.ipv4",
+    "PeerHostIPv6": "peer.ipv6",
+    "PeerPort": "peer.port",
+    "MessageBusDestination": "message_bus.destination",
+    "SamplingPriority": "sampling.priority",
+}
+
+@dataclass
+class CallSite:
+    source: GoFile
+    pos: int
+    end: int
+    callee_end: int
+    mapping: Mapping
+
+    @property
+    def line(self) -> int:
+        return self.source.line_of(self.pos)
+
+def opencensus_call_sites(source: GoFile) -> List[CallSite]:
+    """Every OpenCensus call in the file that has a known OpenTelemetry equivalent"""
+
+    sites = []
+    for (path, func), mapping in OC_FUNCTIONS.items():
+        for alias in [n for n, p in source.imports.items() if p == path]:
+            for call in source.calls(re.escape(alias) + r'\.' + func):
+                sites.append(CallSite(source, call.start, call.end, call.open_paren, mapping))
+
+    for span_var, fn in oc_span_vars(source):
+        start, end = (fn.body_start, fn.body_end) if fn else (0, None)
+        for method, mapping in OC_SPAN_METHODS.items():
+            for call in source.calls(re.escape(span_var) + r'\.' + method, start, end):
+                sites.append(CallSite(source, call.start, call.end, call.open_paren, mapping))
+    return sorted(sites, key=lambda s: s.pos)
+
+def oc_span_vars(source: GoFile) -> List[tuple]:
+    """(variable, enclosing function) pairs holding OpenCensus spans"""
+
+    found = {}
+    for alias in [n for n, p in source.imports.items() if p == OC_TRACE]:
+        patterns = [
+            (r'(\w+)\s*,\s*(\w+)\s*:?=\s*' + re.escape(alias) + r'\.StartSpan', 2),
+            (r'(\w+)\s*:?=\s*' + re.escape(alias) + r'\.FromContext', 1),
+        ]
+        for pattern, group in patterns:
+            for m in re.finditer(pattern, source.masked):
+                fn = source.func_at(m.start(), include_literals=True)
+                if m.group(group) != "_":
+                    found[(m.group(group), fn.start if fn else -1)] = fn
+    return [(name, fn) for (name, _), fn in sorted(found.items(), key=lambda kv: kv[0])]
+
+def opentracing_call_sites(source: GoFile) -> List[CallSite]:
+    """Every opentracing-go call in the file, including span methods and ext tag setters"""
+
+    sites = []
+    for (path, func), mapping in OT_FUNCTIONS.items():
+        for alias in [n for n, p in source.imports.items() if p == path]:
+            for call in source.calls(re.escape(alias) + r'\.' + func):
+                sites.append(CallSite(source, call.start, call.end, call.open_paren, mapping))
+
+    for alias in [n for n, p in source.imports.items() if p == OT_EXT]:
+        for call in source.calls(re.escape(alias) + r'\.(\w+)\.Set'):
+            const = call.name.split(".")[1]
+            key = OT_EXT_TAGS.get(const, const)
+            sites.append(CallSite(source, call.start, call.end, call.open_paren,
+                                  Mapping(f"ext.{const}.Set", ot_tag_equivalent(key))))
+
+    for span_var, fn in ot_span_vars(source):
+        start, end = (fn.body_start, fn.body_end) if fn else (0, None)
+        for method, mapping in OT_SPAN_METHODS.items():
+            for call in source.calls(re.escape(span_var) + r'\.' + method, start, end):
+                if method == "SetTag" and call.args:
+                    key = call.args[0].literal
+                    if key is None:
+                        m = re.fullmatch(r'(?:string\()?\w+\.(\w+)\)?', call.args[0].text.strip())
+                        key = OT_EXT_TAGS.get(m.group(1)) if m else None
+                    if key is not None:
+                        mapping = Mapping(f'span.SetTag("{key}")', ot_tag_equivalent(key))
+                sites.append(CallSite(source, call.start, call.end, call.open_paren, mapping))
+    return sorted(sites, key=lambda s: s.pos)
+
+def ot_tag_equivalent(key: str) -> str:
+    """OpenTelemetry replacement for an OpenTracing tag, checked against the attribute key rules"""
+
+    if key in OT_TAG_KEYS:
+        target = OT_TAG_KEYS[key]
+        if "." in target and " " not in target:
+            return f'span.SetAttributes with key "{target}"'
+        return target
+    problems = attribute_key_problems(key)
+    if problems:
+        return f'span.SetAttributes; key "{key}" {" and ".join(problems)}'
+    return f'span.SetAttributes with key "{key}"'
+
+def ot_span_vars(source: GoFile) -> List[tuple]:
+    """(variable, enclosing function) pairs holding OpenTracing spans"""
+
+    found = {}
+    aliases = [n for n, p in source.imports.items() if p == OT]
+    tracers = set()
+    for alias in aliases:
+        for m in re.finditer(r'(\w+)\s*:?=\s*' + re.escape(alias) + r'\.GlobalTracer\(\)', source.masked):
+            tracers.add(m.group(1))
+        for m in re.finditer(r'(\w+)\s+' + re.escape(alias) + r'\.Tracer\b', source.masked):
+            tracers.add(m.group(1))
+    starters = [(re.escape(a) + r'\.StartSpanFromContext', 1) for a in aliases]
+    starters += [(re.escape(a) + r'\.(?:StartSpan|SpanFromContext)', 1) for a in aliases]
+    starters += [(re.escape(a) + r'\.GlobalTracer\(\)\.StartSpan', 1) for a in aliases]
+    starters += [(re.escape(t) + r'\.StartSpan', 1) for t in sorted(tracers)]
+    for callee, group in starters:
+        # StartSpanFromContext returns (span, ctx), the reverse of tracer.Start
+        for m in re.finditer(r'(\w+)(?:\s*,\s*\w+)?\s*:?=\s*' + callee + r'\s*\(', source.masked):
+            fn = source.func_at(m.start(), include_literals=True)
+            if m.group(group) != "_":
+                found[(m.group(group), fn.start if fn else -1)] = fn
+    for alias in aliases:
+        for m in re.finditer(r'(\w+)\s+' + re.escape(alias) + r'\.Span\b', source.masked):
+            fn = source.func_at(m.start(), include_literals=True)
+            found[(m.group(1), fn.start if fn else -1)] = fn
+    return [(name, fn) for (name, _), fn in sorted(found.items(), key=lambda kv: kv[0])]
+
+def rewrite_opencensus(source: GoFile) -> Optional[str]:
+    """Rewrite a file whose OpenCensus usage is trace-only and fully mechanical.
+    Returns None when any call site needs a human decision."""
+
+    oc_paths = [p for p in source.imports.values() if p.startswith("go.opencensus.io")]
+    if oc_paths != [OC_TRACE]:
+        return None
+    sites = opencensus_call_sites(source)
+    if not sites or any(site.mapping.rewrite is None for site in sites):
+        return None
+    # SpanKind/Status/Link/sampler types have no one-to-one rewrite
+    oc_alias = [n for n, p in source.imports.items() if p == OC_TRACE][0]
+    leftovers = re.findall(re.escape(oc_alias) + r'\.(\w+)', source.masked)
+    mechanical_funcs = {func for (path, func), m in OC_FUNCTIONS.items() if path == OC_TRACE and m.rewrite}
+    if any(name not in mechanical_funcs and name != "Span" for name in leftovers):
+        return None
+
+    code = source.code
+    for site in sorted(sites, key=lambda s: s.pos, reverse=True):
+        callee = code[site.pos:site.callee_end]
+        rewrite = site.mapping.rewrite
+        if "." not in rewrite:
+            # Method rename on the span variable
+            rewrite = callee.rsplit(".", 1)[0] + "." + rewrite
+        code = code[:site.pos] + rewrite + code[site.callee_end:]
+
+    code = re.sub(r'\*' + re.escape(oc_alias) + r'\.Span\b', "trace.Span", code)
+
+    new_imports = ['"go.opentelemetry.io/otel/trace"']
+    if "attribute." in code:
+        new_imports.append('"go.opentelemetry.io/otel/attribute"')
+    needs_tracer = "tracer.Start" in code and not re.search(r'\btracer\s*(?::=|=)', code)
+    if needs_tracer:
+        new_imports.insert(0, '"go.opentelemetry.io/otel"')
+    new_imports.sort()
+    spec = re.compile(r'^([ \t]*)(?:' + re.escape(oc_alias) + r'\s+)?"' + re.escape(OC_TRACE) + r'"\s*$', re.M)
+    code = spec.sub(lambda m: "\n".join(m.group(1) + imp for imp in new_imports), code, count=1)
+
+    if needs_tracer:
+        rewritten = GoFile(source.path, code)
+        pos = rewritten.decl_insert_pos()
+        package = rewritten.package or "app"
+        code = code[:pos] + f'\n\nvar tracer = otel.Tracer("{package}")' + code[pos:]
+    return code
+
+def migration_report(sources: List[GoFile]) -> List[Dict]:
+    """One entry per OpenCensus/OpenTracing call site, mapping it to the OpenTelemetry equivalent"""
+
+    report = []
+    for source in sources:
+        rewritable = rewrite_opencensus(source) is not None
+        ot_sites = opentracing_call_sites(source)
+        for site in opencensus_call_sites(source) + ot_sites:
+            report.append({
+                "file": source.path,
+                "line": site.line,
+                "function": source.function_name_at(site.pos),
+                "legacy": site.mapping.legacy_call,
+                "opentelemetry": site.mapping.otel_equivalent,
+                "code": source.lines[site.line - 1].strip(),
+                "automated": rewritable and site not in ot_sites,
+            })
+    return report
@@ -0,0 +1,73 @@
+"""
+Monorepo support: find every Go module in a tree and analyze each one with its own
+configuration, so a module's findings and score don't depend on its neighbours.
+"""
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional
+
+from .base import TelemetryViolation
+from .config import load_config
+from .context import Context
+from .engine import RuleEngine, ProgressCallback
+from .score import quality_score
+
+# Directories that hold no modules of their own worth analyzing
+SKIP_DIRS = {"vendor", "testdata", "node_modules", "third_party"}
+
+@dataclass
+class Module:
+    path: str  # module path from go.mod
+    directory: Path
+
+@dataclass
+class ModuleReport:
+    module: Module
+    results: Dict[str, List[TelemetryViolation]]
+    score: Dict
+    incomplete: Dict[str, str] = field(default_factory=dict)
+
+def _skipped(path: Path, root: Path) -> bool:
+    parts = path.relative_to(root).parts
+    return any(p in SKIP_DIRS or (p.startswith(".") and p not in (".", "..")) for p in parts)
+
+def discover_modules(root: str) -> List[Module]:
+    """Modules under root (root itself included), outermost first"""
+
+    root_path = Path(root).resolve()
+    modules = []
+    for go_mod in sorted(root_path.rglob("go.mod")):
+        if _skipped(go_mod.parent, root_path):
+            continue
+        m = re.search(r'^module\s+"?([^\s"]+)"?', go_mod.read_text(encoding="utf-8"), re.M)
+        modules.append(Module(m.group(1) if m else go_mod.parent.name, go_mod.parent))
+    return modules
+
+def module_files(module: Module, modules: List[Module]) -> List[Path]:
+    """Go files of module, leaving out nested modules, vendored code and configured excludes"""
+
+    nested = [m.directory for m in modules if m.directory != module.directory
+              and module.directory in m.directory.parents]
+    config = load_config(str(module.directory))
+    return sorted(
+        f for f in module.directory.rglob("*.go")
+        if not _skipped(f.parent, module.directory)
+        and not any(n == f.parent or n in f.parents for n in nested)
+        and not config.is_excluded(str(f))
+    )
+
+def analyze_module(module: Module, modules: List[Module], progress: Optional[ProgressCallback] = None,
+                   ctx: Optional[Context] = None, package_timeout: Optional[float] = None) -> ModuleReport:
+    """Findings and score for one module, using the .ollygarden.yaml nearest to it"""
+
+    engine = RuleEngine.from_config(load_config(str(module.directory)))
+    files = module_files(module, modules)
+    results = engine.analyze_files([str(f) for f in files], progress, ctx, package_timeout)
+    lines = sum(len(f.read_text(encoding="utf-8").splitlines()) for f in files)
+    return ModuleReport(module, results, quality_score(results, lines), dict(engine.incomplete))
+
+def overall_score(reports: List[ModuleReport]) -> Dict:
+    results = {path: v for r in reports for path, v in r.results.items()}
+    return quality_score(results, sum(r.score["lines"] for r in reports))
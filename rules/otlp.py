@@ -0,0 +1,124 @@
+"""
+Export findings to an observability backend over OTLP/HTTP (JSON encoding), so instrumentation
+quality can be queried next to the telemetry it describes. Each finding becomes a log record;
+counts per severity and category, and the quality score when known, become gauges.
+"""
+
+import json
+import os
+import time
+import urllib.parse
+import urllib.request
+from collections import Counter
+from typing import Dict, List, Optional
+
+from .base import TelemetryViolation
+
+DEFAULT_ENDPOINT = "http://localhost:4318"
+SCOPE = {"name": "ollygarden", "version": "1"}
+
+# OTLP SeverityNumber for each finding severity
+SEVERITY_NUMBERS = {"critical": 20, "high": 17, "medium": 13, "low": 9}
+
+def _value(value) -> Dict:
+    if isinstance(value, bool):
+        return {"boolValue": value}
+    if isinstance(value, int):
+        return {"intValue": str(value)}
+    if isinstance(value, float):
+        return {"doubleValue": value}
+    return {"stringValue": str(value)}
+
+def _attributes(values: Dict) -> List[Dict]:
+    return [{"key": k, "value": _value(v)} for k, v in values.items() if v not in (None, "")]
+
+def resource_attributes(root: str) -> Dict:
+    """service.name and OTEL_RESOURCE_ATTRIBUTES as the SDKs read them (values percent-decoded),
+    plus the scanned root"""
+
+    attributes = {}
+    for pair in os.environ.get("OTEL_RESOURCE_ATTRIBUTES", "").split(","):
+        if "=" in pair:
+            key, value = pair.split("=", 1)
+            attributes[key.strip()] = urllib.parse.unquote(value.strip())
+    attributes["service.name"] = os.environ.get("OTEL_SERVICE_NAME") or attributes.get("service.name") or "ollygarden"
+    attributes["ollygarden.scan.root"] = os.path.abspath(root)
+    return attributes
+
+def log_records(results: Dict[str, List[TelemetryViolation]], now_ns: int) -> List[Dict]:
+    records = []
+    for path in sorted(results):
+        for v in results[path]:
+            records.append({
+                "timeUnixNano": str(now_ns),
+                "observedTimeUnixNano": str(now_ns),
+                "severityNumber": SEVERITY_NUMBERS.get(v.severity, 9),
+                "severityText": v.severity.upper(),
+                "body": {"stringValue": v.description},
+                "attributes": _attributes({
+                    "ollygarden.rule_id": v.rule_id or v.rule_violated,
+                    "ollygarden.category": v.violation_type,
+                    "ollygarden.severity": v.severity,
+                    "ollygarden.confidence": float(v.confidence),
+                    "ollygarden.detection_method": v.detection_method,
+                    "ollygarden.fix_suggestion": v.fix_suggestion,
+                    "code.file.path": v.file_path,
+                    "code.line.number": v.location.line_number,
+                    "code.column.number": v.location.column,
+                    "code.function.name": v.location.function_name,
+                }),
+            })
+    return records
+
+def _gauge(name: str, unit: str, description: str, points: List[Dict]) -> Dict:
+    return {"name": name, "unit": unit, "description": description, "gauge": {"dataPoints": points}}
+
+def metrics(results: Dict[str, List[TelemetryViolation]], now_ns: int, score: Optional[Dict] = None) -> List[Dict]:
+    counts = Counter((v.severity, v.violation_type) for violations in results.values() for v in violations)
+    points = [
+        {"timeUnixNano": str(now_ns), "asInt": str(n),
+         "attributes": _attributes({"ollygarden.severity": severity, "ollygarden.category": category})}
+        for (severity, category), n in sorted(counts.items())
+    ]
+    found = [_gauge("ollygarden.findings", "{finding}", "Instrumentation findings in the last analysis", points),
+             _gauge("ollygarden.files", "{file}", "Files analyzed",
+                    [{"timeUnixNano": str(now_ns), "asInt": str(len(results))}])]
+    if score:
+        score_points = [{"timeUnixNano": str(now_ns), "asDouble": float(score["score"])}]
+        score_points += [
+            {"timeUnixNano": str(now_ns), "asDouble": float(sub["score"]),
+             "attributes": _attributes({"ollygarden.category": category})}
+            for category, sub in score["categories"].items()
+        ]
+        found.append(_gauge("ollygarden.score", "1", "Instrumentation quality score (0-100)", score_points))
+    return found
+
+def _headers() -> Dict[str, str]:
+    headers = {"Content-Type": "application/json"}
+    for pair in os.environ.get("OTEL_EXPORTER_OTLP_HEADERS", "").split(","):
+        if "=" in pair:
+            key, value = pair.split("=", 1)
+            headers[key.strip()] = urllib.parse.unquote(value.strip())
+    return headers
+
+def _post(url: str, payload: Dict, timeout: float):
+    request = urllib.request.Request(url, data=json.dumps(payload).encode("utf-8"), headers=_headers(), method="POST")
+    with urllib.request.urlopen(request, timeout=timeout) as response:
+        response.read()
+
+def export(results: Dict[str, List[TelemetryViolation]], root: str, endpoint: Optional[str] = None,
+           score: Optional[Dict] = None, timeout: float = 10.0) -> int:
+    """POST findings to {endpoint}/v1/logs and counts to {endpoint}/v1/metrics; returns the
+    number of log records sent. The endpoint defaults to OTEL_EXPORTER_OTLP_ENDPOINT."""
+
+    endpoint = (endpoint or os.environ.get("OTEL_EXPORTER_OTLP_ENDPOINT") or DEFAULT_ENDPOINT).rstrip("/")
+    now_ns = time.time_ns()
+    resource = {"attributes": _attributes(resource_attributes(root))}
+    records = log_records(results, now_ns)
+    _post(endpoint + "/v1/logs", {
+        "resourceLogs": [{"resource": resource, "scopeLogs": [{"scope": SCOPE, "logRecords": records}]}]
+    }, timeout)
+    _post(endpoint + "/v1/metrics", {
+        "resourceMetrics": [{"resource": resource, "scopeMetrics": [{"scope": SCOPE, "metrics": metrics(results, now_ns, score)}]}]
+    }, timeout)
+    return len(records)
@@ -0,0 +1,303 @@
+"""
+Instrumentation overhead: Go micro-benchmarks that replay the span sites of the most called
+instrumented functions (by call-graph fan-in) without instrumentation, with a noop tracer and with
+the SDK sampling and dropping, so the cost of the spans on hot paths is measured instead of
+guessed.
+
+A site is replayed by its shape: its name, the attributes it records at start and later by type,
+its events and recorded errors. Values are stand-ins, so the numbers are the instrumentation's own
+cost per span, what removing, sampling or slimming the span saves; the service's work isn't in
+them.
+"""
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Tuple
+
+from .budget import Budget, loop_multiplier
+from .golang import GoFile, SpanStart
+from .scaffold import go_mod_requires
+from .traces.attributes import attribute_calls
+
+# Used when the analyzed code doesn't pin the API and SDK itself
+OTEL_VERSION = "v1.45.0"
+# Stand-in values of each constructor, a typical string being about this long
+STAND_INS = {
+    "String": '"0123456789abcdef0123456789abcdef"',
+    "Int": "42",
+    "Int64": "42",
+    "Float64": "4.2",
+    "Bool": "true",
+    "StringSlice": '[]string{"alpha", "beta", "gamma"}',
+    "IntSlice": "[]int{1, 2, 3}",
+    "Int64Slice": "[]int64{1, 2, 3}",
+    "Float64Slice": "[]float64{1.5, 2.5, 3.5}",
+    "BoolSlice": "[]bool{true, false}",
+}
+# The sub-benchmarks of each site, in the order they are reported
+VARIANTS = ["uninstrumented", "noop", "unsampled", "sampled"]
+
+@dataclass
+class SiteShape:
+    """One span site of a hot function and what it records per execution"""
+    function: str
+    location: str
+    line: int
+    span_name: str
+    fan_in: int
+    spans_per_request: float
+    # (key, constructor) of the attributes given at start, and set on the span afterwards
+    start_attributes: List[Tuple[str, str]] = field(default_factory=list)
+    attributes: List[Tuple[str, str]] = field(default_factory=list)
+    events: int = 0
+    errors: int = 0
+
+    @property
+    def benchmark(self) -> str:
+        stem = re.sub(r'\W', "", Path(self.location).stem.title().replace("_", ""))
+        name = self.function[:1].upper() + self.function[1:]
+        return f"Benchmark{stem}{name}L{self.line}"
+
+def _attributes(source: GoFile, start: int, end: int, times: float = 1.0) -> List[Tuple[str, str]]:
+    found = []
+    for call in attribute_calls(source):
+        if start <= call.start < end and len(call.args) >= 2:
+            key = call.args[0].literal or f"attribute.{len(found)}"
+            found += [(key, call.name.rsplit(".", 1)[-1])] * max(1, round(times))
+    # semconv helpers and prebuilt key-values: a string attribute
+    for m in re.finditer(r'\bsemconv\w*\.\w+\(|\w+Key\.String\(', source.masked[start:end]):
+        found += [(f"attribute.{len(found)}", "String")] * max(1, round(times))
+    return found
+
+def _shape(source: GoFile, start: SpanStart, budget: Budget, fan_in: int, spans: float) -> SiteShape:
+    fn = start.func
+    shape = SiteShape(
+        function=fn.name or "func literal",
+        location=source.path,
+        line=source.line_of(start.call.start),
+        span_name=start.name if start.name is not None else "x" * 32,
+        fan_in=fan_in,
+        spans_per_request=spans,
+        start_attributes=_attributes(source, start.call.open_paren, start.call.close_paren),
+    )
+    var = start.span_var
+    if not var or var == "_":
+        return shape
+    site_loop = source.enclosing_loop(start.call.start, fn)
+    since = site_loop[0] if site_loop else -1
+    for call in source.calls(re.escape(var) + r'\.(?:SetAttributes|AddEvent|RecordError)', start.call.end, fn.body_end):
+        times = loop_multiplier(source, fn, call.start, budget.loop_iterations, since)
+        method = call.name.rsplit(".", 1)[-1]
+        if method == "SetAttributes":
+            shape.attributes += _attributes(source, call.open_paren, call.close_paren, times)
+        elif method == "AddEvent":
+            shape.events += round(times)
+        else:
+            shape.errors += round(times)
+    return shape
+
+def hot_sites(sources: List[GoFile], top: int = 10, loop_iterations: int = 10) -> List[SiteShape]:
+    """The span sites of the top instrumented functions, most called first: by the number of call
+    sites calling them, then by the spans they make per request"""
+
+    budget = Budget(sources, loop_iterations)
+    fan_in: Dict[str, int] = {}
+    for calls in budget.callees.values():
+        for callee, _ in calls:
+            fan_in[callee] = fan_in.get(callee, 0) + 1
+    per_request: Dict[str, float] = {}
+    for entry in budget.entry_costs():
+        for where, spans in entry.sites.items():
+            per_request[where] = per_request.get(where, 0.0) + spans
+    ranked = sorted(budget.sites, key=lambda name: (-fan_in.get(name, 0),
+                                                    -sum(per_request.get(s.where, 0.0) for s in budget.sites[name]),
+                                                    name))
+    shapes = []
+    for name in ranked[:top]:
+        for site in budget.sites[name]:
+            shapes.append(_shape(site.source, site.start, budget, fan_in.get(name, 0),
+                                 round(per_request.get(site.where, 0.0), 1)))
+    return shapes
+
+def _benchmark_names(shapes: List[SiteShape]) -> List[str]:
+    """Benchmark function names, numbered where two sites would share one"""
+
+    names, seen = [], {}
+    for s in shapes:
+        seen[s.benchmark] = seen.get(s.benchmark, 0) + 1
+        names.append(s.benchmark + (f"_{seen[s.benchmark]}" if seen[s.benchmark] > 1 else ""))
+    return names
+
+def _go_string(text: str) -> str:
+    return '"' + text.replace("\\", "\\\\").replace('"', '\\"') + '"'
+
+def _go_attributes(attributes: List[Tuple[str, str]]) -> str:
+    if not attributes:
+        return "nil"
+    items = "".join(f"\n\t\t\tattribute.{kind}({_go_string(key)}, {STAND_INS[kind]}),"
+                    for key, kind in attributes)
+    return "[]attribute.KeyValue{" + items + "\n\t\t}"
+
+HARNESS = '''
+// shape is what one span site records per execution.
+type shape struct {
+	name       string
+	start      []attribute.KeyValue
+	attributes []attribute.KeyValue
+	events     int
+	errors     int
+}
+
+var errReplayed = errors.New("replayed error")
+
+func replay(ctx context.Context, tracer trace.Tracer, s shape) {
+	_, span := tracer.Start(ctx, s.name, trace.WithAttributes(s.start...))
+	if len(s.attributes) > 0 {
+		span.SetAttributes(s.attributes...)
+	}
+	for i := 0; i < s.events; i++ {
+		span.AddEvent("replayed")
+	}
+	for i := 0; i < s.errors; i++ {
+		span.RecordError(errReplayed)
+	}
+	span.End()
+}
+
+// sdkTracer exports to nowhere, so the numbers are the SDK's cost up to the exporter.
+func sdkTracer(b *testing.B, sampler sdktrace.Sampler) trace.Tracer {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithBatcher(tracetest.NewNoopExporter()))
+	b.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return tp.Tracer("ollybench")
+}
+
+// run measures the site without a span, with a noop tracer, and with the SDK dropping
+// (NeverSample) and keeping (AlwaysSample) it.
+func run(b *testing.B, s shape) {
+	ctx := context.Background()
+	tracers := map[string]func(*testing.B) trace.Tracer{
+		"noop":      func(*testing.B) trace.Tracer { return noop.NewTracerProvider().Tracer("ollybench") },
+		"unsampled": func(b *testing.B) trace.Tracer { return sdkTracer(b, sdktrace.NeverSample()) },
+		"sampled":   func(b *testing.B) trace.Tracer { return sdkTracer(b, sdktrace.AlwaysSample()) },
+	}
+	b.Run("uninstrumented", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = ctx
+		}
+	})
+	for _, variant := range []string{"noop", "unsampled", "sampled"} {
+		b.Run(variant, func(b *testing.B) {
+			tracer := tracers[variant](b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				replay(ctx, tracer, s)
+			}
+		})
+	}
+}
+'''
+
+def render_benchmarks(shapes: List[SiteShape], root: str = ".") -> str:
+    """overhead_test.go: the harness and one benchmark per site"""
+
+    lines = [
+        "// Code generated by `otel_cli.py overhead`; DO NOT EDIT.",
+        "",
+        "// Package ollybench replays the span sites of the most called instrumented functions.",
+        "package ollybench",
+        "",
+        "import (",
+        '\t"context"',
+        '\t"errors"',
+        '\t"testing"',
+        "",
+        '\t"go.opentelemetry.io/otel/attribute"',
+        '\tsdktrace "go.opentelemetry.io/otel/sdk/trace"',
+        '\t"go.opentelemetry.io/otel/sdk/trace/tracetest"',
+        '\t"go.opentelemetry.io/otel/trace"',
+        '\t"go.opentelemetry.io/otel/trace/noop"',
+        ")",
+        HARNESS.rstrip(),
+    ]
+    for s, name in zip(shapes, _benchmark_names(shapes)):
+        lines += [
+            "",
+            f"// {s.function}, {_relative(s.location, root)}:{s.line}: fan-in {s.fan_in}, "
+            f"{s.spans_per_request:g} spans per request",
+            f"func {name}(b *testing.B) {{",
+            "\trun(b, shape{",
+            f"\t\tname:       {_go_string(s.span_name)},",
+            f"\t\tstart:      {_go_attributes(s.start_attributes)},",
+            f"\t\tattributes: {_go_attributes(s.attributes)},",
+            f"\t\tevents:     {s.events},",
+            f"\t\terrors:     {s.errors},",
+            "\t})",
+            "}",
+        ]
+    return "\n".join(lines) + "\n"
+
+def render_go_mod(root: str) -> str:
+    """go.mod of the benchmark module, on the API and SDK versions the code already uses"""
+
+    requires = go_mod_requires(Path(root)) if Path(root).is_dir() else {}
+    version = requires.get("go.opentelemetry.io/otel/sdk") or requires.get("go.opentelemetry.io/otel") or OTEL_VERSION
+    return ("module ollybench\n\ngo 1.22\n\nrequire (\n"
+            f"\tgo.opentelemetry.io/otel {requires.get('go.opentelemetry.io/otel', version)}\n"
+            f"\tgo.opentelemetry.io/otel/sdk {version}\n"
+            f"\tgo.opentelemetry.io/otel/trace {requires.get('go.opentelemetry.io/otel/trace', version)}\n"
+            ")\n")
+
+def _relative(path: str, root: str) -> str:
+    try:
+        return Path(path).resolve().relative_to(Path(root).resolve()).as_posix()
+    except ValueError:
+        return path
+
+BENCH_LINE = re.compile(r'^(Benchmark\w+)/(\w+)(?:-\d+)?\s+\d+\s+([\d.]+) ns/op(?:\s+(\d+) B/op\s+(\d+) allocs/op)?', re.M)
+
+def parse_benchmarks(output: str) -> Dict[str, Dict[str, Dict[str, float]]]:
+    """benchmark -> variant -> {"ns_per_op", "bytes_per_op", "allocs_per_op"} from `go test -bench` output"""
+
+    results: Dict[str, Dict[str, Dict[str, float]]] = {}
+    for m in BENCH_LINE.finditer(output):
+        results.setdefault(m.group(1), {})[m.group(2)] = {
+            "ns_per_op": float(m.group(3)),
+            "bytes_per_op": float(m.group(4) or 0),
+            "allocs_per_op": float(m.group(5) or 0),
+        }
+    return results
+
+def overhead_report(shapes: List[SiteShape], results: Dict[str, Dict[str, Dict[str, float]]], root: str = ".") -> List[Dict]:
+    """Each site's measured cost per span, and per request from the spans it makes per request"""
+
+    rows = []
+    for s, name in zip(shapes, _benchmark_names(shapes)):
+        measured = results.get(name, {})
+        base = measured.get("uninstrumented", {}).get("ns_per_op", 0.0)
+        row = {
+            "benchmark": name,
+            "function": s.function,
+            "location": f"{_relative(s.location, root)}:{s.line}",
+            "span_name": s.span_name,
+            "fan_in": s.fan_in,
+            "spans_per_request": s.spans_per_request,
+        }
+        for variant in VARIANTS[1:]:
+            if variant in measured:
+                row[f"{variant}_ns_per_span"] = round(measured[variant]["ns_per_op"] - base, 1)
+                row[f"{variant}_allocs_per_span"] = measured[variant]["allocs_per_op"]
+        if "sampled" in measured:
+            row["sampled_us_per_request"] = round(row["sampled_ns_per_span"] * s.spans_per_request / 1000, 2)
+        rows.append(row)
+    return rows
+
+def write_module(output: str, shapes: List[SiteShape], root: str) -> Path:
+    out = Path(output)
+    out.mkdir(parents=True, exist_ok=True)
+    if not (out / "go.mod").exists():
+        (out / "go.mod").write_text(render_go_mod(root), encoding="utf-8")
+    (out / "overhead_test.go").write_text(render_benchmarks(shapes, root), encoding="utf-8")
+    return out
@@ -0,0 +1,223 @@
+"""
+Rule plugins: executables, typically Go programs built on analyzers/plugin, that add rules too
+complex for custom_rules expressions. Plugins are listed in .ollygarden.yaml:
+
+    plugins:
+      - name: fix-protocol
+        command: [./bin/fix-rules]      # relative to the config's directory
+        timeout: 300                    # seconds per run, the default
+
+The engine talks to a plugin over stdin and stdout, in JSON:
+
+    COMMAND describe
+        -> {"protocol": 1, "rules": [{"id", "title", "category", "signal", "severity", "description", "autofix"}]}
+    COMMAND check   <- {"protocol": 1, "root", "rules": [ids], "files": [{"path", "package",
+                        "package_path", "spans": [...], "attributes": [...], "calls": [...]}]}
+        -> {"diagnostics": [{"rule_id", "file", "offset", "end", "message", "suggestion",
+                             "confidence", "severity", "fixes": [{"description", "edits": [...]}]}]}
+
+spans, attributes and calls are the model custom rules see (custom.py), each with the byte
+offset and end of its expression; diagnostics and fix edits are positioned by byte offset as
+well, like the output of rules.analysis. describe runs once when the config is loaded, check
+once per run with every file, whichever of the plugin's rules asks first.
+"""
+
+import json
+import re
+import shlex
+import subprocess
+from dataclasses import dataclass, field
+from functools import lru_cache
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+
+from .base import Diagnostic, Fix, Rule, SEVERITIES, SIGNALS, TextEdit
+from .custom import attributes, calls, package_path, spans
+from .golang import GoFile
+
+PROTOCOL = 1
+KEYS = {"name", "command", "timeout"}
+DEFAULT_TIMEOUT = 300
+
+class PluginError(RuntimeError):
+    """A plugin that failed to run or answered out of protocol"""
+
+    def __init__(self, plugin: str, message: str):
+        super().__init__(f"plugin '{plugin}': {message}")
+        self.plugin = plugin
+        self.reason = message
+
+def _byte_offset(code: str, pos: int) -> int:
+    return len(code[:pos].encode("utf-8"))
+
+def _char_offset(code: str, offset: int) -> int:
+    return len(code.encode("utf-8")[:offset].decode("utf-8", errors="ignore"))
+
+@dataclass
+class Plugin:
+    name: str
+    command: List[str]
+    # Directory the command runs in: the config's
+    root: str = "."
+    timeout: float = DEFAULT_TIMEOUT
+    # Sources of the last check, and its findings by rule id or the error it failed with
+    _findings: Optional[Tuple[List[GoFile], Any]] = field(default=None, repr=False)
+
+    def run(self, action: str, request: Optional[Dict] = None) -> Dict:
+        try:
+            done = subprocess.run(self.command + [action], cwd=self.root, capture_output=True, text=True,
+                                  input=json.dumps(request) if request is not None else "", timeout=self.timeout)
+        except (OSError, subprocess.TimeoutExpired) as e:
+            raise PluginError(self.name, f"{action}: {e}")
+        if done.returncode != 0:
+            stderr = done.stderr.strip().splitlines()
+            raise PluginError(self.name, f"{action} exited with {done.returncode}"
+                              + (f": {stderr[-1]}" if stderr else ""))
+        try:
+            response = json.loads(done.stdout)
+        except ValueError as e:
+            raise PluginError(self.name, f"{action} printed invalid JSON: {e}")
+        if not isinstance(response, dict):
+            raise PluginError(self.name, f"{action} must print a JSON object")
+        return response
+
+    def described(self) -> List[Dict]:
+        return _describe(tuple(self.command), self.root, self.timeout, self.name)
+
+    def rule_ids(self) -> List[str]:
+        return [entry["id"] for entry in self.described()]
+
+    def rules(self, categories) -> List[Rule]:
+        """The rules the plugin describes"""
+
+        found = []
+        for entry in self.described():
+            problem = _rule_problem(entry, categories)
+            if problem:
+                raise PluginError(self.name, f"rule {entry.get('id', '?')!r}: {problem}")
+            found.append(Rule(
+                rule_id=entry["id"],
+                title=entry["title"],
+                category=entry.get("category", "conventions"),
+                signal=entry.get("signal", "traces"),
+                severity=entry.get("severity", "medium"),
+                description=entry.get("description") or f"Rule of the {self.name} plugin.",
+                check=self._check(entry["id"]),
+                scope="project",
+                autofix=bool(entry.get("autofix")),
+                signals=SIGNALS if entry.get("signal") == "all" else (entry.get("signal", "traces"),),
+            ))
+        return found
+
+    def _check(self, rule_id: str):
+        def check(sources: List[GoFile]) -> Iterator[Diagnostic]:
+            yield from self.findings(sources).get(rule_id, [])
+        return check
+
+    def findings(self, sources: List[GoFile]) -> Dict[str, List[Diagnostic]]:
+        """Diagnostics of every rule of the plugin over sources, by rule id; the plugin runs once per
+        set of sources"""
+
+        if self._findings is not None and self._findings[0] is sources:
+            if isinstance(self._findings[1], PluginError):
+                raise self._findings[1]
+            return self._findings[1]
+        files = [s for s in sources if not s.path.endswith("_test.go")]
+        by_path = {str(Path(s.path).resolve()): s for s in files}
+        request = {
+            "protocol": PROTOCOL,
+            "root": str(Path(self.root).resolve()),
+            "rules": self.rule_ids(),
+            "files": [_file_model(s) for s in files],
+        }
+        try:
+            response = self.run("check", request)
+        except PluginError as e:
+            self._findings = (sources, e)
+            raise
+        found: Dict[str, List[Diagnostic]] = {}
+        for d in response.get("diagnostics") or []:
+            source = by_path.get(str(Path(str(d.get("file", ""))).resolve()))
+            if source is None or not isinstance(d.get("offset"), int):
+                continue
+            found.setdefault(str(d.get("rule_id")), []).append(_diagnostic(source, d))
+        self._findings = (sources, found)
+        return found
+
+@lru_cache(maxsize=None)
+def _describe(command: Tuple[str, ...], root: str, timeout: float, name: str) -> List[Dict]:
+    response = Plugin(name, list(command), root, timeout).run("describe")
+    if response.get("protocol") != PROTOCOL:
+        raise PluginError(name, f"speaks protocol {response.get('protocol')!r}, not {PROTOCOL}")
+    described = response.get("rules")
+    if not isinstance(described, list) or not all(isinstance(r, dict) for r in described):
+        raise PluginError(name, "describe must list its rules")
+    return described
+
+def _rule_problem(entry: Dict, categories) -> Optional[str]:
+    if not entry.get("id") or not entry.get("title"):
+        return "needs an id and a title"
+    if not re.fullmatch(r'[a-z][a-z0-9]*(?:-[a-z0-9]+)*', str(entry["id"])):
+        return "id must be lowercase words joined by dashes"
+    if entry.get("severity", "medium") not in SEVERITIES:
+        return f"unknown severity '{entry['severity']}'"
+    if entry.get("category", "conventions") not in categories:
+        return f"unknown category '{entry['category']}'"
+    if entry.get("signal", "traces") not in SIGNALS + ("all",):
+        return f"unknown signal '{entry['signal']}'"
+    return None
+
+def _file_model(source: GoFile) -> Dict[str, Any]:
+    def items(found) -> List[Dict]:
+        return [{**item.model, "offset": _byte_offset(source.code, item.pos),
+                 "end": _byte_offset(source.code, item.end)} for item in found]
+    return {
+        "path": str(Path(source.path).resolve()),
+        "package": source.package,
+        "package_path": package_path(source),
+        "spans": items(spans(source)),
+        "attributes": items(attributes(source)),
+        "calls": items(calls(source)),
+    }
+
+def _diagnostic(source: GoFile, d: Dict) -> Diagnostic:
+    code = source.code
+    pos = _char_offset(code, d["offset"])
+    end = _char_offset(code, d["end"]) if isinstance(d.get("end"), int) else None
+    fix = None
+    for f in d.get("fixes") or []:
+        edits = [TextEdit(_char_offset(code, e["start"]), _char_offset(code, e["end"]), e.get("new_text", ""))
+                 for e in f.get("edits") or []]
+        fix = Fix(f.get("description", ""), edits) if edits else None
+        break
+    severity = d.get("severity")
+    return Diagnostic(
+        pos=pos,
+        end=end,
+        message=str(d.get("message", "")),
+        suggestion=str(d.get("suggestion", "")),
+        confidence=float(d.get("confidence", 0.9)),
+        severity=severity if severity in SEVERITIES else None,
+        fix=fix,
+        file=source,
+    )
+
+def plugin(entry: Any, root: str) -> Plugin:
+    """The Plugin a plugins entry configures; raises ValueError with what's wrong with it"""
+
+    if not isinstance(entry, dict):
+        raise ValueError("must map name and command")
+    unknown = sorted(str(k) for k in set(entry) - KEYS)
+    if unknown:
+        raise ValueError(f"unknown key(s) {', '.join(unknown)}")
+    if not entry.get("name") or not entry.get("command"):
+        raise ValueError("needs a name and a command")
+    command = entry["command"]
+    if isinstance(command, str):
+        command = shlex.split(command)
+    if not isinstance(command, list) or not all(isinstance(c, str) for c in command):
+        raise ValueError("command must be a string or a list of arguments")
+    timeout = entry.get("timeout", DEFAULT_TIMEOUT)
+    if isinstance(timeout, bool) or not isinstance(timeout, (int, float)) or timeout <= 0:
+        raise ValueError("timeout must be a positive number of seconds")
+    return Plugin(str(entry["name"]), command, root, timeout)
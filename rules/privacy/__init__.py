@@ -0,0 +1,5 @@
+"""
+Rules keeping sensitive data out of telemetry
+"""
+
+from . import secrets, classification, pii
@@ -0,0 +1,132 @@
+"""
+Values from fields and constants annotated with `// olly:data-class <class>` reaching telemetry
+"""
+
+import re
+from typing import Dict, Iterator, List, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+from ..taint import SANITIZER
+from .sinks import telemetry_sinks
+
+DIRECTIVE = "olly:data-class"
+
+def classified_names(sources: List[GoFile], classes: List[str]) -> Tuple[Dict[str, str], Dict[str, str]]:
+    """Annotated struct fields and package-level names, each mapped to its data class"""
+
+    fields, names = {}, {}
+    for source in sources:
+        for name, owner, data_class, _ in source.annotations(DIRECTIVE):
+            if data_class not in classes:
+                continue
+            (fields if owner else names)[name] = data_class
+    return fields, names
+
+def _classification(text: str, fields: Dict[str, str], names: Dict[str, str]) -> Tuple[str, str]:
+    for name, data_class in fields.items():
+        if re.search(r'\.' + re.escape(name) + r'\b', text):
+            return name, data_class
+    for name, data_class in names.items():
+        if re.search(r'(?<![\w.])' + re.escape(name) + r'\b', text):
+            return name, data_class
+    return "", ""
+
+def _tainted_locals(source: GoFile, body_start: int, body_end: int,
+                    fields: Dict[str, str], names: Dict[str, str]) -> Dict[str, Tuple[str, str]]:
+    """Local variables assigned from classified values (`email := u.Email`), followed through reassignments"""
+
+    tainted: Dict[str, Tuple[str, str]] = {}
+    body = source.masked[body_start:body_end]
+    assignments = list(re.finditer(r'\b(\w+)\s*:?=\s*([^\n;]+)', body))
+    changed = True
+    while changed:
+        changed = False
+        for m in assignments:
+            var, expr = m.group(1), source.code[body_start + m.start(2):body_start + m.end(2)]
+            if var in tainted or SANITIZER.search(expr):
+                continue
+            origin = _classification(expr, fields, names)
+            if not origin[0]:
+                origin = next((tainted[t] for t in tainted if re.search(r'(?<![\w.])' + re.escape(t) + r'\b', expr)), ("", ""))
+            if origin[0]:
+                tainted[var] = origin
+                changed = True
+    return tainted
+
+@rule(
+    rule_id="classified-data-in-telemetry",
+    title="Annotated sensitive data must not reach telemetry unredacted",
+    category="security",
+    signal="traces",
+    signals=("logs", "baggage"),
+    severity="high",
+    description="Struct fields, constants and variables annotated with `// olly:data-class <class>` hold data "
+                "whose handling is regulated. Their values must not be recorded in span attributes, events, "
+                "logs or baggage unless they are redacted first or the key is on the approved redacted list.",
+    scope="project",
+    options={
+        # Data classes that must not be exported
+        "classes": ["pii", "phi", "pci", "secret", "sensitive"],
+        # Attribute, log field and baggage keys whose values the pipeline redacts
+        "redacted_keys": [],
+    },
+    bad_example='''
+type Customer struct {
+	ID    string
+	Email string // olly:data-class pii
+}
+
+func traceSignup(ctx context.Context, c Customer) {
+	_, span := tracer.Start(ctx, "signup customer")
+	defer span.End()
+	span.SetAttributes(attribute.String("customer.email", c.Email))
+}''',
+    good_example='''
+type Account struct {
+	ID    string
+	Email string // olly:data-class pii
+}
+
+func traceAccount(ctx context.Context, a Account) {
+	_, span := tracer.Start(ctx, "create account")
+	defer span.End()
+	span.SetAttributes(attribute.String("account.id", a.ID), attribute.String("account.email_hash", hashEmail(a.Email)))
+}''',
+)
+def check_classified_data(sources: List[GoFile], options: Dict) -> Iterator[Diagnostic]:
+    fields, names = classified_names(sources, options["classes"])
+    if not fields and not names:
+        return
+    redacted = set(options["redacted_keys"])
+    for source in sources:
+        tainted_by_func = {}
+        for sink in telemetry_sinks(source, include_logs=True):
+            key = sink.key.literal if sink.key else None
+            if key is not None and key in redacted:
+                continue
+            text = sink.value.text
+            if SANITIZER.search(text):
+                continue
+            name, data_class = _classification(text, fields, names)
+            if not name:
+                fn = source.func_at(sink.value.start, include_literals=True)
+                if fn is None:
+                    continue
+                if fn.body_start not in tainted_by_func:
+                    tainted_by_func[fn.body_start] = _tainted_locals(source, fn.body_start, fn.body_end, fields, names)
+                tainted = tainted_by_func[fn.body_start]
+                var = next((v for v in tainted if re.search(r'(?<![\w.])' + re.escape(v) + r'\b', text)), None)
+                if var is None:
+                    continue
+                name, data_class = tainted[var]
+                name = f"{name} (via {var})"
+            yield Diagnostic(
+                pos=sink.value.start,
+                message=f"{sink.what.capitalize()} records {name}, which is classified {data_class}",
+                suggestion="Redact or hash the value before recording it"
+                           + (f", or add '{key}' to redacted_keys if the pipeline redacts it" if key else ""),
+                confidence=0.85,
+                file=source,
+            )
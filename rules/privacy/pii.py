@@ -0,0 +1,223 @@
+"""
+Personal data in span attributes, events and baggage. Detectors recognise values by their shape
+(an email address, a card number passing the Luhn check), attribute keys can be denylisted, and
+user input (request form fields and headers, gRPC request fields, flags) is followed to where it
+is recorded. The same detectors judge exported spans (see telemetry.py).
+"""
+
+import fnmatch
+import ipaddress
+import re
+from dataclasses import dataclass
+from typing import Any, Callable, Dict, Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+from ..taint import SANITIZER, Source, Taint
+from .classification import classified_names
+from .sinks import telemetry_sinks
+
+# Last word(s) of keys and identifiers holding personal data, in snake_case
+PII_NAME = re.compile(
+    r'(?:^|_)(?:e_?mail(?:_address)?|phone(?:_number)?|mobile(?:_number)?|ssn|social_security(?:_number)?'
+    r'|tax_id|passport(?:_number)?|national_id|credit_card(?:_number)?|card_number|cvv|iban'
+    r'|date_of_birth|dob|birth_?date|first_name|last_name|full_name|street(?:_address)?|postal_code|zip_?code)$'
+)
+# Keys semantic conventions define to hold network addresses; the ip detector leaves them alone
+ADDRESS_KEYS = {"client.address", "server.address", "source.address", "destination.address",
+                "network.peer.address", "network.local.address", "net.peer.ip", "net.host.ip",
+                "net.sock.peer.addr", "net.sock.host.addr", "http.client_ip"}
+# Headers proxies put the client's address in
+ADDRESS_HEADERS = {"x-forwarded-for", "x-real-ip", "forwarded", "true-client-ip", "cf-connecting-ip"}
+
+def snake(name: str) -> str:
+    name = re.sub(r'(?<=[a-z0-9])(?=[A-Z])', "_", name)
+    return re.sub(r'[.\-]', "_", name).lower()
+
+def _luhn(digits: str) -> bool:
+    total = 0
+    for i, ch in enumerate(reversed(digits)):
+        d = int(ch) * (2 if i % 2 else 1)
+        total += d - 9 if d > 9 else d
+    return total % 10 == 0
+
+def _card(value: str) -> bool:
+    digits = re.sub(r'[\s-]', "", value)
+    return 13 <= len(digits) <= 19 and _luhn(digits)
+
+def _ssn(value: str) -> bool:
+    area, group, serial = value.split("-")
+    return area not in ("000", "666") and not area.startswith("9") and group != "00" and serial != "0000"
+
+def _public_ip(value: str) -> bool:
+    try:
+        ip = ipaddress.ip_address(value)
+    except ValueError:
+        return False
+    # Private, loopback and link-local addresses are the infrastructure's, not a person's
+    return ip.is_global
+
+@dataclass
+class Detector:
+    name: str
+    what: str
+    pattern: re.Pattern
+    valid: Optional[Callable[[str], bool]] = None
+
+    def matches(self, value: str) -> bool:
+        value = value.strip()
+        return bool(self.pattern.search(value)) and (self.valid is None or self.valid(value))
+
+DETECTORS = {d.name: d for d in [
+    Detector("email", "an email address", re.compile(r'^[^\s@]+@[^\s@]+\.[A-Za-z]{2,}$')),
+    Detector("ssn", "a US social security number", re.compile(r'^\d{3}-\d{2}-\d{4}$'), _ssn),
+    Detector("credit-card", "a payment card number", re.compile(r'^\d(?:[ -]?\d){12,18}$'), _card),
+    Detector("phone", "a phone number", re.compile(r'^(?:\+\d[\d\s().-]{7,}\d|\(?\d{3}\)?[\s.-]\d{3}[\s.-]\d{4})$')),
+    Detector("ip", "a public IP address", re.compile(r'^(?:\d{1,3}(?:\.\d{1,3}){3}|[0-9A-Fa-f:]*:[0-9A-Fa-f:.]*)$'), _public_ip),
+]}
+
+def detectors(options: Dict) -> List[Detector]:
+    """The built-in detectors the options select, then one per configured pattern"""
+
+    found = [DETECTORS[name] for name in options["detectors"] if name in DETECTORS]
+    for name, pattern in (options["patterns"] or {}).items():
+        found.append(Detector(name, f"{name} (a value matching its pattern)", re.compile(pattern)))
+    return found
+
+def denied(key: str, options: Dict) -> bool:
+    return any(fnmatch.fnmatchcase(key, pattern) for pattern in options["denied_keys"])
+
+def value_pii(key: str, value: str, found: List[Detector]) -> Optional[Detector]:
+    """The first detector recognising value as personal data, the ip one aside for address keys"""
+
+    for detector in found:
+        if detector.name == "ip" and key in ADDRESS_KEYS:
+            continue
+        if detector.matches(value):
+            return detector
+    return None
+
+def pii_kind(key: str, value: Any, options: Dict) -> Optional[str]:
+    """What personal data an exported attribute records, judged by its value, then by its key"""
+
+    if not isinstance(value, str) or not value.strip():
+        return None
+    detector = value_pii(key, value, detectors(options))
+    if detector is not None:
+        return detector.what
+    if denied(key, options):
+        return f"personal data ({key} is on denied_keys)"
+    if PII_NAME.search(snake(key)):
+        return f"personal data ({key.rsplit('.', 1)[-1]})"
+    return None
+
+def source_pii(source: Source, options: Dict) -> Optional[str]:
+    """What personal data user input carries, judged by the field or header it was read from"""
+
+    if source.kind == "client address" or (source.kind == "header" and source.field.lower() in ADDRESS_HEADERS):
+        return "the client's IP address" if "ip" in options["detectors"] else None
+    if source.field and PII_NAME.search(snake(source.field)):
+        return "personal data"
+    return None
+
+@rule(
+    rule_id="pii-in-telemetry",
+    title="Personal data must not be recorded in span attributes, events or baggage",
+    category="security",
+    signal="traces",
+    signals=("baggage",),
+    severity="high",
+    scope="project",
+    description="Spans are kept for weeks in backends most engineers can query, and baggage is forwarded to "
+                "every downstream service. Email addresses, social security numbers, card numbers, phone "
+                "numbers and public IP addresses recorded there put the whole trace pipeline in scope of "
+                "privacy law. Detectors (detectors, plus patterns for the team's own regexps) recognise "
+                "literal values, denied_keys lists attribute keys that must never be recorded (globs "
+                "allowed), and values read from request form fields, query parameters and headers, gRPC "
+                "request fields and command-line flags are followed through variables and calls to the "
+                "attribute recording them. Values passed "
+                "through a redact/hash/mask function and keys on redacted_keys are skipped; the IP "
+                "detector leaves semantic convention address keys (client.address) alone.",
+    options={
+        # Built-in detectors to run: email, ssn, credit-card, phone, ip
+        "detectors": ["email", "ssn", "credit-card", "phone", "ip"],
+        # Custom detectors: name -> regexp matched against literal values
+        "patterns": {},
+        # Attribute keys never to record, fnmatch globs allowed
+        "denied_keys": ["user.email", "user.full_name", "user.phone", "user.ssn"],
+        # Keys whose values the pipeline redacts
+        "redacted_keys": [],
+    },
+    bad_example='''
+func handleSignup(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /signup")
+	defer span.End()
+	email := r.FormValue("email")
+	span.SetAttributes(attribute.String("signup.contact", email))
+	createAccount(ctx, email)
+}''',
+    good_example='''
+func handleSignupTraced(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /signup")
+	defer span.End()
+	email := r.FormValue("email")
+	span.SetAttributes(attribute.String("signup.contact_hash", hashEmail(email)))
+	createAccount(ctx, email)
+}''',
+)
+def check_pii_in_telemetry(sources: List[GoFile], options: Dict) -> Iterator[Diagnostic]:
+    found = detectors(options)
+    redacted = set(options["redacted_keys"])
+    # Annotated values are classified-data-in-telemetry's
+    fields, names = classified_names(sources, ["pii", "phi", "pci", "secret", "sensitive"])
+    annotated = [re.compile(r'\.' + re.escape(n) + r'\b') for n in fields] + \
+                [re.compile(r'(?<![\w.])' + re.escape(n) + r'\b') for n in names]
+    taint = Taint(sources)
+    for source in sources:
+        if source.path.endswith("_test.go"):
+            continue
+        for sink in telemetry_sinks(source):
+            key = sink.key.literal if sink.key else None
+            value = sink.value
+            text = value.text.strip()
+            if key in redacted or SANITIZER.search(text) or any(p.search(text) for p in annotated):
+                continue
+            what = sink.what.capitalize() + (f' "{key}"' if key else "")
+            literal = value.literal
+            if literal is not None:
+                detector = value_pii(key or "", literal, found)
+                if detector is not None:
+                    yield Diagnostic(
+                        pos=value.start,
+                        end=value.end,
+                        message=f"{what} records {detector.what}",
+                        suggestion="Record an identifier or a hash instead",
+                        confidence=0.9 if detector.valid else 0.7,
+                        file=source,
+                    )
+                continue
+            fn = source.func_at(value.start, include_literals=True)
+            origin = taint.origin(source, fn, value.start, value.end) if fn is not None else None
+            personal = source_pii(origin, options) if origin is not None else None
+            if personal and not (personal.endswith("IP address") and key in ADDRESS_KEYS):
+                where = f"line {origin.line}" if origin.path == source.path else origin.where
+                yield Diagnostic(
+                    pos=value.start,
+                    end=value.end,
+                    message=f"{what} records {origin.what} ({where}), {personal}",
+                    suggestion="Record an identifier or a hash instead"
+                               + (f", or add '{key}' to redacted_keys if the pipeline redacts it" if key else ""),
+                    confidence=0.8,
+                    file=source,
+                )
+                continue
+            if key and denied(key, options):
+                yield Diagnostic(
+                    pos=sink.key.start,
+                    end=value.end,
+                    message=f"{what} is on denied_keys: it must not be recorded",
+                    suggestion="Drop the attribute, or record a hash under another key",
+                    confidence=0.85,
+                    file=source,
+                )
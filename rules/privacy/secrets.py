@@ -0,0 +1,125 @@
+"""
+Credentials in span attributes, event names and baggage
+"""
+
+import math
+import re
+from typing import Dict, Iterator, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+from .sinks import telemetry_sinks
+
+SECRET_PATTERNS = [
+    (re.compile(r'(?:AKIA|ASIA)[0-9A-Z]{16}'), "an AWS access key id"),
+    (re.compile(r'(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*'), "a Bearer token"),
+    (re.compile(r'eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}'), "a JWT"),
+    (re.compile(r'gh[pousr]_[A-Za-z0-9]{36}'), "a GitHub token"),
+    (re.compile(r'xox[abprs]-[A-Za-z0-9-]{10,}'), "a Slack token"),
+    (re.compile(r'[sr]k_(?:live|test)_[A-Za-z0-9]{16,}'), "a Stripe key"),
+    (re.compile(r'AIza[0-9A-Za-z\-_]{35}'), "a Google API key"),
+    (re.compile(r'-----BEGIN [A-Z ]*PRIVATE KEY-----'), "a private key"),
+]
+
+# Expressions and keys whose names say they hold credentials
+SECRET_NAME = re.compile(r'(?i)(passw(?:or)?d|passwd|secret|token|api[_-]?key|authorization|credential|'
+                         r'private[_-]?key|cookie|session[_-]?id|access[_-]?key)')
+# ...unless they only describe one (tokenCount, len(token), secretName)
+NOT_A_SECRET = re.compile(r'(?i)^len\(|(?:count|len|length|type|kind|name|ttl|expiry|expires\w*|present|set|valid)\)?$')
+
+def shannon_entropy(text: str) -> float:
+    if not text:
+        return 0.0
+    counts: Dict[str, int] = {}
+    for ch in text:
+        counts[ch] = counts.get(ch, 0) + 1
+    return -sum(c / len(text) * math.log2(c / len(text)) for c in counts.values())
+
+def literal_secret(value: str, options: Dict) -> Optional[str]:
+    """What kind of credential a literal looks like, or None"""
+
+    for pattern, what in SECRET_PATTERNS:
+        if pattern.search(value):
+            return what
+    if (len(value) >= options["min_length"] and " " not in value
+            and shannon_entropy(value) >= options["min_entropy"]
+            and re.search(r'\d', value) and re.search(r'[A-Za-z]', value)):
+        return f"a high-entropy string (entropy {shannon_entropy(value):.1f})"
+    return None
+
+def _allowed(text: str, options: Dict) -> bool:
+    return any(re.search(pattern, text) for pattern in options["allowlist"])
+
+def _secret_expression(text: str) -> Optional[str]:
+    """Name or header suggesting an expression carries a credential"""
+
+    text = text.strip()
+    header = re.search(r'Header\.Get\(\s*"([^"]+)"\s*\)', text)
+    if header:
+        return header.group(1) if SECRET_NAME.search(header.group(1)) else None
+    if NOT_A_SECRET.search(text):
+        return None
+    m = SECRET_NAME.search(text.rsplit(".", 1)[-1])
+    return m.group(1) if m else None
+
+@rule(
+    rule_id="secret-in-telemetry",
+    title="Credentials must not be recorded in telemetry",
+    category="security",
+    signal="traces",
+    signals=("baggage",),
+    severity="critical",
+    description="Span attributes, events and baggage are exported to backends with broad read access, and "
+                "baggage is forwarded to every downstream service. API keys, tokens and passwords that end "
+                "up there are a recurring incident source.",
+    options={
+        # Regexps for values or expressions that are known not to be secrets
+        "allowlist": [],
+        "min_entropy": 4.0,
+        "min_length": 24,
+    },
+    bad_example='''
+func traceLogin(ctx context.Context, r *http.Request) {
+	_, span := tracer.Start(ctx, "POST /login")
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.header.authorization", r.Header.Get("Authorization")))
+}''',
+    good_example='''
+func traceLoginSafely(ctx context.Context, r *http.Request) {
+	_, span := tracer.Start(ctx, "POST /login")
+	defer span.End()
+	span.SetAttributes(attribute.Bool("auth.header_present", r.Header.Get("Authorization") != ""))
+}''',
+)
+def check_secrets(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    for sink in telemetry_sinks(source):
+        what, key_arg, value_arg = sink.what, sink.key, sink.value
+        text = value_arg.text.strip()
+        if _allowed(text, options):
+            continue
+        literal = value_arg.literal
+        if literal is not None:
+            kind = literal_secret(literal, options)
+            if kind and not _allowed(literal, options):
+                yield Diagnostic(
+                    pos=value_arg.start,
+                    message=f"{what.capitalize()}{'' if what == 'event name' else ' value'} looks like {kind}",
+                    suggestion="Remove the credential from the code and the telemetry; rotate it if it was ever exported",
+                    confidence=0.9 if "entropy" not in kind else 0.6,
+                )
+            continue
+        # Comparisons and other derived booleans don't carry the credential itself
+        if re.search(r'[!=<>]=|&&|\|\|', text):
+            continue
+        name = _secret_expression(text)
+        key = key_arg.literal if key_arg else None
+        if name is None and key and SECRET_NAME.search(key) and not NOT_A_SECRET.search(key):
+            name = key
+        if name:
+            yield Diagnostic(
+                pos=value_arg.start,
+                message=f"{what.capitalize()} records '{name}', which carries a credential",
+                suggestion="Record whether the credential is present, or a non-reversible identifier, instead of its value",
+                confidence=0.75,
+            )
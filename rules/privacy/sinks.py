@@ -0,0 +1,52 @@
+"""
+Places where values end up in telemetry: attributes, span events, baggage and logs
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Iterator, Optional
+
+from ..golang import GoFile, Arg
+from ..traces.attributes import attribute_calls
+
+@dataclass
+class Sink:
+    what: str
+    key: Optional[Arg]
+    value: Arg
+
+LOG_LEVELS = r'(?:Debug|Info|Warn|Warning|Error|Fatal|Panic)'
+
+def telemetry_sinks(source: GoFile, include_logs: bool = False) -> Iterator[Sink]:
+    for call in attribute_calls(source):
+        if len(call.args) >= 2:
+            yield Sink("attribute", call.args[0], call.args[1])
+    for span_var in source.span_vars():
+        for call in source.calls(re.escape(span_var) + r'\.AddEvent'):
+            if call.args:
+                yield Sink("event name", None, call.args[0])
+    for alias in source.import_alias("go.opentelemetry.io/otel/baggage"):
+        for call in source.calls(re.escape(alias) + r'\.(?:NewMember|NewMemberRaw|NewKeyValueProperty)'):
+            if len(call.args) >= 2:
+                yield Sink("baggage member", call.args[0], call.args[1])
+    if include_logs:
+        yield from _log_sinks(source)
+
+def _log_sinks(source: GoFile) -> Iterator[Sink]:
+    # fmt-style loggers: every argument is rendered into the message
+    for call in source.calls(r'log\.(?:Print|Printf|Println|Fatal|Fatalf|Panic|Panicf)|[\w.]+\.' + LOG_LEVELS + r'f'):
+        for arg in call.args[1:] if call.name.endswith("f") else call.args:
+            yield Sink("log message", None, arg)
+    # slog and structured loggers: alternating key/value pairs after the message
+    for call in source.calls(r'[\w.]+\.' + LOG_LEVELS + r'(?:Context|w)?'):
+        args = call.args
+        if call.name.endswith("Context") and args:
+            args = args[1:]
+        pairs = args[1:]
+        for i in range(0, len(pairs) - 1, 2):
+            if pairs[i].literal is not None:
+                yield Sink("log field", pairs[i], pairs[i + 1])
+    # Typed field constructors: slog.String("k", v), zap.String("k", v)
+    for call in source.calls(r'(?:slog|zap)\.(?:String|Int|Int64|Any|Bool|Float64|Stringer|Time|Duration)'):
+        if len(call.args) >= 2:
+            yield Sink("log field", call.args[0], call.args[1])
@@ -0,0 +1,73 @@
+"""
+Machine interface for the Collector processor in collector/ollylintprocessor and the
+SpanProcessor in spancheck, which check spans in flight with the exported-telemetry checks.
+Both start
+
+    python3 -m rules.processor [--config-dir DIR] [--profile NAME]
+
+once and keep it running. Each line they write to stdin is one batch,
+
+    {"spans": [{"name": ..., "kind": "server", "service": ..., "scope": ...,
+                "attributes": {...}, "events": [{"name": ..., "attributes": {...}}]}]}
+
+and each line read back holds the findings of every span of the batch, in order:
+
+    {"spans": [[{"rule_id": ..., "severity": ..., "message": ..., "attribute": ..., "event": -1}, ...], ...]}
+
+or {"error": ...} for a batch that couldn't be read.
+"""
+
+import argparse
+import json
+import sys
+from typing import Dict, List, Optional, TextIO
+
+from .config import Config, ConfigError, load_config
+from .telemetry import ExportedSpan, TraceChecker
+
+# Spans after which the checker starts over, so a long-running worker's memory stays bounded
+RESET_SPANS = 1_000_000
+
+def _span(data: Dict) -> ExportedSpan:
+    return ExportedSpan(
+        name=str(data.get("name") or ""),
+        kind=str(data.get("kind") or "unspecified"),
+        service=str(data.get("service") or ""),
+        scope=str(data.get("scope") or ""),
+        attributes=dict(data.get("attributes") or {}),
+        events=[(str(e.get("name") or ""), dict(e.get("attributes") or {})) for e in data.get("events") or []],
+    )
+
+def serve(config: Config, stdin: TextIO, stdout: TextIO):
+    """Answer batches until stdin is closed"""
+
+    checker = TraceChecker(config)
+    for line in stdin:
+        if not line.strip():
+            continue
+        try:
+            spans = [_span(s) for s in json.loads(line)["spans"]]
+        except (ValueError, KeyError, TypeError, AttributeError) as e:
+            reply = {"error": f"malformed batch: {e}"}
+        else:
+            if checker.spans > RESET_SPANS:
+                checker = TraceChecker(config)
+            reply = {"spans": [[v.to_dict() for v in checker.check(span)] for span in spans]}
+        stdout.write(json.dumps(reply) + "\n")
+        stdout.flush()
+
+def main(argv: Optional[List[str]] = None) -> int:
+    parser = argparse.ArgumentParser(prog="python -m rules.processor", description=__doc__.split("\n\n")[0])
+    parser.add_argument("--config-dir", default=".", help="directory whose .ollygarden.yaml selects the rules")
+    parser.add_argument("--profile", help="config profile (default: $OLLYGARDEN_PROFILE)")
+    args = parser.parse_args(argv)
+    try:
+        config = load_config(args.config_dir, args.profile)
+    except ConfigError as e:
+        print(f"invalid configuration: {e}", file=sys.stderr)
+        return 2
+    serve(config, sys.stdin, sys.stdout)
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
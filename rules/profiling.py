@@ -0,0 +1,93 @@
+"""
+Profiling the analyzer itself: cProfile and tracemalloc dumps, Chrome traces of rule timings,
+and per-rule benchmarks to find the rules worth disabling on large repositories.
+"""
+
+import cProfile
+import json
+import tracemalloc
+from contextlib import contextmanager
+from typing import Dict, List, Optional
+
+from .engine import RuleEngine, RuleTiming
+from .golang import GoFile
+
+@contextmanager
+def profiled(cpuprofile: Optional[str] = None, memprofile: Optional[str] = None):
+    """Write a cProfile dump (readable with pstats or snakeviz) and/or the top allocation sites"""
+
+    profiler = cProfile.Profile() if cpuprofile else None
+    if memprofile:
+        tracemalloc.start()
+    if profiler:
+        profiler.enable()
+    try:
+        yield
+    finally:
+        if profiler:
+            profiler.disable()
+            profiler.dump_stats(cpuprofile)
+        if memprofile:
+            snapshot = tracemalloc.take_snapshot()
+            current, peak = tracemalloc.get_traced_memory()
+            tracemalloc.stop()
+            with open(memprofile, "w", encoding="utf-8") as f:
+                f.write(f"# current {current / 1e6:.1f} MB, peak {peak / 1e6:.1f} MB\n")
+                for stat in snapshot.statistics("lineno")[:50]:
+                    f.write(f"{stat}\n")
+
+def write_trace(timings: List[RuleTiming], path: str):
+    """Chrome trace event file (chrome://tracing, Perfetto): one row per package, one slice per rule"""
+
+    if not timings:
+        events = []
+    else:
+        origin = min(t.start for t in timings)
+        rows: Dict[str, int] = {}
+        events = [
+            {
+                "name": t.rule_id, "cat": "rule", "ph": "X", "pid": 1,
+                "tid": rows.setdefault(t.package, len(rows) + 1),
+                "ts": round((t.start - origin) * 1e6), "dur": round(t.seconds * 1e6),
+                "args": {"package": t.package, "findings": t.findings},
+            }
+            for t in timings
+        ]
+        events += [
+            {"name": "thread_name", "ph": "M", "pid": 1, "tid": tid, "args": {"name": package}}
+            for package, tid in rows.items()
+        ]
+    with open(path, "w", encoding="utf-8") as f:
+        json.dump({"traceEvents": events, "displayTimeUnit": "ms"}, f)
+
+def benchmark(engine: RuleEngine, sources: List[GoFile], repeat: int = 1) -> List[Dict]:
+    """Per rule and package: the fastest of repeat runs, and the findings produced"""
+
+    best: Dict[tuple, RuleTiming] = {}
+    # Timings someone else (--trace) collects keep receiving every run
+    shared = engine.timings
+    for _ in range(repeat):
+        engine.timings = []
+        engine.analyze_sources(sources)
+        for timing in engine.timings:
+            key = (timing.rule_id, timing.package)
+            if key not in best or timing.seconds < best[key].seconds:
+                best[key] = timing
+        if shared is not None:
+            shared.extend(engine.timings)
+    engine.timings = shared
+    return [
+        {"rule_id": t.rule_id, "package": t.package, "seconds": t.seconds, "findings": t.findings}
+        for t in sorted(best.values(), key=lambda t: -t.seconds)
+    ]
+
+def rule_totals(rows: List[Dict]) -> List[Dict]:
+    """Benchmark rows summed per rule, slowest first"""
+
+    totals: Dict[str, Dict] = {}
+    for row in rows:
+        total = totals.setdefault(row["rule_id"], {"rule_id": row["rule_id"], "seconds": 0.0, "findings": 0, "packages": 0})
+        total["seconds"] += row["seconds"]
+        total["findings"] += row["findings"]
+        total["packages"] += 1
+    return sorted(totals.values(), key=lambda t: -t["seconds"])
@@ -0,0 +1,185 @@
+"""
+A long-running OTLP receiver that scores instrumentation as it arrives: services export spans to
+it over OTLP/HTTP or OTLP/gRPC (directly, or through a Collector fanning out a copy), every span
+goes through the exported-telemetry checks, and per-service finding counts and quality scores are
+exposed in the Prometheus text format. Services in any language can be watched this way.
+"""
+
+import json
+import threading
+from concurrent import futures
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from typing import Dict, List, Optional
+
+from .config import Config
+from .score import SEVERITY_WEIGHTS
+from .telemetry import TraceChecker, TraceFinding, parse_spans
+
+TRACE_SERVICE = "opentelemetry.proto.collector.trace.v1.TraceService"
+# Service label for spans whose resource has no service.name
+UNKNOWN_SERVICE = "unknown_service"
+
+def service_score(spans: int, findings: List[TraceFinding], service: str) -> float:
+    """0-100: each span costs the severity weights of its findings, and a span with a critical
+    one costs as much as it can"""
+
+    if not spans:
+        return 100.0
+    penalty = sum(SEVERITY_WEIGHTS.get(f.severity, 1) * f.services.get(service, 0) for f in findings)
+    return round(max(0.0, 100.0 - 100.0 * penalty / (spans * SEVERITY_WEIGHTS["critical"])), 1)
+
+def _label(value: str) -> str:
+    return value.replace("\\", "\\\\").replace("\n", "\\n").replace('"', '\\"')
+
+class ScoringReceiver:
+    """Checks exported spans as they arrive; safe to call from the HTTP and gRPC server threads"""
+
+    def __init__(self, config: Optional[Config] = None):
+        self.checker = TraceChecker(config)
+        self.lock = threading.Lock()
+        self.requests = 0
+        self.rejected = 0
+
+    def export(self, body: bytes) -> int:
+        """Check the spans of one export request; raises ValueError for a malformed one"""
+
+        try:
+            spans = parse_spans(body)
+        except ValueError:
+            with self.lock:
+                self.rejected += 1
+            raise
+        with self.lock:
+            self.requests += 1
+            for span in spans:
+                span.service = span.service or UNKNOWN_SERVICE
+                self.checker.add(span)
+        return len(spans)
+
+    def report(self) -> Dict:
+        with self.lock:
+            findings = self.checker.results()
+            services = dict(self.checker.services)
+            requests, rejected = self.requests, self.rejected
+        return {
+            "requests": requests,
+            "rejected": rejected,
+            "services": {
+                service: {
+                    "spans": spans,
+                    "score": service_score(spans, findings, service),
+                    "findings": [dict(f.to_dict(), spans=f.services[service]) for f in findings if service in f.services],
+                }
+                for service, spans in sorted(services.items())
+            },
+        }
+
+    def prometheus(self) -> str:
+        """The report in the Prometheus text exposition format"""
+
+        report = self.report()
+        lines = [
+            "# HELP ollygarden_export_requests_total OTLP export requests received",
+            "# TYPE ollygarden_export_requests_total counter",
+            f'ollygarden_export_requests_total{{result="accepted"}} {report["requests"]}',
+            f'ollygarden_export_requests_total{{result="rejected"}} {report["rejected"]}',
+            "# HELP ollygarden_spans_total Spans received",
+            "# TYPE ollygarden_spans_total counter",
+        ]
+        services = report["services"]
+        lines += [f'ollygarden_spans_total{{service="{_label(s)}"}} {v["spans"]}' for s, v in services.items()]
+        lines += [
+            "# HELP ollygarden_span_findings_total Spans with a finding, per rule",
+            "# TYPE ollygarden_span_findings_total counter",
+        ]
+        for service, summary in services.items():
+            per_rule: Dict[tuple, int] = {}
+            for f in summary["findings"]:
+                key = (f["rule_id"], f["severity"])
+                per_rule[key] = per_rule.get(key, 0) + f["spans"]
+            lines += [
+                f'ollygarden_span_findings_total{{service="{_label(service)}",rule_id="{rule_id}",'
+                f'severity="{severity}"}} {count}'
+                for (rule_id, severity), count in sorted(per_rule.items())
+            ]
+        lines += [
+            "# HELP ollygarden_quality_score Instrumentation quality score of the spans received (0-100)",
+            "# TYPE ollygarden_quality_score gauge",
+        ]
+        lines += [f'ollygarden_quality_score{{service="{_label(s)}"}} {v["score"]}' for s, v in services.items()]
+        return "\n".join(lines) + "\n"
+
+def otlp_http_server(receiver: ScoringReceiver, host: str, port: int) -> ThreadingHTTPServer:
+    """OTLP/HTTP on /v1/traces, protobuf or JSON; the empty ExportTraceServiceResponse is sent back
+    in the encoding of the request"""
+
+    class Handler(BaseHTTPRequestHandler):
+        def do_POST(self):
+            if self.path.split("?", 1)[0] != "/v1/traces":
+                self.send_error(404)
+                return
+            body = self.rfile.read(int(self.headers.get("Content-Length") or 0))
+            try:
+                receiver.export(body)
+            except ValueError as e:
+                self.send_error(400, f"Malformed OTLP request: {e}")
+                return
+            json_encoded = "json" in (self.headers.get("Content-Type") or "")
+            payload = b"{}" if json_encoded else b""
+            self.send_response(200)
+            self.send_header("Content-Type", "application/json" if json_encoded else "application/x-protobuf")
+            self.send_header("Content-Length", str(len(payload)))
+            self.end_headers()
+            self.wfile.write(payload)
+
+        def log_message(self, format, *args):
+            pass
+
+    return ThreadingHTTPServer((host, port), Handler)
+
+def metrics_server(receiver: ScoringReceiver, host: str, port: int) -> ThreadingHTTPServer:
+    """Prometheus scrape endpoint on /metrics, and the JSON report on /report"""
+
+    class Handler(BaseHTTPRequestHandler):
+        def do_GET(self):
+            path = self.path.split("?", 1)[0]
+            if path == "/metrics":
+                payload, content_type = receiver.prometheus().encode("utf-8"), "text/plain; version=0.0.4"
+            elif path == "/report":
+                payload, content_type = json.dumps(receiver.report(), indent=2).encode("utf-8"), "application/json"
+            else:
+                self.send_error(404)
+                return
+            self.send_response(200)
+            self.send_header("Content-Type", content_type)
+            self.send_header("Content-Length", str(len(payload)))
+            self.end_headers()
+            self.wfile.write(payload)
+
+        def log_message(self, format, *args):
+            pass
+
+    return ThreadingHTTPServer((host, port), Handler)
+
+def otlp_grpc_server(receiver: ScoringReceiver, host: str, port: int):
+    """OTLP/gRPC TraceService/Export, started; None when grpcio isn't installed. Messages are
+    passed through as bytes, so no generated protobuf code is needed."""
+
+    try:
+        import grpc
+    except ImportError:
+        return None
+
+    def export(request: bytes, context) -> bytes:
+        try:
+            receiver.export(request)
+        except ValueError as e:
+            context.abort(grpc.StatusCode.INVALID_ARGUMENT, f"Malformed OTLP request: {e}")
+        return b""
+
+    handler = grpc.method_handlers_generic_handler(TRACE_SERVICE, {"Export": grpc.unary_unary_rpc_method_handler(export)})
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=8))
+    server.add_generic_rpc_handlers((handler,))
+    server.add_insecure_port(f"{host}:{port}")
+    server.start()
+    return server
@@ -0,0 +1,206 @@
+"""
+A redacting SpanProcessor generated from the secret, PII and classified data findings. Every
+attribute key one of them reports is pre-populated in the processor, which replaces the values
+of those keys in span and event attributes before the span reaches the exporter. It is a
+stopgap for the time it takes to fix the call sites: values recorded under other keys, event
+names, log fields and baggage members are not covered and are listed as such.
+"""
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional
+
+from .engine import RuleEngine
+from .golang import GoFile
+from .privacy.sinks import Sink, telemetry_sinks
+from .registry import get_rule
+
+# Rules whose findings are about values that must not be exported
+REDACTED_RULES = ["secret-in-telemetry", "pii-in-telemetry", "classified-data-in-telemetry"]
+# What redacted values are replaced with, as in the Collector processor
+REDACTED_VALUE = "[REDACTED]"
+
+@dataclass
+class RedactedKey:
+    key: str
+    # rule ID -> "path:line" of the findings on the key
+    sites: Dict[str, List[str]] = field(default_factory=dict)
+
+@dataclass
+class Uncovered:
+    """A finding the processor can't redact: not a literal attribute key"""
+    rule_id: str
+    where: str
+    what: str
+    message: str
+
+def _sink_at(source: GoFile, pos: int) -> Optional[Sink]:
+    for sink in telemetry_sinks(source, include_logs=True):
+        start = sink.key.start if sink.key is not None else sink.value.start
+        if start <= pos < sink.value.end:
+            return sink
+    return None
+
+def _relative(path: str, root: str) -> str:
+    try:
+        return Path(path).resolve().relative_to(Path(root).resolve()).as_posix()
+    except ValueError:
+        return path
+
+def redaction_report(sources: List[GoFile], config, root: str = ".") -> Dict:
+    """The attribute keys the privacy rules report, for the processor to redact, and the findings
+    it can't cover"""
+
+    engine = RuleEngine([get_rule(r) for r in REDACTED_RULES], rule_options=config.options,
+                        span_helpers=config.span_helpers)
+    by_path = {s.path: s for s in sources}
+    keys: Dict[str, RedactedKey] = {}
+    uncovered = []
+    for path, violations in engine.analyze_sources(sources).items():
+        source = by_path[path]
+        for v in violations:
+            where = f"{_relative(path, root)}:{v.location.line_number}"
+            pos = source.line_start(v.location.line_number) + v.location.column - 1
+            sink = _sink_at(source, pos)
+            key = sink.key.literal if sink is not None and sink.key is not None else None
+            if sink is not None and sink.what == "attribute" and key:
+                keys.setdefault(key, RedactedKey(key)).sites.setdefault(v.rule_id, []).append(where)
+                continue
+            what = sink.what if sink is not None else "value"
+            if sink is not None and sink.key is not None and not key:
+                what += f" under the computed key {sink.key.text.strip()}"
+            uncovered.append(Uncovered(v.rule_id, where, what, v.description))
+    return {
+        "keys": [keys[k] for k in sorted(keys)],
+        "uncovered": sorted(uncovered, key=lambda u: u.where),
+    }
+
+def _go_string(text: str) -> str:
+    return '"' + text.replace("\\", "\\\\").replace('"', '\\"') + '"'
+
+PROCESSOR = '''
+// NewSpanProcessor returns a SpanProcessor that replaces the values of RedactedKeys in span and
+// event attributes with RedactedValue, then hands the span to next. Register it in place of
+// next:
+//
+//	sdktrace.WithSpanProcessor(%(package)s.NewSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter)))
+func NewSpanProcessor(next sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+	return &spanProcessor{next: next}
+}
+
+type spanProcessor struct {
+	next sdktrace.SpanProcessor
+}
+
+func (p *spanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd can't change the ended span, so the next processor gets a copy with the values replaced.
+func (p *spanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	attributes, changed := redact(s.Attributes())
+	events, eventsChanged := s.Events(), false
+	for i, event := range events {
+		eventAttributes, ok := redact(event.Attributes)
+		if !ok {
+			continue
+		}
+		if !eventsChanged {
+			events = append([]sdktrace.Event(nil), events...)
+			eventsChanged = true
+		}
+		events[i].Attributes = eventAttributes
+	}
+	if !changed && !eventsChanged {
+		p.next.OnEnd(s)
+		return
+	}
+	p.next.OnEnd(redactedSpan{ReadOnlySpan: s, attributes: attributes, events: events})
+}
+
+func (p *spanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *spanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// redactedSpan is an ended span with redacted attributes; the rest comes from the span itself.
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attributes []attribute.KeyValue
+	events     []sdktrace.Event
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue { return s.attributes }
+
+func (s redactedSpan) Events() []sdktrace.Event { return s.events }
+
+// redact returns attrs with the values of RedactedKeys replaced, copying it on the first one,
+// and whether there were any.
+func redact(attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	var redacted []attribute.KeyValue
+	for i, kv := range attrs {
+		if !RedactedKeys[kv.Key] {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]attribute.KeyValue(nil), attrs...)
+		}
+		redacted[i] = kv.Key.String(RedactedValue)
+	}
+	if redacted == nil {
+		return attrs, false
+	}
+	return redacted, true
+}
+'''
+
+def package_name(output: str) -> str:
+    """The Go package name for the output directory: its name, lowercased, without punctuation"""
+
+    name = re.sub(r'[^a-z0-9_]', '', Path(output).resolve().name.lower())
+    return name if name and not name[0].isdigit() else "redact"
+
+def render_processor(report: Dict, package: str) -> str:
+    """redact.go: the keys found and the SpanProcessor replacing their values"""
+
+    lines = [
+        "// Code generated by `otel_cli.py redaction`; DO NOT EDIT.",
+        "",
+        f"// Package {package} redacts span attributes the secret, PII and classified data rules found",
+        "// recorded with sensitive values. It is a stopgap: fix the call sites listed with each key,",
+        "// then remove the processor.",
+        f"package {package}",
+        "",
+        "import (",
+        '\t"context"',
+        "",
+        '\t"go.opentelemetry.io/otel/attribute"',
+        '\tsdktrace "go.opentelemetry.io/otel/sdk/trace"',
+        ")",
+        "",
+        "// RedactedValue replaces the values of redacted attributes.",
+        f"const RedactedValue = {_go_string(REDACTED_VALUE)}",
+        "",
+        "// RedactedKeys are the attribute keys whose values are replaced, with the findings on each.",
+        "var RedactedKeys = map[attribute.Key]bool{",
+    ]
+    for key in report["keys"]:
+        for rule_id, sites in sorted(key.sites.items()):
+            lines.append(f"\t// {rule_id}: {', '.join(sites)}")
+        lines.append(f"\t{_go_string(key.key)}: true,")
+    lines.append("}")
+    if report["uncovered"]:
+        lines += ["", "// Findings no key covers; fix them at the call site:"]
+        lines += [f"//   - {u.where}: {u.what} ({u.rule_id})" for u in report["uncovered"]]
+    return "\n".join(lines) + "\n" + PROCESSOR % {"package": package}
+
+def write_processor(output: str, report: Dict) -> Path:
+    out = Path(output)
+    out.mkdir(parents=True, exist_ok=True)
+    path = out / "redact.go"
+    path.write_text(render_processor(report, package_name(output)), encoding="utf-8")
+    return path
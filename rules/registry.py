@@ -0,0 +1,51 @@
+"""
+Rule registry. Rule modules register their checks with the @rule decorator at import time.
+"""
+
+from typing import Dict, List, Optional, Tuple
+from .base import Rule, SEVERITIES, SIGNALS
+
+_RULES: Dict[str, Rule] = {}
+
+def rule(rule_id: str, title: str, category: str, signal: str, severity: str,
+         description: str, signals: Tuple[str, ...] = (), **kwargs):
+    """Register the decorated check function as a rule. signals adds the other signals a rule
+    checks to its main one; rules of signal "all" check every signal."""
+
+    if severity not in SEVERITIES:
+        raise ValueError(f"Rule {rule_id} has unknown severity '{severity}'")
+    if signal == "all":
+        signals = SIGNALS
+    else:
+        signals = tuple(dict.fromkeys((signal,) + tuple(signals)))
+    unknown = [s for s in signals if s not in SIGNALS]
+    if unknown:
+        raise ValueError(f"Rule {rule_id} has unknown signal '{unknown[0]}'")
+
+    def decorator(check):
+        if rule_id in _RULES:
+            raise ValueError(f"Duplicate rule id '{rule_id}'")
+        _RULES[rule_id] = Rule(
+            rule_id=rule_id,
+            title=title,
+            category=category,
+            signal=signal,
+            severity=severity,
+            description=description,
+            check=check,
+            signals=signals,
+            **kwargs
+        )
+        return check
+
+    return decorator
+
+def all_rules() -> List[Rule]:
+    return sorted(_RULES.values(), key=lambda r: r.rule_id)
+
+def default_rules() -> List[Rule]:
+    """Rules that run without configuration (everything but opt-in rules)"""
+    return [r for r in all_rules() if not r.opt_in]
+
+def get_rule(rule_id: str) -> Optional[Rule]:
+    return _RULES.get(rule_id)
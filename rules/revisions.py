@@ -0,0 +1,142 @@
+"""
+Compare rule findings between two git revisions.
+Sources are read straight from the object database (git cat-file --batch), so neither revision
+needs to be checked out and the working tree is left alone.
+"""
+
+import subprocess
+from collections import Counter
+from pathlib import PurePosixPath
+from typing import Dict, Iterable, List, Optional, Tuple
+
+from .base import TelemetryViolation
+from .context import Context
+from .engine import RuleEngine, ProgressCallback
+from .golang import GoFile
+from .sdk.library import builds_program, is_main
+
+def _git(repo: str, *args: str) -> str:
+    return subprocess.run(["git", "-C", repo, *args], check=True, capture_output=True, text=True).stdout
+
+def parse_range(spec: str) -> Tuple[str, str]:
+    """"base..head" (head defaults to HEAD) or a single ref compared with HEAD"""
+
+    if "..." in spec:
+        raise ValueError("use base..head; symmetric ranges (a...b) are not supported")
+    if ".." in spec:
+        base, head = spec.split("..", 1)
+        return base or "HEAD", head or "HEAD"
+    return spec, "HEAD"
+
+class RevisionTree:
+    """The files of one commit. Module roots are found among the commit's go.mod files, so a
+    go.mod moved or deleted since doesn't change them."""
+
+    def __init__(self, repo: str, ref: str):
+        self.repo = repo
+        self.commit = _git(repo, "rev-parse", "--verify", ref + "^{commit}").strip()
+        self.paths = [p for p in _git(repo, "ls-tree", "-r", "-z", "--name-only", self.commit).split("\0") if p]
+        self.modules = {PurePosixPath(p).parent for p in self.paths if PurePosixPath(p).name == "go.mod"}
+        self._go_mods: Dict[PurePosixPath, str] = {}
+        self._has_main: Dict[PurePosixPath, bool] = {}
+
+    def read(self, paths: Iterable[str]) -> Dict[str, str]:
+        """Contents of paths, in one git cat-file --batch run"""
+
+        # cat-file reads one object name per line
+        paths = [p for p in paths if "\n" not in p]
+        if not paths:
+            return {}
+        request = "".join(f"{self.commit}:{p}\n" for p in paths).encode("utf-8")
+        out = subprocess.run(["git", "-C", self.repo, "cat-file", "--batch"], input=request,
+                             check=True, capture_output=True).stdout
+        contents, pos = {}, 0
+        for path in paths:
+            end = out.index(b"\n", pos)
+            header = out[pos:end].split()
+            pos = end + 1
+            if header[-1] == b"missing":
+                continue
+            size = int(header[2])
+            contents[path] = out[pos:pos + size].decode("utf-8", errors="replace")
+            pos += size + 1
+        return contents
+
+    def module_root(self, path: str) -> Optional[PurePosixPath]:
+        for directory in PurePosixPath(path).parents:
+            if directory in self.modules:
+                return directory
+        return None
+
+    def go_mod(self, root: PurePosixPath) -> str:
+        if root not in self._go_mods:
+            path = (root / "go.mod").as_posix()
+            self._go_mods[root] = self.read([path]).get(path, "")
+        return self._go_mods[root]
+
+    def has_main(self, root: PurePosixPath) -> bool:
+        """Whether the module at root builds a program (rules/sdk/library.py)"""
+
+        if root not in self._has_main:
+            files = [p for p in self.paths if _under(p, root) and builds_program(PurePosixPath(p).relative_to(root))]
+            self._has_main[root] = any(is_main(code) for code in self.read(files).values())
+        return self._has_main[root]
+
+def _under(path: str, directory: PurePosixPath) -> bool:
+    return directory == PurePosixPath(".") or directory in PurePosixPath(path).parents
+
+def load_revision(repo: str, ref: str, subpath: str = "") -> List[GoFile]:
+    """Go sources at ref, optionally limited to a subdirectory, skipping vendored code"""
+
+    tree = RevisionTree(repo, ref)
+    directory = PurePosixPath(subpath or ".")
+    paths = [p for p in tree.paths if p.endswith(".go") and "vendor" not in PurePosixPath(p).parts and _under(p, directory)]
+    sources = []
+    for path, code in tree.read(paths).items():
+        source = GoFile(path, code)
+        source.tree = tree
+        sources.append(source)
+    return sources
+
+def fingerprint(v: TelemetryViolation) -> Tuple:
+    """Identity of a finding that survives unrelated edits shifting line numbers"""
+    return (v.rule_id, v.file_path, v.location.function_name, v.location.code_snippet, v.description)
+
+def diff_findings(base: Dict[str, List[TelemetryViolation]],
+                  head: Dict[str, List[TelemetryViolation]]) -> Dict[str, List[TelemetryViolation]]:
+    """Findings introduced and resolved between two result sets.
+    Identical findings are matched by count, so adding a second copy still shows up."""
+
+    base_all = [v for vs in base.values() for v in vs]
+    head_all = [v for vs in head.values() for v in vs]
+    remaining = Counter(fingerprint(v) for v in base_all)
+    added = []
+    for v in head_all:
+        if remaining[fingerprint(v)] > 0:
+            remaining[fingerprint(v)] -= 1
+        else:
+            added.append(v)
+    remaining = Counter(fingerprint(v) for v in head_all)
+    resolved = []
+    for v in base_all:
+        if remaining[fingerprint(v)] > 0:
+            remaining[fingerprint(v)] -= 1
+        else:
+            resolved.append(v)
+    return {"added": added, "resolved": resolved}
+
+def diff_revisions(repo: str, base_ref: str, head_ref: str, subpath: str = "",
+                   engine: RuleEngine = None, progress: Optional[ProgressCallback] = None,
+                   ctx: Optional[Context] = None, package_timeout: Optional[float] = None) -> Dict:
+    """diff_findings for two revisions, plus "incomplete": packages either side could not finish"""
+
+    engine = engine or RuleEngine()
+
+    def phase(name: str) -> Optional[ProgressCallback]:
+        return (lambda _, done, total: progress(name, done, total)) if progress else None
+
+    base = engine.analyze_sources(load_revision(repo, base_ref, subpath), phase(f"Analyzing {base_ref}"), ctx, package_timeout)
+    incomplete = {f"{base_ref}: {p}": reason for p, reason in engine.incomplete.items()}
+    head = engine.analyze_sources(load_revision(repo, head_ref, subpath), phase(f"Analyzing {head_ref}"), ctx, package_timeout)
+    incomplete.update({f"{head_ref}: {p}": reason for p, reason in engine.incomplete.items()})
+    return dict(diff_findings(base, head), incomplete=incomplete)
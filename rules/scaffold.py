@@ -0,0 +1,147 @@
+"""
+Repository inspection behind `init`: detects frameworks, semantic convention versions
+and tracer names, and renders a starter .ollygarden.yaml from them.
+"""
+
+import re
+from pathlib import Path
+from typing import Dict, List
+
+from .golang import GoFile
+from .registry import all_rules
+
+# go.mod module path prefix -> framework name
+FRAMEWORKS = {
+    "github.com/gin-gonic/gin": "gin",
+    "github.com/labstack/echo": "echo",
+    "github.com/go-chi/chi": "chi",
+    "github.com/gorilla/mux": "gorilla/mux",
+    "github.com/gofiber/fiber": "fiber",
+    "google.golang.org/grpc": "grpc",
+    "github.com/IBM/sarama": "sarama",
+    "github.com/Shopify/sarama": "sarama",
+    "github.com/segmentio/kafka-go": "kafka-go",
+    "github.com/redis/go-redis": "go-redis",
+    "github.com/go-redis/redis": "go-redis",
+    "gorm.io/gorm": "gorm",
+    "github.com/jackc/pgx": "pgx",
+    "github.com/aws/aws-sdk-go-v2": "aws-sdk-go-v2",
+    "github.com/prometheus/client_golang": "prometheus",
+    "go.opencensus.io": "opencensus",
+    "github.com/opentracing/opentracing-go": "opentracing",
+    "github.com/uber/jaeger-client-go": "jaeger-client",
+}
+
+# Signal -> import path prefixes whose presence means the signal is in use
+SIGNAL_IMPORTS = {
+    "traces": ["go.opentelemetry.io/otel/trace", "go.opentelemetry.io/otel/sdk/trace", "go.opencensus.io/trace",
+               "github.com/opentracing/opentracing-go"],
+    "metrics": ["go.opentelemetry.io/otel/metric", "go.opentelemetry.io/otel/sdk/metric", "go.opencensus.io/stats",
+                "github.com/prometheus/client_golang"],
+    "logs": ["go.opentelemetry.io/otel/log", "go.opentelemetry.io/contrib/bridges"],
+    "baggage": ["go.opentelemetry.io/otel/baggage"],
+}
+
+# Directories that are rarely first-party instrumentation
+EXCLUDE_DIRS = ["vendor", "third_party", "testdata", "mocks", "node_modules"]
+# Generated and test code
+EXCLUDE_GLOBS = ["**/*.pb.go", "**/*_gen.go", "**/zz_generated*.go", "**/*_mock.go", "**/*_test.go"]
+
+def go_mod_requires(root: Path) -> Dict[str, str]:
+    """module path -> version from every go.mod under root"""
+
+    requires = {}
+    for go_mod in root.rglob("go.mod"):
+        if "vendor" in go_mod.parts:
+            continue
+        text = go_mod.read_text(encoding="utf-8")
+        for m in re.finditer(r'^\s*(?:require\s+)?([\w.\-/]+\.[\w.\-/]+)\s+(v[\w.\-+]+)', text, re.M):
+            requires[m.group(1)] = m.group(2)
+    return requires
+
+def inspect_repository(root: str) -> Dict:
+    """Facts about a repository that drive the starter configuration"""
+
+    root_path = Path(root)
+    requires = go_mod_requires(root_path)
+    sources = [
+        GoFile(str(p), p.read_text(encoding="utf-8"))
+        for p in sorted(root_path.rglob("*.go")) if "vendor" not in p.parts
+    ]
+
+    frameworks = sorted({name for prefix, name in FRAMEWORKS.items()
+                         if any(mod == prefix or mod.startswith(prefix + "/") for mod in requires)})
+    semconv_versions = sorted({
+        m.group(1) for s in sources for path in s.imports.values()
+        for m in [re.search(r'/semconv/(v[\d.]+)', path)] if m
+    })
+    tracer_names = sorted({
+        m.group(1) for s in sources
+        for m in re.finditer(r'\.Tracer\s*\(\s*"([^"]+)"', s.code)
+    })
+    signals = sorted({signal for signal, prefixes in SIGNAL_IMPORTS.items()
+                      if any(s.imports_path(prefix) for s in sources for prefix in prefixes)})
+    excludes = [d + "/" for d in EXCLUDE_DIRS if any(p.is_dir() for p in root_path.rglob(d))]
+    excludes += [g for g in EXCLUDE_GLOBS if any(root_path.glob(g))]
+
+    return {
+        "otel_version": requires.get("go.opentelemetry.io/otel", ""),
+        "frameworks": frameworks,
+        "semconv_versions": semconv_versions,
+        "tracer_names": tracer_names,
+        "signals": signals,
+        "exclude": excludes,
+        "files": len(sources),
+    }
+
+def starter_rules(facts: Dict) -> Dict[str, List[str]]:
+    """Rules to enable and disable: rules for signals the repo doesn't use are turned off,
+    except migration rules, which stay on exactly when the legacy library is present"""
+
+    legacy = {"opencensus", "opentracing", "jaeger-client"} & set(facts["frameworks"])
+    disabled = []
+    for r in all_rules():
+        if r.opt_in:
+            continue
+        if r.category == "migration":
+            if not any(lib in r.rule_id for lib in legacy):
+                disabled.append(r.rule_id)
+        elif not r.covers(facts["signals"]):
+            disabled.append(r.rule_id)
+    return {"disable": disabled}
+
+def render_config(facts: Dict) -> str:
+    """Starter .ollygarden.yaml with the detected facts recorded as comments"""
+
+    def listing(items: List[str]) -> str:
+        return ", ".join(items) if items else "none"
+
+    selection = starter_rules(facts)
+    lines = [
+        "# ollygarden configuration, generated by `otel_cli.py init`.",
+        f"# Detected frameworks: {listing(facts['frameworks'])}",
+        f"# OpenTelemetry Go: {facts['otel_version'] or 'not in go.mod'}; "
+        f"semconv packages: {listing(facts['semconv_versions'])}",
+        f"# Tracer names: {listing(facts['tracer_names'])}",
+        f"# Signals in use: {listing(facts['signals'])}",
+        "",
+        "rules:",
+        "  # Rules for signals and legacy libraries this repository doesn't use.",
+    ]
+    if selection["disable"]:
+        lines.append("  disable:")
+        lines.extend(f"    - {rule_id}" for rule_id in selection["disable"])
+    else:
+        lines.append("  disable: []")
+    lines += [
+        "  # Per-rule severity overrides, e.g.",
+        "  #   attribute-set-rebuilt: medium",
+        "  severity: {}",
+        "",
+        "exclude:",
+    ]
+    if facts["exclude"]:
+        lines.extend(f'  - "{pattern}"' for pattern in facts["exclude"])
+    else:
+        lines[-1] = "exclude: []"
+    return "\n".join(lines) + "\n"
@@ -0,0 +1,41 @@
+"""
+Aggregate quality score for a set of analyzed files.
+
+Each finding costs its severity weight; the cost is normalized by code size so large
+codebases aren't punished for being large. A category with no findings scores 100.
+"""
+
+from typing import Dict, Iterable, List
+
+from .base import TelemetryViolation
+from .registry import all_rules
+
+SEVERITY_WEIGHTS = {"critical": 10, "high": 5, "medium": 2, "low": 1}
+
+# Weighted findings per 1000 lines that bring a score down to 0
+ZERO_SCORE_PENALTY = 50
+
+def _score(violations: List[TelemetryViolation], kloc: float) -> float:
+    penalty = sum(SEVERITY_WEIGHTS.get(v.severity, 1) for v in violations)
+    return round(max(0.0, 100.0 - 100.0 * penalty / (ZERO_SCORE_PENALTY * kloc)), 1)
+
+def quality_score(results: Dict[str, List[TelemetryViolation]], total_lines: int) -> Dict:
+    """Overall score and per-category subscores (0-100) for engine results"""
+
+    violations = [v for vs in results.values() for v in vs]
+    # Below 1000 lines a single finding shouldn't dominate the score
+    kloc = max(1.0, total_lines / 1000.0)
+
+    categories = sorted({r.category for r in all_rules()} | {v.violation_type for v in violations})
+    by_category = {}
+    for category in categories:
+        found = [v for v in violations if v.violation_type == category]
+        by_category[category] = {"score": _score(found, kloc), "findings": len(found)}
+
+    return {
+        "score": _score(violations, kloc),
+        "files": len(results),
+        "lines": total_lines,
+        "findings": len(violations),
+        "categories": by_category,
+    }
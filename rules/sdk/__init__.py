@@ -0,0 +1,5 @@
+"""
+SDK setup, exporter and legacy API rules
+"""
+
+from . import legacy, exporters, lifecycle, library
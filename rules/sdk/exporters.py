@@ -0,0 +1,120 @@
+"""
+Exporter setup rules
+"""
+
+import re
+from typing import Iterator, Optional
+from urllib.parse import urlparse
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+
+JAEGER_EXPORTER = "go.opentelemetry.io/otel/exporters/jaeger"
+JAEGER_CLIENT = "github.com/uber/jaeger-client-go"
+JAEGER_LIB = "github.com/uber/jaeger-lib"
+
+OTLP_REPLACEMENT = (
+    "otlptracegrpc.New(ctx) (go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc) "
+    "with OTEL_EXPORTER_OTLP_ENDPOINT pointing at a collector or Jaeger's OTLP port (4317 gRPC, 4318 HTTP)"
+)
+
+def _otlp_endpoint(jaeger_endpoint: str) -> Optional[str]:
+    """OTLP endpoint on the same host as a Jaeger collector/agent endpoint"""
+
+    url = urlparse(jaeger_endpoint if "://" in jaeger_endpoint else "//" + jaeger_endpoint)
+    if not url.hostname:
+        return None
+    # 14268 is the Jaeger collector's Thrift HTTP port and 6831/6832 the agent's UDP ports
+    return f"{url.hostname}:4317"
+
+def _endpoint_hint(source: GoFile) -> str:
+    for call in source.calls(r'[\w.]*\.(?:WithEndpoint|WithAgentHost|WithCollectorEndpoint)'):
+        for arg in call.args:
+            literal = arg.literal
+            endpoint = _otlp_endpoint(literal) if literal else None
+            if endpoint:
+                return f'; e.g. otlptracegrpc.WithEndpoint("{endpoint}") for the endpoint configured here'
+    return ""
+
+@rule(
+    rule_id="jaeger-exporter-deprecated",
+    title="Replace the Jaeger exporter/client with OTLP",
+    category="sdk",
+    signal="traces",
+    severity="high",
+    description="The OpenTelemetry Jaeger exporter was removed and jaeger-client-go is archived. "
+                "Jaeger and the Collector accept OTLP natively, and the legacy Thrift endpoints are "
+                "disabled in modern deployments, so these exporters stop delivering spans without erroring.",
+    bad_example='''
+func newJaegerExporter() (*jaeger.Exporter, error) {
+	return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint("http://jaeger:14268/api/traces")))
+}''',
+    good_example='''
+func newOTLPExporter(ctx context.Context) (*otlptrace.Exporter, error) {
+	return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint("jaeger:4317"), otlptracegrpc.WithInsecure())
+}''',
+)
+def check_jaeger_exporter(source: GoFile) -> Iterator[Diagnostic]:
+    hint = _endpoint_hint(source)
+    for prefix, what in ((JAEGER_EXPORTER, "removed OpenTelemetry Jaeger exporter"),
+                         (JAEGER_CLIENT, "archived jaeger-client-go"),
+                         (JAEGER_LIB, "archived jaeger-lib")):
+        for pos, path in source.import_positions(prefix):
+            # "github.com/uber/jaeger-client-go" is also a prefix of unrelated paths
+            if not re.fullmatch(re.escape(prefix) + r'(?:/.*)?', path):
+                continue
+            yield Diagnostic(
+                pos=pos,
+                message=f"Import of the {what} ({path})",
+                suggestion=f"Export with {OTLP_REPLACEMENT}{hint}",
+                confidence=0.95,
+            )
+            # Point at the constructors too, which is where the replacement goes
+            for alias in [n for n, p in source.imports.items() if p == path]:
+                for call in source.calls(re.escape(alias) + r'\.(?:New\w*|Configuration)'):
+                    yield Diagnostic(
+                        pos=call.start,
+                        message=f"{call.name} from the {what}",
+                        suggestion=f"Export with {OTLP_REPLACEMENT}{hint}",
+                        confidence=0.95,
+                    )
+
+STDOUT_EXPORTERS = "go.opentelemetry.io/otel/exporters/stdout/"
+STDOUT_ITEMS = {"stdouttrace": "span", "stdoutmetric": "metric data point", "stdoutlog": "log record"}
+
+@rule(
+    rule_id="stdout-exporter",
+    title="Don't ship stdout exporters",
+    category="sdk",
+    signal="traces",
+    signals=("metrics", "logs"),
+    severity="medium",
+    description="The stdouttrace, stdoutmetric and stdoutlog exporters pretty-print every span, data point "
+                "or record to standard output. They are meant for local debugging: in a deployment they "
+                "flood the application's logs, block on the output and send nothing to a backend. Keep them "
+                "behind a development profile, or disable this rule for dev in a config profile.",
+    bad_example='''
+func newTraceExporter() (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithPrettyPrint())
+}''',
+    good_example='''
+func newTraceExporterOTLP(ctx context.Context) (sdktrace.SpanExporter, error) {
+	return otlptracegrpc.New(ctx)
+}''',
+)
+def check_stdout_exporter(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    for pos, path in source.import_positions(STDOUT_EXPORTERS):
+        calls = [call for alias in [n for n, p in source.imports.items() if p == path]
+                 for call in source.calls(re.escape(alias) + r'\.New')]
+        item = STDOUT_ITEMS.get(path.rsplit("/", 1)[1], "telemetry item")
+        for call in calls or [None]:
+            yield Diagnostic(
+                pos=call.start if call else pos,
+                message=f"{call.name if call else path} writes every {item} to stdout instead of a backend",
+                suggestion="Export over OTLP (otlptracegrpc/otlpmetricgrpc/otlploggrpc) to a collector; keep stdout "
+                           "exporters to local development",
+                confidence=0.9,
+            )
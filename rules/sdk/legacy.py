@@ -0,0 +1,142 @@
+"""
+Legacy instrumentation APIs that should be migrated to OpenTelemetry
+"""
+
+from typing import Iterator, List
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..migration import opencensus_call_sites, opentracing_call_sites, OC_BRIDGE, OT, OT_BRIDGE
+from ..registry import rule
+
+def _oc_sites(source: GoFile, prefixes) -> Iterator[Diagnostic]:
+    for site in opencensus_call_sites(source):
+        call_path = site.mapping.legacy_call
+        if not any(call_path.startswith(p) for p in prefixes):
+            continue
+        yield Diagnostic(
+            pos=site.pos,
+            message=f"OpenCensus {site.mapping.legacy_call} call",
+            suggestion=f"Replace with {site.mapping.otel_equivalent}",
+            confidence=0.95,
+        )
+
+@rule(
+    rule_id="opencensus-trace-api",
+    title="Migrate OpenCensus tracing to OpenTelemetry",
+    category="migration",
+    signal="traces",
+    severity="medium",
+    description="OpenCensus is archived; its trace API and ochttp/ocgrpc plugins should be replaced "
+                "with the OpenTelemetry API and instrumentation libraries.",
+    bad_example='''
+func legacyHandler(ctx context.Context) {
+	ctx, span := octrace.StartSpan(ctx, "GET /orders")
+	defer span.End()
+	span.AddAttributes(octrace.StringAttribute("order.status", "open"))
+}''',
+    good_example='''
+func migratedHandler(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "GET /orders")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.status", "open"))
+}''',
+)
+def check_opencensus_trace(source: GoFile) -> Iterator[Diagnostic]:
+    if not source.imports_path("go.opencensus.io"):
+        return
+    yield from _oc_sites(source, ("trace.", "span.", "ochttp.", "ocgrpc."))
+
+@rule(
+    rule_id="opencensus-stats-api",
+    title="Migrate OpenCensus stats and tags to OpenTelemetry metrics",
+    category="migration",
+    signal="metrics",
+    severity="medium",
+    description="OpenCensus measures, views and tags map to OpenTelemetry instruments, "
+                "MeterProvider views and metric attributes.",
+    bad_example='''
+var latencyMs = stats.Float64("checkout/latency", "Checkout latency", stats.UnitMilliseconds)
+
+func recordLatency(ctx context.Context, ms float64) {
+	stats.Record(ctx, latencyMs.M(ms))
+}''',
+    good_example='''
+func recordLatencyOTel(ctx context.Context, h metric.Float64Histogram, seconds float64) {
+	h.Record(ctx, seconds)
+}''',
+)
+def check_opencensus_stats(source: GoFile) -> Iterator[Diagnostic]:
+    if not source.imports_path("go.opencensus.io"):
+        return
+    yield from _oc_sites(source, ("stats.", "view.", "tag."))
+
+@rule(
+    rule_id="opencensus-bridge",
+    title="OpenCensus bridge is a temporary measure",
+    category="migration",
+    signal="traces",
+    severity="low",
+    description="The OpenCensus bridge keeps legacy call sites working during a migration; "
+                "it should be removed once no OpenCensus calls remain.",
+    bad_example='''
+func installBridge(tp *sdktrace.TracerProvider) {
+	opencensus.InstallTraceBridge()
+}''',
+    good_example='''
+func noBridge(tp *sdktrace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+}''',
+)
+def check_opencensus_bridge(source: GoFile) -> Iterator[Diagnostic]:
+    if not source.imports_path(OC_BRIDGE):
+        return
+    yield from _oc_sites(source, ("opencensus.",))
+
+@rule(
+    rule_id="opentracing-api",
+    title="OpenTracing used alongside OpenTelemetry",
+    category="migration",
+    signal="traces",
+    severity="medium",
+    description="Modules that already use OpenTelemetry but still call opentracing-go produce two "
+                "disconnected traces unless the OpenTracing bridge is installed. Migrate the call sites, "
+                "or install the bridge until they are migrated.",
+    scope="project",
+    bad_example='''
+func legacyLookup(ctx context.Context, userID string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "lookupUser")
+	defer span.Finish()
+	span.SetTag("userID", userID)
+}
+
+func otelLookup(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "lookup user")
+	defer span.End()
+}''',
+    good_example='''
+func migratedLookup(ctx context.Context, userID string) {
+	ctx, span := tracer.Start(ctx, "lookup user")
+	defer span.End()
+	span.SetAttributes(attribute.String("user.id", userID))
+}''',
+)
+def check_opentracing(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    uses_otel = any(s.imports_path("go.opentelemetry.io/otel") for s in sources)
+    if not uses_otel:
+        return
+    bridged = any(s.imports_path(OT_BRIDGE) for s in sources)
+    for source in sources:
+        if not source.imports_path(OT):
+            continue
+        for site in opentracing_call_sites(source):
+            suggestion = f"Replace with {site.mapping.otel_equivalent}"
+            if not bridged:
+                suggestion += "; until then install the OpenTracing bridge (go.opentelemetry.io/otel/bridge/opentracing) so both APIs share one trace"
+            yield Diagnostic(
+                pos=site.pos,
+                message=f"OpenTracing {site.mapping.legacy_call} call in a module that uses OpenTelemetry",
+                suggestion=suggestion,
+                confidence=0.9,
+                file=source,
+            )
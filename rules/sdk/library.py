@@ -0,0 +1,325 @@
+"""
+Library code: packages other programs import. A library instruments itself against the API and
+leaves the SDK, the global providers and the exporters to the application that links it, which
+is the only place that knows where telemetry goes and how it's sampled.
+
+Whether sources are library code is decided per package, by its import path: a package other
+than main is library code when programs outside its module can import it, and an internal
+package when its module builds no program (example mains aside). So a library's example/ or
+cmd/ main doesn't exempt the library, and a service's internal packages aren't libraries.
+"""
+
+import re
+from functools import lru_cache
+from pathlib import Path, PurePath, PurePosixPath
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile
+from ..registry import rule
+from ..semconv import SEMCONV_PKG
+
+SDK = "go.opentelemetry.io/otel/sdk"
+EXPORTERS = "go.opentelemetry.io/otel/exporters/"
+GLOBAL_SETTERS = (r'(?:otel|global)\.(?:SetTracerProvider|SetMeterProvider|SetLoggerProvider'
+                  r'|SetTextMapPropagator|SetErrorHandler)')
+# Methods of SDK extension points: a file implementing them is part of an SDK plugin, which needs the SDK
+PLUGIN_METHOD = (r'\bfunc\s*\([^)]*\)\s*(?:OnStart|OnEnd|ExportSpans|ShouldSample|OnEmit)\s*\('
+                 r'|\bfunc\s*\([^)]*\)\s*Export\s*\([^)]*\bmetricdata\.')
+
+def module_root(source: GoFile) -> Optional[PurePath]:
+    """Directory of the go.mod the file belongs to, None outside a module. Sources read from a
+    git revision (source.tree) are looked up in that revision's tree, not on disk."""
+
+    if source.tree is not None:
+        return source.tree.module_root(source.path)
+    for directory in Path(source.path).resolve().parents:
+        if (directory / "go.mod").is_file():
+            return directory
+    return None
+
+def module_dir(source: GoFile, root: PurePath) -> PurePath:
+    """Directory of the file relative to its module root"""
+
+    if source.tree is not None:
+        return PurePosixPath(source.path).parent.relative_to(root)
+    return Path(source.path).resolve().parent.relative_to(root)
+
+def module_file(source: GoFile, root: PurePath) -> str:
+    """Contents of the go.mod at root"""
+
+    if source.tree is not None:
+        return source.tree.go_mod(root)
+    return _read_go_mod(root)
+
+@lru_cache(maxsize=None)
+def _read_go_mod(root: Path) -> str:
+    return (root / "go.mod").read_text(encoding="utf-8")
+
+# Directories whose main packages are examples of a library rather than programs built from it
+EXAMPLE_DIRS = {"example", "examples", "_examples", "testdata"}
+
+def builds_program(path: PurePath) -> bool:
+    """Whether a file, relative to its module root, can be part of a program the module builds"""
+
+    parts = path.parts
+    return (path.suffix == ".go" and not path.name.endswith("_test.go")
+            and "vendor" not in parts and not EXAMPLE_DIRS & set(parts))
+
+def is_main(code: str) -> bool:
+    return bool(re.search(r'^package\s+main\b', code[:4096], re.M))
+
+@lru_cache(maxsize=None)
+def _module_has_main(root: Path) -> bool:
+    for f in root.rglob("*.go"):
+        if not builds_program(f.relative_to(root)):
+            continue
+        try:
+            head = f.read_text(encoding="utf-8", errors="replace")[:4096]
+        except OSError:
+            continue
+        if is_main(head):
+            return True
+    return False
+
+def is_library(source: GoFile) -> bool:
+    """Whether the file's package is library code"""
+
+    if source.package == "main":
+        return False
+    root = module_root(source)
+    if root is None:
+        return True
+    if "internal" not in module_dir(source, root).parts:
+        return True
+    if source.tree is not None:
+        return not source.tree.has_main(root)
+    return not _module_has_main(root)
+
+def library_sources(sources: List[GoFile]) -> List[GoFile]:
+    """The non-test files of sources whose package is library code"""
+
+    return [s for s in sources if not s.path.endswith("_test.go") and is_library(s)]
+
+def _plugin(source: GoFile) -> bool:
+    return bool(re.search(PLUGIN_METHOD, source.masked))
+
+@rule(
+    rule_id="library-depends-on-sdk",
+    title="Instrument libraries against the API, not the SDK",
+    category="sdk",
+    signal="all",
+    severity="high",
+    scope="project",
+    description="A library that imports go.opentelemetry.io/otel/sdk forces its SDK version, and often its "
+                "provider setup, on every program that links it, and its telemetry bypasses whatever provider "
+                "the application configured. Libraries take a trace.TracerProvider or metric.MeterProvider "
+                "option, defaulting to otel.GetTracerProvider(), and use only the API packages. Files that "
+                "implement SDK extension points (span processors, exporters, samplers) are exempt.",
+    bad_example='''
+type Client struct {
+	tracer trace.Tracer
+}
+
+func NewClient() *Client {
+	tp := sdktrace.NewTracerProvider()
+	return &Client{tracer: tp.Tracer("example.com/client")}
+}''',
+    good_example='''
+type Store struct {
+	tracer trace.Tracer
+}
+
+func NewStore(tp trace.TracerProvider) *Store {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Store{tracer: tp.Tracer("example.com/store")}
+}''',
+)
+def check_library_depends_on_sdk(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for source in library_sources(sources):
+        if _plugin(source):
+            continue
+        for pos, path in source.import_positions(SDK):
+            aliases = [n for n, p in source.imports.items() if p == path]
+            uses = [re.compile(r'(?<![\w.])' + re.escape(alias) + r'\.\w+').search(source.masked, source.decl_insert_pos())
+                    for alias in aliases]
+            use = min((m for m in uses if m), key=lambda m: m.start(), default=None)
+            yield Diagnostic(
+                pos=use.start() if use else pos,
+                message=f"Library package {source.package} imports the SDK ({path}), pinning it and "
+                        f"bypassing the application's provider",
+                suggestion="Depend on the API (go.opentelemetry.io/otel/trace, /metric) and accept a provider "
+                           "option that defaults to otel.GetTracerProvider()/otel.GetMeterProvider()",
+                confidence=0.85,
+                file=source,
+            )
+
+@rule(
+    rule_id="library-sets-global-provider",
+    title="Leave the global providers and propagator to the application",
+    category="sdk",
+    signal="all",
+    severity="high",
+    scope="project",
+    description="otel.SetTracerProvider, SetMeterProvider, SetTextMapPropagator and SetErrorHandler replace "
+                "process-wide state. Called from a library, they overwrite the application's configuration "
+                "(or get overwritten by it) depending on initialization order, so telemetry silently goes to "
+                "the wrong place or trace context stops propagating.",
+    bad_example='''
+func Instrument(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+}''',
+    good_example='''
+func injectHeaders(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}''',
+)
+def check_library_sets_global_provider(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for source in library_sources(sources):
+        for call in source.calls(GLOBAL_SETTERS):
+            setter = call.name.rsplit(".", 1)[1]
+            getter = setter.replace("Set", "Get", 1)
+            yield Diagnostic(
+                pos=call.start,
+                end=call.end,
+                message=f"Library package {source.package} calls {call.name}, replacing the application's "
+                        f"process-wide configuration",
+                suggestion=f"Remove it and read otel.{getter}() where needed, or take the value as an option"
+                           if setter != "SetErrorHandler" else "Remove it and return or log errors instead",
+                confidence=0.9,
+                file=source,
+            )
+
+@rule(
+    rule_id="library-configures-exporter",
+    title="Don't configure exporters in library code",
+    category="sdk",
+    signal="all",
+    severity="high",
+    scope="project",
+    description="Where telemetry is sent (OTLP endpoint, headers, protocol, stdout) is a deployment decision. "
+                "A library that builds an exporter sends its telemetry to a destination the application "
+                "can't change, opens connections nobody shuts down, and duplicates the application's own "
+                "pipeline.",
+    bad_example='''
+func NewQueue(ctx context.Context) (*Queue, error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{exporter: exporter}, nil
+}''',
+    good_example='''
+func NewWorkQueue(tp trace.TracerProvider) *WorkQueue {
+	return &WorkQueue{tracer: tp.Tracer("example.com/queue")}
+}''',
+)
+def check_library_configures_exporter(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for source in library_sources(sources):
+        if _plugin(source):
+            continue
+        for pos, path in source.import_positions(EXPORTERS):
+            aliases = [n for n, p in source.imports.items() if p == path]
+            calls = [call for alias in aliases for call in source.calls(re.escape(alias) + r'\.New\w*')]
+            for call in calls or [None]:
+                yield Diagnostic(
+                    pos=call.start if call else pos,
+                    message=f"Library package {source.package} configures an exporter "
+                            f"({call.name if call else path}), which only the application should do",
+                    suggestion="Remove the exporter and take a provider option; the application's SDK setup "
+                               "decides where telemetry goes",
+                    confidence=0.85,
+                    file=source,
+                )
+
+# The schema URL's semconv version, as a literal or a semconv package's SchemaURL
+SCHEMA_VERSION = re.compile(r'schemas/(\d+(?:\.\d+)*)')
+
+def _semconv_version(path: str) -> Optional[str]:
+    m = re.fullmatch(re.escape(SEMCONV_PKG) + r'/v(\d+(?:\.\d+)*)', path)
+    return m.group(1) if m else None
+
+@rule(
+    rule_id="library-tracer-scope",
+    title="Give library tracers a version and the schema URL of their semconv",
+    category="sdk",
+    signal="traces",
+    severity="low",
+    scope="project",
+    description="The instrumentation scope is how a backend tells which library, and which release of it, "
+                "produced a span. Without trace.WithInstrumentationVersion spans of two releases can't be "
+                "told apart when their attributes change; without trace.WithSchemaURL the Collector's "
+                "schema processor and backends can't translate the attribute names to the conventions they "
+                "use. The schema URL has to be the one of the semconv package the library takes its keys "
+                "from (semconv.SchemaURL of that import), or it claims names the spans don't use.",
+    bad_example='''
+type Cache struct {
+	tracer trace.Tracer
+}
+
+func NewCache(tp trace.TracerProvider) *Cache {
+	return &Cache{tracer: tp.Tracer("example.com/cache",
+		trace.WithSchemaURL("https://opentelemetry.io/schemas/1.20.0"))}
+}''',
+    good_example='''
+type Index struct {
+	tracer trace.Tracer
+}
+
+func NewIndex(tp trace.TracerProvider) *Index {
+	return &Index{tracer: tp.Tracer("example.com/index",
+		trace.WithInstrumentationVersion(Version), trace.WithSchemaURL(semconv.SchemaURL))}
+}''',
+)
+def check_library_tracer_scope(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for source in library_sources(sources):
+        # The semconv versions the package takes its keys from
+        imported = {_semconv_version(path) for s in source.package_sources for alias, path in s.imports.items()
+                    if _semconv_version(path)
+                    and re.search(r'(?<![\w.])' + re.escape(alias) + r'\.(?!SchemaURL\b)\w', s.masked)}
+        for call in source.calls(r'(?:\w+(?:\(\s*\))?\.)+Tracer'):
+            if not call.args or call.name.split(".")[0] in ("trace", "sdktrace"):
+                continue
+            options = call.args[1:]
+            texts = [source.code[a.start:a.end] for a in options]
+            if not any(re.search(r'\bWithInstrumentationVersion\s*\(', t) for t in texts):
+                yield Diagnostic(
+                    pos=call.start,
+                    end=call.end,
+                    message=f"Tracer {call.args[0].text.strip()} has no instrumentation version",
+                    suggestion="Pass trace.WithInstrumentationVersion with the library's release version",
+                    confidence=0.8,
+                    file=source,
+                )
+            schema = next((t for t in texts if re.search(r'\bWithSchemaURL\s*\(', t)), None)
+            if schema is None:
+                yield Diagnostic(
+                    pos=call.start,
+                    end=call.end,
+                    message=f"Tracer {call.args[0].text.strip()} has no schema URL",
+                    suggestion="Pass trace.WithSchemaURL(semconv.SchemaURL) from the semconv package the "
+                               "library's attribute keys come from",
+                    confidence=0.8,
+                    file=source,
+                )
+                continue
+            version = None
+            literal = SCHEMA_VERSION.search(schema)
+            alias = re.search(r'\bWithSchemaURL\s*\(\s*(\w+)\.SchemaURL\b', schema)
+            if literal:
+                version = literal.group(1)
+            elif alias and alias.group(1) in source.imports:
+                version = _semconv_version(source.imports[alias.group(1)])
+            if version is None or not imported or version in imported:
+                continue
+            yield Diagnostic(
+                pos=call.start,
+                end=call.end,
+                message=f"Tracer {call.args[0].text.strip()} declares schema {version}, but the package's "
+                        f"attribute keys come from semconv {', '.join('v' + v for v in sorted(imported))}",
+                suggestion="Use the SchemaURL of the semconv package the keys are imported from",
+                confidence=0.85,
+                file=source,
+            )
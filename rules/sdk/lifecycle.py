@@ -0,0 +1,255 @@
+"""
+Provider lifecycle: buffered telemetry is only exported if Shutdown runs before the process exits
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc
+from ..registry import rule
+
+PROVIDER_CONSTRUCTORS = r'[\w.]+\.(?:NewTracerProvider|NewMeterProvider|NewLoggerProvider)'
+
+# os.Exit and loggers' Fatal helpers (which call os.Exit), but not testing.T.Fatal
+EXITS = r'\bos\.Exit\s*\(|\b(?:log|klog|logrus|\w*[lL]og\w*|zap\.L\(\)|zap\.S\(\))\.Fatal\w*\s*\('
+SIGNAL_HANDLING = r'\bsignal\.(?:Notify|NotifyContext)\s*\('
+SHUTDOWN_SEQUENCE = SIGNAL_HANDLING + r'|\berrgroup\.|<-\s*[\w.]+(?:\(\))?'
+
+SUGGESTED_PATTERN = (
+    "ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM); defer stop(); "
+    "run until <-ctx.Done(), then call Shutdown with a fresh timeout context before returning from main"
+)
+
+@dataclass
+class Provider:
+    source: GoFile
+    var: str
+    pos: int
+    kind: str
+    func: GoFunc
+
+    @property
+    def label(self) -> str:
+        return self.var or f"returned by {self.func.name}"
+
+def providers(sources: List[GoFile]) -> Iterator[Provider]:
+    for source in sources:
+        for call in source.calls(PROVIDER_CONSTRUCTORS):
+            # Assigned (tp := ...) or returned straight to the caller
+            m = re.search(r'(?:(\w+)\s*:?=|\breturn)\s*(?:&\s*)?$', source.statement_prefix(call.start))
+            fn = source.func_at(call.start)
+            if m and fn:
+                kind = re.search(r'New(\w+Provider)', call.name).group(1)
+                yield Provider(source, m.group(1) or "", call.start, kind, fn)
+
+def _handles(sources: List[GoFile], provider: Provider) -> List[tuple]:
+    """(source, regex, function or None) for expressions that shut the provider down: its own Shutdown
+    inside the function creating it, and the results of that function when it hands the provider or
+    its Shutdown to the caller"""
+
+    fn = provider.func
+    if not provider.var:
+        handles = []
+    else:
+        handles = [(provider.source, re.escape(provider.var) + r'\.Shutdown\b', fn)]
+        body = provider.source.masked[fn.body_start:fn.body_end]
+        hands_off = re.search(r'\breturn\b[^\n]*\b' + re.escape(provider.var) + r'\b', body) or \
+            re.search(re.escape(provider.var) + r'\.Shutdown\b(?!\s*\()', body)
+        if not hands_off:
+            return handles
+    for source in sources:
+        for m in re.finditer(r'(\w+)(?:\s*,\s*\w+)*\s*:?=\s*(?:\w+\.)?' + re.escape(fn.name) + r'\s*\(', source.masked):
+            handles.append((source, r'(?<![\w.])' + re.escape(m.group(1)) + r'(?:\.Shutdown)?\s*\(', None))
+    return handles
+
+def _deferred(source: GoFile, pos: int) -> bool:
+    if re.search(r'\bdefer\s+$', source.statement_prefix(pos)):
+        return True
+    literal = source.func_at(pos, include_literals=True)
+    return bool(literal and literal.is_literal and re.search(r'\bdefer\s+$', source.masked[:literal.start]))
+
+def _unflushed_exits(source: GoFile, start: int, end: int) -> Iterator[int]:
+    """Offsets of exits in source[start:end] that no explicit (non-deferred) Shutdown or ForceFlush precedes"""
+
+    flush = r'(?<![\w.])[\w.]+\.(?:Shutdown|ForceFlush)\s*\('
+    for m in re.finditer(EXITS, source.masked[start:end]):
+        exit_pos = start + m.start()
+        if not any(not _deferred(source, start + f.start()) for f in re.finditer(flush, source.masked[start:exit_pos])):
+            yield exit_pos
+
+def shutdown_gap(sources: List[GoFile], provider: Provider) -> Optional[str]:
+    """Why the provider's Shutdown may not run, or None when it is wired into the exit path"""
+
+    handled_signals = any(re.search(SIGNAL_HANDLING, s.masked) for s in sources)
+    gaps = []
+    for source, pattern, within in _handles(sources, provider):
+        start, end = (within.body_start, within.body_end) if within else (0, len(source.masked))
+        for m in re.finditer(pattern, source.masked[start:end]):
+            pos = start + m.start()
+            owner = source.func_at(pos)
+            if owner is None or owner is provider.func and pos < provider.pos:
+                continue
+            owner_body = source.masked[owner.body_start:owner.body_end]
+            if not _deferred(source, pos):
+                # An explicit call is a shutdown sequence when the function waits for the end first
+                if re.search(SHUTDOWN_SEQUENCE, owner_body) or owner.name != "main":
+                    return None
+                gaps.append(f"Shutdown is called at the end of {owner.name} without waiting for a signal")
+                continue
+            exit_pos = next(_unflushed_exits(source, owner.body_start, owner.body_end), None)
+            if exit_pos is not None:
+                exit_call = re.match(EXITS, source.masked[exit_pos:]).group(0).rstrip("( ")
+                gaps.append(f"the deferred Shutdown in {owner.name} is skipped by "
+                            f"{exit_call} on line {source.line_of(exit_pos)}")
+            elif not handled_signals:
+                gaps.append(f"Shutdown is only deferred in {owner.name} and no signal handling lets it "
+                            f"return on SIGTERM/SIGINT")
+            else:
+                return None
+    return gaps[0] if gaps else "Shutdown is never called"
+
+@rule(
+    rule_id="provider-shutdown-not-wired",
+    title="Wire provider Shutdown into the program's exit path",
+    category="sdk",
+    signal="traces",
+    signals=("metrics", "logs"),
+    severity="high",
+    scope="project",
+    description="TracerProvider, MeterProvider and LoggerProvider buffer telemetry and only flush it in "
+                "Shutdown. A bare defer in main doesn't run on os.Exit or log.Fatal, nor when SIGTERM kills "
+                "the process, so the last batches (often the ones explaining a crash or a deploy) are lost.",
+    bad_example='''
+func main() {
+	exp, _ := otlptracegrpc.New(context.Background())
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+	if err := http.ListenAndServe(":8080", nil); err != nil {
+		log.Fatal(err)
+	}
+}''',
+    good_example='''
+func serve() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	exp, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp))
+	otel.SetTracerProvider(tp)
+	srv := &http.Server{Addr: ":8080"}
+	go srv.ListenAndServe()
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
+	return tp.Shutdown(shutdownCtx)
+}''',
+)
+def check_provider_shutdown(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for provider in providers(sources):
+        if provider.source.path.endswith("_test.go"):
+            continue
+        gap = shutdown_gap(sources, provider)
+        if gap:
+            yield Diagnostic(
+                pos=provider.pos,
+                message=f"{provider.kind} {provider.label} may exit without flushing: {gap}",
+                suggestion=SUGGESTED_PATTERN,
+                confidence=0.7,
+                file=provider.source,
+            )
+
+def _setup_points(sources: List[GoFile], provider: Provider) -> List[tuple]:
+    """(source, function, offset) from which the provider is live: its construction, and calls to
+    the function creating it"""
+
+    points = [(provider.source, provider.func, provider.pos)]
+    for source in sources:
+        for call in source.calls(r'(?:\w+\.)?' + re.escape(provider.func.name)):
+            fn = source.func_at(call.start)
+            if fn is not None and fn is not provider.func:
+                points.append((source, fn, call.start))
+    return points
+
+def _callees(sources: List[GoFile], source: GoFile, start: int, end: int) -> Iterator[tuple]:
+    """(source, function) for project functions called in source[start:end]"""
+
+    for m in re.finditer(r'(?<![\w.])(\w+)\s*\(', source.masked[start:end]):
+        for candidate in sources:
+            fn = candidate.func_named(m.group(1))
+            if fn is not None:
+                yield candidate, fn
+
+def exits_after_setup(sources: List[GoFile], provider: Provider) -> Iterator[tuple]:
+    """(source, offset, setup function name) of exits reachable once the provider exists and before
+    anything flushed it"""
+
+    seen = set()
+    for source, fn, pos in _setup_points(sources, provider):
+        queue = [(source, fn, pos)]
+        while queue:
+            src, func, start = queue.pop()
+            key = (src.path, func.body_start)
+            if key in seen and start == func.body_start:
+                continue
+            seen.add(key)
+            for exit_pos in _unflushed_exits(src, start, func.body_end):
+                yield src, exit_pos, fn.name
+            for callee_src, callee in _callees(sources, src, start, func.body_end):
+                if (callee_src.path, callee.body_start) not in seen:
+                    queue.append((callee_src, callee, callee.body_start))
+
+@rule(
+    rule_id="exit-bypasses-shutdown",
+    title="Don't call os.Exit or log.Fatal once the SDK is running",
+    category="sdk",
+    signal="traces",
+    signals=("metrics", "logs"),
+    severity="medium",
+    scope="project",
+    description="os.Exit, and the log.Fatal helpers that call it, end the process without running deferred "
+                "functions, so a deferred Shutdown or ForceFlush never exports the buffered spans, metrics "
+                "and logs, including the ones describing the failure that caused the exit.",
+    bad_example='''
+func main() {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+	if err := loadConfig(); err != nil {
+		log.Fatalf("config: %v", err)
+	}
+}''',
+    good_example='''
+func runService() error {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+	if err := loadConfig(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}''',
+)
+def check_exit_after_setup(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    reported = set()
+    for provider in providers(sources):
+        if provider.source.path.endswith("_test.go"):
+            continue
+        for source, pos, setup_func in exits_after_setup(sources, provider):
+            if (source.path, pos) in reported:
+                continue
+            reported.add((source.path, pos))
+            exit_call = re.match(EXITS, source.masked[pos:]).group(0).rstrip("( ")
+            yield Diagnostic(
+                pos=pos,
+                message=f"{exit_call} runs after {provider.kind} setup in {setup_func} and skips its deferred Shutdown",
+                suggestion="Return the error up to main and exit after Shutdown has run, or call "
+                           "Shutdown (or ForceFlush) on the provider right before exiting",
+                confidence=0.75,
+                file=source,
+            )
@@ -0,0 +1,253 @@
+"""
+Attribute keys the Go semconv packages (go.opentelemetry.io/otel/semconv/vX) export as typed constants,
+and the values of the keys that semconv defines as enums
+"""
+
+import re
+from typing import Dict, List, Optional, Tuple
+
+from .conventions import HTTP_METHODS
+
+SEMCONV_PKG = "go.opentelemetry.io/otel/semconv"
+# The version SEMCONV_KEYS is taken from, imported when code doesn't pick one
+SEMCONV_VERSION = "v1.26.0"
+# semconv/vX.Y.Z itself; its subpackages (httpconv, netconv) don't export the key constants
+SEMCONV_IMPORT = re.compile(re.escape(SEMCONV_PKG) + r'/v(\d+)\.(\d+)\.\d+$')
+
+# Stable and widely used keys present in semconv/v1.26.0. The inventory ships with the rules, so
+# typos are caught whichever semconv version the code pins, or none.
+SEMCONV_KEYS = [
+    "client.address", "client.port",
+    "server.address", "server.port",
+    "network.peer.address", "network.peer.port", "network.protocol.name", "network.protocol.version",
+    "network.transport", "network.type", "network.local.address", "network.local.port",
+    "url.full", "url.path", "url.query", "url.scheme", "url.fragment",
+    "http.request.method", "http.request.method_original", "http.request.resend_count",
+    "http.request.body.size", "http.response.status_code", "http.response.body.size", "http.route",
+    "user_agent.original",
+    "error.type",
+    "exception.type", "exception.message", "exception.stacktrace", "exception.escaped",
+    "db.system", "db.namespace", "db.operation.name", "db.query.text", "db.collection.name",
+    "messaging.system", "messaging.operation.type", "messaging.operation.name",
+    "messaging.destination.name", "messaging.message.id", "messaging.consumer.group.name",
+    "messaging.batch.message_count",
+    "rpc.system", "rpc.service", "rpc.method", "rpc.grpc.status_code",
+    "rpc.message.type", "rpc.message.id", "rpc.message.compressed_size", "rpc.message.uncompressed_size",
+    "code.function", "code.namespace", "code.filepath", "code.lineno", "code.column", "code.stacktrace",
+    "enduser.id", "enduser.role", "enduser.scope",
+    "user.id", "user.name", "user.email", "user.full_name", "user.hash", "user.roles",
+    "session.id", "session.previous_id",
+    "thread.id", "thread.name",
+    "peer.service",
+    "graphql.operation.name", "graphql.operation.type", "graphql.document",
+    "feature_flag.key", "feature_flag.provider_name", "feature_flag.variant",
+    "tls.protocol.version", "tls.cipher",
+    "event.name",
+    "log.iostream", "log.file.name", "log.file.path", "log.record.uid",
+    "service.name", "service.version", "service.namespace", "service.instance.id",
+    "deployment.environment",
+    "host.name", "host.id", "host.arch", "host.type", "host.ip", "host.mac", "host.image.id", "host.image.name",
+    "os.type", "os.version", "os.name", "os.description", "os.build_id",
+    "process.pid", "process.parent_pid", "process.executable.name", "process.executable.path", "process.command",
+    "process.command_line", "process.command_args", "process.owner", "process.runtime.name",
+    "process.runtime.version",
+    "container.id", "container.name", "container.image.name", "container.image.id", "container.runtime",
+    "k8s.cluster.name", "k8s.namespace.name", "k8s.pod.name", "k8s.pod.uid", "k8s.container.name",
+    "k8s.deployment.name", "k8s.node.name", "k8s.job.name",
+    "cloud.provider", "cloud.region", "cloud.account.id", "cloud.availability_zone", "cloud.platform",
+    "cloud.resource_id",
+    "faas.name", "faas.version", "faas.instance", "faas.trigger", "faas.invocation_id", "faas.coldstart",
+    "faas.max_memory",
+    "telemetry.sdk.name", "telemetry.sdk.language", "telemetry.sdk.version",
+]
+
+# Keys renamed by the HTTP, network and database stabilizations -> their current names
+RENAMED_KEYS = {
+    "http.method": "http.request.method",
+    "http.status_code": "http.response.status_code",
+    "http.url": "url.full",
+    "http.target": "url.path",
+    "http.scheme": "url.scheme",
+    "http.user_agent": "user_agent.original",
+    "http.request_content_length": "http.request.body.size",
+    "http.response_content_length": "http.response.body.size",
+    "net.peer.name": "server.address",
+    "net.peer.port": "server.port",
+    "net.host.name": "server.address",
+    "net.host.port": "server.port",
+    "net.transport": "network.transport",
+    "db.name": "db.namespace",
+    "db.statement": "db.query.text",
+    "db.operation": "db.operation.name",
+    "messaging.operation": "messaging.operation.type",
+}
+
+# Well-known values of enum keys. Values outside the list are allowed by semconv for some keys
+# (db.system, messaging.system) but then rarely match what backends and dashboards expect.
+ENUM_VALUES: Dict[str, List[str]] = {
+    "http.request.method": list(HTTP_METHODS) + ["_OTHER"],
+    "db.system": [
+        "other_sql", "mssql", "mysql", "oracle", "db2", "postgresql", "redshift", "hive", "hsqldb",
+        "hanadb", "mariadb", "sqlite", "teradata", "vertica", "h2", "cassandra", "hbase", "mongodb",
+        "redis", "couchbase", "couchdb", "cosmosdb", "dynamodb", "neo4j", "geode", "elasticsearch",
+        "memcached", "cockroachdb", "opensearch", "clickhouse", "spanner", "trino", "influxdb",
+    ],
+    "db.system.name": [
+        "other_sql", "actian.ingres", "aws.dynamodb", "aws.redshift", "azure.cosmosdb", "cassandra",
+        "clickhouse", "cockroachdb", "couchbase", "couchdb", "derby", "elasticsearch", "firebirdsql",
+        "gcp.spanner", "geode", "h2database", "hbase", "hive", "hsqldb", "ibm.db2", "ibm.informix",
+        "influxdb", "mariadb", "memcached", "microsoft.sql_server", "mongodb", "mysql", "neo4j",
+        "opensearch", "oracle.db", "postgresql", "redis", "sap.hana", "sqlite", "teradata", "trino",
+    ],
+    "messaging.operation.type": ["create", "publish", "receive", "process", "settle"],
+    "messaging.system": [
+        "activemq", "aws_sqs", "eventgrid", "eventhubs", "servicebus", "gcp_pubsub", "jms", "kafka",
+        "rabbitmq", "rocketmq", "pulsar",
+    ],
+    "rpc.system": ["grpc", "java_rmi", "dotnet_wcf", "apache_dubbo", "connect_rpc"],
+    "network.transport": ["tcp", "udp", "pipe", "unix", "quic"],
+    "network.type": ["ipv4", "ipv6"],
+}
+
+# Common spellings of enum values -> the semconv value
+ENUM_SYNONYMS: Dict[str, Dict[str, str]] = {
+    "db.system": {"postgres": "postgresql", "pg": "postgresql", "mongo": "mongodb", "sqlserver": "mssql",
+                  "elastic": "elasticsearch", "dynamo": "dynamodb"},
+    "db.system.name": {"postgres": "postgresql", "pg": "postgresql", "mongo": "mongodb",
+                       "mssql": "microsoft.sql_server", "sqlserver": "microsoft.sql_server",
+                       "oracle": "oracle.db", "db2": "ibm.db2", "dynamodb": "aws.dynamodb",
+                       "cosmosdb": "azure.cosmosdb", "spanner": "gcp.spanner", "elastic": "elasticsearch"},
+    "messaging.operation.type": {"send": "publish", "produce": "publish", "deliver": "process"},
+    "messaging.system": {"sqs": "aws_sqs", "pubsub": "gcp_pubsub", "rabbit": "rabbitmq", "amqp": "rabbitmq"},
+    "rpc.system": {"rmi": "java_rmi", "wcf": "dotnet_wcf", "dubbo": "apache_dubbo", "connect": "connect_rpc"},
+}
+
+def enum_value(key: str, value: str) -> Optional[Tuple[Optional[str], str]]:
+    """(replacement or None, reason) for a value outside key's enum; None when the value is fine
+    or key is not an enum"""
+
+    values = ENUM_VALUES.get(key)
+    if values is None or value in values:
+        return None
+    folded = {v.lower(): v for v in values}
+    if value.lower() in folded:
+        return folded[value.lower()], "has the wrong case"
+    normalized = re.sub(r'[\s-]+', "_", value.strip().lower())
+    if normalized in folded:
+        return folded[normalized], "is not spelled the semconv way"
+    synonym = ENUM_SYNONYMS.get(key, {}).get(normalized)
+    if synonym:
+        return synonym, "is a synonym of the semconv value"
+    if key == "http.request.method":
+        return "_OTHER", "is not a known HTTP method (record it in http.request.method_original)"
+    return None, "is not one of the well-known values"
+
+# Segments the Go generator spells as initialisms
+INITIALISMS = {
+    "http": "HTTP", "url": "URL", "db": "DB", "rpc": "RPC", "grpc": "GRPC", "id": "ID", "ip": "IP",
+    "os": "OS", "tls": "TLS", "k8s": "K8S", "sdk": "SDK", "uid": "UID", "faas": "FaaS", "pid": "PID",
+    "sql": "SQL", "mongodb": "MongoDB", "cosmosdb": "CosmosDB", "otel": "OTel", "ai": "AI",
+}
+
+# Keys of SEMCONV_KEYS first defined after SEMCONV_VERSION, so without a constant in it
+NEWER_KEYS = {"messaging.consumer.group.name", "user.id", "user.name", "user.email", "user.full_name", "user.hash",
+              "user.roles"}
+# Constants the Go generator doesn't name after the key
+CONSTANT_NAMES = {"code.lineno": "CodeLineNumberKey", "db.redis.database_index": "DBRedisDBIndexKey",
+                  "gen_ai.usage.prompt_tokens": "GenAiUsagePromptTokensKey",
+                  "gen_ai.usage.completion_tokens": "GenAiUsageCompletionTokensKey"}
+
+def go_constant(key: str) -> str:
+    """Go identifier of the semconv key constant: "http.request.method" -> "HTTPRequestMethodKey" """
+
+    if key in CONSTANT_NAMES:
+        return CONSTANT_NAMES[key]
+    words = re.split(r'[._]', key)
+    return "".join(INITIALISMS.get(w, w[:1].upper() + w[1:]) for w in words) + "Key"
+
+def semconv_version(path: str) -> Optional[Tuple[int, int]]:
+    """(major, minor) of a semconv import path, None for other paths"""
+
+    m = SEMCONV_IMPORT.match(path)
+    return (int(m.group(1)), int(m.group(2))) if m else None
+
+def semconv_constant(key: str) -> Optional[str]:
+    """Constant for an exact semconv key in SEMCONV_VERSION, or None"""
+    return go_constant(key) if key in SEMCONV_KEYS and key not in NEWER_KEYS else None
+
+def edit_distance(a: str, b: str) -> int:
+    """Levenshtein distance counting an adjacent transposition as one edit"""
+
+    prev2, prev = None, list(range(len(b) + 1))
+    for i in range(1, len(a) + 1):
+        row = [i] + [0] * len(b)
+        for j in range(1, len(b) + 1):
+            row[j] = min(prev[j] + 1, row[j - 1] + 1, prev[j - 1] + (a[i - 1] != b[j - 1]))
+            if prev2 and i > 1 and j > 1 and a[i - 1] == b[j - 2] and a[i - 2] == b[j - 1]:
+                row[j] = min(row[j], prev2[j - 2] + 1)
+        prev2, prev = prev, row
+    return prev[-1]
+
+def closest_key(key: str) -> Optional[Tuple[str, int]]:
+    """(current semconv key, distance) for a key that looks like a misspelling of one, or None.
+    Short keys tolerate one edit, longer ones two."""
+
+    if key in SEMCONV_KEYS or key in RENAMED_KEYS:
+        return None
+    limit = 1 if len(key) < 12 else 2
+    best = None
+    for known in SEMCONV_KEYS + list(RENAMED_KEYS):
+        # Extra segments are a different (custom) key, not a typo
+        if known.count(".") != key.count(".") or abs(len(known) - len(key)) > limit:
+            continue
+        # A changed letter in a short segment is usually another word (user.ip, not user.id)
+        if any(len(a) == len(b) <= 3 and a != b for a, b in zip(key.split("."), known.split("."))):
+            continue
+        distance = edit_distance(key, known)
+        if distance <= limit and (best is None or distance < best[1]):
+            best = (known, distance)
+    if best is None:
+        return None
+    return RENAMED_KEYS.get(best[0], best[0]), best[1]
+
+def _words(key: str) -> Tuple[str, ...]:
+    """Lowercased words of a key however it is separated: "userId", "user_id", "user-id" -> (user, id)"""
+
+    return tuple(w for w in re.split(r'[._\-\s]+', re.sub(r'([a-z0-9])([A-Z])', r'\1_\2', key).lower()) if w)
+
+# Last words a key is commonly written without: http.status for http.status_code
+DROPPED_WORDS = ("code", "count", "size", "name", "id")
+
+def _truncated() -> Dict[str, Optional[str]]:
+    """Known keys with a DROPPED_WORDS suffix cut off their last segment -> the key (None when
+    several keys cut down to it)"""
+
+    found: Dict[str, Optional[str]] = {}
+    for known in SEMCONV_KEYS + list(RENAMED_KEYS):
+        short, _, dropped = known.rpartition("_")
+        if dropped in DROPPED_WORDS and "." in short and short not in SEMCONV_KEYS:
+            found[short] = None if short in found else known
+    return found
+
+# Words of each known key -> the key
+KNOWN_WORDS = {_words(k): k for k in SEMCONV_KEYS + list(RENAMED_KEYS)}
+TRUNCATED_KEYS = _truncated()
+
+def canonical_key(key: str) -> Optional[Tuple[str, str, float]]:
+    """(current semconv key, how key differs from it, confidence) for a key that misspells a
+    semconv key, separates its words otherwise (user_id, userId for user.id) or cuts its last
+    segment short (http.status for http.status_code), or None"""
+
+    if key in SEMCONV_KEYS or key in RENAMED_KEYS:
+        return None
+    spelled = "in other case" if key.lower() in KNOWN_WORDS.values() else "with other separators"
+    for known, how, confidence in ((KNOWN_WORDS.get(_words(key)), spelled, 0.8),
+                                   (TRUNCATED_KEYS.get(key), "cut short", 0.6)):
+        if known is not None:
+            current = RENAMED_KEYS.get(known, known)
+            now = f', now "{current}"' if current != known else ""
+            return current, f'"{known}" {how}{now}', confidence
+    match = closest_key(key)
+    if match is None:
+        return None
+    return match[0], f'a misspelling of "{match[0]}"', 0.85 if match[1] == 1 else 0.65
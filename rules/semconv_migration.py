@@ -0,0 +1,285 @@
+"""
+Semantic convention upgrades: attribute keys renamed between two semconv versions are rewritten in
+attribute constructors, attribute.Key and const declarations, the semconv/vX.Y.Z imports move to
+the new version and the constants of renamed keys follow. Renames that need a human (keys split
+in two, dropped without a replacement, renamed differently on client and server spans, helpers
+and enum members whose signatures changed) are reported instead.
+
+A key is listed under the Go semconv version that dropped its constant, which is when code
+importing semconv has to change; the conventions themselves deprecated most of them a few
+versions earlier. The table covers the HTTP, network, database, messaging and code attribute
+renames; other declarations the packages dropped are listed in each version's MIGRATION.md.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Dict, List, Optional, Tuple
+
+from .base import Fix, TextEdit
+from .fixes import apply_fixes
+from .golang import GoFile
+from .semconv import SEMCONV_PKG, go_constant, semconv_version
+from .traces.attributes import ATTRIBUTE_PKG, attribute_calls
+
+Version = Tuple[int, int]
+
+@dataclass
+class Rename:
+    version: Version
+    key: str
+    # The new key, None when the rename can't be done by key alone (see note)
+    new: Optional[str]
+    note: str = ""
+    # Only the Go constant went away; the key itself is still current
+    constant_only: bool = False
+
+# net.peer.* named the other side of the connection: the server on client spans, the client on
+# server spans
+BY_SPAN_KIND = {
+    "net.peer.name": {"client": "server.address", "server": "client.address"},
+    "net.peer.port": {"client": "server.port", "server": "client.port"},
+}
+
+KEY_RENAMES = [
+    Rename((1, 21), "http.client_ip", "client.address"),
+    Rename((1, 21), "messaging.source.name", None, "removed; consumers record messaging.destination.name"),
+    Rename((1, 22), "client.socket.address", "network.peer.address"),
+    Rename((1, 22), "client.socket.port", "network.peer.port"),
+    Rename((1, 22), "server.socket.address", "network.peer.address"),
+    Rename((1, 22), "server.socket.port", "network.peer.port"),
+    Rename((1, 22), "messaging.message.payload_size_bytes", "messaging.message.body.size"),
+    Rename((1, 22), "container.image.tag", None, "now container.image.tags, a string slice"),
+    Rename((1, 23), "http.resend_count", "http.request.resend_count"),
+    Rename((1, 26), "http.method", "http.request.method"),
+    Rename((1, 26), "http.status_code", "http.response.status_code"),
+    Rename((1, 26), "http.request_content_length", "http.request.body.size"),
+    Rename((1, 26), "http.response_content_length", "http.response.body.size"),
+    Rename((1, 26), "http.url", "url.full"),
+    Rename((1, 26), "http.scheme", "url.scheme"),
+    Rename((1, 26), "http.user_agent", "user_agent.original"),
+    Rename((1, 26), "http.target", None, "split into url.path and url.query"),
+    Rename((1, 26), "http.flavor", None, "now network.protocol.name and network.protocol.version"),
+    Rename((1, 26), "net.host.name", "server.address"),
+    Rename((1, 26), "net.host.port", "server.port"),
+    Rename((1, 26), "net.peer.name", None, "server.address on client spans, client.address on server spans"),
+    Rename((1, 26), "net.peer.port", None, "server.port on client spans, client.port on server spans"),
+    Rename((1, 26), "net.protocol.name", "network.protocol.name"),
+    Rename((1, 26), "net.protocol.version", "network.protocol.version"),
+    Rename((1, 26), "net.transport", "network.transport"),
+    Rename((1, 26), "net.sock.family", None, "now network.type, with ipv4/ipv6 instead of inet/inet6"),
+    Rename((1, 26), "net.sock.host.addr", "network.local.address"),
+    Rename((1, 26), "net.sock.host.port", "network.local.port"),
+    Rename((1, 26), "net.sock.peer.addr", "network.peer.address"),
+    Rename((1, 26), "net.sock.peer.port", "network.peer.port"),
+    Rename((1, 26), "net.sock.peer.name", None, "removed without a replacement"),
+    Rename((1, 26), "db.name", "db.namespace"),
+    Rename((1, 26), "db.statement", "db.query.text"),
+    Rename((1, 26), "db.operation", "db.operation.name"),
+    Rename((1, 26), "db.sql.table", "db.collection.name"),
+    Rename((1, 26), "db.mongodb.collection", "db.collection.name"),
+    Rename((1, 26), "db.cassandra.table", "db.collection.name"),
+    Rename((1, 26), "db.cosmosdb.container", "db.collection.name"),
+    Rename((1, 26), "db.redis.database_index", None, "now part of db.namespace, a string"),
+    Rename((1, 26), "db.connection_string", None, "removed; record server.address and server.port"),
+    Rename((1, 26), "db.user", None, "removed without a replacement"),
+    Rename((1, 26), "message.type", "rpc.message.type"),
+    Rename((1, 26), "message.id", "rpc.message.id"),
+    Rename((1, 26), "message.compressed_size", "rpc.message.compressed_size"),
+    Rename((1, 26), "message.uncompressed_size", "rpc.message.uncompressed_size"),
+    Rename((1, 26), "messaging.client_id", "messaging.client.id"),
+    Rename((1, 26), "messaging.kafka.destination.partition", "messaging.destination.partition.id"),
+    Rename((1, 26), "messaging.operation", "messaging.operation.type"),
+    Rename((1, 26), "otel.library.name", "otel.scope.name"),
+    Rename((1, 26), "otel.library.version", "otel.scope.version"),
+    Rename((1, 27), "deployment.environment", "deployment.environment.name"),
+    Rename((1, 27), "messaging.kafka.consumer.group", "messaging.consumer.group.name"),
+    Rename((1, 27), "messaging.rocketmq.client_group", "messaging.consumer.group.name"),
+    Rename((1, 27), "messaging.eventhubs.consumer.group", "messaging.consumer.group.name"),
+    Rename((1, 27), "messaging.kafka.message.offset", "messaging.kafka.offset"),
+    Rename((1, 27), "messaging.servicebus.destination.subscription_name", "messaging.destination.subscription.name"),
+    Rename((1, 27), "tls.client.server_name", "server.address"),
+    Rename((1, 27), "gen_ai.usage.prompt_tokens", "gen_ai.usage.input_tokens"),
+    Rename((1, 27), "gen_ai.usage.completion_tokens", "gen_ai.usage.output_tokens"),
+    Rename((1, 27), "enduser.id", None, "removed; user.id records the user's ID"),
+    Rename((1, 27), "enduser.role", None, "removed; user.roles records the user's roles"),
+    Rename((1, 27), "enduser.scope", None, "removed without a replacement"),
+    Rename((1, 30), "code.lineno", "code.line.number"),
+    Rename((1, 30), "code.column", "code.column.number"),
+    Rename((1, 30), "code.function", None, "now code.function.name, which holds the fully qualified name"),
+    Rename((1, 30), "db.system", None, "now db.system.name, with new values for some systems (mssql is "
+                                       "microsoft.sql_server)"),
+    Rename((1, 30), "exception.escaped", None, "removed without a replacement"),
+    Rename((1, 31), "code.filepath", "code.file.path"),
+    Rename((1, 31), "code.namespace", None, "now part of code.function.name"),
+    Rename((1, 40), "rpc.message.type", None, "removed with the RPC message events"),
+    Rename((1, 40), "rpc.message.id", None, "removed with the RPC message events"),
+    Rename((1, 40), "rpc.message.compressed_size", None, "removed with the RPC message events"),
+    Rename((1, 40), "rpc.message.uncompressed_size", None, "removed with the RPC message events"),
+    Rename((1, 42), "gen_ai.usage.input_tokens", None, "no constant from v1.42 on; use the key as a string",
+           constant_only=True),
+    Rename((1, 42), "gen_ai.usage.output_tokens", None, "no constant from v1.42 on; use the key as a string",
+           constant_only=True),
+]
+
+# semconv subpackages the Go module dropped, by the version that dropped them
+DROPPED_PACKAGES = {"httpconv": (1, 21), "netconv": (1, 21)}
+
+def parse_version(text: str) -> Version:
+    """(major, minor) of "v1.27", "1.27" or "v1.27.0"; ValueError otherwise"""
+
+    m = re.fullmatch(r'v?(\d+)\.(\d+)(?:\.\d+)?', text.strip())
+    if not m:
+        raise ValueError(f"not a semconv version: {text!r} (expected e.g. v1.27)")
+    return int(m.group(1)), int(m.group(2))
+
+def import_path(text: str) -> str:
+    """Import path of the semconv package of a version: v1.27 -> .../semconv/v1.27.0"""
+
+    version = text.strip().lstrip("v")
+    return f"{SEMCONV_PKG}/v{version if version.count('.') == 2 else version + '.0'}"
+
+@dataclass
+class Change:
+    line: int
+    old: str
+    # What it becomes, None when there is no direct replacement
+    new: Optional[str]
+    note: str = ""
+    # Set for the changes made automatically
+    edit: Optional[TextEdit] = None
+
+def renames_between(old: Version, new: Version) -> Dict[str, Rename]:
+    """Key -> its rename, for the keys dropped after old up to and including new. A key renamed
+    to one dropped later in the range goes straight to the end of the chain."""
+
+    renames = {r.key: r for r in KEY_RENAMES if old < r.version <= new}
+    for key, rename in list(renames.items()):
+        while rename.new in renames and not renames[rename.new].constant_only:
+            later = renames[rename.new]
+            rename = Rename(rename.version, key, later.new, later.note)
+        renames[key] = rename
+    return renames
+
+def _span_kind(source: GoFile, pos: int) -> str:
+    """Kind of the spans started in the function around pos, "" when none or several"""
+
+    fn = source.func_at(pos, include_literals=True)
+    kinds = {s.kind or "internal" for s in source.span_starts if fn is not None and s.func is fn}
+    return kinds.pop() if len(kinds) == 1 else ""
+
+def _new_key(source: GoFile, rename: Rename, pos: int) -> Tuple[Optional[str], str]:
+    """(new key, note) of the rename at pos; net.peer.* look at the span the function starts"""
+
+    if rename.key in BY_SPAN_KIND:
+        kind = _span_kind(source, pos)
+        side = "server" if kind == "server" else "client" if kind in ("client", "producer", "consumer") else ""
+        if side:
+            return BY_SPAN_KIND[rename.key][side], f"on a {kind} span"
+    return rename.new, rename.note
+
+def _key_calls(source: GoFile) -> list:
+    """Attribute constructor and attribute.Key calls of source"""
+
+    aliases = "|".join(re.escape(a) for a in source.import_alias(ATTRIBUTE_PKG))
+    return list(attribute_calls(source)) + (list(source.calls(f'(?:{aliases})\\.Key')) if aliases else [])
+
+def _key_literals(source: GoFile) -> Dict[int, Tuple[int, str]]:
+    """start -> (end, key) of the string literals used as attribute keys: attribute constructor
+    and attribute.Key arguments, and the package-level consts and vars declared as attribute.Key
+    or passed as a key somewhere in the package. Other package-level strings may name a flag, a
+    column or anything else, so they are left to be reported."""
+
+    found = {}
+    for call in _key_calls(source):
+        key = call.args[0] if call.args else None
+        if key is not None and key.literal is not None:
+            start = key.start + len(key.text) - len(key.text.lstrip())
+            found[start] = (start + len(key.text.strip()), key.literal)
+    key_types = {f"{a}.Key" for a in source.import_alias(ATTRIBUTE_PKG)}
+    key_names = {call.args[0].text.strip() for s in source.package_sources for call in _key_calls(s)
+                 if call.args and call.args[0].literal is None}
+    for m in re.finditer(r'^[ \t]*(?:(?:const|var)[ \t]+)?(\w+)(?:[ \t]+([\w.]+))?[ \t]*=[ \t]*("[^"\n]*")[ \t]*$',
+                         source.masked, re.M):
+        if source.func_at(m.start(3), include_literals=True) is None and \
+                (m.group(2) in key_types or m.group(1) in key_names):
+            found[m.start(3)] = (m.end(3), source.code[m.start(3) + 1:m.end(3) - 1])
+    return found
+
+def _constants(renames: Dict[str, Rename]) -> Dict[str, Rename]:
+    """Go identifier prefix (HTTPMethod) -> the rename of its key"""
+    return {go_constant(key)[:-len("Key")]: rename for key, rename in renames.items()}
+
+def _identifiers(source: GoFile, alias: str, renames: Dict[str, Rename]) -> List[Change]:
+    """Changes to the semconv identifiers of renamed keys: their Key constants are renamed, their
+    helpers and enum members reported, as the new key's may take another type or values"""
+
+    prefixes = _constants(renames)
+    changes = []
+    for m in re.finditer(r'(?<![\w.])' + re.escape(alias) + r'\.(\w+)', source.masked):
+        ident = m.group(1)
+        prefix = max((p for p in prefixes if ident.startswith(p)), key=len, default=None)
+        if prefix is None:
+            continue
+        rename = prefixes[prefix]
+        new, note = _new_key(source, rename, m.start())
+        old = f"{alias}.{ident}"
+        line = source.line_of(m.start())
+        if new in renames:
+            # Renamed into a key whose constant is gone too
+            new, note = None, renames[new].note
+        if ident == prefix + "Key" and new is not None:
+            changes.append(Change(line, old, f"{alias}.{go_constant(new)}", note,
+                                  TextEdit(m.start(1), m.end(1), go_constant(new))))
+        elif ident == prefix + "Key":
+            changes.append(Change(line, old, None, note))
+        else:
+            what = "helper" if re.match(r'\s*\(', source.masked[m.end():]) else "enum member"
+            hint = f"use {alias}.{go_constant(new)}" if new is not None else note
+            changes.append(Change(line, old, None, f"{what} of {rename.key}; {hint}"))
+    return changes
+
+def plan_migration(source: GoFile, old: Version, new: Version, target: str) -> List[Change]:
+    """Every change moving source from semconv old to new, by line; target is the new import path"""
+
+    renames = renames_between(old, new)
+    changes = []
+    keys = _key_literals(source)
+    for start, (end, key) in keys.items():
+        rename = renames.get(key)
+        if rename is None or rename.constant_only:
+            continue
+        renamed, note = _new_key(source, rename, start)
+        edit = TextEdit(start, end, f'"{renamed}"') if renamed is not None else None
+        changes.append(Change(source.line_of(start), key, renamed, note, edit))
+    # The same strings elsewhere (maps, queries, log fields) may or may not be attribute keys
+    for m in re.finditer(r'"([\w.]+)"', source.code):
+        rename = renames.get(m.group(1))
+        if rename is not None and not rename.constant_only and m.start() not in keys \
+                and source.masked[m.start()] == '"':
+            changes.append(Change(source.line_of(m.start()), m.group(1), rename.new,
+                                  "not an attribute key here; rename it if it names one"))
+
+    for alias, path in source.imports.items():
+        pos = source.code.find(f'"{path}"') + 1
+        version = semconv_version(path)
+        sub = re.fullmatch(re.escape(SEMCONV_PKG) + r'/(v[\d.]+)/(\w+)', path)
+        dropped = DROPPED_PACKAGES.get(sub.group(2)) if sub else None
+        if dropped is not None and semconv_version(f"{SEMCONV_PKG}/{sub.group(1)}") < dropped <= new:
+            changes.append(Change(source.line_of(pos), path, None,
+                                  f"the {sub.group(2)} helpers were dropped in v{dropped[0]}.{dropped[1]}; set the "
+                                  f"attributes with the semconv constants or use the contrib instrumentation"))
+        if version is None or version >= new:
+            continue
+        if version < old:
+            changes.append(Change(source.line_of(pos), path, None,
+                                  f"older than v{old[0]}.{old[1]}; migrate it with --from v{version[0]}.{version[1]}"))
+            continue
+        changes.append(Change(source.line_of(pos), path, target, "", TextEdit(pos, pos + len(path), target)))
+        changes += _identifiers(source, alias, renames)
+    return sorted(changes, key=lambda c: c.line)
+
+def migrate_file(source: GoFile, changes: List[Change]) -> Tuple[str, int]:
+    """source's code with the automated changes made, and how many there were"""
+
+    edits = [c.edit for c in changes if c.edit is not None]
+    return apply_fixes(source.code, [Fix("migrate semconv", [e]) for e in edits])
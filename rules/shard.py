@@ -0,0 +1,55 @@
+"""
+Splitting a scan across parallel CI jobs and merging their reports.
+Files are assigned to shards by a hash of their package directory relative to the scan root,
+so every job computes the same split without coordination and a package is never divided.
+"""
+
+import hashlib
+from pathlib import Path
+from typing import Dict, Iterable, List, Tuple
+
+def parse_shard(spec: str) -> Tuple[int, int]:
+    """"i/n" with 1 <= i <= n, e.g. "2/4" for the second of four jobs"""
+
+    try:
+        index, count = (int(part) for part in spec.split("/"))
+    except ValueError:
+        raise ValueError(f"expected i/n, got '{spec}'")
+    if count < 1 or not 1 <= index <= count:
+        raise ValueError(f"shard index must be between 1 and {count}, got {index}")
+    return index, count
+
+def package_shard(package: str, count: int) -> int:
+    """1-based shard a package directory belongs to"""
+
+    digest = hashlib.sha256(package.encode("utf-8")).hexdigest()
+    return int(digest[:16], 16) % count + 1
+
+def select_shard(paths: Iterable[Path], root: str, index: int, count: int) -> List[Path]:
+    """The paths whose package falls into shard index of count"""
+
+    selected = []
+    for path in paths:
+        try:
+            package = path.resolve().parent.relative_to(Path(root).resolve()).as_posix()
+        except ValueError:
+            package = path.parent.as_posix()
+        if package_shard(package, count) == index:
+            selected.append(path)
+    return selected
+
+def merge_reports(reports: List[Dict]) -> Tuple[Dict, List[str]]:
+    """Union of per-file reports, plus the files that appeared in more than one report, which
+    means the shards were not computed over the same tree. A report is either a scan report
+    (scan --format json) or a score report with its findings (score --shard --format json)."""
+
+    merged, duplicates = {}, []
+    for report in reports:
+        if "score" in report and isinstance(report.get("results"), dict):
+            report = report["results"]
+        for path, result in report.items():
+            if path in merged:
+                duplicates.append(path)
+                continue
+            merged[path] = result
+    return {path: merged[path] for path in sorted(merged)}, sorted(set(duplicates))
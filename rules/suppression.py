@@ -0,0 +1,106 @@
+"""
+Inline suppressions: `//otel:ignore RULE_ID[,RULE_ID...] -- reason`.
+
+Trailing the offending line, or on the line above it, the directive silences the listed rules on
+that line. In the comment block above a func declaration it silences them in the whole function.
+The reason is mandatory: a directive without one suppresses nothing and is reported by
+invalid-suppression, which can't itself be suppressed.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Dict, Iterator, List
+
+from .base import Diagnostic
+from .golang import GoFile
+from .registry import get_rule, rule
+
+DIRECTIVE = re.compile(r'//\s*otel:ignore\b([^\n]*)')
+
+@dataclass
+class Suppression:
+    rule_ids: List[str]
+    reason: str
+    # Offset of the directive and the range of code it covers
+    pos: int
+    start: int
+    end: int
+
+    def covers(self, rule_id: str, pos: int) -> bool:
+        return bool(self.reason) and rule_id in self.rule_ids and self.start <= pos < self.end
+
+def suppressions(source: GoFile) -> List[Suppression]:
+    found = []
+    code = source.code
+    for m in DIRECTIVE.finditer(code):
+        line_start = code.rfind("\n", 0, m.start()) + 1
+        before = source.masked[line_start:m.start()]
+        if before.count('"') % 2 or "`" in before:
+            continue  # inside a string literal
+        ids, _, reason = m.group(1).partition("--")
+        rule_ids = [r for r in re.split(r'[\s,]+', ids) if r]
+        if before.strip():
+            start = line_start
+        else:
+            # Own line: applies to the next line that isn't a comment
+            start = code.find("\n", m.end()) + 1 or len(code)
+            while start < len(code) and re.match(r'[ \t]*//', code[start:]):
+                start = code.find("\n", start) + 1 or len(code)
+        end = code.find("\n", start)
+        end = len(code) if end == -1 else end
+        fn = next((f for f in source.functions if not f.is_literal and start <= f.start < end), None)
+        if fn is not None and not before.strip():
+            end = fn.body_end + 1
+        found.append(Suppression(rule_ids, reason.strip(), m.start(), start, end))
+    return found
+
+@rule(
+    rule_id="invalid-suppression",
+    title="Give every otel:ignore directive a rule ID and a reason",
+    category="correctness",
+    signal="all",
+    severity="high",
+    description="`//otel:ignore RULE_ID -- reason` silences a rule on one line or, above a func, in one "
+                "function. The reason is what lets a reviewer tell an intentional deviation (a legacy span "
+                "name a dashboard depends on) from a finding swept under the rug, so a directive without "
+                "one, or naming a rule that doesn't exist, suppresses nothing and is reported.",
+    options={
+        # IDs of the config's custom and plugin rules, which directives may name too; filled in when the config is loaded
+        "known_rules": [],
+    },
+    bad_example='''
+func legacyCheckout(ctx context.Context) {
+	//otel:ignore span-name-convention
+	ctx, span := tracer.Start(ctx, "legacyCheckout")
+	defer span.End()
+	checkout(ctx)
+}''',
+    good_example='''
+func legacyCheckoutDocumented(ctx context.Context) {
+	//otel:ignore span-name-convention -- the checkout SLO dashboard queries this name
+	ctx, span := tracer.Start(ctx, "legacyCheckout")
+	defer span.End()
+	checkout(ctx)
+}''',
+)
+def check_invalid_suppression(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    known = set(options.get("known_rules") or [])
+    for s in suppressions(source):
+        problems = []
+        if not s.rule_ids:
+            problems.append("names no rule")
+        unknown = [r for r in s.rule_ids if get_rule(r) is None and r not in known]
+        if unknown:
+            problems.append(f"names unknown rule(s) {', '.join(unknown)}")
+        if not s.reason:
+            problems.append("has no reason after '--'")
+        if not problems:
+            continue
+        yield Diagnostic(
+            pos=s.pos,
+            end=source.code.find("\n", s.pos) if "\n" in source.code[s.pos:] else len(source.code),
+            message=f"otel:ignore directive {' and '.join(problems)}"
+                    + (", so it suppresses nothing" if not s.rule_ids or not s.reason else ""),
+            suggestion="Write it as //otel:ignore RULE_ID -- why this deviation is intentional",
+            confidence=1.0,
+        )
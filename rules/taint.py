@@ -0,0 +1,375 @@
+"""
+User input followed through the code: values read from an *http.Request (form fields, query
+and path parameters, headers, cookies, the URL, the client address), from gin, echo and fiber
+request contexts, from the fields of gRPC request messages and from command-line arguments and
+flags are tracked through assignments, into the functions they are passed to and out of the
+functions returning them, so a rule can tell where a value recorded in telemetry came from.
+Assignments count in statement order: a variable assigned another value, in a block that holds
+the use, no longer carries the input. Comparisons, booleans and lengths carry none.
+"""
+
+import os
+import re
+from dataclasses import dataclass
+from typing import Dict, List, Optional, Tuple
+
+from .golang import GoFile, GoFunc, match_bracket, parse_params, split_args, string_literal
+
+# Calls that make a value safe to export
+SANITIZER = re.compile(r'(?i)\b\w*(?:redact|hash|mask|anonymi[sz]e|pseudonymi[sz]e|tokeni[sz]e)\w*\(')
+
+# http.Request accessors of client-supplied values, and what they read
+REQUEST_ACCESSORS = {
+    "FormValue": "form field",
+    "PostFormValue": "form field",
+    "Form.Get": "form field",
+    "PostForm.Get": "form field",
+    "URL.Query().Get": "query parameter",
+    "Header.Get": "header",
+    "Header.Values": "header",
+    "PathValue": "path parameter",
+    "Cookie": "cookie",
+    "UserAgent": "header",
+    "Referer": "header",
+}
+# Accessors reading one header without naming it
+ACCESSOR_HEADERS = {"UserAgent": "User-Agent", "Referer": "Referer"}
+ACCESSOR = re.compile(
+    r'(?<![\w.])([\w.]+?)\.(' + "|".join(re.escape(a) for a in REQUEST_ACCESSORS) + r')\s*\(\s*([^()]*?)\s*\)')
+# http.Request fields set from the request line and the connection
+REQUEST_FIELDS = {"RemoteAddr": "client address", "URL.Path": "URL path", "URL.RawQuery": "query string",
+                  "RequestURI": "URL path"}
+REQUEST_FIELD = re.compile(r'(?<![\w.])([\w.]+?)\.(' + "|".join(re.escape(f) for f in REQUEST_FIELDS) + r')\b')
+# gorilla/mux and chi path parameters: mux.Vars(r)["id"], chi.URLParam(r, "id")
+ROUTER_PARAM = re.compile(r'(?<![\w.])(?:mux\.Vars\s*\(\s*(\w+)\s*\)\s*\[\s*([^\]\n]*?)\s*\]'
+                          r'|chi\.URLParam\s*\(\s*(\w+)\s*,\s*([^()\n]*?)\s*\))')
+# Request contexts of web frameworks, by parameter type, and their accessors of client-supplied values
+FRAMEWORKS = {
+    "gin": (re.compile(r'\*\s*gin\.Context'), {
+        "Param": "path parameter", "Query": "query parameter", "DefaultQuery": "query parameter",
+        "PostForm": "form field", "DefaultPostForm": "form field", "GetHeader": "header",
+        "ClientIP": "client address"}),
+    "echo": (re.compile(r'echo\.Context'), {
+        "Param": "path parameter", "QueryParam": "query parameter", "FormValue": "form field",
+        "RealIP": "client address"}),
+    "fiber": (re.compile(r'\*\s*fiber\.Ctx'), {
+        "Params": "path parameter", "Query": "query parameter", "FormValue": "form field", "Get": "header",
+        "IP": "client address"}),
+}
+METHOD_CALL = re.compile(r'(?<![\w.])(\w+)\.(\w+)\s*\(\s*([^()]*?)\s*\)')
+# Generated gRPC request messages (*pb.GetUserRequest), read through getters or fields
+GRPC_REQUEST = re.compile(r'\*\s*(?:\w+\.)?[A-Z]\w*Request')
+MESSAGE_FIELD = re.compile(r'(?<![\w.])(\w+)\.(?:Get([A-Z]\w*)\s*\(\s*\)|([A-Z]\w*)\b(?!\s*\())')
+# Command-line input: os.Args, flag.Arg(i), and the values of flag, pflag and cobra flags
+CLI_ARGS = re.compile(r'(?<![\w.])(?:os\.Args\b|p?flag\.Args?\s*\()')
+FLAG = r'(?:(?<![\w.])p?flag|\.(?:Persistent)?Flags\(\s*\))\.(?:String|StringSlice|Int|Int64|Uint|Uint64)'
+CLI_FLAG = re.compile(FLAG + r'P?\s*\(\s*("[^"\n]*")')
+CLI_FLAG_VAR = re.compile(FLAG + r'VarP?\s*\(\s*&(\w+)\s*,\s*("[^"\n]*")')
+ASSIGNMENT = re.compile(r'(?<![\w.])(\w+)(?:\s*,\s*\w+)*\s*:?=(?!=)\s*([^\n;]+)')
+# Results that take a few values whatever the input: comparisons, boolean operations, lengths and
+# the usual predicates
+COMPARISON = re.compile(r'==|!=|<=|>=|&&|\|\||<(?![-<])|(?<![->])>|^\s*!')
+BOUNDED_CALL = re.compile(r'\s*(?:len|cap|strings\.(?:HasPrefix|HasSuffix|Contains\w*|EqualFold)|errors\.(?:Is|As)'
+                          r'|(?:\w+\.)?MatchString|slices\.Contains)\s*\(\s*\)\s*')
+# Calls whose result is made of their arguments
+FORMAT_CALL = re.compile(r'\s*fmt\.Sprint[fl]?\s*\(\s*\)\s*')
+CALL = re.compile(r'(?<![\w.])(?:\w+\.)?(\w+)\s*\(')
+# Passes over the call graph; values are followed this many calls deep
+MAX_DEPTH = 4
+
+@dataclass(frozen=True)
+class Source:
+    """Where a value enters the program"""
+
+    kind: str
+    # Form field or header name, "" when not a constant
+    field: str
+    path: str
+    line: int
+
+    @property
+    def what(self) -> str:
+        if self.kind == "client address":
+            return f"the request's {self.field}" if self.field else "the client's address"
+        if self.kind in ("URL path", "query string"):
+            return f"the request's {self.kind}"
+        if self.kind == "request field":
+            return f"the gRPC request's {self.field} field"
+        if self.kind == "command-line flag":
+            return f"the -{self.field} flag" if self.field else "a command-line flag"
+        if self.kind == "command-line argument":
+            return "a command-line argument"
+        return f'the request\'s "{self.field}" {self.kind}' if self.field else f"a request {self.kind}"
+
+    @property
+    def where(self) -> str:
+        return f"{self.path}:{self.line}"
+
+def _inputs(fn: GoFunc) -> Dict[str, str]:
+    """Parameters of fn carrying user input, by what they are: http, grpc, args or the framework"""
+
+    params = parse_params(fn.params)
+    types = [type_ for _, type_ in params]
+    found = {}
+    for name, type_ in params:
+        if not name:
+            continue
+        if re.fullmatch(r'\*\s*http\.Request', type_):
+            found[name] = "http"
+        elif GRPC_REQUEST.fullmatch(type_) and "context.Context" in types:
+            found[name] = "grpc"
+        elif type_ == "[]string" and any(re.fullmatch(r'\*\s*cobra\.Command', t) for t in types):
+            found[name] = "args"
+        else:
+            for framework, (pattern, _) in FRAMEWORKS.items():
+                if pattern.fullmatch(type_):
+                    found[name] = framework
+    return found
+
+def _literal(source: GoFile, start: int, end: int) -> str:
+    """The string a field or flag name argument holds, a constant's included; "" when unknown"""
+
+    argument = source.code[start:end]
+    field = string_literal(argument)
+    if field is None and re.fullmatch(r'\w+', argument):
+        field = string_literal(source.constants.get(argument, "") or "")
+    return field or ""
+
+def _read(source: GoFile, inputs: Dict[str, str], start: int, end: int) -> Optional[Source]:
+    """The first value read from user input between start and end"""
+
+    def is_request(receiver: str) -> bool:
+        return inputs.get(receiver) == "http" or receiver.endswith(".Request")
+
+    found: List[Tuple[int, str, str]] = []
+    for m in ACCESSOR.finditer(source.masked, start, end):
+        if is_request(m.group(1)):
+            field = ACCESSOR_HEADERS.get(m.group(2)) or _literal(source, m.start(3), m.end(3))
+            found.append((m.start(), REQUEST_ACCESSORS[m.group(2)], field))
+    for m in REQUEST_FIELD.finditer(source.masked, start, end):
+        if is_request(m.group(1)):
+            kind = REQUEST_FIELDS[m.group(2)]
+            found.append((m.start(), kind, m.group(2) if kind == "client address" else ""))
+    for m in ROUTER_PARAM.finditer(source.masked, start, end):
+        if is_request(m.group(1) or m.group(3)):
+            field = _literal(source, *m.span(2)) if m.group(1) else _literal(source, *m.span(4))
+            found.append((m.start(), "path parameter", field))
+    for m in METHOD_CALL.finditer(source.masked, start, end):
+        accessors = FRAMEWORKS[inputs[m.group(1)]][1] if inputs.get(m.group(1)) in FRAMEWORKS else {}
+        if m.group(2) in accessors:
+            kind = accessors[m.group(2)]
+            field = m.group(2) if kind == "client address" else _literal(source, m.start(3), m.end(3))
+            found.append((m.start(), kind, field))
+    for m in MESSAGE_FIELD.finditer(source.masked, start, end):
+        if inputs.get(m.group(1)) == "grpc":
+            found.append((m.start(), "request field", m.group(2) or m.group(3)))
+    for name in [n for n, kind in inputs.items() if kind == "args"]:
+        m = re.compile(r'(?<![\w.])' + re.escape(name) + r'\b').search(source.masked, start, end)
+        if m:
+            found.append((m.start(), "command-line argument", ""))
+    for m in CLI_ARGS.finditer(source.masked, start, end):
+        found.append((m.start(), "command-line argument", ""))
+    for m in CLI_FLAG.finditer(source.masked, start, end):
+        found.append((m.start(), "command-line flag", _literal(source, m.start(1), m.end(1))))
+    if not found:
+        return None
+    pos, kind, field = min(found)
+    return Source(kind, field, source.path, source.line_of(pos))
+
+def _flag_vars(source: GoFile) -> Dict[str, Source]:
+    """Package-level variables holding flag values: var user = flag.String("user", ...), and the
+    targets of flag.StringVar(&user, "user", ...) wherever it is called"""
+
+    found = {}
+    for m in CLI_FLAG_VAR.finditer(source.masked):
+        found[m.group(1)] = Source("command-line flag", _literal(source, *m.span(2)), source.path,
+                                   source.line_of(m.start()))
+    for m in re.finditer(r'(?m)^\s*(?:var\s+)?(\w+)(?:\s+\*?\w+)?\s*=\s*', source.masked):
+        flag = CLI_FLAG.match(source.masked, m.end())
+        if flag and source.func_at(m.start()) is None:
+            found[m.group(1)] = Source("command-line flag", _literal(source, *flag.span(1)), source.path,
+                                       source.line_of(m.start(1)))
+    return found
+
+def _flat(masked: str, start: int, end: int) -> str:
+    """masked[start:end] with whatever is inside brackets blanked, so operators and calls of the
+    expression itself stand out"""
+
+    out, depth = list(masked[start:end]), 0
+    for i, ch in enumerate(out):
+        if ch in ")]}":
+            depth -= 1
+        if depth > 0 and ch != "\n":
+            out[i] = " "
+        if ch in "([{":
+            depth += 1
+    return "".join(out)
+
+def _parts(masked: str, start: int, end: int) -> List[Tuple[int, int]]:
+    """Pieces of the expression between start and end that can carry user input: none for a
+    comparison, a boolean or a length; concatenations and fmt.Sprint calls piece by piece"""
+
+    while start < end and masked[start].isspace():
+        start += 1
+    while end > start and masked[end - 1].isspace():
+        end -= 1
+    if start < end and masked[start] == "(" and match_bracket(masked, start) == end - 1:
+        return _parts(masked, start + 1, end - 1)
+    flat = _flat(masked, start, end)
+    if COMPARISON.search(flat) or BOUNDED_CALL.fullmatch(flat):
+        return []
+    if "+" in flat:
+        parts, last = [], start
+        for m in re.finditer(r'\+', flat):
+            parts += _parts(masked, last, start + m.start())
+            last = start + m.end()
+        return parts + _parts(masked, last, end)
+    if FORMAT_CALL.fullmatch(flat):
+        open_paren = start + flat.index("(")
+        close = match_bracket(masked, open_paren)
+        return [p for s, e in split_args(masked, open_paren + 1, close) for p in _parts(masked, s, e)]
+    return [(start, end)] if start < end else []
+
+# An assignment: (offset, end of the block it is in, the user input the value carries or None)
+Assignment = Tuple[int, int, Optional[Source]]
+
+def _live(assigned: Dict[str, List[Assignment]], pos: int) -> Dict[str, Source]:
+    """Variables holding user input at pos: assigned it before pos, and not assigned another value
+    since, in a block that holds pos"""
+
+    live = {}
+    for name, assignments in assigned.items():
+        origin = None
+        for at, block_end, value in assignments:
+            if at >= pos:
+                break
+            if value is not None:
+                origin = value
+            elif pos <= block_end:
+                origin = None
+        if origin is not None:
+            live[name] = origin
+    return live
+
+class Taint:
+    """Values from user input in every function of the code, by variable"""
+
+    def __init__(self, sources: List[GoFile]):
+        self.sources = [s for s in sources if not s.path.endswith("_test.go")]
+        self.functions: Dict[str, List[Tuple[GoFile, GoFunc]]] = {}
+        for source in self.sources:
+            for fn in source.functions:
+                if not fn.is_literal and fn.name:
+                    self.functions.setdefault(fn.name, []).append((source, fn))
+        # (path, function start) -> variable -> its assignments in order, parameters included
+        self._assigned: Dict[Tuple[str, int], Dict[str, List[Assignment]]] = {}
+        # function name -> the source of a value it returns
+        self.returns: Dict[str, Source] = {}
+        # package directory -> package-level variables set from flags
+        self.package: Dict[str, Dict[str, Source]] = {}
+        for source in self.sources:
+            self.package.setdefault(os.path.dirname(source.path), {}).update(_flag_vars(source))
+        seeds: Dict[Tuple[str, int], Dict[str, Source]] = {}
+        for _ in range(MAX_DEPTH):
+            changed = False
+            for defs in self.functions.values():
+                for source, fn in defs:
+                    key = (source.path, fn.start)
+                    package = self.package.get(os.path.dirname(source.path), {})
+                    assigned = self._propagate(source, fn, {**package, **seeds.get(key, {})})
+                    if assigned != self._assigned.get(key):
+                        self._assigned[key] = assigned
+                        changed = True
+                    changed |= self._pass_on(source, fn, assigned, seeds)
+            if not changed:
+                break
+
+    def tainted(self, source: GoFile, fn: GoFunc, pos: int) -> Dict[str, Source]:
+        """Variables of fn (a function literal: of the function it is in) holding user input at pos"""
+
+        outer = fn
+        while outer is not None and outer.is_literal:
+            outer = source.func_at(outer.start - 1, include_literals=True)
+        if outer is None:
+            return {}
+        return _live(self._assigned.get((source.path, outer.start), {}), pos)
+
+    def origin(self, source: GoFile, fn: GoFunc, start: int, end: int,
+               tainted: Optional[Dict[str, Source]] = None) -> Optional[Source]:
+        """The user input the expression between start and end carries, unless it is sanitized or
+        its value is bounded (a comparison, a boolean, a length)"""
+
+        code = source.code[start:end]
+        if SANITIZER.search(code):
+            return None
+        inputs = _inputs(fn)
+        outer = fn
+        while outer.is_literal:
+            parent = source.func_at(outer.start - 1, include_literals=True)
+            if parent is None:
+                break
+            outer = parent
+            inputs = {**_inputs(outer), **inputs}
+        tainted = self.tainted(source, fn, start) if tainted is None else tainted
+        for part_start, part_end in _parts(source.masked, start, end):
+            read = _read(source, inputs, part_start, part_end)
+            if read is not None:
+                return read
+            for name, origin in tainted.items():
+                if re.search(r'(?<![\w.])' + re.escape(name) + r'\b(?!\s*\()', source.masked[part_start:part_end]):
+                    return origin
+            for m in CALL.finditer(source.masked, part_start, part_end):
+                if m.group(1) in self.returns:
+                    return self.returns[m.group(1)]
+        return None
+
+    def _propagate(self, source: GoFile, fn: GoFunc, seeds: Dict[str, Source]) -> Dict[str, List[Assignment]]:
+        """The assignments of fn in order, with the user input each value carries: seeds (parameters,
+        flag variables) where fn starts, then each local as its value is computed from those before"""
+
+        assigned = {name: [(fn.start, fn.body_end, origin)] for name, origin in seeds.items()}
+        for m in ASSIGNMENT.finditer(source.masked, fn.body_start, fn.body_end):
+            if m.group(1) == "_":
+                continue
+            inner = source.func_at(m.start(), include_literals=True) or fn
+            origin = self.origin(source, inner, m.start(2), m.end(2), _live(assigned, m.start()))
+            assigned.setdefault(m.group(1), []).append((m.start(), source.block_end(m.start(), fn), origin))
+        return assigned
+
+    def _pass_on(self, source: GoFile, fn: GoFunc, assigned: Dict[str, List[Assignment]],
+                 seeds: Dict[Tuple[str, int], Dict[str, Source]]) -> bool:
+        """Seed the parameters of the functions fn passes user input to, and record whether fn
+        returns some; True when anything new was found"""
+
+        changed = False
+        for m in CALL.finditer(source.masked, fn.body_start, fn.body_end):
+            callee = m.group(1)
+            if callee not in self.functions or callee == fn.name:
+                continue
+            close = match_bracket(source.masked, m.end() - 1)
+            if close == -1:
+                continue
+            inner = source.func_at(m.start(), include_literals=True) or fn
+            tainted = _live(assigned, m.start())
+            for index, (start, end) in enumerate(split_args(source.masked, m.end(), close)):
+                origin = self.origin(source, inner, start, end, tainted)
+                if origin is None:
+                    continue
+                for callee_source, callee_fn in self.functions[callee]:
+                    params = [name for name, _ in parse_params(callee_fn.params)]
+                    if index < len(params) and params[index]:
+                        entry = seeds.setdefault((callee_source.path, callee_fn.start), {})
+                        if params[index] not in entry:
+                            entry[params[index]] = origin
+                            changed = True
+        if fn.name not in self.returns:
+            for m in re.finditer(r'\breturn\b([^\n;]*)', source.masked[fn.body_start:fn.body_end]):
+                pos = fn.body_start + m.start(1)
+                if source.func_at(pos, include_literals=True) is not fn:
+                    continue
+                origin = self.origin(source, fn, pos, fn.body_start + m.end(1), _live(assigned, pos))
+                if origin is not None:
+                    self.returns[fn.name] = origin
+                    changed = True
+                    break
+        return changed
@@ -0,0 +1,540 @@
+"""
+Exported telemetry: OTLP trace files (JSON, JSON lines from the Collector's file exporter, or
+protobuf) read back and held to the naming, attribute, cardinality and privacy conventions the
+rules check statically. Span names are grouped by their shape ("GET /users/{id}"), so each
+problem is reported once per operation with the number of spans that have it.
+"""
+
+import gzip
+import json
+import re
+import struct
+from collections import defaultdict
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterator, List, Optional, Set, Tuple
+
+from .config import Config
+from .conventions import event_name_problems, free_text_problems, span_name_problems, vocabulary_problems
+from .privacy.pii import pii_kind
+from .privacy.secrets import NOT_A_SECRET, SECRET_NAME, literal_secret
+from .registry import get_rule
+from .semconv import canonical_key
+from .traces.attributes import STATUS_KEY
+
+# OTLP SpanKind
+KINDS = {0: "unspecified", 1: "internal", 2: "server", 3: "client", 4: "producer", 5: "consumer"}
+
+# Pieces of a recorded name that identify one request or entity, and what they stand for
+ID_TOKENS = [
+    (re.compile(r'[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}'), "{id}", "a UUID"),
+    (re.compile(r'[^\s@/]+@[^\s@/]+\.\w+'), "{email}", "an email address"),
+    (re.compile(r'(?<![\w])(?=[0-9a-fA-F]*\d)(?=[0-9a-fA-F]*[a-fA-F])[0-9a-fA-F]{16,}(?![\w])'), "{id}", "a hex ID"),
+    (re.compile(r'(?<![\w.])\d+(?![\w])'), "{id}", "a number"),
+]
+
+# Rules whose checks apply to exported spans
+CHECKED_RULES = ("span-name-convention", "span-name-unbounded", "span-event-name", "attribute-key-typo",
+                 "attribute-key-too-long", "attribute-value-enum", "secret-in-telemetry",
+                 "pii-in-telemetry")
+# Distinct names remembered per stem; enough to count them past any sensible max_values
+STEM_NAMES = 1000
+
+# Values already redacted or hashed
+REDACTED_VALUE = re.compile(r'^(?:\*+|\[?redacted\]?|<redacted>|x{3,}|[0-9a-f]{32}|[0-9a-f]{40}|[0-9a-f]{64})$', re.I)
+
+@dataclass
+class ExportedSpan:
+    name: str
+    kind: str
+    service: str
+    scope: str
+    attributes: Dict[str, Any] = field(default_factory=dict)
+    # (name, attributes) of each span event
+    events: List[Tuple[str, Dict[str, Any]]] = field(default_factory=list)
+
+@dataclass
+class TraceFinding:
+    """One problem shared by the spans of one operation (span name shape)"""
+    rule_id: str
+    severity: str
+    span_name: str
+    message: str
+    suggestion: str
+    spans: int = 0
+    # service.name -> spans
+    services: Dict[str, int] = field(default_factory=dict)
+    # A recorded name or value showing the problem
+    example: str = ""
+
+    def to_dict(self) -> Dict:
+        return {
+            "rule_id": self.rule_id,
+            "severity": self.severity,
+            "span_name": self.span_name,
+            "message": self.message,
+            "suggestion": self.suggestion,
+            "spans": self.spans,
+            "services": sorted(s for s in self.services if s),
+            "example": self.example,
+        }
+
+@dataclass
+class SpanViolation:
+    """A finding on one span, for annotating or redacting it in flight"""
+    rule_id: str
+    severity: str
+    message: str
+    # Attribute the finding is about, "" for the span itself; event is the index of the span
+    # event holding it, -1 for the span's own attributes
+    attribute: str = ""
+    event: int = -1
+
+    def to_dict(self) -> Dict:
+        return {"rule_id": self.rule_id, "severity": self.severity, "message": self.message,
+                "attribute": self.attribute, "event": self.event}
+
+# --- OTLP JSON -------------------------------------------------------------------------------
+
+def _get(data: Dict, camel: str, default=None):
+    """Field of an OTLP JSON object, in the lowerCamelCase the spec uses or the proto's snake_case"""
+
+    return data.get(camel, data.get(re.sub(r'(?<=[a-z])(?=[A-Z])', "_", camel).lower(), default))
+
+def _object(value: Any, what: str) -> Dict:
+    """value as a JSON object, {} for null; ValueError for anything else"""
+
+    if value is None:
+        return {}
+    if not isinstance(value, dict):
+        raise ValueError(f"{what} is not an object")
+    return value
+
+def _array(value: Any, what: str) -> List:
+    if value is None:
+        return []
+    if not isinstance(value, list):
+        raise ValueError(f"{what} is not an array")
+    return value
+
+def _string(value: Any, what: str) -> str:
+    if value is None:
+        return ""
+    if not isinstance(value, str):
+        raise ValueError(f"{what} is not a string")
+    return value
+
+def _json_value(value: Dict) -> Any:
+    for key, types in (("stringValue", str), ("boolValue", bool), ("doubleValue", (int, float, str)),
+                       ("bytesValue", str)):
+        v = _get(value, key)
+        if v is None:
+            continue
+        # bools are ints in Python
+        if not isinstance(v, types) or (key == "doubleValue" and isinstance(v, bool)):
+            raise ValueError(f"{key} is not a {'number' if key == 'doubleValue' else types.__name__}")
+        if key == "doubleValue" and isinstance(v, str):
+            # Proto JSON writes "NaN", "Infinity" and "-Infinity", and accepts numbers as strings
+            try:
+                return float(v)
+            except ValueError:
+                raise ValueError("doubleValue is not a number")
+        return v
+    if _get(value, "intValue") is not None:
+        # int64 is a string in OTLP JSON, and a number in what some exporters write
+        v = _get(value, "intValue")
+        if isinstance(v, bool) or not isinstance(v, (int, str)):
+            raise ValueError("intValue is not an integer")
+        try:
+            return int(v)
+        except ValueError:
+            raise ValueError("intValue is not an integer")
+    if _get(value, "arrayValue") is not None:
+        values = _array(_object(_get(value, "arrayValue"), "arrayValue").get("values"), "arrayValue.values")
+        return [_json_value(_object(v, "array element")) for v in values]
+    if _get(value, "kvlistValue") is not None:
+        return _json_attributes(_object(_get(value, "kvlistValue"), "kvlistValue").get("values"))
+    return None
+
+def _json_attributes(attributes: Any) -> Dict[str, Any]:
+    found = {}
+    for a in _array(attributes, "attributes"):
+        a = _object(a, "attribute")
+        found[_string(a.get("key"), "attribute key")] = _json_value(_object(a.get("value"), "attribute value"))
+    return found
+
+def _json_kind(kind: Any) -> str:
+    if isinstance(kind, int) and not isinstance(kind, bool):
+        return KINDS.get(kind, "unspecified")
+    return _string(kind, "span kind").lower().replace("span_kind_", "") or "unspecified"
+
+def _json_spans(data: Any) -> Iterator[ExportedSpan]:
+    """Spans of one OTLP JSON document; ValueError for anything not shaped like one"""
+
+    for resource_spans in _array(_get(_object(data, "document"), "resourceSpans"), "resourceSpans"):
+        resource_spans = _object(resource_spans, "resourceSpans element")
+        resource = _json_attributes(_object(resource_spans.get("resource"), "resource").get("attributes"))
+        service = str(resource.get("service.name", ""))
+        scopes = _get(resource_spans, "scopeSpans") or _get(resource_spans, "instrumentationLibrarySpans")
+        for scope_spans in _array(scopes, "scopeSpans"):
+            scope_spans = _object(scope_spans, "scopeSpans element")
+            scope = _object(scope_spans.get("scope") or _get(scope_spans, "instrumentationLibrary"), "scope")
+            scope_name = _string(scope.get("name"), "scope name")
+            for span in _array(scope_spans.get("spans"), "spans"):
+                span = _object(span, "span")
+                events = []
+                for e in _array(span.get("events"), "events"):
+                    e = _object(e, "event")
+                    events.append((_string(e.get("name"), "event name"), _json_attributes(e.get("attributes"))))
+                yield ExportedSpan(
+                    name=_string(span.get("name"), "span name"),
+                    kind=_json_kind(span.get("kind")),
+                    service=service,
+                    scope=scope_name,
+                    attributes=_json_attributes(span.get("attributes")),
+                    events=events,
+                )
+
+# --- OTLP protobuf ---------------------------------------------------------------------------
+# Just enough of the wire format to read TracesData / ExportTraceServiceRequest, which share
+# their field numbers, without depending on generated protobuf code.
+
+def _varint(buf: bytes, i: int) -> Tuple[int, int]:
+    value, shift = 0, 0
+    while True:
+        if i >= len(buf):
+            raise ValueError("truncated varint")
+        b = buf[i]
+        value |= (b & 0x7F) << shift
+        i += 1
+        if not b & 0x80:
+            return value, i
+        shift += 7
+
+def _fields(buf: bytes) -> Iterator[Tuple[int, Any]]:
+    """(field number, value) of a message: ints for varint and fixed fields, bytes for the rest"""
+
+    i = 0
+    while i < len(buf):
+        tag, i = _varint(buf, i)
+        number, wire = tag >> 3, tag & 7
+        if wire == 0:
+            value, i = _varint(buf, i)
+        elif wire == 1:
+            value, i = int.from_bytes(buf[i:i + 8], "little"), i + 8
+        elif wire == 2:
+            length, i = _varint(buf, i)
+            value, i = buf[i:i + length], i + length
+        elif wire == 5:
+            value, i = int.from_bytes(buf[i:i + 4], "little"), i + 4
+        else:
+            raise ValueError(f"unsupported wire type {wire}")
+        if i > len(buf):
+            raise ValueError("truncated message")
+        yield number, value
+
+def _bytes(value: Any, what: str) -> bytes:
+    """A length-delimited field's value; ValueError when the field came with another wire type"""
+
+    if not isinstance(value, bytes):
+        raise ValueError(f"{what} is not length-delimited")
+    return value
+
+def _int(value: Any, what: str) -> int:
+    if not isinstance(value, int):
+        raise ValueError(f"{what} is length-delimited")
+    return value
+
+def _text(value: Any, what: str) -> str:
+    return _bytes(value, what).decode("utf-8", "replace")
+
+def _proto_value(buf: bytes) -> Any:
+    for number, value in _fields(buf):
+        if number == 1:
+            return _text(value, "string_value")
+        if number == 2:
+            return bool(_int(value, "bool_value"))
+        if number == 3:
+            value = _int(value, "int_value")
+            return value - (1 << 64) if value >= 1 << 63 else value
+        if number == 4:
+            return struct.unpack("<d", _int(value, "double_value").to_bytes(8, "little"))[0]
+        if number == 5:
+            return [_proto_value(_bytes(v, "array value")) for n, v in _fields(_bytes(value, "array_value")) if n == 1]
+        if number == 6:
+            return _proto_attributes(v for n, v in _fields(_bytes(value, "kvlist_value")) if n == 1)
+        if number == 7:
+            return _bytes(value, "bytes_value")
+    return None
+
+def _proto_attributes(key_values) -> Dict[str, Any]:
+    attributes = {}
+    for kv in key_values:
+        key, value = "", None
+        for number, v in _fields(_bytes(kv, "attribute")):
+            if number == 1:
+                key = _text(v, "attribute key")
+            elif number == 2:
+                value = _proto_value(_bytes(v, "attribute value"))
+        attributes[key] = value
+    return attributes
+
+def _proto_span(buf: bytes, service: str, scope: str) -> ExportedSpan:
+    span = ExportedSpan(name="", kind="unspecified", service=service, scope=scope)
+    attributes = []
+    for number, value in _fields(buf):
+        if number == 5:
+            span.name = _text(value, "span name")
+        elif number == 6:
+            span.kind = KINDS.get(_int(value, "span kind"), "unspecified")
+        elif number == 9:
+            attributes.append(value)
+        elif number == 11:
+            name, event_attributes = "", []
+            for n, v in _fields(_bytes(value, "event")):
+                if n == 2:
+                    name = _text(v, "event name")
+                elif n == 3:
+                    event_attributes.append(v)
+            span.events.append((name, _proto_attributes(event_attributes)))
+    span.attributes = _proto_attributes(attributes)
+    return span
+
+def _proto_spans(buf: bytes) -> Iterator[ExportedSpan]:
+    for number, resource_spans in _fields(buf):
+        if number != 1:
+            continue
+        parts = list(_fields(_bytes(resource_spans, "resource_spans")))
+        resource = _bytes(next((v for n, v in parts if n == 1), b""), "resource")
+        service = str(_proto_attributes(v for n, v in _fields(resource) if n == 1).get("service.name", ""))
+        # 2: scope_spans, 1000: the deprecated instrumentation_library_spans of the same shape
+        for scope_spans in (v for n, v in parts if n in (2, 1000)):
+            scope_parts = list(_fields(_bytes(scope_spans, "scope_spans")))
+            scope = _bytes(next((v for n, v in scope_parts if n == 1), b""), "scope")
+            scope_name = next((_text(v, "scope name") for n, v in _fields(scope) if n == 1), "")
+            for n, span in scope_parts:
+                if n == 2:
+                    yield _proto_span(_bytes(span, "span"), service, scope_name)
+
+def _length_prefixed(buf: bytes) -> Iterator[bytes]:
+    """Messages of the Collector file exporter's proto format, each after a 4-byte big-endian length"""
+
+    i = 0
+    while i < len(buf):
+        if i + 4 > len(buf):
+            raise ValueError("truncated length prefix")
+        length = int.from_bytes(buf[i:i + 4], "big")
+        if i + 4 + length > len(buf):
+            raise ValueError("truncated message")
+        yield buf[i + 4:i + 4 + length]
+        i += 4 + length
+
+def read_spans(path: str) -> List[ExportedSpan]:
+    with open(path, "rb") as f:
+        return parse_spans(f.read())
+
+def parse_spans(buf: bytes) -> List[ExportedSpan]:
+    """Spans of an OTLP JSON document, JSON lines of them, or OTLP protobuf (optionally gzipped).
+    Raises ValueError when buf is none of these."""
+
+    try:
+        return _parse_spans(buf)
+    except RecursionError:
+        raise ValueError("nested too deeply")
+
+def _parse_spans(buf: bytes) -> List[ExportedSpan]:
+    if buf[:2] == b"\x1f\x8b":
+        try:
+            buf = gzip.decompress(buf)
+        except (OSError, EOFError) as e:
+            raise ValueError(f"bad gzip data: {e}")
+    if buf.lstrip()[:1] in (b"{", b"["):
+        try:
+            return _parse_json(buf)
+        except ValueError:
+            # The tag of a bare message is a newline, and its length may be "{" or "["
+            if buf[:1] != b"\x0a":
+                raise
+    # A bare message starts with the tag of field 1 (resource_spans); a length prefix with zeros
+    messages = [buf] if buf[:1] == b"\x0a" else list(_length_prefixed(buf))
+    return [span for message in messages for span in _proto_spans(message)]
+
+def _parse_json(buf: bytes) -> List[ExportedSpan]:
+    text = buf.decode("utf-8")
+    try:
+        documents = json.loads(text)
+        documents = documents if isinstance(documents, list) else [documents]
+    except json.JSONDecodeError:
+        documents = [json.loads(line) for line in text.splitlines() if line.strip()]
+    return [span for document in documents for span in _json_spans(document)]
+
+# --- Checks ----------------------------------------------------------------------------------
+
+def name_shape(name: str) -> Tuple[str, List[str]]:
+    """name with its IDs, numbers and email addresses replaced by placeholders, and what they were"""
+
+    found = []
+    for pattern, placeholder, what in ID_TOKENS:
+        if pattern.search(name):
+            found.append(what)
+            name = pattern.sub(placeholder, name)
+    return name, found
+
+def _stem(name: str) -> Optional[str]:
+    """name without its last segment, which varies in names like "GET /files/report.pdf" """
+
+    cut = max(name.rfind(c) for c in " /:")
+    return name[:cut + 1] + "*" if cut > 0 else None
+
+class TraceChecker:
+    """Applies the enabled rules' checks to exported spans, aggregated per span name shape"""
+
+    def __init__(self, config: Optional[Config] = None):
+        config = config or Config()
+        self.config = config
+        self.enabled = {r.rule_id for r in config.select_rules()}
+        self._options = {rule_id: {**get_rule(rule_id).options, **config.options.get(rule_id, {})}
+                         for rule_id in CHECKED_RULES}
+        self.findings: Dict[Tuple[str, str, str], TraceFinding] = {}
+        # stem -> distinct names (up to STEM_NAMES) and spans per service, for names varying in
+        # a segment that isn't an ID
+        self._stems: Dict[str, Tuple[Set[str], Dict[str, int]]] = {}
+        # service.name -> spans
+        self.services: Dict[str, int] = defaultdict(int)
+        self.spans = 0
+        # Findings on the span check() is looking at
+        self._current: Optional[List[SpanViolation]] = None
+
+    def options(self, rule_id: str) -> Dict:
+        return self._options[rule_id]
+
+    def severity(self, rule_id: str) -> str:
+        return self.config.severity.get(rule_id) or get_rule(rule_id).severity
+
+    def _report(self, rule_id: str, shape: str, span: ExportedSpan, message: str, suggestion: str, example: str = "",
+                attribute: str = "", event: int = -1):
+        if rule_id not in self.enabled:
+            return
+        if self._current is not None:
+            self._current.append(SpanViolation(rule_id, self.severity(rule_id), message, attribute, event))
+        key = (rule_id, shape, message)
+        if key not in self.findings:
+            self.findings[key] = TraceFinding(rule_id, self.severity(rule_id), shape, message, suggestion, example=example)
+        finding = self.findings[key]
+        finding.spans += 1
+        finding.services[span.service] = finding.services.get(span.service, 0) + 1
+
+    def add(self, span: ExportedSpan):
+        self.spans += 1
+        self.services[span.service] += 1
+        shape, tokens = name_shape(span.name)
+        if tokens:
+            self._report("span-name-unbounded", shape, span,
+                         f"Span names embed {' and '.join(dict.fromkeys(tokens))}, so every request gets its own name",
+                         "Use a fixed name (the operation, or the route template for HTTP) and record the value as "
+                         "an attribute", example=span.name)
+        else:
+            stem = _stem(span.name)
+            if stem:
+                names, services = self._stems.setdefault(stem, (set(), defaultdict(int)))
+                if len(names) < STEM_NAMES:
+                    names.add(span.name)
+                services[span.service] += 1
+        self._check_name(span, shape)
+        self._check_attributes(span, shape, span.attributes, "attribute")
+        for index, (event, attributes) in enumerate(span.events):
+            if "span-event-name" in self.enabled:
+                problems = event_name_problems(event, span.name)
+                if problems:
+                    self._report("span-event-name", shape, span, f'Event "{event}" {"; ".join(problems)}',
+                                 "Name events with lowercase dot separated words and move values into attributes",
+                                 event=index)
+            self._check_attributes(span, shape, attributes, f'attribute of event "{event}"', index)
+
+    def check(self, span: ExportedSpan) -> List[SpanViolation]:
+        """add() the span and return its own findings. Names varying only in their last segment
+        are only judged by results(), over every span."""
+
+        self._current = []
+        try:
+            self.add(span)
+            return self._current
+        finally:
+            self._current = None
+
+    def _check_name(self, span: ExportedSpan, shape: str):
+        if "span-name-convention" not in self.enabled:
+            return
+        options = self.options("span-name-convention")
+        # The placeholders stand for the values reported as unbounded
+        problems = [p for p in span_name_problems(shape, options["separators"], options["max_length"], options["terms"])
+                    if not p.startswith("contains ")]
+        problems += vocabulary_problems(shape, options["verbs"], options["terms"])
+        if problems:
+            self._report("span-name-convention", shape, span, f'Span name {"; ".join(problems)}',
+                         "Name the span after the operation ('{verb} {object}') in the wrapper that starts it")
+
+    def _check_attributes(self, span: ExportedSpan, shape: str, attributes: Dict[str, Any], what: str, event: int = -1):
+        pii = self.options("pii-in-telemetry")
+        redacted = set(pii["redacted_keys"])
+        secrets = self.options("secret-in-telemetry")
+        too_long = self.options("attribute-key-too-long")
+        for key, value in attributes.items():
+            typo = canonical_key(key)
+            if typo:
+                self._report("attribute-key-typo", shape, span, f'{what.capitalize()} "{key}" looks like {typo[1]}',
+                             f'Record it as "{typo[0]}"', attribute=key, event=event)
+            if len(key) > too_long["max_length"] or key.count(".") + 1 > too_long["max_segments"]:
+                self._report("attribute-key-too-long", shape, span,
+                             f'{what.capitalize()} key "{key}" is {len(key)} characters and {key.count(".") + 1} '
+                             f'segments long', "Keep the key fixed and move identifiers into attribute values",
+                             attribute=key, event=event)
+            if isinstance(value, str) and STATUS_KEY.search(key):
+                problems = free_text_problems(value)
+                if problems:
+                    self._report("attribute-value-enum", shape, span, f'{what.capitalize()} "{key}" '
+                                 f'{"; ".join(problems)}', "Record one of a few fixed codes and put the details "
+                                 "in an event or log", example=value,
+                                 attribute=key, event=event)
+            if not isinstance(value, str) or key in redacted:
+                continue
+            if any(re.search(p, value) for p in secrets["allowlist"]):
+                continue
+            secret = literal_secret(value, secrets)
+            if secret is None and SECRET_NAME.search(key) and not NOT_A_SECRET.search(key) \
+                    and value.strip() and not REDACTED_VALUE.match(value.strip()):
+                secret = "a credential"
+            if secret:
+                self._report("secret-in-telemetry", shape, span, f'{what.capitalize()} "{key}" holds {secret}',
+                             "Stop recording it, or redact it in the wrapper or the Collector",
+                             attribute=key, event=event)
+                continue
+            personal = None if REDACTED_VALUE.match(value.strip()) else pii_kind(key, value, pii)
+            if personal:
+                self._report("pii-in-telemetry", shape, span,
+                             f'{what.capitalize()} "{key}" holds {personal}',
+                             f"Record an identifier or a hash instead, or add '{key}' to redacted_keys if "
+                             f"the pipeline redacts it", attribute=key, event=event)
+
+    def results(self) -> List[TraceFinding]:
+        """Findings so far, most spans first. Names varying in their last segment are judged on
+        every span seen, so spans may still be added afterwards."""
+
+        found = list(self.findings.values())
+        limit = self.options("span-name-unbounded")["max_values"]
+        for stem, (names, services) in self._stems.items():
+            if len(names) <= limit or "span-name-unbounded" not in self.enabled:
+                continue
+            count = f"{len(names)}{'+' if len(names) >= STEM_NAMES else ''}"
+            found.append(TraceFinding(
+                "span-name-unbounded", self.severity("span-name-unbounded"), stem,
+                f"{count} distinct span names differ only in their last segment",
+                "Use a fixed name (the route template for HTTP) and record the varying part as an attribute",
+                spans=sum(services.values()), services=dict(services), example=min(names),
+            ))
+        return sorted(found, key=lambda f: (-f.spans, f.rule_id, f.span_name))
+
+def analyze_spans(spans: List[ExportedSpan], config: Optional[Config] = None) -> List[TraceFinding]:
+    checker = TraceChecker(config)
+    for span in spans:
+        checker.add(span)
+    return checker.results()
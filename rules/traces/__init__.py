@@ -0,0 +1,5 @@
+"""
+Trace signal rules
+"""
+
+from . import processors, attributes, parenting, limits, sampling, boundaries, names, status, lifetime, propagators, jobs, dead, concurrency, granularity, options, input, values, links, deadlines
@@ -0,0 +1,582 @@
+"""
+Attribute construction rules
+"""
+
+import re
+from typing import Iterator, Optional, Tuple
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..fixes import import_edit, unused_import_edit
+from ..golang import GoFile, Arg, match_bracket, string_literal, split_args, split_plus
+from ..registry import rule
+from ..conventions import free_text_problems
+from ..semconv import (ENUM_VALUES, RENAMED_KEYS, SEMCONV_KEYS, SEMCONV_PKG, SEMCONV_VERSION, enum_value, go_constant,
+                       semconv_constant, semconv_version, canonical_key)
+
+ATTRIBUTE_PKG = "go.opentelemetry.io/otel/attribute"
+
+# Format verbs that only ever render a number or a bool
+VERB_CONSTRUCTORS = {
+    "d": "attribute.Int",
+    "t": "attribute.Bool",
+    "f": "attribute.Float64",
+    "g": "attribute.Float64",
+    "e": "attribute.Float64",
+}
+
+STRCONV_CONSTRUCTORS = {
+    "Itoa": "attribute.Int",
+    "FormatInt": "attribute.Int64",
+    "FormatUint": "attribute.Int64",
+    "FormatBool": "attribute.Bool",
+    "FormatFloat": "attribute.Float64",
+}
+
+def attribute_calls(source: GoFile, constructors: str = r'String|Int|Int64|Float64|Bool|StringSlice|IntSlice|Int64Slice|Float64Slice|BoolSlice'):
+    """attribute.<Constructor>(key, value) calls, honoring the import alias"""
+
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        yield from source.calls(re.escape(alias) + r'\.(?:' + constructors + r')')
+
+def _package_names(source: GoFile, path: str) -> Optional[str]:
+    """Regex alternation of the local names source imports path under, or None when it doesn't"""
+
+    names = [n for n in source.import_alias(path) if n not in ("_", ".")]
+    return "(?:" + "|".join(map(re.escape, names)) + ")" if names else None
+
+def _formatted_value(source: GoFile, value: Arg) -> Optional[Tuple[str, str]]:
+    """(suggested constructor, how the value was built) for numeric/bool data rendered to a string"""
+
+    text = value.text.strip()
+    if len(split_plus(text)) > 1:
+        return None
+    fmt_pkg, strconv_pkg = _package_names(source, "fmt"), _package_names(source, "strconv")
+    if fmt_pkg:
+        m = re.fullmatch(r'(' + fmt_pkg + r')\.Sprintf\s*\(\s*("[^"]*")\s*,\s*([^,]+)\)', text, re.S)
+        if m:
+            fmt = string_literal(m.group(2)) or ""
+            # A width or flags (%05d, %-8d, %+d) lay the value out in a way a typed value wouldn't keep
+            verb = re.fullmatch(r'%(?:\.\d+)?([dtfge])', fmt)
+            if verb:
+                return VERB_CONSTRUCTORS[verb.group(1)], f'{m.group(1)}.Sprintf("{fmt}", ...)'
+        m = re.fullmatch(r'(' + fmt_pkg + r')\.Sprint\s*\(\s*[^,]+\)', text, re.S)
+        if m:
+            return "the typed attribute constructor for the operand", f"{m.group(1)}.Sprint(...)"
+    if strconv_pkg:
+        m = re.fullmatch(r'(' + strconv_pkg + r')\.(\w+)\s*\(.*\)', text, re.S)
+        if m and m.group(2) in STRCONV_CONSTRUCTORS:
+            return STRCONV_CONSTRUCTORS[m.group(2)], f"{m.group(1)}.{m.group(2)}(...)"
+    return None
+
+@rule(
+    rule_id="attribute-stringified-number",
+    title="Use typed attribute constructors for numeric and boolean values",
+    category="performance",
+    signal="traces",
+    severity="low",
+    description="Rendering numbers or bools to strings with fmt/strconv before attribute.String allocates "
+                "on every call and loses the value type in the backend (no range queries, no aggregation).",
+    bad_example='''
+func recordItems(ctx context.Context, items int) {
+	_, span := tracer.Start(ctx, "POST /orders")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.items", strconv.Itoa(items)))
+}
+''',
+    good_example='''
+func recordItemsTyped(ctx context.Context, items int) {
+	_, span := tracer.Start(ctx, "POST /orders")
+	defer span.End()
+	span.SetAttributes(attribute.Int("order.items", items))
+}
+''',
+)
+def check_stringified_number(source: GoFile) -> Iterator[Diagnostic]:
+    for call in attribute_calls(source, r'String'):
+        if len(call.args) < 2:
+            continue
+        value = call.args[1]
+        formatted = _formatted_value(source, value)
+        if formatted:
+            constructor, built_with = formatted
+            yield Diagnostic(
+                pos=call.start,
+                message=f"Attribute {call.args[0].text} is a number/bool rendered with {built_with}",
+                suggestion=f"Pass the raw value to {constructor}({call.args[0].text}, ...) instead of formatting it",
+                confidence=0.9,
+            )
+            continue
+
+        # strconv.Itoa(secs) + "." + strconv.Itoa(ms): a number assembled from formatted parts. Text
+        # such as "retry-" + strconv.Itoa(n) makes it a label, which a typed value can't hold.
+        strconv_pkg, fmt_pkg = _package_names(source, "strconv"), _package_names(source, "fmt")
+        formatters = ([strconv_pkg + r'\.(?:' + "|".join(STRCONV_CONSTRUCTORS) + r')\s*\('] if strconv_pkg else []) + \
+                     ([fmt_pkg + r'\.Sprint\s*\('] if fmt_pkg else [])
+        operands = [o.strip() for o in split_plus(value.text)]
+        formatted = [o for o in operands if formatters and re.match(r'(?:' + "|".join(formatters) + r')', o) and o.endswith(")")]
+        if len(operands) > 1 and formatted and all(o in formatted or _numeric_text(o) for o in operands):
+            yield Diagnostic(
+                pos=call.start,
+                message=f"Attribute {call.args[0].text} is built by concatenating formatted numbers",
+                suggestion="Record the number with a typed constructor instead of assembling its digits into a string",
+                confidence=0.8,
+            )
+
+def _numeric_text(text: str) -> bool:
+    """Whether text is a string literal that could be part of a number ("", ".", "-")"""
+
+    literal = string_literal(text)
+    return literal is not None and re.fullmatch(r'[\d.+-]*', literal) is not None
+
+def _is_constant_attribute(source: GoFile, arg: Arg, aliases) -> bool:
+    text = arg.text.strip()
+    if re.fullmatch(r'semconv\.\w+', text):
+        return True
+    alias_re = "|".join(re.escape(a) for a in aliases) + "|semconv"
+    m = re.fullmatch(r'(?:' + alias_re + r')\.\w+\s*\((.*)\)', text, re.S)
+    if not m:
+        return False
+    masked = source.masked[arg.start:arg.end]
+    inner_start = arg.start + masked.index("(") + 1
+    parts = [source.code[s:e] for s, e in split_args(source.masked, inner_start, arg.end - 1)]
+    return all(_is_constant_expr(source, p) for p in parts)
+
+def _is_constant_expr(source: GoFile, text: str) -> bool:
+    text = text.strip()
+    if string_literal(text) is not None:
+        return True
+    if re.fullmatch(r'-?\d+(?:\.\d+)?|true|false', text):
+        return True
+    return text in source.constants
+
+def _attrs_var_name(source: GoFile, func_name: str, taken: set) -> str:
+    base = (func_name[:1].lower() + func_name[1:] if func_name else "common") + "Attrs"
+    name, n = base, 2
+    while name in taken or re.search(r'\b' + name + r'\b', source.masked):
+        name = f"{base}{n}"
+        n += 1
+    taken.add(name)
+    return name
+
+@rule(
+    rule_id="attribute-set-rebuilt",
+    title="Hoist constant attribute sets out of hot paths",
+    category="performance",
+    signal="traces",
+    severity="low",
+    autofix=True,
+    description="Attribute lists made only of constants are rebuilt (and allocated) on every call; "
+                "declaring them once at package level avoids the per-request cost.",
+    bad_example='''
+func handleCheckout(ctx context.Context) {
+	_, span := tracer.Start(ctx, "POST /checkout")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("service.tier", "gold"),
+		attribute.Bool("feature.fast_checkout", true),
+	)
+}
+''',
+    good_example='''
+var checkoutAttrs = []attribute.KeyValue{
+	attribute.String("service.tier", "gold"),
+	attribute.Bool("feature.fast_checkout", true),
+}
+
+func handleCheckoutHoisted(ctx context.Context) {
+	_, span := tracer.Start(ctx, "POST /checkout")
+	defer span.End()
+	span.SetAttributes(checkoutAttrs...)
+}
+''',
+)
+def check_attribute_set_rebuilt(source: GoFile) -> Iterator[Diagnostic]:
+    aliases = source.import_alias(ATTRIBUTE_PKG)
+    if not aliases:
+        return
+
+    candidates = []
+    for call in source.calls(r'[\w.]+\.SetAttributes|trace\.WithAttributes|metric\.WithAttributes'):
+        fn = source.func_at(call.start, include_literals=True)
+        if fn is None or not call.args or any(a.text.rstrip().endswith("...") for a in call.args):
+            continue
+        if all(_is_constant_attribute(source, a, aliases) for a in call.args):
+            key = tuple(re.sub(r'\s+', "", a.text) for a in call.args)
+            candidates.append((key, call, fn))
+
+    counts = {}
+    for key, _, _ in candidates:
+        counts[key] = counts.get(key, 0) + 1
+
+    names, taken = {}, set()
+    insert_at = source.decl_insert_pos()
+    for key, call, fn in candidates:
+        if len(call.args) < 2 and counts[key] < 2:
+            continue
+        if key not in names:
+            var_name = _attrs_var_name(source, source.function_name_at(call.start), taken)
+            body = "".join(f"\t{a.text.strip()},\n" for a in call.args)
+            decl = (f"\n\n// {var_name} never changes, so it is built once instead of per call.\n"
+                    f"var {var_name} = []{aliases[0]}.KeyValue{{\n{body}}}")
+            names[key] = (var_name, decl)
+        var_name, decl = names[key]
+
+        where = "in multiple places" if counts[key] > 1 else "on every call"
+        hint = ""
+        if call.name.startswith("metric."):
+            hint = " (for metrics, metric.WithAttributeSet(attribute.NewSet(...)) also avoids re-sorting)"
+        yield Diagnostic(
+            pos=call.start,
+            message=f"Constant attribute set with {len(call.args)} attribute(s) is rebuilt {where}",
+            suggestion=f"Declare it once as a package-level []attribute.KeyValue and pass {var_name}...{hint}",
+            confidence=0.85,
+            fix=Fix(
+                description=f"Hoist attributes into package-level {var_name}",
+                edits=[
+                    TextEdit(insert_at, insert_at, decl),
+                    TextEdit(call.args[0].start, call.args[-1].end, f"{var_name}..."),
+                ],
+            ),
+        )
+
+# Typed Key methods semconv constants offer for each attribute constructor
+KEY_METHODS = {
+    "String": "String", "Int": "Int", "Int64": "Int64", "Float64": "Float64", "Bool": "Bool",
+    "StringSlice": "StringSlice", "IntSlice": "IntSlice", "Int64Slice": "Int64Slice",
+    "Float64Slice": "Float64Slice", "BoolSlice": "BoolSlice",
+}
+
+def semconv_import(source: GoFile) -> Optional[Tuple[str, str]]:
+    """(local name, path) of the semconv/vX.Y.Z import of the file, else of another file of its
+    package (local name None: the file doesn't import it yet)"""
+
+    for name, path in source.imports.items():
+        if semconv_version(path):
+            return name, path
+    for other in source.package_sources:
+        for path in other.imports.values():
+            if semconv_version(path):
+                return None, path
+    return None
+
+@rule(
+    rule_id="semconv-constant-available",
+    title="Use semconv constants for standard attribute keys",
+    category="conventions",
+    signal="traces",
+    severity="low",
+    opt_in=True,
+    autofix=True,
+    description="A string literal key that semconv exports as a typed constant goes unnoticed when the "
+                "convention is renamed; with the constant, upgrading the semconv package turns the rename "
+                "into a compile error. Keys semconv has since renamed (\"http.method\", \"net.peer.name\") "
+                "are reported with the constant of their current name. The fix uses the semconv version the "
+                "package already imports (v1.26.0 when none does), adding the import to the file; files on "
+                "versions before v1.26 are reported without one, as their constants may be named differently.",
+    bad_example='''
+func traceOrderRequest(ctx context.Context, method string) {
+	_, span := tracer.Start(ctx, "GET /orders")
+	defer span.End()
+	span.SetAttributes(attribute.String("http.request.method", method))
+}''',
+    good_example='''
+func traceOrderRequestTyped(ctx context.Context, method string) {
+	_, span := tracer.Start(ctx, "GET /orders")
+	defer span.End()
+	span.SetAttributes(semconv.HTTPRequestMethodKey.String(method))
+}''',
+)
+def check_semconv_constant(source: GoFile) -> Iterator[Diagnostic]:
+    imported = semconv_import(source)
+    name, path = imported if imported else (None, f"{SEMCONV_PKG}/{SEMCONV_VERSION}")
+    semconv = name or "semconv"
+    # Constants are only known to match SEMCONV_KEYS from v1.26 on
+    fixable = semconv_version(path) >= (1, 26) and (name is not None or semconv not in source.imports)
+
+    def constant_for(key: Optional[str]) -> Optional[Tuple[str, str, str]]:
+        """(constant, current key, what renaming it means) for a key semconv defines, now or under
+        a new name"""
+
+        current = RENAMED_KEYS.get(key, key) if key else None
+        constant = semconv_constant(current) if current else None
+        if constant is None:
+            return None
+        renamed = f'is the deprecated name of "{current}", which semconv defines as {constant}' if current != key else ""
+        return constant, current, renamed
+
+    extra = [] if name else [import_edit(source, path, "semconv")]
+    # Drop the attribute import when the fixes replace every use of it
+    replaced = [(c.start, c.args[1].start) for c in attribute_calls(source)
+                if len(c.args) == 2 and constant_for(c.args[0].literal)]
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        replaced += [(c.start, c.end) for c in source.calls(re.escape(alias) + r'\.Key')
+                     if len(c.args) == 1 and constant_for(c.args[0].literal)]
+        unused = unused_import_edit(source, alias, replaced)
+        extra += [unused] if unused else []
+
+    for call in attribute_calls(source):
+        key = call.args[0].literal if call.args else None
+        found = constant_for(key)
+        if found is None:
+            continue
+        constant, current, renamed = found
+        use = f"{semconv}.{constant}.{KEY_METHODS[call.name.rsplit('.', 1)[-1]]}"
+        yield Diagnostic(
+            pos=call.args[0].start,
+            message=f"Attribute key \"{key}\" " + (renamed or f"is a string literal but semconv defines {constant}"),
+            suggestion=f"Use {use}(...) so a semconv rename fails to compile",
+            confidence=0.8 if renamed else 0.95,
+            fix=Fix(
+                description=f"Use {use}",
+                edits=[TextEdit(call.start, call.args[1].start, use + "(")] + extra,
+            ) if fixable and len(call.args) == 2 else None,
+        )
+
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        for call in source.calls(re.escape(alias) + r'\.Key'):
+            key = call.args[0].literal if len(call.args) == 1 else None
+            found = constant_for(key)
+            if found is None:
+                continue
+            constant, current, renamed = found
+            yield Diagnostic(
+                pos=call.start,
+                message=f"{call.name}(\"{key}\") " + (renamed or f"duplicates semconv's {constant}"),
+                suggestion=f"Use {semconv}.{constant}",
+                confidence=0.8 if renamed else 0.95,
+                fix=Fix(
+                    description=f"Use {semconv}.{constant}",
+                    edits=[TextEdit(call.start, call.end, f"{semconv}.{constant}")] + extra,
+                ) if fixable else None,
+            )
+
+@rule(
+    rule_id="attribute-key-typo",
+    title="Attribute keys must not misspell semconv keys",
+    category="conventions",
+    signal="traces",
+    severity="medium",
+    autofix=True,
+    description="A key one or two edits away from a semantic convention key (\"http.methd\", \"db.sytem\"), "
+                "with its words separated otherwise (\"user_id\", \"userId\" for user.id) or with the last "
+                "word of a segment cut off (\"http.status\" for http.status_code) is recorded as a separate "
+                "attribute, silently splitting the data that dashboards and queries for the real key rely on. "
+                "Keys are matched against the semconv key inventory shipped with the rules; renamed keys "
+                "are suggested under their current name, and the fix uses the semconv constant where the "
+                "file imports semconv.",
+    bad_example='''
+func traceQuery(ctx context.Context, system string) {
+	_, span := tracer.Start(ctx, "SELECT orders")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.sytem", system))
+}''',
+    good_example='''
+func traceQuerySpelled(ctx context.Context, system string) {
+	_, span := tracer.Start(ctx, "SELECT orders")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.system", system))
+}''',
+)
+def check_attribute_key_typo(source: GoFile) -> Iterator[Diagnostic]:
+    imported = semconv_import(source)
+    # Constants replace the key only where the file already imports a version that has them
+    semconv = imported[0] if imported and semconv_version(imported[1]) >= (1, 26) else None
+    # (key argument, call, typed Key method a semconv constant takes its place with: None for
+    # attribute.Key, "" when the call has no single value)
+    keys = [(call.args[0], call, KEY_METHODS[call.name.rsplit(".", 1)[-1]] if len(call.args) == 2 else "")
+            for call in attribute_calls(source) if call.args]
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        keys.extend((call.args[0], call, None) for call in source.calls(re.escape(alias) + r'\.Key')
+                    if len(call.args) == 1)
+    found = []
+    for arg, call, method in keys:
+        match = canonical_key(arg.literal) if arg.literal else None
+        if match is None:
+            continue
+        correct, how, confidence = match
+        constant = semconv_constant(correct) if semconv else None
+        if constant and method is None:
+            edit = TextEdit(call.start, call.end, f"{semconv}.{constant}")
+        elif constant and method:
+            edit = TextEdit(call.start, call.args[1].start, f"{semconv}.{constant}.{method}(")
+        else:
+            edit = TextEdit(arg.start, arg.end, f'"{correct}"')
+        found.append((arg, match, edit))
+    # Drop the attribute import when the fixes replace every use of it
+    extra = []
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        unused = unused_import_edit(source, alias, [(e.start, e.end) for _, _, e in found])
+        extra += [unused] if unused else []
+
+    for arg, (correct, how, confidence), edit in found:
+        if edit.start == arg.start:
+            constant = semconv_constant(correct)
+            use = f'"{correct}"' + (f" ({semconv or 'semconv'}.{constant})" if constant else "")
+        else:
+            use = edit.new_text + ("...)" if edit.new_text.endswith("(") else "")
+        yield Diagnostic(
+            pos=arg.start,
+            message=f"Attribute key \"{arg.literal}\" looks like {how}",
+            suggestion=f"Use {use} so the value lands in the same attribute as everywhere else",
+            confidence=confidence,
+            fix=Fix(
+                description=f"Replace \"{arg.literal}\" with {edit.new_text.rstrip('(')}",
+                edits=[edit] + (extra if edit.start != arg.start else []),
+            ),
+        )
+
+def _key_text(source: GoFile, arg: Arg) -> Optional[Tuple[str, bool]]:
+    """(key, built at runtime) for a literal key or a fmt.Sprintf format used as one"""
+
+    if arg.literal is not None:
+        return arg.literal, False
+    fmt_pkg = _package_names(source, "fmt")
+    if not fmt_pkg:
+        return None
+    m = re.fullmatch(fmt_pkg + r'\.Sprintf\(\s*("(?:[^"\\]|\\.)*")\s*,.*\)', arg.text.strip(), re.S)
+    if m and string_literal(m.group(1)) is not None:
+        return re.sub(r'%[-+# 0-9.]*[a-zA-Z]', "{}", string_literal(m.group(1))), True
+    return None
+
+@rule(
+    rule_id="attribute-key-too-long",
+    title="Attribute keys must be short and shallow",
+    category="conventions",
+    signal="traces",
+    severity="low",
+    description="Very long keys or keys with many dot segments usually carry data (IDs, tenant or item "
+                "names) in the key itself, which makes every value a new attribute for backends to index.",
+    options={
+        "max_length": 64,
+        # Dot separated segments; semconv keys use at most 5
+        "max_segments": 5,
+    },
+    bad_example='''
+func traceCart(ctx context.Context, itemID string, qty int) {
+	_, span := tracer.Start(ctx, "update cart")
+	defer span.End()
+	span.SetAttributes(attribute.Int(fmt.Sprintf("cart.items.%s.quantity.current.value", itemID), qty))
+}''',
+    good_example='''
+func traceCartItem(ctx context.Context, itemID string, qty int) {
+	_, span := tracer.Start(ctx, "update cart")
+	defer span.End()
+	span.SetAttributes(attribute.String("cart.item.id", itemID), attribute.Int("cart.item.quantity", qty))
+}''',
+)
+def check_attribute_key_shape(source: GoFile, options: dict) -> Iterator[Diagnostic]:
+    keys = [call.args[0] for call in attribute_calls(source) if call.args]
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        keys.extend(call.args[0] for call in source.calls(re.escape(alias) + r'\.Key') if len(call.args) == 1)
+    for arg in keys:
+        found = _key_text(source, arg)
+        if found is None:
+            continue
+        key, dynamic = found
+        segments = key.split(".")
+        problems = []
+        if len(segments) > options["max_segments"]:
+            problems.append(f"has {len(segments)} segments (max {options['max_segments']})")
+        if not dynamic and len(key) > options["max_length"]:
+            problems.append(f"is {len(key)} characters long (max {options['max_length']})")
+        if not problems:
+            continue
+        data = [seg for seg in segments if seg == "{}" or re.fullmatch(r'\d+|[0-9a-fA-F-]{8,}', seg)]
+        hint = " and embeds a value in a segment" if data else ""
+        yield Diagnostic(
+            pos=arg.start,
+            message=f"Attribute key \"{key}\" {' and '.join(problems)}{hint}",
+            suggestion="Keep the key fixed and move identifiers or item names into attribute values",
+            confidence=0.8 if data else 0.6,
+        )
+
+# Keys whose values should be one of a few codes even though semconv doesn't enumerate them
+STATUS_KEY = re.compile(r'^error\.type$|(?:^|[._])(?:status|state|result|outcome)$')
+
+def string_attributes(source: GoFile) -> Iterator[Tuple[str, Arg]]:
+    """(key, value argument) of string attributes with a literal key: attribute.String("k", v),
+    attribute.Key("k").String(v) and semconv.<Key>.String(v)"""
+
+    for call in attribute_calls(source, "String"):
+        if len(call.args) == 2 and call.args[0].literal is not None:
+            yield call.args[0].literal, call.args[1]
+    # Offset just after a key expression, and the key
+    keyed = []
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        keyed.extend((call.end, call.args[0].literal) for call in source.calls(re.escape(alias) + r'\.Key')
+                     if len(call.args) == 1 and call.args[0].literal is not None)
+    constants = {go_constant(k): k for k in SEMCONV_KEYS + list(ENUM_VALUES)}
+    for alias in source.import_alias(SEMCONV_PKG):
+        for m in re.finditer(r'(?<![\w.])' + re.escape(alias) + r'\.(\w+Key)\b', source.masked):
+            if m.group(1) in constants:
+                keyed.append((m.end(), constants[m.group(1)]))
+    for end, key in keyed:
+        method = re.match(r'\s*\.String\s*\(', source.masked[end:])
+        if not method:
+            continue
+        open_paren = end + method.end() - 1
+        args = split_args(source.masked, open_paren + 1, match_bracket(source.masked, open_paren))
+        if len(args) == 1:
+            start, stop = args[0]
+            yield key, Arg(source.code[start:stop], start, stop)
+
+@rule(
+    rule_id="attribute-value-enum",
+    title="Use the semconv values of enum attributes",
+    category="conventions",
+    signal="traces",
+    severity="medium",
+    autofix=True,
+    description="Semconv fixes the values of keys like http.request.method, db.system and "
+                "messaging.operation.type; \"get\" or \"Postgres\" doesn't match what backends, "
+                "instrumentation libraries and dashboards filter on. Status-like keys (error.type, "
+                "*.status, *.state, *.result) should likewise hold one of a few codes, not free text "
+                "such as \"APPROVED_OK_200_SUCCESS\".",
+    bad_example='''
+func traceCharge(ctx context.Context) {
+	_, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.system", "Postgres"),
+		attribute.String("payment.status", "APPROVED_OK_200_SUCCESS"))
+}''',
+    good_example='''
+func traceChargeEnum(ctx context.Context) {
+	_, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.system", "postgresql"),
+		attribute.String("payment.status", "approved"))
+}''',
+)
+def check_attribute_value_enum(source: GoFile) -> Iterator[Diagnostic]:
+    for key, value in string_attributes(source):
+        literal = value.literal
+        if literal is None:
+            continue
+        found = enum_value(key, literal)
+        if found is not None:
+            replacement, reason = found
+            allowed = ", ".join(ENUM_VALUES[key][:8]) + (", ..." if len(ENUM_VALUES[key]) > 8 else "")
+            yield Diagnostic(
+                pos=value.start,
+                end=value.end,
+                message=f'"{literal}" for {key} {reason}',
+                suggestion=f'Use "{replacement}"' if replacement else f"Use one of {allowed}",
+                confidence=0.9 if replacement else 0.6,
+                # _OTHER loses the method unless http.request.method_original is added by hand
+                fix=Fix(description=f'Replace "{literal}" with "{replacement}"',
+                        edits=[TextEdit(value.start, value.end, f'"{replacement}"')])
+                if replacement and replacement != "_OTHER" else None,
+            )
+        elif STATUS_KEY.search(key):
+            problems = free_text_problems(literal)
+            if problems:
+                yield Diagnostic(
+                    pos=value.start,
+                    end=value.end,
+                    message=f'"{literal}" for {key} {" and ".join(problems)}',
+                    suggestion="Record the error's type or a short code (\"timeout\", \"_OTHER\") and put the "
+                               "message in exception.message" if key == "error.type" else
+                               "Record a short lowercase code (\"approved\", \"declined\") and put details in "
+                               "another attribute or an event",
+                    confidence=0.7,
+                )
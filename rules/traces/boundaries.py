@@ -0,0 +1,285 @@
+"""
+Process boundaries: HTTP and gRPC handlers, outgoing HTTP requests, database calls and message
+publishes and consumes. These are where traces cross services, so each one should either start a
+span or go through an instrumentation library.
+"""
+
+import re
+from collections import OrderedDict
+from dataclasses import dataclass
+from typing import Dict, Iterator, List, Optional, Set
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, parse_params, string_literal
+from ..registry import rule
+
+KIND_LABELS = OrderedDict([
+    ("http.server", "HTTP handlers"),
+    ("rpc.server", "gRPC methods"),
+    ("http.client", "outgoing HTTP requests"),
+    ("db", "database calls"),
+    ("messaging.publish", "message publishes"),
+    ("messaging.consume", "message consumes"),
+])
+
+# What a function does at one or more boundaries of a kind, for messages
+KIND_PHRASES = {
+    "http.server": ("is an HTTP handler", "is an HTTP handler"),
+    "rpc.server": ("is a gRPC method", "is a gRPC method"),
+    "http.client": ("sends an HTTP request", "sends {} HTTP requests"),
+    "db": ("makes a database call", "makes {} database calls"),
+    "messaging.publish": ("publishes messages", "publishes messages at {} call sites"),
+    "messaging.consume": ("consumes messages", "consumes messages at {} call sites"),
+}
+
+# Handler parameter types per web framework, with the expression giving the request method
+HANDLER_PARAMS = [
+    (r'\*?http\.Request', "{}.Method"),
+    (r'\*gin\.Context', "{}.Request.Method"),
+    (r'echo\.Context', "{}.Request().Method"),
+    (r'\*fiber\.Ctx', "{}.Method()"),
+]
+ROUTE_METHODS = r'Handle|HandleFunc|GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|Get|Post|Put|Patch|Delete|Head|Options'
+
+HTTP_CLIENT_CALLS = (r'http\.(?:Get|Post|Head|PostForm)'
+                     r'|(?:http\.DefaultClient|(?:[\w.]+\.)?(?:client|httpClient|HTTPClient))\.(?:Do|Get|Post|Head)')
+
+DB_IMPORTS = ("database/sql", "github.com/jmoiron/sqlx", "github.com/jackc/pgx")
+DB_CALLS = r'[\w.]+\.(?:Query|QueryRow|Exec|Prepare)(?:Context)?|[\w.]+\.(?:QueryRowx|Queryx|Select|Get)Context'
+SQL_TARGET = re.compile(
+    r'^\s*(SELECT|INSERT|UPDATE|DELETE|UPSERT|REPLACE|MERGE)\b(?:.*?\b(?:FROM|INTO)\s+|\s+)([\w."]+)',
+    re.I | re.S)
+
+MESSAGING_IMPORTS = ("github.com/segmentio/kafka-go", "github.com/IBM/sarama", "github.com/Shopify/sarama",
+                     "github.com/confluentinc/confluent-kafka-go", "github.com/rabbitmq/amqp091-go",
+                     "github.com/streadway/amqp", "github.com/nats-io/nats.go", "cloud.google.com/go/pubsub")
+PUBLISH_CALLS = r'[\w.]+\.(?:WriteMessages|SendMessage|SendMessages|Produce|Publish|PublishWithContext|PublishMsg)'
+CONSUME_CALLS = r'[\w.]+\.(?:ReadMessage|FetchMessage|Consume|Subscribe|QueueSubscribe|Receive)'
+
+# Instrumentation libraries that cover a kind of boundary for the whole program once installed
+WRAPPERS = {
+    "http.server": r'\b(?:otelhttp\.(?:NewHandler|NewMiddleware|WithRouteTag)|otel(?:gin|echo|mux|chi|fiber)\.Middleware)\b',
+    "rpc.server": r'\botelgrpc\.(?:NewServerHandler|UnaryServerInterceptor|StreamServerInterceptor)\b',
+    "http.client": r'\botelhttp\.(?:NewTransport|DefaultClient)\b',
+}
+WRAPPER_IMPORTS = {
+    "db": r'otelsql|otelsqlx|otelpgx|otelgorm|gorm\.io/plugin/opentelemetry',
+    "messaging.publish": r'otel(?:sarama|kafka|amqp|nats|pubsub)|/splunk-otel-go/instrumentation/',
+    "messaging.consume": r'otel(?:sarama|kafka|amqp|nats|pubsub)|/splunk-otel-go/instrumentation/',
+}
+
+@dataclass
+class Boundary:
+    source: GoFile
+    kind: str
+    pos: int
+    func: Optional[GoFunc]
+    # Route, RPC method, table or destination when known
+    target: str = ""
+    # Span name semconv suggests for it; a Go expression when quoted is False
+    span_name: str = ""
+    quoted: bool = True
+    # Covered by an instrumentation library at registration (e.g. a route wrapped in otelhttp)
+    wrapped: bool = False
+
+    @property
+    def suggested_name(self) -> str:
+        return f'"{self.span_name}"' if self.quoted else self.span_name
+
+def _skipped(source: GoFile) -> bool:
+    return source.path.endswith("_test.go") or source.path.endswith(".pb.go")
+
+def _routes(sources: List[GoFile]) -> Dict[str, tuple]:
+    """Handler function name -> (method, route, wrapped) from router registrations"""
+
+    routes = {}
+    for source in sources:
+        for call in source.calls(r'[\w.]+\.(?:' + ROUTE_METHODS + r')'):
+            if len(call.args) < 2 or call.args[0].literal is None:
+                continue
+            handler = call.args[-1].text
+            name = re.search(r'(\w+)\s*\)*\s*$', handler)
+            if not name:
+                continue
+            method = call.name.rsplit(".", 1)[1].upper()
+            route = call.args[0].literal
+            if method in ("HANDLE", "HANDLEFUNC"):
+                # Go 1.22 patterns may carry the method: "GET /users/{id}"
+                method, _, path = route.partition(" ") if " " in route else ("", "", route)
+                route = path
+            routes[name.group(1)] = (method, route, bool(re.search(WRAPPERS["http.server"], handler)))
+    return routes
+
+def _http_handlers(source: GoFile, routes: Dict[str, tuple]) -> Iterator[Boundary]:
+    for fn in source.functions:
+        if fn.is_literal:
+            continue
+        params = parse_params(fn.params)
+        for pattern, method_expr in HANDLER_PARAMS:
+            request = next((name for name, typ in params if name and re.fullmatch(pattern, typ)), None)
+            if request is None:
+                continue
+            if pattern.endswith(r'http\.Request') and not any(t.endswith("http.ResponseWriter") for _, t in params):
+                continue
+            method, route, wrapped = routes.get(fn.name, ("", "", False))
+            if method and route:
+                name, quoted = f"{method} {route}", True
+            elif route:
+                name, quoted = f'{method_expr.format(request)} + " {route}"', False
+            else:
+                name, quoted = method_expr.format(request), False
+            yield Boundary(source, "http.server", fn.start, fn, route, name, quoted, wrapped)
+            break
+
+def _grpc_methods(source: GoFile) -> Iterator[Boundary]:
+    for m in re.finditer(r'\btype\s+(\w+)\s+struct\s*\{[^}]*?\bUnimplemented(\w+)Server\b', source.masked):
+        server, service = m.group(1), m.group(2)
+        for name, fn in source.methods_of(server).items():
+            params = parse_params(fn.params)
+            if not name[:1].isupper() or len(params) != 2 or not params[0][1].endswith("context.Context"):
+                continue
+            yield Boundary(source, "rpc.server", fn.start, fn, f"{service}/{name}", f"{service}/{name}")
+
+def _sql_target(text: str) -> str:
+    m = SQL_TARGET.match(text or "")
+    if not m:
+        return ""
+    return f"{m.group(1).upper()} {m.group(2).strip(chr(34))}"
+
+def _db_calls(source: GoFile) -> Iterator[Boundary]:
+    if not any(source.imports_path(p) for p in DB_IMPORTS):
+        return
+    for call in source.calls(DB_CALLS):
+        query_index = 1 if call.name.endswith("Context") else 0
+        if call.name.endswith(("SelectContext", "GetContext")):
+            query_index = 2
+        if len(call.args) <= query_index:
+            continue
+        query = call.args[query_index]
+        target = _sql_target(query.literal or string_literal(source.constants.get(query.text.strip(), "")))
+        yield Boundary(source, "db", call.start, source.func_at(call.start), target, target or "<operation> <table>")
+
+def _destination(source: GoFile, call, fn: Optional[GoFunc]) -> str:
+    for arg in call.args:
+        if arg.literal:
+            return arg.literal
+    if fn:
+        m = re.search(r'\b(?:Topic|Subject|Queue|Exchange)\s*:\s*"([^"]+)"', source.code[fn.body_start:fn.body_end])
+        if m:
+            return m.group(1)
+    return ""
+
+def _messaging(source: GoFile) -> Iterator[Boundary]:
+    if not any(source.imports_path(p) for p in MESSAGING_IMPORTS):
+        return
+    for kind, pattern, operation in (("messaging.publish", PUBLISH_CALLS, "publish"),
+                                     ("messaging.consume", CONSUME_CALLS, "process")):
+        for call in source.calls(pattern):
+            fn = source.func_at(call.start)
+            destination = _destination(source, call, fn)
+            name = f"{operation} {destination or '<destination>'}"
+            yield Boundary(source, kind, call.start, fn, destination, name)
+
+def _http_clients(source: GoFile) -> Iterator[Boundary]:
+    if not source.imports_path("net/http"):
+        return
+    for call in source.calls(HTTP_CLIENT_CALLS):
+        verb = call.name.rsplit(".", 1)[1]
+        if verb == "Do":
+            request = call.args[0].text.strip() if call.args else "req"
+            name, quoted = f"{request}.Method", False
+        else:
+            name, quoted = "POST" if verb == "PostForm" else verb.upper(), True
+        yield Boundary(source, "http.client", call.start, source.func_at(call.start), "", name, quoted)
+
+def find_boundaries(sources: List[GoFile]) -> List[Boundary]:
+    """Every boundary in the non-test, non-generated sources"""
+
+    routes = _routes(sources)
+    found = []
+    for source in sources:
+        if _skipped(source):
+            continue
+        for finder in (_http_handlers(source, routes), _grpc_methods(source), _http_clients(source),
+                       _db_calls(source), _messaging(source)):
+            found.extend(finder)
+    return found
+
+def wrapped_kinds(sources: List[GoFile]) -> Set[str]:
+    """Boundary kinds an instrumentation library covers somewhere in the program"""
+
+    kinds = set()
+    for source in sources:
+        for kind, pattern in WRAPPERS.items():
+            if re.search(pattern, source.masked):
+                kinds.add(kind)
+        for kind, pattern in WRAPPER_IMPORTS.items():
+            if any(re.search(pattern, path) for path in source.imports.values()):
+                kinds.add(kind)
+    return kinds
+
+def is_instrumented(boundary: Boundary, wrapped: Set[str]) -> bool:
+    """Whether a span is started in the function containing the boundary (or one enclosing it),
+    or an instrumentation library covers it"""
+
+    if boundary.wrapped or boundary.kind in wrapped:
+        return True
+    source = boundary.source
+    anchor = boundary.func.body_start if boundary.func else boundary.pos
+    enclosing = [fn for fn in source.functions if fn.contains(anchor)]
+    return any(fn.contains(start.call.start) for fn in enclosing for start in source.span_starts)
+
+@rule(
+    rule_id="boundary-not-instrumented",
+    title="Instrument functions that cross process boundaries",
+    category="coverage",
+    signal="traces",
+    severity="medium",
+    scope="project",
+    description="HTTP and gRPC handlers, outgoing requests, database calls and message publishes and "
+                "consumes are where a trace crosses into another service. Without a span there, or an "
+                "instrumentation library such as otelhttp, otelgrpc or otelsql, the trace breaks and the "
+                "time spent waiting on the other side is invisible.",
+    bad_example='''
+func getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := loadUser(r.Context(), r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}''',
+    good_example='''
+func getUserTraced(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), r.Method+" /users", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	user, err := loadUser(ctx, r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}''',
+)
+def check_boundary_not_instrumented(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    wrapped = wrapped_kinds(sources)
+    # One finding per function and kind of boundary, at its first occurrence
+    gaps: Dict[tuple, List[Boundary]] = OrderedDict()
+    for boundary in find_boundaries(sources):
+        if not is_instrumented(boundary, wrapped):
+            key = (boundary.source.path, boundary.func.start if boundary.func else boundary.pos, boundary.kind)
+            gaps.setdefault(key, []).append(boundary)
+    for group in gaps.values():
+        first = group[0]
+        where = f"{first.func.name} " if first.func and not first.func.is_literal else ""
+        one, many = KIND_PHRASES[first.kind]
+        what = one if len(group) == 1 else many.format(len(group))
+        names = sorted({b.suggested_name for b in group})
+        yield Diagnostic(
+            pos=first.pos,
+            message=f"Function {where}{what} but starts no span and isn't covered by an instrumentation library",
+            suggestion=f"Start a span named {' / '.join(names)} with the matching span kind, "
+                       f"or install the instrumentation library for it",
+            confidence=0.6,
+            file=first.source,
+        )
@@ -0,0 +1,177 @@
+"""
+Spans shared between goroutines: a span belongs to the goroutine doing its work. Attributes and
+events set on it from goroutines fanned out in a loop, or from a worker pool, race with each other
+and with the owner, and the last writer wins on every key.
+"""
+
+import re
+from typing import Iterator, List, Optional, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, match_bracket
+from ..registry import rule
+
+# Span methods that change what gets exported
+MUTATIONS = r'SetAttributes|AddEvent|RecordError|SetStatus|SetName|AddLink'
+# Methods of errgroup, conc and worker pool libraries that run a function on another goroutine
+POOL_SUBMIT = r'Go|Submit|SubmitWait|TrySubmit|Invoke|Schedule|Enqueue'
+
+def _spans(source: GoFile) -> Iterator[Tuple[str, int, GoFunc]]:
+    """(variable, offset, function) of each span a function starts or takes from its context"""
+
+    for start in source.span_starts:
+        if start.span_var and start.span_var != "_" and start.func is not None:
+            yield start.span_var, start.call.start, start.func
+    for m in re.finditer(r'(?<![\w.])(\w+)\s*:=\s*(?:\w+\.)?SpanFromContext\s*\(', source.masked):
+        fn = source.func_at(m.start(), include_literals=True)
+        if fn is not None:
+            yield m.group(1), m.start(), fn
+
+def _launcher(source: GoFile, literal: GoFunc) -> Optional[str]:
+    """How a function literal is run on another goroutine: "go", the pool method, or None"""
+
+    before = source.masked[max(0, literal.start - 64):literal.start]
+    if re.search(r'\bgo\s+$', before):
+        return "go"
+    m = re.search(r'\w+\.(' + POOL_SUBMIT + r')\s*\(\s*$', before)
+    return m.group(1) if m else None
+
+def _shadows(source: GoFile, literal: GoFunc, span: str) -> bool:
+    """Whether the literal declares its own span under the same name"""
+
+    if re.search(r'(?<![\w.])' + span + r'\b', literal.params):
+        return True
+    body = source.masked[literal.body_start:literal.body_end]
+    return bool(re.search(r'(?<![\w.])' + span + r'\b[\w\s,]*:=|\bvar\s+' + span + r'\b', body))
+
+def _mutations(source: GoFile, span: str, lo: int, hi: int) -> List[Tuple[int, str]]:
+    """(offset, method) of each call between lo and hi changing the span"""
+
+    pattern = re.compile(r'(?<![\w.])' + span + r'\.(' + MUTATIONS + r')\s*\(')
+    return [(m.start(), m.group(1)) for m in pattern.finditer(source.masked, lo, hi)]
+
+@rule(
+    rule_id="span-shared-across-goroutines",
+    title="Don't set attributes on one span from several goroutines",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    description="A span is owned by the goroutine doing its work. When goroutines started in a loop, "
+                "a worker pool or errgroup, or the owner and a goroutine at once call SetAttributes, "
+                "AddEvent or RecordError on the same span, the writes race: the last one wins on every "
+                "key, events interleave, and at high rates the span's lock becomes a contention point. "
+                "Start a child span from ctx in each goroutine, or collect results and set them on the "
+                "parent after Wait.",
+    bad_example='''
+func fetchAll(ctx context.Context, ids []string) {
+	ctx, span := tracer.Start(ctx, "fetch all")
+	defer span.End()
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := fetch(ctx, id); err != nil {
+				span.RecordError(err)
+			}
+		}(id)
+	}
+	wg.Wait()
+}''',
+    good_example='''
+func fetchAllTraced(ctx context.Context, ids []string) {
+	ctx, span := tracer.Start(ctx, "fetch all")
+	defer span.End()
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			ctx, child := tracer.Start(ctx, "fetch")
+			defer child.End()
+			if err := fetch(ctx, id); err != nil {
+				child.RecordError(err)
+			}
+		}(id)
+	}
+	wg.Wait()
+	span.SetAttributes(attribute.Int("fetch.count", len(ids)))
+}''',
+)
+def check_span_shared_across_goroutines(source: GoFile) -> Iterator[Diagnostic]:
+    masked = source.masked
+    seen = set()
+    for span, declared, fn in _spans(source):
+        if (span, fn.start) in seen:
+            continue
+        seen.add((span, fn.start))
+        name = re.escape(span)
+        literals = []
+        for literal in source.functions:
+            if not literal.is_literal or not (declared < literal.start < fn.body_end):
+                continue
+            if source.func_at(literal.start, include_literals=True) is not fn:
+                continue
+            launcher = _launcher(source, literal)
+            if launcher is None or _shadows(source, literal, name):
+                continue
+            found = _mutations(source, name, literal.body_start, literal.body_end)
+            if not found and launcher not in ("go", "Go"):
+                # Pool tasks are many by design: any use of the span shares it
+                use = re.search(r'(?<![\w.])' + name + r'\b', masked[literal.body_start:literal.body_end])
+                found = [(literal.body_start + use.start(), "")] if use else []
+            if found:
+                literals.append((literal, launcher, found[0]))
+        for literal, launcher, (pos, method) in literals:
+            in_loop = source.enclosing_loop(literal.start, fn) is not None
+            # The owner writing while the goroutine runs: after the launch, before any Wait
+            wait = re.search(r'\.Wait\s*\(', masked[literal.body_end:fn.body_end])
+            until = literal.body_end + wait.start() if wait else fn.body_end
+            owner = next((p for p, _ in _mutations(source, name, literal.body_end, until)
+                          if source.func_at(p, include_literals=True) is fn), None)
+            if launcher not in ("go", "Go"):
+                why = f"a task handed to {launcher}, which runs it on a pool goroutine"
+            elif in_loop:
+                why = "goroutines started in a loop"
+            elif len(literals) > 1:
+                why = f"{len(literals)} goroutines"
+            elif owner is not None:
+                why = f"a goroutine while {fn.name or 'the function'} also writes to it (line {source.line_of(owner)})"
+            else:
+                continue
+            what = f"{span}.{method} is called from" if method else f"{span} is used in"
+            yield Diagnostic(
+                pos=pos,
+                message=f"{what} {why}, so writes to the span race",
+                suggestion=f"Start a child span from ctx in the goroutine, or collect the results and set them on "
+                           f"{span} after the goroutines finish",
+                confidence=0.75,
+            )
+        # The span itself handed to another goroutine or a pool
+        for m in re.finditer(r'\bgo\s+[\w.]+\s*\(|\w+\.(?:' + POOL_SUBMIT + r')\s*\(|(?<![\w.])\w+\s*<-\s*' + name + r'\b(?!\s*\.)',
+                             masked[declared:fn.body_end]):
+            pos = declared + m.start()
+            if source.func_at(pos, include_literals=True) is not fn:
+                continue
+            if "<-" in m.group():
+                shared = True
+                how = "sent on a channel to whichever goroutine receives it"
+            else:
+                open_paren = declared + m.end() - 1
+                args = masked[open_paren + 1:match_bracket(masked, open_paren)]
+                # Function literals among the arguments were checked above
+                while re.search(r'\{[^{}]*\}', args):
+                    args = re.sub(r'\{[^{}]*\}', '', args)
+                shared = bool(re.search(r'(?<![\w.&])&?' + name + r'\b(?!\s*\.)', args))
+                how = "passed to a goroutine started in a loop" if m.group().startswith("go") else \
+                      f"passed to {m.group().rstrip('( ')}, which runs on a pool goroutine"
+                if m.group().startswith("go") and source.enclosing_loop(pos, fn) is None:
+                    shared = False
+            if shared:
+                yield Diagnostic(
+                    pos=pos,
+                    message=f"{span} is {how}, so several goroutines may write to it at once",
+                    suggestion="Pass ctx instead and start a child span per task; link it to the parent "
+                               "(trace.WithLinks) when the task outlives the request",
+                    confidence=0.65,
+                )
@@ -0,0 +1,277 @@
+"""
+Dead instrumentation: spans, events and attributes in code that can never run (behind a feature
+flag that is a constant false, after a return) and tracers nothing starts spans with. It costs
+nothing at runtime, but readers take it for coverage the service doesn't have, and it still has
+to be kept compiling through every refactoring and semconv upgrade.
+"""
+
+import re
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile, match_bracket
+from ..registry import rule
+
+# Statements after which the rest of the block never runs
+TERMINATOR = re.compile(r'^[ \t]*(?:return\b|panic\s*\(|os\.Exit\s*\(|log\.Fatal\w*\s*\(|goto\s+\w+|break\b|continue\b)', re.M)
+# Lines that start reachable code again inside the same block
+BLOCK_RESUME = re.compile(r'^[ \t]*(?:case\b[^:]*|default\s*|\w+\s*):[ \t]*$', re.M)
+SPAN_METHODS = r'AddEvent|SetAttributes|RecordError|SetStatus|SetName|AddLink'
+TRACER_CREATION = re.compile(
+    r'^[ \t]*(?:var\s+)?(\w+)(?:\s+[\w.]+)?\s*:?=\s*((?:\w+(?:\(\))?\.)*Tracer)\s*\(', re.M)
+TRACER_FIELD = re.compile(r'(?:\b\w+\.(\w+)\s*=|(?:^|[{,])[ \t]*(\w+)\s*:)\s*((?:\w+(?:\(\))?\.)*Tracer)\s*\(', re.M)
+
+def _packages(sources: List[GoFile]) -> Dict[Tuple[str, str], List[GoFile]]:
+    """(directory, package name) -> files of that package"""
+
+    packages: Dict[Tuple[str, str], List[GoFile]] = {}
+    for source in sources:
+        packages.setdefault((str(Path(source.path).parent), source.package), []).append(source)
+    return packages
+
+def _bool_constants(files: List[GoFile]) -> Dict[str, bool]:
+    constants = {}
+    for source in files:
+        for name, value in source.constants.items():
+            if value.strip() in ("true", "false"):
+                constants[name] = value.strip() == "true"
+    return constants
+
+def _split_top(condition: str, op: str) -> List[str]:
+    """condition split at the occurrences of op outside parentheses"""
+
+    parts, depth, last, i = [], 0, 0, 0
+    while i < len(condition):
+        ch = condition[i]
+        if ch in "([{":
+            depth += 1
+        elif ch in ")]}":
+            depth -= 1
+        elif depth == 0 and condition.startswith(op, i):
+            parts.append(condition[last:i])
+            last = i + len(op)
+            i += len(op)
+            continue
+        i += 1
+    parts.append(condition[last:])
+    return parts
+
+def _constant_value(condition: str, constants: Dict[str, bool]) -> Optional[Tuple[bool, str]]:
+    """(value, the operand deciding it) for a condition decided by constants: false, a false
+    constant, !a true one, or a && / || chain with such an operand"""
+
+    condition = condition.strip()
+    if ";" in condition:
+        condition = condition.rsplit(";", 1)[1].strip()
+    if condition.startswith("(") and match_bracket(condition, 0) == len(condition) - 1:
+        condition = condition[1:-1].strip()
+    # || binds looser than &&
+    for op, decides in (("||", True), ("&&", False)):
+        operands = _split_top(condition, op)
+        if len(operands) == 1:
+            continue
+        values = [_constant_value(o, constants) for o in operands]
+        deciding = next((v for v in values if v is not None and v[0] == decides), None)
+        if deciding is not None:
+            return deciding
+        if all(v is not None for v in values):
+            return not decides, values[0][1]
+        return None
+    negated = condition.startswith("!")
+    name = condition.lstrip("!").strip()
+    if name in ("true", "false"):
+        value = name == "true"
+    elif name in constants:
+        value = constants[name]
+    else:
+        return None
+    return value != negated, condition
+
+def _statement_end(masked: str, pos: int) -> int:
+    """Offset of the newline ending the statement starting at pos"""
+
+    depth = 0
+    for i in range(pos, len(masked)):
+        ch = masked[i]
+        if ch in "([{":
+            depth += 1
+        elif ch in ")]}":
+            if depth == 0:
+                return i
+            depth -= 1
+        elif ch == "\n" and depth == 0:
+            return i
+    return len(masked)
+
+def _enclosing_block(masked: str, pos: int, floor: int) -> int:
+    """Offset of the { of the innermost block around pos, no further out than floor"""
+
+    depth = 0
+    for i in range(pos - 1, floor - 1, -1):
+        if masked[i] == "}":
+            depth += 1
+        elif masked[i] == "{":
+            if depth == 0:
+                return i
+            depth -= 1
+    return floor
+
+def dead_regions(source: GoFile, constants: Dict[str, bool]) -> Iterator[Tuple[int, int, str]]:
+    """(start, end, why) of the code in source that can never run"""
+
+    masked = source.masked
+    for fn in source.functions:
+        if fn.is_literal:
+            continue
+        for m in re.finditer(r'\bif\b([^{]*)\{', masked[fn.body_start:fn.body_end]):
+            decided = _constant_value(m.group(1), constants)
+            if decided is None:
+                continue
+            open_brace = fn.body_start + m.end() - 1
+            close = match_bracket(masked, open_brace)
+            if close == -1:
+                continue
+            value, operand = decided
+            if not value:
+                yield open_brace + 1, close, f"behind `if {m.group(1).strip()}`, which is always false ({operand})"
+                continue
+            branch = re.match(r'\s*else\s*\{', masked[close + 1:])
+            if branch:
+                else_open = close + branch.end()
+                yield else_open + 1, match_bracket(masked, else_open), \
+                    f"in the else branch of `if {m.group(1).strip()}`, which is always true ({operand})"
+        for m in TERMINATOR.finditer(masked, fn.body_start, fn.body_end):
+            stop = _statement_end(masked, m.end())
+            open_brace = _enclosing_block(masked, m.start(), fn.body_start - 1)
+            close = match_bracket(masked, open_brace)
+            if close == -1 or stop >= close:
+                continue
+            resume = BLOCK_RESUME.search(masked, stop, close)
+            what = masked[m.start():m.end()].strip().rstrip("(").strip()
+            yield stop, resume.start() if resume else close, f"after `{what}`, so it never runs"
+
+def _telemetry_in(source: GoFile, start: int, end: int, span_vars: Set[str]) -> List[Tuple[int, str]]:
+    """(offset, description) of the span starts and span calls in start..end"""
+
+    found = []
+    for s in source.span_starts:
+        if start <= s.call.start < end:
+            found.append((s.call.start, f'span "{s.name}"' if s.name is not None else "a span"))
+    for m in re.finditer(r'(?<![\w.])(\w+)\.(' + SPAN_METHODS + r')\s*\(', source.masked[start:end]):
+        if m.group(1) in span_vars:
+            found.append((start + m.start(), f"{m.group(1)}.{m.group(2)}"))
+    return sorted(found)
+
+@rule(
+    rule_id="dead-instrumentation",
+    title="Delete instrumentation that can never run",
+    category="coverage",
+    signal="traces",
+    severity="low",
+    scope="project",
+    description="Spans, events and attributes behind a feature flag that is a constant false, in the "
+                "branch of a condition that can't be taken, or after a return, panic or os.Exit in the "
+                "same block never reach a backend. They read like coverage the service doesn't have and "
+                "still need maintaining; delete them, or make the flag a runtime setting if the telemetry "
+                "is meant to be switchable.",
+    bad_example='''
+const traceCacheLookups = false
+
+func lookupPrice(ctx context.Context, sku string) (int64, error) {
+	span := trace.SpanFromContext(ctx)
+	if traceCacheLookups {
+		span.AddEvent("price.cache.lookup", trace.WithAttributes(attribute.String("product.sku", sku)))
+	}
+	return prices.Get(ctx, sku)
+}''',
+    good_example='''
+var traceStockLookups = os.Getenv("TRACE_STOCK_LOOKUPS") != ""
+
+func lookupStock(ctx context.Context, sku string) (int, error) {
+	span := trace.SpanFromContext(ctx)
+	if traceStockLookups {
+		span.AddEvent("stock.cache.lookup", trace.WithAttributes(attribute.String("product.sku", sku)))
+	}
+	return stock.Get(ctx, sku)
+}''',
+)
+def check_dead_instrumentation(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for files in _packages(sources).values():
+        constants = _bool_constants(files)
+        for source in files:
+            span_vars = set(source.span_vars())
+            reported = []
+            for start, end, why in sorted(dead_regions(source, constants)):
+                if any(s <= start and end <= e for s, e in reported):
+                    continue
+                telemetry = _telemetry_in(source, start, end, span_vars)
+                if not telemetry:
+                    continue
+                reported.append((start, end))
+                what = ", ".join(dict.fromkeys(t[1] for t in telemetry[:3])) + (", ..." if len(telemetry) > 3 else "")
+                yield Diagnostic(
+                    pos=telemetry[0][0],
+                    message=f"Instrumentation {why}: {what}",
+                    suggestion="Delete the dead instrumentation, or read the flag at runtime if it should be switchable",
+                    confidence=0.8,
+                    file=source,
+                )
+
+def _tracer_declarations(source: GoFile) -> Iterator[Tuple[str, int, bool]]:
+    """(name, offset, is a struct field) of package level variables and struct fields holding a
+    tracer created with Tracer(...)"""
+
+    for m in TRACER_CREATION.finditer(source.masked):
+        if source.func_at(m.start(1)) is None and m.group(1) != "_":
+            yield m.group(1), m.start(1), False
+    for m in TRACER_FIELD.finditer(source.masked):
+        name = m.group(1) or m.group(2)
+        yield name, m.start(1) if m.group(1) else m.start(2), True
+
+@rule(
+    rule_id="tracer-unused",
+    title="Delete tracers that start no spans",
+    category="coverage",
+    signal="traces",
+    severity="low",
+    scope="project",
+    description="A package level tracer or tracer field that nothing in its package reads is left over "
+                "from removed instrumentation, or from instrumentation that was planned and never written. "
+                "It suggests the package is traced when it isn't.",
+    bad_example='''
+var auditTracer = otel.Tracer("example.com/shop/audit")
+
+func recordAudit(ctx context.Context, entry AuditEntry) error {
+	return auditLog.Append(ctx, entry)
+}''',
+    good_example='''
+var ordersTracer = otel.Tracer("example.com/shop/orders")
+
+func placeOrder(ctx context.Context, order Order) error {
+	ctx, span := ordersTracer.Start(ctx, "place order")
+	defer span.End()
+	return orders.Insert(ctx, order)
+}''',
+)
+def check_tracer_unused(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    for files in _packages(sources).values():
+        for source in files:
+            for name, pos, field in _tracer_declarations(source):
+                if name[0].isupper():
+                    # Exported: other packages may use it
+                    continue
+                if field:
+                    # Reads of the field, not the assignments that set it
+                    use = re.compile(r'\.' + re.escape(name) + r'\b(?!\s*=[^=])')
+                else:
+                    use = re.compile(r'(?<![\w.])' + re.escape(name) + r'\b')
+                if any(not (f is source and m.start() == pos) for f in files for m in use.finditer(f.masked)):
+                    continue
+                yield Diagnostic(
+                    pos=pos,
+                    message=f"Tracer {name} is created but never used to start a span",
+                    suggestion=f"Delete {name}, or instrument the operations it was meant for",
+                    confidence=0.75,
+                    file=source,
+                )
@@ -0,0 +1,222 @@
+"""
+Deadlines and retries under a span: an operation bounded by context.WithTimeout that runs out of
+time must show up on the span as an error, not as a slow success, and attempts of a retry loop
+each get a span of their own so the trace shows how many there were and what each one did.
+"""
+
+import re
+from typing import Iterator, Optional, Set, Tuple
+
+from ..base import Diagnostic
+from ..golang import Call, GoFile, GoFunc, SpanStart, match_bracket
+from ..registry import rule
+from .granularity import RETRY_LOOP
+
+# Waits between attempts
+BACKOFF = re.compile(r'\btime\.(?:Sleep|After)\s*\(|\b[Bb]ack[Oo]ff\b|\.NextBackOff\s*\(')
+# Instrumentation starting a client span per request, so per attempt
+PER_REQUEST_SPANS = "go.opentelemetry.io/contrib/instrumentation"
+
+def _timeouts(source: GoFile, fn: GoFunc) -> Iterator[Tuple[str, str, int]]:
+    """(context variable, WithTimeout or WithDeadline, offset) of contexts with a deadline derived in fn"""
+
+    for alias in source.import_alias("context"):
+        pattern = r'(?<![\w.])(\w+)\s*,\s*\w+\s*:?=\s*' + re.escape(alias) + r'\.(WithTimeout|WithDeadline)\s*\('
+        for m in re.finditer(pattern, source.masked[fn.body_start:fn.body_end]):
+            yield m.group(1), m.group(2), fn.body_start + m.start()
+
+def _marks(source: GoFile, span: str, lo: int, hi: int) -> bool:
+    """Whether code in [lo, hi) records an error or status on span, or hands span to a helper"""
+
+    return bool(re.search(r'(?<![\w.])' + re.escape(span) + r'\s*(?:\.\s*(?:RecordError|SetStatus)\s*\(|[,)])',
+                          source.masked[lo:hi]))
+
+def _deferred_marking(source: GoFile, fn: GoFunc, span: str) -> bool:
+    """Whether a closure deferred in fn marks span, e.g. from a named error result"""
+
+    for m in re.finditer(r'\bdefer\s+func\s*\(\s*\)\s*\{', source.masked[fn.body_start:fn.body_end]):
+        open_brace = fn.body_start + m.end() - 1
+        if _marks(source, span, open_brace, match_bracket(source.masked, open_brace)):
+            return True
+    return False
+
+def _error_path(source: GoFile, call: Call) -> Optional[Tuple[int, int, int]]:
+    """(if offset, body open, body close) of the err != nil check right after call's error
+    result is assigned"""
+
+    prefix = source.statement_prefix(call.start)
+    m = re.search(r'((?:\w+\s*,\s*)*\w+)\s*:?=\s*$', prefix)
+    if not m:
+        return None
+    err = m.group(1).split(",")[-1].strip()
+    if err == "_":
+        return None
+    if re.search(r'\bif\s', prefix):
+        # if err := op(ctx); err != nil {
+        head = re.match(r'\s*;([^{]*)\{', source.masked[call.end:])
+        pos = call.start - len(prefix) + prefix.rfind("if")
+    else:
+        head = re.match(r'\s*if\b([^{]*)\{', source.masked[call.end:])
+        pos = call.end + head.start(1) - 2 if head else -1
+    if not head or not re.search(r'(?<![\w.])' + re.escape(err) + r'\s*!=\s*nil\b|\bDeadlineExceeded\b', head.group(1)):
+        return None
+    open_brace = call.end + head.end() - 1
+    return pos, open_brace, match_bracket(source.masked, open_brace)
+
+def _timeout_paths(source: GoFile, start: SpanStart) -> Iterator[Tuple[Call, str, int, Tuple[int, int, int]]]:
+    """(operation call, WithTimeout/WithDeadline, its offset, error path) for operations on a
+    deadline context in start's function whose error path returns without marking the span"""
+
+    fn, span = start.func, start.span_var
+    if _deferred_marking(source, fn, span):
+        return
+    for ctx, how, pos in _timeouts(source, fn):
+        for call in source.calls(r'[\w.]+', pos, fn.body_end):
+            if call.name.split(".")[0] in ("context", span) or source.func_at(call.start, include_literals=True) is not fn:
+                continue
+            if not any(a.text.strip() == ctx for a in call.args):
+                continue
+            path = _error_path(source, call)
+            if path is None or path[2] == -1:
+                continue
+            _, open_brace, close = path
+            if re.search(r'\breturn\b', source.masked[open_brace:close]) and not _marks(source, span, open_brace, close):
+                yield call, how, pos, path
+
+def _span_starters(source: GoFile) -> Set[str]:
+    """Names of the package's functions that start a span"""
+
+    return {s.func.name for f in source.package_sources for s in f.span_starts
+            if s.func is not None and not s.func.is_literal and s.func.name}
+
+def _retry_loops(source: GoFile, start: SpanStart) -> Iterator[tuple]:
+    """Retry loops after start in its function whose attempts start no span of their own"""
+
+    fn = start.func
+    starters = _span_starters(source)
+    for loop in source.loops(start.call.end, fn.body_end):
+        if source.func_at(loop[0], include_literals=True) is not fn:
+            continue
+        header = source.masked[loop[0] + 3:loop[1]].strip()
+        body = source.masked[loop[1]:loop[2]]
+        retry = RETRY_LOOP.search(header) or (
+            (not header or re.match(r'\w+\s*:=\s*0\s*;', header)) and BACKOFF.search(body)
+            and re.search(r'\b(?:break|return)\b', body))
+        if not retry or not re.search(r'\w+\s*\(\s*(?:\w*[cC]tx\w*|\w+\.Context\s*\(\s*\))\s*[,)]', body):
+            continue
+        if any(loop[1] < s.call.start < loop[2] for s in source.span_starts):
+            continue
+        if any(re.search(r'(?<![\w])' + re.escape(name) + r'\s*\(', body) for name in starters if name != fn.name):
+            continue
+        yield loop
+
+@rule(
+    rule_id="span-timeout-retry",
+    title="Mark timed-out operations on their span, and give each retry attempt a span",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    description="A span around an operation bounded by context.WithTimeout or WithDeadline lasts as long "
+                "as the deadline allows; when the error path of the operation returns without "
+                "span.RecordError or span.SetStatus(codes.Error, ...), a timed-out call shows up as an "
+                "Unset span of exactly the timeout, indistinguishable from a slow success. Mark the span "
+                "(error.type \"timeout\" for context.DeadlineExceeded) before returning; a deferred closure "
+                "doing it for every return counts. The semconv retry guidance makes every attempt of a "
+                "retried request its own span (with http.request.resend_count from the second one): a loop "
+                "retrying an operation under one span merges the attempts, their latencies and the waits "
+                "between them into one bar. Start a child span per attempt, or use otelhttp/otelgrpc, "
+                "which do so per request.",
+    bad_example='''
+func fetchQuote(ctx context.Context, client *QuoteClient, symbol string) (Quote, error) {
+	ctx, span := tracer.Start(ctx, "fetch quote")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	q, err := client.Get(ctx, symbol)
+	if err != nil {
+		return Quote{}, err
+	}
+	return q, nil
+}
+
+func publishWithRetry(ctx context.Context, msg Message) error {
+	ctx, span := tracer.Start(ctx, "publish", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if err = broker.Publish(ctx, msg); err == nil {
+			return nil
+		}
+		time.Sleep(backoff(attempt))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "publish failed")
+	return err
+}''',
+    good_example='''
+func fetchQuoteTraced(ctx context.Context, client *QuoteClient, symbol string) (Quote, error) {
+	ctx, span := tracer.Start(ctx, "fetch quote")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	q, err := client.Get(ctx, symbol)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "fetch quote failed")
+		return Quote{}, err
+	}
+	return q, nil
+}
+
+func publishAttempt(ctx context.Context, msg Message, attempt int) error {
+	ctx, span := tracer.Start(ctx, "publish", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+	if attempt > 0 {
+		span.SetAttributes(attribute.Int("messaging.resend_count", attempt))
+	}
+	return broker.Publish(ctx, msg)
+}
+
+func publishWithRetryTraced(ctx context.Context, msg Message) error {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if err = publishAttempt(ctx, msg, attempt); err == nil {
+			return nil
+		}
+		time.Sleep(backoff(attempt))
+	}
+	return err
+}''',
+)
+def check_span_timeout_retry(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    per_request = source.imports_path(PER_REQUEST_SPANS)
+    for start in source.span_starts:
+        if start.func is None or not start.span_var or start.span_var == "_":
+            continue
+        span = start.span_var
+        for call, how, pos, (if_pos, open_brace, _) in _timeout_paths(source, start):
+            yield Diagnostic(
+                pos=if_pos,
+                end=open_brace,
+                message=f"The error path of {call.name}() returns without marking {span}: when the "
+                        f"context.{how} deadline on line {source.line_of(pos)} expires, {span} ends Unset after "
+                        f"the full timeout, like a slow success",
+                suggestion=f"Call {span}.RecordError(err) and {span}.SetStatus(codes.Error, ...) before returning; "
+                           f"set error.type to \"timeout\" when errors.Is(err, context.DeadlineExceeded)",
+                confidence=0.75,
+            )
+        if per_request:
+            continue
+        for loop in _retry_loops(source, start):
+            yield Diagnostic(
+                pos=loop[0],
+                end=loop[1],
+                message=f"Every attempt of the retry loop runs under the one span "
+                        f"{start.name_arg.text.strip() if start.name_arg else span}, so the trace shows a single "
+                        f"bar for all attempts and the waits between them",
+                suggestion="Start a child span per attempt (a function starting it, called from the loop), with "
+                           "the attempt number as a resend count attribute",
+                confidence=0.6,
+            )
@@ -0,0 +1,277 @@
+"""
+Span granularity: a span stands for a unit of work worth its own bar in the trace view. Spans
+started per item of a collection multiply a trace's size by the collection's length and bury the
+operation they belong to; internal spans nested call after call turn a request into a deep tree
+that takes scrolling to read.
+"""
+
+import re
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from ..base import Diagnostic
+from ..budget import Budget
+from ..golang import GoFile, GoFunc, SpanStart
+from ..registry import rule
+from .lifetime import is_channel
+
+# Loop variables of retries, where a span per attempt is what the conventions ask for
+RETRY_LOOP = re.compile(r'\b(?:attempts?|retr(?:y|ies)|tries|try)\b', re.I)
+
+def _wrappers(source: GoFile) -> List[str]:
+    """Names of this file's functions that start a span and return it"""
+
+    found = []
+    for start in source.span_starts:
+        fn = start.func
+        if fn is None or fn.is_literal or not fn.name or not start.span_var or start.span_var == "_":
+            continue
+        returns = re.findall(r'\breturn\b([^\n;]*)', source.masked[start.call.end:fn.body_end])
+        if any(re.search(r'(?<![\w.])' + re.escape(start.span_var) + r'\b', r) for r in returns):
+            found.append(fn.name)
+    return found
+
+def _per_iteration(source: GoFile, pos: int, fn: GoFunc) -> Optional[Tuple[tuple, str]]:
+    """(loop, how) when the code at pos runs once per iteration of a loop: in its body, or in a
+    function literal the body calls. Goroutines fanned out per item are left alone: their spans
+    show the work running in parallel."""
+
+    loop = source.enclosing_loop(pos, fn)
+    if loop is not None:
+        return loop, ""
+    if not fn.is_literal or source.masked[fn.body_end + 1:fn.body_end + 2] != "(":
+        return None
+    parent = source.func_at(fn.start, include_literals=True)
+    if parent is None or parent is fn or re.search(r'\bgo\s+$', source.masked[max(0, fn.start - 16):fn.start]):
+        return None
+    loop = source.enclosing_loop(fn.start, parent)
+    return (loop, " in a closure called") if loop is not None else None
+
+def _unit_of_work_loop(source: GoFile, loop: tuple) -> bool:
+    """Loops whose iterations are units of work of their own: for {}, receives from a channel,
+    selects, and retries"""
+
+    header = source.masked[loop[0] + 3:loop[1]].strip()
+    if not header or RETRY_LOOP.search(header):
+        return True
+    m = re.search(r'\brange\s+([\w.]+)\s*$', header)
+    if m and is_channel(source, m.group(1)):
+        return True
+    return bool(re.match(r'\s*select\s*\{', source.masked[loop[1] + 1:loop[2]]))
+
+@rule(
+    rule_id="span-in-loop",
+    title="Don't start a span per item of a loop",
+    category="performance",
+    signal="traces",
+    severity="low",
+    description="A span started in a for or range body, directly, through a helper returning the span "
+                "or in a closure called per iteration, turns one operation into as many spans as there "
+                "are items: the trace grows with the input, hits span limits and sampling budgets, and "
+                "the operation's own span disappears among its items. Start one span around the loop "
+                "and record per-item detail as attributes (counts) or events (failures). Loops over "
+                "messages, channels, selects and retry attempts are units of work and aren't reported; "
+                "allowed_kinds exempts spans of the given kinds, consumer spans per message by default.",
+    options={
+        # Span kinds per-iteration spans are expected for, e.g. one consumer span per message of a batch
+        "allowed_kinds": ["consumer"],
+    },
+    bad_example='''
+func resizeImages(ctx context.Context, images []Image) {
+	for _, img := range images {
+		_, span := tracer.Start(ctx, "resize image")
+		resize(img)
+		span.End()
+	}
+}''',
+    good_example='''
+func resizeImagesTraced(ctx context.Context, images []Image) {
+	_, span := tracer.Start(ctx, "resize images",
+		trace.WithAttributes(attribute.Int("image.count", len(images))))
+	defer span.End()
+	for _, img := range images {
+		resize(img)
+	}
+}''',
+)
+def check_span_in_loop(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    allowed = {str(k).lower() for k in options.get("allowed_kinds") or []}
+    sites = [(s.call.start, s.call.end, s.name_arg.text.strip() if s.name_arg else "", s.kind, s.func, "")
+             for s in source.span_starts if s.func is not None]
+    wrappers = _wrappers(source)
+    if wrappers:
+        starts = {site[0] for site in sites}
+        for call in source.calls("|".join(re.escape(w) for w in wrappers)):
+            fn = source.func_at(call.start, include_literals=True)
+            if fn is not None and fn.name != call.name and call.start not in starts:
+                sites.append((call.start, call.end, "", "", fn, call.name))
+    for pos, end, name, kind, fn, wrapper in sites:
+        if kind in allowed:
+            continue
+        found = _per_iteration(source, pos, fn)
+        if found is None:
+            continue
+        loop, how = found
+        if _unit_of_work_loop(source, loop):
+            continue
+        what = f"{wrapper}() starts a span" if wrapper else f"Span {name or 'here'} is started"
+        yield Diagnostic(
+            pos=pos,
+            end=end,
+            message=f"{what}{how} on every iteration of the loop on line {source.line_of(loop[0])}",
+            suggestion="Start one span around the loop and record per-item detail as attributes or events; "
+                       "if each item is a unit of work of its own, give the span that kind or allow it with "
+                       "allowed_kinds",
+            confidence=0.7,
+        )
+
+# One span of a call chain: where it's started and the function starting it
+Nested = Tuple[GoFile, SpanStart, str]
+
+class Nesting:
+    """The deepest chain of nested spans below each function, along the package call graph"""
+
+    def __init__(self, sources: List[GoFile]):
+        self.graph = Budget(sources)
+        self.deepest: Dict[str, List[Nested]] = {}
+
+    def chain(self, name: str, stack: Set[str] = frozenset()) -> List[Nested]:
+        """Spans a call of name nests, outermost first: a span it starts wraps the spans of the
+        calls after it; recursion is followed once"""
+
+        if name in self.deepest:
+            return self.deepest[name]
+        best: List[Nested] = []
+        stack = stack | {name}
+        for source, fn in self.graph.functions.get(name, []):
+            starts = [s for s in source.span_starts if fn.contains(s.call.start)]
+            if starts and not best:
+                best = [(source, starts[0], name)]
+            for m in re.finditer(r'(?<![\w])(?:\w+\.)?(\w+)\s*\(', source.masked[fn.body_start:fn.body_end]):
+                callee = m.group(1)
+                if callee in stack or callee not in self.graph.functions:
+                    continue
+                pos = fn.body_start + m.start()
+                outer = [s for s in starts if s.call.end <= pos]
+                found = ([(source, outer[-1], name)] if outer else []) + self.chain(callee, stack)
+                if len(found) > len(best):
+                    best = found
+        self.deepest[name] = best
+        return best
+
+def _records(source: GoFile, start: SpanStart) -> bool:
+    """Whether the span gets attributes, events or a status besides its name"""
+
+    if re.search(r'WithAttributes\s*\(', source.masked[start.call.open_paren:start.call.close_paren]):
+        return True
+    if not start.span_var or start.span_var == "_" or start.func is None:
+        return False
+    pattern = re.escape(start.span_var) + r'\.(?:SetAttributes|AddEvent|RecordError|SetStatus)'
+    return any(True for _ in source.calls(pattern, start.call.end, start.func.body_end))
+
+@rule(
+    rule_id="span-hierarchy-depth",
+    title="Keep the span tree of a request shallow",
+    category="performance",
+    signal="traces",
+    severity="low",
+    scope="project",
+    description="Every span started below another one adds a level to the trace view, and a span per "
+                "internal function (handleCheckout, computeTotals, applyDiscounts, chargeCardInternal) "
+                "nests a request's spans deeper than anyone reads: the calls to other services, which are "
+                "what a trace is for, end up under levels of in-process bookkeeping. The call graph of the "
+                "code is followed from every function nothing calls (handlers, consumers, main) through the "
+                "calls made after each span start, and chains nesting more than max_depth spans are reported "
+                "at their outermost span, with the internal spans worth dropping: those recording nothing "
+                "first, then the innermost. Server, client, producer and consumer spans are never suggested; "
+                "calls through interfaces and function values aren't followed.",
+    options={
+        # Nested spans per request beyond which a chain is reported
+        "max_depth": 4,
+    },
+    bad_example='''
+func handleCheckout(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /checkout", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	placeOrder(ctx)
+}
+
+func placeOrder(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "place order")
+	defer span.End()
+	computeTotals(ctx)
+}
+
+func computeTotals(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "compute totals")
+	defer span.End()
+	applyDiscounts(ctx)
+}
+
+func applyDiscounts(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "apply discounts")
+	defer span.End()
+	chargeCardInternal(ctx)
+}
+
+func chargeCardInternal(ctx context.Context) {
+	_, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+}''',
+    good_example='''
+func handleCheckoutTraced(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "POST /checkout", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	placeOrderTraced(ctx)
+}
+
+func placeOrderTraced(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "place order")
+	defer span.End()
+	computeTotalsTraced(ctx)
+}
+
+func computeTotalsTraced(ctx context.Context) {
+	applyDiscountsTraced(ctx)
+}
+
+func applyDiscountsTraced(ctx context.Context) {
+	chargeCardTraced(ctx)
+}
+
+func chargeCardTraced(ctx context.Context) {
+	_, span := tracer.Start(ctx, "charge card", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+}''',
+)
+def check_span_hierarchy_depth(sources: List[GoFile], options: Dict) -> Iterator[Diagnostic]:
+    max_depth = int(options["max_depth"])
+    nesting = Nesting(sources)
+    reported = set()
+    chains = [nesting.chain(entry) for entry in nesting.graph.entries]
+    for chain in sorted(chains, key=len, reverse=True):
+        if len(chain) <= max_depth:
+            break
+        root_source, root, _ = chain[0]
+        if (root_source.path, root.call.start) in reported:
+            continue
+        reported.add((root_source.path, root.call.start))
+        internal = [(i, n) for i, n in enumerate(chain) if i > 0 and n[1].kind in ("", "internal")]
+        internal.sort(key=lambda item: (_records(item[1][0], item[1][1]), -item[0]))
+        drop = sorted(internal[:len(chain) - max_depth])
+        functions = " -> ".join(name for _, _, name in chain)
+        if drop:
+            names = ", ".join(f"{n[2]} ({n[1].name_arg.text.strip() if n[1].name_arg else 'its span'})" for _, n in drop)
+            suggestion = f"Drop the internal spans of {names} and record what they measure as attributes or " \
+                         f"events of the span around them"
+        else:
+            suggestion = "Start fewer spans along the chain: keep the ones crossing a process boundary"
+        yield Diagnostic(
+            pos=root.call.start,
+            end=root.call.end,
+            message=f"Spans nest {len(chain)} deep along {functions}, more than max_depth {max_depth}",
+            suggestion=suggestion,
+            confidence=0.5,
+            file=root_source,
+        )
@@ -0,0 +1,108 @@
+"""
+User input in span names and metric attributes. Names and metric attribute values built from a
+parameter are left to the callers by span-name-unbounded and metric-attribute-high-cardinality;
+the taint pass follows request values, gRPC request fields and command-line input through those
+calls and reports where they entered the program.
+"""
+
+from typing import Iterator, List
+
+from ..base import Diagnostic
+from ..cardinality import Bounds
+from ..golang import GoFile
+from ..metrics.attributes import unbounded_attribute
+from ..metrics.instruments import metric_attributes
+from ..registry import rule
+from ..taint import Source, Taint
+
+def _confidence(origin: Source) -> float:
+    # A command-line value is fixed for the life of the process; only its runs multiply
+    return 0.6 if origin.kind.startswith("command-line") else 0.8
+
+def _where(source: GoFile, origin: Source) -> str:
+    return f"line {origin.line}" if origin.path == source.path else origin.where
+
+@rule(
+    rule_id="user-input-cardinality",
+    title="Keep user input out of span names and metric attributes",
+    category="performance",
+    signal="traces",
+    signals=("metrics",),
+    severity="high",
+    scope="project",
+    description="A span name or metric attribute set from user input takes as many values as clients care "
+                "to send: every path, query or gRPC request field becomes its own span name or time series, "
+                "and a single client can blow up the backend's indexes. Values read from http.Request "
+                "(form fields, query and path parameters, headers, cookies, the URL), gin, echo and fiber "
+                "contexts, gRPC request messages and command-line arguments and flags are followed through "
+                "variables, calls and return values to the tracer.Start name or metric.WithAttributes "
+                "value they end up in, and the finding names where the input was read. A variable "
+                "assigned another value before it is used no longer counts, nor do comparisons, "
+                "lengths and other booleans computed from the input. Values passed "
+                "through a redact/hash/mask function are skipped, as are names and attributes that "
+                "span-name-unbounded or metric-attribute-high-cardinality already report. Span attributes "
+                "may hold such values (pii-in-telemetry checks them for personal data).",
+    bad_example='''
+func handleReportPage(w http.ResponseWriter, r *http.Request) {
+	renderReportPage(r.Context(), w, r.PathValue("report"))
+}
+
+func renderReportPage(ctx context.Context, w io.Writer, report string) {
+	ctx, span := tracer.Start(ctx, "render "+report)
+	defer span.End()
+	drawReport(ctx, w, report)
+}''',
+    good_example='''
+func handleReportTraced(w http.ResponseWriter, r *http.Request) {
+	renderReportTraced(r.Context(), w, r.PathValue("report"))
+}
+
+func renderReportTraced(ctx context.Context, w io.Writer, report string) {
+	ctx, span := tracer.Start(ctx, "render report")
+	defer span.End()
+	span.SetAttributes(attribute.String("report.name", report))
+	drawReport(ctx, w, report)
+}''',
+)
+def check_user_input_cardinality(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    taint = Taint(sources)
+    for source in taint.sources:
+        names = Bounds(source)
+        for start in source.span_starts:
+            arg = start.name_arg
+            if arg is None or start.name is not None:
+                continue
+            fn = source.func_at(arg.start, include_literals=True)
+            origin = taint.origin(source, fn, arg.start, arg.end) if fn is not None else None
+            # Names that can't be bounded in their own function are span-name-unbounded's
+            if origin is None or names.values(arg.text, arg.start) is None:
+                continue
+            yield Diagnostic(
+                pos=arg.start,
+                end=arg.end,
+                message=f"Span name {arg.text.strip()} carries {origin.what} ({_where(source, origin)}), "
+                        f"so every distinct input becomes its own span name",
+                suggestion="Use a fixed name (the operation, or the route template for HTTP) and record the "
+                           "input as a span attribute",
+                confidence=_confidence(origin),
+                file=source,
+            )
+        values = Bounds(source, 100)
+        for call in metric_attributes(source):
+            key, value = call.args
+            fn = source.func_at(value.start, include_literals=True)
+            if fn is None or unbounded_attribute(call, values) is not None:
+                continue
+            origin = taint.origin(source, fn, value.start, value.end)
+            if origin is None:
+                continue
+            yield Diagnostic(
+                pos=call.start,
+                end=call.end,
+                message=f"Metric attribute {key.text.strip()} carries {origin.what} ({_where(source, origin)}), "
+                        f"so each distinct input becomes its own time series",
+                suggestion="Drop the attribute or map the input onto a small fixed set of values first; record "
+                           "it on the span instead",
+                confidence=_confidence(origin),
+                file=source,
+            )
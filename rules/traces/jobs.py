@@ -0,0 +1,206 @@
+"""
+Asynchronous work: messages, task queues and job tables. The consumer runs later, in another
+process, so the trace only continues if the producer injects the context into what it enqueues
+and the consumer extracts it before starting its span.
+"""
+
+import re
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional, Set
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, parse_params
+from ..registry import rule
+from .boundaries import DB_CALLS, find_boundaries, wrapped_kinds
+
+# Task queue libraries: import path -> (label, enqueue call, handler parameter type)
+TASK_QUEUES = {
+    "github.com/hibiken/asynq": ("asynq task", r'[\w.]+\.Enqueue(?:Context)?', r'\*asynq\.Task'),
+    "github.com/riverqueue/river": ("river job", r'[\w.]+\.Insert(?:Tx|Many|ManyTx)?', r'\*river\.Job\[.*\]'),
+    "github.com/gocraft/work": ("gocraft/work job", r'[\w.]+\.Enqueue(?:Unique)?(?:In)?', r'\*work\.Job'),
+    "github.com/contribsys/faktory": ("faktory job", r'[\w.]+\.Push', r'\*faktory\.Job'),
+    "github.com/RichardKnop/machinery": ("machinery task", r'[\w.]+\.SendTask(?:WithContext)?', None),
+}
+# Tables that hold work for another process
+JOB_TABLE = re.compile(r'(?i)"?\w*(?:jobs?|tasks?|queue|outbox)"?')
+# Consumers claim rows; plain SELECTs on a job table are usually dashboards
+CLAIM = re.compile(r'(?i)\bSKIP\s+LOCKED\b|\bFOR\s+UPDATE\b|\bRETURNING\b')
+# A column or payload field carrying the context
+CONTEXT_COLUMN = re.compile(r'(?i)trace_?parent|trace_?context|trace_?carrier|otel_?\w*|carrier')
+
+INJECT = r'\.Inject\s*\('
+EXTRACT = r'\.Extract\s*\('
+
+@dataclass
+class Endpoint:
+    """One end of an asynchronous hand-off"""
+    source: GoFile
+    pos: int
+    func: Optional[GoFunc]
+    producer: bool
+    system: str
+    target: str
+    # The context is injected (producer) or extracted (consumer) here
+    propagates: bool
+
+    @property
+    def where(self) -> str:
+        return f"{Path(self.source.path).name}:{self.source.line_of(self.pos)}"
+
+def _helpers(sources: List[GoFile], pattern: str) -> Set[str]:
+    """Functions of the program that inject (or extract) the context themselves"""
+
+    return {fn.name for source in sources for fn in source.functions
+            if not fn.is_literal and re.search(pattern, source.masked[fn.body_start:fn.body_end])}
+
+def _does(source: GoFile, fn: Optional[GoFunc], pattern: str, helpers: Set[str]) -> bool:
+    if fn is None:
+        return False
+    body = source.masked[fn.body_start:fn.body_end]
+    return bool(re.search(pattern, body) or any(re.search(r'(?<![\w])' + re.escape(h) + r'\s*\(', body)
+                                                for h in helpers - {fn.name}))
+
+def _task_type(source: GoFile, fn: Optional[GoFunc]) -> str:
+    if fn is None:
+        return ""
+    m = re.search(r'\bNewTask\s*\(\s*"([^"]+)"', source.code[fn.body_start:fn.body_end])
+    return m.group(1) if m else ""
+
+def _task_handlers(sources: List[GoFile]) -> Dict[str, str]:
+    """Handler function name -> task type, from mux.HandleFunc("type", handler) registrations"""
+
+    handlers = {}
+    for source in sources:
+        if not source.imports_path("github.com/hibiken/asynq"):
+            continue
+        for call in source.calls(r'[\w.]+\.Handle(?:Func)?'):
+            if len(call.args) == 2 and call.args[0].literal:
+                name = re.search(r'(\w+)\s*\)*\s*$', call.args[1].text)
+                if name:
+                    handlers[name.group(1)] = call.args[0].literal
+    return handlers
+
+def endpoints(sources: List[GoFile]) -> List[Endpoint]:
+    injectors, extractors = _helpers(sources, INJECT), _helpers(sources, EXTRACT)
+    wrapped = wrapped_kinds(sources)
+    found = []
+    for b in find_boundaries(sources):
+        fn = b.func
+        if b.kind in ("messaging.publish", "messaging.consume"):
+            producer = b.kind == "messaging.publish"
+            found.append(Endpoint(b.source, b.pos, fn, producer, "message", b.target,
+                                  b.kind in wrapped or _does(b.source, fn, INJECT if producer else EXTRACT,
+                                                             injectors if producer else extractors)))
+        elif b.kind == "db" and b.target:
+            operation, table = b.target.split(" ", 1)
+            if not JOB_TABLE.fullmatch(table):
+                continue
+            call = next((c for c in b.source.calls(DB_CALLS, b.pos) if c.start == b.pos), None)
+            query = b.source.code[call.open_paren:call.close_paren] if call else ""
+            if operation == "INSERT":
+                propagates = bool(CONTEXT_COLUMN.search(query)) or _does(b.source, fn, INJECT, injectors)
+                found.append(Endpoint(b.source, b.pos, fn, True, "job table", table, propagates))
+            elif CLAIM.search(query):
+                found.append(Endpoint(b.source, b.pos, fn, False, "job table", table,
+                                      _does(b.source, fn, EXTRACT, extractors)))
+    handlers = _task_handlers(sources)
+    for source in sources:
+        if source.path.endswith("_test.go"):
+            continue
+        for path, (label, enqueue, handler_type) in TASK_QUEUES.items():
+            if not source.imports_path(path):
+                continue
+            for call in source.calls(enqueue):
+                fn = source.func_at(call.start)
+                found.append(Endpoint(source, call.start, fn, True, label, _task_type(source, fn),
+                                      _does(source, fn, INJECT, injectors)))
+            if handler_type is None:
+                continue
+            for fn in source.functions:
+                if any(re.fullmatch(handler_type, typ) for _, typ in parse_params(fn.params)):
+                    found.append(Endpoint(source, fn.start, fn, False, label, handlers.get(fn.name, ""),
+                                          _does(source, fn, EXTRACT, extractors)))
+    return found
+
+def _action(endpoint: Endpoint) -> str:
+    target = endpoint.target
+    if endpoint.system == "job table":
+        return f"Inserts into job table {target}" if endpoint.producer else f"Claims jobs from table {target}"
+    if endpoint.system == "message":
+        to = f" to {target}" if endpoint.producer else f" from {target}"
+        return ("Publishes messages" if endpoint.producer else "Consumes messages") + (to if target else "")
+    kind = f"{endpoint.system} {target!r}" if target else f"a {endpoint.system}"
+    return f"Enqueues {kind}" if endpoint.producer else f"Handles {kind}"
+
+def _counterpart(endpoint: Endpoint, all_endpoints: List[Endpoint]) -> Optional[Endpoint]:
+    for other in all_endpoints:
+        if (other.producer != endpoint.producer and other.system == endpoint.system
+                and (other.target == endpoint.target or not other.target or not endpoint.target)):
+            return other
+    return None
+
+@rule(
+    rule_id="async-context-not-propagated",
+    title="Carry trace context through queues, task payloads and job tables",
+    category="propagation",
+    signal="traces",
+    severity="high",
+    scope="project",
+    description="Work handed to a queue, a task library (asynq, river, gocraft/work, faktory, machinery) or a "
+                "jobs/outbox table runs later in another process. Unless the producer injects the context "
+                "into what it enqueues (message headers, a carrier in the payload, a trace_context column) "
+                "and the consumer extracts it before starting its span, the consumer starts a new trace and "
+                "the request that caused the work never shows what it led to. Both ends are reported, each "
+                "with the other when it can be found.",
+    bad_example='''
+func enqueueInvoice(ctx context.Context, db *sql.DB, orderID string) error {
+	ctx, span := tracer.Start(ctx, "enqueue invoice")
+	defer span.End()
+	_, err := db.ExecContext(ctx, "INSERT INTO invoice_jobs (order_id) VALUES ($1)", orderID)
+	return err
+}''',
+    good_example='''
+func enqueueInvoiceTraced(ctx context.Context, db *sql.DB, orderID string) error {
+	ctx, span := tracer.Start(ctx, "enqueue invoice")
+	defer span.End()
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	_, err := db.ExecContext(ctx, "INSERT INTO invoice_jobs (order_id, trace_context) VALUES ($1, $2)",
+		orderID, carrier["traceparent"])
+	return err
+}''',
+)
+def check_async_context_not_propagated(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    found = endpoints(sources)
+    for endpoint in found:
+        if endpoint.propagates:
+            continue
+        other = _counterpart(endpoint, found)
+        if endpoint.producer:
+            consumer = f"the consumer at {other.where}" if other else "its consumer"
+            message = f"{_action(endpoint)} without injecting the trace context, so {consumer} starts a new trace"
+            if endpoint.system == "message":
+                suggestion = ("Inject it into the message headers with otel.GetTextMapPropagator().Inject(ctx, carrier) "
+                              "over a propagation.TextMapCarrier for them, or use the client's otel instrumentation")
+            else:
+                suggestion = ("Inject it into a carrier stored with the job: carrier := propagation.MapCarrier{}; "
+                              "otel.GetTextMapPropagator().Inject(ctx, carrier); then save the carrier in the "
+                              "payload (or a trace_context column)")
+        else:
+            if other and other.propagates:
+                producer = f"although the producer at {other.where} injects it"
+            else:
+                producer = f"so it isn't connected to the producer{' at ' + other.where if other else ''}"
+            message = f"{_action(endpoint)} without extracting the trace context, {producer}"
+            carrier = "the message headers" if endpoint.system == "message" else "the carrier saved with the job"
+            suggestion = (f"Extract it from {carrier} before starting the consumer span: "
+                          f"ctx = otel.GetTextMapPropagator().Extract(ctx, carrier), or link to it when "
+                          f"processing a batch")
+        yield Diagnostic(
+            pos=endpoint.pos,
+            message=message,
+            suggestion=suggestion,
+            confidence=0.7 if other else 0.6,
+            file=endpoint.source,
+        )
@@ -0,0 +1,718 @@
+"""
+Span lifetimes: a span covers one unit of work, not a goroutine that waits or loops indefinitely
+"""
+
+import re
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..golang import Call, GoFile, GoFunc, SpanStart, match_bracket
+from ..registry import rule
+
+# A select case or default that bounds the wait
+SELECT_TIMEOUT = r'\btime\.After\s*\(|\.Done\s*\(\s*\)|\bdefault\s*:|\.C\b'
+
+def span_extent(source: GoFile, start: SpanStart) -> Tuple[int, int]:
+    """Offsets the span is open between: from Start to the first End at the top level of the
+    function, or to the end of the function when End is deferred or only called conditionally"""
+
+    fn = start.func
+    masked = source.masked
+    span = re.escape(start.span_var)
+    for m in re.finditer(r'(?<![\w.])' + span + r'\.End\s*\(', masked[start.call.end:fn.body_end]):
+        pos = start.call.end + m.start()
+        if source.func_at(pos, include_literals=True) is not fn or re.search(r'\bdefer\s+$', source.statement_prefix(pos)):
+            continue
+        between = masked[fn.body_start + 1:pos]
+        if between.count("{") == between.count("}"):
+            return start.call.end, pos
+    return start.call.end, fn.body_end
+
+def _is_receive(masked: str, pos: int) -> bool:
+    """Whether the <- at pos receives (rather than sends, or spells a channel type)"""
+
+    before = masked[:pos].rstrip()
+    if before.endswith("chan") or masked[pos + 2:].lstrip().startswith("chan"):
+        return False
+    if re.search(r'\b(?:return|case)$', before):
+        return True
+    return not before or not (before[-1].isalnum() or before[-1] in "_)]")
+
+def blocking_construct(source: GoFile, start: SpanStart, lo: int, hi: int) -> Optional[Tuple[int, str, float]]:
+    """(offset, description, confidence) of the first construct between lo and hi that can keep the
+    span open indefinitely; code in function literals and goroutines doesn't count"""
+
+    masked = source.masked
+    fn = start.func
+    found = []
+
+    def own(pos: int) -> bool:
+        return source.func_at(pos, include_literals=True) is fn
+
+    for m in re.finditer(r'\bselect\s*\{\s*\}', masked[lo:hi]):
+        if own(lo + m.start()):
+            found.append((lo + m.start(), "an empty select{}, which blocks forever", 0.7))
+    for kw, body_open, body_close in source.loops(lo, hi):
+        if not own(kw):
+            continue
+        header = masked[kw + 3:body_open].strip()
+        body = masked[body_open:body_close]
+        ranged = re.match(r'(?:[\w\s,]+:?=\s*)?range\s+([\w.]+(?:\([^()]*\))?)$', header)
+        if re.search(r'\btime\.Tick\s*\(|\.C$', header) or re.search(r'<-\s*[\w.]+\.C\b|\btime\.Tick\s*\(', body):
+            found.append((kw, "a ticker loop", 0.7))
+        elif not header and re.search(r'\bselect\s*\{', body):
+            found.append((kw, "a for-select loop with no exit condition", 0.7))
+        elif re.search(r'\btime\.Sleep\s*\(', body) and not (ranged or ";" in header):
+            # Counted and ranged loops end; a bare or conditional one may spin until shutdown
+            found.append((kw, "time.Sleep in a loop", 0.7))
+        elif ranged and is_channel(source, ranged.group(1)):
+            found.append((kw, f"a loop over channel {ranged.group(1)}, which runs until it is closed", 0.7))
+    for m in re.finditer(r'\bselect\s*\{', masked[lo:hi]):
+        pos = lo + m.start()
+        close = match_bracket(masked, lo + m.end() - 1)
+        loop = source.enclosing_loop(pos, fn)
+        if (own(pos) and masked[lo + m.end():close].strip() and not re.search(SELECT_TIMEOUT, masked[pos:close])
+                and not (loop and loop[0] >= lo)):
+            found.append((pos, "a select with no timeout, cancellation or default case", 0.7))
+    for m in re.finditer(r'<-', masked[lo:hi]):
+        pos = lo + m.start()
+        if not own(pos) or not _is_receive(masked, pos) or re.search(r'\bcase\b(?:[^:]|:=)*$', source.statement_prefix(pos)):
+            continue
+        operand = re.match(r'\s*([\w.]+(?:\(\))?)', masked[pos + 2:])
+        what = operand.group(1) if operand else "a channel"
+        if re.search(r'\.Done\(\)$', what):
+            found.append((pos, f"a wait on {what} with no other way out", 0.7))
+        elif not re.search(r'\.C$|\btime\.After', what):
+            # Usually a goroutine's result, which does arrive
+            found.append((pos, f"a receive from {what} with no timeout", 0.5))
+    return min(found) if found else None
+
+def is_channel(source: GoFile, name: str) -> bool:
+    base = re.escape(name.split(".")[-1])
+    return bool(re.search(r'\b' + base + r'\s*(?::=\s*make\s*\(\s*(?:<-\s*)?chan\b|\s+(?:<-\s*)?chan\b|\s+chan\s*<-)',
+                          source.masked))
+
+@rule(
+    rule_id="span-long-lived",
+    title="End spans before waiting or looping indefinitely",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    description="A span is exported when it ends. One left open around a for-select loop, a ticker, "
+                "time.Sleep in a loop or a receive with no timeout lives as long as the goroutine: it "
+                "holds memory, never shows up in the backend (or shows up once, hours long, when the "
+                "process stops) and every child lands in a trace that never completes. Start one span "
+                "per unit of work (per message, per tick) inside the loop, and end setup spans before it.",
+    bad_example='''
+func runWorker(ctx context.Context, jobs <-chan string) {
+	ctx, span := tracer.Start(ctx, "run worker")
+	defer span.End()
+	for {
+		select {
+		case job := <-jobs:
+			process(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}''',
+    good_example='''
+func runWorkerPerJob(ctx context.Context, jobs <-chan string) {
+	for {
+		select {
+		case job := <-jobs:
+			jobCtx, span := tracer.Start(ctx, "process job")
+			process(jobCtx, job)
+			span.End()
+		case <-ctx.Done():
+			return
+		}
+	}
+}''',
+)
+def check_span_long_lived(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    for start in source.span_starts:
+        if not start.span_var or start.span_var == "_" or start.func is None:
+            continue
+        lo, hi = span_extent(source, start)
+        found = blocking_construct(source, start, lo, hi)
+        if found is None:
+            continue
+        pos, what, confidence = found
+        name = start.name_arg.text.strip() if start.name_arg else start.span_var
+        yield Diagnostic(
+            pos=start.call.start,
+            end=start.call.end,
+            message=f"Span {name} stays open across {what} (line {source.line_of(pos)}), so it may never end",
+            suggestion="End the span before the loop or wait, and start a span per unit of work inside it",
+            confidence=confidence,
+        )
+
+# Calls that serve until the process stops: net/http, gRPC, and gin/echo style Run(":8080")
+SERVE_CALLS = re.compile(r'(?<![\w.])(?:\w+\.)*(?:ListenAndServe(?:TLS)?|Serve(?:TLS)?|RunTLS)\s*\('
+                         r'|(?<![\w.])\w+\.(?:Run|Start)\s*\(\s*"[^"]*:\d*"\s*\)')
+CALL = re.compile(r'(?<![\w])(?:\w+\.)?(\w+)\s*\(')
+
+class _CallGraph:
+    """Calls between the named functions of the code. A call is plain when the caller makes it
+    itself, once: not in a loop, a goroutine or a function literal (a handler, a callback)."""
+
+    def __init__(self, sources: List[GoFile]):
+        self.functions: Dict[str, List[Tuple[GoFile, GoFunc]]] = {}
+        for source in sources:
+            if source.path.endswith("_test.go"):
+                continue
+            for fn in source.functions:
+                if not fn.is_literal and fn.name:
+                    self.functions.setdefault(fn.name, []).append((source, fn))
+        # callee -> [(caller, plain)]
+        self.callers: Dict[str, List[Tuple[str, bool]]] = {}
+        # caller -> [(callee, source, offset)] of its plain calls
+        self.plain: Dict[str, List[Tuple[str, GoFile, int]]] = {}
+        for name, defs in self.functions.items():
+            for source, fn in defs:
+                for m in CALL.finditer(source.masked, fn.body_start, fn.body_end):
+                    callee = m.group(1)
+                    if callee not in self.functions or callee == name:
+                        continue
+                    plain = (source.func_at(m.start(), include_literals=True) is fn
+                             and source.enclosing_loop(m.start(), fn) is None
+                             and not re.search(r'\bgo\s+$', source.statement_prefix(m.start())))
+                    self.callers.setdefault(callee, []).append((name, plain))
+                    if plain:
+                        self.plain.setdefault(name, []).append((callee, source, m.start()))
+
+    def bootstrap(self) -> Set[str]:
+        """main, init, and the functions only ever called plainly from them, transitively"""
+
+        found = {n for n in ("main", "init") if any(not fn.receiver for _, fn in self.functions.get(n, []))}
+        changed = True
+        while changed:
+            changed = False
+            for name in self.functions:
+                callers = self.callers.get(name)
+                if name not in found and callers and all(plain and caller in found for caller, plain in callers):
+                    found.add(name)
+                    changed = True
+        return found
+
+    def serving(self) -> Dict[str, str]:
+        """Functions that serve until the process stops, directly or through a plain call, with
+        the call that does it"""
+
+        found: Dict[str, str] = {}
+        for name, defs in self.functions.items():
+            for source, fn in defs:
+                for pos, what in serve_calls(source, fn, fn.body_start, fn.body_end):
+                    found.setdefault(name, what)
+        changed = True
+        while changed:
+            changed = False
+            for name, calls in self.plain.items():
+                if name in found:
+                    continue
+                callee = next((c for c, _, _ in calls if c in found), None)
+                if callee is not None:
+                    found[name] = f"{callee}()"
+                    changed = True
+        return found
+
+    def reaching_spans(self, sources: List[GoFile]) -> Set[str]:
+        """Functions that start a span or call one that does"""
+
+        found = {start.func.name for source in sources for start in source.span_starts
+                 if start.func is not None and start.func.name and not start.func.is_literal}
+        changed = True
+        while changed:
+            changed = False
+            for callee, callers in self.callers.items():
+                if callee in found:
+                    for caller, _ in callers:
+                        if caller not in found:
+                            found.add(caller)
+                            changed = True
+        return found
+
+def serve_calls(source: GoFile, fn: GoFunc, lo: int, hi: int) -> Iterator[Tuple[int, str]]:
+    """(offset, call) of the serve calls fn makes itself between lo and hi, goroutines aside"""
+
+    for m in SERVE_CALLS.finditer(source.code, lo, hi):
+        pos = m.start()
+        if source.masked[pos] == " " or source.func_at(pos, include_literals=True) is not fn:
+            continue
+        if re.search(r'\bgo\s+$', source.statement_prefix(pos)):
+            continue
+        yield pos, m.group().split("(")[0].strip() + "()"
+
+@rule(
+    rule_id="span-app-lifetime",
+    title="Don't keep a startup span open while the application runs",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    scope="project",
+    description="A span started in main, init or the bootstrap code they call, and ended by a defer that "
+                "only runs at exit, stays open for the life of the process: it's exported at shutdown "
+                "if at all (not on os.Exit, log.Fatal or SIGKILL), shows up hours long, and every "
+                "request handled with its context joins one trace that never completes. Bootstrap "
+                "functions are found from the call graph: those only called from main or init, or from "
+                "other bootstrap functions, once and not from a handler or goroutine. Spans open across "
+                "ListenAndServe, Serve or Run, and spans main defers the end of across a loop calling "
+                "instrumented code, are reported; "
+                "those waiting on a select or channel are left to span-long-lived.",
+    bad_example='''
+func main() {
+	if err := run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "application startup")
+	defer span.End()
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	return http.ListenAndServe(cfg.Addr, newRouter())
+}''',
+    good_example='''
+func runTraced(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "load config")
+	cfg, err := loadConfig(ctx)
+	span.End()
+	if err != nil {
+		return err
+	}
+	return http.ListenAndServe(cfg.Addr, newRouter())
+}''',
+)
+def check_span_app_lifetime(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    graph = _CallGraph(sources)
+    bootstrap = graph.bootstrap()
+    if not bootstrap:
+        return
+    serving = graph.serving()
+    instrumented = graph.reaching_spans(sources)
+    for source in sources:
+        if source.path.endswith("_test.go"):
+            continue
+        for start in source.span_starts:
+            fn = start.func
+            if (fn is None or fn.is_literal or fn.name not in bootstrap or not start.span_var
+                    or start.span_var == "_"):
+                continue
+            lo, hi = span_extent(source, start)
+            if blocking_construct(source, start, lo, hi) is not None:
+                continue
+            found = min(list(serve_calls(source, fn, lo, hi))
+                        + [(pos, f"{callee}()") for callee, s, pos in graph.plain.get(fn.name, [])
+                           if s is source and lo <= pos < hi and callee in serving], default=None)
+            confidence = 0.8
+            if found is not None:
+                pos, what = found
+                across = f"{what} (line {source.line_of(pos)})"
+            elif fn.name == "main" and hi == fn.body_end:
+                loop = next((kw for kw, body_open, body_close in source.loops(lo, hi)
+                             if source.func_at(kw, include_literals=True) is fn
+                             and any(m.group(1) in instrumented and m.group(1) != fn.name
+                                     for m in CALL.finditer(source.masked, body_open, body_close))), None)
+                if loop is None:
+                    continue
+                across = f"the loop on line {source.line_of(loop)}, whose operations all become its children"
+                confidence = 0.5
+            else:
+                continue
+            name = start.name_arg.text.strip() if start.name_arg else start.span_var
+            where = fn.name if fn.name in ("main", "init") else f"{fn.name}, which only runs at startup,"
+            yield Diagnostic(
+                pos=start.call.start,
+                end=start.call.end,
+                message=f"Span {name} in {where} stays open across {across}, so it lasts as long as the process",
+                suggestion="End the span once startup is done, before serving or looping, and start a span per "
+                           "request or job from a context that doesn't carry it",
+                confidence=confidence,
+                file=source,
+            )
+
+# Statements that leave the function or the current iteration
+EXITS = re.compile(r'\b(?:return|continue|break|goto)\b|(?<![\w.])panic\s*\(')
+
+def _open_blocks(masked: str, lo: int, pos: int) -> List[int]:
+    """Offsets of the braces still open at pos, scanning from lo (a function body's brace)"""
+
+    stack = []
+    for i in range(lo, pos):
+        if masked[i] == "{":
+            stack.append(i)
+        elif masked[i] == "}" and stack:
+            stack.pop()
+    return stack
+
+def _escapes(source: GoFile, start: SpanStart) -> bool:
+    """Whether the span leaves the function (returned, stored, passed on) or a goroutine or
+    callback ends it, so its End is someone else's business"""
+
+    fn = start.func
+    span = re.escape(start.span_var)
+    for m in re.finditer(r'(?<![\w.])' + span + r'\b(?!\s*(?:\.|:?=[^=]|,[\w\s,]*:?=))', source.masked[start.call.end:fn.body_end]):
+        pos = start.call.end + m.start()
+        if source.func_at(pos, include_literals=True) is fn:
+            return True
+    for literal in source.functions:
+        if (literal.is_literal and fn.body_start < literal.start < fn.body_end and literal.start > start.call.start
+                and re.search(r'(?<![\w.])' + span + r'\.End\s*\(', source.masked[literal.body_start:literal.body_end])
+                and not re.search(r'\bdefer\s+$', source.masked[:literal.start])):
+            return True
+    return False
+
+def _ends(source: GoFile, start: SpanStart) -> List[Tuple[int, bool]]:
+    """(offset, deferred) of each span.End() after Start in the function, a deferred closure
+    calling it included"""
+
+    fn = start.func
+    span = re.escape(start.span_var)
+    found = []
+    for m in re.finditer(r'(?<![\w.])' + span + r'\.End\s*\(', source.masked[start.call.end:fn.body_end]):
+        pos = start.call.end + m.start()
+        inner = source.func_at(pos, include_literals=True)
+        if inner is fn:
+            found.append((pos, bool(re.search(r'\bdefer\s+$', source.statement_prefix(pos)))))
+        elif inner is not None and inner.is_literal and re.search(r'\bdefer\s+$', source.masked[:inner.start]):
+            found.append((inner.start, True))
+    return found
+
+def unended_exit(source: GoFile, start: SpanStart) -> Optional[Tuple[int, str]]:
+    """(offset, description) of the first way out of the span's block that doesn't end the span,
+    or None when every path ends it. Ends count for the exits of the block they are in and the
+    blocks nested in it; a deferred End covers every exit after it."""
+
+    fn = start.func
+    masked = source.masked
+    block = _open_blocks(masked, fn.body_start, start.call.start)
+    home = block[-1] if block else fn.body_start
+    home_close = match_bracket(masked, home)
+    ends = _ends(source, start)
+    if not ends:
+        return start.call.start, "any path"
+    deferred = min((pos for pos, is_deferred in ends
+                    if is_deferred and _open_blocks(masked, fn.body_start, pos)[-1:] == [home]), default=None)
+    for m in EXITS.finditer(masked, start.call.end, home_close):
+        pos = m.start()
+        if source.func_at(pos, include_literals=True) is not fn:
+            continue
+        if deferred is not None and pos > deferred:
+            break
+        word = m.group().rstrip("( \t")
+        if word in ("continue", "break") and home == fn.body_start:
+            continue
+        enclosing = _open_blocks(masked, fn.body_start, pos)
+        if any(end < pos and _open_blocks(masked, fn.body_start, end)[-1] in enclosing for end, _ in ends):
+            continue
+        what = {"return": "the return", "panic": "the panic", "continue": "continue", "break": "break",
+                "goto": "goto"}[word]
+        if deferred is not None:
+            return pos, f"{what} on line {source.line_of(pos)}, before defer {start.span_var}.End()"
+        return pos, f"{what} on line {source.line_of(pos)}"
+    if deferred is not None:
+        return None
+    if any(_open_blocks(masked, fn.body_start, end)[-1:] == [home] for end, _ in ends):
+        return None
+    last = masked[home + 1:home_close].rstrip()
+    last_line = last[last.rfind("\n") + 1:].strip()
+    if last.endswith("}") or EXITS.match(last_line):
+        # Ends in an if/else or switch whose branches were checked above, or in an exit
+        return None
+    return home_close, f"the end of the {'function' if home == fn.body_start else 'block'} on line {source.line_of(home_close)}"
+
+@rule(
+    rule_id="span-not-ended",
+    title="End every span on every path",
+    category="correctness",
+    signal="traces",
+    severity="high",
+    autofix=True,
+    description="A span that is never ended is never exported, and keeps its attributes, events and "
+                "children in memory for as long as the process runs. An early return, a continue or a "
+                "panic between tracer.Start and span.End() leaks it just the same. defer span.End() "
+                "right after Start covers every path; spans that are returned, stored or handed to "
+                "another function are left to whoever ends them.",
+    bad_example='''
+func loadOrder(ctx context.Context, id string) (*Order, error) {
+	ctx, span := tracer.Start(ctx, "load order")
+	order, err := fetchOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	span.End()
+	return order, nil
+}''',
+    good_example='''
+func loadOrderTraced(ctx context.Context, id string) (*Order, error) {
+	ctx, span := tracer.Start(ctx, "load order")
+	defer span.End()
+	return fetchOrder(ctx, id)
+}''',
+)
+def check_span_not_ended(source: GoFile) -> Iterator[Diagnostic]:
+    for start in source.span_starts:
+        if start.func is None or not start.assign_op:
+            continue
+        name = start.name_arg.text.strip() if start.name_arg else "span"
+        if start.span_var == "_":
+            yield Diagnostic(
+                pos=start.call.start,
+                end=start.call.end,
+                message=f"Span {name} is discarded with _, so it can never be ended",
+                suggestion="Keep the span and defer its End()",
+                confidence=0.9,
+            )
+            continue
+        if _escapes(source, start):
+            continue
+        found = unended_exit(source, start)
+        if found is None:
+            continue
+        pos, where = found
+        fix = None
+        # A defer in a loop only runs when the function returns
+        in_loop = source.enclosing_loop(start.call.start, start.func) is not None
+        if where == "any path" and in_loop:
+            message = f"Span {name} is never ended"
+        elif where == "any path":
+            line_end = source.masked.find("\n", start.call.end)
+            line_start = source.code.rfind("\n", 0, start.call.start) + 1
+            indent = re.match(r'[ \t]*', source.code[line_start:]).group()
+            fix = Fix(description=f"Defer {start.span_var}.End() after Start",
+                      edits=[TextEdit(line_end, line_end, f"\n{indent}defer {start.span_var}.End()")])
+            message = f"Span {name} is never ended"
+        else:
+            message = f"Span {name} is not ended on {where}"
+        yield Diagnostic(
+            pos=start.call.start,
+            end=start.call.end,
+            message=message,
+            suggestion=f"End it at the end of each iteration and before every continue, break or return"
+                       if in_loop else f"Add defer {start.span_var}.End() right after Start"
+                       + ("" if fix else f", or end it before {where.split(' on line')[0]}"),
+            confidence=0.8,
+            fix=fix,
+        )
+
+# Span methods that add an event
+EVENT_METHODS = r'AddEvent|RecordError'
+
+def _deferred_at(source: GoFile, fn, pos: int) -> Optional[int]:
+    """Offset the defer running the call at pos is ordered by (the call itself, or the deferred
+    closure holding it), or None when the call isn't deferred by fn"""
+
+    if source.func_at(pos, include_literals=True) is fn:
+        return pos if re.search(r'\bdefer\s+$', source.statement_prefix(pos)) else None
+    inner = source.func_at(pos, include_literals=True)
+    if (inner is not None and inner.is_literal and source.func_at(inner.start, include_literals=True) is fn
+            and re.search(r'\bdefer\s+$', source.masked[max(0, inner.start - 32):inner.start])):
+        return inner.start
+    return None
+
+def span_calls(source: GoFile, start: SpanStart, methods: str) -> Iterator[Call]:
+    """Calls of methods (a regex alternation) on start's span variable, up to a later Start
+    reusing the variable, which begins another span"""
+
+    fn = start.func
+    restart = min((s.call.start for s in source.span_starts
+                   if s.func is fn and s.span_var == start.span_var and s.call.start > start.call.start),
+                  default=fn.body_end)
+    yield from source.calls(re.escape(start.span_var) + r'\.(?:' + methods + r')', start.call.end, restart)
+
+def after_end(source: GoFile, start: SpanStart, pos: int) -> Optional[str]:
+    """How the call on start's span at pos runs after the span's End, or None when it doesn't:
+    deferred past an explicit End or a later deferred one, or simply following End"""
+
+    fn = start.func
+    span = start.span_var
+    _, extent_end = span_extent(source, start)
+    end = extent_end if extent_end < fn.body_end else None
+    deferred_ends = [p for p, deferred in _ends(source, start) if deferred]
+    deferred = _deferred_at(source, fn, pos)
+    if deferred is not None and end is not None:
+        return f"runs when the function returns, after {span}.End() on line {source.line_of(end)}"
+    if deferred is not None and any(p > deferred for p in deferred_ends):
+        later = min(p for p in deferred_ends if p > deferred)
+        return f"is deferred before {span}.End() (line {source.line_of(later)}) and runs after it"
+    if deferred is None and end is not None and pos > end and source.func_at(pos, include_literals=True) is fn:
+        return f"comes after {span}.End() on line {source.line_of(end)}"
+    return None
+
+def _timestamp_problem(source: GoFile, start: SpanStart, call, end: Optional[int]) -> Optional[str]:
+    """Why the event's explicit timestamp may fall outside the span, or None"""
+
+    option = next((a for a in call.args[1:] if re.match(r'(?:\w+\.)?WithTimestamp\s*\(', a.text.strip())), None)
+    if option is None:
+        return None
+    text = source.code[option.start:option.end].strip()
+    value = text[text.index("(") + 1:text.rindex(")")].strip()
+    if re.fullmatch(r'time\.Time\s*\{\s*\}', value):
+        return "the zero time, which precedes the span start"
+    if re.match(r'time\.Now\s*\(\s*\)\.Add\s*\(\s*-', value):
+        return f"{value}, which can precede the span start"
+    if not re.fullmatch(r'\w+', value):
+        return None
+    # The span start backdated to the same time keeps it inside
+    if re.search(r'WithTimestamp\s*\(\s*' + re.escape(value) + r'\s*\)', source.masked[start.call.start:start.call.end]):
+        return None
+    fn = start.func
+    assigned = None
+    for m in re.finditer(r'(?<![\w.])' + re.escape(value) + r'\s*:?=\s*time\.Now\s*\(\s*\)',
+                         source.masked[fn.body_start:call.start]):
+        assigned = fn.body_start + m.start()
+    if assigned is None:
+        return None
+    if assigned < start.call.start:
+        return f"{value}, taken on line {source.line_of(assigned)} before the span started"
+    if end is not None and assigned > end:
+        return f"{value}, taken on line {source.line_of(assigned)} after the span ended"
+    return None
+
+@rule(
+    rule_id="span-event-outside-span",
+    title="Keep span events between the span's start and End",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    description="An event added after span.End() is dropped by the SDK, and one that a deferred "
+                "function adds after a deferred End (defers run last registered first) or after an "
+                "explicit End is lost the same way. An event whose trace.WithTimestamp is taken before "
+                "tracer.Start, or is the zero time, lands before the span it belongs to, which "
+                "backends draw outside the bar or reorder. Add events before End, defer End first so "
+                "it runs last, and backdate the span with trace.WithTimestamp too when its events are.",
+    bad_example='''
+func sendBatch(ctx context.Context, batch []string) error {
+	queued := time.Now()
+	ctx, span := tracer.Start(ctx, "send batch")
+	defer func() {
+		span.AddEvent("batch.sent")
+	}()
+	defer span.End()
+	span.AddEvent("batch.queued", trace.WithTimestamp(queued))
+	return publish(ctx, batch)
+}''',
+    good_example='''
+func sendBatchTraced(ctx context.Context, batch []string) error {
+	queued := time.Now()
+	ctx, span := tracer.Start(ctx, "send batch", trace.WithTimestamp(queued))
+	defer span.End()
+	defer func() {
+		span.AddEvent("batch.sent")
+	}()
+	span.AddEvent("batch.queued", trace.WithTimestamp(queued))
+	return publish(ctx, batch)
+}''',
+)
+def check_span_event_outside_span(source: GoFile) -> Iterator[Diagnostic]:
+    for start in source.span_starts:
+        if not start.span_var or start.span_var == "_" or start.func is None:
+            continue
+        _, extent_end = span_extent(source, start)
+        end = extent_end if extent_end < start.func.body_end else None
+        span = start.span_var
+        for call in span_calls(source, start, EVENT_METHODS):
+            method = call.name.split(".")[-1]
+            what = f"{span}.{method}"
+            if method == "AddEvent" and call.args:
+                what += f"({call.args[0].text.strip()})"
+            problem = after_end(source, start, call.start)
+            if problem:
+                yield Diagnostic(
+                    pos=call.start,
+                    end=call.end,
+                    message=f"{what} {problem}, so the event is dropped",
+                    suggestion=f"Add the event before {span}.End(), or defer {span}.End() first so it runs last",
+                    confidence=0.85,
+                )
+                continue
+            timestamp = _timestamp_problem(source, start, call, end)
+            if timestamp:
+                yield Diagnostic(
+                    pos=call.start,
+                    end=call.end,
+                    message=f"{what} is timestamped with {timestamp}",
+                    suggestion="Take the timestamp while the span is open, or start the span with the same "
+                               "trace.WithTimestamp",
+                    confidence=0.7,
+                )
+
+def _closure_fix(source: GoFile, loop: tuple) -> Optional[Fix]:
+    """Wrap the loop body in a function literal called per iteration, so its defers run at the
+    end of each iteration. Not offered when the body leaves the loop or the function, which a
+    closure would change the meaning of."""
+
+    body = source.code[loop[1] + 1:loop[2]]
+    if EXITS.search(source.masked[loop[1] + 1:loop[2]]) or "\n" not in body:
+        return None
+    line_start = source.code.rfind("\n", 0, loop[0]) + 1
+    indent = re.match(r'[ \t]*', source.code[line_start:]).group()
+    lines = body.lstrip("\n").rstrip().split("\n")
+    wrapped = "\n".join("\t" + line if line.strip() else line for line in lines)
+    return Fix(description="Wrap the loop body in a closure so the deferred End runs per iteration",
+               edits=[TextEdit(loop[1] + 1, loop[2], f"\n{indent}\tfunc() {{\n{wrapped}\n{indent}\t}}()\n{indent}")])
+
+@rule(
+    rule_id="defer-end-in-loop",
+    title="Don't defer span.End() in a loop body",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    autofix=True,
+    description="defer runs when the function returns, not when the iteration ends. A defer span.End() "
+                "in a loop body keeps every iteration's span open until the whole loop (and whatever "
+                "follows it) is done: each span's duration covers all later iterations, the spans pile up "
+                "in memory, and for a long-running loop they are never exported. Move the iteration into "
+                "a function (or a closure called per iteration) that defers End, or call End explicitly "
+                "at the end of the iteration and before every continue.",
+    bad_example='''
+func consumeOrders(ctx context.Context, orders <-chan Order) {
+	for order := range orders {
+		ctx, span := tracer.Start(ctx, "process order", trace.WithSpanKind(trace.SpanKindConsumer))
+		defer span.End()
+		handleOrder(ctx, order)
+	}
+}''',
+    good_example='''
+func consumeOrdersTraced(ctx context.Context, orders <-chan Order) {
+	for order := range orders {
+		func() {
+			ctx, span := tracer.Start(ctx, "process order", trace.WithSpanKind(trace.SpanKindConsumer))
+			defer span.End()
+			handleOrder(ctx, order)
+		}()
+	}
+}''',
+)
+def check_defer_end_in_loop(source: GoFile) -> Iterator[Diagnostic]:
+    spans = source.span_vars()
+    for m in re.finditer(r'\bdefer\s+(?:(\w+)\.End\s*\(|func\s*\(\s*\)\s*\{)', source.masked):
+        span = m.group(1)
+        if span is None:
+            # A deferred closure ending a span
+            open_brace = m.end() - 1
+            inner = re.search(r'(?<![\w.])(\w+)\.End\s*\(', source.masked[open_brace:match_bracket(source.masked, open_brace)])
+            span = inner.group(1) if inner else None
+        if span not in spans:
+            continue
+        fn = source.func_at(m.start(), include_literals=True)
+        loop = source.enclosing_loop(m.start(), fn) if fn is not None else None
+        if loop is None:
+            continue
+        where = f"{fn.name} returns" if fn.name else "the enclosing function returns"
+        yield Diagnostic(
+            pos=m.start(),
+            end=m.end(),
+            message=f"defer {span}.End() in the loop on line {source.line_of(loop[0])} only runs when {where}, "
+                    f"so each iteration's span stays open until the loop is done",
+            suggestion="Run the iteration in a closure or function that defers End, or call "
+                       f"{span}.End() at the end of the iteration and before each continue",
+            confidence=0.9,
+            fix=_closure_fix(source, loop),
+        )
@@ -0,0 +1,158 @@
+"""
+Span limits: attributes, events and links beyond the SDK's per-span caps are dropped without notice
+"""
+
+import re
+from dataclasses import dataclass, field
+from typing import Dict, Iterator, List, Optional, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, SpanStart
+from ..registry import rule
+
+# sdktrace defaults (also the OTEL_SPAN_*_COUNT_LIMIT defaults)
+DEFAULT_LIMITS = {"AttributeCountLimit": 128, "EventCountLimit": 128, "LinkCountLimit": 128}
+
+LIMIT_NAMES = {"AttributeCountLimit": "attributes", "EventCountLimit": "events", "LinkCountLimit": "links"}
+
+@dataclass
+class Tally:
+    """What a span can record, and the call that pushes it furthest"""
+    count: int = 0
+    pos: int = -1
+    reasons: List[str] = field(default_factory=list)
+
+    def add(self, n: int, pos: int, reason: str):
+        self.count += n
+        if n > 1 or self.pos == -1:
+            self.pos = pos
+        if reason not in self.reasons:
+            self.reasons.append(reason)
+
+def configured_limits(sources: List[GoFile]) -> Tuple[Dict[str, int], Optional[GoFile]]:
+    """Limits set through sdktrace.SpanLimits literals or field assignments, over the defaults"""
+
+    limits, where = dict(DEFAULT_LIMITS), None
+    for source in sources:
+        for name in DEFAULT_LIMITS:
+            for m in re.finditer(r'\b' + name + r'\s*(?::|=)\s*(-?\d+)', source.code):
+                # Mentions in comments and strings are masked out
+                if source.masked[m.start()] == " ":
+                    continue
+                limits[name] = int(m.group(1))
+                where = source
+    return limits, where
+
+def _int_value(source: GoFile, text: str) -> Optional[int]:
+    text = source.constants.get(text, text).strip()
+    return int(text) if re.fullmatch(r'\d+', text) else None
+
+def loop_iterations(source: GoFile, loop: tuple) -> Optional[int]:
+    """Iteration count of a for loop with literal (or constant) bounds; None when it isn't known"""
+
+    header = source.code[loop[0] + 3:loop[1]].strip()
+    m = re.fullmatch(r'\w+\s*:=\s*(\w+)\s*;\s*\w+\s*(<=?)\s*(\w+)\s*;\s*\w+(?:\+\+|\s*\+=\s*1)', header)
+    if m:
+        lo, hi = _int_value(source, m.group(1)), _int_value(source, m.group(3))
+        if lo is None or hi is None:
+            return None
+        return max(0, hi - lo + (1 if m.group(2) == "<=" else 0))
+    m = re.fullmatch(r'(?:\w+\s*:=\s*)?range\s+(\w+)', header)
+    return _int_value(source, m.group(1)) if m else None
+
+def _multiplier(source: GoFile, pos: int, fn: GoFunc) -> Tuple[int, str]:
+    """Product of the iteration counts of the known-bound loops around pos"""
+
+    n, described = 1, []
+    for loop in source.loops(fn.body_start, fn.body_end):
+        if loop[1] < pos < loop[2]:
+            iterations = loop_iterations(source, loop)
+            if iterations is None:
+                return 1, ""
+            n *= iterations
+            described.append(str(iterations))
+    return n, " x ".join(described)
+
+def _tally_span(source: GoFile, start: SpanStart) -> Dict[str, Tally]:
+    tallies = {name: Tally() for name in DEFAULT_LIMITS}
+    fn, var = start.func, start.span_var
+    options = " ".join(a.text for a in start.call.args[2:])
+    for m in re.finditer(r'WithAttributes\s*\(([^()]*(?:\([^()]*\)[^()]*)*)\)', options):
+        keys = len(re.findall(r'\.(?:String|Int|Int64|Float64|Bool|\w+Slice)\s*\(', m.group(1)))
+        if keys:
+            tallies["AttributeCountLimit"].add(keys, start.call.start, "start options")
+
+    literal_keys = set()
+    for call in source.calls(re.escape(var) + r'\.(?:SetAttributes|AddEvent|RecordError|AddLink)',
+                             start.call.end, fn.body_end):
+        method = call.name.rsplit(".", 1)[-1]
+        n, loops = _multiplier(source, call.start, fn)
+        in_loop = f"{method} in a loop of {loops} iterations" if loops else method
+        if method == "SetAttributes":
+            for arg in call.args:
+                if arg.text.rstrip().endswith("..."):
+                    continue
+                key_m = re.match(r'[\w.]+\s*\(\s*("[^"]*")', arg.text.strip())
+                # Setting the same key again overwrites it instead of taking another slot
+                if key_m:
+                    if key_m.group(1) not in literal_keys:
+                        literal_keys.add(key_m.group(1))
+                        tallies["AttributeCountLimit"].add(1, call.start, method)
+                else:
+                    tallies["AttributeCountLimit"].add(n, call.start, in_loop)
+        elif method == "AddLink":
+            tallies["LinkCountLimit"].add(n, call.start, in_loop)
+        else:
+            tallies["EventCountLimit"].add(n, call.start, in_loop)
+    return tallies
+
+@rule(
+    rule_id="span-limits-exceeded",
+    title="Spans must stay within the configured span limits",
+    category="sdk",
+    signal="traces",
+    severity="medium",
+    scope="project",
+    description="The SDK keeps at most 128 attributes, events and links per span by default (or what "
+                "sdktrace.WithSpanLimits configures) and silently drops the rest, so the last retries, "
+                "batch items or errors recorded are exactly the ones that go missing.",
+    bad_example='''
+func importRows(ctx context.Context, rows []string) {
+	_, span := tracer.Start(ctx, "import rows")
+	defer span.End()
+	for i := 0; i < 500; i++ {
+		span.AddEvent("row.imported")
+	}
+}''',
+    good_example='''
+func importRowsSummarized(ctx context.Context, rows []string) {
+	_, span := tracer.Start(ctx, "import rows")
+	defer span.End()
+	imported := 0
+	for i := 0; i < 500; i++ {
+		imported++
+	}
+	span.SetAttributes(attribute.Int("import.rows", imported))
+}''',
+)
+def check_span_limits(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    limits, configured_in = configured_limits(sources)
+    origin = f"configured in {configured_in.path.rsplit('/', 1)[-1]}" if configured_in else "the SDK default"
+    for source in sources:
+        for start in source.span_starts:
+            if not start.span_var or start.span_var == "_" or start.func is None:
+                continue
+            for name, tally in _tally_span(source, start).items():
+                limit = limits[name]
+                if limit < 0 or tally.count <= limit:
+                    continue
+                span = start.name_arg.text if start.name_arg else start.span_var
+                yield Diagnostic(
+                    pos=tally.pos,
+                    message=f"Span {span} can record {tally.count} {LIMIT_NAMES[name]} "
+                            f"({', '.join(tally.reasons)}) but the limit is {limit} ({origin}); the rest are dropped",
+                    suggestion=f"Aggregate into fewer {LIMIT_NAMES[name]} (counts, a summary attribute) or "
+                               f"raise {name} in sdktrace.WithSpanLimits",
+                    confidence=0.8,
+                    file=source,
+                )
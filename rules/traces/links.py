@@ -0,0 +1,174 @@
+"""
+Span links added with span.AddLink (go.opentelemetry.io/otel/trace v1.23+): a link added once the
+span has ended is dropped like any other late change, a link to the span's own context points
+nowhere useful, and links appended per item of an unbounded loop run into the SDK's link limit.
+"""
+
+import re
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import GoFile, SpanStart, match_bracket
+from ..registry import rule
+from .limits import configured_limits, loop_iterations
+from .lifetime import after_end, span_calls
+
+# Links built from a context or a span context
+LINK = r'(?:\w+\.)?(?:LinkFromContext\s*\(|Link\s*\{)'
+# A comparison capping the iterations that add links: if i < max { ... } or if n >= max { break }
+CAP = re.compile(r'\bif\b[^{;]*[<>]=?[^{;]*\{')
+
+def _self_link(source: GoFile, start: SpanStart, pos: int, text: str) -> Optional[str]:
+    """How the link in text points at start's own span, or None"""
+
+    span = re.escape(start.span_var)
+    if re.search(r'(?<![\w.])' + span + r'\.SpanContext\s*\(', text):
+        return f"{start.span_var}.SpanContext()"
+    if start.ctx_var and start.ctx_var != "_":
+        ctx = re.escape(start.ctx_var)
+        # ctx assigned again holds something else by then
+        if re.search(r'(?<![\w.])' + ctx + r'\s*(?:,\s*\w+\s*)*:?=(?!=)', source.masked[start.call.end:pos]):
+            return None
+        m = re.search(r'(?:LinkFromContext|SpanContextFromContext|SpanFromContext)\s*\(\s*' + ctx + r'\s*[,)]', text)
+        if m:
+            return f"{start.ctx_var}, the context Start returned"
+    return None
+
+def _capped(source: GoFile, loop: tuple, pos: int) -> bool:
+    """Whether a comparison in the loop body guards pos or breaks out of the loop before it"""
+
+    for m in CAP.finditer(source.masked, loop[1] + 1, pos):
+        close = match_bracket(source.masked, m.end() - 1)
+        if close > pos or re.match(r'\s*break\b', source.masked[m.end():close]):
+            return True
+    return False
+
+def _unbounded_loop(source: GoFile, start: SpanStart, pos: int) -> Optional[tuple]:
+    """The loop around pos, entered after the span started, when neither its header nor a
+    comparison in its body bounds the iterations reaching pos"""
+
+    loop = source.enclosing_loop(pos, start.func)
+    if loop is None or loop[0] < start.call.start or loop_iterations(source, loop) is not None:
+        return None
+    return None if _capped(source, loop, pos) else loop
+
+def _appended_links(source: GoFile, start: SpanStart) -> Iterator[tuple]:
+    """(append offset, end, variable, loop) of link slices grown in an unbounded loop
+    of start's function and passed to a later Start's trace.WithLinks"""
+
+    fn = start.func
+    pattern = r'(?<![\w.])(\w+)\s*=\s*append\s*\(\s*(\w+)\s*,\s*' + LINK
+    for m in re.finditer(pattern, source.masked[fn.body_start:fn.body_end]):
+        if m.group(1) != m.group(2):
+            continue
+        pos = fn.body_start + m.start()
+        loop = source.enclosing_loop(pos, fn)
+        if loop is None or loop[0] > start.call.start or loop_iterations(source, loop) is not None \
+                or _capped(source, loop, pos):
+            continue
+        used = re.search(r'WithLinks\s*\(\s*' + re.escape(m.group(1)) + r'\s*\.\.\.',
+                         source.masked[start.call.open_paren:start.call.close_paren])
+        if used:
+            yield pos, fn.body_start + m.end(), m.group(1), loop
+
+@rule(
+    rule_id="span-link-misuse",
+    title="Add span links while the span is open, to other spans, a bounded number of times",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    scope="project",
+    description="span.AddLink records a link after the span started, e.g. to the messages a batch "
+                "consumer turns out to process. A link added after span.End() (explicit, or deferred "
+                "to run before a deferred AddLink) is dropped by the SDK. A link to the span's own "
+                "context, its span.SpanContext() or the ctx tracer.Start returned, points back at the span "
+                "itself; link the context the work came from instead. Links added per iteration of a loop "
+                "with no known bound, or appended to the slice a later tracer.Start passes to "
+                "trace.WithLinks, grow with the input, and everything past LinkCountLimit (128 unless "
+                "sdktrace.WithSpanLimits says otherwise) is silently dropped: cap them, or start a span "
+                "per item linked to the batch. Loops with a literal bound are left to span-limits-exceeded.",
+    bad_example='''
+func consumeBatch(ctx context.Context, msgs []Message) {
+	ctx, span := tracer.Start(ctx, "process batch", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+	for _, msg := range msgs {
+		span.AddLink(trace.LinkFromContext(msg.Context()))
+		handle(ctx, msg)
+	}
+	span.AddLink(trace.LinkFromContext(ctx))
+}''',
+    good_example='''
+func consumeBatchTraced(ctx context.Context, msgs []Message) {
+	ctx, span := tracer.Start(ctx, "process batch", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+	for i, msg := range msgs {
+		if i < maxLinks {
+			span.AddLink(trace.LinkFromContext(msg.Context()))
+		}
+		handle(ctx, msg)
+	}
+	span.SetAttributes(attribute.Int("messaging.batch.message_count", len(msgs)))
+}''',
+)
+def check_span_link_misuse(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    limits, configured_in = configured_limits(sources)
+    limit = limits["LinkCountLimit"]
+    origin = f"configured in {configured_in.path.rsplit('/', 1)[-1]}" if configured_in else "the SDK default"
+    for source in sources:
+        for start in source.span_starts:
+            if start.func is None:
+                continue
+            for pos, end, var, loop in _appended_links(source, start):
+                yield Diagnostic(
+                    pos=pos,
+                    end=end,
+                    message=f"{var} gets a link per iteration of the loop on line {source.line_of(loop[0])} and "
+                            f"goes to trace.WithLinks on line {source.line_of(start.call.start)}; links past "
+                            f"LinkCountLimit ({limit}, {origin}) are dropped",
+                    suggestion="Stop appending once the limit is reached, or start a span per item linked to "
+                               "the batch instead",
+                    confidence=0.6,
+                    file=source,
+                )
+            if not start.span_var or start.span_var == "_":
+                continue
+            span = start.span_var
+            for call in span_calls(source, start, "AddLink"):
+                text = source.code[call.open_paren:call.close_paren]
+                problem = after_end(source, start, call.start)
+                if problem:
+                    yield Diagnostic(
+                        pos=call.start,
+                        end=call.end,
+                        message=f"{span}.AddLink {problem}, so the link is dropped",
+                        suggestion=f"Add the link before {span}.End(), or pass it to tracer.Start with "
+                                   f"trace.WithLinks",
+                        confidence=0.85,
+                        file=source,
+                    )
+                    continue
+                own = _self_link(source, start, call.start, text)
+                if own:
+                    yield Diagnostic(
+                        pos=call.start,
+                        end=call.end,
+                        message=f"{span}.AddLink links the span to itself through {own}",
+                        suggestion="Link the context of the work this span relates to: the message, request or "
+                                   "batch it came from",
+                        confidence=0.8,
+                        file=source,
+                    )
+                    continue
+                loop = _unbounded_loop(source, start, call.start)
+                if loop is not None:
+                    yield Diagnostic(
+                        pos=call.start,
+                        end=call.end,
+                        message=f"{span}.AddLink runs on every iteration of the loop on line "
+                                f"{source.line_of(loop[0])}, which has no bound; links past LinkCountLimit "
+                                f"({limit}, {origin}) are dropped",
+                        suggestion="Stop adding links once the limit is reached, or start a span per item "
+                                   "linked to the batch instead",
+                        confidence=0.6,
+                        file=source,
+                    )
@@ -0,0 +1,282 @@
+"""
+Span and span event names: they are aggregated on, so they must stay low cardinality
+"""
+
+import re
+from typing import Dict, Iterator, Optional
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..cardinality import Bounds
+from ..conventions import (SPAN_NAME_SEPARATORS, event_name, event_name_problems, span_name, span_name_problems,
+                           vocabulary_problems)
+from ..golang import GoFile
+from ..registry import rule
+
+@rule(
+    rule_id="span-name-unbounded",
+    title="Build span names only from a small, fixed set of values",
+    category="conventions",
+    signal="traces",
+    severity="medium",
+    options={"allow_bounded_dynamic_names": True, "max_values": 20},
+    description="Backends group, sample and compute metrics by span name, so a name containing IDs, paths "
+                "or user input creates a new series per request. A name built from variables is accepted "
+                "when every piece provably comes from a small constant set: constants, a typed enum, the "
+                "cases of a switch, the keys of a constant map or the HTTP method and route template. "
+                "Helper functions of the package (spanNameFor(op)) are followed with their arguments, and "
+                "fmt.Sprintf templates operand by operand: %t and %T are bounded whatever they format, "
+                "and the finding names the operand (a timestamp, an ID, a loop variable) and the verb "
+                "that make the name unbounded. "
+                "A name built from a parameter is checked at the calls of its function in the package, "
+                "with each argument in place of its parameter (declare helpers called from other "
+                "packages under span_helpers so their call sites are checked too).",
+    bad_example='''
+func handleDownload(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "GET "+r.URL.Path)
+	defer span.End()
+	serveFile(ctx, w, r.URL.Path)
+}''',
+    good_example='''
+type JobKind string
+
+const (
+	JobExport JobKind = "export"
+	JobImport JobKind = "import"
+)
+
+func runJob(ctx context.Context, kind JobKind, id string) {
+	ctx, span := tracer.Start(ctx, "run "+string(kind)+" job")
+	defer span.End()
+	span.SetAttributes(attribute.String("job.id", id))
+}''',
+)
+def check_span_name_unbounded(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    bounds = Bounds(source, options["max_values"])
+    for start in source.span_starts:
+        if start.name_arg is None or start.name is not None:
+            continue
+        text = start.name_arg.text.strip()
+        values = bounds.values(text, start.name_arg.start)
+        if values is None:
+            yield Diagnostic(
+                pos=start.name_arg.start,
+                end=start.name_arg.end,
+                message=f"Span name {text} is built from {bounds.explain()}, which can't be shown to "
+                        f"take only a few values",
+                suggestion=f"Use a fixed name (the operation, or the route template for HTTP) and record "
+                           f"{bounds.culprit} as an attribute instead",
+                confidence=0.7,
+            )
+        elif bounds.params or len(values) <= 1:
+            continue
+        elif not options["allow_bounded_dynamic_names"]:
+            yield Diagnostic(
+                pos=start.name_arg.start,
+                end=start.name_arg.end,
+                message=f"Span name {text} is dynamic; it takes one of {len(values)} values, but "
+                        f"allow_bounded_dynamic_names is off",
+                suggestion="Start the span with a literal name per case",
+                confidence=0.9,
+            )
+    yield from _unbounded_arguments(source, options)
+
+def _unbounded_arguments(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    """Calls in source that pass an unbounded value to a function of the package whose span name
+    is built from that parameter"""
+
+    bounds = Bounds(source, options["max_values"])
+    for callee in source.package_sources:
+        callee_bounds = Bounds(callee, options["max_values"])
+        for start in callee.span_starts:
+            fn = callee.func_at(start.call.start)
+            if start.name_arg is None or start.name is not None or fn is None:
+                continue
+            text = start.name_arg.text.strip()
+            if callee_bounds.values(text, start.name_arg.start) is None or not callee_bounds.params:
+                continue
+            params = ", ".join(sorted(callee_bounds.params))
+            callee_name = (r'\w+(?:\.\w+)*\.' if fn.receiver_type else "") + re.escape(fn.name)
+            for call in source.calls(callee_name):
+                # Leaves out the declaration, which isn't inside a function body
+                if source.func_at(call.start, include_literals=True) is None:
+                    continue
+                if bounds.values_at_call(text, start.name_arg.start, callee, fn, call) is not None:
+                    continue
+                yield Diagnostic(
+                    pos=call.start,
+                    end=call.end,
+                    message=f"{fn.name} names its span {text} after {params}, and this call passes "
+                            f"{bounds.explain()}, which can't be shown to take only a few values",
+                    suggestion=f"Pass {fn.name} a fixed name and record {bounds.culprit} as an attribute instead",
+                    confidence=0.7,
+                )
+
+def _dictionary_hint(options: Dict) -> str:
+    verbs, terms = options["verbs"], options["terms"]
+    hint = [f"an approved verb ({', '.join(verbs[:5])}{', ...' if len(verbs) > 5 else ''})"] if verbs else []
+    if terms:
+        hint.append(f"a domain term ({', '.join(terms[:5])}{', ...' if len(terms) > 5 else ''})")
+    return " and ".join(hint)
+
+@rule(
+    rule_id="span-name-convention",
+    title="Name spans '{verb} {object}' with the project's separators",
+    category="conventions",
+    signal="traces",
+    severity="medium",
+    autofix=True,
+    options={"separators": list(SPAN_NAME_SEPARATORS), "max_length": 0, "verbs": [], "terms": []},
+    description="Span names are what people search and group by, so they should read as a short, low "
+                "cardinality operation (\"fetch user\", \"GET /users/{id}\") rather than a function name "
+                "(\"fetchUser\", \"fetch_user\"). Names built from constants, package-level variables "
+                "or helper functions are checked in every value they can take. separators lists the "
+                "characters allowed between words; max_length, when set, caps the length of a name. "
+                "verbs and terms (usually set once under naming in the project config) are the team's "
+                "approved operation verbs and domain terms: names must then start with one of the verbs "
+                "and mention one of the terms (\"reserve inventory\"), and terms written with capitals "
+                "(\"PayPal\", \"iOS\") must be spelled as listed.",
+    bad_example='''
+func processUserData(ctx context.Context, u *User) error {
+	ctx, span := tracer.Start(ctx, "processUserData")
+	defer span.End()
+	return store(ctx, u)
+}''',
+    good_example='''
+func processUserDataTraced(ctx context.Context, u *User) error {
+	ctx, span := tracer.Start(ctx, "process user data")
+	defer span.End()
+	return store(ctx, u)
+}''',
+)
+def check_span_name_convention(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    separators, max_length = options["separators"], options["max_length"]
+    verbs, terms = options["verbs"], options["terms"]
+    bounds = Bounds(source)
+    for start in source.span_starts:
+        if start.name_arg is None:
+            continue
+        if start.name is None:
+            yield from _resolved_name_problems(start.name_arg, bounds, options)
+            continue
+        problems = span_name_problems(start.name, separators, max_length, terms)
+        vocabulary = vocabulary_problems(start.name, verbs, terms)
+        if not problems and not vocabulary:
+            continue
+        fix = None
+        suggestion = "Name the span after the operation ('{verb} {object}') and move variable parts into attributes"
+        fixed = span_name(start.name, separators[0] if separators else " ", terms)
+        if vocabulary:
+            suggestion = f"Name the span with {_dictionary_hint(options)}"
+        if fixed != start.name and not span_name_problems(fixed, separators, max_length, terms):
+            suggestion = f'Rename the span to "{fixed}"' + (f", with {_dictionary_hint(options)}" if vocabulary else "")
+            fix = Fix(description=f'Rename span "{start.name}" to "{fixed}"',
+                      edits=[TextEdit(start.name_arg.start, start.name_arg.end, f'"{fixed}"')])
+        yield Diagnostic(
+            pos=start.name_arg.start,
+            end=start.name_arg.end,
+            message=f'Span name "{start.name}" {"; ".join(problems + vocabulary)}',
+            suggestion=suggestion,
+            confidence=0.8 if problems else 0.7,
+            fix=fix,
+        )
+
+def _resolved_name_problems(name_arg, bounds: Bounds, options: Dict) -> Iterator[Diagnostic]:
+    """span-name-convention for a name that isn't a literal: the first value it can take that
+    breaks the convention, left alone when the name can't be resolved"""
+
+    separators, max_length, terms = options["separators"], options["max_length"], options["terms"]
+    text = name_arg.text.strip()
+    for value in sorted(bounds.values(text, name_arg.start) or ()):
+        if "<" in value:
+            continue
+        problems = span_name_problems(value, separators, max_length, terms)
+        problems += vocabulary_problems(value, options["verbs"], terms)
+        if not problems:
+            continue
+        fixed = span_name(value, separators[0] if separators else " ", terms)
+        yield Diagnostic(
+            pos=name_arg.start,
+            end=name_arg.end,
+            message=f'Span name {text} resolves to "{value}", which {"; ".join(problems)}',
+            suggestion=f'Change the value it comes from to "{fixed}"'
+                       if fixed != value and not span_name_problems(fixed, separators, max_length, terms)
+                       else "Name the span after the operation ('{verb} {object}')",
+            confidence=0.7,
+        )
+        return
+
+def _span_name_for(source: GoFile, span_var: str, pos: int) -> Optional[str]:
+    """Literal name of the span held in span_var at pos, when it was started in the same function"""
+
+    name = None
+    for start in source.span_starts:
+        if start.span_var == span_var and start.call.start < pos and start.func and start.func.contains(pos):
+            name = start.name
+    return name
+
+@rule(
+    rule_id="span-event-name",
+    title="Name span events with lowercase dot separated words",
+    category="conventions",
+    signal="traces",
+    severity="low",
+    autofix=True,
+    description="Event names identify a kind of occurrence (\"cache.miss\", \"retry.scheduled\"), so like span "
+                "names they must be low cardinality: IDs and values belong in the event's attributes. "
+                "camelCase and spaces break the semconv style, and an event repeating its span's name adds "
+                "nothing the span doesn't already say.",
+    bad_example='''
+func lookupPrice(ctx context.Context, sku string) {
+	ctx, span := tracer.Start(ctx, "lookup price")
+	defer span.End()
+	span.AddEvent("cacheMiss")
+	span.AddEvent("fetched price for " + sku)
+}''',
+    good_example='''
+func lookupPriceTraced(ctx context.Context, sku string) {
+	ctx, span := tracer.Start(ctx, "lookup price")
+	defer span.End()
+	span.AddEvent("cache.miss", trace.WithAttributes(attribute.String("product.sku", sku)))
+}''',
+)
+def check_span_event_name(source: GoFile) -> Iterator[Diagnostic]:
+    bounds = Bounds(source)
+    for span_var in source.span_vars():
+        for call in source.calls(re.escape(span_var) + r'\.AddEvent'):
+            if not call.args:
+                continue
+            arg = call.args[0]
+            name = arg.literal
+            if name is None:
+                # Unlike span names, event names aren't forwarded through helpers, so parameters count too
+                if bounds.values(arg.text, arg.start) is None or bounds.params:
+                    culprit = bounds.culprit or sorted(bounds.params)[0]
+                    yield Diagnostic(
+                        pos=arg.start,
+                        end=arg.end,
+                        message=f"Event name {arg.text.strip()} is built from "
+                                f"{bounds.explain() if bounds.culprit else culprit}, which can't be "
+                                f"shown to take only a few values",
+                        suggestion=f"Use a fixed event name and record {culprit} as an event attribute",
+                        confidence=0.7,
+                    )
+                continue
+            span_name = _span_name_for(source, span_var, call.start)
+            problems = event_name_problems(name, span_name)
+            if not problems:
+                continue
+            fix = None
+            suggestion = "Rename the event after what happened and move variable parts into attributes"
+            fixed = event_name(name)
+            if not event_name_problems(fixed, span_name):
+                suggestion = f'Rename the event to "{fixed}"'
+                fix = Fix(description=f'Rename event "{name}" to "{fixed}"',
+                          edits=[TextEdit(arg.start, arg.end, f'"{fixed}"')])
+            yield Diagnostic(
+                pos=arg.start,
+                end=arg.end,
+                message=f'Event name "{name}" {"; ".join(problems)}',
+                suggestion=suggestion,
+                confidence=0.8,
+                fix=fix,
+            )
@@ -0,0 +1,132 @@
+"""
+Span start options: what tracer.Start is given is evaluated on every call, whether or not the span
+is sampled or the tracer is a noop, and later options silently override earlier ones.
+"""
+
+import re
+from typing import Iterator, List, Optional
+
+from ..base import Diagnostic
+from ..golang import Arg, GoFile, SpanStart, match_bracket
+from ..registry import rule
+
+# Calls whose cost grows with their input: serializing, dumping, reading whole bodies, stacks
+EXPENSIVE_CALLS = re.compile(
+    r'(?<![\w.])(?:json|xml|yaml|proto|protojson|msgpack)\.Marshal\w*\s*\(|\bhttputil\.Dump\w+\s*\('
+    r'|\bio\.ReadAll\s*\(|\b(?:debug|runtime)\.Stack\s*\(|\bfmt\.Sprintf\s*\(\s*"[^"]*%[+#]v')
+EMPTY_SLICE = r'(?:\[\]\s*[\w.]*KeyValue\s*\{\s*\}|make\s*\(\s*\[\]\s*[\w.]*KeyValue\s*,\s*0\s*(?:,[^()]*)?\)|nil)'
+
+def _options(start: SpanStart) -> List[Arg]:
+    """The SpanStartOptions of a span start, the arguments after its name"""
+
+    if start.name_arg is None:
+        return []
+    return [a for a in start.call.args if a.start > start.name_arg.start]
+
+def _empty_slice(source: GoFile, name: str, lo: int, pos: int) -> Optional[int]:
+    """Offset of the empty slice assigned to name that still holds at pos, or None"""
+
+    assigned = None
+    for m in re.finditer(r'(?<![\w.])' + re.escape(name) + r'\s*(?::=|=(?!=))\s*([^\n;]*)|\bvar\s+'
+                         + re.escape(name) + r'\s+\[\][\w.]*KeyValue\s*(?:=\s*([^\n;]*))?$', source.masked[lo:pos], re.M):
+        value = (m.group(1) if m.group(1) is not None else m.group(2) or "nil").strip()
+        assigned = lo + m.start() if re.fullmatch(EMPTY_SLICE, value) else None
+    if assigned is None:
+        return None
+    # Filled in before Start
+    filled = re.search(r'(?<![\w.])' + re.escape(name) + r'\s*=\s*append\s*\(|(?<![\w.])' + re.escape(name) + r'\s*\[(?!\s*\])',
+                       source.masked[assigned:pos])
+    return None if filled else assigned
+
+def _expensive_helpers(source: GoFile) -> List[str]:
+    """Functions of the file whose body loops or makes an expensive call"""
+
+    return [fn.name for fn in source.functions if not fn.is_literal and fn.name
+            and re.search(r'\bfor\b|' + EXPENSIVE_CALLS.pattern, source.masked[fn.body_start:fn.body_end])]
+
+@rule(
+    rule_id="span-start-options",
+    title="Keep tracer.Start options consistent and cheap",
+    category="performance",
+    signal="traces",
+    severity="low",
+    description="tracer.Start evaluates its options on every call, before anything knows whether the "
+                "span is sampled or the tracer is a noop. Options serializing payloads, dumping requests or "
+                "calling helpers that loop cost the same whether the span is kept or dropped; set those "
+                "attributes after Start inside if span.IsRecording() (unless a sampler needs them at "
+                "start). trace.WithAttributes of a slice that is always empty there allocates for nothing. "
+                "And of two trace.WithSpanKind options the last one silently wins.",
+    bad_example='''
+func forwardRequest(ctx context.Context, req *http.Request) {
+	ctx, span := tracer.Start(ctx, "forward request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.request.dump", dumpRequest(req))))
+	defer span.End()
+	send(ctx, req)
+}
+
+func dumpRequest(req *http.Request) string {
+	dump, _ := httputil.DumpRequest(req, true)
+	return string(dump)
+}''',
+    good_example='''
+func forwardRequestTraced(ctx context.Context, req *http.Request) {
+	ctx, span := tracer.Start(ctx, "forward request", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(semconv.HTTPRequestBodySize(int(req.ContentLength)))
+	}
+	send(ctx, req)
+}''',
+)
+def check_span_start_options(source: GoFile) -> Iterator[Diagnostic]:
+    helpers = _expensive_helpers(source)
+    helper_call = re.compile(r'(?<![\w.])(?:' + "|".join(re.escape(h) for h in helpers) + r')\s*\(') if helpers else None
+    for start in source.span_starts:
+        options = _options(start)
+        if not options:
+            continue
+        name = start.name_arg.text.strip()
+        kinds = [re.search(r'SpanKind(\w+)', o.text) for o in options if re.search(r'\bWithSpanKind\s*\(', o.text)]
+        if len(kinds) > 1:
+            yield Diagnostic(
+                pos=start.call.start,
+                end=start.call.end,
+                message=f"Span {name} is given {len(kinds)} WithSpanKind options "
+                        f"({', '.join(k.group(1) if k else '?' for k in kinds)}); only the last one applies",
+                suggestion="Keep the one WithSpanKind option that says what the span is",
+                confidence=0.9,
+            )
+        fn = start.func
+        for option in options:
+            m = re.match(r'(?:\w+\.)?WithAttributes\s*\(', option.text.strip())
+            if not m:
+                continue
+            open_paren = option.start + (len(option.text) - len(option.text.lstrip())) + m.end() - 1
+            inner = source.masked[open_paren + 1:match_bracket(source.masked, open_paren)].strip()
+            spread = re.fullmatch(r'(\w+)\s*\.\.\.', inner)
+            empty = not inner or re.fullmatch(EMPTY_SLICE + r'\s*\.\.\.', inner)
+            if not empty and spread and fn is not None:
+                empty = _empty_slice(source, spread.group(1), fn.body_start, start.call.start) is not None
+            if empty:
+                yield Diagnostic(
+                    pos=option.start,
+                    end=option.end,
+                    message=f"WithAttributes on span {name} is always given an empty list here",
+                    suggestion="Drop the option, or fill the slice before Start",
+                    confidence=0.8,
+                )
+                continue
+            # Code, not masked: the Sprintf pattern looks at the format string
+            text = source.code[option.start:option.end]
+            costly = EXPENSIVE_CALLS.search(text) or (helper_call.search(text) if helper_call else None)
+            if costly:
+                yield Diagnostic(
+                    pos=option.start + costly.start(),
+                    message=f"Span {name} computes {costly.group().rstrip('( ')}(…) for its start options on "
+                            f"every call, even when the span isn't sampled or the tracer is a noop",
+                    suggestion="Set the attribute after Start inside if span.IsRecording(), unless a sampler "
+                               "needs it at start",
+                    confidence=0.6,
+                )
@@ -0,0 +1,537 @@
+"""
+How spans get their parents: new roots, links and contexts carrying spans
+"""
+
+import re
+from typing import Dict, Iterator, List, Optional, Tuple
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..conventions import span_name
+from ..golang import GoFile, SpanStart, match_bracket, parse_params
+from ..registry import rule
+from .lifetime import span_extent
+
+TRACE_PKG = "go.opentelemetry.io/otel/trace"
+
+def _context_params(source: GoFile, pos: int) -> List[str]:
+    """context.Context parameters of every function enclosing pos, closures included"""
+
+    names = []
+    for fn in source.functions:
+        if fn.contains(pos):
+            names.extend(name for name, typ in parse_params(fn.params) if name and typ.endswith("context.Context"))
+    return names
+
+def _parent_source(source: GoFile, start: SpanStart) -> Optional[str]:
+    """Why the context passed to Start probably already carries a span, or None"""
+
+    if not start.call.args:
+        return None
+    ctx = start.call.args[0].text.strip()
+    if re.fullmatch(r'context\.(?:Background|TODO)\(\)', ctx):
+        return None
+    if re.fullmatch(r'\w+\.Context\(\)', ctx):
+        return f"the request context {ctx}"
+    if ctx in _context_params(source, start.call.start):
+        return f"the caller's context {ctx}"
+    for earlier in source.span_starts:
+        if (earlier.ctx_var == ctx and earlier.call.start < start.call.start
+                and earlier.func and earlier.func.contains(start.call.start)):
+            return f"{ctx}, which carries span {earlier.name_arg.text if earlier.name_arg else ''}"
+    return None
+
+def _in_goroutine(source: GoFile, start: SpanStart) -> bool:
+    fn = start.func
+    return bool(fn and fn.is_literal and re.search(r'\bgo\s+$', source.masked[:fn.start]))
+
+@rule(
+    rule_id="span-new-root-in-request",
+    title="Don't cut traces with WithNewRoot inside a request",
+    category="propagation",
+    signal="traces",
+    severity="high",
+    description="trace.WithNewRoot ignores the span in the context, so work started from a request "
+                "becomes a separate trace that can't be found from the request. To decouple async or "
+                "batch work, start a new root and link it to the request span instead.",
+    bad_example='''
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "export report", trace.WithNewRoot())
+	defer span.End()
+	exportReport(ctx)
+}''',
+    good_example='''
+func handleExportLinked(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "export report",
+		trace.WithNewRoot(), trace.WithLinks(trace.LinkFromContext(r.Context())))
+	defer span.End()
+	exportReport(ctx)
+}''',
+)
+def check_new_root(source: GoFile) -> Iterator[Diagnostic]:
+    aliases = source.import_alias(TRACE_PKG)
+    if not aliases:
+        return
+    alias_re = "|".join(re.escape(a) for a in aliases)
+    for start in source.span_starts:
+        options = " ".join(a.text for a in start.call.args[2:])
+        if not re.search(r'\b(?:' + alias_re + r')\.WithNewRoot\s*\(', options):
+            continue
+        if re.search(r'\bWithLinks\s*\(', options):
+            continue
+        parent = _parent_source(source, start)
+        if parent is None:
+            continue
+        where = "in a goroutine started from " if _in_goroutine(source, start) else "from "
+        yield Diagnostic(
+            pos=start.call.start,
+            message=f"Span {start.name_arg.text if start.name_arg else ''} is started with WithNewRoot "
+                    f"{where}{parent}, detaching it from the current trace",
+            suggestion="Drop WithNewRoot to keep the span in the trace, or keep it and add "
+                       f"{aliases[0]}.WithLinks({aliases[0]}.LinkFromContext(ctx)) so the new trace points back",
+            confidence=0.8,
+        )
+
+# Evidence that a function turns raw text or bytes into trace IDs itself
+ID_PARSING = r'\b(?:TraceIDFromHex|SpanIDFromHex)\s*\(|\bhex\.Decode\w*\s*\(|\b(?:TraceID|SpanID)\s*\{|\bcopy\s*\(\s*\w+\s*\[\s*:\s*\]'
+
+ID_ORIGINS = [
+    (r'Header\.Get\s*\(|Header\[', "a request header"),
+    (r'\.(?:Scan|QueryRow\w*)\s*\(', "a database row"),
+    (r'strings\.(?:Split\w*|Fields|Cut)\s*\(|regexp\.|json\.Unmarshal', "parsed text"),
+]
+
+def _is_propagator_method(source: GoFile, fn) -> bool:
+    if fn is None or fn.name != "Extract" or not fn.receiver_type:
+        return False
+    return "Inject" in source.methods_of(fn.receiver_type.lstrip("*"))
+
+@rule(
+    rule_id="span-context-hand-built",
+    title="Don't build SpanContexts from hand-parsed IDs",
+    category="propagation",
+    signal="traces",
+    severity="medium",
+    description="Parsing trace and span IDs out of headers, rows or log lines and assembling them with "
+                "trace.NewSpanContext duplicates what propagators do, and usually gets the edge cases wrong: "
+                "parse errors are dropped, trace flags are lost (making every child unsampled) and the "
+                "context isn't marked remote.",
+    bad_example='''
+func parentFromHeader(r *http.Request) context.Context {
+	traceID, _ := trace.TraceIDFromHex(r.Header.Get("X-Trace-Id"))
+	spanID, _ := trace.SpanIDFromHex(r.Header.Get("X-Span-Id"))
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	return trace.ContextWithRemoteSpanContext(r.Context(), sc)
+}''',
+    good_example='''
+func parentFromHeaders(r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}''',
+)
+def check_hand_built_span_context(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    for alias in source.import_alias(TRACE_PKG):
+        for call in source.calls(re.escape(alias) + r'\.NewSpanContext'):
+            fn = source.func_at(call.start, include_literals=True)
+            if fn is None or _is_propagator_method(source, source.func_at(call.start)):
+                continue
+            body = source.code[fn.body_start:fn.body_end]
+            if not re.search(ID_PARSING, source.masked[fn.body_start:fn.body_end]):
+                continue
+            origin = next((what for pattern, what in ID_ORIGINS if re.search(pattern, body)), "hand-parsed IDs")
+            config = call.args[0].text if call.args else ""
+            problems = []
+            for m in re.finditer(r'\b\w+\s*,\s*_\s*:?=\s*(?:\w+\.)?((?:Trace|Span)IDFromHex)\s*\(', source.masked[fn.body_start:fn.body_end]):
+                problems.append(f"the {m.group(1)} error is ignored")
+            if "SpanContextConfig" in config:
+                if "TraceFlags" not in config:
+                    problems.append("TraceFlags is unset (children are never sampled)")
+                if not re.search(r'\bRemote\s*:\s*true\b', config) and "ContextWithRemoteSpanContext" not in body:
+                    problems.append("it isn't marked Remote")
+            if not re.search(r'\.IsValid\s*\(\s*\)', body):
+                problems.append("its validity is never checked")
+            source_text = f"from {origin}" if origin != "hand-parsed IDs" else "from hand-parsed IDs"
+            yield Diagnostic(
+                pos=call.start,
+                message=f"SpanContext is assembled {source_text}"
+                        + (f"; {', '.join(problems)}" if problems else ""),
+                suggestion="Use a propagator (otel.GetTextMapPropagator().Extract with a carrier), or implement "
+                           "propagation.TextMapPropagator if the format is custom",
+                confidence=0.8 if origin != "hand-parsed IDs" else 0.6,
+            )
+
+def _ended_before(source: GoFile, span_var: str, pos: int, fn) -> Optional[int]:
+    """Offset of a non-deferred span.End() in fn that runs before pos"""
+
+    for call in source.calls(re.escape(span_var) + r'\.End', fn.body_start, pos):
+        if not re.search(r'\bdefer\s+$', source.statement_prefix(call.start)):
+            return call.start
+    return None
+
+@rule(
+    rule_id="context-with-span-misuse",
+    title="Don't re-attach ended spans or smuggle spans into goroutines",
+    category="propagation",
+    signal="traces",
+    severity="medium",
+    description="trace.ContextWithSpan puts any span into a context, including one that has already ended "
+                "or one owned by a function that ends it while a goroutine is still running. Children then "
+                "attach to a finished parent, and the goroutine's data lands on a span that may already be "
+                "exported.",
+    bad_example='''
+func enqueueReport(ctx context.Context) {
+	_, span := tracer.Start(ctx, "enqueue report")
+	defer span.End()
+	go func() {
+		bg := trace.ContextWithSpan(context.Background(), span)
+		buildReport(bg)
+	}()
+}''',
+    good_example='''
+func enqueueReportLinked(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "enqueue report")
+	defer span.End()
+	go func(ctx context.Context) {
+		ctx, child := tracer.Start(ctx, "build report")
+		defer child.End()
+		buildReport(ctx)
+	}(context.WithoutCancel(ctx))
+}''',
+)
+def check_context_with_span(source: GoFile) -> Iterator[Diagnostic]:
+    for alias in source.import_alias(TRACE_PKG):
+        for call in source.calls(re.escape(alias) + r'\.ContextWithSpan'):
+            if len(call.args) != 2:
+                continue
+            span_var = call.args[1].text.strip()
+            if not re.fullmatch(r'\w+', span_var):
+                continue
+            fn = source.func_at(call.start, include_literals=True)
+            if fn is None:
+                continue
+            ended = _ended_before(source, span_var, call.start, fn)
+            if ended is not None:
+                yield Diagnostic(
+                    pos=call.start,
+                    message=f"{call.name} re-attaches {span_var} after {span_var}.End() on line {source.line_of(ended)}",
+                    suggestion="Start a new span (linked to the ended one if needed) instead of reviving a finished one",
+                    confidence=0.85,
+                )
+                continue
+            # Captured from, or passed in by, the function that started the goroutine
+            declared_here = re.search(r'\b' + re.escape(span_var) + r'\b[^\n]*:=', source.masked[fn.body_start:call.start])
+            if fn.is_literal and re.search(r'\bgo\s+$', source.masked[:fn.start]) and not declared_here:
+                yield Diagnostic(
+                    pos=call.start,
+                    message=f"Goroutine re-attaches {span_var} from the function that started it with {call.name}; "
+                            f"that function may end it while the goroutine runs",
+                    suggestion="Pass ctx into the goroutine (context.WithoutCancel(ctx) to outlive the request) "
+                               "and start a child span there",
+                    confidence=0.75,
+                )
+
+SPAN_VALUE = r'(?:\w+\.)?SpanFromContext\s*\([^()]*\)'
+SPAN_CONTEXT_VALUE = r'.*\.SpanContext\s*\(\s*\)|(?:\w+\.)?(?:SpanContextFromContext|NewSpanContext)\s*\(.*'
+TRACE_ID_VALUE = r'.*\.(?:TraceID|SpanID)\s*\(\s*\)(?:\.String\s*\(\s*\))?'
+
+def _telemetry_value(source: GoFile, expr: str, pos: int) -> Optional[str]:
+    """What kind of telemetry state expr holds at pos: "span", "span context", "trace ID" or None"""
+
+    expr = expr.strip().lstrip("&")
+    fn = source.func_at(pos, include_literals=True)
+    name = expr if re.fullmatch(r'\w+', expr) else None
+    if name and fn is not None:
+        # Follow the last value assigned to a local
+        assigned = re.findall(r'(?<![\w.])' + re.escape(name) + r'\s*:?=(?!=)\s*([^\n;]+)',
+                              source.masked[fn.body_start:pos])
+        if assigned:
+            expr = assigned[-1].strip()
+    for pattern, kind in ((SPAN_VALUE, "span"), (SPAN_CONTEXT_VALUE, "span context"), (TRACE_ID_VALUE, "trace ID")):
+        if re.fullmatch(pattern, expr, re.S):
+            return kind
+    return "span" if name and name in source.span_vars() else None
+
+@rule(
+    rule_id="span-in-context-value",
+    title="Put spans in contexts with trace.ContextWithSpan, not context.WithValue",
+    category="propagation",
+    signal="traces",
+    severity="high",
+    description="A span, SpanContext or trace ID stored under a custom context key is invisible to "
+                "trace.SpanFromContext: tracer.Start creates new roots instead of children, propagators "
+                "inject nothing and instrumentation libraries lose the trace. The trace API already keeps "
+                "the current span in the context, and the trace ID is read from it with "
+                "trace.SpanContextFromContext(ctx).TraceID().",
+    bad_example='''
+type spanKey struct{}
+
+func withRequestSpan(ctx context.Context) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, "handle request")
+	return context.WithValue(ctx, spanKey{}, span), span
+}''',
+    good_example='''
+func withRequestSpanPropagated(ctx context.Context) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "handle request")
+}
+
+func requestSpan(ctx context.Context) trace.Span {
+	return trace.SpanFromContext(ctx)
+}''',
+)
+def check_span_in_context_value(source: GoFile) -> Iterator[Diagnostic]:
+    trace_alias = (source.import_alias(TRACE_PKG) or ["trace"])[0]
+    for call in source.calls(r'context\.WithValue'):
+        if len(call.args) != 3:
+            continue
+        value = call.args[2]
+        kind = _telemetry_value(source, value.text, call.start)
+        if kind is None:
+            continue
+        text = value.text.strip()
+        if kind == "span":
+            suggestion = (f"Use {trace_alias}.ContextWithSpan(ctx, {text}) and read it back with "
+                          f"{trace_alias}.SpanFromContext(ctx)")
+        elif kind == "span context":
+            suggestion = (f"Use {trace_alias}.ContextWithSpanContext(ctx, {text}) "
+                          f"({trace_alias}.ContextWithRemoteSpanContext for one received from another process)")
+        else:
+            suggestion = (f"Drop the key: the ID is already in the context, read it with "
+                          f"{trace_alias}.SpanContextFromContext(ctx).TraceID()")
+        yield Diagnostic(
+            pos=call.start,
+            end=call.end,
+            message=f"context.WithValue stores a {kind} ({text}) under custom key {call.args[1].text.strip()}, "
+                    f"where {trace_alias}.SpanFromContext and propagators can't see it",
+            suggestion=suggestion,
+            confidence=0.85 if kind != "trace ID" else 0.7,
+        )
+    # The reading side of the same pattern
+    for m in re.finditer(r'\.Value\s*\([^()]*(?:\([^()]*\))?[^()]*\)\s*\.\(\s*\*?(?:\w+\.)?(Span|SpanContext)\s*\)', source.masked):
+        yield Diagnostic(
+            pos=m.start() + 1,
+            end=m.end(),
+            message=f"A {m.group(1)} is read back from a custom context key",
+            suggestion=f"Use {trace_alias}.SpanFromContext(ctx)" if m.group(1) == "Span"
+                       else f"Use {trace_alias}.SpanContextFromContext(ctx)",
+            confidence=0.75,
+        )
+
+# Span names that say where code runs rather than what it does
+GENERIC_SPAN_NAME = re.compile(
+    r'(?i)(?:anon(?:ymous)?|func\d*|closure|lambda|inner|go ?routine|callback|cb|async|background|worker|'
+    r'task|job|work|handler|fn|do|run|exec(?:ute)?|process|operation|op|span|child|sub ?span|tmp|todo)[ ._-]?\d*')
+
+def _closure_parent(source: GoFile, fn, ctx: str) -> Optional[SpanStart]:
+    """A span the enclosing function started from ctx, into a new context, and still has open
+    where the function literal fn is declared"""
+
+    found = None
+    for start in source.span_starts:
+        if (start.func is None or start.func is fn or not start.func.contains(fn.start)
+                or start.call.start > fn.start or not start.call.args):
+            continue
+        if start.call.args[0].text.strip() != ctx or start.ctx_var in ("", "_", ctx):
+            continue
+        if start.span_var and start.span_var != "_" and span_extent(source, start)[1] < fn.start:
+            continue
+        found = start
+    return found
+
+@rule(
+    rule_id="closure-span-attribution",
+    title="Give spans started in closures their own name and the current context",
+    category="propagation",
+    signal="traces",
+    severity="medium",
+    autofix=True,
+    options={"terms": []},
+    description="A span started in a goroutine or callback is only useful if it says what that code does "
+                "(\"worker\" or \"func1\" could be anything) and hangs off the right parent. Closures "
+                "capture variables, not values at a point in time: one that uses the outer ctx after the "
+                "enclosing function derived spanCtx from it, or that ignores its own ctx parameter, starts "
+                "a sibling of the enclosing span instead of its child. Names that are one of the project's "
+                "domain terms (terms, usually set under naming in the project config) aren't generic there, "
+                "so a scheduler can call a span \"job\".",
+    bad_example='''
+func importAll(ctx context.Context, files []string) {
+	batchCtx, span := tracer.Start(ctx, "import files")
+	defer span.End()
+	importFiles(batchCtx, files)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, child := tracer.Start(ctx, "worker")
+		defer child.End()
+		child.SetAttributes(attribute.Int("file.count", len(files)))
+	}()
+	<-done
+}''',
+    good_example='''
+func importAllTraced(ctx context.Context, files []string) {
+	batchCtx, span := tracer.Start(ctx, "import files")
+	defer span.End()
+	importFiles(batchCtx, files)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, child := tracer.Start(batchCtx, "rebuild index")
+		defer child.End()
+		child.SetAttributes(attribute.Int("file.count", len(files)))
+	}()
+	<-done
+}''',
+)
+def check_closure_span_attribution(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    terms = {span_name(t) for t in options["terms"]}
+    for start in source.span_starts:
+        fn = start.func
+        if fn is None or not fn.is_literal or not start.call.args:
+            continue
+        where = "a goroutine" if re.search(r'\bgo\s+$', source.masked[:fn.start]) else "a function literal"
+        if (start.name is not None and GENERIC_SPAN_NAME.fullmatch(start.name.strip())
+                and span_name(start.name) not in terms):
+            yield Diagnostic(
+                pos=start.name_arg.start,
+                end=start.name_arg.end,
+                message=f'Span "{start.name}" started in {where} in {source.function_name_at(fn.start)} has a '
+                        f'generic name',
+                suggestion="Name the span after the operation the closure performs",
+                confidence=0.7,
+            )
+        ctx_arg = start.call.args[0]
+        ctx = ctx_arg.text.strip()
+        if not re.fullmatch(r'\w+', ctx) or re.search(r'(?<![\w.])' + ctx + r'\b[\w\s,]*:=',
+                                                       source.masked[fn.body_start:start.call.start]):
+            continue
+        own = [name for name, typ in parse_params(fn.params) if name and typ.endswith("context.Context")]
+        if own and ctx not in own:
+            replacement, message = own[0], (f"Span started in {where} uses the captured {ctx} instead of "
+                                            f"the closure's own context parameter {own[0]}")
+        else:
+            parent = _closure_parent(source, fn, ctx)
+            if parent is None:
+                continue
+            name = parent.name_arg.text.strip() if parent.name_arg else parent.span_var
+            replacement, message = parent.ctx_var, (f"Span started in {where} uses {ctx}, the context from "
+                                                    f"before span {name} was started, so it becomes that "
+                                                    f"span's sibling instead of its child")
+        yield Diagnostic(
+            pos=ctx_arg.start,
+            end=ctx_arg.end,
+            message=message,
+            suggestion=f"Start it from {replacement}",
+            confidence=0.75,
+            fix=Fix(description=f"Start the span from {replacement}",
+                    edits=[TextEdit(ctx_arg.start, ctx_arg.end, replacement)]),
+        )
+
+def _block_at(masked: str, lo: int, pos: int) -> int:
+    """Offset of the innermost { open at pos, counting from lo (a function body's brace)"""
+
+    stack = [lo]
+    for i in range(lo + 1, pos):
+        if masked[i] == "{":
+            stack.append(i)
+        elif masked[i] == "}" and len(stack) > 1:
+            stack.pop()
+    return stack[-1]
+
+def _parent_uses(source: GoFile, start: SpanStart, parent: str, lo: int, hi: int) -> List[Tuple[int, int, str]]:
+    """(start, end, callee) of calls between lo and hi in the span's own function that take parent as
+    their context, up to the first reassignment of parent"""
+
+    masked = source.masked
+    name = re.escape(parent)
+    reassigned = re.search(r'(?<![\w.])' + name + r'\s*(?:,\s*\w+\s*)*=(?!=)', masked[lo:hi])
+    if reassigned:
+        hi = lo + reassigned.start()
+    uses = []
+    for m in re.finditer(r'([\w.]+)\s*\(\s*(' + name + r')\s*[,)]', masked[lo:hi]):
+        if source.func_at(lo + m.start(), include_literals=True) is start.func:
+            uses.append((lo + m.start(2), lo + m.end(2), m.group(1)))
+    return uses
+
+@rule(
+    rule_id="span-context-discarded",
+    title="Pass the context tracer.Start returns to the work the span covers",
+    category="propagation",
+    signal="traces",
+    severity="high",
+    autofix=True,
+    description="tracer.Start returns a new context carrying the span, and only calls given that context "
+                "become its children. Discarding it with _, or passing the old ctx on after deriving spanCtx, "
+                "makes database calls, outgoing requests and child spans siblings of the span they belong "
+                "to; so does a ctx, span := in an inner block whose span outlives the block.",
+    bad_example='''
+func saveOrder(ctx context.Context, db *sql.DB, id string) error {
+	_, span := tracer.Start(ctx, "save order")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.id", id))
+	_, err := db.ExecContext(ctx, "INSERT INTO orders (id) VALUES (?)", id)
+	return err
+}''',
+    good_example='''
+func saveOrderTraced(ctx context.Context, db *sql.DB, id string) error {
+	ctx, span := tracer.Start(ctx, "save order")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.id", id))
+	_, err := db.ExecContext(ctx, "INSERT INTO orders (id) VALUES (?)", id)
+	return err
+}''',
+)
+def check_span_context_discarded(source: GoFile) -> Iterator[Diagnostic]:
+    masked = source.masked
+    for start in source.span_starts:
+        fn = start.func
+        if fn is None or not start.call.args or not start.ctx_var:
+            continue
+        parent = start.call.args[0].text.strip()
+        if not re.fullmatch(r'\w+', parent):
+            continue
+        end = span_extent(source, start)[1] if start.span_var and start.span_var != "_" else fn.body_end
+        block = _block_at(masked, fn.body_start, start.call.start)
+        block_end = match_bracket(masked, block)
+        name = start.name_arg.text.strip() if start.name_arg else start.span_var
+        fix = None
+        if start.ctx_var == parent:
+            # ctx, span := in an inner block: the outer ctx is back once the block closes
+            if start.assign_op != ":=" or block == fn.body_start or block_end >= end:
+                continue
+            uses = _parent_uses(source, start, parent, block_end, end)
+            if not uses:
+                continue
+            message = (f"{parent}, span := in an inner block shadows {parent}, so {uses[0][2]}({parent}) on line "
+                       f"{source.line_of(uses[0][0])}, after the block, runs outside span {name}, which is still open")
+            suggestion = "Declare the span before the block and assign with =, or end it inside the block"
+            confidence = 0.7
+        else:
+            uses = _parent_uses(source, start, parent, start.call.end, end)
+            if not uses:
+                continue
+            in_block = all(pos < block_end for pos, _, _ in uses)
+            if start.ctx_var == "_":
+                message = (f"The context tracer.Start returns is discarded, so {uses[0][2]}({parent}) on line "
+                           f"{source.line_of(uses[0][0])} runs outside span {name}")
+                prefix = source.statement_prefix(start.call.start)
+                blank = re.match(r'(\s*)_\s*,', prefix)
+                if in_block and blank:
+                    pos = start.call.start - len(prefix) + blank.end(1)
+                    fix = Fix(description=f"Keep the context as {parent}", edits=[TextEdit(pos, pos + 1, parent)])
+                suggestion = f"Assign the returned context ({parent}, {start.span_var} := ...) and pass it on"
+                confidence = 0.8
+            else:
+                message = (f"{uses[0][2]}({parent}) on line {source.line_of(uses[0][0])} gets {parent} instead of "
+                           f"{start.ctx_var}, the context carrying span {name}, so it runs outside the span")
+                if in_block:
+                    fix = Fix(description=f"Pass {start.ctx_var} instead of {parent}",
+                              edits=[TextEdit(pos, e, start.ctx_var) for pos, e, _ in uses])
+                suggestion = f"Pass {start.ctx_var} to the calls the span covers"
+                confidence = 0.75
+        yield Diagnostic(
+            pos=start.call.start,
+            end=start.call.end,
+            message=message,
+            suggestion=suggestion,
+            confidence=confidence,
+            fix=fix,
+        )
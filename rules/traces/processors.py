@@ -0,0 +1,321 @@
+"""
+Safety checks for user-defined sdktrace.SpanProcessor implementations
+"""
+
+import re
+from typing import Iterator, List, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, parse_params
+from ..registry import rule
+
+MUTATING_SPAN_METHODS = r'SetAttributes|SetName|SetStatus|AddEvent|AddLink|RecordError|End'
+
+HTTP_PKG = "net/http"
+
+# Keywords that can stand before a receive (`if <-ready {`, `return <-ch`), which isn't a send
+GO_KEYWORDS = {"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+               "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select",
+               "struct", "switch", "type", "var"}
+
+BLOCKING_CALLS = [
+    (r'time\.Sleep', "time.Sleep"),
+    (r'net\.Dial\w*', "a network dial"),
+    (r'[\w.]+\.(?:Query|QueryRow|Exec)(?:Context)?', "a database round trip"),
+    (r'os\.(?:Open|Create|ReadFile|WriteFile)', "file I/O"),
+    (r'[\w.]+\.Wait', "a blocking Wait()"),
+]
+
+def _http_calls(source: GoFile, fn: GoFunc) -> List[Tuple[str, str]]:
+    """Patterns of the net/http calls that block in fn: the package's request functions, and Do
+    on an *http.Client (http.DefaultClient, a receiver field, or a variable assigned a client)"""
+
+    found = []
+    for alias in (name for name, path in source.imports.items() if path == HTTP_PKG):
+        a = re.escape(alias)
+        client_type = r'\*?' + a + r'\.Client\b'
+        clients = [a + r'\.DefaultClient']
+        if fn.receiver:
+            for field, typ in source.struct_fields(fn.receiver_type.lstrip("*")).items():
+                if re.match(client_type, typ):
+                    clients.append(re.escape(fn.receiver) + r'\.' + re.escape(field))
+        for m in re.finditer(r'\b(\w+)\s*:?=\s*&?' + a + r'\.(?:Client\s*\{|DefaultClient\b)'
+                             r'|\bvar\s+(\w+)\s+' + client_type, source.masked):
+            clients.append(re.escape(m.group(1) or m.group(2)))
+        found.append((a + r'\.(?:Get|Post|PostForm|Head)', "a synchronous HTTP request"))
+        found.append((r'(?:' + "|".join(dict.fromkeys(clients)) + r')\.Do', "a synchronous HTTP request"))
+    return found
+
+def span_processors(source: GoFile) -> Iterator[Tuple[str, dict]]:
+    """Types in this file that implement OnStart and OnEnd"""
+
+    seen = set()
+    for fn in source.functions:
+        type_name = fn.receiver_type.lstrip("*")
+        if not type_name or type_name in seen:
+            continue
+        methods = source.methods_of(type_name)
+        if "OnStart" in methods and "OnEnd" in methods:
+            seen.add(type_name)
+            yield type_name, methods
+
+def _span_param(fn: GoFunc) -> str:
+    for name, typ in parse_params(fn.params):
+        if "Span" in typ:
+            return name
+    return ""
+
+@rule(
+    rule_id="span-processor-onend-mutation",
+    title="SpanProcessor must not mutate spans in OnEnd",
+    category="sdk",
+    signal="traces",
+    severity="high",
+    description="OnEnd receives a ReadOnlySpan after the span has ended; type-asserting it back to a "
+                "ReadWriteSpan and mutating it races with exporters and is silently ignored by the SDK.",
+    bad_example='''
+type tagger struct{}
+
+func (tagger) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+func (tagger) OnEnd(s sdktrace.ReadOnlySpan) {
+	rw := s.(sdktrace.ReadWriteSpan)
+	rw.SetAttributes(attribute.Bool("tagged", true))
+}
+''',
+    good_example='''
+type enricher struct{}
+
+func (enricher) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	s.SetAttributes(attribute.Bool("enriched", true))
+}
+
+func (enricher) OnEnd(s sdktrace.ReadOnlySpan) {}
+''',
+)
+def check_onend_mutation(source: GoFile) -> Iterator[Diagnostic]:
+    for type_name, methods in span_processors(source):
+        on_end = methods["OnEnd"]
+        body_start, body_end = on_end.body_start, on_end.body_end
+        body = source.masked[body_start:body_end]
+
+        targets = {_span_param(on_end)} - {"", "_"}
+        for m in re.finditer(r'(\w+)\s*(?:,\s*\w+\s*)?:?=\s*\w+\.\(\s*(?:\w+\.)?ReadWriteSpan\s*\)', body):
+            targets.add(m.group(1))
+            yield Diagnostic(
+                pos=body_start + m.start(),
+                message=f"{type_name}.OnEnd type-asserts the ended span to ReadWriteSpan",
+                suggestion="Treat the span as read-only in OnEnd; enrich spans in OnStart instead",
+                confidence=0.9,
+            )
+
+        for target in targets:
+            for m in re.finditer(r'\b' + re.escape(target) + r'\.(' + MUTATING_SPAN_METHODS + r')\s*\(', body):
+                yield Diagnostic(
+                    pos=body_start + m.start(),
+                    message=f"{type_name}.OnEnd calls {m.group(1)}() on an ended span",
+                    suggestion="Move enrichment to OnStart, or use a separate processor that wraps the exporter",
+                    confidence=0.9,
+                )
+
+@rule(
+    rule_id="span-processor-blocking-onstart",
+    title="SpanProcessor OnStart must not block",
+    category="sdk",
+    signal="traces",
+    severity="high",
+    description="OnStart runs synchronously on the caller's goroutine for every span started; "
+                "blocking work there adds latency to every instrumented operation.",
+    bad_example='''
+type notifier struct{}
+
+func (notifier) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	http.Get("http://audit.internal/notify")
+}
+
+func (notifier) OnEnd(s sdktrace.ReadOnlySpan) {}
+''',
+    good_example='''
+type queueNotifier struct{ names chan string }
+
+func (n queueNotifier) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	select {
+	case n.names <- s.Name():
+	default:
+	}
+}
+
+func (n queueNotifier) OnEnd(s sdktrace.ReadOnlySpan) {}
+''',
+)
+def check_blocking_onstart(source: GoFile) -> Iterator[Diagnostic]:
+    for type_name, methods in span_processors(source):
+        on_start = methods["OnStart"]
+        for pattern, what in BLOCKING_CALLS + _http_calls(source, on_start):
+            for call in source.calls(pattern, on_start.body_start, on_start.body_end):
+                # Work handed to another goroutine doesn't block the caller
+                if re.search(r'\bgo\s+(?:func\b.*)?$', source.statement_prefix(call.start)):
+                    continue
+                yield Diagnostic(
+                    pos=call.start,
+                    message=f"{type_name}.OnStart performs {what} ({call.name})",
+                    suggestion="Keep OnStart to cheap in-memory work; hand slow work to a buffered channel or the exporter",
+                    confidence=0.85,
+                )
+
+        # Unbuffered sends outside a select can block indefinitely. A send is a statement of its
+        # own, starting with the channel; a select case or a goroutine doesn't block the caller
+        body = source.masked[on_start.body_start:on_start.body_end]
+        for m in re.finditer(r'(?<![\w.\]])([A-Za-z_][\w.]*(?:\[[^\]\n]*\])*)\s*<-\s*\S', body):
+            pos = on_start.body_start + m.start()
+            prefix = source.statement_prefix(pos)
+            if (m.group(1) in GO_KEYWORDS or not re.fullmatch(r'(?:.*[;{])?\s*', prefix)
+                    or re.search(r'\bgo\s+func\b', prefix)):
+                continue
+            yield Diagnostic(
+                pos=pos,
+                message=f"{type_name}.OnStart sends on a channel outside a select",
+                suggestion="Use select with a default branch so a full channel drops data instead of blocking span creation",
+                confidence=0.8,
+            )
+
+@rule(
+    rule_id="span-processor-ignores-context",
+    title="SpanProcessor Shutdown/ForceFlush must honor their context",
+    category="sdk",
+    signal="traces",
+    severity="medium",
+    description="Shutdown and ForceFlush receive a context carrying the caller's deadline; "
+                "ignoring it can hang application shutdown indefinitely.",
+    bad_example='''
+type drainer struct{ pending chan sdktrace.ReadOnlySpan }
+
+func (d *drainer) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+func (d *drainer) OnEnd(s sdktrace.ReadOnlySpan)                            {}
+
+func (d *drainer) Shutdown(ctx context.Context) error {
+	for range d.pending {
+	}
+	return nil
+}
+''',
+    good_example='''
+type boundedDrainer struct{ pending chan sdktrace.ReadOnlySpan }
+
+func (d *boundedDrainer) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+func (d *boundedDrainer) OnEnd(s sdktrace.ReadOnlySpan)                            {}
+
+func (d *boundedDrainer) Shutdown(ctx context.Context) error {
+	for {
+		select {
+		case <-d.pending:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+''',
+)
+def check_ignores_context(source: GoFile) -> Iterator[Diagnostic]:
+    for type_name, methods in span_processors(source):
+        for method_name in ("Shutdown", "ForceFlush"):
+            fn = methods.get(method_name)
+            if fn is None:
+                continue
+            params = parse_params(fn.params)
+            ctx_name = next((n for n, t in params if t.endswith("Context")), "")
+            body = source.masked[fn.body_start + 1:fn.body_end]
+            # Nothing to wait on, so nothing to time out
+            if re.fullmatch(r'\s*(?:return\s+nil\s*)?', body):
+                continue
+            if ctx_name in ("", "_") or not re.search(r'\b' + re.escape(ctx_name) + r'\b', body):
+                yield Diagnostic(
+                    pos=fn.start,
+                    message=f"{type_name}.{method_name} ignores its context argument",
+                    suggestion="Select on ctx.Done() while draining/flushing and return ctx.Err() when the deadline passes",
+                    confidence=0.85,
+                )
+
+@rule(
+    rule_id="span-processor-not-concurrency-safe",
+    title="SpanProcessor state must be concurrency-safe",
+    category="sdk",
+    signal="traces",
+    severity="high",
+    description="OnStart and OnEnd are called concurrently from every goroutine that creates spans; "
+                "unsynchronized writes to processor fields are data races. A write is synchronized "
+                "between Lock() and Unlock() of a mutex of the processor, or after Lock() when the "
+                "Unlock() is deferred; a read lock doesn't count.",
+    bad_example='''
+type counter struct{ seen map[string]int }
+
+func (c *counter) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	c.seen[s.Name()]++
+}
+
+func (c *counter) OnEnd(s sdktrace.ReadOnlySpan) {}
+''',
+    good_example='''
+type lockedCounter struct {
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+func (c *lockedCounter) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[s.Name()]++
+}
+
+func (c *lockedCounter) OnEnd(s sdktrace.ReadOnlySpan) {}
+''',
+)
+def check_concurrency(source: GoFile) -> Iterator[Diagnostic]:
+    for type_name, methods in span_processors(source):
+        fields = source.struct_fields(type_name)
+        for method_name in ("OnStart", "OnEnd"):
+            fn = methods[method_name]
+            recv = fn.receiver
+            if not recv:
+                continue
+            body = source.masked[fn.body_start:fn.body_end]
+            locked = _locked(recv, body)
+            reported = set()
+            for offset, field_name in _field_writes(recv, body):
+                field_type = fields.get(field_name, "")
+                if field_name in reported or re.search(r'atomic\.|sync\.', field_type):
+                    continue
+                if any(start <= offset < end for start, end in locked):
+                    continue
+                reported.add(field_name)
+                yield Diagnostic(
+                    pos=fn.body_start + offset,
+                    message=f"{type_name}.{method_name} writes field '{field_name}' without synchronization",
+                    suggestion="Guard the field with a sync.Mutex, use sync/atomic types, or a sync.Map",
+                    confidence=0.85,
+                )
+
+def _locked(recv: str, body: str) -> List[Tuple[int, int]]:
+    """Ranges of body that hold a mutex of the receiver: from Lock() to the Unlock() that follows,
+    or to the end of the body when the Unlock is deferred. A read lock doesn't make writes safe."""
+
+    ranges = []
+    for m in re.finditer(r'\b' + re.escape(recv) + r'((?:\.\w+)*)\.Lock\s*\(\s*\)', body):
+        unlock = re.escape(recv + m.group(1)) + r'\.Unlock\s*\(\s*\)'
+        if re.search(r'\bdefer\s+' + unlock, body[m.end():]):
+            ranges.append((m.end(), len(body)))
+            continue
+        end = re.search(r'\b' + unlock, body[m.end():])
+        ranges.append((m.end(), m.end() + end.start() if end else len(body)))
+    return ranges
+
+def _field_writes(recv: str, body: str) -> List[Tuple[int, str]]:
+    """(offset, field) of every write to a field of the receiver, in order"""
+
+    r = re.escape(recv)
+    patterns = [
+        r'\b' + r + r'\.(\w+)(?:\[[^\]]*\])?\s*(?:[+\-*/]?=(?!=)|\+\+|--)',
+        r'\bdelete\s*\(\s*' + r + r'\.(\w+)',
+    ]
+    writes = [(m.start(), m.group(1)) for pattern in patterns for m in re.finditer(pattern, body)]
+    return sorted(writes)
@@ -0,0 +1,124 @@
+"""
+Propagator setup: which formats a service reads and writes trace context and baggage in
+"""
+
+import re
+from pathlib import Path
+from typing import Iterator, List, Tuple
+
+from ..base import Diagnostic, Fix, TextEdit
+from ..golang import Arg, Call, GoFile
+from ..registry import rule
+
+BAGGAGE_PKG = "go.opentelemetry.io/otel/baggage"
+
+# Propagator expression -> format it speaks
+FORMATS = [
+    (r'(?:\w+\.)?TraceContext\s*\{\s*\}', "tracecontext"),
+    (r'(?:\w+\.)?Baggage\s*\{\s*\}', "baggage"),
+    (r'b3\.New\s*\(.*B3MultipleHeader.*\)', "b3multi"),
+    (r'b3\.New\s*\(.*\)', "b3"),
+    (r'(?:\w+\.)?Jaeger\s*\{\s*\}', "jaeger"),
+    (r'(?:\w+\.)?OT\s*\{\s*\}', "ottrace"),
+    (r'xray\.Propagator\s*\{\s*\}', "xray"),
+    # OTEL_PROPAGATORS, tracecontext and baggage by default
+    (r'autoprop\.NewTextMapPropagator\s*\(.*\)', "autoprop"),
+]
+
+def propagator_format(expr: str) -> str:
+    for pattern, name in FORMATS:
+        if re.fullmatch(pattern, expr.strip(), re.S):
+            return name
+    return ""
+
+def propagator_setups(source: GoFile) -> Iterator[Tuple[Call, List[Tuple[str, Arg]]]]:
+    """Composite propagators, and single ones passed straight to SetTextMapPropagator, with the
+    format of each member ("" when it isn't a known propagator)"""
+
+    for call in source.calls(r'(?:\w+\.)?NewCompositeTextMapPropagator'):
+        yield call, [(propagator_format(arg.text), arg) for arg in call.args]
+    for call in source.calls(r'(?:\w+\.)?SetTextMapPropagator'):
+        if len(call.args) == 1 and propagator_format(call.args[0].text):
+            yield call, [(propagator_format(call.args[0].text), call.args[0])]
+
+def baggage_use(source: GoFile) -> int:
+    """Offset of the first baggage API call, -1 if there is none"""
+
+    for alias in source.import_alias(BAGGAGE_PKG):
+        m = re.search(r'(?<![\w.])' + re.escape(alias) + r'\.(?:New|NewMember|Parse|ContextWithBaggage|FromContext)\s*\(',
+                      source.masked)
+        if m:
+            return m.start()
+    return -1
+
+@rule(
+    rule_id="propagator-composition",
+    title="Compose propagators without duplicates, and with baggage when baggage is used",
+    category="propagation",
+    signal="traces",
+    signals=("baggage",),
+    severity="medium",
+    scope="project",
+    autofix=True,
+    description="The global propagator decides what crosses process boundaries. Baggage set with "
+                "baggage.ContextWithBaggage is silently dropped at the next hop unless propagation.Baggage{} "
+                "is part of it, and listing a propagator twice makes it inject its headers twice and "
+                "extract twice, the second overwriting the first.",
+    bad_example='''
+func initPropagation() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.TraceContext{}))
+}
+
+func withTenant(ctx context.Context, tenant string) context.Context {
+	member, _ := baggage.NewMember("tenant.id", tenant)
+	bag, _ := baggage.New(member)
+	return baggage.ContextWithBaggage(ctx, bag)
+}''',
+    good_example='''
+func initPropagationWithBaggage() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}''',
+)
+def check_propagator_composition(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    baggage_user = next((f"{Path(s.path).name}:{s.line_of(pos)}" for s in sources
+                         for pos in [baggage_use(s)] if pos >= 0), None)
+    for source in sources:
+        for call, members in propagator_setups(source):
+            seen = {}
+            for i, (name, arg) in enumerate(members):
+                if not name or name not in seen:
+                    seen.setdefault(name, arg)
+                    continue
+                previous = members[i - 1][1]
+                first_line = source.line_of(seen[name].start)
+                where = "" if first_line == source.line_of(arg.start) else f" (also on line {first_line})"
+                yield Diagnostic(
+                    pos=arg.start,
+                    end=arg.end,
+                    message=f"{arg.text.strip()} is composed twice{where}, "
+                            f"so its headers are injected and extracted twice",
+                    suggestion="Remove the duplicate",
+                    confidence=0.9,
+                    fix=Fix(description=f"Remove the duplicate {arg.text.strip()}",
+                            edits=[TextEdit(previous.end, arg.end, "")]),
+                    file=source,
+                )
+            names = {name for name, _ in members}
+            if baggage_user is None or names & {"baggage", "autoprop"} or "" in names:
+                continue
+            alias = (source.import_alias("go.opentelemetry.io/otel/propagation") or ["propagation"])[0]
+            composite = call.name.endswith("NewCompositeTextMapPropagator")
+            yield Diagnostic(
+                pos=call.start,
+                end=call.end,
+                message=f"Baggage is used ({baggage_user}) but the propagator has no Baggage "
+                        f"member, so it never reaches downstream services",
+                suggestion=f"Add {alias}.Baggage{{}} to the composite propagator",
+                confidence=0.8,
+                fix=Fix(description=f"Add {alias}.Baggage{{}}",
+                        edits=[TextEdit(members[-1][1].end, members[-1][1].end, f", {alias}.Baggage{{}}")])
+                if composite and members else None,
+                file=source,
+            )
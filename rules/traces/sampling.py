@@ -0,0 +1,288 @@
+"""
+Sampler configuration, and application behavior that changes with the sampling decision
+"""
+
+import fnmatch
+import re
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile, match_bracket
+from ..registry import rule
+from .attributes import ATTRIBUTE_PKG, attribute_calls
+
+SAMPLING_CHECK = r'\.(?:IsRecording|IsSampled)\s*\(\s*\)'
+
+# Statements that only feed telemetry
+TELEMETRY_STATEMENT = re.compile(
+    r'^\s*(?:\w*[sS]pan\w*|trace\.SpanFromContext\([^)]*\))\.\w+\s*\('
+    r'|\b(?:attribute|semconv)\.|\b(?:SetAttributes|AddEvent|RecordError|SetStatus|SetName|AddLink)\s*\('
+    r'|^\s*(?:log|slog|logger|\w*[lL]og(?:ger)?)\.\w+\s*\('
+)
+# Structure that neither does nor decides anything by itself (returns of plain values included)
+NEUTRAL_STATEMENT = re.compile(r'^\s*(?:\}|\{|\}\s*else\b.*\{|else\s*\{|for\b[^()]*\{|if\b[^()]*\{|return(?:\s+[\w.&]+(?:\s*,\s*[\w.&]+)*)?\s*|defer\s+\w*[sS]pan\w*\.End\(\))?\s*$')
+
+def _statements(source: GoFile, start: int, end: int) -> List[Tuple[int, str]]:
+    """(offset, masked text) of the non-empty lines in start..end"""
+
+    found, pos = [], start
+    for line in source.masked[start:end].split("\n"):
+        if line.strip():
+            found.append((pos + len(line) - len(line.lstrip()), line))
+        pos += len(line) + 1
+    return found
+
+def business_statement(source: GoFile, start: int, end: int) -> Optional[int]:
+    """Offset of the first statement in start..end that does more than record telemetry, or None.
+    Locals that only feed telemetry statements count as telemetry too."""
+
+    statements = _statements(source, start, end)
+    telemetry = [s for s in statements if TELEMETRY_STATEMENT.search(s[1])]
+    for pos, text in statements:
+        if NEUTRAL_STATEMENT.match(text) or TELEMETRY_STATEMENT.search(text):
+            continue
+        assigned = re.match(r'\s*(\w+(?:\s*,\s*\w+)*)\s*:?=', text)
+        if assigned:
+            names = [n.strip() for n in assigned.group(1).split(",") if n.strip() != "_"]
+            used_elsewhere = [
+                s for s in statements
+                if s[0] != pos and any(re.search(r'(?<![\w.])' + n + r'\b', s[1]) for n in names)
+            ]
+            if used_elsewhere and all(s in telemetry for s in used_elsewhere):
+                continue
+        return pos
+    return None
+
+def _branches(source: GoFile, start: int, end: int) -> Iterator[Tuple[int, str, int, int]]:
+    """(if offset, condition, body start, end of the if/else chain) for ifs testing the sampling decision"""
+
+    for m in re.finditer(r'\bif\b([^{;]*(?:;[^{]*)?)\{', source.masked[start:end]):
+        condition = m.group(1)
+        if not re.search(SAMPLING_CHECK, condition):
+            continue
+        open_brace = start + m.end() - 1
+        close = match_bracket(source.masked, open_brace)
+        if close == -1:
+            continue
+        chain_end = close
+        tail = re.match(r'\s*else\s*(?:if\b[^{]*)?\{', source.masked[close + 1:])
+        if tail:
+            chain_end = match_bracket(source.masked, close + tail.end())
+        yield start + m.start(), condition.strip(), open_brace + 1, max(chain_end, close)
+
+@rule(
+    rule_id="sampling-dependent-logic",
+    title="Business logic must not depend on the sampling decision",
+    category="correctness",
+    signal="traces",
+    severity="high",
+    description="span.IsRecording() and SpanContext().IsSampled() exist to skip expensive telemetry work. "
+                "When they guard anything else (validation, writes, returned values), the program behaves "
+                "differently for sampled and unsampled requests, so changing the sampling rate changes "
+                "application behavior, and traces only ever show one of the two paths.",
+    bad_example='''
+func chargeOrder(ctx context.Context, order Order) error {
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		if err := validateOrder(order); err != nil {
+			return err
+		}
+	}
+	return payments.Charge(ctx, order)
+}''',
+    good_example='''
+func chargeOrderTraced(ctx context.Context, order Order) error {
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Int("order.items", len(order.Items)))
+	}
+	if err := validateOrder(order); err != nil {
+		return err
+	}
+	return payments.Charge(ctx, order)
+}''',
+)
+def check_sampling_dependent_logic(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    for fn in source.functions:
+        if fn.is_literal:
+            continue
+        for if_pos, condition, body_start, chain_end in _branches(source, fn.body_start, fn.body_end):
+            found = business_statement(source, body_start, chain_end)
+            what = "guards"
+            body = source.masked[body_start:chain_end]
+            if found is None and re.search(r'\breturn\b', body):
+                # An early return decides whether the rest of the function runs
+                found = business_statement(source, chain_end + 1, fn.body_end)
+                what = "returns early, skipping"
+            if found is None:
+                continue
+            statement = source.code[found:source.code.find("\n", found)].strip()
+            check = re.search(r'[\w.()]*' + SAMPLING_CHECK, condition).group(0)
+            yield Diagnostic(
+                pos=if_pos,
+                message=f"Branch on {check} {what} non-telemetry code ({statement})",
+                suggestion="Only guard telemetry work (attribute building, events) with the sampling decision; "
+                           "run application logic unconditionally",
+                confidence=0.75,
+            )
+
+ALWAYS_ON_ENV = r'Setenv\s*\(\s*"OTEL_TRACES_SAMPLER"\s*,\s*"always_on"'
+
+@rule(
+    rule_id="sampler-always-on",
+    title="Don't sample every trace with AlwaysSample",
+    category="sdk",
+    signal="traces",
+    severity="medium",
+    description="A bare AlwaysSample sampler records and exports every span and ignores the sampling "
+                "decision of upstream services, so traces sampled out upstream show up as fragments. It "
+                "suits development; in production use ParentBased with a TraceIDRatioBased root sampler, "
+                "or sample in the Collector. ParentBased(AlwaysSample()), the SDK default, is not reported.",
+    bad_example='''
+func newTracerProvider(exp sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithSampler(sdktrace.AlwaysSample()))
+}''',
+    good_example='''
+func newTracerProviderSampled(exp sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.1))))
+}''',
+)
+def check_sampler_always_on(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    for call in source.calls(r'[\w.]*\.WithSampler'):
+        if call.args and re.fullmatch(r'(?:\w+\.)?AlwaysSample\s*\(\s*\)', call.args[0].text.strip()):
+            yield Diagnostic(
+                pos=call.args[0].start,
+                end=call.args[0].end,
+                message="Every span is sampled with AlwaysSample, ignoring the parent's sampling decision",
+                suggestion="Use ParentBased(TraceIDRatioBased(ratio)) or tail sampling in the Collector",
+                confidence=0.85,
+            )
+    for m in re.finditer(ALWAYS_ON_ENV, source.code):
+        yield Diagnostic(
+            pos=m.start(),
+            message="OTEL_TRACES_SAMPLER is set to always_on, which ignores the parent's sampling decision",
+            suggestion="Use parentbased_traceidratio with OTEL_TRACES_SAMPLER_ARG, or sample in the Collector",
+            confidence=0.85,
+        )
+
+def _recorded_keys(source: GoFile, start: int, end: int) -> Set[str]:
+    """Literal attribute keys set by attribute.<Type>("k", v) and attribute.Key("k") in start..end"""
+
+    keys = {c.args[0].literal for c in attribute_calls(source) if start <= c.start < end and c.args}
+    for alias in source.import_alias(ATTRIBUTE_PKG):
+        keys.update(c.args[0].literal for c in source.calls(re.escape(alias) + r'\.Key', start, end) if len(c.args) == 1)
+    keys.discard(None)
+    return keys
+
+def _operations(options: Dict) -> List[Tuple[Optional[str], List[str]]]:
+    """(span name pattern or None, attribute keys the policy matches on) of each critical operation;
+    a bare string is a name the policy matches without attributes"""
+
+    found = []
+    for entry in options["operations"]:
+        if isinstance(entry, str):
+            found.append((entry, []))
+        elif isinstance(entry, dict):
+            found.append((entry.get("name"), list(entry.get("attributes") or [])))
+    return found
+
+@rule(
+    rule_id="critical-span-sampling",
+    title="Spans that must always be sampled must carry what the sampling policy matches on",
+    category="correctness",
+    signal="traces",
+    severity="high",
+    description="Operations listed under operations (by span name, with the attribute keys the policy "
+                "keys on) are meant to survive sampling. A head sampler decides when the span starts and "
+                "only sees the name and the attributes passed to Start with trace.WithAttributes: setting "
+                "the attribute later or renaming the span with SetName is too late, and the span is dropped "
+                "at the regular rate. A tail sampling policy sees the finished span, so the attributes only "
+                "need to be recorded at some point. By default, spans that record sampling.priority (which "
+                "samplers and the Collector's probabilistic_sampler honor) are checked.",
+    options={
+        # head: a Sampler in the SDK decides at span start; tail: the Collector decides on whole traces
+        "sampling": "head",
+        # Span name (* wildcards) and attribute keys of each operation the policy must keep;
+        # an entry without a name covers every span recording one of its attributes
+        "operations": [{"attributes": ["sampling.priority"]}],
+    },
+    bad_example='''
+func chargeCard(ctx context.Context, amount int64) error {
+	ctx, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+	span.SetAttributes(attribute.Int("sampling.priority", 1))
+	return gateway.Charge(ctx, amount)
+}''',
+    good_example='''
+func chargeCardSampled(ctx context.Context, amount int64) error {
+	ctx, span := tracer.Start(ctx, "charge card", trace.WithAttributes(attribute.Int("sampling.priority", 1)))
+	defer span.End()
+	return gateway.Charge(ctx, amount)
+}''',
+)
+def check_critical_span_sampling(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go"):
+        return
+    head = options["sampling"] != "tail"
+    operations = _operations(options)
+    for start in source.span_starts:
+        fn, var = start.func, start.span_var
+        if fn is None:
+            continue
+        at_start = _recorded_keys(source, start.call.open_paren, start.call.close_paren)
+        later: Dict[str, int] = {}
+        renames: List[Tuple[int, str]] = []
+        if var and var != "_":
+            for call in source.calls(re.escape(var) + r'\.(?:SetAttributes|SetName)', start.call.end, fn.body_end):
+                if call.name.endswith("SetName"):
+                    if call.args and call.args[0].literal is not None:
+                        renames.append((call.start, call.args[0].literal))
+                    continue
+                for key in _recorded_keys(source, call.open_paren, call.close_paren):
+                    later.setdefault(key, call.start)
+        for pattern, attributes in operations:
+            if pattern is None:
+                if not any(k in at_start or k in later for k in attributes):
+                    continue
+                label = f'Span "{start.name}"' if start.name is not None else "Span"
+            elif start.name is not None and fnmatch.fnmatchcase(start.name, pattern):
+                label = f'Span "{start.name}"'
+            else:
+                renamed = next(((pos, name) for pos, name in renames if fnmatch.fnmatchcase(name, pattern)), None)
+                if renamed is None:
+                    continue
+                label = f'Span "{renamed[1]}"'
+                if head:
+                    yield Diagnostic(
+                        pos=renamed[0],
+                        message=f'{label} must always be sampled, but gets that name with SetName after the '
+                                f'head sampler saw "{start.name or start.name_arg.text.strip()}"',
+                        suggestion=f'Start the span as "{renamed[1]}" so the sampler can match it',
+                        confidence=0.8,
+                    )
+            missing = [k for k in attributes if k not in at_start]
+            if not head:
+                missing = [k for k in missing if k not in later]
+            for key in missing:
+                if key in later:
+                    yield Diagnostic(
+                        pos=later[key],
+                        message=f'{label} must always be sampled, but {key} is set after the head sampler '
+                                f'has decided',
+                        suggestion=f"Pass {key} to Start with trace.WithAttributes",
+                        confidence=0.85,
+                    )
+                else:
+                    yield Diagnostic(
+                        pos=start.call.start,
+                        end=start.call.end,
+                        message=f"{label} must always be sampled, but never records {key}, which the sampling "
+                                f"policy matches on",
+                        suggestion=f"Record {key} on the span" + (" when starting it" if head else ""),
+                        confidence=0.7,
+                    )
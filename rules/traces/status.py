@@ -0,0 +1,484 @@
+"""
+Span status: what Error status and error.type must reflect
+"""
+
+import re
+from typing import Dict, Iterator, List, Optional, Tuple
+
+from ..base import Diagnostic
+from ..golang import GoFile, GoFunc, SpanStart
+from ..registry import rule
+from .attributes import string_attributes
+from .boundaries import HTTP_CLIENT_CALLS, WRAPPERS
+
+# The response status code recorded as an attribute, old and current semconv
+STATUS_CODE_ATTRIBUTE = (r'\battribute\.Int(?:64)?\s*\(\s*"http\.(?:response\.)?status_code"'
+                         r'|\bsemconv\.HTTP(?:Response)?StatusCode(?:Key\.Int)?\s*\(')
+ERROR_TYPE_ATTRIBUTE = r'"error\.type"|\bsemconv\.ErrorType\w*'
+# A comparison that tells 4xx/5xx responses apart
+STATUS_CODE_CHECK = (r'\.StatusCode\s*(?:>=|>|<|<=|==|!=)|(?:>=|>|<|<=|==|!=)\s*[\w.]*\.StatusCode\b'
+                     r'|\bswitch\s+[\w.]*\.StatusCode\b|\bhttp\.StatusText\s*\(|\bsemconv\.HTTPClientStatus\s*\(')
+
+def _client_span(source: GoFile, fn: GoFunc, pos: int) -> Optional[SpanStart]:
+    """The span started in fn before pos, the one around an HTTP call at pos"""
+
+    found = None
+    for start in source.span_starts:
+        if start.func is fn and start.call.start < pos and start.span_var:
+            found = start
+    return found
+
+@rule(
+    rule_id="http-client-status-not-set",
+    title="Set Error status and error.type on client spans for 4xx and 5xx responses",
+    category="correctness",
+    signal="traces",
+    severity="medium",
+    description="An HTTP client call that returns a response has succeeded as far as Go is concerned, so "
+                "a span that only checks err stays Unset for a 404 or a 503. Semconv makes 4xx and 5xx "
+                "responses of a CLIENT span errors: set Error status and error.type (the status code, "
+                "\"500\") when the status code says so. Recording http.response.status_code alone leaves "
+                "error rates and tail-based sampling blind to them. otelhttp.NewTransport does all of this.",
+    bad_example='''
+func fetchProfile(ctx context.Context, client *http.Client, req *http.Request) error {
+	ctx, span := tracer.Start(ctx, "GET", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	return nil
+}''',
+    good_example='''
+func fetchProfileTraced(ctx context.Context, client *http.Client, req *http.Request) error {
+	ctx, span := tracer.Start(ctx, "GET", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(resp.StatusCode)))
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}''',
+)
+def check_http_client_status_not_set(source: GoFile) -> Iterator[Diagnostic]:
+    if source.path.endswith("_test.go") or not source.imports_path("net/http"):
+        return
+    if re.search(WRAPPERS["http.client"], source.masked):
+        return
+    reported = set()
+    for call in source.calls(HTTP_CLIENT_CALLS):
+        fn = source.func_at(call.start)
+        if fn is None or fn.start in reported:
+            continue
+        start = _client_span(source, fn, call.start)
+        if start is None:
+            continue
+        body = source.masked[fn.body_start:fn.body_end]
+        code = source.code[fn.body_start:fn.body_end]
+        recorded = re.search(STATUS_CODE_ATTRIBUTE, code)
+        if start.kind != "client" and not recorded:
+            continue
+        span = re.escape(start.span_var)
+        sets_error = re.search(r'\b' + span + r'\.SetStatus\s*\(\s*codes\.Error\b', body)
+        checks_code = re.search(STATUS_CODE_CHECK, body)
+        error_type = re.search(ERROR_TYPE_ATTRIBUTE, code)
+        if sets_error and checks_code and error_type:
+            continue
+        if sets_error and checks_code:
+            problem, confidence = "sets Error status for 4xx/5xx responses but not error.type", 0.6
+        elif sets_error:
+            problem, confidence = "sets Error status only when the request fails outright, not for 4xx/5xx responses", 0.7
+        elif recorded:
+            problem, confidence = "records the response status code but never sets Error status for 4xx/5xx", 0.8
+        else:
+            problem, confidence = "never sets Error status, so 4xx/5xx responses look successful", 0.7
+        reported.add(fn.start)
+        pos = fn.body_start + recorded.start() if recorded else call.start
+        yield Diagnostic(
+            pos=pos,
+            end=source.masked.find("\n", pos),
+            message=f"Client span {start.span_var} around {call.name}() {problem}",
+            suggestion=f"After the call, if resp.StatusCode >= 400 set error.type to the status code and "
+                       f"{start.span_var}.SetStatus(codes.Error, http.StatusText(resp.StatusCode)); or use "
+                       f"otelhttp.NewTransport",
+            confidence=confidence,
+        )
+
+# Keys classifying an error: semconv's error.type and look-alikes such as error.kind or payment.error_type
+ERROR_CLASS_KEY = re.compile(r'^error\.(?:type|kind|class|code)$|(?:^|[._])error[._]?(?:type|kind|class)$')
+
+def _unstable_error_value(expr: str) -> Optional[str]:
+    """What is wrong with an expression used as an error classification value, or None"""
+
+    expr = expr.strip()
+    if re.fullmatch(r'[\w.]+\.Error\s*\(\s*\)', expr) \
+            or re.fullmatch(r'fmt\.Sprint(?:ln)?\s*\(\s*\w+\s*\)', expr) \
+            or re.fullmatch(r'fmt\.Sprintf\s*\(\s*"%[vs]"\s*,\s*\w+\s*\)', expr):
+        return "the error message, which differs per occurrence (IDs, addresses, wrapped causes)"
+    if re.fullmatch(r'fmt\.Sprintf\s*\(\s*"%T"\s*,\s*\w+\s*\)', expr) \
+            or re.fullmatch(r'reflect\.TypeOf\s*\(\s*\w+\s*\)\.String\s*\(\s*\)', expr):
+        return "the Go type name, which is \"*errors.errorString\" or \"*fmt.wrapError\" for most errors"
+    return None
+
+@rule(
+    rule_id="error-type-value",
+    title="Classify errors with stable, low-cardinality error.type values",
+    category="conventions",
+    signal="traces",
+    severity="medium",
+    description="error.type groups failures: dashboards count spans and requests per value and alerts "
+                "fire on new ones. err.Error() puts the message there, with the IDs, addresses and "
+                "wrapped causes it contains, so every failure is its own class; the %T or reflect type "
+                "name is \"*errors.errorString\" for any errors.New error and \"*fmt.wrapError\" for "
+                "anything wrapped, so unrelated failures share a class. Map errors to a fixed set of "
+                "values (\"timeout\", \"not_found\", a status code) with errors.Is/errors.As and use "
+                "\"_OTHER\" for the rest; the message belongs in RecordError or exception.message.",
+    bad_example='''
+func traceFetch(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "fetch order")
+	defer span.End()
+	err := orders.Fetch(ctx, id)
+	if err != nil {
+		span.SetAttributes(attribute.String("error.type", err.Error()))
+	}
+	return err
+}''',
+    good_example='''
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, sql.ErrNoRows):
+		return "not_found"
+	}
+	return "_OTHER"
+}
+
+func traceFetchClassified(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "fetch order")
+	defer span.End()
+	err := orders.Fetch(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attribute.String("error.type", errorType(err)))
+	}
+	return err
+}''',
+)
+def check_error_type_value(source: GoFile) -> Iterator[Diagnostic]:
+    for key, value in string_attributes(source):
+        if not ERROR_CLASS_KEY.search(key):
+            continue
+        text = value.text.strip()
+        problem = _unstable_error_value(text)
+        via = ""
+        fn = source.func_at(value.start, include_literals=True)
+        if problem is None and re.fullmatch(r'\w+', text) and fn is not None:
+            # A local assigned from one of those before the use
+            assigned = None
+            for m in re.finditer(r'(?<![\w.])' + re.escape(text) + r'\s*:?=\s*([^\n;]+)',
+                                 source.masked[fn.body_start:value.start]):
+                assigned = source.code[fn.body_start + m.start(1):fn.body_start + m.end(1)]
+            problem = _unstable_error_value(assigned) if assigned else None
+            via = f" (via {text})" if problem else ""
+        if problem is None:
+            continue
+        yield Diagnostic(
+            pos=value.start,
+            end=value.end,
+            message=f"{key} is set to {problem}{via}",
+            suggestion="Map the error to a fixed value with errors.Is/errors.As (\"timeout\", \"not_found\", "
+                       "\"_OTHER\" otherwise) and record the message with span.RecordError",
+            confidence=0.85,
+        )
+
+def _span_vars(source: GoFile, fn: GoFunc) -> List[str]:
+    """Spans fn starts or takes from its context"""
+
+    names = [s.span_var for s in source.span_starts if s.func is fn and s.span_var and s.span_var != "_"]
+    body = source.masked[fn.body_start:fn.body_end]
+    names += re.findall(r'(?<![\w.])(\w+)\s*:=\s*(?:\w+\.)?SpanFromContext\s*\(', body)
+    return names
+
+def _records_and_returns(source: GoFile, fn: GoFunc) -> Optional[int]:
+    """Offset of a RecordError in fn whose error fn then returns, or None"""
+
+    if not re.search(r'\berror\s*\)?\s*\{\s*$', source.masked[fn.start:fn.body_start + 1]):
+        return None
+    spans = _span_vars(source, fn)
+    for call in source.calls(r'\w+\.RecordError', fn.body_start, fn.body_end):
+        if call.name.split(".")[0] not in spans or not call.args or source.func_at(call.start) is not fn:
+            continue
+        err = call.args[0].text.strip()
+        if not re.fullmatch(r'\w+', err):
+            continue
+        for m in re.finditer(r'\breturn\b([^\n;]*)', source.masked[call.end:fn.body_end]):
+            if re.search(r'\b' + re.escape(err) + r'\b', m.group(1).split(",")[-1]):
+                return call.start
+    return None
+
+def _assigned_error(source: GoFile, call) -> Optional[str]:
+    """The variable the error result of call is assigned to"""
+
+    m = re.search(r'(?:\bif\s+)?((?:\w+\s*,\s*)*\w+)\s*:?=\s*$', source.statement_prefix(call.start))
+    if not m:
+        return None
+    name = m.group(1).split(",")[-1].strip()
+    return None if name == "_" else name
+
+@rule(
+    rule_id="error-recorded-twice",
+    title="Record an error on one span, not at every layer it is returned through",
+    category="conventions",
+    signal="traces",
+    severity="low",
+    scope="project",
+    description="A function that calls span.RecordError(err) and then returns err hands its caller an "
+                "error that is already in the trace. When the caller records it again, the trace holds "
+                "the same exception event once per layer, error counts derived from events are "
+                "inflated, and the stack of the first record is the only one worth reading. The span "
+                "the error happened in records it; callers that merely return it set their own status "
+                "to Error (which every failing span should) without recording it again. Only the layer "
+                "that handles the error (retries, falls back, maps it to a response) adds an event, "
+                "and then one that says what it did.",
+    bad_example='''
+func chargeCard(ctx context.Context, card string) error {
+	ctx, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+	if err := gateway.Charge(ctx, card); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "charge failed")
+		return err
+	}
+	return nil
+}
+
+func checkout(ctx context.Context, card string) error {
+	ctx, span := tracer.Start(ctx, "checkout")
+	defer span.End()
+	if err := chargeCard(ctx, card); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "checkout failed")
+		return err
+	}
+	return nil
+}''',
+    good_example='''
+func chargeCardOnce(ctx context.Context, card string) error {
+	ctx, span := tracer.Start(ctx, "charge card")
+	defer span.End()
+	if err := gateway.Charge(ctx, card); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "charge failed")
+		return err
+	}
+	return nil
+}
+
+func checkoutOnce(ctx context.Context, card string) error {
+	ctx, span := tracer.Start(ctx, "checkout")
+	defer span.End()
+	if err := chargeCardOnce(ctx, card); err != nil {
+		span.SetStatus(codes.Error, "checkout failed")
+		return err
+	}
+	return nil
+}''',
+)
+def check_error_recorded_twice(sources: List[GoFile]) -> Iterator[Diagnostic]:
+    # Functions, by name, that record an error and return it
+    recorders: Dict[str, List[Tuple[GoFile, GoFunc, int]]] = {}
+    for source in sources:
+        for fn in source.functions:
+            if fn.is_literal or not fn.name:
+                continue
+            pos = _records_and_returns(source, fn)
+            if pos is not None:
+                recorders.setdefault(fn.name, []).append((source, fn, pos))
+    if not recorders:
+        return
+    names = "|".join(re.escape(n) for n in sorted(recorders))
+    for source in sources:
+        for call in source.calls(r'(?:\w+\.)?(?:' + names + r')'):
+            name = call.name.split(".")[-1]
+            caller = source.func_at(call.start)
+            if caller is None:
+                continue
+            callee_source, callee, recorded = recorders[name][0]
+            if callee is caller:
+                continue
+            # A plain call reaches a function of the same package; a selector may be a method of
+            # another type, or another package's function of that name
+            same_package = any(s.package == source.package for s, _, _ in recorders[name])
+            if "." not in call.name and not same_package:
+                continue
+            err = _assigned_error(source, call)
+            spans = _span_vars(source, caller)
+            if err is None or not spans:
+                continue
+            reassigned = re.compile(r'(?<![\w.])' + re.escape(err) + r'\s*(?:,\s*\w+\s*)*:?=(?!=)')
+            for record in source.calls(r'\w+\.RecordError', call.end, caller.body_end):
+                if record.name.split(".")[0] not in spans or not record.args:
+                    continue
+                if reassigned.search(source.masked, call.end, record.start):
+                    break
+                if not re.search(r'\b' + re.escape(err) + r'\b', record.args[0].text):
+                    continue
+                where = f"line {callee_source.line_of(recorded)}"
+                if callee_source is not source:
+                    where = f"{callee_source.path}:{callee_source.line_of(recorded)}"
+                yield Diagnostic(
+                    pos=record.start,
+                    end=record.end,
+                    message=f"{err} from {name}() is recorded again; {name} already records it on its own "
+                            f"span ({where}) before returning it",
+                    suggestion=f"Set {record.name.split('.')[0]}'s status to Error without recording the error, "
+                               f"or drop the RecordError in {name} if this is the layer that handles it",
+                    confidence=0.8 if "." not in call.name else 0.6,
+                    file=source,
+                )
+                break
+
+# Functions aggregating errors into one, by import path
+AGGREGATORS = {
+    "errors": r'Join',
+    "github.com/hashicorp/go-multierror": r'Append',
+    "go.uber.org/multierr": r'AppendInto|Append|Combine',
+}
+
+def _aggregate_calls(source: GoFile) -> Optional[str]:
+    """Pattern of the calls of source returning an aggregate of several errors, None without any import of them"""
+
+    calls = [re.escape(alias) + r'\.(?:' + names + r')'
+             for path, names in AGGREGATORS.items() for alias in source.import_alias(path)]
+    return r'(?:' + "|".join(calls) + r')' if calls else None
+
+def _aggregate(source: GoFile, fn: GoFunc, expr: str, pos: int, calls: str) -> Optional[str]:
+    """The aggregating call behind expr at pos in fn: the call itself, a variable last assigned
+    from one (or filled by multierr.AppendInto), or its ErrorOrNil()"""
+
+    expr = re.sub(r'\.ErrorOrNil\s*\(\s*\)$', "", expr.strip())
+    m = re.match(calls + r'\s*\(', expr)
+    if m:
+        return m.group(0)[:-1].strip()
+    if not re.fullmatch(r'\w+', expr):
+        return None
+    name = re.escape(expr)
+    assigned = None
+    pattern = (r'(?<![\w.])(?:\w+\s*,\s*)*' + name + r'\s*(?:,\s*\w+\s*)*:?=(?!=)\s*(?:range\b)?\s*(' + calls
+               + r')?|(' + calls + r')\s*\(\s*&' + name + r'\b')
+    for m in re.finditer(pattern, source.masked[fn.body_start:pos]):
+        assigned = m.group(1) or m.group(2)
+    return assigned
+
+def _describes(desc: str, expr: str) -> bool:
+    """Whether a status description is built from the error expr: expr.Error(), or expr formatted"""
+
+    expr = re.escape(expr.strip())
+    return bool(re.search(r'(?<![\w.])' + expr + r'\s*\.\s*Error\s*\(\s*\)', desc)
+                or re.match(r'fmt\.Sprint\w*\s*\(.*(?<![\w.&])' + expr + r'\s*[,)]', desc.strip(), re.S))
+
+@rule(
+    rule_id="aggregate-error-recorded",
+    title="Record the errors of an errors.Join or multierror aggregate one by one",
+    category="conventions",
+    signal="traces",
+    severity="medium",
+    description="errors.Join, multierror.Append and multierr.Combine/Append fold several failures into one "
+                "error whose message is every constituent message concatenated. span.RecordError on the "
+                "aggregate adds a single exception event with that concatenation as exception.message and "
+                "the aggregate's Go type as exception.type, so the individual failures can't be counted, "
+                "grouped or searched for; and using its Error() as the status description puts a different, "
+                "arbitrarily long string on every failing span. Record each constituent error (a bounded "
+                "number of them, since events past the SDK's limit are dropped), or a summary such as a "
+                "failure count attribute, and keep the status description fixed.",
+    bad_example='''
+func uploadAll(ctx context.Context, files []File) error {
+	ctx, span := tracer.Start(ctx, "upload files")
+	defer span.End()
+	var errs []error
+	for _, f := range files {
+		if err := upload(ctx, f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}''',
+    good_example='''
+func uploadAllTraced(ctx context.Context, files []File) error {
+	ctx, span := tracer.Start(ctx, "upload files")
+	defer span.End()
+	var errs []error
+	for _, f := range files {
+		if err := upload(ctx, f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		for _, err := range errs[:min(len(errs), maxRecordedErrors)] {
+			span.RecordError(err)
+		}
+		span.SetAttributes(attribute.Int("upload.failed_count", len(errs)))
+		span.SetStatus(codes.Error, "some uploads failed")
+	}
+	return errors.Join(errs...)
+}''',
+)
+def check_aggregate_error_recorded(source: GoFile) -> Iterator[Diagnostic]:
+    calls = _aggregate_calls(source)
+    if calls is None:
+        return
+    for fn in source.functions:
+        spans = _span_vars(source, fn)
+        if not spans:
+            continue
+        for call in source.calls(r'\w+\.(?:RecordError|SetStatus)', fn.body_start, fn.body_end):
+            span, method = call.name.split(".")
+            if span not in spans or source.func_at(call.start, include_literals=True) is not fn:
+                continue
+            if method == "RecordError" and call.args:
+                expr = call.args[0].text.strip()
+                aggregate = _aggregate(source, fn, expr, call.start, calls)
+                if aggregate is None:
+                    continue
+                yield Diagnostic(
+                    pos=call.start,
+                    end=call.end,
+                    message=f"{span}.RecordError({expr}) records the errors {aggregate}() aggregates as one "
+                            f"exception event, with every message concatenated",
+                    suggestion="Record each constituent error, up to a limit, and the number of failures as an "
+                               "attribute; the aggregate is still what the function returns",
+                    confidence=0.8,
+                )
+            elif method == "SetStatus" and len(call.args) > 1:
+                desc = call.args[1].text
+                for m in re.finditer(r'(?<![\w.])(\w+)\s*\.\s*Error\s*\(|(\w+)\s*[,)]', desc):
+                    expr = m.group(1) or m.group(2)
+                    aggregate = _aggregate(source, fn, expr, call.start, calls) if _describes(desc, expr) else None
+                    if aggregate is None:
+                        continue
+                    yield Diagnostic(
+                        pos=call.args[1].start,
+                        end=call.args[1].end,
+                        message=f"The status description of {span} is the message of the {aggregate}() "
+                                f"aggregate {expr}, which differs with every combination of failures",
+                        suggestion="Use a fixed description such as \"some uploads failed\" and record the "
+                                   "individual errors as events",
+                        confidence=0.75,
+                    )
+                    break
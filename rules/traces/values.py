@@ -0,0 +1,228 @@
+"""
+Attribute values minted where they are recorded: timestamps, random numbers, freshly generated
+IDs, request IDs and process-wide counters take a new value on every span or measurement and
+identify nothing outside the trace, which already has its own timestamps and IDs.
+"""
+
+import fnmatch
+import re
+from dataclasses import dataclass
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from ..base import Diagnostic
+from ..cardinality import Bounds
+from ..golang import Call, GoFile, GoFunc, split_args
+from ..metrics.attributes import unbounded_attribute
+from ..metrics.instruments import metric_attributes
+from ..registry import rule
+from .attributes import attribute_calls
+
+# What the default producers return, by call pattern
+PRODUCER_KINDS = [
+    ("a generated ID", ["uuid.*", "ksuid.*", "xid.*", "ulid.*", "shortid.*", "nanoid.*"]),
+    ("a timestamp", ["time.Now", "time.Since"]),
+    ("a random number", ["rand.*"]),
+    ("a request ID", ["middleware.GetReqID", "requestid.Get", "requestid.FromContext"]),
+    ("a counter", ["atomic.Add*"]),
+]
+# Headers carrying a request ID set by a proxy or the client
+REQUEST_ID_HEADER = re.compile(r'(?i)"x-(?:request|correlation)-id"')
+CALL = re.compile(r'(?<![\w.])((?:\w+\.)?\w+)\s*\(')
+REFERENCE = re.compile(r'(?<![\w.])([A-Za-z_]\w*)\b(?!\s*\()')
+# Values only other systems can make sense of, even when a span mints them
+SUGGESTIONS = {
+    "a timestamp": "Drop it: spans and events carry their own timestamps, and a duration belongs in the "
+                   "span's length or a histogram",
+    "a request ID": "The trace ID already ties the request's spans together; drop it, or add the key to "
+                    "allowed_keys if other systems only know the request ID",
+}
+# Follow helper functions and local variables this many steps
+MAX_DEPTH = 3
+# rand.Intn(8) and the like take at most this many values before they count as unbounded
+MAX_RANDOM_VALUES = 100
+
+@dataclass
+class Producer:
+    call: str
+    kind: str
+    # Local variable the value went through, "" when the call is in the attribute itself
+    variable: str = ""
+
+def producer_kind(name: str) -> str:
+    for kind, patterns in PRODUCER_KINDS:
+        if any(fnmatch.fnmatchcase(name, p) for p in patterns):
+            return kind
+    return "an unbounded value"
+
+def _counters(source: GoFile) -> Set[str]:
+    """Package-level variables incremented somewhere in the package"""
+
+    declared = {m.group(1) for s in source.package_sources
+                for m in re.finditer(r'^(?:var\s+)?(\w+)\s+(?:u?int\d*|atomic\.U?[Ii]nt\d+)\b', s.masked, re.M)
+                if s.func_at(m.start()) is None}
+    counters = set()
+    for s in source.package_sources:
+        for m in re.finditer(r'(?<![\w.])(\w+)\s*(?:\+\+|\+=)|(?<![\w.])(\w+)\.(?:Add|Inc)\s*\(', s.masked):
+            name = m.group(1) or m.group(2)
+            if name in declared:
+                counters.add(name)
+    return counters
+
+class Producers:
+    """Finds the producer calls a value comes from, through locals and the package's helpers"""
+
+    def __init__(self, source: GoFile, patterns: List[str]):
+        self.source = source
+        self.patterns = patterns
+        self.counters = _counters(source)
+
+    def find(self, source: GoFile, fn: Optional[GoFunc], start: int, end: int, depth: int = 0) -> Optional[Producer]:
+        if depth > MAX_DEPTH:
+            return None
+        for m in CALL.finditer(source.masked, start, end):
+            name = m.group(1)
+            if any(fnmatch.fnmatchcase(name, p) for p in self.patterns):
+                if not self._small_range(source, name, m.end()):
+                    return Producer(name + "()", producer_kind(name))
+                continue
+            if "." not in name:
+                found = self._helper(source, name, depth)
+                if found is not None:
+                    return found
+        m = REQUEST_ID_HEADER.search(source.code, start, end)
+        if m:
+            return Producer(f"the {m.group(0)[1:-1]} header", "a request ID")
+        for m in REFERENCE.finditer(source.masked, start, end):
+            name = m.group(1)
+            if name in self.counters:
+                return Producer(name, "a counter")
+            if fn is None:
+                continue
+            for value_start, value_end in self._assignments(source, fn, name, m.start()):
+                found = self.find(source, fn, value_start, value_end, depth + 1)
+                if found is not None:
+                    return Producer(found.call, found.kind, found.variable or name)
+        return None
+
+    @staticmethod
+    def _small_range(source: GoFile, name: str, open_end: int) -> bool:
+        """Whether the call is rand.Intn(n) or the like with a literal n of a few values"""
+
+        bound = re.match(r'\s*(\d+)\s*\)', source.masked[open_end:])
+        return bool(re.fullmatch(r'rand\.(?:\w+n|N)', name) and bound and int(bound.group(1)) < MAX_RANDOM_VALUES)
+
+    def _helper(self, source: GoFile, name: str, depth: int) -> Optional[Producer]:
+        """The producer a function of the package returns the value of"""
+
+        for declared in source.package_sources:
+            for fn in declared.functions:
+                if fn.is_literal or fn.name != name or fn.receiver:
+                    continue
+                for m in re.finditer(r'\breturn\b([^\n;]*)', declared.masked[fn.body_start:fn.body_end]):
+                    pos = fn.body_start + m.start(1)
+                    if declared.func_at(pos, include_literals=True) is not fn:
+                        continue
+                    found = self.find(declared, fn, pos, fn.body_start + m.end(1), depth + 1)
+                    if found is not None:
+                        return Producer(found.call, found.kind)
+        return None
+
+    @staticmethod
+    def _assignments(source: GoFile, fn: GoFunc, name: str, pos: int) -> List[Tuple[int, int]]:
+        """(start, end) of the values assigned to the local name in fn before pos; the value of
+        the whole call for id, err := f()"""
+
+        found = []
+        pattern = r'(?<![\w.])(\w+(?:\s*,\s*\w+)*)\s*:?=(?!=)\s*'
+        for m in re.finditer(pattern, source.masked[fn.body_start:pos]):
+            names = [n.strip() for n in m.group(1).split(",")]
+            if name not in names:
+                continue
+            start = fn.body_start + m.end()
+            end = min(i for i in (source.masked.find("\n", start), source.masked.find(";", start), pos) if i != -1)
+            values = split_args(source.masked, start, end)
+            if len(values) == len(names):
+                found.append(values[names.index(name)])
+            else:
+                found.append((start, end))
+        return found
+
+def _used_elsewhere(source: GoFile, fn: GoFunc, name: str, telemetry: List[Call]) -> bool:
+    """Whether the local name is read outside attribute constructors: passed on, stored, returned"""
+
+    for m in re.finditer(r'(?<![\w.])' + re.escape(name) + r'\b(?!\s*(?:,\s*\w+\s*)*:?=(?!=))',
+                         source.masked[fn.body_start:fn.body_end]):
+        pos = fn.body_start + m.start()
+        if not any(c.start <= pos < c.end for c in telemetry):
+            return True
+    return False
+
+@rule(
+    rule_id="attribute-value-unbounded",
+    title="Don't record values minted on the spot as attributes",
+    category="performance",
+    signal="traces",
+    signals=("metrics",),
+    severity="medium",
+    options={
+        # Calls returning a new value every time; fnmatch globs over the call as written
+        "producers": [p for _, patterns in PRODUCER_KINDS for p in patterns],
+        # Keys that are meant to hold such values
+        "allowed_keys": ["service.instance.id", "messaging.message.id"],
+    },
+    description="A timestamp, random number, freshly generated UUID or process-wide counter takes a new value "
+                "on every span or measurement: it grows the backend's attribute indexes by one value per "
+                "span, can't be grouped or searched on, and a timestamp or request ID only repeats what the "
+                "span's own start time and trace ID record. Values of the producers calls (uuid, ksuid, "
+                "xid, ulid, time.Now, rand, chi's middleware.GetReqID, atomic.Add; extend the list with the "
+                "team's own ID generators) are followed through locals and the package's helper functions "
+                "to the attribute; X-Request-Id headers and incremented package-level counters count too. "
+                "Generated IDs and random values that the code also passes on or stores are real "
+                "identifiers and are left alone, as are allowed_keys (service.instance.id). Metric "
+                "attributes that metric-attribute-high-cardinality reports are skipped.",
+    bad_example='''
+func submitOrder(ctx context.Context, cart Cart) error {
+	ctx, span := tracer.Start(ctx, "submit order")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.ref", fmt.Sprintf("ord-%d", time.Now().UnixNano())))
+	return orders.Submit(ctx, cart)
+}''',
+    good_example='''
+func submitOrderTraced(ctx context.Context, cart Cart) error {
+	ctx, span := tracer.Start(ctx, "submit order")
+	defer span.End()
+	ref := uuid.NewString()
+	span.SetAttributes(attribute.String("order.ref", ref))
+	return orders.Submit(ctx, cart, ref)
+}''',
+)
+def check_attribute_value_unbounded(source: GoFile, options: Dict) -> Iterator[Diagnostic]:
+    producers = Producers(source, options["producers"])
+    allowed = options["allowed_keys"]
+    telemetry = [c for c in attribute_calls(source) if len(c.args) >= 2]
+    metric = {c.start for c in metric_attributes(source)}
+    bounds = Bounds(source, 100)
+    for call in telemetry:
+        key, value = call.args[0], call.args[1]
+        if key.literal is not None and any(fnmatch.fnmatchcase(key.literal, p) for p in allowed):
+            continue
+        if call.start in metric and unbounded_attribute(call, bounds) is not None:
+            continue
+        fn = source.func_at(value.start, include_literals=True)
+        found = producers.find(source, fn, value.start, value.end)
+        if found is None:
+            continue
+        if found.variable and found.kind in ("a generated ID", "a random number", "a request ID") \
+                and fn is not None and _used_elsewhere(source, fn, found.variable, telemetry):
+            continue
+        via = f" (through {found.variable})" if found.variable else ""
+        what = "measurement" if call.start in metric else "span"
+        yield Diagnostic(
+            pos=call.start,
+            end=call.end,
+            message=f"Attribute {key.text.strip()} is set from {found.call}{via}, {found.kind}: it takes a "
+                    f"new value on every {what}",
+            suggestion=SUGGESTIONS.get(found.kind, "Record an ID other systems know (the order, user or job it "
+                                                   "is about) instead, or drop the attribute"),
+            confidence=0.5 if found.kind == "a request ID" else 0.8,
+        )
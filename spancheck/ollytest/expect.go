@@ -0,0 +1,200 @@
+package ollytest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Expectation checks one property of a span: it returns "" when the span has it, and otherwise
+// why not.
+type Expectation func(sdktrace.ReadOnlySpan) string
+
+// WithName expects the span name to be name.
+func WithName(name string) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		if s.Name() == name {
+			return ""
+		}
+		return fmt.Sprintf("name is %q, want %q", s.Name(), name)
+	}
+}
+
+// WithNameMatching expects the whole span name to match the regular expression pattern.
+func WithNameMatching(pattern string) Expectation {
+	re := regexp.MustCompile("^(?:" + pattern + ")$")
+	return func(s sdktrace.ReadOnlySpan) string {
+		if re.MatchString(s.Name()) {
+			return ""
+		}
+		return fmt.Sprintf("name %q does not match /%s/", s.Name(), pattern)
+	}
+}
+
+// WithKind expects the span kind to be kind.
+func WithKind(kind trace.SpanKind) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		if s.SpanKind() == kind {
+			return ""
+		}
+		return fmt.Sprintf("kind is %s, want %s", s.SpanKind(), kind)
+	}
+}
+
+// WithAttr expects the span to carry the attribute, with the same type and value:
+// WithAttr(attribute.String("http.request.method", "GET")).
+func WithAttr(kv attribute.KeyValue) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		v, ok := value(s, kv.Key)
+		if !ok {
+			return fmt.Sprintf("missing attribute %q", kv.Key)
+		}
+		if v.Type() != kv.Value.Type() || !reflect.DeepEqual(v.AsInterface(), kv.Value.AsInterface()) {
+			return fmt.Sprintf("attribute %q is %s(%s), want %s(%s)", kv.Key, v.Type(), v.Emit(), kv.Value.Type(), kv.Value.Emit())
+		}
+		return ""
+	}
+}
+
+// HasAttr expects the span to carry an attribute with this key, whatever its value.
+func HasAttr(key string) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		if _, ok := value(s, attribute.Key(key)); ok {
+			return ""
+		}
+		return fmt.Sprintf("missing attribute %q", key)
+	}
+}
+
+// WithoutAttr expects the span not to carry an attribute with this key.
+func WithoutAttr(key string) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		if _, ok := value(s, attribute.Key(key)); ok {
+			return fmt.Sprintf("unexpected attribute %q", key)
+		}
+		return ""
+	}
+}
+
+// WithStatus expects the span status code to be code.
+func WithStatus(code codes.Code) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		if s.Status().Code == code {
+			return ""
+		}
+		return fmt.Sprintf("status is %s, want %s", s.Status().Code, code)
+	}
+}
+
+// WithEvent expects the span to have an event with this name.
+func WithEvent(name string) Expectation {
+	return func(s sdktrace.ReadOnlySpan) string {
+		var names []string
+		for _, e := range s.Events() {
+			if e.Name == name {
+				return ""
+			}
+			names = append(names, e.Name)
+		}
+		return fmt.Sprintf("no event named %q (have %s)", name, strings.Join(names, ", "))
+	}
+}
+
+func value(s sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// Ended returns the ended spans of source: a *tracetest.InMemoryExporter, a
+// *tracetest.SpanRecorder, tracetest.SpanStubs or a []sdktrace.ReadOnlySpan.
+func Ended(t testing.TB, source any) []sdktrace.ReadOnlySpan {
+	t.Helper()
+	switch s := source.(type) {
+	case *tracetest.InMemoryExporter:
+		return s.GetSpans().Snapshots()
+	case *tracetest.SpanRecorder:
+		return s.Ended()
+	case tracetest.SpanStubs:
+		return s.Snapshots()
+	case []sdktrace.ReadOnlySpan:
+		return s
+	}
+	t.Fatalf("ollytest: can't read spans from %T", source)
+	return nil
+}
+
+// FindSpans returns the ended spans of source that meet every expectation.
+func FindSpans(t testing.TB, source any, expectations ...Expectation) []sdktrace.ReadOnlySpan {
+	t.Helper()
+	var found []sdktrace.ReadOnlySpan
+	for _, s := range Ended(t, source) {
+		if len(unmet(s, expectations)) == 0 {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func unmet(s sdktrace.ReadOnlySpan, expectations []Expectation) []string {
+	var reasons []string
+	for _, expect := range expectations {
+		if reason := expect(s); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// ExpectSpan fails the test unless an ended span of source meets every expectation, and
+// returns the first that does (nil when none does):
+//
+//	exporter := tracetest.NewInMemoryExporter()
+//	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+//	// ... exercise the handler ...
+//	ollytest.ExpectSpan(t, exporter, ollytest.WithName("GET /users/{id}"), ollytest.WithKind(trace.SpanKindServer),
+//		ollytest.WithAttr(attribute.String("http.request.method", "GET")))
+//
+// The failure describes the span closest to the expectations, with what it lacks.
+func ExpectSpan(t testing.TB, source any, expectations ...Expectation) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := Ended(t, source)
+	var closest sdktrace.ReadOnlySpan
+	var missing []string
+	for _, s := range spans {
+		reasons := unmet(s, expectations)
+		if len(reasons) == 0 {
+			return s
+		}
+		if closest == nil || len(reasons) < len(missing) {
+			closest, missing = s, reasons
+		}
+	}
+	if closest == nil {
+		t.Errorf("ollytest: expected a matching span, but no spans ended")
+		return nil
+	}
+	t.Errorf("ollytest: no matching span among %d ended; closest was %q: %s", len(spans), closest.Name(), strings.Join(missing, "; "))
+	return nil
+}
+
+// ExpectNoSpan fails the test when an ended span of source meets every expectation.
+func ExpectNoSpan(t testing.TB, source any, expectations ...Expectation) bool {
+	t.Helper()
+	found := FindSpans(t, source, expectations...)
+	for _, s := range found {
+		t.Errorf("ollytest: unexpected matching span %q", s.Name())
+	}
+	return len(found) == 0
+}
@@ -0,0 +1,95 @@
+package ollytest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingT collects the failures of the helpers under test instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func handle(tp trace.TracerProvider) {
+	_, span := tp.Tracer("ollytest").Start(context.Background(), "GET /users/{id}",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.request.method", "GET"), attribute.Int("http.response.status_code", 404)))
+	span.AddEvent("cache miss")
+	span.SetStatus(codes.Error, "not found")
+	span.End()
+}
+
+func TestExpectSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	recorder := tracetest.NewSpanRecorder()
+	handle(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSpanProcessor(recorder)))
+
+	for name, source := range map[string]any{"exporter": exporter, "recorder": recorder} {
+		t.Run(name, func(t *testing.T) {
+			span := ExpectSpan(t, source, WithName("GET /users/{id}"), WithKind(trace.SpanKindServer),
+				WithAttr(attribute.String("http.request.method", "GET")), WithAttr(attribute.Int("http.response.status_code", 404)),
+				HasAttr("http.request.method"), WithoutAttr("user.id"), WithStatus(codes.Error), WithEvent("cache miss"),
+				WithNameMatching(`GET /users/\{\w+\}`))
+			if span == nil || span.Name() != "GET /users/{id}" {
+				t.Fatalf("ExpectSpan returned %v", span)
+			}
+		})
+	}
+}
+
+func TestExpectSpanFailure(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	handle(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	r := &recordingT{TB: t}
+	if span := ExpectSpan(r, recorder, WithName("GET /users/{id}"), WithKind(trace.SpanKindClient),
+		WithAttr(attribute.String("http.response.status_code", "404"))); span != nil {
+		t.Errorf("ExpectSpan returned %q for unmet expectations", span.Name())
+	}
+	if len(r.errors) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(r.errors), r.errors)
+	}
+	for _, want := range []string{`closest was "GET /users/{id}"`, "kind is server, want client",
+		`attribute "http.response.status_code" is INT64(404), want STRING(404)`} {
+		if !strings.Contains(r.errors[0], want) {
+			t.Errorf("error %q doesn't say %q", r.errors[0], want)
+		}
+	}
+
+	r = &recordingT{TB: t}
+	ExpectSpan(r, tracetest.NewSpanRecorder(), WithName("GET /users/{id}"))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "no spans ended") {
+		t.Errorf("without spans, got %v", r.errors)
+	}
+}
+
+func TestExpectNoSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	handle(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	if !ExpectNoSpan(t, recorder, WithName("GET /orders")) {
+		t.Error("ExpectNoSpan failed without a matching span")
+	}
+	r := &recordingT{TB: t}
+	if ExpectNoSpan(r, recorder, WithKind(trace.SpanKindServer)) || len(r.errors) != 1 {
+		t.Errorf("ExpectNoSpan passed with a matching span: %v", r.errors)
+	}
+	if got := FindSpans(t, recorder.Ended(), WithEvent("cache miss")); len(got) != 1 {
+		t.Errorf("FindSpans found %d spans, want 1", len(got))
+	}
+}
@@ -0,0 +1,135 @@
+// Package ollytest asserts in unit tests on the spans a test recorded: that the expected spans
+// were emitted, with their names, kinds and attributes, and that they keep to the ollygarden
+// rules, so instrumentation quality is gated by `go test` rather than a separate CLI run:
+//
+//	func TestCheckout(t *testing.T) {
+//		recorder := tracetest.NewSpanRecorder()
+//		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+//
+//		checkout(context.Background(), cart)
+//
+//		ollytest.ExpectSpan(t, recorder, ollytest.WithName("checkout"), ollytest.WithKind(trace.SpanKindInternal),
+//			ollytest.WithAttr(attribute.Int("cart.items", 3)))
+//		ollytest.AssertNoViolations(t, recorder)
+//		ollytest.AssertNoViolations(t, recorder, ollytest.Rule("span-name-unbounded"), ollytest.Span("checkout"))
+//	}
+//
+// Only spans that have ended are checked. The rules run in one spancheck.Processor shared by the
+// test binary, started on first use with the options given to Configure, so the
+// .ollygarden.yaml of the package under test (or a directory above it) applies. The rules are
+// found through OLLYGARDEN_HOME, or spancheck.WithHome given to Configure.
+package ollytest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/spancheck"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	mu      sync.Mutex
+	options []spancheck.Option
+	checker *spancheck.Processor
+)
+
+// Configure sets the options the shared processor is started with. Call it before the first
+// check, from TestMain say; once the processor runs it has no effect.
+func Configure(opts ...spancheck.Option) {
+	mu.Lock()
+	defer mu.Unlock()
+	options = opts
+}
+
+func processor() (*spancheck.Processor, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if checker == nil {
+		p, err := spancheck.New(options...)
+		if err != nil {
+			return nil, err
+		}
+		checker = p
+	}
+	return checker, nil
+}
+
+// Matcher selects violations.
+type Matcher func(spancheck.Violation) bool
+
+// Rule matches violations of the rule with this ID.
+func Rule(id string) Matcher {
+	return func(v spancheck.Violation) bool { return v.RuleID == id }
+}
+
+// Span matches violations of spans with this name.
+func Span(name string) Matcher {
+	return func(v spancheck.Violation) bool { return v.Span == name }
+}
+
+// Attribute matches violations about this attribute.
+func Attribute(key string) Matcher {
+	return func(v spancheck.Violation) bool { return v.Attribute == key }
+}
+
+// Severity matches violations of this severity ("critical", "high", "medium", "low").
+func Severity(severity string) Matcher {
+	return func(v spancheck.Violation) bool { return strings.EqualFold(v.Severity, severity) }
+}
+
+// Violations returns the violations of the recorder's ended spans that match every matcher
+// (all of them without matchers). It fails the test when the rules can't be run.
+func Violations(t testing.TB, recorder *tracetest.SpanRecorder, matchers ...Matcher) []spancheck.Violation {
+	t.Helper()
+	return check(t, recorder.Ended(), matchers)
+}
+
+// AssertNoViolations reports each violation of the recorder's ended spans that matches every
+// matcher as a test error, and returns whether there were none.
+func AssertNoViolations(t testing.TB, recorder *tracetest.SpanRecorder, matchers ...Matcher) bool {
+	t.Helper()
+	found := Violations(t, recorder, matchers...)
+	for _, v := range found {
+		t.Errorf("ollytest: %s", v)
+	}
+	return len(found) == 0
+}
+
+// AssertViolation fails the test unless some ended span has a violation matching every
+// matcher, e.g. to pin down a known gap until it is fixed.
+func AssertViolation(t testing.TB, recorder *tracetest.SpanRecorder, matchers ...Matcher) bool {
+	t.Helper()
+	if len(Violations(t, recorder, matchers...)) == 0 {
+		t.Errorf("ollytest: no matching violation among %d ended spans", len(recorder.Ended()))
+		return false
+	}
+	return true
+}
+
+func check(t testing.TB, spans []sdktrace.ReadOnlySpan, matchers []Matcher) []spancheck.Violation {
+	t.Helper()
+	p, err := processor()
+	if err != nil {
+		t.Fatalf("ollytest: starting rules: %v", err)
+	}
+	var found []spancheck.Violation
+	for _, s := range spans {
+		violations, err := p.Check(s)
+		if err != nil {
+			t.Fatalf("ollytest: checking span %q: %v", s.Name(), err)
+		}
+	next:
+		for _, v := range violations {
+			for _, match := range matchers {
+				if !match(v) {
+					continue next
+				}
+			}
+			found = append(found, v)
+		}
+	}
+	return found
+}
@@ -0,0 +1,209 @@
+// Package spancheck is an sdktrace.SpanProcessor that holds every ended span to the ollygarden
+// rules, for local and staging tracer providers. It catches what static analysis can't see:
+// span names and attributes computed at runtime, spans started by instrumentation libraries,
+// personal data in values.
+//
+//	checker, err := spancheck.New(spancheck.WithMode(spancheck.Panic))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(checker), sdktrace.WithBatcher(exp))
+//
+// The checks are the exported-telemetry checks of `otel_cli.py analyze-traces`, run by a
+// `python3 -m rules.processor` the processor keeps running (see internal/pyworker), and each
+// span is checked synchronously in End. That is what makes Panic point at the code ending the span, and
+// also why the processor doesn't belong in production tracer providers.
+package spancheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ErrShutdown is returned by Check once the processor was shut down.
+var ErrShutdown = errors.New("spancheck: processor shut down")
+
+// Mode says what happens when a span breaks a rule.
+type Mode int
+
+const (
+	// Log logs each violation as a warning.
+	Log Mode = iota
+	// Panic panics in the goroutine ending the span, so tests and local runs stop at the culprit.
+	Panic
+)
+
+// Violation is one rule an ended span breaks.
+type Violation struct {
+	// Span is the span's name.
+	Span     string
+	RuleID   string
+	Severity string
+	Message  string
+	// Attribute is the attribute the violation is about, "" for the span itself.
+	Attribute string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("span %q: %s [%s] %s", v.Span, v.RuleID, v.Severity, v.Message)
+}
+
+type config struct {
+	mode    Mode
+	logger  *slog.Logger
+	handler func(Violation)
+	worker  pyworker.Options
+}
+
+// Option configures a Processor.
+type Option func(*config)
+
+// WithMode sets what happens on a violation (default Log).
+func WithMode(mode Mode) Option {
+	return func(c *config) { c.mode = mode }
+}
+
+// WithLogger sets the logger of Log mode and of the processor's own errors (default slog.Default()).
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) { c.logger = logger }
+}
+
+// WithHandler calls handler for each violation instead of logging or panicking, e.g. to collect
+// them in a test.
+func WithHandler(handler func(Violation)) Option {
+	return func(c *config) { c.handler = handler }
+}
+
+// WithConfigDir sets where the .ollygarden.yaml selecting and configuring the rules is looked
+// up (default the working directory).
+func WithConfigDir(dir string) Option {
+	return func(c *config) { c.worker.ConfigDir = dir }
+}
+
+// WithProfile selects a .ollygarden.yaml profile (default OLLYGARDEN_PROFILE).
+func WithProfile(profile string) Option {
+	return func(c *config) { c.worker.Profile = profile }
+}
+
+// WithPython sets the interpreter running the rules (default OLLYGARDEN_PYTHON, or python3).
+func WithPython(python string) Option {
+	return func(c *config) { c.worker.Python = python }
+}
+
+// WithHome sets the checkout holding the rules package (default OLLYGARDEN_HOME). One of the
+// two is required.
+func WithHome(home string) Option {
+	return func(c *config) { c.worker.Home = home }
+}
+
+// WithTimeout bounds the check of one span, waiting for the spans ended before it included
+// (default 5s); spans taking longer go unchecked.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) { c.worker.Timeout = timeout }
+}
+
+// Processor checks ended spans. It does not export them: register an exporting processor as
+// well.
+type Processor struct {
+	cfg   config
+	rules *pyworker.Checker
+}
+
+var _ sdktrace.SpanProcessor = (*Processor)(nil)
+
+// New starts the rules and returns a processor using them. It fails when the rules can't be
+// run or the project config is invalid.
+func New(opts ...Option) (*Processor, error) {
+	cfg := config{logger: slog.Default(), worker: pyworker.Options{Timeout: 5 * time.Second}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	rules, err := pyworker.Start(cfg.worker)
+	if err != nil {
+		return nil, err
+	}
+	return &Processor{cfg: cfg, rules: rules}, nil
+}
+
+// OnStart does nothing: names and attributes are only final when the span ends.
+func (p *Processor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd checks the span and reports each violation as the Mode says.
+func (p *Processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	violations, err := p.Check(s)
+	if err != nil {
+		p.cfg.logger.Error("spancheck: span not checked", "span", s.Name(), "error", err)
+		return
+	}
+	for _, v := range violations {
+		switch {
+		case p.cfg.handler != nil:
+			p.cfg.handler(v)
+		case p.cfg.mode == Panic:
+			panic("spancheck: " + v.String())
+		default:
+			p.cfg.logger.Warn("spancheck: "+v.Message, "span", v.Span, "rule_id", v.RuleID, "severity", v.Severity)
+		}
+	}
+}
+
+// Check returns the rules an ended span breaks, without reporting them. Spans ended on several
+// goroutines are checked together rather than one after another. After Shutdown it returns
+// ErrShutdown.
+func (p *Processor) Check(s sdktrace.ReadOnlySpan) ([]Violation, error) {
+	found, err := p.rules.Check([]pyworker.Span{toSpan(s)})
+	if errors.Is(err, pyworker.ErrClosed) {
+		return nil, ErrShutdown
+	}
+	if err != nil {
+		return nil, err
+	}
+	var violations []Violation
+	for _, f := range found[0] {
+		violations = append(violations, Violation{Span: s.Name(), RuleID: f.RuleID, Severity: f.Severity, Message: f.Message, Attribute: f.Attribute})
+	}
+	return violations, nil
+}
+
+// Shutdown stops the rules, waiting for them to exit until ctx is done. Spans ending after it
+// are not checked.
+func (p *Processor) Shutdown(ctx context.Context) error {
+	return p.rules.Close(ctx)
+}
+
+// ForceFlush does nothing: spans are checked as they end.
+func (p *Processor) ForceFlush(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func toSpan(s sdktrace.ReadOnlySpan) pyworker.Span {
+	out := pyworker.Span{
+		Name:       s.Name(),
+		Kind:       strings.ToLower(s.SpanKind().String()),
+		Scope:      s.InstrumentationScope().Name,
+		Attributes: map[string]any{},
+	}
+	if s.Resource() != nil {
+		if v, ok := s.Resource().Set().Value("service.name"); ok {
+			out.Service = v.Emit()
+		}
+	}
+	for _, kv := range s.Attributes() {
+		out.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	for _, e := range s.Events() {
+		attrs := map[string]any{}
+		for _, kv := range e.Attributes {
+			attrs[string(kv.Key)] = kv.Value.AsInterface()
+		}
+		out.Events = append(out.Events, pyworker.Event{Name: e.Name, Attributes: attrs})
+	}
+	return out
+}
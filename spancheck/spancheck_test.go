@@ -0,0 +1,152 @@
+package spancheck
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker"
+	"github.com/aditya-prakash-git/ollygarden-opentelemetry/internal/pyworker/pyworkertest"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rules flags span names holding a path and user.email attributes, and crashes on "crash".
+func rules(s pyworker.Span) []pyworker.Finding {
+	var found []pyworker.Finding
+	if s.Name == "crash" {
+		os.Exit(3)
+	}
+	if strings.Contains(s.Name, "/") {
+		found = append(found, pyworker.Finding{RuleID: "span-name-unbounded", Severity: "high", Message: "name holds a path", Event: -1})
+	}
+	if _, ok := s.Attributes["user.email"]; ok {
+		found = append(found, pyworker.Finding{RuleID: "pii-in-telemetry", Severity: "critical", Message: "email address", Attribute: "user.email", Event: -1})
+	}
+	return found
+}
+
+func TestMain(m *testing.M) {
+	pyworkertest.Main(rules)
+	os.Exit(m.Run())
+}
+
+func newProcessor(t *testing.T, opts ...Option) *Processor {
+	t.Helper()
+	fake := pyworkertest.Options(t)
+	p, err := New(append([]Option{WithPython(fake.Python), WithHome(fake.Home)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { p.Shutdown(context.Background()) })
+	return p
+}
+
+func endSpan(tp trace.TracerProvider, name string, attrs ...attribute.KeyValue) {
+	_, span := tp.Tracer("spancheck").Start(context.Background(), name, trace.WithAttributes(attrs...))
+	span.End()
+}
+
+func TestOnEnd(t *testing.T) {
+	var mu sync.Mutex
+	var got []Violation
+	p := newProcessor(t, WithHandler(func(v Violation) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, v)
+	}))
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p))
+	endSpan(tp, "GET /users/42", attribute.String("user.email", "a@example.com"))
+	endSpan(tp, "checkout")
+
+	if len(got) != 2 {
+		t.Fatalf("got %v, want 2 violations", got)
+	}
+	if got[0].Span != "GET /users/42" || got[0].RuleID != "span-name-unbounded" {
+		t.Errorf("first violation %v", got[0])
+	}
+	if got[1].RuleID != "pii-in-telemetry" || got[1].Attribute != "user.email" || got[1].Severity != "critical" {
+		t.Errorf("second violation %v", got[1])
+	}
+}
+
+func TestPanicMode(t *testing.T) {
+	p := newProcessor(t, WithMode(Panic))
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p))
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "span-name-unbounded") {
+			t.Errorf("recovered %v", r)
+		}
+	}()
+	endSpan(tp, "GET /orders/7")
+}
+
+func TestConcurrentOnEnd(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	p := newProcessor(t, WithHandler(func(Violation) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+	}))
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p))
+	var wg sync.WaitGroup
+	for range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			endSpan(tp, "GET /items/1")
+		}()
+	}
+	wg.Wait()
+	if count != 40 {
+		t.Errorf("got %d violations, want 40", count)
+	}
+}
+
+func TestRestartAfterCrash(t *testing.T) {
+	p := newProcessor(t)
+	tp := sdktrace.NewTracerProvider()
+	_, crash := tp.Tracer("spancheck").Start(context.Background(), "crash")
+	crash.End()
+	if _, err := p.Check(crash.(sdktrace.ReadOnlySpan)); err == nil {
+		t.Fatal("no error from crashed rules")
+	}
+	_, span := tp.Tracer("spancheck").Start(context.Background(), "GET /a/b")
+	span.End()
+	if found, err := p.Check(span.(sdktrace.ReadOnlySpan)); err != nil || len(found) != 1 {
+		t.Fatalf("after crash: got %v, %v", found, err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	p := newProcessor(t)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("spancheck").Start(context.Background(), "GET /a/b")
+	span.End()
+	if _, err := p.Check(span.(sdktrace.ReadOnlySpan)); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Check after Shutdown: got %v, want ErrShutdown", err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
+
+func TestShutdownHonorsContext(t *testing.T) {
+	p := newProcessor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Shutdown(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := p.ForceFlush(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ForceFlush: got %v, want context.Canceled", err)
+	}
+}
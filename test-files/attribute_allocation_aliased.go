@@ -0,0 +1,38 @@
+// attribute_allocation_aliased.go
+// Attribute values formatted through renamed fmt and strconv imports.
+package main
+
+import (
+	"context"
+	format "fmt"
+	conv "strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type fmtHelper struct{}
+
+func (fmtHelper) Sprintf(layout string, args ...any) string { return layout }
+
+func handleRefund(ctx context.Context, amount float64, partial bool, attempt int) {
+	_, span := tracer.Start(ctx, "POST /refunds")
+	defer span.End()
+
+	span.SetAttributes(
+		// VIOLATION 1: Sprintf of a float through the renamed fmt
+		attribute.String("refund.amount", format.Sprintf("%.2f", amount)),
+		// VIOLATION 2: strconv of a bool through the renamed strconv
+		attribute.String("refund.partial", conv.FormatBool(partial)),
+		// VIOLATION 3: concatenation with the renamed strconv
+		attribute.String("refund.attempt", conv.Itoa(attempt)+conv.Itoa(0)),
+		// CORRECT: a label holding the attempt
+		attribute.String("refund.label", "attempt-"+conv.Itoa(attempt)),
+	)
+
+	// CORRECT: fmt here is a local value, not the fmt package
+	fmt := fmtHelper{}
+	span.SetAttributes(
+		attribute.Float64("refund.amount", amount),
+		attribute.String("refund.reason", fmt.Sprintf("%d", attempt)),
+	)
+}
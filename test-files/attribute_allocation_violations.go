@@ -0,0 +1,44 @@
+// attribute_allocation_violations.go
+// Attribute values built by formatting numbers and bools into strings.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func handleOrder(ctx context.Context, items int, total float64, express bool, retries, seconds, millis int64) {
+	_, span := tracer.Start(ctx, "POST /orders")
+	defer span.End()
+
+	span.SetAttributes(
+		// VIOLATION 1: Sprintf of an integer
+		attribute.String("order.items", fmt.Sprintf("%d", items)),
+		// VIOLATION 2: strconv of a bool
+		attribute.String("order.express", strconv.FormatBool(express)),
+		// VIOLATION 3: Sprintf of a float
+		attribute.String("order.total", fmt.Sprintf("%.2f", total)),
+		// VIOLATION 4: a number assembled from formatted parts
+		attribute.String("order.latency", strconv.FormatInt(seconds, 10)+"."+strconv.FormatInt(millis, 10)),
+		// VIOLATION 5: Itoa
+		attribute.String("order.item_count", strconv.Itoa(items)),
+	)
+
+	// CORRECT: typed constructors
+	span.SetAttributes(
+		attribute.Int("order.items", items),
+		attribute.Bool("order.express", express),
+		attribute.Float64("order.total", total),
+		attribute.String("order.currency", fmt.Sprintf("%s", "EUR")),
+	)
+
+	// CORRECT: labels and padded codes are strings, not numbers
+	span.SetAttributes(
+		attribute.String("order.retries", "retries-"+strconv.FormatInt(retries, 10)),
+		attribute.String("order.code", fmt.Sprintf("%05d", items)),
+		attribute.String("order.slot", fmt.Sprintf("%-4d", items)),
+	)
+}
@@ -0,0 +1,56 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("orders")
+
+type Step string
+
+const (
+	StepReserve Step = "reserve"
+	StepCharge  Step = "charge"
+)
+
+// The span name comes from n, so the callers decide its cardinality
+func processBatch(ctx context.Context, n int64) {
+	_, span := tracer.Start(ctx, fmt.Sprintf("order-%d", n))
+	defer span.End()
+}
+
+func runStep(ctx context.Context, step Step) {
+	name := "step " + string(step)
+	_, span := tracer.Start(ctx, name)
+	defer span.End()
+}
+
+func scheduleBatch(ctx context.Context) {
+	processBatch(ctx, time.Now().Unix())
+}
+
+func scheduleFirst(ctx context.Context) {
+	processBatch(ctx, 1)
+}
+
+func checkout(ctx context.Context) {
+	runStep(ctx, StepReserve)
+	runStep(ctx, StepCharge)
+}
+
+type handler struct{}
+
+func (h *handler) handle(ctx context.Context, op string) {
+	_, span := tracer.Start(ctx, "handle "+op)
+	defer span.End()
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handle(r.Context(), r.Method)
+	h.handle(r.Context(), r.URL.Path)
+}
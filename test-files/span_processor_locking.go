@@ -0,0 +1,46 @@
+package processors
+
+import (
+	"context"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type stats struct {
+	mu    sync.Mutex
+	other sync.Mutex
+	n     int
+	names []string
+	ready chan bool
+	out   chan string
+}
+
+func (p *stats) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if <-p.ready {
+		p.mu.Lock()
+		p.names = append(p.names, s.Name())
+		p.mu.Unlock()
+	}
+	go func() { p.out <- s.Name() }()
+	p.out <- s.Name()
+}
+
+func (p *stats) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.other.Lock()
+	p.other.Unlock()
+	p.n++
+}
+
+type deferred struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (d *deferred) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.n++
+}
+
+func (d *deferred) OnEnd(s sdktrace.ReadOnlySpan) {}
@@ -0,0 +1,101 @@
+// span_processor_violations.go
+// Custom SpanProcessor implementations with common safety bugs.
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type auditProcessor struct {
+	counts  map[string]int
+	started int
+	events  chan string
+}
+
+// VIOLATION 1: blocking HTTP call and sleep in OnStart
+func (p *auditProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	http.Get("http://audit.internal/notify")
+	time.Sleep(5 * time.Millisecond)
+
+	// VIOLATION 2: unsynchronized field writes from concurrent OnStart calls
+	p.started++
+	p.counts[s.Name()] = p.counts[s.Name()] + 1
+
+	// VIOLATION 3: unguarded channel send can block span creation
+	p.events <- s.Name()
+}
+
+// VIOLATION 4: mutating an ended span
+func (p *auditProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	rw := s.(sdktrace.ReadWriteSpan)
+	rw.SetAttributes(attribute.String("audit.done", "true"))
+}
+
+// VIOLATION 5: Shutdown ignores its context
+func (p *auditProcessor) Shutdown(ctx context.Context) error {
+	close(p.events)
+	return nil
+}
+
+func (p *auditProcessor) ForceFlush(context.Context) error { return nil }
+
+// CORRECT: guarded state, non-blocking send, context-aware shutdown
+type safeProcessor struct {
+	mu     sync.Mutex
+	counts map[string]int
+	events chan string
+}
+
+func (p *safeProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	s.SetAttributes(attribute.String("deployment.environment.name", "prod"))
+
+	p.mu.Lock()
+	p.counts[s.Name()]++
+	p.mu.Unlock()
+
+	select {
+	case p.events <- s.Name():
+	default:
+	}
+}
+
+func (p *safeProcessor) OnEnd(s sdktrace.ReadOnlySpan) {}
+
+func (p *safeProcessor) Shutdown(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		close(p.events)
+		return nil
+	}
+}
+
+func (p *safeProcessor) ForceFlush(ctx context.Context) error { return ctx.Err() }
+
+// VIOLATION 6: a request through the processor's HTTP client (the sync.Once is fine), and a
+// write under a read lock
+type enrichProcessor struct {
+	once   sync.Once
+	mu     sync.RWMutex
+	client *http.Client
+	last   string
+}
+
+func (p *enrichProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.once.Do(func() {})
+	req, _ := http.NewRequest(http.MethodGet, "http://enrich.internal/"+s.Name(), nil)
+	p.client.Do(req)
+
+	p.mu.RLock()
+	p.last = s.Name()
+	p.mu.RUnlock()
+}
+
+func (p *enrichProcessor) OnEnd(s sdktrace.ReadOnlySpan) {}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	tracer   = otel.Tracer("search")
+	searches metric.Int64Counter
+)
+
+func render(ctx context.Context, view string) {
+	_, span := tracer.Start(ctx, "render "+view)
+	defer span.End()
+}
+
+// q is reassigned a constant before it is used
+func handleFixed(w http.ResponseWriter, r *http.Request) {
+	q := r.FormValue("q")
+	q = "results"
+	render(r.Context(), q)
+}
+
+// Reassigned in a branch only, so it may still hold the input
+func handleBranch(w http.ResponseWriter, r *http.Request) {
+	q := r.FormValue("q")
+	if q == "" {
+		q = "empty"
+	}
+	render(r.Context(), q)
+}
+
+// Used before the reassignment
+func handleBefore(w http.ResponseWriter, r *http.Request) {
+	q := r.FormValue("q")
+	render(r.Context(), q)
+	q = "results"
+	_ = q
+}
+
+// Comparisons, lengths and booleans take a few values
+func handleBounded(w http.ResponseWriter, r *http.Request) {
+	long := len(r.FormValue("q")) > 3
+	searches.Add(r.Context(), 1, metric.WithAttributes(attribute.Bool("search.long", long)))
+	searches.Add(r.Context(), 1, metric.WithAttributes(attribute.Int("search.length", len(r.FormValue("q")))))
+	render(r.Context(), fmt.Sprintf("search long=%t", len(r.FormValue("q")) > 3))
+}
@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+"""
+Tests for the CEL subset of custom rules (rules/cel.py):
+
+    python -m unittest test_cel
+"""
+
+import sys
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.cel import CelError, Expression
+
+def evaluate(text: str, **env):
+    return Expression(text).evaluate(env)
+
+class CelTest(unittest.TestCase):
+    def test_bools_are_not_numbers(self):
+        for text in ("1 in [true]", "true in [1]", "[1] == [true]", "{1: 'a'} == {true: 'a'}", "true in {1: 'a'}",
+                     "[[0]] == [[false]]"):
+            self.assertIs(evaluate(text), False, text)
+        for text in ("1 in [1.0, true]", "[1, true] == [1, true]", "{'a': [1]} == {'a': [1]}", "1 in {1: 'a'}"):
+            self.assertIs(evaluate(text), True, text)
+
+    def test_map_index_matches_the_key_type(self):
+        self.assertEqual(evaluate("{1: 'a'}[1]"), "a")
+        with self.assertRaises(CelError):
+            evaluate("{1: 'a'}[true]")
+
+    def test_ascii_case(self):
+        self.assertEqual(evaluate("s.lowerAscii()", s="GET İSTANBUL"), "get İstanbul")
+        self.assertEqual(evaluate("s.upperAscii()", s="straße"), "STRAßE")
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,99 @@
+#!/usr/bin/env python3
+"""
+Tests for the patches `--fix --dry-run` and `migrate-semconv --dry-run` print, and for
+`run --fix`:
+
+    python -m unittest test_fixes
+"""
+
+import importlib.util
+import shutil
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.fixes import fix_diff
+
+HERE = Path(__file__).parent
+
+SHOP = {
+    "go.mod": "module example.com/shop\n\ngo 1.22\n",
+    "a.go": '''package shop
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func methodAttr(r *http.Request) attribute.KeyValue {
+	return attribute.String("http.method", r.Method)
+}
+''',
+    "b.go": '''package shop
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func requestAttr(r *http.Request) attribute.KeyValue {
+	return attribute.String("http.request.method", r.Method)
+}
+''',
+}
+
+class FixDiffTest(unittest.TestCase):
+    def apply(self, original: str, fixed: str) -> str:
+        with tempfile.TemporaryDirectory() as repo:
+            subprocess.run(["git", "init", "-q", repo], check=True)
+            target = Path(repo) / "svc" / "a.go"
+            target.parent.mkdir()
+            target.write_text(original)
+            patch = fix_diff(str(target), original, fixed)
+            self.assertIn("a/svc/a.go", patch)
+            # From anywhere in the repository, like the rules root
+            subprocess.run(["git", "apply", "-"], cwd=repo, input=patch, text=True, check=True)
+            return target.read_text()
+
+    def test_applies(self):
+        self.assertEqual(self.apply("x\ny\n", "x\nz\n"), "x\nz\n")
+
+    def test_applies_without_trailing_newline(self):
+        patch = fix_diff("a.go", "x\ny", "x\nz")
+        self.assertIn("-y\n\\ No newline at end of file\n+z\n\\ No newline at end of file\n", patch)
+        self.assertEqual(self.apply("x\ny", "x\nz"), "x\nz")
+        self.assertEqual(self.apply("x\ny", "x\nz\n"), "x\nz\n")
+
+    def test_unchanged(self):
+        self.assertEqual(fix_diff("a.go", "x\n", "x\n"), "")
+
+@unittest.skipUnless(importlib.util.find_spec("click"), "the CLI needs click")
+class RunFixTest(unittest.TestCase):
+    def run_fix(self, *args):
+        module = Path(tempfile.mkdtemp())
+        self.addCleanup(shutil.rmtree, module)
+        for name, code in SHOP.items():
+            (module / name).write_text(code)
+        out = subprocess.run([sys.executable, str(HERE / "otel_cli.py"), "--no-progress", "--no-baseline",
+                              "run", str(module), "--fix", *args], capture_output=True, text=True, check=True)
+        return module, out.stdout
+
+    def test_fixes_of_project_rules(self):
+        # semconv-version-mixed compares the files of the module, so file-by-file commands miss it
+        module, _ = self.run_fix()
+        self.assertIn('attribute.String("http.request.method", r.Method)', (module / "a.go").read_text())
+
+    def test_dry_run(self):
+        module, patch = self.run_fix("--dry-run")
+        self.assertEqual((module / "a.go").read_text(), SHOP["a.go"])
+        self.assertTrue(patch.startswith("diff --git a/a.go b/a.go\n"), patch)
+        self.assertIn('+\treturn attribute.String("http.request.method", r.Method)\n', patch)
+
+if __name__ == "__main__":
+    unittest.main()
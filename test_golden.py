@@ -0,0 +1,40 @@
+#!/usr/bin/env python3
+"""
+Tests for the golden-file comparison behind test_rule_golden.py:
+
+    python -m unittest test_golden
+"""
+
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.golden import check_golden
+
+FIXTURE = '''package main
+
+import "time"
+
+func main() { time.Sleep(time.Second) }
+'''
+
+class CheckGoldenTest(unittest.TestCase):
+    def test_missing_golden_fails_until_updated(self):
+        with tempfile.TemporaryDirectory() as tmp:
+            root, golden = Path(tmp) / "fixtures", Path(tmp) / "golden"
+            root.mkdir()
+            fixture = root / "sleep.go"
+            fixture.write_text(FIXTURE)
+
+            mismatches = check_golden([fixture], golden, root)
+            self.assertIn("is missing", mismatches[str(fixture)])
+
+            self.assertEqual(check_golden([fixture], golden, root, update=True), {})
+            self.assertTrue((golden / "sleep.go.golden").exists())
+            self.assertEqual(check_golden([fixture], golden, root), {})
+
+if __name__ == "__main__":
+    unittest.main()
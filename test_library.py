@@ -0,0 +1,51 @@
+#!/usr/bin/env python3
+"""
+Tests for telling library packages from application code (rules/sdk/library.py):
+
+    python -m unittest test_library
+"""
+
+import shutil
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.golang import GoFile
+from rules.sdk.library import is_library
+
+class IsLibraryTest(unittest.TestCase):
+    def module(self, files):
+        root = Path(tempfile.mkdtemp())
+        self.addCleanup(shutil.rmtree, root)
+        (root / "go.mod").write_text("module example.com/m\n\ngo 1.22\n")
+        for path, package in files.items():
+            (root / path).parent.mkdir(parents=True, exist_ok=True)
+            (root / path).write_text(f"package {package}\n")
+        return root
+
+    def source(self, root, path):
+        return GoFile(str(root / path), (root / path).read_text())
+
+    def test_library_with_example_and_cmd_mains(self):
+        root = self.module({"client.go": "m", "example/main.go": "main", "cmd/mtool/main.go": "main",
+                            "internal/wire/wire.go": "wire"})
+        self.assertTrue(is_library(self.source(root, "client.go")))
+        self.assertFalse(is_library(self.source(root, "example/main.go")))
+        self.assertFalse(is_library(self.source(root, "cmd/mtool/main.go")))
+        # cmd/ builds a program, which the internal package may be part of
+        self.assertFalse(is_library(self.source(root, "internal/wire/wire.go")))
+
+    def test_internal_packages_of_a_library(self):
+        root = self.module({"client.go": "m", "internal/wire/wire.go": "wire", "examples/basic/main.go": "main"})
+        self.assertTrue(is_library(self.source(root, "internal/wire/wire.go")))
+
+    def test_service(self):
+        root = self.module({"main.go": "main", "internal/server/server.go": "server", "pkg/api/api.go": "api"})
+        self.assertFalse(is_library(self.source(root, "internal/server/server.go")))
+        self.assertTrue(is_library(self.source(root, "pkg/api/api.go")))
+
+if __name__ == "__main__":
+    unittest.main()
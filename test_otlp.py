@@ -0,0 +1,44 @@
+#!/usr/bin/env python3
+"""
+Tests for exporting findings over OTLP (rules/otlp.py):
+
+    python -m unittest test_otlp
+"""
+
+import os
+import sys
+import unittest
+from pathlib import Path
+from unittest import mock
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.base import CodeLocation, TelemetryViolation
+from rules.otlp import log_records, resource_attributes
+
+def violation() -> TelemetryViolation:
+    return TelemetryViolation(
+        violation_id="v1", severity="high", file_path="svc/a.go",
+        location=CodeLocation(line_number=12, column=3, function_name="handle", code_snippet="", context_lines=[]),
+        violation_type="privacy", rule_violated="pii", description="user.email recorded", fix_suggestion="",
+        kb_reference="", confidence=0.9, detection_method="rule", language="go", rule_id="pii-in-telemetry")
+
+class OTLPTest(unittest.TestCase):
+    def test_code_attributes(self):
+        records = log_records({"svc/a.go": [violation()]}, 0)
+        attributes = {a["key"]: a["value"] for a in records[0]["attributes"]}
+        self.assertEqual(attributes["code.file.path"], {"stringValue": "svc/a.go"})
+        self.assertEqual(attributes["code.line.number"], {"intValue": "12"})
+        self.assertEqual(attributes["code.column.number"], {"intValue": "3"})
+        self.assertEqual(attributes["code.function.name"], {"stringValue": "handle"})
+        self.assertFalse([k for k in attributes if k in ("code.filepath", "code.lineno", "code.function")])
+
+    def test_resource_attributes_are_percent_decoded(self):
+        env = {"OTEL_RESOURCE_ATTRIBUTES": "service.name=ci%20lint,team=a%2Cb", "OTEL_SERVICE_NAME": ""}
+        with mock.patch.dict(os.environ, env):
+            attributes = resource_attributes(".")
+        self.assertEqual(attributes["service.name"], "ci lint")
+        self.assertEqual(attributes["team"], "a,b")
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,120 @@
+#!/usr/bin/env python3
+"""
+Tests for reading OTLP spans (rules/telemetry.py) and the scoring receiver (rules/receiver.py):
+
+    python -m unittest test_receiver
+"""
+
+import json
+import random
+import sys
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.receiver import ScoringReceiver
+from rules.telemetry import parse_spans
+
+def _varint(n: int) -> bytes:
+    out = b""
+    while True:
+        b = n & 0x7F
+        n >>= 7
+        if n:
+            out += bytes([b | 0x80])
+        else:
+            return out + bytes([b])
+
+def _field(number: int, value) -> bytes:
+    if isinstance(value, int):
+        return _varint(number << 3) + _varint(value)
+    if isinstance(value, str):
+        value = value.encode("utf-8")
+    return _varint(number << 3 | 2) + _varint(len(value)) + value
+
+def _kv(key: str, value: str) -> bytes:
+    return _field(1, key) + _field(2, _field(1, value))
+
+def _request(*spans: bytes) -> bytes:
+    resource = _field(1, _kv("service.name", "shop"))
+    scope_spans = _field(1, _field(1, "shop/http")) + b"".join(_field(2, s) for s in spans)
+    return _field(1, _field(1, resource) + _field(2, scope_spans))
+
+SPAN = _field(5, "GET /users/42") + _field(6, 2) + _field(9, _kv("user.email", "a@example.com"))
+
+DOCUMENT = {"resourceSpans": [{
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "shop"}}]},
+    "scopeSpans": [{"scope": {"name": "shop/http"}, "spans": [
+        {"name": "checkout", "kind": 2},
+        {"name": "GET /users/42", "kind": "SPAN_KIND_SERVER",
+         "attributes": [{"key": "retries", "value": {"intValue": "3"}},
+                        {"key": "ratio", "value": {"doubleValue": "NaN"}}],
+         "events": [{"name": "signup", "attributes": [{"key": "user.email", "value": {"stringValue": "a@b.co"}}]}]},
+    ]}],
+}]}
+
+class ParseSpansTest(unittest.TestCase):
+    def test_proto(self):
+        spans = parse_spans(_request(SPAN))
+        self.assertEqual([(s.name, s.kind, s.service, s.scope) for s in spans],
+                         [("GET /users/42", "server", "shop", "shop/http")])
+        self.assertEqual(spans[0].attributes, {"user.email": "a@example.com"})
+
+    def test_json(self):
+        spans = parse_spans(json.dumps(DOCUMENT).encode())
+        self.assertEqual([s.name for s in spans], ["checkout", "GET /users/42"])
+        self.assertEqual(spans[1].attributes["retries"], 3)
+        self.assertEqual(spans[1].events, [("signup", {"user.email": "a@b.co"})])
+
+    def test_malformed_json_raises_value_error(self):
+        for document in ({"resourceSpans": 5}, {"resourceSpans": [5]}, {"resourceSpans": [{"scopeSpans": 5}]},
+                         {"resourceSpans": [{"scopeSpans": [{"spans": [{"name": 5}]}]}]},
+                         {"resourceSpans": [{"scopeSpans": [{"spans": [{"attributes": [{"key": "k", "value": {
+                             "stringValue": 5}}]}]}]}]},
+                         {"resourceSpans": [{"scopeSpans": [{"spans": [{"attributes": [{"key": "k", "value": {
+                             "intValue": "three"}}]}]}]}]},
+                         [1, 2]):
+            with self.subTest(document=document), self.assertRaises(ValueError):
+                parse_spans(json.dumps(document).encode())
+
+    def test_malformed_proto_raises_value_error(self):
+        for request in (
+                # A varint where the span message belongs
+                _field(1, _field(2, _field(2, 7))),
+                # A length-delimited span kind
+                _request(_field(5, "checkout") + _field(6, "server")),
+                # A varint attribute value
+                _request(_field(5, "checkout") + _field(9, _field(1, "k") + _field(2, 1))),
+                b"\x1f\x8b\x08\x00broken"):
+            with self.subTest(request=request), self.assertRaises(ValueError):
+                parse_spans(request)
+
+    def test_mutations_only_raise_value_error(self):
+        valid = [_request(SPAN, _field(5, "checkout") + _field(11, _field(2, "retry") + _field(3, _kv("n", "1")))),
+                 json.dumps(DOCUMENT).encode()]
+        rng = random.Random(7)
+        for _ in range(3000):
+            buf = bytearray(rng.choice(valid))
+            for _ in range(rng.randint(1, 4)):
+                buf[rng.randrange(len(buf))] = rng.randrange(256)
+            try:
+                spans = parse_spans(bytes(buf))
+            except ValueError:
+                continue
+            receiver = ScoringReceiver()
+            for span in spans:
+                receiver.checker.add(span)
+
+class ScoringReceiverTest(unittest.TestCase):
+    def test_malformed_request_leaves_no_trace(self):
+        receiver = ScoringReceiver()
+        bad = {"resourceSpans": [{"scopeSpans": [{"spans": [{"name": "GET /users/42"}, {"name": 5}]}]}]}
+        with self.assertRaises(ValueError):
+            receiver.export(json.dumps(bad).encode())
+        self.assertEqual((receiver.requests, receiver.rejected, receiver.checker.spans), (0, 1, 0))
+        self.assertEqual(receiver.export(_request(SPAN)), 1)
+        self.assertEqual((receiver.requests, receiver.checker.spans), (1, 1))
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,87 @@
+#!/usr/bin/env python3
+"""
+Tests for reading sources from git revisions (rules/revisions.py):
+
+    python -m unittest test_revisions
+"""
+
+import shutil
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path, PurePosixPath
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.custom import package_path
+from rules.revisions import diff_revisions, load_revision
+from rules.sdk.library import is_library, module_root
+
+class RepoTest(unittest.TestCase):
+    def setUp(self):
+        self.repo = Path(tempfile.mkdtemp())
+        self.addCleanup(shutil.rmtree, self.repo)
+        self.git("init", "-q")
+
+    def git(self, *args):
+        return subprocess.run(["git", "-C", str(self.repo), "-c", "user.name=t", "-c", "user.email=t@example.com",
+                               *args], check=True, capture_output=True, text=True).stdout.strip()
+
+    def commit(self, files):
+        for path, content in files.items():
+            if content is None:
+                (self.repo / path).unlink()
+                continue
+            (self.repo / path).parent.mkdir(parents=True, exist_ok=True)
+            (self.repo / path).write_text(content)
+        self.git("add", "-A")
+        self.git("commit", "-q", "-m", "change")
+        return self.git("rev-parse", "HEAD")
+
+    def sources(self, ref, subpath=""):
+        return {s.path: s for s in load_revision(str(self.repo), ref, subpath)}
+
+class LoadRevisionTest(RepoTest):
+
+    def test_reads_every_file_of_the_revision(self):
+        first = self.commit({"go.mod": "module example.com/svc\n", "main.go": "package main\n",
+                             "internal/store/store.go": "package store\n", "vendor/x/x.go": "package x\n",
+                             "tools/vendor/y/y.go": "package y\n", "internal/myvendor/z.go": "package myvendor\n"})
+        self.commit({"main.go": "package main\n\nfunc main() {}\n", "api/api.go": "package api\n"})
+        sources = self.sources(first)
+        self.assertEqual(sorted(sources), ["internal/myvendor/z.go", "internal/store/store.go", "main.go"])
+        self.assertEqual(sources["main.go"].code, "package main\n")
+        self.assertEqual(sorted(self.sources("HEAD", "api")), ["api/api.go"])
+
+    def test_module_root_from_the_revision_tree(self):
+        # At first the repository is one service module; then go.mod moves into svc/ and the
+        # working tree no longer has it at the root
+        first = self.commit({"go.mod": "module example.com/svc\n", "main.go": "package main\n",
+                             "internal/store/store.go": "package store\n"})
+        self.commit({"go.mod": None, "svc/go.mod": "module example.com/svc\n",
+                     "main.go": None, "svc/main.go": "package main\n"})
+        store = self.sources(first)["internal/store/store.go"]
+        self.assertEqual(module_root(store), PurePosixPath("."))
+        self.assertEqual(package_path(store), "example.com/svc/internal/store")
+        # The module builds a program, so its internal package isn't library code
+        self.assertFalse(is_library(store))
+
+        head = self.sources("HEAD")["internal/store/store.go"]
+        self.assertIsNone(module_root(head))
+        self.assertTrue(is_library(head))
+
+class DiffRevisionsTest(RepoTest):
+    def test_suppression_added_in_head(self):
+        handler = (
+            'package shop\n\nimport "context"\n\nfunc handle(ctx context.Context) {\n'
+            '\t{ignore}_, span := tracer.Start(ctx, "HandleCheckout")\n\tdefer span.End()\n}\n')
+        base = self.commit({"go.mod": "module example.com/shop\n", "shop.go": handler.replace("{ignore}", "")})
+        self.commit({"shop.go": handler.replace(
+            "{ignore}", "//otel:ignore span-name-convention -- matches the upstream dashboard\n\t")})
+        diff = diff_revisions(str(self.repo), base, "HEAD")
+        self.assertEqual([v.rule_id for v in diff["added"]], [])
+        self.assertIn("span-name-convention", [v.rule_id for v in diff["resolved"]])
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+"""
+Runs every rule against the examples in its own metadata (rules/fixtures.py), as
+`otel_cli.py gen-fixtures` does: the violation example must be reported and the correct one
+must not be.
+
+    python -m unittest test_rule_examples
+"""
+
+import sys
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules import all_rules
+from rules.fixtures import verify_rule
+
+class RuleExamplesTest(unittest.TestCase):
+    def test_rules_match_their_examples(self):
+        for rule in all_rules():
+            with self.subTest(rule=rule.rule_id):
+                self.assertEqual(verify_rule(rule), [])
+
+if __name__ == "__main__":
+    unittest.main()
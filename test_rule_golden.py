@@ -0,0 +1,41 @@
+#!/usr/bin/env python3
+"""
+Snapshot test for the deterministic rule engine.
+Runs every rule over the Go fixtures in test-files/ and compares the diagnostics with
+test-files/golden/. After intentionally changing a rule, refresh the snapshots with:
+
+    python test_rule_golden.py --update
+"""
+
+import argparse
+import sys
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.golden import check_golden
+
+ROOT = Path(__file__).parent / "test-files"
+GOLDEN_DIR = ROOT / "golden"
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__, formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--update", action="store_true", help="Rewrite golden files from current output")
+    parser.add_argument("fixtures", nargs="*", help="Fixtures to check (default: all .go files in test-files/)")
+    args = parser.parse_args()
+
+    fixtures = [Path(f).resolve() for f in args.fixtures] or sorted(ROOT.rglob("*.go"))
+    mismatches = check_golden(fixtures, GOLDEN_DIR.resolve(), ROOT.resolve(), update=args.update)
+
+    if args.update:
+        print(f"Updated {len(fixtures)} golden file(s) in {GOLDEN_DIR}")
+        return 0
+
+    for fixture, diff in mismatches.items():
+        print(f"FAIL {fixture}")
+        print(diff)
+    print(f"{len(fixtures) - len(mismatches)}/{len(fixtures)} fixtures match their golden files")
+    return 1 if mismatches else 0
+
+if __name__ == "__main__":
+    sys.exit(main())
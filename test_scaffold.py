@@ -0,0 +1,38 @@
+#!/usr/bin/env python3
+"""
+Tests for the starter configuration `otel_cli.py init` writes:
+
+    python -m unittest test_scaffold
+"""
+
+import sys
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.registry import get_rule
+from rules.scaffold import starter_rules
+
+def facts(signals, frameworks=()):
+    return {"signals": list(signals), "frameworks": list(frameworks)}
+
+class StarterRulesTest(unittest.TestCase):
+    def test_rules_of_every_signal_stay_on(self):
+        self.assertEqual(get_rule("invalid-suppression").signal, "all")
+        for signals in (["traces"], ["traces", "metrics", "logs", "baggage"]):
+            disabled = starter_rules(facts(signals))["disable"]
+            self.assertNotIn("invalid-suppression", disabled)
+            self.assertNotIn("library-configures-exporter", disabled)
+
+    def test_rules_of_unused_signals_are_off(self):
+        disabled = starter_rules(facts(["traces"]))["disable"]
+        self.assertNotIn("span-name-unbounded", disabled)
+        self.assertIn("metric-name-convention", disabled)
+
+    def test_migration_rules_follow_legacy_libraries(self):
+        self.assertIn("opencensus-trace-api", starter_rules(facts(["traces"]))["disable"])
+        self.assertNotIn("opencensus-trace-api", starter_rules(facts(["traces"], ["opencensus"]))["disable"])
+
+if __name__ == "__main__":
+    unittest.main()
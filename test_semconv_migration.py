@@ -0,0 +1,76 @@
+#!/usr/bin/env python3
+"""
+Tests for moving code between semconv versions (rules/semconv_migration.py):
+
+    python -m unittest test_semconv_migration
+"""
+
+import importlib.util
+import json
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.golang import GoFile
+from rules.semconv_migration import import_path, migrate_file, plan_migration
+
+SOURCE = '''package store
+
+import (
+	"flag"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const dbKey attribute.Key = "db.name"
+
+const methodKey = "http.method"
+
+var dbFlag = "db.name"
+
+func init() {
+	flag.String(dbFlag, "orders", "database to use")
+}
+
+func attrs(method string) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String(methodKey, method)}
+}
+'''
+
+class PlanMigrationTest(unittest.TestCase):
+    def migrate(self, code):
+        source = GoFile("store.go", code)
+        changes = plan_migration(source, (1, 20), (1, 27), import_path("v1.27"))
+        return changes, migrate_file(source, changes)[0]
+
+    def test_only_attribute_key_declarations_are_rewritten(self):
+        changes, code = self.migrate(SOURCE)
+        self.assertIn('const dbKey attribute.Key = "db.namespace"', code)
+        # methodKey is passed to attribute.String
+        self.assertIn('const methodKey = "http.request.method"', code)
+        # A flag name: changing it would change the command line
+        self.assertIn('var dbFlag = "db.name"', code)
+        flag = [c for c in changes if c.line == 13]
+        self.assertEqual(len(flag), 1)
+        self.assertIsNone(flag[0].edit)
+        self.assertIn("not an attribute key here", flag[0].note)
+
+@unittest.skipUnless(importlib.util.find_spec("click"), "the CLI needs click")
+class MigrateCommandTest(unittest.TestCase):
+    def test_dry_run_json_keeps_stdout_json(self):
+        with tempfile.TemporaryDirectory() as root:
+            (Path(root) / "store.go").write_text(SOURCE)
+            out = subprocess.run([sys.executable, str(Path(__file__).parent / "otel_cli.py"), "migrate-semconv",
+                                  root, "--from", "v1.20", "--to", "v1.27", "--dry-run", "--format", "json"],
+                                 capture_output=True, text=True, check=True)
+            report = json.loads(out.stdout)
+            self.assertTrue(any(e["automated"] for e in report))
+            self.assertIn('+const dbKey attribute.Key = "db.namespace"', out.stderr)
+            self.assertEqual((Path(root) / "store.go").read_text(), SOURCE)
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,63 @@
+#!/usr/bin/env python3
+"""
+Tests for sharded runs and merging their reports (rules/shard.py):
+
+    python -m unittest test_shard
+"""
+
+import importlib.util
+import json
+import shutil
+import subprocess
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).parent))
+
+from rules.shard import merge_reports
+
+HERE = Path(__file__).parent
+# Package directory -> fixture, two packages in each of two shards
+FIXTURES = {"billing": "pii_in_telemetry.go", "orders": "attribute_stringified_number.go",
+            "search": "span_name_unbounded.go", "users": "log_pii_field.go"}
+
+class MergeReportsTest(unittest.TestCase):
+    def test_score_and_scan_reports(self):
+        scan = {"a/a.go": {"violations": [{"rule_id": "x"}]}}
+        score = {"score": 90.0, "results": {"b/b.go": {"violations": [{"rule_id": "y"}]}}}
+        merged, duplicates = merge_reports([scan, score, scan])
+        self.assertEqual(sorted(merged), ["a/a.go", "b/b.go"])
+        self.assertEqual(duplicates, ["a/a.go"])
+
+@unittest.skipUnless(importlib.util.find_spec("click"), "the CLI needs click")
+class ShardedScoreTest(unittest.TestCase):
+    def cli(self, *args):
+        out = subprocess.run([sys.executable, str(HERE / "otel_cli.py"), "--no-progress", "--no-baseline", *args],
+                             capture_output=True, text=True)
+        return json.loads(out.stdout)
+
+    def test_shards_run_project_rules(self):
+        root = Path(tempfile.mkdtemp())
+        self.addCleanup(shutil.rmtree, root)
+        for name, fixture in FIXTURES.items():
+            package = root / name
+            package.mkdir()
+            shutil.copy(HERE / "test-files" / "generated" / fixture, package / fixture)
+
+        def findings(report):
+            return sorted((path, v["rule_id"], v["line_number"])
+                          for path, result in report.items() for v in result["violations"])
+
+        whole = self.cli("score", str(root), "--shard", "1/1", "--format", "json")
+        shards = [self.cli("score", str(root), "--shard", f"{i}/2", "--format", "json") for i in (1, 2)]
+        merged, duplicates = merge_reports(shards)
+        self.assertEqual([s["files"] for s in shards], [2, 2])
+        self.assertEqual(duplicates, [])
+        self.assertEqual(findings(merged), findings(whole["results"]))
+        # A project-scope rule
+        self.assertIn("pii-in-telemetry", {rule for _, rule, _ in findings(merged)})
+
+if __name__ == "__main__":
+    unittest.main()